@@ -0,0 +1,77 @@
+package peering
+
+import (
+	"fmt"
+	"log"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Manual Acceptance
+// -------------------------------------------------------------------------------------------------
+
+// Acceptance modes of a connection.
+const (
+	AcceptanceAuto   = "auto"   // The stack accepts the peering itself (default).
+	AcceptanceManual = "manual" // The stack only requests it; the accept stack accepts it after approval.
+)
+
+// AcceptStackName is the stack holding the accepter side of manually accepted connections.
+const AcceptStackName = StackName + "-accept"
+
+// IsRequesterOnly reports whether the main stack builds only the peering request of a connection,
+// leaving its acceptance, options, and routes to the accept stack.
+func IsRequesterOnly(peer PeerConfig) bool {
+	return peer.ManualAcceptance && !peer.LooksUpPeering()
+}
+
+// RequesterOnlyPeers returns the connections as the main stack builds them: those awaiting manual
+// acceptance route nothing, since routes and options need an active peering.
+func RequesterOnlyPeers(peers []PeerConfig) []PeerConfig {
+	out := make([]PeerConfig, len(peers))
+	for i, peer := range peers {
+		if IsRequesterOnly(peer) {
+			peer.SourceRouting = RoutingConfig{Strategy: RoutingNone}
+			peer.PeerRouting = RoutingConfig{Strategy: RoutingNone}
+		}
+		out[i] = peer
+	}
+	return out
+}
+
+// AcceptStackPeers returns the manually accepted connections as the accept stack builds them: each
+// looks up its requested peering by pcx-id and accepts it, then manages both sides' options and the
+// routes. Connections whose peering has not been requested yet are left out until it has.
+func AcceptStackPeers(peers []PeerConfig, peeringIDs map[string]string) []PeerConfig {
+	var out []PeerConfig
+	for _, peer := range peers {
+		if !peer.ManualAcceptance {
+			continue
+		}
+		id := peeringIDs[ConnectionKey(peer)]
+		if id == "" {
+			log.Printf("[accept] %s has no requested peering in state yet; apply %s first", ConnectionKey(peer), StackName)
+			continue
+		}
+		peer.ExternalPeeringID = id
+		peer.ManageExternalOptions = true
+		out = append(out, peer)
+	}
+	return out
+}
+
+// RequestedPeeringIDs reads the pcx-ids of the manually accepted connections from the main stack's
+// state: a raw state file, or an initialized stack directory pulled through its backend.
+func RequestedPeeringIDs(namer Namer, peers []PeerConfig, statePath string) (map[string]string, error) {
+	snap, err := readStateArg(statePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the state of %s: %w", StackName, err)
+	}
+	report := BuildStateReport(BuildAddressMap(namer, peers), snap)
+	ids := make(map[string]string)
+	for _, cs := range report.Connections {
+		if cs.PeeringID != "" {
+			ids[cs.Key] = cs.PeeringID
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,66 @@
+package peering
+
+import (
+	"testing"
+)
+
+// TestResolveConnectionAcceptance tests the acceptance setting and its conflicts.
+func TestResolveConnectionAcceptance(t *testing.T) {
+	cfg := YAMLConfig{Peers: map[string]YAMLPeer{"foo": {VpcID: "vpc-1"}, "bar": {VpcID: "vpc-2"}}}
+	no := false
+	tests := []struct {
+		entry  MatrixEntry
+		valid  bool
+		manual bool
+	}{
+		{MatrixEntry{Peer: "bar"}, true, false},
+		{MatrixEntry{Peer: "bar", Acceptance: AcceptanceAuto}, true, false},
+		{MatrixEntry{Peer: "bar", Acceptance: AcceptanceManual}, true, true},
+		{MatrixEntry{Peer: "bar", Acceptance: "later"}, false, false},
+		{MatrixEntry{Peer: "bar", Acceptance: AcceptanceManual, ManagePeering: &no, PeeringID: "pcx-0abc123"}, false, false},
+	}
+	for _, tt := range tests {
+		peer, err := ResolveConnection(cfg, "foo", tt.entry)
+		if (err == nil) != tt.valid {
+			t.Errorf("ResolveConnection(%+v) error = %v, want valid=%v", tt.entry, err, tt.valid)
+			continue
+		}
+		if peer.ManualAcceptance != tt.manual {
+			t.Errorf("ResolveConnection(%+v).ManualAcceptance = %v, want %v", tt.entry, peer.ManualAcceptance, tt.manual)
+		}
+	}
+}
+
+// TestManualAcceptanceStacks tests which resources the main and the accept stack manage for a
+// manually accepted connection.
+func TestManualAcceptanceStacks(t *testing.T) {
+	routed := RoutingConfig{Strategy: RoutingMain}
+	peers := []PeerConfig{
+		{SourceName: "prod", Name: "prod-eu", PeerRegion: "eu-west-1", SourceRouting: routed, PeerRouting: routed, ManualAcceptance: true},
+		{SourceName: "prod", Name: "prod-us", PeerRegion: "us-east-1", SourceRouting: routed, PeerRouting: routed, ManualAcceptance: true},
+		{SourceName: "prod", Name: "dev", SourceRouting: routed, PeerRouting: routed},
+	}
+
+	requester := RequesterOnlyPeers(peers)
+	if requester[0].SourceRouting.Strategy != RoutingNone || requester[2].SourceRouting.Strategy != RoutingMain {
+		t.Errorf("unexpected main stack routing: %+v", requester)
+	}
+	requested := ConnectionAddresses(LegacyNamer{}, ConnectionNameContext(0, peers[0]), peers[0])
+	if len(requested) != 1 || requested[KindPeering] != "aws_vpc_peering_connection.VpcPeering0" {
+		t.Errorf("main stack should only request the peering, got %v", requested)
+	}
+
+	accept := AcceptStackPeers(peers, map[string]string{"prod/prod-eu": "pcx-0abc"})
+	if len(accept) != 1 || accept[0].ExternalPeeringID != "pcx-0abc" || !accept[0].ManageExternalOptions {
+		t.Fatalf("expected only the requested connection in the accept stack, got %+v", accept)
+	}
+	accepted := ConnectionAddresses(LegacyNamer{}, ConnectionNameContext(0, accept[0]), accept[0])
+	for _, kind := range []string{KindAccepter, KindOptions, KindAccepterOptions, KindSourceMainRoute, KindPeerMainRoute} {
+		if _, ok := accepted[kind]; !ok {
+			t.Errorf("accept stack is missing %s: %v", kind, accepted)
+		}
+	}
+	if _, ok := accepted[KindPeering]; ok {
+		t.Errorf("accept stack must not create the peering: %v", accepted)
+	}
+}
@@ -0,0 +1,84 @@
+package peering
+
+import (
+	"fmt"
+	"log"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Peer Account Resolution
+// -------------------------------------------------------------------------------------------------
+
+// AccountResolver looks up the account a role belongs to. Implemented with STS and by fakes in
+// tests.
+type AccountResolver interface {
+	AccountID(region, roleArn string) (string, error)
+}
+
+// STSAccountResolver resolves accounts by assuming the role and calling sts:GetCallerIdentity.
+type STSAccountResolver struct {
+	Settings ProviderSettings // Endpoint and session settings for the AWS CLI.
+}
+
+// AccountID implements AccountResolver.
+func (r STSAccountResolver) AccountID(region, roleArn string) (string, error) {
+	cli, err := NewAWSCLI(region, roleArn, r.Settings)
+	if err != nil {
+		return "", err
+	}
+	var out struct {
+		Account string `json:"Account"`
+	}
+	if err := cli.Run(&out, "sts", "get-caller-identity"); err != nil {
+		return "", err
+	}
+	return out.Account, nil
+}
+
+// PeerAccount returns the peer account of a connection: the resolved one when set, otherwise the
+// one in the peer role ARN. Empty means the peering is requested as same-account.
+func PeerAccount(peer PeerConfig) string {
+	if peer.PeerAccountID != "" {
+		return peer.PeerAccountID
+	}
+	return GetAccountIDFromRoleArn(peer.PeerRoleArn)
+}
+
+// ResolvePeerAccountIDs fills PeerAccountID for every connection whose peer role ARN does not carry
+// a parseable account (SSO and unusual role paths), resolving each role once.
+func ResolvePeerAccountIDs(peers []PeerConfig, resolver AccountResolver) error {
+	resolved := make(map[string]string)
+	for i := range peers {
+		peer := &peers[i]
+		if peer.PeerRoleArn == "" || PeerAccount(*peer) != "" {
+			continue
+		}
+		account, ok := resolved[peer.PeerRoleArn]
+		if !ok {
+			var err error
+			account, err = resolver.AccountID(peer.PeerRegion, peer.PeerRoleArn)
+			if err != nil {
+				return fmt.Errorf("failed to resolve the account of %s: %w", peer.PeerRoleArn, err)
+			}
+			if account == "" {
+				return fmt.Errorf("sts returned no account for %s", peer.PeerRoleArn)
+			}
+			resolved[peer.PeerRoleArn] = account
+			log.Printf("[accounts] Resolved %s to account %s", peer.PeerRoleArn, account)
+		}
+		peer.PeerAccountID = account
+	}
+	return nil
+}
+
+// WarnUnresolvedAccounts logs every connection that would be requested with an empty peer owner,
+// which AWS treats as the requester's own account.
+func WarnUnresolvedAccounts(peers []PeerConfig) {
+	for _, peer := range peers {
+		if peer.PeerRoleArn != "" && PeerAccount(peer) == "" {
+			log.Printf("[accounts] WARNING: cannot derive the account of %s for %s; "+
+				"the peering is requested as same-account (set resolve_account_ids to look it up with STS)",
+				peer.PeerRoleArn, ConnectionKey(peer))
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package peering
+
+import "testing"
+
+// fakeResolver returns fixed accounts per role and counts lookups.
+type fakeResolver struct {
+	accounts map[string]string
+	calls    int
+}
+
+// AccountID implements AccountResolver.
+func (f *fakeResolver) AccountID(region, roleArn string) (string, error) {
+	f.calls++
+	return f.accounts[roleArn], nil
+}
+
+// TestResolvePeerAccountIDs tests that only unparseable role ARNs are resolved, once per role.
+func TestResolvePeerAccountIDs(t *testing.T) {
+	sso := "arn:aws:iam::role/aws-reserved/sso.amazonaws.com/AWSReservedSSO_Network"
+	peers := []PeerConfig{
+		{SourceName: "dev", Name: "a", PeerRoleArn: sso},
+		{SourceName: "dev", Name: "b", PeerRoleArn: "arn:aws:iam::222222222222:role/peering"},
+		{SourceName: "qa", Name: "a", PeerRoleArn: sso},
+	}
+	resolver := &fakeResolver{accounts: map[string]string{sso: "333333333333"}}
+	if err := ResolvePeerAccountIDs(peers, resolver); err != nil {
+		t.Fatal(err)
+	}
+	if resolver.calls != 1 {
+		t.Errorf("expected 1 lookup, got %d", resolver.calls)
+	}
+
+	tests := []struct {
+		peer PeerConfig
+		want string
+	}{
+		{peers[0], "333333333333"},
+		{peers[1], "222222222222"},
+		{peers[2], "333333333333"},
+	}
+	for _, tt := range tests {
+		if got := PeerAccount(tt.peer); got != tt.want {
+			t.Errorf("%s: PeerAccount = %q, want %q", ConnectionKey(tt.peer), got, tt.want)
+		}
+	}
+
+	if err := ResolvePeerAccountIDs([]PeerConfig{{PeerRoleArn: "bad"}}, &fakeResolver{}); err == nil {
+		t.Error("expected an error when STS returns no account")
+	}
+}
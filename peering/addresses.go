@@ -0,0 +1,246 @@
+package peering
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Resource Addresses
+// -------------------------------------------------------------------------------------------------
+
+// managedResourceTypes maps each managed (non data source) kind to its Terraform resource type.
+var managedResourceTypes = map[string]string{
+	KindPeering:              "aws_vpc_peering_connection",
+	KindAccepter:             "aws_vpc_peering_connection_accepter",
+	KindOptions:              "aws_vpc_peering_connection_options",
+	KindAccepterOptions:      "aws_vpc_peering_connection_options",
+	KindSourceMainRoute:      "aws_route",
+	KindPeerMainRoute:        "aws_route",
+	KindSourceSubnetRoute:    "aws_route",
+	KindPeerSubnetRoute:      "aws_route",
+	KindSourceAllRoute:       "aws_route",
+	KindPeerAllRoute:         "aws_route",
+	KindSourceDedicatedRt:    "aws_route_table",
+	KindPeerDedicatedRt:      "aws_route_table",
+	KindSourceRtAssociation:  "aws_route_table_association",
+	KindPeerRtAssociation:    "aws_route_table_association",
+	KindSourceApplianceRoute: "aws_route",
+	KindPeerApplianceRoute:   "aws_route",
+}
+
+// AddressMap records the Terraform address of every managed resource, keyed by connection key and
+// then by resource kind ("<kind>:<cidr>" for CIDR-specific routes). It is the format of the
+// state-address mapping file.
+type AddressMap map[string]map[string]string
+
+// ConnectionKey identifies a connection independently of its position in the stack.
+func ConnectionKey(peer PeerConfig) string {
+	return fmt.Sprintf("%s/%s", peer.SourceName, ConnectionNameContext(0, peer).Peer)
+}
+
+// IsAutoAccept reports whether the requester can accept the peering itself, which is only the
+// case when both sides live in the same region and the peer is not a third party.
+func IsAutoAccept(peer PeerConfig) bool {
+	return !peer.ThirdParty && ResolveRegion(peer.SourceRegion) == ResolveRegion(peer.PeerRegion)
+}
+
+// ConnectionAddresses returns the Terraform addresses of the managed resources of one connection,
+// keyed by resource kind.
+func ConnectionAddresses(namer Namer, ctx NameContext, peer PeerConfig) map[string]string {
+	if IsRequesterOnly(peer) {
+		return map[string]string{KindPeering: managedResourceTypes[KindPeering] + "." + namer.ID(ctx, KindPeering)}
+	}
+
+	var kinds []string
+	switch {
+	case peer.RetiredPeeringID != "":
+	case peer.ThirdParty && !peer.LooksUpPeering():
+		kinds = append(kinds, KindPeering)
+	case !peer.LooksUpPeering():
+		kinds = append(kinds, KindPeering, KindOptions, KindAccepterOptions)
+		if !IsAutoAccept(peer) {
+			kinds = append(kinds, KindAccepter)
+		}
+	case peer.ManageExternalOptions:
+		kinds = append(kinds, KindOptions, KindAccepterOptions)
+	}
+	if peer.ManualAcceptance && peer.ExternalPeeringID != "" {
+		kinds = append(kinds, KindAccepter)
+	}
+	sourceKinds := sourceSide.managedRouteKinds(peer.SourceRouting, peer.Inspection != nil)
+	peerKinds := peerSide.managedRouteKinds(peer.PeerRouting, peer.Inspection != nil && peer.Inspection.Peer != nil)
+	kinds = append(kinds, sourceKinds...)
+	kinds = append(kinds, peerKinds...)
+	if peer.SourceRouting.DedicatedRouteTable {
+		kinds = append(kinds, KindSourceDedicatedRt, KindSourceRtAssociation)
+	}
+	if peer.PeerRouting.DedicatedRouteTable {
+		kinds = append(kinds, KindPeerDedicatedRt, KindPeerRtAssociation)
+	}
+
+	addresses := make(map[string]string, len(kinds))
+	for _, kind := range kinds {
+		addresses[kind] = managedResourceTypes[kind] + "." + namer.ID(ctx, kind)
+	}
+
+	// Routes toward explicit destination CIDRs replace the single whole-VPC route.
+	if len(peer.DestinationCidrs) > 0 {
+		for _, kind := range sourceKinds {
+			if _, ok := addresses[kind]; !ok {
+				continue
+			}
+			delete(addresses, kind)
+			for _, cidr := range peer.DestinationCidrs {
+				addresses[kind+":"+cidr] = managedResourceTypes[kind] + "." + CidrRouteID(namer.ID(ctx, kind), cidr)
+			}
+		}
+	}
+
+	// Extra routes come in addition to the regular ones, on their own side.
+	for _, s := range []struct {
+		kinds []string
+		cidrs []string
+	}{{sourceKinds, peer.SourceExtraCidrs}, {peerKinds, peer.PeerExtraCidrs}} {
+		for _, kind := range s.kinds {
+			for _, cidr := range s.cidrs {
+				addresses[kind+":"+cidr] = managedResourceTypes[kind] + "." + CidrRouteID(namer.ID(ctx, kind), cidr)
+			}
+		}
+	}
+	return addresses
+}
+
+// BuildAddressMap computes the AddressMap for all connections of a stack.
+func BuildAddressMap(namer Namer, peers []PeerConfig) AddressMap {
+	m := make(AddressMap, len(peers))
+	for i, peer := range peers {
+		m[ConnectionKey(peer)] = ConnectionAddresses(namer, ConnectionNameContext(i, peer), peer)
+	}
+	return m
+}
+
+// LoadAddressMap reads a state-address mapping file.
+func LoadAddressMap(path string) (AddressMap, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m AddressMap
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse address map %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// WriteAddressMap writes a state-address mapping file as indented JSON.
+func WriteAddressMap(path string, m AddressMap) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// AddressesFile is the name of the AddressMap every synth writes into each stack's directory under
+// cdktf.out/stacks, and the default of verify-addresses' committed baseline in the working directory.
+const AddressesFile = "addresses.json"
+
+// AddressChange is a resource whose address differs between two AddressMaps.
+type AddressChange struct {
+	Key  string // Connection key.
+	Kind string // Resource kind, "<kind>:<cidr>" for CIDR-specific routes.
+	From string // Address in the previous map.
+	To   string // Address in the current map.
+}
+
+// AddressChanges returns the resources of connections present in both maps whose address changed,
+// sorted by connection and kind. Resources added or removed with a connection's config are not
+// changes: only a resource both maps record under a new address would be destroyed and recreated.
+func AddressChanges(previous, current AddressMap) []AddressChange {
+	var changes []AddressChange
+	for key, addresses := range current {
+		old, ok := previous[key]
+		if !ok {
+			continue
+		}
+		for kind, to := range addresses {
+			if from, ok := old[kind]; ok && from != to {
+				changes = append(changes, AddressChange{Key: key, Kind: kind, From: from, To: to})
+			}
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Key != changes[j].Key {
+			return changes[i].Key < changes[j].Key
+		}
+		return changes[i].Kind < changes[j].Kind
+	})
+	return changes
+}
+
+// -------------------------------------------------------------------------------------------------
+// Moved Blocks
+// -------------------------------------------------------------------------------------------------
+
+// Move is a single Terraform moved block.
+type Move struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// PlanMoves compares a previous AddressMap with the current one and returns the moved blocks that
+// keep every surviving connection's resources in place. Only connections present in both maps are
+// considered. A move into any address the previous map holds is rejected, whether its connection
+// stays or departs: Terraform applies the moves as one chain, and a move into an address still in
+// state is blocked, leaving the connection behind to be replaced.
+func PlanMoves(previous, current AddressMap) ([]Move, error) {
+	occupied := make(map[string]string)
+	for key, addresses := range previous {
+		for _, address := range addresses {
+			occupied[address] = key
+		}
+	}
+
+	var moves []Move
+	for key, addresses := range current {
+		old, ok := previous[key]
+		if !ok {
+			continue
+		}
+		for kind, to := range addresses {
+			from, ok := old[kind]
+			if !ok || from == to {
+				continue
+			}
+			if owner, taken := occupied[to]; taken {
+				return nil, fmt.Errorf(
+					"connection %q would move %s to %s, which is still held by %q; switch to an index-free "+
+						"naming pattern such as \"{source}-{peer}-{kind}\" first, then remove or reorder peers in a second apply",
+					key, from, to, owner,
+				)
+			}
+			moves = append(moves, Move{From: from, To: to})
+		}
+	}
+
+	sort.Slice(moves, func(i, j int) bool { return moves[i].From < moves[j].From })
+	return moves, nil
+}
+
+// AddMovedBlocks writes the moves into the stack as top-level Terraform moved blocks.
+func AddMovedBlocks(stack cdktf.TerraformStack, moves []Move) {
+	if len(moves) == 0 {
+		return
+	}
+	blocks := make([]map[string]string, 0, len(moves))
+	for _, m := range moves {
+		blocks = append(blocks, map[string]string{"from": m.From, "to": m.To})
+	}
+	stack.AddOverride(jsii.String("moved"), blocks)
+}
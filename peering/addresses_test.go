@@ -0,0 +1,140 @@
+package peering
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestConnectionAddresses tests which managed resources are recorded for a connection.
+func TestConnectionAddresses(t *testing.T) {
+	peer := PeerConfig{SourceName: "dev", Name: "prod", SourceRegion: "us-east-1", PeerRegion: "us-west-2"}
+	got := ConnectionAddresses(LegacyNamer{}, ConnectionNameContext(1, peer), peer)
+	if got[KindPeering] != "aws_vpc_peering_connection.VpcPeering1" {
+		t.Errorf("unexpected peering address: %q", got[KindPeering])
+	}
+	if got[KindAccepter] != "aws_vpc_peering_connection_accepter.VpcPeeringAccepter1" {
+		t.Errorf("expected accepter for cross-region peering, got %q", got[KindAccepter])
+	}
+	if _, ok := got[KindSourceSubnetRoute]; ok {
+		t.Errorf("unexpected subnet route without additional routes")
+	}
+
+	peer.PeerRegion = "us-east-1"
+	if _, ok := ConnectionAddresses(LegacyNamer{}, ConnectionNameContext(1, peer), peer)[KindAccepter]; ok {
+		t.Errorf("unexpected accepter for same-region peering")
+	}
+
+	peer.ExternalPeeringID = "pcx-0abc"
+	got = ConnectionAddresses(LegacyNamer{}, ConnectionNameContext(1, peer), peer)
+	if _, ok := got[KindPeering]; ok {
+		t.Errorf("unexpected peering for an external peering")
+	}
+	if _, ok := got[KindOptions]; ok {
+		t.Errorf("unexpected options for an external peering without manage_options")
+	}
+	if _, ok := got[KindSourceMainRoute]; !ok {
+		t.Errorf("expected routes for an external peering, got %v", got)
+	}
+	peer.ManageExternalOptions = true
+	if _, ok := ConnectionAddresses(LegacyNamer{}, ConnectionNameContext(1, peer), peer)[KindOptions]; !ok {
+		t.Errorf("expected options for an external peering with manage_options")
+	}
+
+	peer.PeerRouting = RoutingConfig{Strategy: RoutingMain}
+	peer.PeerExtraCidrs = []string{"100.64.0.0/16"}
+	got = ConnectionAddresses(LegacyNamer{}, ConnectionNameContext(1, peer), peer)
+	if _, ok := got[KindPeerMainRoute]; !ok {
+		t.Errorf("expected the whole-VPC route next to an extra route, got %v", got)
+	}
+	if got[KindPeerMainRoute+":100.64.0.0/16"] != "aws_route.PeerToPeerMainRoute1_100_64_0_0_16" {
+		t.Errorf("unexpected extra route address: %v", got)
+	}
+}
+
+// TestPlanMoves tests moved block generation for renamed connections.
+func TestPlanMoves(t *testing.T) {
+	peers := []PeerConfig{
+		{SourceName: "dev", Name: "prod"},
+		{SourceName: "dev", Name: "qa"},
+	}
+	previous := BuildAddressMap(LegacyNamer{}, peers)
+	current := BuildAddressMap(PatternNamer{Pattern: "{source}-{peer}-{kind}"}, peers)
+
+	moves, err := PlanMoves(previous, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moves) != 10 {
+		t.Fatalf("expected 10 moves, got %d: %v", len(moves), moves)
+	}
+	for _, m := range moves {
+		if m.From == "aws_vpc_peering_connection.VpcPeering1" && m.To != "aws_vpc_peering_connection.dev-qa-peering" {
+			t.Errorf("unexpected move target for qa peering: %q", m.To)
+		}
+	}
+
+	if moves, err := PlanMoves(previous, previous); err != nil || len(moves) != 0 {
+		t.Errorf("expected no moves for unchanged addresses, got %v, %v", moves, err)
+	}
+}
+
+// TestPlanMovesRejectsOccupiedTargets tests that moves into an address another connection keeps
+// are rejected.
+func TestPlanMovesRejectsOccupiedTargets(t *testing.T) {
+	previous := AddressMap{
+		"dev/prod": {KindPeering: "aws_vpc_peering_connection.VpcPeering0"},
+		"dev/qa":   {KindPeering: "aws_vpc_peering_connection.VpcPeering1"},
+	}
+	current := AddressMap{
+		"dev/prod": {KindPeering: "aws_vpc_peering_connection.VpcPeering0"},
+		"dev/qa":   {KindPeering: "aws_vpc_peering_connection.VpcPeering0"},
+	}
+	if _, err := PlanMoves(previous, current); err == nil || !strings.Contains(err.Error(), `still held by "dev/prod"`) {
+		t.Errorf("expected error when moving into an occupied address, got %v", err)
+	}
+}
+
+// TestPlanMovesRejectsRemovedTargets tests that removing the first of three peers with index-based
+// names is rejected: Terraform would run the shifted moves as one chain, and the move into the
+// removed peer's address, still in state, would block the others.
+func TestPlanMovesRejectsRemovedTargets(t *testing.T) {
+	previous := BuildAddressMap(LegacyNamer{}, []PeerConfig{
+		{SourceName: "dev", Name: "prod"},
+		{SourceName: "dev", Name: "qa"},
+		{SourceName: "dev", Name: "ops"},
+	})
+	current := BuildAddressMap(LegacyNamer{}, []PeerConfig{
+		{SourceName: "dev", Name: "qa"},
+		{SourceName: "dev", Name: "ops"},
+	})
+	if _, err := PlanMoves(previous, current); err == nil || !strings.Contains(err.Error(), "still held by") {
+		t.Errorf("expected error when moving into a removed connection's address, got %v", err)
+	}
+}
+
+// TestAddressChanges tests that only renamed resources of connections in both maps are reported.
+func TestAddressChanges(t *testing.T) {
+	previous := AddressMap{
+		"dev/prod":  {KindPeering: "aws_vpc_peering_connection.VpcPeering0", KindAccepter: "aws_vpc_peering_connection_accepter.VpcPeeringAccepter0"},
+		"dev/stage": {KindPeering: "aws_vpc_peering_connection.VpcPeering1"},
+		"dev/gone":  {KindPeering: "aws_vpc_peering_connection.VpcPeering2"},
+	}
+	current := AddressMap{
+		"dev/prod":  {KindPeering: "aws_vpc_peering_connection.VpcPeering1", KindSourceMainRoute: "aws_route.SourceToPeerMainRoute1"},
+		"dev/stage": {KindPeering: "aws_vpc_peering_connection.VpcPeering1"},
+		"dev/new":   {KindPeering: "aws_vpc_peering_connection.VpcPeering0"},
+	}
+	want := []AddressChange{{
+		Key:  "dev/prod",
+		Kind: KindPeering,
+		From: "aws_vpc_peering_connection.VpcPeering0",
+		To:   "aws_vpc_peering_connection.VpcPeering1",
+	}}
+	if got := AddressChanges(previous, current); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if got := AddressChanges(current, current); len(got) != 0 {
+		t.Errorf("expected no changes, got %v", got)
+	}
+}
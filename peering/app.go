@@ -0,0 +1,550 @@
+// -----------------------------------------------------------------------------
+// Package peering implements a CDKTF VPC Peering Stack with Bi-Directional Routing,
+// DNS, and Automatic Subnet Route Management. Handles cross-account/region
+// peering with explicit accepter resource. The command at the module root runs
+// Main; other programs import the package to build stacks themselves.
+// -----------------------------------------------------------------------------
+package peering
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// -----------------------------------------------------------------------------
+// Stack Construction
+// -----------------------------------------------------------------------------
+
+// StackName is the ID of the synthesized stack and the name of its directory under cdktf.out/stacks.
+const StackName = "cdktf-vpc-peering-module"
+
+// StackOptions holds stack-wide settings that are not specific to a single peer.
+type StackOptions struct {
+	Namer           Namer                 // Naming strategy for construct IDs and Name tags (LegacyNamer if nil).
+	MovedFrom       AddressMap            // Previous resource addresses to generate moved blocks from (optional).
+	Imports         []ImportBlock         // Existing routes to adopt with import blocks (optional).
+	Provider        ProviderSettings      // Settings applied to every AWS provider.
+	Checks          bool                  // Emit a connectivity check block per connection.
+	Aspects         []cdktf.IAspect       // Aspects applied to every construct, built-in and user-supplied.
+	Inventory       InventoryConfig       // Sinks the connection inventory is written to on apply.
+	Provenance      ProvenanceConfig      // Route table tags and SSM parameters marking managed routes.
+	RoleVars        bool                  // Assume roles through sensitive variables instead of literal ARNs.
+	Terraform       TerraformSettings     // Terraform and AWS provider version constraints.
+	VerifyCidrs     bool                  // Look up VPCs with a pinned cidr and fail the plan if it changed.
+	Rollout         RolloutConfig         // Routes staged across applies with the rollout_batch variable.
+	CidrVars        bool                  // Route destination_cidrs through per-connection variables.
+	OwnedPeerings   OwnedPeeringsConfig   // How stacks share the pcx-id of peerings both sources list.
+	AccepterDetails AccepterDetailsConfig // SSM parameters publishing connection details in accepter accounts.
+	Lattice         *LatticeConfig        // Service network of the lattice connections (none if nil).
+	LatticeVpcs     []LatticeAssociation  // VPCs the stack associates with the service network.
+	Forget          AddressMap            // Addresses of forgotten connections to write removed blocks for (optional).
+}
+
+// synthTarget is one stack of a synth and the connections it holds.
+type synthTarget struct {
+	Stack   string               // Stack ID and directory name under cdktf.out/stacks.
+	Source  string               // Source filter of the stack; empty for every source.
+	Peers   []PeerConfig         // Connections of the stack.
+	Lattice []LatticeAssociation // VPCs the stack associates with the lattice service network.
+}
+
+// StackOptionsFor returns the stack options set by the config. Options from flags and environment
+// variables (VerifyCidrs, MovedFrom, Imports) are left to the caller.
+func StackOptionsFor(cfg YAMLConfig) StackOptions {
+	return StackOptions{
+		Namer:           NewNamer(cfg.Naming),
+		Provider:        cfg.Provider,
+		Checks:          cfg.ConnectivityChecks,
+		Aspects:         cfg.Aspects.Build(),
+		Inventory:       cfg.Inventory,
+		Provenance:      cfg.RouteProvenance,
+		RoleVars:        cfg.RoleArnVariables,
+		Terraform:       cfg.Terraform,
+		Rollout:         cfg.Rollout,
+		CidrVars:        cfg.CidrVariables,
+		OwnedPeerings:   cfg.OwnedPeerings,
+		AccepterDetails: cfg.AccepterDetails,
+		Lattice:         cfg.Lattice,
+	}
+}
+
+/*
+NewMyStack constructs the CDKTF stack for VPC peering, bi-directional routing, and DNS management.
+
+Parameters:
+
+	scope     - The CDKTF construct scope.
+	id        - Logical stack identifier.
+	sourceID  - The source identifier for this resource.
+	peers     - Slice of PeerConfig describing all peering relationships.
+	opts      - Stack-wide settings such as the naming strategy.
+
+Returns:
+
+	PeeringStack wrapping the cdktf.TerraformStack, with typed access to every peering, accepter,
+	route, provider, and data source it defines.
+*/
+func NewMyStack(scope constructs.Construct, id string, sourceID string, peers []PeerConfig, opts StackOptions) PeeringStack {
+	stack := cdktf.NewTerraformStack(scope, &id)
+	result := PeeringStack{Stack: stack}
+	opts.Terraform.Apply(stack)
+
+	var namer Namer = LegacyNamer{}
+	if opts.Namer != nil {
+		namer = opts.Namer
+	}
+	namer = NewIDRegistry(namer)
+	peers = RequesterOnlyPeers(peers)
+
+	cdktf.NewTerraformVariable(stack, jsii.String("source_id"), &cdktf.TerraformVariableConfig{
+		Type:        jsii.String("string"),
+		Description: jsii.String("The source identifier for this resource"),
+		Default:     jsii.String("default-source"),
+	})
+
+	// Instantiate real factories for production use
+	providerFactory := &RealAwsProviderFactory{Settings: opts.Provider}
+	if opts.RoleVars {
+		providerFactory.RoleRefs = AddRoleVariables(stack, peers)
+	}
+	vpcFactory := &RealDataAwsVpcFactory{}
+	rtFactory := &RealDataAwsRouteTableFactory{}
+
+	for i, peer := range peers {
+		// --- Validate peer configuration or set defaults ---
+		sourceRegion := ResolveRegion(peer.SourceRegion)
+		peerRegion := ResolveRegion(peer.PeerRegion)
+
+		// --- Get core info on each peer ---
+		ctx := ConnectionNameContext(i, peer)
+		core := SetupPeerCoreResources(
+			providerFactory,
+			vpcFactory,
+			rtFactory,
+			stack,
+			namer,
+			ctx,
+			peer,
+			sourceRegion,
+			peerRegion,
+			opts.VerifyCidrs,
+		)
+
+		// --- Prepare peering connection and related resources ---
+		peerOwnerID := PeerAccount(peer)
+		autoAccept := sourceRegion == peerRegion && !peer.ManualAcceptance && !peer.ThirdParty
+
+		peeringRes := CreatePeeringResources(
+			stack,
+			namer,
+			ctx,
+			peer,
+			core,
+			peerOwnerID,
+			autoAccept,
+			peerRegion,
+		)
+
+		// --- Create all main and subnet routes for this peer ---
+		routes := CreateBiDirectionalSubnetRoutes(
+			stack,
+			namer,
+			ctx,
+			peer,
+			core,
+			peeringRes,
+		)
+
+		result.Connections = append(result.Connections, ConnectionResources{
+			Peer:    peer,
+			Core:    core,
+			Peering: peeringRes,
+			Routes:  routes,
+		})
+	}
+
+	WireOwnedPeerings(stack, namer, result.Connections, opts.OwnedPeerings)
+	WireConnectionDependencies(result.Connections)
+	AddOutputs(stack, namer, result.Connections)
+	AddInventory(stack, namer, sourceID, result.Connections, opts.Inventory, opts.Provider)
+	AddAccepterDetails(stack, namer, result.Connections, opts.AccepterDetails)
+	AddDNSProfiles(stack, namer, result.Connections)
+	AddRouteProvenance(stack, namer, result.Connections, opts.Provenance)
+	if opts.Checks {
+		AddConnectivityChecks(stack, namer, peers)
+	}
+	AddLattice(stack, opts.Lattice, opts.LatticeVpcs, opts.Provider)
+	ApplyRollout(stack, result.Connections, opts.Rollout)
+	AddCidrVariables(stack, result.Connections, opts.CidrVars)
+	ApplyLifecycle(result.Connections)
+
+	// --- Keep renamed or reordered resources in place ---
+	if opts.MovedFrom != nil {
+		moves, err := PlanMoves(opts.MovedFrom, BuildAddressMap(namer, peers))
+		if err != nil {
+			Failf(ExitSynth, "failed to plan moved blocks: %v", err)
+		}
+		AddMovedBlocks(stack, moves)
+	}
+	AddRemovedBlocks(stack, opts.Forget)
+	AddImportBlocks(stack, opts.Imports)
+	AddAspects(stack, opts.Aspects)
+	return result
+}
+
+// -----------------------------------------------------------------------------
+// Main Entrypoint
+// -----------------------------------------------------------------------------
+
+/*
+Main is the entrypoint for the CDKTF VPC peering stack application, run by the command at the
+module root.
+
+  - Exports --config as CDKTF_PEERING_CONFIG, for subcommands and the synths they start, and
+    --error-format as CDKTF_ERROR_FORMAT; failures exit with the code of their class (see Fail).
+  - Dispatches to a subcommand when one is given (see Commands).
+  - Parses synth flags (--endpoint-url, defaulting to CDKTF_ENDPOINT_URL; --watch for watch mode; --accept for the accept stack).
+  - Loads configuration from CDKTF_PEERING_CONFIG or the config search paths.
+  - Picks the first role of each peer's role_arns that can be assumed, unless --offline.
+  - Determines the source ID from environment or default; a list or glob in CDKTF_SOURCE selects
+    a stack per matching source, synthesized in a pool of processes with per-source logs under --jobs.
+  - Converts config to a PeerConfig slice per stack, and collects the VPCs its lattice connections
+    associate with the service network (each VPC once, in the first stack that connects it).
+  - Fails if no peers match.
+  - Under --offline, checks that every lookup is declared in the config and skips connectivity checks.
+  - Resolves unparseable peer accounts with STS when resolve_account_ids is set.
+  - Orders stacks whose connections depend on connections of another stack (depends_on).
+  - Sends synth duration, connection counts, and failures to statsd when CDKTF_STATSD_ADDR is set.
+  - Loads previous resource addresses from CDKTF_MOVED_FROM, if set.
+  - Loads import blocks for existing routes from CDKTF_IMPORTS, if set.
+  - Synthesizes the CDKTF app, with the accept stack of requested manual peerings under --accept,
+    reporting jsii failures with a hint and exit code instead of a stack trace (--debug adds the full
+    error and the construct tree).
+*/
+func Main() {
+	// --- Initialize logging ---
+	log.SetFlags(0)
+	log.SetOutput(os.Stdout)
+
+	// --- Export --config so subcommands and synths started by them find the same file ---
+	configFlag, args, err := ExtractConfigFlag(os.Args[1:])
+	if err != nil {
+		Fail(WithExitCode(ExitConfig, err))
+	}
+	if configFlag != "" && !IsRemoteConfig(configFlag) {
+		if abs, err := filepath.Abs(configFlag); err == nil {
+			configFlag = abs
+		}
+	}
+	if configFlag != "" {
+		os.Setenv(ConfigEnvVar, configFlag)
+	}
+
+	// --- Export --error-format the same way, so every failure is reported in it ---
+	errorFormat, args, err := ExtractGlobalFlag(args, "error-format")
+	if err == nil {
+		err = ValidateErrorFormat(errorFormat)
+	}
+	if err != nil {
+		Fail(err)
+	}
+	if errorFormat != "" {
+		os.Setenv(ErrorFormatEnvVar, errorFormat)
+	}
+
+	// --- And --verify-config, so every command refuses configs that fail verification ---
+	verifyConfig, args, err := ExtractGlobalFlag(args, "verify-config")
+	if err == nil {
+		err = ValidateConfigVerify(verifyConfig)
+	}
+	if err != nil {
+		Fail(WithExitCode(ExitConfig, err))
+	}
+	if verifyConfig != "" {
+		os.Setenv(ConfigVerifyEnvVar, verifyConfig)
+	}
+	configSHA256, args, err := ExtractGlobalFlag(args, "config-sha256")
+	if err != nil {
+		Fail(WithExitCode(ExitConfig, err))
+	}
+	if configSHA256 != "" {
+		os.Setenv(ConfigSHA256EnvVar, configSHA256)
+	}
+
+	if len(args) > 0 && (!strings.HasPrefix(args[0], "-") || IsHelp(args[0])) {
+		if err := RunCommand(args[0], args[1:]); err != nil {
+			Fail(fmt.Errorf("%s: %w", args[0], err))
+		}
+		return
+	}
+
+	fs := flag.NewFlagSet("synth", flag.ExitOnError)
+	endpointURL := fs.String("endpoint-url", os.Getenv("CDKTF_ENDPOINT_URL"), "point every provider at this endpoint")
+	watch := fs.Bool("watch", false, "re-lint and re-synthesize whenever the config changes")
+	accept := fs.Bool("accept", false, "also synthesize the accept stack for connections with acceptance: manual")
+	acceptState := fs.String("accept-state", stackOutDir(StackName), "state file or initialized stack directory of the main stack, read by --accept")
+	offline := fs.Bool("offline", os.Getenv("CDKTF_OFFLINE") != "", "synthesize without data sources, from CIDRs and route table IDs declared in the config")
+	verifyCidrs := fs.Bool("verify-cidrs", os.Getenv("CDKTF_VERIFY_CIDRS") != "", "look up VPCs with a pinned cidr anyway and fail the plan when it no longer matches")
+	emitHCL := fs.Bool("emit-hcl", os.Getenv("CDKTF_EMIT_HCL") != "", "also write each synthesized stack as HCL to cdktf.out/hcl/<stack>/main.tf for review")
+	debug := fs.Bool("debug", os.Getenv("CDKTF_DEBUG") != "", "on a synth failure, print the full error and the construct tree")
+	placementsPath := fs.String("placements", os.Getenv(PlacementsEnvVar), "committed file recording the stack of every connection of a stack split by max_providers_per_stack; updated by every synth")
+	jobsDefault, err := SynthJobsFromEnv()
+	if err != nil {
+		Fail(WithExitCode(ExitConfig, err))
+	}
+	jobs := fs.Int("jobs", jobsDefault, "synthesize the sources CDKTF_SOURCE selects in up to this many processes at once, logging each to cdktf.out/logs/<source>.log")
+	_ = fs.Parse(args)
+	if *jobs < 1 {
+		Failf(ExitValidation, "--jobs must be at least 1, got %d", *jobs)
+	}
+
+	if *watch {
+		var synthArgs []string
+		fs.Visit(func(f *flag.Flag) {
+			if f.Name != "watch" {
+				synthArgs = append(synthArgs, "--"+f.Name+"="+f.Value.String())
+			}
+		})
+		if err := RunWatch(synthArgs); err != nil {
+			Fail(fmt.Errorf("watch: %w", err))
+		}
+		return
+	}
+
+	// --- Emit synth metrics when a statsd agent is configured ---
+	if metrics, err = MetricsFromEnv(); err != nil {
+		log.Printf("[metrics] WARNING: %v; not emitting metrics", err)
+	}
+	started := time.Now()
+
+	// --- Check the generated bindings before anything is built with them ---
+	if err := VerifyAwsBindings(); err != nil {
+		ExitSynthFailure(err)
+	}
+
+	cfg := LoadConfig(ConfigPath())
+
+	// --- Synthesize the sources of a CDKTF_SOURCE pattern in a pool of processes under --jobs ---
+	sourceID := os.Getenv("CDKTF_SOURCE")
+	if *jobs > 1 && IsSourcePattern(sourceID) && os.Getenv(SynthWorkerEnvVar) == "" {
+		if *accept {
+			Failf(ExitValidation, "--accept reads the state of a single stack; set CDKTF_SOURCE to one source")
+		}
+		if err := RunSynthPool(cfg, sourceID, *jobs, *placementsPath); err != nil {
+			Fail(err)
+		}
+		return
+	}
+
+	// --- Fall back to the next role_arns entry of peers whose first role cannot be assumed ---
+	if !*offline {
+		roleSettings := cfg.Provider
+		if *endpointURL != "" {
+			roleSettings.EndpointURL = *endpointURL
+		}
+		if err := SelectRoleArns(&cfg, STSAccountResolver{Settings: roleSettings}); err != nil {
+			Fail(err)
+		}
+	}
+
+	// --- Select the stacks: one for CDKTF_SOURCE ("" matches all sources), or one per source it matches ---
+	if IsSourcePattern(sourceID) && *accept {
+		Failf(ExitValidation, "--accept reads the state of a single stack; set CDKTF_SOURCE to one source")
+	}
+	targets, err := SelectTargets(cfg, sourceID)
+	if err != nil {
+		Fail(err)
+	}
+	if cfg.Lattice != nil && cfg.Lattice.Name != "" && len(targets) > 1 {
+		Failf(ExitValidation, "lattice.name creates the service network in a single stack; with several stacks, create it once and set lattice.service_network")
+	}
+
+	if *endpointURL != "" {
+		cfg.Provider.EndpointURL = *endpointURL
+	}
+	if err := cfg.Provider.Validate(); err != nil {
+		Failf(ExitValidation, "invalid provider settings: %v", err)
+	}
+	if err := cfg.Inventory.Validate(); err != nil {
+		Failf(ExitValidation, "invalid inventory settings: %v", err)
+	}
+	if err := cfg.RouteProvenance.Validate(); err != nil {
+		Failf(ExitValidation, "invalid route provenance settings: %v", err)
+	}
+	if err := cfg.Terraform.Validate(); err != nil {
+		Failf(ExitValidation, "invalid terraform settings: %v", err)
+	}
+	if err := cfg.Rollout.Validate(); err != nil {
+		Failf(ExitValidation, "invalid rollout settings: %v", err)
+	}
+	if err := cfg.OwnedPeerings.Validate(); err != nil {
+		Failf(ExitValidation, "invalid owned peering settings: %v", err)
+	}
+	if err := cfg.AccepterDetails.Validate(); err != nil {
+		Failf(ExitValidation, "invalid accepter details settings: %v", err)
+	}
+	if err := cfg.DNSPolicy.Validate(); err != nil {
+		Failf(ExitValidation, "invalid DNS resolution policy: %v", err)
+	}
+	if *offline {
+		if *verifyCidrs || *accept {
+			Failf(ExitValidation, "--offline excludes --verify-cidrs and --accept, which look up VPCs and requested peerings")
+		}
+		for _, t := range targets {
+			if err := CheckOffline(t.Peers); err != nil {
+				Fail(WithExitCode(ExitValidation, err))
+			}
+		}
+		if cfg.ConnectivityChecks {
+			log.Printf("[offline] Skipping connectivity checks, which read route tables and peerings")
+			cfg.ConnectivityChecks = false
+		}
+	}
+	for _, t := range targets {
+		if cfg.ResolveAccountIDs {
+			if err := ResolvePeerAccountIDs(t.Peers, STSAccountResolver{Settings: cfg.Provider}); err != nil {
+				Fail(err)
+			}
+		}
+		WarnUnresolvedAccounts(t.Peers)
+	}
+
+	// --- Partition and split the stacks, now that every connection's peer account is known ---
+	placed, err := PlacementsFor(cfg, *placementsPath)
+	if err != nil {
+		Fail(err)
+	}
+	if targets, err = PlaceTargets(cfg, targets, placed); err != nil {
+		Fail(err)
+	}
+	if *accept && len(targets) > 1 {
+		Failf(ExitValidation, "--accept reads the state of a single stack, but stack_partitioning or max_providers_per_stack splits it into %d", len(targets))
+	}
+	stackDependencies, err := StackDependencies(targets)
+	if err != nil {
+		Fail(WithExitCode(ExitValidation, err))
+	}
+	metrics.Gauge("config.peers", len(cfg.Peers), nil)
+	metrics.Gauge("synth.stacks", len(targets), nil)
+	for _, t := range targets {
+		metrics.Gauge("synth.connections", len(t.Peers), t.metricTags())
+	}
+
+	opts := StackOptionsFor(cfg)
+	opts.VerifyCidrs = *verifyCidrs
+	if path := os.Getenv("CDKTF_MOVED_FROM"); path != "" {
+		movedFrom, err := LoadAddressMap(path)
+		if err != nil {
+			Failf(ExitConfig, "failed to load address map: %v", err)
+		}
+		opts.MovedFrom = movedFrom
+	}
+	if path := os.Getenv("CDKTF_IMPORTS"); path != "" {
+		imports, err := LoadImports(path)
+		if err != nil {
+			Failf(ExitConfig, "failed to load imports: %v", err)
+		}
+		opts.Imports = imports
+	}
+	forgets, err := ForgetTargets(cfg, targets, opts.MovedFrom)
+	if err != nil {
+		Fail(err)
+	}
+
+	for _, t := range targets {
+		if len(targets) > 1 {
+			log.Printf("[stats] Stack %s:", t.Stack)
+		}
+		stats := ComputeStats(opts.Namer, t.Peers)
+		PrintStats(log.Writer(), stats)
+		diagnostics := append(StackBudget(stats), ProviderBudget(t.Stack, stats)...)
+		for _, d := range append(diagnostics, ResourceBudgets(t.Peers)...) {
+			log.Printf("[stats] %s: %s: %s", strings.ToUpper(d.Severity), d.Subject, d.Message)
+		}
+	}
+
+	var acceptPeers []PeerConfig
+	if *accept {
+		ids, err := RequestedPeeringIDs(opts.Namer, targets[0].Peers, *acceptState)
+		if err != nil {
+			Fail(err)
+		}
+		acceptPeers = AcceptStackPeers(targets[0].Peers, ids)
+	}
+
+	app := cdktf.NewApp(nil)
+	var stacks []string
+	err = RunSynth(app, func() {
+		built := make([]cdktf.TerraformStack, len(targets))
+		for i, t := range targets {
+			stackStarted := time.Now()
+			stackOpts := opts
+			stackOpts.LatticeVpcs = t.Lattice
+			stackOpts.Forget = forgets[i]
+			built[i] = NewMyStack(app, t.Stack, t.Source, t.Peers, stackOpts).Stack
+			stacks = append(stacks, t.Stack)
+			metrics.Timing("synth.stack_duration", time.Since(stackStarted), t.metricTags())
+		}
+		// Stacks with connections depending on another stack's are deployed after it.
+		for i, deps := range stackDependencies {
+			for _, j := range deps {
+				built[i].AddDependency(built[j])
+			}
+		}
+		if len(acceptPeers) > 0 {
+			acceptOpts := opts
+			acceptOpts.MovedFrom, acceptOpts.Imports, acceptOpts.Inventory, acceptOpts.Forget = nil, nil, InventoryConfig{}, nil
+			NewMyStack(app, AcceptStackName, sourceID, acceptPeers, acceptOpts)
+			stacks = append(stacks, AcceptStackName)
+		}
+	}, *debug)
+	if err != nil {
+		ExitSynthFailure(err)
+	}
+
+	// --- Record the resource addresses for verify-addresses ---
+	for i, t := range targets {
+		addressesPath := filepath.Join(stackOutDir(t.Stack), AddressesFile)
+		addresses := BuildAddressMap(opts.Namer, t.Peers)
+		// Forgotten connections stay recorded, so synthesizing again writes the same removed blocks.
+		for key, kinds := range forgets[i] {
+			addresses[key] = kinds
+		}
+		if err := WriteAddressMap(addressesPath, addresses); err != nil {
+			Failf(ExitSynth, "failed to write %s: %v", addressesPath, err)
+		}
+	}
+
+	// --- Record each connection's stack; a pooled synth leaves the file to the pool ---
+	if placed != nil {
+		placed.Record(targets)
+		path := *placementsPath
+		if os.Getenv(SynthWorkerEnvVar) != "" {
+			path = filepath.Join(synthOutdir(), PlacementsFile)
+		}
+		if err := WritePlacements(path, placed); err != nil {
+			Failf(ExitSynth, "failed to write %s: %v", path, err)
+		}
+	}
+
+	if *emitHCL {
+		for _, stack := range stacks {
+			path, err := EmitHCL(stack)
+			if err != nil {
+				Failf(ExitSynth, "failed to emit HCL: %v", err)
+			}
+			log.Printf("[hcl] Wrote %s", path)
+		}
+	}
+	metrics.Timing("synth.duration", time.Since(started), nil)
+	metrics.Count("synth.success", 1, nil)
+}
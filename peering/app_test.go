@@ -0,0 +1,381 @@
+package peering
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestGetAccountIDFromRoleArn tests extraction of account ID from various ARNs.
+func TestGetAccountIDFromRoleArn(t *testing.T) {
+	tests := []struct {
+		arn      string
+		expected string
+	}{
+		{"arn:aws:iam::123456789012:role/MyRole", "123456789012"},
+		{"arn:aws:iam::role/MyRole", ""},
+		{"", ""},
+		{"arn:aws:iam:123456789012", ""},
+		{"arn:aws-us-gov:iam::123456789012:role/MyRole", "123456789012"},
+	}
+	for _, tt := range tests {
+		got := GetAccountIDFromRoleArn(tt.arn)
+		if got != tt.expected {
+			t.Errorf("GetAccountIDFromRoleArn(%q) = %q, want %q", tt.arn, got, tt.expected)
+		}
+	}
+}
+
+// TestValidateRoleArn tests role ARN validation and the diagnostics for near misses.
+func TestValidateRoleArn(t *testing.T) {
+	tests := []struct {
+		arn, wantErr string
+	}{
+		{"arn:aws:iam::123456789012:role/peering", ""},
+		{"arn:aws-us-gov:iam::123456789012:role/peering", ""},
+		{"arn:aws:iam::123456789012:role/aws-reserved/sso.amazonaws.com/AWSReservedSSO_Admin_0123", ""},
+		{"arn:aws:iam::123456789012:user/alice", "IAM user ARN, not a role"},
+		{"arn:aws:iam::123456789012:instance-profile/web", "instance profile ARN; use the ARN of the role it contains"},
+		{"arn:aws:sts::123456789012:assumed-role/peering/session", "use arn:aws:iam::123456789012:role/peering"},
+		{"arn:aws:ec2:us-east-1:123456789012:vpc/vpc-1", "ec2 ARN, not an IAM role"},
+		{"arn:aws:iam::12345678:role/peering", "not 12 digits; is it truncated?"},
+		{"arn:aws:iam::123456789012:role/", `invalid role name ""`},
+		{"arn:aws:iam::1234567", "is not an ARN"},
+		{"arn:azure:iam::123456789012:role/peering", `unknown partition "azure"`},
+		{"arn:aws:iam:us-east-1:123456789012:role/peering", `sets region "us-east-1"`},
+	}
+	for _, tt := range tests {
+		err := ValidateRoleArn(tt.arn)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("ValidateRoleArn(%q) = %v, want nil", tt.arn, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("ValidateRoleArn(%q) = %v, want an error containing %q", tt.arn, err, tt.wantErr)
+		}
+	}
+}
+
+// TestValidatePeerRoleArns tests that the failing peer is named and peers without a role pass.
+func TestValidatePeerRoleArns(t *testing.T) {
+	cfg := YAMLConfig{Peers: map[string]YAMLPeer{
+		"ambient": {VpcID: "vpc-1"},
+		"dev":     {VpcID: "vpc-2", RoleArn: "arn:aws:iam::123456789012:user/ci"},
+		"prod":    {VpcID: "vpc-3", RoleArn: "arn:aws:iam::123456789012:role/peering"},
+	}}
+	err := ValidatePeerRoleArns(cfg)
+	if err == nil || !strings.HasPrefix(err.Error(), `peer "dev": role_arn "arn:aws:iam::123456789012:user/ci"`) {
+		t.Errorf("ValidatePeerRoleArns = %v, want an error naming peer dev", err)
+	}
+	delete(cfg.Peers, "dev")
+	if err := ValidatePeerRoleArns(cfg); err != nil {
+		t.Errorf("ValidatePeerRoleArns = %v, want nil", err)
+	}
+}
+
+// TestLoadConfig tests loading a valid YAML config.
+func TestLoadConfig(t *testing.T) {
+	yaml := `
+peers:
+  foo:
+    vpc_id: vpc-1
+    region: us-west-2
+    role_arn: arn:aws:iam::123456789012:role/x
+    dns_resolution: true
+    has_additional_routes: false
+peering_matrix:
+  foo: []
+`
+	tmp, err := os.CreateTemp("", "peering-*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write([]byte(yaml)); err != nil {
+		t.Fatal(err)
+	}
+	tmp.Close()
+
+	cfg := LoadConfig(tmp.Name())
+	if len(cfg.Peers) != 1 {
+		t.Errorf("expected 1 peer, got %d", len(cfg.Peers))
+	}
+	if _, ok := cfg.Peers["foo"]; !ok {
+		t.Errorf("expected peer 'foo' in config")
+	}
+}
+
+// TestConvertToPeerConfigs tests conversion from YAMLConfig to PeerConfig.
+func TestConvertToPeerConfigs(t *testing.T) {
+	cfg := YAMLConfig{
+		Peers: map[string]YAMLPeer{
+			"foo": {
+				VpcID:               "vpc-1",
+				Region:              "us-west-2",
+				RoleArn:             "arn:aws:iam::123:role/x",
+				DNSResolution:       true,
+				HasAdditionalRoutes: false,
+			},
+			"bar": {
+				VpcID:               "vpc-2",
+				Region:              "us-east-1",
+				RoleArn:             "arn:aws:iam::456:role/y",
+				DNSResolution:       false,
+				HasAdditionalRoutes: true,
+			},
+		},
+		PeeringMatrix: map[string][]MatrixEntry{
+			"foo": {{Peer: "bar"}},
+		},
+	}
+	peers := ConvertToPeerConfigs(cfg, "")
+	if len(peers) != 1 {
+		t.Fatalf("expected 1 peer config, got %d", len(peers))
+	}
+	pc := peers[0]
+	if pc.SourceVpcID != "vpc-1" || pc.PeerVpcID != "vpc-2" {
+		t.Errorf("unexpected VPC IDs: %q, %q", pc.SourceVpcID, pc.PeerVpcID)
+	}
+	if pc.EnableDNSResolution != false || pc.HasExtraPeerRouteTables != true {
+		t.Errorf("unexpected DNS or route table flags: %v, %v", pc.EnableDNSResolution, pc.HasExtraPeerRouteTables)
+	}
+}
+
+// TestRouteTargets tests CIDR-suffixed route IDs and the whole-VPC fallback.
+func TestRouteTargets(t *testing.T) {
+	ctx := NameContext{Index: 0, Source: "hub", Peer: "shared"}
+	fallback := "${data.aws_vpc.PeerVpcData0.cidr_block}"
+
+	targets := RouteTargets(LegacyNamer{}, ctx, KindSourceMainRoute, nil, nil, &fallback)
+	if len(targets) != 1 || targets[0].ID != "SourceToPeerMainRoute0" || *targets[0].Cidr != fallback {
+		t.Errorf("unexpected fallback targets: %+v", targets)
+	}
+
+	targets = RouteTargets(LegacyNamer{}, ctx, KindSourceMainRoute, []string{"10.2.0.0/24", "10.2.1.0/24"}, nil, &fallback)
+	if len(targets) != 2 || targets[1].ID != "SourceToPeerMainRoute0_10_2_1_0_24" || *targets[1].Cidr != "10.2.1.0/24" {
+		t.Errorf("unexpected CIDR targets: %+v", targets)
+	}
+
+	targets = RouteTargets(LegacyNamer{}, ctx, KindSourceMainRoute, nil, []string{"100.64.0.0/16"}, &fallback)
+	if len(targets) != 2 || targets[0].ID != "SourceToPeerMainRoute0" || targets[1].ID != "SourceToPeerMainRoute0_100_64_0_0_16" {
+		t.Errorf("unexpected extra targets: %+v", targets)
+	}
+}
+
+// TestConvertToPeerConfigsRouting tests legacy routing defaults and explicit per-side overrides.
+func TestConvertToPeerConfigsRouting(t *testing.T) {
+	manageRoutes := false
+	cfg := YAMLConfig{
+		Peers: map[string]YAMLPeer{
+			"foo": {VpcID: "vpc-1", Routes: &RoutingConfig{Strategy: RoutingAll}},
+			"bar": {VpcID: "vpc-2", HasAdditionalRoutes: true},
+			"baz": {VpcID: "vpc-3"},
+			"qux": {VpcID: "vpc-4", Routes: &RoutingConfig{Strategy: RoutingAll}},
+		},
+		PeeringMatrix: map[string][]MatrixEntry{
+			"foo": {
+				{Peer: "bar"},
+				{Peer: "baz", SourceRoutes: &RoutingConfig{Strategy: RoutingMain}},
+				{Peer: "qux", ManageRoutes: &manageRoutes},
+			},
+		},
+	}
+	peers := ConvertToPeerConfigs(cfg, "foo")
+	if len(peers) != 3 {
+		t.Fatalf("expected 3 peer configs, got %d", len(peers))
+	}
+	if peers[0].SourceRouting.Strategy != RoutingAll {
+		t.Errorf("expected peer-level routing for source, got %+v", peers[0].SourceRouting)
+	}
+	if peers[0].PeerRouting.Strategy != RoutingFiltered || peers[0].PeerRouting.SubnetTags["cdktf-peer-main-rt"] != "" {
+		t.Errorf("expected legacy filtered routing for peer, got %+v", peers[0].PeerRouting)
+	}
+	if peers[1].SourceRouting.Strategy != RoutingMain || peers[1].PeerRouting.Strategy != RoutingMain {
+		t.Errorf("expected entry override and main default, got %+v / %+v", peers[1].SourceRouting, peers[1].PeerRouting)
+	}
+	if peers[2].SourceRouting.Strategy != RoutingNone || peers[2].PeerRouting.Strategy != RoutingNone {
+		t.Errorf("expected no routing with manage_routes: false, got %+v / %+v", peers[2].SourceRouting, peers[2].PeerRouting)
+	}
+	if _, err := ResolveConnection(cfg, "foo", MatrixEntry{Peer: "qux", ManageRoutes: &manageRoutes, PeerRoutes: &RoutingConfig{Strategy: RoutingMain}}); err == nil {
+		t.Error("expected an error for manage_routes: false with peer_routes")
+	}
+}
+
+// TestResolveConnectionExternalPeering tests validation of route-only connections.
+func TestResolveConnectionExternalPeering(t *testing.T) {
+	cfg := YAMLConfig{Peers: map[string]YAMLPeer{"foo": {VpcID: "vpc-1"}, "bar": {VpcID: "vpc-2"}}}
+	no := false
+	tests := []struct {
+		entry MatrixEntry
+		valid bool
+	}{
+		{MatrixEntry{Peer: "bar", ManagePeering: &no, PeeringID: "pcx-0abc123"}, true},
+		{MatrixEntry{Peer: "bar", ManagePeering: &no, PeeringID: "pcx-0abc123", ManageOptions: true}, true},
+		{MatrixEntry{Peer: "bar", ManagePeering: &no}, false},
+		{MatrixEntry{Peer: "bar", ManagePeering: &no, PeeringID: "vpc-0abc123"}, false},
+		{MatrixEntry{Peer: "bar", ManagePeering: &no, PeeringID: "pcx-0abc123", ManageRoutes: &no}, false},
+		{MatrixEntry{Peer: "bar", PeeringID: "pcx-0abc123"}, false},
+		{MatrixEntry{Peer: "bar", ManageOptions: true}, false},
+	}
+	for _, tt := range tests {
+		peer, err := ResolveConnection(cfg, "foo", tt.entry)
+		if (err == nil) != tt.valid {
+			t.Errorf("ResolveConnection(%+v) error = %v, want valid=%v", tt.entry, err, tt.valid)
+			continue
+		}
+		if tt.valid && (peer.ExternalPeeringID != tt.entry.PeeringID || peer.ManageExternalOptions != tt.entry.ManageOptions) {
+			t.Errorf("unexpected external peering settings: %+v", peer)
+		}
+	}
+}
+
+// TestResolveConnectionExtraRoutes tests that extra routes are split per side and validated.
+func TestResolveConnectionExtraRoutes(t *testing.T) {
+	cfg := YAMLConfig{Peers: map[string]YAMLPeer{"foo": {VpcID: "vpc-1"}, "bar": {VpcID: "vpc-2"}}}
+	tests := []struct {
+		entry      MatrixEntry
+		source     []string
+		peer       []string
+		wantErrSub string
+	}{
+		{entry: MatrixEntry{Peer: "bar", ExtraRoutes: []ExtraRoute{{Cidr: "10.8.0.0/16"}, {Cidr: "100.64.0.0/16", Side: "peer"}}},
+			source: []string{"10.8.0.0/16"}, peer: []string{"100.64.0.0/16"}},
+		{entry: MatrixEntry{Peer: "bar", ExtraRoutes: []ExtraRoute{{Cidr: "10.8.0.0/16"}, {Cidr: "10.8.0.0/16", Side: "peer"}}},
+			source: []string{"10.8.0.0/16"}, peer: []string{"10.8.0.0/16"}},
+		{entry: MatrixEntry{Peer: "bar", ExtraRoutes: []ExtraRoute{{Cidr: "10.8.0.0"}}}, wantErrSub: "invalid extra route"},
+		{entry: MatrixEntry{Peer: "bar", ExtraRoutes: []ExtraRoute{{Cidr: "10.8.0.0/16", Side: "both"}}}, wantErrSub: "unknown extra route side"},
+		{entry: MatrixEntry{Peer: "bar", ExtraRoutes: []ExtraRoute{{Cidr: "10.8.0.0/16"}, {Cidr: "10.8.0.0/16", Side: "source"}}}, wantErrSub: "listed twice"},
+		{entry: MatrixEntry{Peer: "bar", DestinationCidrs: []string{"10.8.0.0/16"}, ExtraRoutes: []ExtraRoute{{Cidr: "10.8.0.0/16"}}}, wantErrSub: "both destination_cidrs"},
+	}
+	for _, tt := range tests {
+		peer, err := ResolveConnection(cfg, "foo", tt.entry)
+		if tt.wantErrSub != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErrSub) {
+				t.Errorf("ResolveConnection(%+v) error = %v, want %q", tt.entry, err, tt.wantErrSub)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ResolveConnection(%+v) unexpected error: %v", tt.entry, err)
+			continue
+		}
+		if !reflect.DeepEqual(peer.SourceExtraCidrs, tt.source) || !reflect.DeepEqual(peer.PeerExtraCidrs, tt.peer) {
+			t.Errorf("extra CIDRs = %v / %v, want %v / %v", peer.SourceExtraCidrs, peer.PeerExtraCidrs, tt.source, tt.peer)
+		}
+	}
+}
+
+// TestResolveConfigPath tests the explicit path and the search order of the config location.
+func TestResolveConfigPath(t *testing.T) {
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "missing", ConfigFileName)
+	found := filepath.Join(dir, ConfigFileName)
+	if err := os.WriteFile(found, []byte("peers: {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := ResolveConfigPath("explicit.yaml", []string{found}); err != nil || got != "explicit.yaml" {
+		t.Errorf("expected the explicit path, got %q, %v", got, err)
+	}
+	if got, err := ResolveConfigPath("", []string{missing, dir, found}); err != nil || got != found {
+		t.Errorf("expected the first existing file, got %q, %v", got, err)
+	}
+	if _, err := ResolveConfigPath("", []string{missing}); err == nil {
+		t.Error("expected an error when no candidate exists")
+	}
+}
+
+// TestExtractConfigFlag tests removing --config from the command line in every form.
+func TestExtractConfigFlag(t *testing.T) {
+	tests := []struct {
+		args []string
+		path string
+		rest []string
+	}{
+		{[]string{"--config", "a.yaml", "lint"}, "a.yaml", []string{"lint"}},
+		{[]string{"lint", "-config=b.yaml", "-format", "json"}, "b.yaml", []string{"lint", "-format", "json"}},
+		{[]string{"--endpoint-url", "http://localhost:4566"}, "", []string{"--endpoint-url", "http://localhost:4566"}},
+		{[]string{"lint", "--", "--config", "c.yaml"}, "", []string{"lint", "--", "--config", "c.yaml"}},
+	}
+	for _, tt := range tests {
+		path, rest, err := ExtractConfigFlag(tt.args)
+		if err != nil || path != tt.path || strings.Join(rest, " ") != strings.Join(tt.rest, " ") {
+			t.Errorf("ExtractConfigFlag(%v) = %q, %v, %v", tt.args, path, rest, err)
+		}
+	}
+	if _, _, err := ExtractConfigFlag([]string{"--config"}); err == nil {
+		t.Error("expected an error for --config without a path")
+	}
+	format, rest, err := ExtractGlobalFlag([]string{"lint", "--error-format=json", "-format", "json"}, "error-format")
+	if err != nil || format != "json" || strings.Join(rest, " ") != "lint -format json" {
+		t.Errorf("ExtractGlobalFlag = %q, %v, %v", format, rest, err)
+	}
+}
+
+// TestSidePartition tests partition detection from role ARNs and regions.
+func TestSidePartition(t *testing.T) {
+	tests := []struct {
+		roleArn, region, want string
+	}{
+		{"arn:aws:iam::111111111111:role/peering", "us-gov-west-1", PartitionAWS},
+		{"arn:aws-us-gov:iam::111111111111:role/peering", "", PartitionGovCloud},
+		{"arn:aws-cn:iam::111111111111:role/peering", "cn-north-1", PartitionChina},
+		{"", "cn-northwest-1", PartitionChina},
+		{"", "us-isob-east-1", PartitionISOB},
+		{"", "us-iso-east-1", PartitionISO},
+		{"", "", PartitionAWS},
+	}
+	for _, tt := range tests {
+		if got := SidePartition(tt.roleArn, tt.region); got != tt.want {
+			t.Errorf("SidePartition(%q, %q) = %q, want %q", tt.roleArn, tt.region, got, tt.want)
+		}
+	}
+}
+
+// TestConvertToPeerConfigsDisabled tests that disabled entries are left out of the connections but
+// kept, with their status, in the matrix rows.
+func TestConvertToPeerConfigsDisabled(t *testing.T) {
+	disabled := false
+	cfg := YAMLConfig{
+		Peers: map[string]YAMLPeer{
+			"foo": {VpcID: "vpc-1"},
+			"bar": {VpcID: "vpc-2"},
+			"baz": {VpcID: "vpc-3"},
+		},
+		PeeringMatrix: map[string][]MatrixEntry{
+			"foo": {{Peer: "bar"}, {Peer: "baz", Enabled: &disabled}},
+		},
+	}
+	peers := ConvertToPeerConfigs(cfg, "")
+	if len(peers) != 1 || peers[0].Name != "bar" {
+		t.Fatalf("expected only foo/bar, got %+v", peers)
+	}
+
+	rows := BuildMatrixRows(cfg)
+	if len(rows) != 2 || rows[0].status() != "ok" || rows[1].status() != "disabled" {
+		t.Errorf("unexpected rows: %+v", rows)
+	}
+	cfg.Peers["baz"] = YAMLPeer{VpcID: "vpc-1"}
+	if rows = BuildMatrixRows(cfg); rows[1].status() != "invalid" {
+		t.Errorf("expected disabled entries to be validated, got %+v", rows[1])
+	}
+}
+
+// TestConnectionSummary tests the configured part of a connection's peering summary entry.
+func TestConnectionSummary(t *testing.T) {
+	peer := PeerConfig{SourceName: "dev", Name: "prod", DestinationCidrs: []string{"10.1.0.0/24"},
+		SourceRouting: RoutingConfig{Strategy: RoutingAll}, PeerRouting: RoutingConfig{Strategy: RoutingNone},
+		ManualAcceptance: true, EnableDNSResolution: true}
+	got := ConnectionSummary(peer)
+	if got["key"] != "dev/prod" || got["source_routing"] != RoutingAll || got["peer_routing"] != RoutingNone {
+		t.Errorf("unexpected summary: %+v", got)
+	}
+	if cidrs := got["peer_extra_cidrs"].([]string); cidrs == nil || len(cidrs) != 0 {
+		t.Errorf("expected an empty list for unset CIDRs, got %#v", cidrs)
+	}
+	want := map[string]bool{"dns_resolution": true, "manual_acceptance": true, "external_peering": false, "shared_peering": false, "decommissioning": false, "inspected": false}
+	if !reflect.DeepEqual(got["flags"], want) {
+		t.Errorf("expected flags %v, got %v", want, got["flags"])
+	}
+}
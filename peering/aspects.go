@@ -0,0 +1,184 @@
+package peering
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Aspects
+// -------------------------------------------------------------------------------------------------
+
+// AspectsConfig enables the built-in aspects, which run over every resource of the stack after it
+// is built, including resources library consumers add themselves.
+type AspectsConfig struct {
+	EnforceTags   map[string]string   `yaml:"enforce_tags,omitempty"`   // Tags set on every taggable resource, over all other tags.
+	IgnoreChanges map[string][]string `yaml:"ignore_changes,omitempty"` // Resource type ("*" for all) to lifecycle ignore_changes attributes.
+	NamePrefix    string              `yaml:"name_prefix,omitempty"`    // Prefix added to every Name tag.
+}
+
+// Build returns the configured aspects in the order they apply: tags, ignore_changes, then the
+// Name prefix, so an enforced Name tag is prefixed as well. They are pointers, since jsii passes only
+// pointers to Go values across to the stack.
+func (c AspectsConfig) Build() []cdktf.IAspect {
+	var aspects []cdktf.IAspect
+	if len(c.EnforceTags) > 0 {
+		aspects = append(aspects, &EnforceTagsAspect{Tags: c.EnforceTags})
+	}
+	types := make([]string, 0, len(c.IgnoreChanges))
+	for resourceType := range c.IgnoreChanges {
+		types = append(types, resourceType)
+	}
+	sort.Strings(types)
+	for _, resourceType := range types {
+		aspects = append(aspects, &IgnoreChangesAspect{ResourceType: resourceType, Attributes: c.IgnoreChanges[resourceType]})
+	}
+	if c.NamePrefix != "" {
+		aspects = append(aspects, &NamePrefixAspect{Prefix: c.NamePrefix})
+	}
+	return aspects
+}
+
+// aspectResource is the part of a Terraform element the built-in aspects read and change.
+type aspectResource interface {
+	TerraformResourceType() *string
+	ToTerraform() interface{}
+	AddOverride(path *string, value interface{})
+}
+
+// taggableResource is implemented by the bindings of every resource type with a tags argument.
+type taggableResource interface {
+	SetTags(val *map[string]*string)
+}
+
+// taggableTypes lists the types with a tags argument among the resources the stack creates without
+// bindings, which have no SetTags to tell.
+var taggableTypes = map[string]bool{
+	"aws_iam_role":                                   true,
+	"aws_ram_resource_share":                         true,
+	"aws_route53profiles_association":                true,
+	"aws_route_table":                                true,
+	"aws_s3_object":                                  true,
+	"aws_ssm_parameter":                              true,
+	"aws_vpc_peering_connection_accepter":            true,
+	"aws_vpclattice_service_network":                 true,
+	"aws_vpclattice_service_network_vpc_association": true,
+}
+
+// resourceAttributes returns the synthesized attributes of a resource, overrides included, or nil
+// when the node is not a resource. Data sources synthesize under "data" and are left out.
+func resourceAttributes(node constructs.IConstruct) (aspectResource, map[string]interface{}) {
+	res, ok := node.(aspectResource)
+	if !ok {
+		return nil, nil
+	}
+	doc, _ := res.ToTerraform().(map[string]interface{})
+	byType, _ := doc["resource"].(map[string]interface{})
+	byID, _ := byType[*res.TerraformResourceType()].(map[string]interface{})
+	for _, attrs := range byID {
+		m, _ := attrs.(map[string]interface{})
+		if m == nil {
+			m = make(map[string]interface{})
+		}
+		return res, m
+	}
+	return nil, nil
+}
+
+// EnforceTagsAspect sets fixed tags on every resource whose type supports tags, whether or not it
+// has tags yet.
+type EnforceTagsAspect struct {
+	Tags map[string]string // Tags to set, overriding any existing value.
+}
+
+// Visit implements cdktf.IAspect.
+func (a EnforceTagsAspect) Visit(node constructs.IConstruct) {
+	res, attrs := resourceAttributes(node)
+	if res == nil {
+		return
+	}
+	_, tagged := attrs["tags"]
+	if _, taggable := node.(taggableResource); !tagged && !taggable && !taggableTypes[*res.TerraformResourceType()] {
+		return
+	}
+	for key, value := range a.Tags {
+		res.AddOverride(jsii.String("tags."+key), value)
+	}
+}
+
+// IgnoreChangesAspect injects lifecycle ignore_changes into resources of one type.
+type IgnoreChangesAspect struct {
+	ResourceType string   // Terraform resource type, or "*" for every resource.
+	Attributes   []string // Attributes Terraform should not reconcile, e.g. tags.
+}
+
+// Visit implements cdktf.IAspect. The attributes are added to the resource's ignore_changes, set by
+// an earlier aspect or a connection's lifecycle block, rather than replacing it.
+func (a IgnoreChangesAspect) Visit(node constructs.IConstruct) {
+	res, attrs := resourceAttributes(node)
+	if res == nil || (a.ResourceType != "*" && a.ResourceType != *res.TerraformResourceType()) {
+		return
+	}
+	lifecycle, _ := attrs["lifecycle"].(map[string]interface{})
+	res.AddOverride(jsii.String("lifecycle.ignore_changes"), mergeIgnoreChanges(lifecycle["ignore_changes"], a.Attributes))
+}
+
+// mergeIgnoreChanges returns the union of an ignore_changes value and further attributes, in order,
+// or the all keyword when either ignores every attribute.
+func mergeIgnoreChanges(existing interface{}, attributes []string) interface{} {
+	var merged []string
+	switch v := existing.(type) {
+	case string:
+		if v == LifecycleIgnoreAll {
+			return LifecycleIgnoreAll
+		}
+	case []string:
+		merged = append(merged, v...)
+	case []interface{}:
+		for _, attr := range v {
+			if s, ok := attr.(string); ok {
+				merged = append(merged, s)
+			}
+		}
+	}
+	for _, attr := range attributes {
+		if attr == LifecycleIgnoreAll {
+			return LifecycleIgnoreAll
+		}
+		found := false
+		for _, m := range merged {
+			found = found || m == attr
+		}
+		if !found {
+			merged = append(merged, attr)
+		}
+	}
+	return merged
+}
+
+// NamePrefixAspect prefixes the Name tag of every resource that has one.
+type NamePrefixAspect struct {
+	Prefix string // Prefix added unless the name already starts with it.
+}
+
+// Visit implements cdktf.IAspect.
+func (a NamePrefixAspect) Visit(node constructs.IConstruct) {
+	res, attrs := resourceAttributes(node)
+	tags, _ := attrs["tags"].(map[string]interface{})
+	name, ok := tags["Name"].(string)
+	if !ok || strings.HasPrefix(name, a.Prefix) {
+		return
+	}
+	res.AddOverride(jsii.String("tags.Name"), a.Prefix+name)
+}
+
+// AddAspects registers aspects on the stack in order.
+func AddAspects(stack cdktf.TerraformStack, aspects []cdktf.IAspect) {
+	for _, aspect := range aspects {
+		cdktf.Aspects_Of(stack).Add(aspect)
+	}
+}
@@ -0,0 +1,168 @@
+package peering
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// fakeResource is a resource the aspects can visit without jsii: it synthesizes its attributes and
+// applies overrides to them.
+type fakeResource struct {
+	resourceType string                 // Terraform resource type.
+	block        string                 // Top-level block it synthesizes under: resource or data.
+	attrs        map[string]interface{} // Attributes, overrides included.
+}
+
+func newFakeResource(resourceType string, attrs map[string]interface{}) *fakeResource {
+	if attrs == nil {
+		attrs = make(map[string]interface{})
+	}
+	return &fakeResource{resourceType: resourceType, block: "resource", attrs: attrs}
+}
+
+func (f *fakeResource) Node() constructs.Node          { return nil }
+func (f *fakeResource) TerraformResourceType() *string { return &f.resourceType }
+func (f *fakeResource) ToTerraform() interface{} {
+	return map[string]interface{}{f.block: map[string]interface{}{f.resourceType: map[string]interface{}{"r": f.attrs}}}
+}
+func (f *fakeResource) AddOverride(path *string, value interface{}) {
+	parts := strings.Split(*path, ".")
+	m := f.attrs
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := m[part].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			m[part] = next
+		}
+		m = next
+	}
+	m[parts[len(parts)-1]] = value
+}
+
+// fakeTaggableResource is a fakeResource whose type has a tags argument.
+type fakeTaggableResource struct{ *fakeResource }
+
+func (f fakeTaggableResource) SetTags(*map[string]*string) {}
+
+// visitAll applies every aspect to every node, in order, as synth does.
+func visitAll(aspects []cdktf.IAspect, nodes ...constructs.IConstruct) {
+	for _, aspect := range aspects {
+		for _, node := range nodes {
+			aspect.Visit(node)
+		}
+	}
+}
+
+// TestAspectsConfigBuild tests that configured aspects are built in their documented order.
+func TestAspectsConfigBuild(t *testing.T) {
+	cfg := AspectsConfig{
+		NamePrefix:    "acme-",
+		EnforceTags:   map[string]string{"CostCenter": "42"},
+		IgnoreChanges: map[string][]string{"aws_route": {"route_table_id"}, "*": {"tags"}},
+	}
+	want := []cdktf.IAspect{
+		&EnforceTagsAspect{Tags: map[string]string{"CostCenter": "42"}},
+		&IgnoreChangesAspect{ResourceType: "*", Attributes: []string{"tags"}},
+		&IgnoreChangesAspect{ResourceType: "aws_route", Attributes: []string{"route_table_id"}},
+		&NamePrefixAspect{Prefix: "acme-"},
+	}
+	if got := cfg.Build(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Build() = %#v, want %#v", got, want)
+	}
+	if got := (AspectsConfig{}).Build(); len(got) != 0 {
+		t.Errorf("expected no aspects, got %v", got)
+	}
+}
+
+// TestIgnoreChangesAspectVisit tests that ignore_changes aspects add to the list a resource already
+// has, from another aspect or a connection's lifecycle block, and that all wins.
+func TestIgnoreChangesAspectVisit(t *testing.T) {
+	route := newFakeResource("aws_route", map[string]interface{}{
+		"lifecycle": map[string]interface{}{"ignore_changes": []interface{}{"vpc_peering_connection_id"}},
+	})
+	peering := newFakeResource("aws_vpc_peering_connection", nil)
+	lookup := newFakeResource("aws_route_table", nil)
+	lookup.block = "data"
+
+	cfg := AspectsConfig{IgnoreChanges: map[string][]string{"*": {"tags"}, "aws_route": {"destination_cidr_block", "tags"}}}
+	visitAll(cfg.Build(), route, peering, lookup)
+
+	lifecycle := func(f *fakeResource) interface{} {
+		lc, _ := f.attrs["lifecycle"].(map[string]interface{})
+		return lc["ignore_changes"]
+	}
+	if got, want := lifecycle(route), []string{"vpc_peering_connection_id", "tags", "destination_cidr_block"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("route ignore_changes = %#v, want %#v", got, want)
+	}
+	if got, want := lifecycle(peering), []string{"tags"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("peering ignore_changes = %#v, want %#v", got, want)
+	}
+	if got := lifecycle(lookup); got != nil {
+		t.Errorf("expected data sources to be left alone, got %#v", got)
+	}
+
+	all := AspectsConfig{IgnoreChanges: map[string][]string{"*": {LifecycleIgnoreAll}, "aws_route": {"tags"}}}
+	visitAll(all.Build(), route)
+	if got := lifecycle(route); got != LifecycleIgnoreAll {
+		t.Errorf("expected all to win, got %#v", got)
+	}
+	ignored := newFakeResource("aws_route", map[string]interface{}{"lifecycle": map[string]interface{}{"ignore_changes": LifecycleIgnoreAll}})
+	visitAll(cfg.Build(), ignored)
+	if got := lifecycle(ignored); got != LifecycleIgnoreAll {
+		t.Errorf("expected an existing all to be kept, got %#v", got)
+	}
+}
+
+// TestEnforceTagsAspectVisit tests that enforced tags reach every resource whose type supports tags,
+// including those without tags yet or bindings, and that the Name prefix applies after them.
+func TestEnforceTagsAspectVisit(t *testing.T) {
+	tagged := newFakeResource("aws_vpc_peering_connection", map[string]interface{}{"tags": map[string]interface{}{"Name": "dev-prod"}})
+	untagged := fakeTaggableResource{newFakeResource("aws_route_table", nil)}
+	generic := newFakeResource("aws_ssm_parameter", nil)
+	route := newFakeResource("aws_route", nil)
+
+	cfg := AspectsConfig{EnforceTags: map[string]string{"CostCenter": "42"}, NamePrefix: "acme-"}
+	visitAll(cfg.Build(), tagged, untagged, generic, route)
+
+	if want := map[string]interface{}{"Name": "acme-dev-prod", "CostCenter": "42"}; !reflect.DeepEqual(tagged.attrs["tags"], want) {
+		t.Errorf("tagged resource tags = %v, want %v", tagged.attrs["tags"], want)
+	}
+	if want := map[string]interface{}{"CostCenter": "42"}; !reflect.DeepEqual(untagged.attrs["tags"], want) {
+		t.Errorf("taggable resource without tags = %v, want %v", untagged.attrs["tags"], want)
+	}
+	if want := map[string]interface{}{"CostCenter": "42"}; !reflect.DeepEqual(generic.attrs["tags"], want) {
+		t.Errorf("taggable resource without bindings = %v, want %v", generic.attrs["tags"], want)
+	}
+	if _, ok := route.attrs["tags"]; ok {
+		t.Errorf("expected no tags on a resource type without them, got %v", route.attrs["tags"])
+	}
+}
+
+// TestAddAspects tests that the built aspects can be registered on a stack and reach its resources
+// at synth.
+func TestAddAspects(t *testing.T) {
+	stack := cdktf.NewTerraformStack(cdktf.Testing_App(nil), jsii.String("peering"))
+	table := cdktf.NewTerraformResource(stack, jsii.String("Table"), &cdktf.TerraformResourceConfig{
+		TerraformResourceType: jsii.String("aws_route_table"),
+	})
+	table.AddOverride(jsii.String("tags"), map[string]string{"Name": "dev-prod"})
+	AddAspects(stack, AspectsConfig{EnforceTags: map[string]string{"CostCenter": "42"}, NamePrefix: "acme-"}.Build())
+
+	var synthesized struct {
+		Resource map[string]map[string]struct {
+			Tags map[string]string `json:"tags"`
+		} `json:"resource"`
+	}
+	if err := json.Unmarshal([]byte(*cdktf.Testing_Synth(stack, nil)), &synthesized); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := synthesized.Resource["aws_route_table"]["Table"].Tags, map[string]string{"Name": "acme-dev-prod", "CostCenter": "42"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tags = %v, want %v", got, want)
+	}
+}
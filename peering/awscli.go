@@ -0,0 +1,278 @@
+package peering
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// -------------------------------------------------------------------------------------------------
+// AWS CLI Access
+// -------------------------------------------------------------------------------------------------
+
+// AWSCLI runs aws CLI commands in one region, optionally with credentials of an assumed role. It
+// is used by commands that inspect live accounts; synth only calls AWS to resolve peer accounts
+// when resolve_account_ids is set.
+type AWSCLI struct {
+	Region      string   // Region passed to every call.
+	EndpointURL string   // Endpoint override for every call (e.g. LocalStack), empty for AWS.
+	Env         []string // Environment of the CLI process, including assumed-role credentials.
+}
+
+// lookPath finds executables on PATH; tests replace it.
+var lookPath = exec.LookPath
+
+// awsVersion returns what "aws --version" prints; tests replace it.
+var awsVersion = func() (string, error) {
+	out, err := exec.Command("aws", "--version").CombinedOutput()
+	return string(out), err
+}
+
+// minAWSCLIMajor is the oldest aws CLI major version the tool supports. Version 1 does not resolve
+// sso-session profiles and pages some list output differently, so its results are not trusted.
+const minAWSCLIMajor = 2
+
+var (
+	awsCLIMu sync.Mutex
+	awsCLIOK bool // Whether a CLI of a supported version was found, so the check runs once.
+)
+
+// CheckAWSCLI reports whether the aws CLI, which every live AWS call goes through, is on PATH and
+// at least version 2. The tool drives the CLI rather than linking the AWS SDK, so it is a runtime
+// dependency like terraform; commands check it when they create their runner, before any call.
+func CheckAWSCLI() error {
+	awsCLIMu.Lock()
+	defer awsCLIMu.Unlock()
+	if awsCLIOK {
+		return nil
+	}
+	if _, err := lookPath("aws"); err != nil {
+		return WithExitCode(ExitAWS, fmt.Errorf("the aws CLI (v2) is required for this command and was not found on PATH; "+
+			"install it from %s", awsCLIInstallURL))
+	}
+	out, err := awsVersion()
+	if err != nil {
+		return WithExitCode(ExitAWS, fmt.Errorf("failed to run aws --version: %w", err))
+	}
+	major, version, err := parseAWSCLIVersion(out)
+	if err != nil {
+		return WithExitCode(ExitAWS, err)
+	}
+	if major < minAWSCLIMajor {
+		return WithExitCode(ExitAWS, fmt.Errorf("the aws CLI on PATH is version %s, but version %d or later is required; "+
+			"install it from %s", version, minAWSCLIMajor, awsCLIInstallURL))
+	}
+	awsCLIOK = true
+	return nil
+}
+
+// awsCLIInstallURL is where the install hints point.
+const awsCLIInstallURL = "https://docs.aws.amazon.com/cli/latest/userguide/getting-started-install.html"
+
+// parseAWSCLIVersion reads the major version and full version from "aws --version" output, which
+// starts with "aws-cli/2.15.30 Python/3.11.8 ...".
+func parseAWSCLIVersion(out string) (int, string, error) {
+	fields := strings.Fields(out)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "aws-cli/") {
+		return 0, "", fmt.Errorf("unrecognized aws --version output %q", strings.TrimSpace(out))
+	}
+	version := strings.TrimPrefix(fields[0], "aws-cli/")
+	major, err := strconv.Atoi(strings.SplitN(version, ".", 2)[0])
+	if err != nil {
+		return 0, "", fmt.Errorf("unrecognized aws CLI version %q", version)
+	}
+	return major, version, nil
+}
+
+// NewAWSCLI returns a CLI runner for the region, assuming roleArn first when it is set. Provider
+// settings apply as they do to the stack's providers: an endpoint URL sends every call there with
+// static credentials, and assumed-role sessions carry the configured name and session tags.
+func NewAWSCLI(region, roleArn string, settings ProviderSettings) (*AWSCLI, error) {
+	if err := CheckAWSCLI(); err != nil {
+		return nil, err
+	}
+	cli := &AWSCLI{Region: ResolveRegion(region), EndpointURL: settings.EndpointURL, Env: os.Environ()}
+	if cli.EndpointURL != "" {
+		cli.Env = append(cli.Env, "AWS_ACCESS_KEY_ID="+localCredential, "AWS_SECRET_ACCESS_KEY="+localCredential)
+	}
+	if roleArn == "" {
+		return cli, nil
+	}
+
+	var out struct {
+		Credentials struct {
+			AccessKeyID     string `json:"AccessKeyId"`
+			SecretAccessKey string `json:"SecretAccessKey"`
+			SessionToken    string `json:"SessionToken"`
+		} `json:"Credentials"`
+	}
+	sessionName := settings.AssumeRole.SessionName
+	if sessionName == "" {
+		sessionName = "vpc-peering-tool"
+	}
+	args := []string{"sts", "assume-role", "--role-arn", roleArn, "--role-session-name", sessionName}
+	tags := settings.AssumeRole.SessionTags()
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if len(keys) > 0 {
+		args = append(args, "--tags")
+	}
+	for _, key := range keys {
+		args = append(args, fmt.Sprintf("Key=%s,Value=%s", key, tags[key]))
+	}
+	if len(settings.AssumeRole.TransitiveTagKeys) > 0 {
+		args = append(args, "--transitive-tag-keys")
+		args = append(args, settings.AssumeRole.TransitiveTagKeys...)
+	}
+	if err := cli.Run(&out, args...); err != nil {
+		return nil, fmt.Errorf("failed to assume %s: %w", roleArn, err)
+	}
+	cli.Env = append(cli.Env,
+		"AWS_ACCESS_KEY_ID="+out.Credentials.AccessKeyID,
+		"AWS_SECRET_ACCESS_KEY="+out.Credentials.SecretAccessKey,
+		"AWS_SESSION_TOKEN="+out.Credentials.SessionToken,
+	)
+	return cli, nil
+}
+
+// Run executes "aws <args>" with JSON output and decodes the result into out. Commands without
+// output, such as ec2 delete-route, pass a nil out.
+func (c *AWSCLI) Run(out interface{}, args ...string) error {
+	data, err := c.Output(args...)
+	if err != nil || out == nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// Output executes "aws <args>" and returns what it printed, for commands whose output is not an API
+// response, such as s3 cp to stdout.
+func (c *AWSCLI) Output(args ...string) ([]byte, error) {
+	if err := CheckAWSCLI(); err != nil {
+		return nil, err
+	}
+	args = append(args, "--region", c.Region, "--output", "json")
+	if c.EndpointURL != "" {
+		args = append(args, "--endpoint-url", c.EndpointURL)
+	}
+	cmd := exec.Command("aws", args...)
+	cmd.Env = c.Env
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	data, err := cmd.Output()
+	if err != nil {
+		return nil, &AWSAPIError{Command: strings.Join(args[:2], " "), Err: err, Stderr: strings.TrimSpace(stderr.String())}
+	}
+	return data, nil
+}
+
+// -------------------------------------------------------------------------------------------------
+// VPC Network Lookup
+// -------------------------------------------------------------------------------------------------
+
+// RouteTable is a live route table with its associations and route destinations.
+type RouteTable struct {
+	ID           string   // Route table ID.
+	Main         bool     // Whether this is the VPC's main route table.
+	SubnetIDs    []string // Explicitly associated subnets.
+	Destinations []string // IPv4 destination CIDRs of existing routes.
+}
+
+// VpcNetworkLookup reads live network details of a VPC. Implemented by the AWS CLI and by fakes in
+// tests.
+type VpcNetworkLookup interface {
+	VpcCidr(vpcID string) (string, error)
+	RouteTables(vpcID string) ([]RouteTable, error)
+	Subnets(vpcID string, tags map[string]string) ([]string, error)
+}
+
+// VpcCidr returns the primary IPv4 CIDR of a VPC.
+func (c *AWSCLI) VpcCidr(vpcID string) (string, error) {
+	var out struct {
+		Vpcs []struct {
+			CidrBlock string `json:"CidrBlock"`
+		} `json:"Vpcs"`
+	}
+	if err := c.Run(&out, "ec2", "describe-vpcs", "--vpc-ids", vpcID); err != nil {
+		return "", err
+	}
+	if len(out.Vpcs) == 0 {
+		return "", fmt.Errorf("vpc %s not found in %s", vpcID, c.Region)
+	}
+	return out.Vpcs[0].CidrBlock, nil
+}
+
+// RouteTables returns every route table of a VPC.
+func (c *AWSCLI) RouteTables(vpcID string) ([]RouteTable, error) {
+	var out struct {
+		RouteTables []struct {
+			RouteTableID string `json:"RouteTableId"`
+			Associations []struct {
+				Main     bool   `json:"Main"`
+				SubnetID string `json:"SubnetId"`
+			} `json:"Associations"`
+			Routes []struct {
+				DestinationCidrBlock string `json:"DestinationCidrBlock"`
+			} `json:"Routes"`
+		} `json:"RouteTables"`
+	}
+	if err := c.Run(&out, "ec2", "describe-route-tables", "--filters", "Name=vpc-id,Values="+vpcID); err != nil {
+		return nil, err
+	}
+
+	tables := make([]RouteTable, 0, len(out.RouteTables))
+	for _, rt := range out.RouteTables {
+		table := RouteTable{ID: rt.RouteTableID}
+		for _, a := range rt.Associations {
+			table.Main = table.Main || a.Main
+			if a.SubnetID != "" {
+				table.SubnetIDs = append(table.SubnetIDs, a.SubnetID)
+			}
+		}
+		for _, r := range rt.Routes {
+			if r.DestinationCidrBlock != "" {
+				table.Destinations = append(table.Destinations, r.DestinationCidrBlock)
+			}
+		}
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+// Subnets returns the IDs of the subnets of a VPC carrying all given tags, using the same filters
+// as the stack's subnet data sources.
+func (c *AWSCLI) Subnets(vpcID string, tags map[string]string) ([]string, error) {
+	args := []string{"ec2", "describe-subnets", "--filters", "Name=vpc-id,Values=" + vpcID}
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, fmt.Sprintf("Name=tag:%s,Values=%s", key, tags[key]))
+	}
+
+	var out struct {
+		Subnets []struct {
+			SubnetID string `json:"SubnetId"`
+		} `json:"Subnets"`
+	}
+	if err := c.Run(&out, args...); err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(out.Subnets))
+	for _, s := range out.Subnets {
+		ids = append(ids, s.SubnetID)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
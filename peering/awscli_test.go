@@ -0,0 +1,79 @@
+package peering
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// TestCheckAWSCLI tests that a missing aws CLI fails calls with an install hint before running them.
+func TestCheckAWSCLI(t *testing.T) {
+	defer func(saved func(string) (string, error)) { lookPath = saved }(lookPath)
+	lookPath = func(string) (string, error) { return "", exec.ErrNotFound }
+
+	_, err := (&AWSCLI{Region: "us-east-1"}).Output("ec2", "describe-vpcs")
+	if err == nil || !strings.Contains(err.Error(), "aws CLI (v2) is required") {
+		t.Fatalf("expected the missing CLI to be reported, got %v", err)
+	}
+	if code := ExitCodeOf(err); code != ExitAWS {
+		t.Errorf("expected exit code %d, got %d", ExitAWS, code)
+	}
+	if _, err := NewAWSCLI("us-east-1", "arn:aws:iam::111111111111:role/peering", ProviderSettings{}); err == nil {
+		t.Error("expected NewAWSCLI to report the missing CLI")
+	}
+
+	defer func(saved func() (string, error)) { awsVersion = saved }(awsVersion)
+	defer func() { awsCLIOK = false }()
+	lookPath = func(string) (string, error) { return "/usr/local/bin/aws", nil }
+	awsVersion = func() (string, error) { return "aws-cli/2.15.30 Python/3.11.8", nil }
+	if err := CheckAWSCLI(); err != nil {
+		t.Errorf("expected the CLI to be found, got %v", err)
+	}
+}
+
+// TestCheckAWSCLIVersion tests that an aws CLI older than v2, or one whose version cannot be read,
+// is rejected, and that a supported CLI is only checked once.
+func TestCheckAWSCLIVersion(t *testing.T) {
+	defer func(saved func(string) (string, error)) { lookPath = saved }(lookPath)
+	defer func(saved func() (string, error)) { awsVersion = saved }(awsVersion)
+	defer func() { awsCLIOK = false }()
+	lookPath = func(string) (string, error) { return "/usr/local/bin/aws", nil }
+
+	tests := []struct {
+		out     string
+		wantErr string
+	}{
+		{"aws-cli/2.15.30 Python/3.11.8 Linux/6.5.0 exe/x86_64.ubuntu.22\n", ""},
+		{"aws-cli/1.32.0 Python/3.11.8 Linux/6.5.0 botocore/1.34.0\n", "version 1.32.0, but version 2 or later is required"},
+		{"command not found\n", "unrecognized aws --version output"},
+		{"aws-cli/dev Python/3.11.8\n", "unrecognized aws CLI version"},
+	}
+	for _, tt := range tests {
+		awsCLIOK = false
+		awsVersion = func() (string, error) { return tt.out, nil }
+		err := CheckAWSCLI()
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%q: unexpected error %v", tt.out, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.out, tt.wantErr, err)
+		} else if code := ExitCodeOf(err); code != ExitAWS {
+			t.Errorf("%q: expected exit code %d, got %d", tt.out, ExitAWS, code)
+		}
+	}
+
+	awsCLIOK = false
+	calls := 0
+	awsVersion = func() (string, error) { calls++; return "aws-cli/2.0.0 Python/3.7.3", nil }
+	for i := 0; i < 3; i++ {
+		if err := CheckAWSCLI(); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected the version to be read once, got %d calls", calls)
+	}
+}
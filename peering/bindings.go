@@ -0,0 +1,116 @@
+package peering
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	awsprovider "cdk.tf/go/stack/generated/hashicorp/aws/provider"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Provider Binding Compatibility
+// -------------------------------------------------------------------------------------------------
+
+// Range of AWS provider versions the generated bindings may come from: from MinAwsBindingsVersion
+// up to, but not including, MaxAwsBindingsVersion. Bindings outside it lack or rename attributes
+// the stack sets, which jsii only reports mid-synth as an opaque runtime error. Keep it in step
+// with terraformProviders in cdktf.json.
+const (
+	MinAwsBindingsVersion = "5.0.0"
+	MaxAwsBindingsVersion = "6.0.0"
+)
+
+// SkipBindingsCheckEnvVar skips the binding version check, for trying out bindings outside the
+// supported range.
+const SkipBindingsCheckEnvVar = "CDKTF_SKIP_BINDINGS_CHECK"
+
+// parseVersion parses a version such as "5.42.0", "v5.42", or "5.42.0-beta1" into its major, minor,
+// and patch numbers; missing parts are 0 and a pre-release suffix is ignored.
+func parseVersion(v string) ([3]int, error) {
+	var parts [3]int
+	core := strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(core, "-+"); i >= 0 {
+		core = core[:i]
+	}
+	fields := strings.Split(core, ".")
+	if core == "" || len(fields) > 3 {
+		return parts, fmt.Errorf("invalid version %q", v)
+	}
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 {
+			return parts, fmt.Errorf("invalid version %q", v)
+		}
+		parts[i] = n
+	}
+	return parts, nil
+}
+
+// compareVersions returns -1, 0, or 1 as a is lower than, equal to, or higher than b.
+func compareVersions(a, b [3]int) int {
+	for i := range a {
+		switch {
+		case a[i] < b[i]:
+			return -1
+		case a[i] > b[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+// CheckBindingsVersion returns a SynthFailure, with instructions to regenerate the bindings, when
+// the AWS provider version they were generated from is outside the supported range.
+func CheckBindingsVersion(version string) error {
+	supported := fmt.Sprintf(">= %s, < %s", MinAwsBindingsVersion, MaxAwsBindingsVersion)
+	hint := fmt.Sprintf("set \"hashicorp/aws@%s\" in terraformProviders of cdktf.json, rerun make get, then go mod tidy "+
+		"(or set %s=1 to synthesize anyway)", supported, SkipBindingsCheckEnvVar)
+	v, err := parseVersion(version)
+	if err != nil {
+		return &SynthFailure{Kind: "provider bindings unrecognized", Hint: hint,
+			Err: fmt.Errorf("cannot read the AWS provider version of the generated bindings: %w", err)}
+	}
+	low, _ := parseVersion(MinAwsBindingsVersion)
+	high, _ := parseVersion(MaxAwsBindingsVersion)
+	if compareVersions(v, low) < 0 || compareVersions(v, high) >= 0 {
+		return &SynthFailure{Kind: "provider bindings unsupported", Hint: hint,
+			Err: fmt.Errorf("the AWS provider bindings were generated from version %s, outside the supported range %s", version, supported)}
+	}
+	return nil
+}
+
+// AwsBindingsVersion returns the AWS provider version the generated bindings come from, read from
+// the generator metadata of a provider built in a throwaway app that is never synthesized. A jsii
+// failure, such as missing bindings, is returned as a classified SynthFailure.
+func AwsBindingsVersion() (version string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoveredFailure(r)
+		}
+	}()
+	app := cdktf.NewApp(nil)
+	stack := cdktf.NewTerraformStack(app, jsii.String("bindings-check"))
+	provider := awsprovider.NewAwsProvider(stack, jsii.String("aws"), &awsprovider.AwsProviderConfig{Region: jsii.String(DefaultRegion)})
+	metadata := provider.TerraformGeneratorMetadata()
+	if metadata == nil || metadata.ProviderVersion == nil {
+		return "", nil
+	}
+	return *metadata.ProviderVersion, nil
+}
+
+// VerifyAwsBindings checks, before any stack is built, that the generated AWS provider bindings
+// are from a supported provider version, unless SkipBindingsCheckEnvVar is set.
+func VerifyAwsBindings() error {
+	if os.Getenv(SkipBindingsCheckEnvVar) != "" {
+		return nil
+	}
+	version, err := AwsBindingsVersion()
+	if err != nil {
+		return err
+	}
+	return CheckBindingsVersion(version)
+}
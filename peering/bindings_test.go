@@ -0,0 +1,62 @@
+package peering
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestParseVersion tests version parsing, including short and pre-release versions.
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		in   string
+		want [3]int
+		ok   bool
+	}{
+		{"5.42.0", [3]int{5, 42, 0}, true},
+		{"v5.42", [3]int{5, 42, 0}, true},
+		{"6.0.0-beta1", [3]int{6, 0, 0}, true},
+		{"", [3]int{}, false},
+		{"5.x", [3]int{}, false},
+		{"5.1.2.3", [3]int{}, false},
+	}
+	for _, tt := range tests {
+		got, err := parseVersion(tt.in)
+		if (err == nil) != tt.ok || (tt.ok && got != tt.want) {
+			t.Errorf("parseVersion(%q) = %v, %v; want %v, ok=%v", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+// TestCheckBindingsVersion tests the supported range bounds and the failure reported outside it.
+func TestCheckBindingsVersion(t *testing.T) {
+	tests := []struct {
+		version  string
+		wantKind string
+	}{
+		{"5.0.0", ""},
+		{"5.42.0", ""},
+		{"5.99.1", ""},
+		{"4.67.0", "provider bindings unsupported"},
+		{"6.0.0", "provider bindings unsupported"},
+		{"6.0.0-beta1", "provider bindings unsupported"},
+		{"", "provider bindings unrecognized"},
+	}
+	for _, tt := range tests {
+		err := CheckBindingsVersion(tt.version)
+		if tt.wantKind == "" {
+			if err != nil {
+				t.Errorf("CheckBindingsVersion(%q) = %v, want nil", tt.version, err)
+			}
+			continue
+		}
+		var failure *SynthFailure
+		if !errors.As(err, &failure) || failure.Kind != tt.wantKind {
+			t.Errorf("CheckBindingsVersion(%q) = %v, want kind %q", tt.version, err, tt.wantKind)
+			continue
+		}
+		if !strings.Contains(failure.Hint, "make get") || !strings.Contains(failure.Hint, SkipBindingsCheckEnvVar) {
+			t.Errorf("CheckBindingsVersion(%q) hint %q lacks upgrade instructions", tt.version, failure.Hint)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package peering
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+
+	awsprovider "cdk.tf/go/stack/generated/hashicorp/aws/provider"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Role Bootstrap Stacks
+// -------------------------------------------------------------------------------------------------
+
+// BootstrapOutdir is the default output directory of the bootstrap stacks, kept apart from the
+// peering stack so the two are never applied together.
+const BootstrapOutdir = "cdktf.out.bootstrap"
+
+// BootstrapOptions controls who may assume the bootstrapped roles.
+type BootstrapOptions struct {
+	TrustedPrincipals []string      // IAM principal ARNs (or account IDs) allowed to assume the roles.
+	ExternalID        string        // Required sts:ExternalId, if any.
+	TagSession        bool          // Allow sts:TagSession, needed when session tags are configured.
+	Policy            PolicyOptions // Optional features granted in the inline policies.
+}
+
+// BootstrapStackName returns the stack ID of an account's bootstrap stack.
+func BootstrapStackName(account string) string {
+	return "bootstrap-" + account
+}
+
+// BootstrapAccounts groups the policies of every assumed role by the account that owns the role.
+func BootstrapAccounts(peers []PeerConfig, opts PolicyOptions) map[string]map[string]PolicyDocument {
+	accounts := make(map[string]map[string]PolicyDocument)
+	for roleArn, policy := range RolePolicies(peers, opts) {
+		account := GetAccountIDFromRoleArn(roleArn)
+		if account == "" {
+			Failf(ExitValidation, "[bootstrap] cannot derive the account of role %q", roleArn)
+		}
+		if accounts[account] == nil {
+			accounts[account] = make(map[string]PolicyDocument)
+		}
+		accounts[account][roleArn] = policy
+	}
+	return accounts
+}
+
+// TrustPolicy returns the assume-role policy of a bootstrapped role.
+func TrustPolicy(opts BootstrapOptions) PolicyDocument {
+	actions := []string{"sts:AssumeRole"}
+	if opts.TagSession {
+		actions = append(actions, "sts:TagSession")
+	}
+	statement := PolicyStatement{
+		Sid:       "AssumedByPeeringTool",
+		Effect:    "Allow",
+		Principal: map[string][]string{"AWS": opts.TrustedPrincipals},
+		Action:    actions,
+	}
+	if opts.ExternalID != "" {
+		statement.Condition = map[string]map[string][]string{"StringEquals": {"sts:ExternalId": {opts.ExternalID}}}
+	}
+	return PolicyDocument{Version: "2012-10-17", Statement: []PolicyStatement{statement}}
+}
+
+/*
+NewBootstrapStack constructs the companion stack of one account, creating each role the peering
+stack assumes there with its least-privilege inline policy.
+
+Parameters:
+
+	scope     - The CDKTF construct scope.
+	account   - The AWS account the roles live in.
+	roles     - Policy documents keyed by role ARN.
+	opts      - Trust settings of the roles.
+	settings  - Provider settings (endpoints, proxy, retries).
+
+Returns:
+
+	cdktf.TerraformStack with one role, one inline policy, and one ARN output per role.
+*/
+func NewBootstrapStack(scope constructs.Construct, account string, roles map[string]PolicyDocument, opts BootstrapOptions, settings ProviderSettings) cdktf.TerraformStack {
+	stack := cdktf.NewTerraformStack(scope, jsii.String(BootstrapStackName(account)))
+
+	cfg := &awsprovider.AwsProviderConfig{
+		Region:            jsii.String(DefaultRegion),
+		AllowedAccountIds: &[]*string{jsii.String(account)},
+	}
+	settings.Apply(cfg)
+	awsprovider.NewAwsProvider(stack, jsii.String("aws"), cfg)
+
+	trust := mustJSON(TrustPolicy(opts))
+
+	arns := make([]string, 0, len(roles))
+	for roleArn := range roles {
+		arns = append(arns, roleArn)
+	}
+	sort.Strings(arns)
+
+	for _, roleArn := range arns {
+		name, path := RoleNameAndPath(roleArn)
+		id := strings.NewReplacer(".", "_", "@", "_", "+", "_", "=", "_", ",", "_").Replace(name)
+
+		role := cdktf.NewTerraformResource(stack, jsii.String(id+"_role"), &cdktf.TerraformResourceConfig{
+			TerraformResourceType: jsii.String("aws_iam_role"),
+		})
+		role.AddOverride(jsii.String("name"), name)
+		role.AddOverride(jsii.String("path"), path)
+		role.AddOverride(jsii.String("description"), "Assumed by the VPC peering tool")
+		role.AddOverride(jsii.String("assume_role_policy"), trust)
+		role.AddOverride(jsii.String("tags"), map[string]string{"ManagedBy": "cdktf"})
+
+		policy := cdktf.NewTerraformResource(stack, jsii.String(id+"_policy"), &cdktf.TerraformResourceConfig{
+			TerraformResourceType: jsii.String("aws_iam_role_policy"),
+		})
+		policy.AddOverride(jsii.String("name"), "vpc-peering")
+		policy.AddOverride(jsii.String("role"), role.GetStringAttribute(jsii.String("name")))
+		policy.AddOverride(jsii.String("policy"), mustJSON(roles[roleArn]))
+
+		cdktf.NewTerraformOutput(stack, jsii.String(id+"_arn"), &cdktf.TerraformOutputConfig{
+			Value: role.GetStringAttribute(jsii.String("arn")),
+		})
+	}
+	return stack
+}
+
+// mustJSON encodes a policy document. Policy documents always encode.
+func mustJSON(doc PolicyDocument) string {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		Failf(ExitSynth, "failed to encode policy: %v", err)
+	}
+	return string(data)
+}
+
+// -------------------------------------------------------------------------------------------------
+// bootstrap
+// -------------------------------------------------------------------------------------------------
+
+// runBootstrap synthesizes one bootstrap stack per account into its own output directory. Each stack
+// is applied with credentials of its account before the peering stack is first deployed.
+func runBootstrap(args []string) error {
+	fs := flag.NewFlagSet("bootstrap", flag.ContinueOnError)
+	trust := fs.String("trust", "", "comma-separated principal ARNs or account IDs allowed to assume the roles (required)")
+	externalID := fs.String("external-id", "", "require this sts:ExternalId when assuming the roles")
+	outdir := fs.String("o", BootstrapOutdir, "output directory of the synthesized stacks")
+	flowLogs := fs.Bool("flow-logs", false, "also grant managing VPC flow logs")
+	route53 := fs.Bool("route53", false, "also grant private hosted zone associations")
+	quotas := fs.Bool("quotas", false, "also grant reading the VPC quotas quota-check compares")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *trust == "" {
+		return fmt.Errorf("-trust is required")
+	}
+
+	cfg, peers := loadSourcePeers(sourceArg(fs))
+	if err := cfg.Provider.Validate(); err != nil {
+		return fmt.Errorf("invalid provider settings: %w", err)
+	}
+	if err := cfg.Terraform.Validate(); err != nil {
+		return fmt.Errorf("invalid terraform settings: %w", err)
+	}
+
+	opts := BootstrapOptions{
+		ExternalID: *externalID,
+		TagSession: len(cfg.Provider.AssumeRole.Tags) > 0,
+		Policy:     PolicyOptions{FlowLogs: *flowLogs, Route53: *route53, Quotas: *quotas, Provenance: cfg.RouteProvenance, OwnedPeerings: cfg.OwnedPeerings, AccepterDetails: cfg.AccepterDetails},
+	}
+	for _, principal := range strings.Split(*trust, ",") {
+		if principal = strings.TrimSpace(principal); principal != "" {
+			opts.TrustedPrincipals = append(opts.TrustedPrincipals, principal)
+		}
+	}
+
+	accounts := BootstrapAccounts(peers, opts.Policy)
+	ids := make([]string, 0, len(accounts))
+	for account := range accounts {
+		ids = append(ids, account)
+	}
+	sort.Strings(ids)
+
+	app := cdktf.NewApp(&cdktf.AppConfig{Outdir: jsii.String(*outdir)})
+	for _, account := range ids {
+		cfg.Terraform.Apply(NewBootstrapStack(app, account, accounts[account], opts, cfg.Provider))
+	}
+	app.Synth()
+
+	for _, account := range ids {
+		fmt.Printf("%s/stacks/%s (%d roles)\n", *outdir, BootstrapStackName(account), len(accounts[account]))
+	}
+	return nil
+}
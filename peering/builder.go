@@ -0,0 +1,141 @@
+package peering
+
+import (
+	"fmt"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Programmatic Configuration
+// -------------------------------------------------------------------------------------------------
+
+// FromYAML parses a config document the way LoadConfig parses peering.yaml, for callers that hold
+// the config in memory: peer_defaults are applied, older schema versions migrated, and regions and
+// role ARNs checked. Nothing is read from disk or AWS, so secret references, peer manifests,
+// discovery, and IPAM are left unresolved.
+func FromYAML(data []byte) (YAMLConfig, error) {
+	cfg, err := parseConfig(data, "config")
+	if err != nil {
+		return YAMLConfig{}, err
+	}
+	if err := ApplyRoleArns(&cfg); err != nil {
+		return YAMLConfig{}, WithExitCode(ExitValidation, fmt.Errorf("invalid role ARNs: %w", err))
+	}
+	if err := ValidatePeerRoleArns(cfg); err != nil {
+		return YAMLConfig{}, WithExitCode(ExitValidation, fmt.Errorf("invalid role ARNs: %w", err))
+	}
+	if err := ValidateThirdPartyPeers(cfg); err != nil {
+		return YAMLConfig{}, WithExitCode(ExitValidation, fmt.Errorf("invalid account IDs: %w", err))
+	}
+	return cfg, nil
+}
+
+// ConnectionBuilder builds one connection in code rather than as a peering_matrix entry, for teams
+// generating connections from their own inventory:
+//
+//	peer, err := NewConnection("dev-peer", "prod-peer").
+//		WithDNS().
+//		WithExtraRoutes(ExtraRoute{Cidr: "100.64.0.0/16"}).
+//		Build(cfg)
+//
+// Each With method returns the builder; the connection is validated by Build or AddTo.
+type ConnectionBuilder struct {
+	source string      // Source peer, which requests the peering.
+	entry  MatrixEntry // The connection as a matrix entry of the source.
+	dns    bool        // DNS resolution across the peering, whatever the peer sets.
+}
+
+// NewConnection starts a connection from source to peer with default settings.
+func NewConnection(source, peer string) *ConnectionBuilder {
+	return &ConnectionBuilder{source: source, entry: MatrixEntry{Peer: peer}}
+}
+
+// WithDNS enables DNS resolution across the peering.
+func (b *ConnectionBuilder) WithDNS() *ConnectionBuilder {
+	b.dns = true
+	return b
+}
+
+// WithDestinationCidrs routes these peer-side CIDRs instead of the whole peer VPC.
+func (b *ConnectionBuilder) WithDestinationCidrs(cidrs ...string) *ConnectionBuilder {
+	b.entry.DestinationCidrs = append(b.entry.DestinationCidrs, cidrs...)
+	return b
+}
+
+// WithExtraRoutes routes destinations beyond the VPC CIDRs through the peering in addition.
+func (b *ConnectionBuilder) WithExtraRoutes(routes ...ExtraRoute) *ConnectionBuilder {
+	b.entry.ExtraRoutes = append(b.entry.ExtraRoutes, routes...)
+	return b
+}
+
+// WithSourceRoutes sets the route management of the source VPC.
+func (b *ConnectionBuilder) WithSourceRoutes(routing RoutingConfig) *ConnectionBuilder {
+	b.entry.SourceRoutes = &routing
+	return b
+}
+
+// WithPeerRoutes sets the route management of the peer VPC.
+func (b *ConnectionBuilder) WithPeerRoutes(routing RoutingConfig) *ConnectionBuilder {
+	b.entry.PeerRoutes = &routing
+	return b
+}
+
+// WithTags adds tags to both sides of the peering, over the config-level tags.
+func (b *ConnectionBuilder) WithTags(tags map[string]string) *ConnectionBuilder {
+	b.entry.Tags = mergeTags(tags, b.entry.Tags)
+	return b
+}
+
+// WithManualAcceptance leaves the peering to be accepted by the accept stack after approval.
+func (b *ConnectionBuilder) WithManualAcceptance() *ConnectionBuilder {
+	b.entry.Acceptance = AcceptanceManual
+	return b
+}
+
+// WithExternalPeering routes through a peering created elsewhere instead of creating one.
+func (b *ConnectionBuilder) WithExternalPeering(peeringID string) *ConnectionBuilder {
+	manage := false
+	b.entry.ManagePeering = &manage
+	b.entry.PeeringID = peeringID
+	return b
+}
+
+// DependsOn establishes the named connections (<source>/<peer>) before this one.
+func (b *ConnectionBuilder) DependsOn(keys ...string) *ConnectionBuilder {
+	b.entry.DependsOn = append(b.entry.DependsOn, keys...)
+	return b
+}
+
+// Entry returns the connection as a peering_matrix entry of its source. DNS resolution is not part
+// of it: it is set on the peer (see AddTo).
+func (b *ConnectionBuilder) Entry() MatrixEntry {
+	return b.entry
+}
+
+// Build resolves the connection against the peers and config-level settings of cfg, as
+// ConvertToPeerConfigs resolves a matrix entry, ready for NewMyStack.
+func (b *ConnectionBuilder) Build(cfg YAMLConfig) (PeerConfig, error) {
+	peer, err := ResolveConnection(cfg, b.source, b.entry)
+	if err != nil {
+		return PeerConfig{}, err
+	}
+	peer.EnableDNSResolution = peer.EnableDNSResolution || b.dns
+	return peer, nil
+}
+
+// AddTo validates the connection and appends it to the peering matrix of cfg. WithDNS sets
+// dns_resolution on the peer, which enables it for every connection to that peer, as in YAML.
+func (b *ConnectionBuilder) AddTo(cfg *YAMLConfig) error {
+	if _, err := b.Build(*cfg); err != nil {
+		return err
+	}
+	if b.dns {
+		peer := cfg.Peers[b.entry.Peer]
+		peer.DNSResolution = true
+		cfg.Peers[b.entry.Peer] = peer
+	}
+	if cfg.PeeringMatrix == nil {
+		cfg.PeeringMatrix = make(map[string][]MatrixEntry)
+	}
+	cfg.PeeringMatrix[b.source] = append(cfg.PeeringMatrix[b.source], b.entry)
+	return nil
+}
@@ -0,0 +1,81 @@
+package peering
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestFromYAML tests that an in-memory config is parsed like peering.yaml, with peer_defaults
+// applied and role ARNs checked.
+func TestFromYAML(t *testing.T) {
+	cfg, err := FromYAML([]byte(`
+peer_defaults:
+  region: us-east-1
+peers:
+  dev: { vpc_id: vpc-1 }
+  prod: { vpc_id: vpc-2, region: eu-west-1 }
+peering_matrix:
+  dev: [prod]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Peers["dev"].Region != "us-east-1" || cfg.Peers["prod"].Region != "eu-west-1" {
+		t.Errorf("expected peer_defaults to be applied, got %+v", cfg.Peers)
+	}
+
+	_, err = FromYAML([]byte("peers:\n  dev: { vpc_id: vpc-1, role_arn: not-an-arn }\n"))
+	if err == nil || ExitCodeOf(err) != ExitValidation || !strings.Contains(err.Error(), "role_arn") {
+		t.Errorf("expected a validation error for the role ARN, got %v", err)
+	}
+	if _, err := FromYAML([]byte("peers: [")); err == nil || ExitCodeOf(err) != ExitConfig {
+		t.Errorf("expected a config error for invalid YAML, got %v", err)
+	}
+}
+
+// TestConnectionBuilder tests that a built connection resolves like the equivalent matrix entry.
+func TestConnectionBuilder(t *testing.T) {
+	cfg := YAMLConfig{Peers: map[string]YAMLPeer{"dev": {VpcID: "vpc-1"}, "prod": {VpcID: "vpc-2"}}}
+	b := NewConnection("dev", "prod").
+		WithDNS().
+		WithExtraRoutes(ExtraRoute{Cidr: "100.64.0.0/16"}, ExtraRoute{Cidr: "100.65.0.0/16", Side: ExtraRoutePeer}).
+		WithTags(map[string]string{"Team": "net"})
+
+	peer, err := b.Build(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !peer.EnableDNSResolution || peer.SourceName != "dev" || peer.Name != "prod" || peer.PeerVpcID != "vpc-2" {
+		t.Errorf("unexpected connection %+v", peer)
+	}
+	if !reflect.DeepEqual(peer.SourceExtraCidrs, []string{"100.64.0.0/16"}) || !reflect.DeepEqual(peer.PeerExtraCidrs, []string{"100.65.0.0/16"}) {
+		t.Errorf("unexpected extra routes %v, %v", peer.SourceExtraCidrs, peer.PeerExtraCidrs)
+	}
+	if peer.Tags["Team"] != "net" {
+		t.Errorf("unexpected tags %v", peer.Tags)
+	}
+	if entry := NewConnection("dev", "prod").DependsOn("dev/shared").Entry(); !reflect.DeepEqual(entry.DependsOn, []string{"dev/shared"}) {
+		t.Errorf("unexpected dependencies %v", entry.DependsOn)
+	}
+
+	if err := b.AddTo(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Peers["prod"].DNSResolution || len(cfg.PeeringMatrix["dev"]) != 1 {
+		t.Errorf("expected the connection and DNS resolution in the config, got %+v", cfg)
+	}
+	if peers := ConvertToPeerConfigs(cfg, "dev"); len(peers) != 1 || !peers[0].EnableDNSResolution {
+		t.Errorf("expected the added connection to convert, got %+v", peers)
+	}
+
+	if err := NewConnection("dev", "missing").AddTo(&cfg); err == nil {
+		t.Error("expected an error for an unknown peer")
+	}
+	if err := NewConnection("dev", "prod").WithDestinationCidrs("10.0.0.0/33").AddTo(&cfg); err == nil {
+		t.Error("expected an error for an invalid destination CIDR")
+	}
+	if len(cfg.PeeringMatrix["dev"]) != 1 {
+		t.Errorf("expected invalid connections to be left out, got %+v", cfg.PeeringMatrix["dev"])
+	}
+}
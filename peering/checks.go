@@ -0,0 +1,125 @@
+package peering
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Connectivity Checks
+// -------------------------------------------------------------------------------------------------
+
+// CheckIPs are representative addresses on each side of a connection that connectivity checks
+// expect to be routable through the peering.
+type CheckIPs struct {
+	Source string `yaml:"source,omitempty"` // Address in the source VPC, checked from the peer side.
+	Peer   string `yaml:"peer,omitempty"`   // Address in the peer VPC, checked from the source side.
+}
+
+// ConnectivityCheck builds a Terraform check block for one connection. It asserts that the peering
+// is active and that each side's main route table sends the representative address of the other
+// side through it (the first host of each destination CIDR when no address is configured). Sides
+// without routing are not checked.
+func ConnectivityCheck(namer Namer, ctx NameContext, peer PeerConfig) (string, map[string]interface{}) {
+	name := namer.ID(ctx, KindConnectivityCheck)
+	pcxData := name + "_peering"
+	pcxRef := fmt.Sprintf("aws_vpc_peering_connection.%s.id", namer.ID(ctx, KindPeering))
+	if peer.ExternalPeeringID != "" {
+		pcxRef = fmt.Sprintf("%q", peer.ExternalPeeringID)
+	} else if peer.PeeringOwner != "" {
+		pcxRef = fmt.Sprintf("data.aws_vpc_peering_connection.%s.id", namer.ID(ctx, KindPeeringData))
+	}
+	sourceProvider := "aws." + namer.ID(ctx, KindSourceProviderAlias)
+	peerProvider := "aws." + namer.ID(ctx, KindPeerProviderAlias)
+
+	routeTables := map[string]interface{}{}
+	asserts := []map[string]string{{
+		"condition":     fmt.Sprintf(`${data.aws_vpc_peering_connection.%s.status == "active"}`, pcxData),
+		"error_message": fmt.Sprintf("Peering %s -> %s is not active.", ctx.Source, ctx.Peer),
+	}}
+
+	type side struct {
+		suffix   string
+		routing  RoutingConfig
+		mainRt   string
+		mainID   string
+		provider string
+		ips      []string
+		toward   string
+	}
+	peerIPs := hostExprs(peer.DestinationCidrs, vpcCidrExpr(namer, ctx, KindPeerVpc, peer.PeerCidr))
+	if peer.CheckIPs.Peer != "" {
+		peerIPs = []string{fmt.Sprintf("%q", peer.CheckIPs.Peer)}
+	}
+	sourceIPs := hostExprs(nil, vpcCidrExpr(namer, ctx, KindSourceVpc, peer.SourceCidr))
+	if peer.CheckIPs.Source != "" {
+		sourceIPs = []string{fmt.Sprintf("%q", peer.CheckIPs.Source)}
+	}
+
+	for _, s := range []side{
+		{"_source_rt", peer.SourceRouting, namer.ID(ctx, KindSourceMainRt), peer.SourceMainRouteTableID, sourceProvider, peerIPs, ctx.Peer},
+		{"_peer_rt", peer.PeerRouting, namer.ID(ctx, KindPeerMainRt), peer.PeerMainRouteTableID, peerProvider, sourceIPs, ctx.Source},
+	} {
+		if s.routing.Strategy == RoutingNone {
+			continue
+		}
+		data := name + s.suffix
+		routeTableID := fmt.Sprintf("${data.aws_route_table.%s.id}", s.mainRt)
+		if s.mainID != "" {
+			routeTableID = s.mainID
+		}
+		routeTables[data] = map[string]interface{}{
+			"route_table_id": routeTableID,
+			"provider":       s.provider,
+		}
+		for _, ip := range s.ips {
+			asserts = append(asserts, map[string]string{
+				"condition": fmt.Sprintf(
+					`${anytrue([for r in data.aws_route_table.%s.routes : r.vpc_peering_connection_id == %s && r.cidr_block != "" && cidrhost(format("%%s/%%s", %s, split("/", r.cidr_block)[1]), 0) == cidrhost(r.cidr_block, 0)])}`,
+					data, pcxRef, ip),
+				"error_message": fmt.Sprintf("Main route table %s does not route %s toward %s through the peering.", s.mainRt, strings.Trim(ip, `"`), s.toward),
+			})
+		}
+	}
+
+	block := map[string]interface{}{
+		"data": map[string]interface{}{
+			"aws_vpc_peering_connection": map[string]interface{}{
+				pcxData: map[string]interface{}{"id": "${" + pcxRef + "}", "provider": sourceProvider},
+			},
+		},
+		"assert": asserts,
+	}
+	if len(routeTables) > 0 {
+		block["data"].(map[string]interface{})["aws_route_table"] = routeTables
+	}
+	return name, block
+}
+
+// hostExprs returns expressions for the first host of each CIDR, or of the fallback expression.
+func hostExprs(cidrs []string, fallback string) []string {
+	if len(cidrs) == 0 {
+		return []string{fmt.Sprintf("cidrhost(%s, 1)", fallback)}
+	}
+	exprs := make([]string, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		exprs = append(exprs, fmt.Sprintf("cidrhost(%q, 1)", cidr))
+	}
+	return exprs
+}
+
+// AddConnectivityChecks writes one check block per connection into the stack, so terraform plan
+// reports connections whose peering or routes are broken. Connections whose peering was removed
+// ahead of their routes are skipped.
+func AddConnectivityChecks(stack cdktf.TerraformStack, namer Namer, peers []PeerConfig) {
+	for i, peer := range peers {
+		if peer.RetiredPeeringID != "" {
+			continue
+		}
+		name, block := ConnectivityCheck(namer, ConnectionNameContext(i, peer), peer)
+		stack.AddOverride(jsii.String("check."+name), block)
+	}
+}
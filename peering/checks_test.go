@@ -0,0 +1,65 @@
+package peering
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestConnectivityCheck tests the check block emitted for a connection.
+func TestConnectivityCheck(t *testing.T) {
+	peer := PeerConfig{
+		SourceName:       "dev",
+		Name:             "prod",
+		DestinationCidrs: []string{"10.1.0.0/24", "10.1.8.0/24"},
+		SourceRouting:    RoutingConfig{Strategy: RoutingMain},
+		PeerRouting:      RoutingConfig{Strategy: RoutingNone},
+		CheckIPs:         CheckIPs{Source: "10.0.1.10"},
+	}
+	name, block := ConnectivityCheck(LegacyNamer{}, ConnectionNameContext(0, peer), peer)
+	if name != "ConnectivityCheck0" {
+		t.Errorf("unexpected check name: %q", name)
+	}
+
+	asserts := block["assert"].([]map[string]string)
+	if len(asserts) != 3 {
+		t.Fatalf("expected peering assert and one per destination CIDR, got %d", len(asserts))
+	}
+	if !strings.Contains(asserts[1]["condition"], `cidrhost("10.1.0.0/24", 1)`) ||
+		!strings.Contains(asserts[1]["condition"], "aws_vpc_peering_connection.VpcPeering0.id") {
+		t.Errorf("unexpected route condition: %s", asserts[1]["condition"])
+	}
+
+	data, err := json.Marshal(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "ConnectivityCheck0_peer_rt") {
+		t.Errorf("expected no peer route table check without peer routing: %s", data)
+	}
+	if !strings.Contains(string(data), `"provider":"aws.source0"`) {
+		t.Errorf("expected scoped data sources to use the source provider: %s", data)
+	}
+}
+
+// TestConnectivityCheckPinnedCidr tests that checks use pinned CIDRs instead of the VPC lookups.
+func TestConnectivityCheckPinnedCidr(t *testing.T) {
+	peer := PeerConfig{
+		SourceName:    "dev",
+		Name:          "prod",
+		SourceCidr:    "10.0.0.0/16",
+		SourceRouting: RoutingConfig{Strategy: RoutingMain},
+		PeerRouting:   RoutingConfig{Strategy: RoutingMain},
+	}
+	_, block := ConnectivityCheck(LegacyNamer{}, ConnectionNameContext(0, peer), peer)
+	asserts := block["assert"].([]map[string]string)
+	if len(asserts) != 3 {
+		t.Fatalf("expected peering assert and one per side, got %d", len(asserts))
+	}
+	if !strings.Contains(asserts[1]["condition"], "cidrhost(data.aws_vpc.PeerVpcData0.cidr_block, 1)") {
+		t.Errorf("expected the unpinned peer CIDR to be looked up: %s", asserts[1]["condition"])
+	}
+	if !strings.Contains(asserts[2]["condition"], `cidrhost("10.0.0.0/16", 1)`) {
+		t.Errorf("expected the pinned source CIDR: %s", asserts[2]["condition"])
+	}
+}
@@ -0,0 +1,239 @@
+package peering
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Local CIDR Conflicts
+// -------------------------------------------------------------------------------------------------
+
+// CidrLookup reads the CIDR blocks of a VPC. Implemented by the AWS CLI and by fakes in tests.
+type CidrLookup interface {
+	// VpcCidrs returns every IPv4 CIDR block associated with the VPC.
+	VpcCidrs(vpcID string) ([]string, error)
+}
+
+// parseCidrs parses CIDR blocks, naming the first invalid one.
+func parseCidrs(cidrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// cidrsOverlap reports whether two CIDR blocks share any address.
+func cidrsOverlap(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// firstOverlap returns the first pair of overlapping blocks from a and b, or nils.
+func firstOverlap(a, b []*net.IPNet) (*net.IPNet, *net.IPNet) {
+	for _, x := range a {
+		for _, y := range b {
+			if cidrsOverlap(x, y) {
+				return x, y
+			}
+		}
+	}
+	return nil, nil
+}
+
+// KnownCidrs returns the CIDR blocks declared for a peer's VPC: its cidrs, or else its pinned cidr.
+func (p YAMLPeer) KnownCidrs() []string {
+	if len(p.Cidrs) == 0 && p.Cidr != "" {
+		return []string{p.Cidr}
+	}
+	return p.Cidrs
+}
+
+// ValidatePinnedCidr checks that a pinned cidr is a network address, as AWS reports the VPC's
+// primary CIDR, and one of the peer's cidrs when those are declared too.
+func ValidatePinnedCidr(p YAMLPeer) error {
+	if p.Cidr == "" {
+		return nil
+	}
+	_, n, err := net.ParseCIDR(p.Cidr)
+	if err != nil {
+		return fmt.Errorf("invalid cidr: %w", err)
+	}
+	if n.String() != p.Cidr {
+		return fmt.Errorf("cidr %s is not a network address; use %s", p.Cidr, n)
+	}
+	if len(p.Cidrs) == 0 {
+		return nil
+	}
+	for _, cidr := range p.Cidrs {
+		if cidr == p.Cidr {
+			return nil
+		}
+	}
+	return fmt.Errorf("cidr %s is not one of the cidrs of the VPC", p.Cidr)
+}
+
+// CheckLocalCidrs rejects a connection whose VPCs overlap, which AWS refuses to peer, and
+// destinations that equal or overlap the CIDRs of the VPC whose route tables would get them, which
+// AWS rejects since they conflict with the local route. Unknown (empty) CIDR sets are not checked.
+func CheckLocalCidrs(peer PeerConfig, sourceCidrs, peerCidrs []string) error {
+	sourceNets, err := parseCidrs(sourceCidrs)
+	if err != nil {
+		return fmt.Errorf("CIDRs of %s: %w", peer.SourceName, err)
+	}
+	peerName := ConnectionNameContext(0, peer).Peer
+	peerNets, err := parseCidrs(peerCidrs)
+	if err != nil {
+		return fmt.Errorf("CIDRs of %s: %w", peerName, err)
+	}
+	if a, b := firstOverlap(sourceNets, peerNets); a != nil {
+		return fmt.Errorf("VPC CIDR %s of %s overlaps VPC CIDR %s of %s; AWS cannot peer overlapping VPCs", a, peer.SourceName, b, peerName)
+	}
+
+	for _, side := range []struct {
+		name         string
+		local        []*net.IPNet
+		destinations []string
+	}{
+		{peer.SourceName, sourceNets, append(append([]string(nil), peer.DestinationCidrs...), peer.SourceExtraCidrs...)},
+		{peerName, peerNets, peer.PeerExtraCidrs},
+	} {
+		destinations, err := parseCidrs(side.destinations)
+		if err != nil {
+			return err
+		}
+		if dest, local := firstOverlap(destinations, side.local); dest != nil {
+			return fmt.Errorf("routes %s from %s, which overlaps its own VPC CIDR %s and conflicts with the local route", dest, side.name, local)
+		}
+	}
+	return nil
+}
+
+// lintLocalCidrs returns a rule checking every connection against the live CIDR blocks of its VPCs,
+// for peers that do not declare cidrs. Lookup failures are reported as warnings.
+func lintLocalCidrs(connect func(region, roleArn string) (CidrLookup, error)) LintRule {
+	return LintRule{Name: "local-cidr-conflict", Check: func(cfg YAMLConfig) []Diagnostic {
+		known := make(map[string][]string)
+		var out []Diagnostic
+		cidrs := func(subject string, declared []string, vpcID, region, roleArn string) []string {
+			if len(declared) > 0 {
+				return declared
+			}
+			if found, ok := known[vpcID]; ok {
+				return found
+			}
+			known[vpcID] = nil
+			lookup, err := connect(region, roleArn)
+			if err == nil {
+				known[vpcID], err = lookup.VpcCidrs(vpcID)
+			}
+			if err != nil {
+				out = append(out, Diagnostic{Severity: SeverityWarning, Subject: subject,
+					Message: fmt.Sprintf("could not look up the CIDRs of %s: %v", vpcID, err)})
+			}
+			return known[vpcID]
+		}
+
+		for _, row := range BuildMatrixRows(cfg) {
+			if row.Err != nil || row.Disabled {
+				continue
+			}
+			peer := row.Config
+			subject := ConnectionKey(peer)
+			sourceCidrs := cidrs(subject, peer.SourceCidrs, peer.SourceVpcID, peer.SourceRegion, peer.SourceRoleArn)
+			peerCidrs := cidrs(subject, peer.PeerCidrs, peer.PeerVpcID, peer.PeerRegion, peer.PeerRoleArn)
+			if err := CheckLocalCidrs(peer, sourceCidrs, peerCidrs); err != nil {
+				out = append(out, Diagnostic{Severity: SeverityError, Subject: subject, Message: err.Error()})
+			}
+		}
+		return out
+	}}
+}
+
+// VpcCidrs returns every IPv4 CIDR block associated with a VPC.
+func (c *AWSCLI) VpcCidrs(vpcID string) ([]string, error) {
+	var out struct {
+		Vpcs []struct {
+			CidrBlockAssociationSet []struct {
+				CidrBlock      string `json:"CidrBlock"`
+				CidrBlockState struct {
+					State string `json:"State"`
+				} `json:"CidrBlockState"`
+			} `json:"CidrBlockAssociationSet"`
+		} `json:"Vpcs"`
+	}
+	if err := c.Run(&out, "ec2", "describe-vpcs", "--vpc-ids", vpcID); err != nil {
+		return nil, err
+	}
+	if len(out.Vpcs) == 0 {
+		return nil, fmt.Errorf("vpc %s not found in %s", vpcID, c.Region)
+	}
+	var cidrs []string
+	for _, assoc := range out.Vpcs[0].CidrBlockAssociationSet {
+		if assoc.CidrBlockState.State == "associated" {
+			cidrs = append(cidrs, assoc.CidrBlock)
+		}
+	}
+	return cidrs, nil
+}
+
+// -------------------------------------------------------------------------------------------------
+// More-Specific Routes
+// -------------------------------------------------------------------------------------------------
+
+// Route kinds a connection's routes can be made to win over with prefer_over.
+const (
+	PreferOverTGW = "tgw" // A transit gateway route to the same destination.
+	PreferOverVPN = "vpn" // A VPN (virtual private gateway) route, static or propagated.
+)
+
+// SplitCidr returns the two halves of an IPv4 CIDR block, e.g. 10.0.0.0/17 and 10.0.128.0/17 for
+// 10.0.0.0/16. Route tables pick the longest matching prefix, so routes to the halves win over a
+// route to the whole block whatever its target.
+func SplitCidr(cidr string) ([]string, error) {
+	_, n, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+	}
+	ones, bits := n.Mask.Size()
+	if bits != 32 {
+		return nil, fmt.Errorf("%s is not an IPv4 CIDR", cidr)
+	}
+	if ones == 32 {
+		return nil, fmt.Errorf("%s cannot be split further", cidr)
+	}
+	mask := net.CIDRMask(ones+1, 32)
+	high := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(high, binary.BigEndian.Uint32(n.IP.To4())|1<<uint(31-ones))
+	return []string{(&net.IPNet{IP: n.IP, Mask: mask}).String(), (&net.IPNet{IP: high, Mask: mask}).String()}, nil
+}
+
+// PreferredDestinations returns the destinations of a connection that prefers its routes over a
+// TGW or VPN route: each of the destinations, or of the peer VPC's declared CIDRs without any,
+// split into halves. A looked-up VPC CIDR is only known at apply time and cannot be split, so one
+// of them must be declared.
+func PreferredDestinations(preferOver string, destinations, peerCidrs []string) ([]string, error) {
+	if preferOver != PreferOverTGW && preferOver != PreferOverVPN {
+		return nil, fmt.Errorf("unknown prefer_over %q (want %s or %s)", preferOver, PreferOverTGW, PreferOverVPN)
+	}
+	if len(destinations) == 0 {
+		destinations = peerCidrs
+	}
+	if len(destinations) == 0 {
+		return nil, fmt.Errorf("prefer_over needs destination_cidrs or the peer's cidr or cidrs, since a looked-up VPC CIDR cannot be split")
+	}
+	var out []string
+	for _, cidr := range destinations {
+		halves, err := SplitCidr(cidr)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, halves...)
+	}
+	return out, nil
+}
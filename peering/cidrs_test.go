@@ -0,0 +1,136 @@
+package peering
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestCheckLocalCidrs tests overlap detection between destinations and the routing VPC's CIDRs.
+func TestCheckLocalCidrs(t *testing.T) {
+	base := PeerConfig{SourceName: "dev", Name: "prod"}
+	source := []string{"10.1.0.0/16", "100.64.0.0/16"}
+	peerCidrs := []string{"10.2.0.0/16"}
+	tests := []struct {
+		name       string
+		modify     func(p *PeerConfig)
+		source     []string
+		peer       []string
+		wantErrSub string
+	}{
+		{name: "no conflicts", modify: func(p *PeerConfig) { p.SourceExtraCidrs = []string{"10.3.0.0/16"} }, source: source, peer: peerCidrs},
+		{name: "unknown CIDRs", modify: func(p *PeerConfig) { p.DestinationCidrs = []string{"10.1.0.0/24"} }},
+		{name: "overlapping VPCs", modify: func(*PeerConfig) {}, source: source, peer: []string{"10.1.128.0/17"},
+			wantErrSub: "VPC CIDR 10.1.0.0/16 of dev overlaps VPC CIDR 10.1.128.0/17 of prod"},
+		{name: "destination inside local CIDR", modify: func(p *PeerConfig) { p.DestinationCidrs = []string{"10.1.4.0/24"} }, source: source, peer: peerCidrs,
+			wantErrSub: "routes 10.1.4.0/24 from dev, which overlaps its own VPC CIDR 10.1.0.0/16"},
+		{name: "extra route covering secondary CIDR", modify: func(p *PeerConfig) { p.SourceExtraCidrs = []string{"100.0.0.0/8"} }, source: source, peer: peerCidrs,
+			wantErrSub: "routes 100.0.0.0/8 from dev, which overlaps its own VPC CIDR 100.64.0.0/16"},
+		{name: "peer extra route", modify: func(p *PeerConfig) { p.PeerExtraCidrs = []string{"10.2.0.0/16"} }, peer: peerCidrs,
+			wantErrSub: "routes 10.2.0.0/16 from prod"},
+		{name: "invalid declared CIDR", modify: func(*PeerConfig) {}, source: []string{"10.1.0.0"}, wantErrSub: "CIDRs of dev"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			peer := base
+			tt.modify(&peer)
+			err := CheckLocalCidrs(peer, tt.source, tt.peer)
+			if tt.wantErrSub == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErrSub) {
+				t.Errorf("error = %v, want %q", err, tt.wantErrSub)
+			}
+		})
+	}
+}
+
+// fakeCidrLookup returns fixed CIDRs per VPC.
+type fakeCidrLookup map[string][]string
+
+func (f fakeCidrLookup) VpcCidrs(vpcID string) ([]string, error) { return f[vpcID], nil }
+
+// TestLintLocalCidrs tests that looked-up CIDRs fill in for peers without declared cidrs.
+func TestLintLocalCidrs(t *testing.T) {
+	cfg := YAMLConfig{
+		Peers: map[string]YAMLPeer{
+			"dev":  {VpcID: "vpc-1", Cidrs: []string{"10.1.0.0/16"}},
+			"prod": {VpcID: "vpc-2"},
+		},
+		PeeringMatrix: map[string][]MatrixEntry{
+			"dev": {{Peer: "prod", ExtraRoutes: []ExtraRoute{{Cidr: "10.9.0.0/24", Side: ExtraRoutePeer}}}},
+		},
+	}
+	lookups := 0
+	rule := lintLocalCidrs(func(string, string) (CidrLookup, error) {
+		lookups++
+		return fakeCidrLookup{"vpc-2": {"10.9.0.0/16"}}, nil
+	})
+	got := rule.Check(cfg)
+	if lookups != 1 {
+		t.Errorf("expected only the undeclared VPC to be looked up, got %d lookups", lookups)
+	}
+	if len(got) != 1 || got[0].Subject != "dev/prod" || !strings.Contains(got[0].Message, "routes 10.9.0.0/24 from prod") {
+		t.Errorf("unexpected diagnostics: %+v", got)
+	}
+}
+
+// TestValidatePinnedCidr tests the checks on a peer's pinned cidr.
+func TestValidatePinnedCidr(t *testing.T) {
+	tests := []struct {
+		name  string
+		peer  YAMLPeer
+		known []string
+		err   string
+	}{
+		{"unpinned", YAMLPeer{Cidrs: []string{"10.0.0.0/16"}}, []string{"10.0.0.0/16"}, ""},
+		{"pinned only", YAMLPeer{Cidr: "10.0.0.0/16"}, []string{"10.0.0.0/16"}, ""},
+		{"pinned in cidrs", YAMLPeer{Cidr: "10.0.0.0/16", Cidrs: []string{"100.64.0.0/16", "10.0.0.0/16"}}, []string{"100.64.0.0/16", "10.0.0.0/16"}, ""},
+		{"host address", YAMLPeer{Cidr: "10.0.0.1/16"}, []string{"10.0.0.1/16"}, "use 10.0.0.0/16"},
+		{"invalid", YAMLPeer{Cidr: "10.0.0.0"}, []string{"10.0.0.0"}, "invalid cidr"},
+		{"not in cidrs", YAMLPeer{Cidr: "10.0.0.0/16", Cidrs: []string{"100.64.0.0/16"}}, []string{"100.64.0.0/16"}, "not one of the cidrs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePinnedCidr(tt.peer)
+			if (tt.err == "") != (err == nil) || (err != nil && !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("expected error %q, got %v", tt.err, err)
+			}
+			if known := tt.peer.KnownCidrs(); strings.Join(known, ",") != strings.Join(tt.known, ",") {
+				t.Errorf("expected known CIDRs %v, got %v", tt.known, known)
+			}
+		})
+	}
+}
+
+// TestPreferredDestinations tests splitting destinations into halves for prefer_over.
+func TestPreferredDestinations(t *testing.T) {
+	tests := []struct {
+		name         string
+		preferOver   string
+		destinations []string
+		peerCidrs    []string
+		want         []string
+		wantErr      bool
+	}{
+		{name: "peer cidrs", preferOver: PreferOverTGW, peerCidrs: []string{"10.1.0.0/16"}, want: []string{"10.1.0.0/17", "10.1.128.0/17"}},
+		{name: "destinations first", preferOver: PreferOverVPN, destinations: []string{"10.1.4.0/24", "10.1.8.0/31"}, peerCidrs: []string{"10.1.0.0/16"},
+			want: []string{"10.1.4.0/25", "10.1.4.128/25", "10.1.8.0/32", "10.1.8.1/32"}},
+		{name: "unknown cidr", preferOver: PreferOverTGW, wantErr: true},
+		{name: "unknown kind", preferOver: "dx", peerCidrs: []string{"10.1.0.0/16"}, wantErr: true},
+		{name: "host route", preferOver: PreferOverTGW, destinations: []string{"10.1.0.1/32"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := PreferredDestinations(tt.preferOver, tt.destinations, tt.peerCidrs)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.wantErr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package peering
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Destination CIDR Variables
+// -------------------------------------------------------------------------------------------------
+
+// CidrVariablePrefix prefixes the names of the Terraform variables carrying destination CIDRs.
+const CidrVariablePrefix = "destination_cidrs_"
+
+// CidrVariables names one Terraform variable per connection with destination_cidrs, after its source
+// and peer, and returns connection key -> variable name. Connections routing the whole peer VPC get
+// none.
+func CidrVariables(peers []PeerConfig) map[string]string {
+	var keys []string
+	for _, peer := range peers {
+		if len(peer.DestinationCidrs) > 0 {
+			keys = append(keys, ConnectionKey(peer))
+		}
+	}
+	sort.Strings(keys)
+
+	vars := make(map[string]string, len(keys))
+	taken := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		base := CidrVariablePrefix + invalidIDChars.ReplaceAllString(strings.Replace(key, "/", "_", 1), "_")
+		name := base
+		for i := 2; taken[name]; i++ {
+			name = fmt.Sprintf("%s_%d", base, i)
+		}
+		taken[name] = true
+		vars[key] = name
+	}
+	return vars
+}
+
+// CidrVariableIndexes returns, for each route, the index of its destination in destination_cidrs,
+// or -1 for routes to other destinations (extra routes and the fallback VPC CIDR).
+func CidrVariableIndexes(destinations []string, routes []RouteInfo) []int {
+	index := make(map[string]int, len(destinations))
+	for i, cidr := range destinations {
+		index[cidr] = i
+	}
+	out := make([]int, len(routes))
+	for i, r := range routes {
+		out[i] = -1
+		if r.Cidr == nil {
+			continue
+		}
+		if j, ok := index[*r.Cidr]; ok {
+			out[i] = j
+		}
+	}
+	return out
+}
+
+// AddCidrVariables declares a list(string) variable per connection with destination_cidrs,
+// defaulting to them, and points the source-side routes to those destinations at its elements, so
+// an emergency change can be applied with -var without a new synth. The list must keep its length:
+// routes, and their addresses, are still one per configured destination. It does nothing unless
+// enabled.
+func AddCidrVariables(stack cdktf.TerraformStack, connections []ConnectionResources, enabled bool) {
+	if !enabled {
+		return
+	}
+	peers := make([]PeerConfig, len(connections))
+	for i, c := range connections {
+		peers[i] = c.Peer
+	}
+	vars := CidrVariables(peers)
+	for _, c := range connections {
+		name, ok := vars[ConnectionKey(c.Peer)]
+		if !ok {
+			continue
+		}
+		n := len(c.Peer.DestinationCidrs)
+		cdktf.NewTerraformVariable(stack, jsii.String(name), &cdktf.TerraformVariableConfig{
+			Type:        jsii.String("list(string)"),
+			Default:     c.Peer.DestinationCidrs,
+			Description: jsii.String(fmt.Sprintf("Destination CIDRs of %s, in destination_cidrs order", ConnectionKey(c.Peer))),
+			Validation: &[]*cdktf.TerraformVariableValidationConfig{{
+				Condition:    fmt.Sprintf("${length(var.%s) == %d && alltrue([for c in var.%s : can(cidrnetmask(c))])}", name, n, name),
+				ErrorMessage: jsii.String(fmt.Sprintf("%s takes exactly %d IPv4 CIDR block(s); adding or removing destinations needs a synth.", name, n)),
+			}},
+		})
+		for i, j := range CidrVariableIndexes(c.Peer.DestinationCidrs, c.Routes.Source.Info) {
+			if j >= 0 {
+				c.Routes.Source.Routes[i].AddOverride(jsii.String("destination_cidr_block"), fmt.Sprintf("${var.%s[%d]}", name, j))
+			}
+		}
+	}
+	if len(vars) > 0 {
+		log.Printf("[convert] Routing destination_cidrs of %d connection(s) through %s* variables", len(vars), CidrVariablePrefix)
+	}
+}
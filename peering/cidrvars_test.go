@@ -0,0 +1,43 @@
+package peering
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/jsii-runtime-go"
+)
+
+// TestCidrVariables tests that only connections with destination_cidrs get a variable, named after
+// the connection.
+func TestCidrVariables(t *testing.T) {
+	peers := []PeerConfig{
+		{SourceName: "dev-peer", Name: "prod-peer", DestinationCidrs: []string{"10.20.0.0/24"}},
+		{SourceName: "dev-peer", Name: "shared.vpc", DestinationCidrs: []string{"10.30.0.0/24"}},
+		{SourceName: "dev-peer", Name: "shared_vpc", DestinationCidrs: []string{"10.40.0.0/24"}},
+		{SourceName: "dev-peer", Name: "qa-peer"},
+	}
+	want := map[string]string{
+		"dev-peer/prod-peer":  "destination_cidrs_dev-peer_prod-peer",
+		"dev-peer/shared.vpc": "destination_cidrs_dev-peer_shared_vpc",
+		"dev-peer/shared_vpc": "destination_cidrs_dev-peer_shared_vpc_2",
+	}
+	if got := CidrVariables(peers); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+// TestCidrVariableIndexes tests that routes are matched to their destination_cidrs element and other
+// routes are left alone.
+func TestCidrVariableIndexes(t *testing.T) {
+	routes := []RouteInfo{
+		{Cidr: jsii.String("10.20.8.0/24")},
+		{Cidr: jsii.String("100.64.0.0/16")},
+		{Cidr: jsii.String("10.20.0.0/24"), ForEach: "toset([\"rtb-1\"])"},
+		{Cidr: jsii.String("${TfToken[TOKEN.1]}")},
+		{},
+	}
+	want := []int{1, -1, 0, -1, -1}
+	if got := CidrVariableIndexes([]string{"10.20.0.0/24", "10.20.8.0/24"}, routes); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
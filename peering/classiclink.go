@@ -0,0 +1,83 @@
+package peering
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// -------------------------------------------------------------------------------------------------
+// ClassicLink Options
+// -------------------------------------------------------------------------------------------------
+
+// classicLinkOptions are the peering options that linked EC2-Classic instances across a peering.
+// EC2-Classic is retired in every account and AWS provider 5 removed them from
+// aws_vpc_peering_connection_options, so the stack never sets them: DNS resolution is the only
+// peering option it manages.
+var classicLinkOptions = []string{"allow_classic_link_to_remote_vpc", "allow_vpc_to_remote_classic_link"}
+
+// CheckClassicLinkOptions rejects a config document that asks for ClassicLink options anywhere, as a
+// key (under a peer, a matrix entry, or peer_defaults) or as an attribute name in a list such as an
+// aspects ignore_changes entry. The config schema has no such fields, so without this check they
+// would be dropped silently. Every offending path is reported, sorted by key.
+func CheckClassicLinkOptions(data []byte) error {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	var paths []string
+	walkClassicLinkOptions(doc, "", &paths)
+	if len(paths) > 0 {
+		return fmt.Errorf("ClassicLink options are not supported (EC2-Classic is retired and AWS provider 5 "+
+			"removed them; only dns_resolution is managed), remove %s", strings.Join(paths, ", "))
+	}
+	return nil
+}
+
+// walkClassicLinkOptions appends the path of every ClassicLink option under node to paths.
+func walkClassicLinkOptions(node interface{}, path string, paths *[]string) {
+	switch n := node.(type) {
+	case map[interface{}]interface{}:
+		keys := make([]string, 0, len(n))
+		values := make(map[string]interface{}, len(n))
+		for k, v := range n {
+			key := fmt.Sprint(k)
+			keys = append(keys, key)
+			values[key] = v
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			child := key
+			if path != "" {
+				child = path + "." + key
+			}
+			if isClassicLinkOption(key) {
+				*paths = append(*paths, child)
+				continue
+			}
+			walkClassicLinkOptions(values[key], child, paths)
+		}
+	case []interface{}:
+		for i, v := range n {
+			walkClassicLinkOptions(v, fmt.Sprintf("%s[%d]", path, i), paths)
+		}
+	case string:
+		if isClassicLinkOption(n) {
+			*paths = append(*paths, path)
+		}
+	}
+}
+
+// isClassicLinkOption reports whether a key or attribute names a ClassicLink option, bare or
+// qualified by a side (requester.allow_classic_link_to_remote_vpc).
+func isClassicLinkOption(name string) bool {
+	name = name[strings.LastIndex(name, ".")+1:]
+	for _, option := range classicLinkOptions {
+		if name == option {
+			return true
+		}
+	}
+	return false
+}
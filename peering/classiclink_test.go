@@ -0,0 +1,51 @@
+package peering
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestCheckClassicLinkOptions tests that ClassicLink options are rejected wherever they appear and
+// that a config managing only DNS resolution passes.
+func TestCheckClassicLinkOptions(t *testing.T) {
+	clean := `
+peers:
+  dev: { vpc_id: vpc-1, dns_resolution: true }
+  prod: { vpc_id: vpc-2 }
+peering_matrix:
+  dev: [prod]
+`
+	if err := CheckClassicLinkOptions([]byte(clean)); err != nil {
+		t.Errorf("clean config: %v", err)
+	}
+
+	data := `
+peer_defaults:
+  allow_vpc_to_remote_classic_link: false
+peers:
+  dev: { vpc_id: vpc-1, allow_classic_link_to_remote_vpc: true }
+  prod: { vpc_id: vpc-2 }
+peering_matrix:
+  dev:
+    - peer: prod
+      requester:
+        allow_classic_link_to_remote_vpc: true
+aspects:
+  ignore_changes:
+    aws_vpc_peering_connection_options: [accepter.allow_vpc_to_remote_classic_link]
+`
+	err := CheckClassicLinkOptions([]byte(data))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, path := range []string{
+		"aspects.ignore_changes.aws_vpc_peering_connection_options[0]",
+		"peer_defaults.allow_vpc_to_remote_classic_link",
+		"peering_matrix.dev[0].requester.allow_classic_link_to_remote_vpc",
+		"peers.dev.allow_classic_link_to_remote_vpc",
+	} {
+		if !strings.Contains(err.Error(), path) {
+			t.Errorf("error %q does not name %s", err, path)
+		}
+	}
+}
@@ -0,0 +1,340 @@
+package peering
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Peering Debris Cleanup
+// -------------------------------------------------------------------------------------------------
+
+// Actions taken on peering debris.
+const (
+	CleanupDelete   = "delete"   // Deleted with -delete: a stale request of the VPC, or a blackhole route.
+	CleanupReject   = "reject"   // Rejected with -delete: a stale request to the VPC.
+	CleanupExpires  = "expires"  // Cannot be deleted; AWS removes it on its own.
+	CleanupDeleted  = "deleted"  // Deleted by this run.
+	CleanupRejected = "rejected" // Rejected by this run.
+)
+
+// inactivePeeringStates are the peering states the cleanup lists.
+var inactivePeeringStates = []string{"pending-acceptance", "rejected", "failed", "expired", "deleted"}
+
+// InactivePeering is a peering connection of a VPC that is not active.
+type InactivePeering struct {
+	PeeringID      string // pcx-id of the connection.
+	Status         string // Status code, e.g. rejected.
+	RequesterVpcID string // VPC that requested the connection.
+	AccepterVpcID  string // VPC the connection was requested to.
+}
+
+// BlackholeRoute is a route to a peering connection that no longer exists.
+type BlackholeRoute struct {
+	RouteTableID string // Route table holding the route.
+	Destination  string // Destination CIDR of the route.
+	PeeringID    string // pcx-id the route targets.
+}
+
+// CleanupClient reads and removes peering debris in one account and region. Implemented by the AWS
+// CLI and by fakes in tests.
+type CleanupClient interface {
+	InactivePeerings(vpcID string) ([]InactivePeering, error)
+	BlackholeRoutes(vpcID string) ([]BlackholeRoute, error)
+	DeletePeering(peeringID string) error
+	RejectPeering(peeringID string) error
+	DeleteRoute(routeTableID, destination string) error
+}
+
+// CleanupItem is one piece of debris found in a managed VPC.
+type CleanupItem struct {
+	VpcID        string // Managed VPC the debris was found in.
+	Kind         string // peering or route.
+	PeeringID    string // pcx-id of the peering, or of the dead peering a route targets.
+	Status       string // Peering status code, or blackhole for routes.
+	RouteTableID string // Route table of a route.
+	Destination  string // Destination CIDR of a route.
+	Action       string // What -delete does, or did, about it.
+}
+
+// cleanupVpc is a managed VPC with the region and role of its side.
+type cleanupVpc struct {
+	VpcID   string
+	Region  string
+	RoleArn string
+}
+
+// managedVpcs lists the VPCs on either side of the connections, sorted by ID. Third parties' VPCs
+// are left out, as they cannot be read.
+func managedVpcs(peers []PeerConfig) []cleanupVpc {
+	byID := make(map[string]cleanupVpc)
+	for _, peer := range peers {
+		for _, side := range []cleanupVpc{
+			{peer.SourceVpcID, peer.SourceRegion, peer.SourceRoleArn},
+			{peer.PeerVpcID, peer.PeerRegion, peer.PeerRoleArn},
+		} {
+			if peer.ThirdParty && side.VpcID == peer.PeerVpcID {
+				continue
+			}
+			if _, ok := byID[side.VpcID]; !ok && side.VpcID != "" {
+				byID[side.VpcID] = side
+			}
+		}
+	}
+	vpcs := make([]cleanupVpc, 0, len(byID))
+	for _, vpc := range byID {
+		vpcs = append(vpcs, vpc)
+	}
+	sort.Slice(vpcs, func(i, j int) bool { return vpcs[i].VpcID < vpcs[j].VpcID })
+	return vpcs
+}
+
+// FindDebris lists the inactive peering connections and blackhole peering routes of the VPCs of
+// peers, reading each with its own side's credentials. Requests awaiting acceptance for a connection
+// of all, every configured connection of every source, and peerings created elsewhere are not debris
+// and are left out; pending-report covers them. A scanned VPC also peers with other sources, whose
+// requests are theirs to accept. A connection seen from both of its VPCs is listed once.
+func FindDebris(peers, all []PeerConfig, connect func(region, roleArn string) (CleanupClient, error)) ([]CleanupItem, error) {
+	configured := make(map[string]bool)
+	for _, peer := range all {
+		configured[peer.SourceVpcID+"|"+peer.PeerVpcID] = true
+		configured[peer.PeerVpcID+"|"+peer.SourceVpcID] = true
+		if peer.ExternalPeeringID != "" {
+			configured[peer.ExternalPeeringID] = true
+		}
+	}
+
+	seen := make(map[string]bool)
+	var items []CleanupItem
+	for _, vpc := range managedVpcs(peers) {
+		client, err := connect(vpc.Region, vpc.RoleArn)
+		if err != nil {
+			return nil, err
+		}
+		peerings, err := client.InactivePeerings(vpc.VpcID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list peerings of %s: %w", vpc.VpcID, err)
+		}
+		for _, p := range peerings {
+			if seen[p.PeeringID] || configured[p.PeeringID] {
+				continue
+			}
+			seen[p.PeeringID] = true
+			action := CleanupExpires
+			if p.Status == "pending-acceptance" {
+				if configured[p.RequesterVpcID+"|"+p.AccepterVpcID] {
+					continue
+				}
+				action = CleanupReject
+				if p.RequesterVpcID == vpc.VpcID {
+					action = CleanupDelete
+				}
+			}
+			items = append(items, CleanupItem{VpcID: vpc.VpcID, Kind: "peering", PeeringID: p.PeeringID, Status: p.Status, Action: action})
+		}
+
+		routes, err := client.BlackholeRoutes(vpc.VpcID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list routes of %s: %w", vpc.VpcID, err)
+		}
+		for _, r := range routes {
+			items = append(items, CleanupItem{VpcID: vpc.VpcID, Kind: "route", PeeringID: r.PeeringID, Status: "blackhole",
+				RouteTableID: r.RouteTableID, Destination: r.Destination, Action: CleanupDelete})
+		}
+	}
+	return items, nil
+}
+
+// RemoveDebris deletes the blackhole routes and stale requests of the VPCs, and rejects stale
+// requests sent to them, recording what was done on each item. Debris AWS removes itself is left.
+func RemoveDebris(items []CleanupItem, peers []PeerConfig, connect func(region, roleArn string) (CleanupClient, error)) error {
+	vpcs := make(map[string]cleanupVpc)
+	for _, vpc := range managedVpcs(peers) {
+		vpcs[vpc.VpcID] = vpc
+	}
+	for i := range items {
+		item := &items[i]
+		if item.Action != CleanupDelete && item.Action != CleanupReject {
+			continue
+		}
+		vpc := vpcs[item.VpcID]
+		client, err := connect(vpc.Region, vpc.RoleArn)
+		if err != nil {
+			return err
+		}
+		done := CleanupDeleted
+		switch {
+		case item.Kind == "route":
+			err = client.DeleteRoute(item.RouteTableID, item.Destination)
+		case item.Action == CleanupReject:
+			err = client.RejectPeering(item.PeeringID)
+			done = CleanupRejected
+		default:
+			err = client.DeletePeering(item.PeeringID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to clean up %s in %s: %w", describeDebris(*item), item.VpcID, err)
+		}
+		item.Action = done
+	}
+	return nil
+}
+
+// describeDebris names a piece of debris, e.g. "pcx-1" or "rtb-1 10.1.0.0/16 -> pcx-1".
+func describeDebris(item CleanupItem) string {
+	if item.Kind == "route" {
+		return fmt.Sprintf("%s %s -> %s", item.RouteTableID, item.Destination, item.PeeringID)
+	}
+	return item.PeeringID
+}
+
+// PrintDebris writes the debris as a table.
+func PrintDebris(w io.Writer, items []CleanupItem) {
+	if len(items) == 0 {
+		fmt.Fprintln(w, "No peering debris found.")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VPC\tKIND\tRESOURCE\tSTATUS\tACTION")
+	for _, item := range items {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", item.VpcID, item.Kind, describeDebris(item), item.Status, item.Action)
+	}
+	tw.Flush()
+}
+
+// -------------------------------------------------------------------------------------------------
+// AWS CLI Implementation
+// -------------------------------------------------------------------------------------------------
+
+// InactivePeerings returns the peering connections a VPC requested or was requested to that are
+// not active.
+func (c *AWSCLI) InactivePeerings(vpcID string) ([]InactivePeering, error) {
+	var peerings []InactivePeering
+	seen := make(map[string]bool)
+	for _, side := range []string{"requester-vpc-info.vpc-id", "accepter-vpc-info.vpc-id"} {
+		var out struct {
+			VpcPeeringConnections []struct {
+				VpcPeeringConnectionID string `json:"VpcPeeringConnectionId"`
+				Status                 struct {
+					Code string `json:"Code"`
+				} `json:"Status"`
+				RequesterVpcInfo struct {
+					VpcID string `json:"VpcId"`
+				} `json:"RequesterVpcInfo"`
+				AccepterVpcInfo struct {
+					VpcID string `json:"VpcId"`
+				} `json:"AccepterVpcInfo"`
+			} `json:"VpcPeeringConnections"`
+		}
+		states := "Name=status-code,Values=" + inactivePeeringStates[0]
+		for _, state := range inactivePeeringStates[1:] {
+			states += "," + state
+		}
+		if err := c.Run(&out, "ec2", "describe-vpc-peering-connections", "--filters", "Name="+side+",Values="+vpcID, states); err != nil {
+			return nil, err
+		}
+		for _, pcx := range out.VpcPeeringConnections {
+			if seen[pcx.VpcPeeringConnectionID] {
+				continue
+			}
+			seen[pcx.VpcPeeringConnectionID] = true
+			peerings = append(peerings, InactivePeering{PeeringID: pcx.VpcPeeringConnectionID, Status: pcx.Status.Code,
+				RequesterVpcID: pcx.RequesterVpcInfo.VpcID, AccepterVpcID: pcx.AccepterVpcInfo.VpcID})
+		}
+	}
+	sort.Slice(peerings, func(i, j int) bool { return peerings[i].PeeringID < peerings[j].PeeringID })
+	return peerings, nil
+}
+
+// BlackholeRoutes returns the routes of a VPC's route tables that target a peering connection and
+// are in the blackhole state.
+func (c *AWSCLI) BlackholeRoutes(vpcID string) ([]BlackholeRoute, error) {
+	var out struct {
+		RouteTables []struct {
+			RouteTableID string `json:"RouteTableId"`
+			Routes       []struct {
+				DestinationCidrBlock   string `json:"DestinationCidrBlock"`
+				VpcPeeringConnectionID string `json:"VpcPeeringConnectionId"`
+				State                  string `json:"State"`
+			} `json:"Routes"`
+		} `json:"RouteTables"`
+	}
+	if err := c.Run(&out, "ec2", "describe-route-tables", "--filters", "Name=vpc-id,Values="+vpcID, "Name=route.state,Values=blackhole"); err != nil {
+		return nil, err
+	}
+	var routes []BlackholeRoute
+	for _, rt := range out.RouteTables {
+		for _, r := range rt.Routes {
+			if r.State == "blackhole" && r.VpcPeeringConnectionID != "" && r.DestinationCidrBlock != "" {
+				routes = append(routes, BlackholeRoute{RouteTableID: rt.RouteTableID, Destination: r.DestinationCidrBlock, PeeringID: r.VpcPeeringConnectionID})
+			}
+		}
+	}
+	return routes, nil
+}
+
+// DeletePeering deletes a peering connection.
+func (c *AWSCLI) DeletePeering(peeringID string) error {
+	var out struct {
+		Return bool `json:"Return"`
+	}
+	return c.Run(&out, "ec2", "delete-vpc-peering-connection", "--vpc-peering-connection-id", peeringID)
+}
+
+// RejectPeering rejects a peering request.
+func (c *AWSCLI) RejectPeering(peeringID string) error {
+	var out struct {
+		Return bool `json:"Return"`
+	}
+	return c.Run(&out, "ec2", "reject-vpc-peering-connection", "--vpc-peering-connection-id", peeringID)
+}
+
+// DeleteRoute deletes the route to a destination from a route table.
+func (c *AWSCLI) DeleteRoute(routeTableID, destination string) error {
+	return c.Run(nil, "ec2", "delete-route", "--route-table-id", routeTableID, "--destination-cidr-block", destination)
+}
+
+// -------------------------------------------------------------------------------------------------
+// cleanup
+// -------------------------------------------------------------------------------------------------
+
+// runCleanup lists the peering debris of the source's VPCs and, with -delete, removes it. Requests
+// of every source's connections are kept.
+func runCleanup(args []string) error {
+	fs := flag.NewFlagSet("cleanup", flag.ContinueOnError)
+	remove := fs.Bool("delete", false, "delete blackhole routes and stale requests, and reject stale requests to managed VPCs")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, peers := loadSourcePeers(sourceArg(fs))
+	var all []PeerConfig
+	quietLogs(func() { all = ConvertToPeerConfigs(cfg, "") })
+	clients := make(map[string]CleanupClient)
+	connect := func(region, roleArn string) (CleanupClient, error) {
+		key := region + "|" + roleArn
+		if c, ok := clients[key]; ok {
+			return c, nil
+		}
+		c, err := NewAWSCLI(region, roleArn, cfg.Provider)
+		if err != nil {
+			return nil, err
+		}
+		clients[key] = c
+		return c, nil
+	}
+
+	items, err := FindDebris(peers, all, connect)
+	if err != nil {
+		return err
+	}
+	if *remove {
+		err = RemoveDebris(items, peers, connect)
+	}
+	PrintDebris(os.Stdout, items)
+	return err
+}
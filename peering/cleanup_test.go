@@ -0,0 +1,118 @@
+package peering
+
+import (
+	"reflect"
+	"testing"
+)
+
+// fakeCleanup returns fixed debris per VPC and records what is removed.
+type fakeCleanup struct {
+	peerings map[string][]InactivePeering
+	routes   map[string][]BlackholeRoute
+	removed  *[]string
+}
+
+func (f fakeCleanup) InactivePeerings(vpcID string) ([]InactivePeering, error) {
+	return f.peerings[vpcID], nil
+}
+func (f fakeCleanup) BlackholeRoutes(vpcID string) ([]BlackholeRoute, error) {
+	return f.routes[vpcID], nil
+}
+func (f fakeCleanup) DeletePeering(id string) error {
+	*f.removed = append(*f.removed, "delete "+id)
+	return nil
+}
+func (f fakeCleanup) RejectPeering(id string) error {
+	*f.removed = append(*f.removed, "reject "+id)
+	return nil
+}
+func (f fakeCleanup) DeleteRoute(rtb, dest string) error {
+	*f.removed = append(*f.removed, "delete "+rtb+" "+dest)
+	return nil
+}
+
+// TestCleanupDebris tests which debris is listed and how each piece is removed.
+func TestCleanupDebris(t *testing.T) {
+	var removed []string
+	client := fakeCleanup{removed: &removed,
+		peerings: map[string][]InactivePeering{
+			"vpc-1": {
+				{PeeringID: "pcx-wanted", Status: "pending-acceptance", RequesterVpcID: "vpc-1", AccepterVpcID: "vpc-2"},
+				{PeeringID: "pcx-stale", Status: "pending-acceptance", RequesterVpcID: "vpc-1", AccepterVpcID: "vpc-9"},
+				{PeeringID: "pcx-gone", Status: "deleted", RequesterVpcID: "vpc-1", AccepterVpcID: "vpc-2"},
+			},
+			"vpc-2": {
+				{PeeringID: "pcx-gone", Status: "deleted", RequesterVpcID: "vpc-1", AccepterVpcID: "vpc-2"},
+				{PeeringID: "pcx-incoming", Status: "pending-acceptance", RequesterVpcID: "vpc-8", AccepterVpcID: "vpc-2"},
+				{PeeringID: "pcx-ext", Status: "pending-acceptance", RequesterVpcID: "vpc-7", AccepterVpcID: "vpc-2"},
+			},
+		},
+		routes: map[string][]BlackholeRoute{
+			"vpc-2": {{RouteTableID: "rtb-2", Destination: "10.1.0.0/16", PeeringID: "pcx-gone"}},
+		},
+	}
+	peers := []PeerConfig{
+		{SourceName: "dev", Name: "prod", SourceVpcID: "vpc-1", PeerVpcID: "vpc-2"},
+		{SourceName: "dev", Name: "ext", SourceVpcID: "vpc-1", PeerVpcID: "vpc-2", ExternalPeeringID: "pcx-ext"},
+	}
+	connect := func(string, string) (CleanupClient, error) { return client, nil }
+
+	items, err := FindDebris(peers, peers, connect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []CleanupItem{
+		{VpcID: "vpc-1", Kind: "peering", PeeringID: "pcx-stale", Status: "pending-acceptance", Action: CleanupDelete},
+		{VpcID: "vpc-1", Kind: "peering", PeeringID: "pcx-gone", Status: "deleted", Action: CleanupExpires},
+		{VpcID: "vpc-2", Kind: "peering", PeeringID: "pcx-incoming", Status: "pending-acceptance", Action: CleanupReject},
+		{VpcID: "vpc-2", Kind: "route", PeeringID: "pcx-gone", Status: "blackhole", RouteTableID: "rtb-2", Destination: "10.1.0.0/16", Action: CleanupDelete},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Fatalf("expected %+v, got %+v", want, items)
+	}
+
+	if err := RemoveDebris(items, peers, connect); err != nil {
+		t.Fatal(err)
+	}
+	wantRemoved := []string{"delete pcx-stale", "reject pcx-incoming", "delete rtb-2 10.1.0.0/16"}
+	if !reflect.DeepEqual(removed, wantRemoved) {
+		t.Errorf("expected %v, got %v", wantRemoved, removed)
+	}
+	if items[0].Action != CleanupDeleted || items[1].Action != CleanupExpires || items[2].Action != CleanupRejected {
+		t.Errorf("unexpected actions after removal: %+v", items)
+	}
+}
+
+// TestCleanupKeepsOtherSourcesRequests tests that scanning one source's VPCs leaves the pending
+// requests of another source's connections to that source.
+func TestCleanupKeepsOtherSourcesRequests(t *testing.T) {
+	var removed []string
+	client := fakeCleanup{removed: &removed,
+		peerings: map[string][]InactivePeering{
+			"vpc-2": {
+				{PeeringID: "pcx-prod", Status: "pending-acceptance", RequesterVpcID: "vpc-2", AccepterVpcID: "vpc-3"},
+				{PeeringID: "pcx-stale", Status: "pending-acceptance", RequesterVpcID: "vpc-2", AccepterVpcID: "vpc-9"},
+			},
+		},
+	}
+	dev := []PeerConfig{{SourceName: "dev", Name: "prod", SourceVpcID: "vpc-1", PeerVpcID: "vpc-2"}}
+	all := append(dev, PeerConfig{SourceName: "prod", Name: "qa", SourceVpcID: "vpc-2", PeerVpcID: "vpc-3"})
+	connect := func(string, string) (CleanupClient, error) { return client, nil }
+
+	items, err := FindDebris(dev, all, connect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []CleanupItem{
+		{VpcID: "vpc-2", Kind: "peering", PeeringID: "pcx-stale", Status: "pending-acceptance", Action: CleanupDelete},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Fatalf("expected %+v, got %+v", want, items)
+	}
+	if err := RemoveDebris(items, dev, connect); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(removed, []string{"delete pcx-stale"}) {
+		t.Errorf("expected only the stale request to be deleted, got %v", removed)
+	}
+}
@@ -0,0 +1,382 @@
+package peering
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Command Registry
+// -------------------------------------------------------------------------------------------------
+
+// Command is a CLI subcommand that runs instead of the default synth.
+type Command struct {
+	Name    string                    // Name used on the command line.
+	Usage   string                    // Argument synopsis shown in help output.
+	Summary string                    // One-line description shown in help output.
+	Run     func(args []string) error // Entrypoint receiving the arguments after the command name.
+}
+
+// Commands returns all registered subcommands.
+func Commands() map[string]Command {
+	list := []Command{
+		{
+			Name:    "init",
+			Usage:   "[-o dir] [-defaults] [-force]",
+			Summary: "Write a commented starter peering.yaml and cdktf.json, tailored by prompts",
+			Run:     runInit,
+		},
+		{
+			Name:    "addresses",
+			Usage:   "[-o file] [source]",
+			Summary: "Print the state-address mapping of every managed resource",
+			Run:     runAddresses,
+		},
+		{
+			Name:    "verify-addresses",
+			Usage:   "[-baseline file] [-update] [source]",
+			Summary: "Fail when resource addresses of existing connections changed",
+			Run:     runVerifyAddresses,
+		},
+		{
+			Name:    "lint",
+			Usage:   "[-f peering.yaml] [-format text|json]",
+			Summary: "Check the config for errors, likely mistakes, and notable settings",
+			Run:     runLint,
+		},
+		{
+			Name:    "describe",
+			Usage:   "<source> <peer>",
+			Summary: "Show everything resolved for a single connection",
+			Run:     runDescribe,
+		},
+		{
+			Name:    "what-if",
+			Usage:   "--source <peer> --peer <peer> [-lookup] [-format text|json]",
+			Summary: "Show the findings, quota impact, and resources of a connection not yet in the matrix",
+			Run:     runWhatIf,
+		},
+		{
+			Name:    "diff-config",
+			Usage:   "[-format text|json] [-exit-code] <old.yaml> <new.yaml>",
+			Summary: "Show the connections added, removed, or modified between two configs, ignoring formatting",
+			Run:     runDiffConfig,
+		},
+		{
+			Name:    "inspect",
+			Usage:   "[-format tree|json] [source]",
+			Summary: "Print every construct the stack creates with its Terraform type and provider",
+			Run:     runInspect,
+		},
+		{
+			Name:    "target-list",
+			Usage:   "[-args] <source> <peer>",
+			Summary: "Print the Terraform addresses of one connection for -target",
+			Run:     runTargetList,
+		},
+		{
+			Name:    "state-report",
+			Usage:   "[-state file | -dir stack-dir] [-history file] [source]",
+			Summary: "Report live peerings and routes from Terraform state against the config",
+			Run:     runStateReport,
+		},
+		{
+			Name:    "conflicts",
+			Usage:   "-other state-or-dir [-other ...] [-state file | -dir stack-dir] [-format text|json]",
+			Summary: "Find peerings and routes that other Terraform states also manage",
+			Run:     runConflicts,
+		},
+		{
+			Name:    "plan-summary",
+			Usage:   "[-dir stack-dir] [-plan file] [-format text|comment] [source]",
+			Summary: "Summarize a saved plan per connection, optionally as a merge request comment",
+			Run:     runPlanSummary,
+		},
+		{
+			Name:    "import-routes",
+			Usage:   "[-o file] [source]",
+			Summary: "Generate import blocks for routes that already exist in managed route tables",
+			Run:     runImportRoutes,
+		},
+		{
+			Name:    "migrate-config",
+			Usage:   "[-f peering.yaml] [-n]",
+			Summary: "Rewrite the config file in the current schema version",
+			Run:     runMigrateConfig,
+		},
+		{
+			Name:    "verify",
+			Usage:   "[-protocol tcp] [-port n] [-timeout 5m] [-keep] [source]",
+			Summary: "Run Reachability Analyzer across every connection and report PASS/FAIL",
+			Run:     runVerify,
+		},
+		{
+			Name:    "quota-check",
+			Usage:   "[source]",
+			Summary: "Compare VPC peering and route quotas of every involved account with the config",
+			Run:     runQuotaCheck,
+		},
+		{
+			Name:    "pending-report",
+			Usage:   "[-within duration] [-sns topic-arn] [source]",
+			Summary: "List peering requests awaiting acceptance with their age and time before expiry",
+			Run:     runPendingReport,
+		},
+		{
+			Name:    "cleanup",
+			Usage:   "[-delete] [source]",
+			Summary: "List inactive peerings and blackhole peering routes of managed VPCs, and optionally remove them",
+			Run:     runCleanup,
+		},
+		{
+			Name:    "apply",
+			Usage:   "-via-tfc -org org -workspace name [-hostname host] [-auto-apply] [-no-synth] [source]",
+			Summary: "Upload the synthesized stack to a Terraform Cloud workspace and follow its run",
+			Run:     runApply,
+		},
+		{
+			Name:    "bootstrap",
+			Usage:   "-trust principal[,principal] [-external-id id] [-flow-logs] [-route53] [-quotas] [-o dir] [source]",
+			Summary: "Synthesize per-account stacks creating the roles this tool assumes",
+			Run:     runBootstrap,
+		},
+		{
+			Name:    "iam-policy",
+			Usage:   "[-role arn] [-flow-logs] [-route53] [-quotas] [-o file] [source]",
+			Summary: "Print the least-privilege IAM policy of every role the config assumes",
+			Run:     runIAMPolicy,
+		},
+		{
+			Name:    "role-vars",
+			Usage:   "[-o file] [source]",
+			Summary: "Print the role ARN variable values as tfvars JSON",
+			Run:     runRoleVars,
+		},
+		{
+			Name:    "docs",
+			Usage:   "[-o dir|-] [-check] [peer]",
+			Summary: "Generate a Markdown runbook per peer from the config, or check they are current",
+			Run:     runDocs,
+		},
+		{
+			Name:    "export",
+			Usage:   "[-format backstage|servicenow|atlantis|spacelift] [-owner team] [-system name] [-outdir dir] [-o file] [source]",
+			Summary: "Export VPCs and peerings for a catalog or CMDB, or a project per source for Atlantis or Spacelift",
+			Run:     runExport,
+		},
+		{
+			Name:    "tui",
+			Usage:   "[-plain]",
+			Summary: "Browse, filter, and validate the peering matrix interactively",
+			Run:     runTUI,
+		},
+	}
+	m := make(map[string]Command, len(list))
+	for _, c := range list {
+		m[c.Name] = c
+	}
+	return m
+}
+
+// RunCommand dispatches to the named subcommand. Log output is sent to stderr so command output on
+// stdout stays machine-readable.
+func RunCommand(name string, args []string) error {
+	log.SetOutput(os.Stderr)
+
+	if IsHelp(name) {
+		printUsage()
+		return nil
+	}
+	cmd, ok := Commands()[name]
+	if !ok {
+		printUsage()
+		return fmt.Errorf("unknown command %q", name)
+	}
+	return cmd.Run(args)
+}
+
+// quietLogs runs fn with the standard logger discarded and then restores its previous writer, for
+// commands whose output the conversion logs would interleave with.
+func quietLogs(fn func()) {
+	previous := log.Writer()
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(previous)
+	fn()
+}
+
+// IsHelp reports whether a command-line argument asks for usage.
+func IsHelp(arg string) bool {
+	return arg == "help" || arg == "-h" || arg == "--help"
+}
+
+// printUsage lists the available subcommands.
+func printUsage() {
+	cmds := Commands()
+	names := make([]string, 0, len(cmds))
+	for name := range cmds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "usage: vpc-peering-tool [--config peering.yaml] [command] [args]")
+	fmt.Fprintf(os.Stderr, "\nThe config is --config, $%s, or the first %s in: %s\n", ConfigEnvVar, ConfigFileName, strings.Join(ConfigSearchPaths(), ", "))
+	fmt.Fprintln(os.Stderr, "\nWith no command, the stack is synthesized. Synth accepts:")
+	fmt.Fprintf(os.Stderr, "  %-12s %s\n", "--endpoint-url", "Point every provider at one endpoint, e.g. LocalStack (default $CDKTF_ENDPOINT_URL)")
+	fmt.Fprintf(os.Stderr, "  %-12s %s\n", "--watch", "Re-lint and re-synthesize on every config change, printing what changed")
+	fmt.Fprintf(os.Stderr, "  %-12s %s\n", "--accept", "Also synthesize "+AcceptStackName+", accepting requested manual peerings")
+	fmt.Fprintf(os.Stderr, "  %-12s %s\n", "--offline", "Synthesize without data sources from declared CIDRs and route tables (default $CDKTF_OFFLINE)")
+	fmt.Fprintf(os.Stderr, "  %-12s %s\n", "--verify-cidrs", "Look up VPCs with a pinned cidr and fail the plan on a mismatch (default $CDKTF_VERIFY_CIDRS)")
+	fmt.Fprintf(os.Stderr, "  %-12s %s\n", "--emit-hcl", "Also write each stack as HCL under cdktf.out/hcl for review (default $CDKTF_EMIT_HCL)")
+	fmt.Fprintf(os.Stderr, "  %-12s %s\n", "--placements", "Committed file recording the stack of every connection split by max_providers_per_stack (default $"+PlacementsEnvVar+")")
+	fmt.Fprintf(os.Stderr, "  %-12s %s\n", "--debug", "On a synth failure, print the full error and the construct tree (default $CDKTF_DEBUG)")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", name, cmds[name].Summary)
+		fmt.Fprintf(os.Stderr, "  %-12s   %s %s\n", "", name, cmds[name].Usage)
+	}
+}
+
+// loadSourcePeers loads the config and converts the connections of one source, or of all sources
+// when source is empty. It fails when nothing matches.
+func loadSourcePeers(source string) (YAMLConfig, []PeerConfig) {
+	cfg := LoadConfig(ConfigPath())
+	peers := ConvertToPeerConfigs(cfg, source)
+	if len(peers) == 0 {
+		Failf(ExitValidation, "no peers matched for source: %s", source)
+	}
+	return cfg, peers
+}
+
+// loadSynthTargets loads the config and returns the stacks a synth of source builds, selected,
+// partitioned, and split as synth does, so commands number each connection within the stack that
+// holds it. It fails when nothing matches.
+func loadSynthTargets(source, placementsPath string) (YAMLConfig, []synthTarget) {
+	cfg := LoadConfig(ConfigPath())
+	targets, err := SelectTargets(cfg, source)
+	if err != nil {
+		Fail(err)
+	}
+	if cfg.ResolveAccountIDs {
+		for _, t := range targets {
+			if err := ResolvePeerAccountIDs(t.Peers, STSAccountResolver{Settings: cfg.Provider}); err != nil {
+				Fail(err)
+			}
+		}
+	}
+	placed, err := PlacementsFor(cfg, placementsPath)
+	if err != nil {
+		Fail(err)
+	}
+	if targets, err = PlaceTargets(cfg, targets, placed); err != nil {
+		Fail(err)
+	}
+	return cfg, targets
+}
+
+// placementsFlag registers -placements, the placements file synth is given for stacks split by
+// max_providers_per_stack.
+func placementsFlag(fs *flag.FlagSet) *string {
+	return fs.String("placements", os.Getenv(PlacementsEnvVar), "placements file of stacks split by max_providers_per_stack, as given to synth")
+}
+
+// targetsAddressMap returns the AddressMap of every stack's connections, numbered within their
+// stacks, and the stack of every connection key.
+func targetsAddressMap(namer Namer, targets []synthTarget) (AddressMap, map[string]string) {
+	m := make(AddressMap)
+	stacks := make(map[string]string)
+	for _, t := range targets {
+		for key, kinds := range BuildAddressMap(namer, t.Peers) {
+			m[key] = kinds
+			stacks[key] = t.Stack
+		}
+	}
+	return m, stacks
+}
+
+// sourceArg returns the optional positional source argument, defaulting to CDKTF_SOURCE.
+func sourceArg(fs *flag.FlagSet) string {
+	if fs.NArg() > 0 {
+		return fs.Arg(0)
+	}
+	return os.Getenv("CDKTF_SOURCE")
+}
+
+// -------------------------------------------------------------------------------------------------
+// addresses
+// -------------------------------------------------------------------------------------------------
+
+// runAddresses prints or writes the AddressMap for a source, numbered within the stacks synth
+// builds, and lists the stack of each connection on stderr. Saving it before a naming or ordering change and
+// passing it back via CDKTF_MOVED_FROM lets synth generate moved blocks.
+func runAddresses(args []string) error {
+	fs := flag.NewFlagSet("addresses", flag.ContinueOnError)
+	out := fs.String("o", "", "write the mapping to this file instead of stdout")
+	placements := placementsFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, targets := loadSynthTargets(sourceArg(fs), *placements)
+	m, _ := targetsAddressMap(NewNamer(cfg.Naming), targets)
+	// On stderr, so the mapping on stdout stays valid JSON.
+	for _, t := range targets {
+		for _, peer := range t.Peers {
+			fmt.Fprintf(os.Stderr, "%s: stack %s\n", ConnectionKey(peer), t.Stack)
+		}
+	}
+
+	if *out != "" {
+		return WriteAddressMap(*out, m)
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// runVerifyAddresses compares the AddressMap of a source, numbered within the stacks synth builds,
+// with a committed baseline and fails when a connection in both would have a resource renamed, which Terraform plans as a destroy and create.
+// -update rewrites the baseline after an intended change.
+func runVerifyAddresses(args []string) error {
+	fs := flag.NewFlagSet("verify-addresses", flag.ContinueOnError)
+	baseline := fs.String("baseline", AddressesFile, "committed address mapping to compare with, created with -update (or addresses -o); a synth's cdktf.out/stacks/<stack>/"+AddressesFile+" also works")
+	update := fs.Bool("update", false, "write the current mapping to the baseline instead of comparing")
+	placements := placementsFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, targets := loadSynthTargets(sourceArg(fs), *placements)
+	current, stacks := targetsAddressMap(NewNamer(cfg.Naming), targets)
+	if *update {
+		return WriteAddressMap(*baseline, current)
+	}
+	previous, err := LoadAddressMap(*baseline)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("no baseline at %s; create and commit it with -update, or pass a synth's %s with -baseline",
+				*baseline, filepath.Join(stackOutDir(StackName), AddressesFile))
+		}
+		return err
+	}
+
+	changes := AddressChanges(previous, current)
+	if len(changes) == 0 {
+		fmt.Println("No resource address of an existing connection changed")
+		return nil
+	}
+	for _, c := range changes {
+		fmt.Printf("%s %s %s: %s -> %s\n", stacks[c.Key], c.Key, c.Kind, c.From, c.To)
+	}
+	return fmt.Errorf("%d resource address(es) changed; synth with CDKTF_MOVED_FROM=%s to move them, then run with -update", len(changes), *baseline)
+}
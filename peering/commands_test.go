@@ -0,0 +1,25 @@
+package peering
+
+import (
+	"bytes"
+	"log"
+	"testing"
+)
+
+// TestQuietLogs tests that logs are discarded while the function runs and that the writer set before
+// is restored, not replaced with stderr.
+func TestQuietLogs(t *testing.T) {
+	previous := log.Writer()
+	defer log.SetOutput(previous)
+
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	quietLogs(func() { log.Print("hidden") })
+	log.Print("shown")
+	if got := out.String(); got != "shown\n" && !bytes.HasSuffix(out.Bytes(), []byte(" shown\n")) {
+		t.Errorf("unexpected log output %q", got)
+	}
+	if log.Writer() != &out {
+		t.Error("expected the previous writer to be restored")
+	}
+}
@@ -0,0 +1,615 @@
+package peering
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Config Location
+// -------------------------------------------------------------------------------------------------
+
+const (
+	ConfigFileName = "peering.yaml"         // Name of the config file in every searched directory.
+	ConfigEnvVar   = "CDKTF_PEERING_CONFIG" // Explicit config path, also set by --config.
+	configAppDir   = "vpc-peering-tool"     // Directory of the config under the user config directory.
+)
+
+// ConfigSearchPaths returns the locations searched for the config when none is given explicitly:
+// the working directory, the user config directory ($XDG_CONFIG_HOME or ~/.config on Linux,
+// %AppData% on Windows, ~/Library/Application Support on macOS), then ~/.vpc-peering-tool.
+func ConfigSearchPaths() []string {
+	paths := []string{ConfigFileName}
+	if dir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(dir, configAppDir, ConfigFileName))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, "."+configAppDir, ConfigFileName))
+	}
+	return paths
+}
+
+// ResolveConfigPath returns the explicit path when one is given, and otherwise the first candidate
+// that exists.
+func ResolveConfigPath(explicit string, candidates []string) (string, error) {
+	if explicit != "" {
+		return explicit, nil
+	}
+	for _, path := range candidates {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no %s found (searched %s); pass --config or set %s",
+		ConfigFileName, strings.Join(candidates, ", "), ConfigEnvVar)
+}
+
+// ConfigPath locates the config from CDKTF_PEERING_CONFIG or the search paths.
+func ConfigPath() string {
+	path, err := ResolveConfigPath(os.Getenv(ConfigEnvVar), ConfigSearchPaths())
+	if err != nil {
+		Fail(WithExitCode(ExitConfig, err))
+	}
+	return path
+}
+
+// ExtractConfigFlag removes a --config (or -config) flag from the command line, wherever it appears,
+// and returns its value with the remaining arguments.
+func ExtractConfigFlag(args []string) (string, []string, error) {
+	return ExtractGlobalFlag(args, "config")
+}
+
+// ExtractGlobalFlag removes a flag that applies to every command (--name value, --name=value, or
+// the single-dash forms) from the command line, wherever it appears before "--", and returns its
+// value with the remaining arguments.
+func ExtractGlobalFlag(args []string, name string) (string, []string, error) {
+	var value string
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return value, append(rest, args[i:]...), nil
+		case arg == "--"+name || arg == "-"+name:
+			if i+1 >= len(args) {
+				return "", nil, fmt.Errorf("%s needs a value", arg)
+			}
+			i++
+			value = args[i]
+		case strings.HasPrefix(arg, "--"+name+"=") || strings.HasPrefix(arg, "-"+name+"="):
+			value = arg[strings.Index(arg, "=")+1:]
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return value, rest, nil
+}
+
+// -------------------------------------------------------------------------------------------------
+// Peer Defaults
+// -------------------------------------------------------------------------------------------------
+
+// ApplyPeerDefaults fills every peer from peer_defaults, field by field, wherever the peer does not
+// set the field itself. It works on the raw document, since a decoded peer cannot tell an explicit
+// false or empty value from an absent one. YAML anchors, aliases, and merge keys are resolved first.
+//
+//	peer_defaults:
+//	  region: us-east-1
+//	  role_arn: arn:aws:iam::111111111111:role/peering
+//	peers:
+//	  dev-peer: { vpc_id: vpc-0aaa }
+//	  dr-peer: { vpc_id: vpc-0bbb, region: us-west-2 }
+func ApplyPeerDefaults(data []byte, cfg *YAMLConfig) error {
+	if cfg.PeerDefaults == nil {
+		return nil
+	}
+	if cfg.PeerDefaults.HasAdditionalRoutes {
+		return fmt.Errorf("has_additional_routes is not supported in peer_defaults; use routes")
+	}
+	if cfg.PeerDefaults.VpcID != "" {
+		return fmt.Errorf("vpc_id cannot be defaulted")
+	}
+	if len(cfg.PeerDefaults.Cidrs) > 0 || cfg.PeerDefaults.Cidr != "" {
+		return fmt.Errorf("cidrs and cidr cannot be defaulted")
+	}
+	if cfg.PeerDefaults.MainRouteTableID != "" || len(cfg.PeerDefaults.RouteTableIDs) > 0 {
+		return fmt.Errorf("main_route_table_id and route_table_ids cannot be defaulted")
+	}
+
+	var raw struct {
+		PeerDefaults map[string]interface{}            `yaml:"peer_defaults"`
+		Peers        map[string]map[string]interface{} `yaml:"peers"`
+	}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	defaults, err := yaml.Marshal(raw.PeerDefaults)
+	if err != nil {
+		return err
+	}
+
+	for name, fields := range raw.Peers {
+		own, err := yaml.Marshal(fields)
+		if err != nil {
+			return err
+		}
+		// Decode the defaults and then the peer's own fields into a fresh value, so the peer only
+		// overwrites what it sets.
+		var peer YAMLPeer
+		if err := yaml.Unmarshal(defaults, &peer); err != nil {
+			return err
+		}
+		if err := yaml.Unmarshal(own, &peer); err != nil {
+			return fmt.Errorf("peer %q: %w", name, err)
+		}
+		// A peer's own role_arn or role_arns replaces a defaulted one of either kind.
+		if _, ok := fields["role_arn"]; ok && fields["role_arns"] == nil {
+			peer.RoleArns = nil
+		}
+		if _, ok := fields["role_arns"]; ok && fields["role_arn"] == nil {
+			peer.RoleArn = ""
+		}
+		cfg.Peers[name] = peer
+	}
+	return nil
+}
+
+// -------------------------------------------------------------------------------------------------
+// Duplicate VPCs
+// -------------------------------------------------------------------------------------------------
+
+// DuplicateVpc is a VPC ID registered under more than one peer name.
+type DuplicateVpc struct {
+	VpcID string   // The shared VPC ID.
+	Peers []string // Peer names registering it, in order.
+}
+
+// DuplicateVpcIDs returns every VPC ID registered under several peer names, in VPC ID order.
+// Connections between two such names would peer the VPC with itself, and connections from both to
+// a third peer would create two peerings between the same VPCs.
+func DuplicateVpcIDs(cfg YAMLConfig) []DuplicateVpc {
+	byVpc := make(map[string][]string)
+	for name, peer := range cfg.Peers {
+		if peer.VpcID != "" {
+			byVpc[peer.VpcID] = append(byVpc[peer.VpcID], name)
+		}
+	}
+	var out []DuplicateVpc
+	for vpcID, names := range byVpc {
+		if len(names) > 1 {
+			sort.Strings(names)
+			out = append(out, DuplicateVpc{VpcID: vpcID, Peers: names})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].VpcID < out[j].VpcID })
+	return out
+}
+
+// -------------------------------------------------------------------------------------------------
+// Peering Matrix Entries
+// -------------------------------------------------------------------------------------------------
+
+// MatrixEntry is a single target in the peering matrix. In YAML it is either a bare peer name or a
+// mapping carrying per-connection settings:
+//
+//	peering_matrix:
+//	  dev-peer:
+//	    - prod-peer
+//	    - peer: staging-peer
+//	      name_tag_template: "{{.SourceName}}<->{{.PeerName}}"
+type MatrixEntry struct {
+	Peer             string            `yaml:"peer"`                                   // Name of the target peer.
+	NameTagTemplate  string            `yaml:"name_tag_template,omitempty"`            // Overrides the config-level Name tag template.
+	DestinationCidrs []string          `yaml:"destination_cidrs,omitempty"`            // Peer-side CIDRs to route instead of the whole VPC.
+	PreferOver       string            `yaml:"prefer_over,omitempty"`                  // tgw or vpn: route the destinations as halves to win over that route.
+	ExtraRoutes      []ExtraRoute      `yaml:"extra_routes,omitempty"`                 // Destinations beyond the VPC CIDRs routed in addition.
+	SourceRoutes     *RoutingConfig    `yaml:"source_routes,omitempty"`                // Route management for the source VPC.
+	PeerRoutes       *RoutingConfig    `yaml:"peer_routes,omitempty"`                  // Route management for the peer VPC.
+	ManageRoutes     *bool             `yaml:"manage_routes,omitempty"`                // false leaves routing on both sides to another system.
+	ManagePeering    *bool             `yaml:"manage_peering,omitempty"`               // false routes through the existing peering_id instead of creating one.
+	PeeringID        string            `yaml:"peering_id,omitempty"`                   // pcx-id of a peering created elsewhere (manage_peering: false only).
+	ManageOptions    bool              `yaml:"manage_options,omitempty"`               // Manage the DNS options of an existing peering (manage_peering: false only).
+	Acceptance       string            `yaml:"acceptance,omitempty"`                   // auto (default) or manual, accepted by the accept stack after approval.
+	State            string            `yaml:"state,omitempty"`                        // present (default) or absent.
+	Deprecated       bool              `yaml:"deprecated,omitempty"`                   // Shorthand for state: absent.
+	Decommission     string            `yaml:"decommission,omitempty"`                 // What the first apply of an absent connection removes.
+	CheckIPs         *CheckIPs         `yaml:"check_ips,omitempty"`                    // Representative addresses for connectivity checks.
+	Tags             map[string]string `yaml:"tags,omitempty"`                         // Tags for both sides, over the config-level tags.
+	RequesterTags    map[string]string `yaml:"requester_tags,omitempty"`               // Tags for the requester side only.
+	AccepterTags     map[string]string `yaml:"accepter_tags,omitempty"`                // Tags for the accepter side only.
+	Enabled          *bool             `yaml:"enabled,omitempty"`                      // false keeps the entry in the config but leaves it out of synthesis.
+	Owner            string            `yaml:"owner,omitempty"`                        // Source creating the peering when both sources list each other.
+	Lifecycle        *LifecycleConfig  `yaml:"lifecycle,omitempty"`                    // ignore_changes of the connection's routes and peering.
+	CrossRegionDNS   bool              `yaml:"acknowledge_cross_region_dns,omitempty"` // Silences the warning about DNS resolution across regions.
+	ConnectivityMode string            `yaml:"connectivity_mode,omitempty"`            // peering (default) or lattice, through the lattice service network.
+	DNSProfileArn    string            `yaml:"dns_profile_arn,omitempty"`              // Route 53 Profile associated with both VPCs.
+	DependsOn        []string          `yaml:"depends_on,omitempty"`                   // Connections (<source>/<peer>) established before this one.
+	Freeze           *FreezeWindow     `yaml:"freeze,omitempty"`                       // Period during which the connection must not change.
+	OnRemove         string            `yaml:"on_remove,omitempty"`                    // destroy (default) or forget the resources of an absent connection.
+	Inspection       *InspectionConfig `yaml:"via_inspection,omitempty"`               // Route through GWLB endpoints instead of straight to the peering.
+}
+
+// Disabled reports whether the entry is kept for documentation only and not synthesized.
+func (e MatrixEntry) Disabled() bool {
+	return e.Enabled != nil && !*e.Enabled
+}
+
+// UnmarshalYAML accepts both the bare string and the mapping form of a matrix entry.
+func (e *MatrixEntry) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var name string
+	if err := unmarshal(&name); err == nil {
+		*e = MatrixEntry{Peer: name}
+		return nil
+	}
+
+	type plain MatrixEntry
+	var entry plain
+	if err := unmarshal(&entry); err != nil {
+		return err
+	}
+	if entry.Peer == "" {
+		return fmt.Errorf("peering matrix entry is missing the peer name")
+	}
+	*e = MatrixEntry(entry)
+	return nil
+}
+
+// MarshalYAML writes entries without per-connection settings back in the bare string form.
+func (e MatrixEntry) MarshalYAML() (interface{}, error) {
+	if reflect.DeepEqual(e, MatrixEntry{Peer: e.Peer}) {
+		return e.Peer, nil
+	}
+	type plain MatrixEntry
+	return plain(e), nil
+}
+
+// -------------------------------------------------------------------------------------------------
+// Label Selectors
+// -------------------------------------------------------------------------------------------------
+
+// labelSelectorPrefix marks a matrix entry that selects peers by label instead of naming one, e.g.
+// "label:team-data" or "label:team-data,prod" (all labels must match).
+const labelSelectorPrefix = "label:"
+
+// SelectorLabels returns the labels of a selector entry, or false for an entry naming a single peer.
+func (e MatrixEntry) SelectorLabels() ([]string, bool) {
+	if !strings.HasPrefix(e.Peer, labelSelectorPrefix) {
+		return nil, false
+	}
+	var labels []string
+	for _, label := range strings.Split(strings.TrimPrefix(e.Peer, labelSelectorPrefix), ",") {
+		if label = strings.TrimSpace(label); label != "" {
+			labels = append(labels, label)
+		}
+	}
+	return labels, true
+}
+
+// hasLabels reports whether a peer carries every given label.
+func hasLabels(peer YAMLPeer, labels []string) bool {
+	for _, want := range labels {
+		found := false
+		for _, label := range peer.Labels {
+			found = found || label == want
+		}
+		if !found {
+			return false
+		}
+	}
+	return len(labels) > 0
+}
+
+// ValidateSelectorNaming requires a naming pattern without {index} when the peering matrix selects
+// peers by label. Selectors expand in peer name order, so a newly labelled peer shifts the index of
+// every connection after it; with index-based construct IDs, as the legacy naming uses, the next
+// apply would replace those connections' peerings and routes.
+func ValidateSelectorNaming(cfg YAMLConfig) error {
+	if cfg.Naming.Pattern != "" && !strings.Contains(cfg.Naming.Pattern, "{index}") {
+		return nil
+	}
+	for _, source := range sortedMatrixSources(cfg) {
+		for _, entry := range cfg.PeeringMatrix[source] {
+			if _, ok := entry.SelectorLabels(); ok {
+				return fmt.Errorf("%q selects peers with %q, which requires a naming.pattern without {index} "+
+					"(e.g. \"{source}-{peer}-{kind}\"): a newly labelled peer would shift the index of the connections "+
+					"after it and replace their resources", source, entry.Peer)
+			}
+		}
+	}
+	return nil
+}
+
+// ExpandMatrixEntries replaces selector entries of a source with one entry per matching peer, in
+// name order and carrying the selector's settings. The source itself, peers already named explicitly,
+// and peers matched by an earlier selector are skipped.
+func ExpandMatrixEntries(cfg YAMLConfig, source string, entries []MatrixEntry) []MatrixEntry {
+	return expandMatrixEntries(cfg, source, entries, log.Printf)
+}
+
+// expandMatrixEntries expands selector entries, reporting the number of peers each matched to logf.
+func expandMatrixEntries(cfg YAMLConfig, source string, entries []MatrixEntry, logf func(string, ...interface{})) []MatrixEntry {
+	seen := map[string]bool{source: true}
+	for _, entry := range entries {
+		if _, ok := entry.SelectorLabels(); !ok {
+			seen[entry.Peer] = true
+		}
+	}
+
+	names := make([]string, 0, len(cfg.Peers))
+	for name := range cfg.Peers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var expanded []MatrixEntry
+	for _, entry := range entries {
+		labels, ok := entry.SelectorLabels()
+		if !ok {
+			expanded = append(expanded, entry)
+			continue
+		}
+		matched := 0
+		for _, name := range names {
+			if seen[name] || !hasLabels(cfg.Peers[name], labels) {
+				continue
+			}
+			seen[name] = true
+			matched++
+			concrete := entry
+			concrete.Peer = name
+			expanded = append(expanded, concrete)
+		}
+		logf("[convert] Selector %q of %q matched %d peers", entry.Peer, source, matched)
+	}
+	return expanded
+}
+
+// -------------------------------------------------------------------------------------------------
+// Staged Decommissioning
+// -------------------------------------------------------------------------------------------------
+
+// Connection states of a matrix entry.
+const (
+	StatePresent = "present" // Fully managed (default).
+	StateAbsent  = "absent"  // Being decommissioned: one part goes first, the rest when the entry is removed.
+)
+
+// Decommission stages select what the first apply of an absent connection removes; deleting the
+// matrix entry removes the rest. The route stages keep the peering. The peering stage removes the
+// peering and its options first and keeps the routes, which then point at the deleted peering's
+// pcx-id (blackholed) until the entry is removed.
+const (
+	DecommissionRoutes       = "routes"        // Routes on both sides (default).
+	DecommissionSourceRoutes = "source-routes" // Routes in the source VPC only.
+	DecommissionPeerRoutes   = "peer-routes"   // Routes in the peer VPC only.
+	DecommissionPeering      = "peering"       // The peering, its accepter, and options; needs peering_id.
+)
+
+// DecommissionStage returns the decommission stage of the entry, or "" when it is present.
+func (e MatrixEntry) DecommissionStage() (string, error) {
+	switch e.State {
+	case "", StatePresent:
+		if !e.Deprecated {
+			if e.Decommission != "" {
+				return "", fmt.Errorf("decommission requires state: absent")
+			}
+			return "", nil
+		}
+	case StateAbsent:
+	default:
+		return "", fmt.Errorf("unknown state %q (want present or absent)", e.State)
+	}
+
+	switch e.Decommission {
+	case "":
+		return DecommissionRoutes, nil
+	case DecommissionRoutes, DecommissionSourceRoutes, DecommissionPeerRoutes, DecommissionPeering:
+		return e.Decommission, nil
+	default:
+		return "", fmt.Errorf("unknown decommission stage %q (want routes, source-routes, peer-routes, or peering)", e.Decommission)
+	}
+}
+
+// DecommissionRouting disables the routing of the sides a decommission stage removes. The peering
+// stage keeps the routes of both sides.
+func DecommissionRouting(stage string, source, peer RoutingConfig) (RoutingConfig, RoutingConfig) {
+	if stage == DecommissionPeering {
+		return source, peer
+	}
+	none := RoutingConfig{Strategy: RoutingNone}
+	if stage != DecommissionPeerRoutes {
+		source = none
+	}
+	if stage != DecommissionSourceRoutes {
+		peer = none
+	}
+	return source, peer
+}
+
+// -------------------------------------------------------------------------------------------------
+// Duplicate VPC Pair Merging
+// -------------------------------------------------------------------------------------------------
+
+// MergeDuplicatePairs folds connections between the same two VPCs into one, in either direction,
+// since AWS allows a single peering per VPC pair. The first entry keeps its name and position; an
+// entry in the opposite direction is turned around first. Destination CIDRs are unioned (an entry
+// routing the whole VPC CIDR wins over subsets), DNS resolution is enabled if any merged entry
+// enables it, and each side keeps the broadest routing strategy. Connections both sources list
+// and whose peering is already shared between their stacks are left apart. Lifecycle ignore_changes
+// lists are unioned, manual acceptance applies if any entry asks for it, and the cross-region DNS
+// caveats count as acknowledged only if every entry enabling DNS resolution acknowledges them. It
+// fails when the filtered routing of two entries cannot be combined, or when they set different
+// external peerings, DNS profiles, or check addresses.
+func MergeDuplicatePairs(peers []PeerConfig) ([]PeerConfig, error) {
+	var merged []PeerConfig
+	index := make(map[string]int)
+
+	for _, peer := range peers {
+		pair := peerPair(peer.SourceVpcID, peer.PeerVpcID)
+		i, seen := index[pair]
+		if !seen || peer.SharedPeering || merged[i].SharedPeering {
+			if !seen {
+				index[pair] = len(merged)
+			}
+			merged = append(merged, peer)
+			continue
+		}
+
+		first := &merged[i]
+		log.Printf("[convert] Merging %q -> %q into %q -> %q (same VPC pair %s)",
+			peer.SourceName, peer.Name, first.SourceName, first.Name, pair)
+		if peer.SourceVpcID != first.SourceVpcID {
+			peer = reversePeerConfig(peer)
+		}
+		if first.EnableDNSResolution != peer.EnableDNSResolution ||
+			!reflect.DeepEqual(first.SourceRouting, peer.SourceRouting) ||
+			!reflect.DeepEqual(first.PeerRouting, peer.PeerRouting) {
+			log.Printf("[convert] WARNING: merged entries for %s disagree on DNS or routing; using the broadest settings", pair)
+		}
+
+		if err := mergeSettings(first, peer); err != nil {
+			return nil, fmt.Errorf("cannot merge %q -> %q into %q -> %q: %w", peer.SourceName, peer.Name, first.SourceName, first.Name, err)
+		}
+		if first.EnableDNSResolution || peer.EnableDNSResolution {
+			first.CrossRegionDNSAcked = (first.CrossRegionDNSAcked || !first.EnableDNSResolution) &&
+				(peer.CrossRegionDNSAcked || !peer.EnableDNSResolution)
+		}
+		first.EnableDNSResolution = first.EnableDNSResolution || peer.EnableDNSResolution
+		if first.EnableDNSResolution {
+			first.DNSResolutionTags = nil
+		}
+		first.HasExtraPeerRouteTables = first.HasExtraPeerRouteTables || peer.HasExtraPeerRouteTables
+		firstRoutesSource := first.SourceRouting.Strategy != RoutingNone
+		var err error
+		if first.SourceRouting, err = broaderRouting(first.SourceRouting, peer.SourceRouting); err == nil {
+			first.PeerRouting, err = broaderRouting(first.PeerRouting, peer.PeerRouting)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot merge %q -> %q into %q -> %q: %w", peer.SourceName, peer.Name, first.SourceName, first.Name, err)
+		}
+		if peer.Decommission == "" {
+			first.Decommission, first.RetiredPeeringID = "", ""
+		}
+		first.Tags = mergeTags(first.Tags, peer.Tags)
+		first.RequesterTags = mergeTags(first.RequesterTags, peer.RequesterTags)
+		first.AccepterTags = mergeTags(first.AccepterTags, peer.AccepterTags)
+		if first.NameTagTemplate == "" {
+			first.NameTagTemplate = peer.NameTagTemplate
+		}
+		// Destination CIDRs only count for entries routing the source side: an entry with none
+		// routing there neither widens nor narrows the other's destinations.
+		switch {
+		case peer.SourceRouting.Strategy == RoutingNone:
+		case !firstRoutesSource:
+			first.DestinationCidrs = peer.DestinationCidrs
+		case len(first.DestinationCidrs) == 0 || len(peer.DestinationCidrs) == 0:
+			first.DestinationCidrs = nil
+		default:
+			first.DestinationCidrs = unionStrings(first.DestinationCidrs, peer.DestinationCidrs)
+		}
+		first.SourceExtraCidrs = unionStrings(first.SourceExtraCidrs, peer.SourceExtraCidrs)
+		first.PeerExtraCidrs = unionStrings(first.PeerExtraCidrs, peer.PeerExtraCidrs)
+		first.DependsOn = unionStrings(first.DependsOn, peer.DependsOn)
+		if first.Inspection == nil {
+			first.Inspection = peer.Inspection
+		} else if peer.Inspection != nil && !reflect.DeepEqual(first.Inspection, peer.Inspection) {
+			log.Printf("[convert] WARNING: merged entries for %s disagree on via_inspection; using %q -> %q's", pair, first.SourceName, first.Name)
+		}
+		for _, w := range peer.Freezes {
+			if !containsFreeze(first.Freezes, w) {
+				first.Freezes = append(first.Freezes, w)
+			}
+		}
+	}
+	return merged, nil
+}
+
+// mergeSettings merges the settings of a connection that hold a single value into the one it is
+// merged into: one entry setting a value is enough, and two setting different values conflict.
+// Lifecycle lists are unioned and manual acceptance is kept if either entry asks for it.
+func mergeSettings(first *PeerConfig, peer PeerConfig) error {
+	for _, setting := range []struct {
+		field string
+		dst   *string
+		value string
+	}{
+		{"peering_id", &first.ExternalPeeringID, peer.ExternalPeeringID},
+		{"dns_profile_arn", &first.DNSProfileArn, peer.DNSProfileArn},
+		{"check_ips.source", &first.CheckIPs.Source, peer.CheckIPs.Source},
+		{"check_ips.peer", &first.CheckIPs.Peer, peer.CheckIPs.Peer},
+	} {
+		switch {
+		case setting.value == "" || setting.value == *setting.dst:
+		case *setting.dst == "":
+			*setting.dst = setting.value
+		default:
+			return fmt.Errorf("%s %q conflicts with %q", setting.field, setting.value, *setting.dst)
+		}
+	}
+	first.ManageExternalOptions = first.ManageExternalOptions || peer.ManageExternalOptions
+	first.ManualAcceptance = first.ManualAcceptance || peer.ManualAcceptance
+	if first.ManualAcceptance && first.ExternalPeeringID != "" {
+		return fmt.Errorf("acceptance: manual conflicts with peering_id %q, a peering created elsewhere", first.ExternalPeeringID)
+	}
+	first.Lifecycle.IgnoreChanges = unionIgnoreChanges(first.Lifecycle.IgnoreChanges, peer.Lifecycle.IgnoreChanges)
+	first.Lifecycle.PeeringIgnoreChanges = unionIgnoreChanges(first.Lifecycle.PeeringIgnoreChanges, peer.Lifecycle.PeeringIgnoreChanges)
+	return nil
+}
+
+// unionIgnoreChanges returns the union of two lifecycle ignore_changes lists, or the all keyword
+// alone when either holds it.
+func unionIgnoreChanges(a, b []string) []string {
+	for _, attr := range append(append([]string(nil), a...), b...) {
+		if attr == LifecycleIgnoreAll {
+			return []string{LifecycleIgnoreAll}
+		}
+	}
+	return unionStrings(a, b)
+}
+
+// reversePeerConfig turns a connection around for merging into one between the same VPCs in the
+// opposite direction: the settings of each side move to the other. Its destination CIDRs scoped
+// the old source side's routes, so they are dropped; unless its new source side routes nothing,
+// it routes the whole CIDR of the VPC it now peers with.
+func reversePeerConfig(peer PeerConfig) PeerConfig {
+	peer.SourceVpcID, peer.PeerVpcID = peer.PeerVpcID, peer.SourceVpcID
+	peer.SourceRouting, peer.PeerRouting = peer.PeerRouting, peer.SourceRouting
+	peer.SourceExtraCidrs, peer.PeerExtraCidrs = peer.PeerExtraCidrs, peer.SourceExtraCidrs
+	peer.RequesterTags, peer.AccepterTags = peer.AccepterTags, peer.RequesterTags
+	peer.CheckIPs.Source, peer.CheckIPs.Peer = peer.CheckIPs.Peer, peer.CheckIPs.Source
+	peer.DestinationCidrs = nil
+	return peer
+}
+
+// containsFreeze reports whether a connection's freezes already hold a window.
+func containsFreeze(windows []FreezeWindow, w FreezeWindow) bool {
+	for _, x := range windows {
+		if x.From == w.From && x.To == w.To && x.Reason == w.Reason {
+			return true
+		}
+	}
+	return false
+}
+
+// unionStrings appends the values of b missing from a, preserving order.
+func unionStrings(a, b []string) []string {
+	seen := make(map[string]bool, len(a))
+	out := append([]string(nil), a...)
+	for _, v := range a {
+		seen[v] = true
+	}
+	for _, v := range b {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,465 @@
+package peering
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+// TestMatrixEntryUnmarshal tests that matrix entries accept both bare names and mappings.
+func TestMatrixEntryUnmarshal(t *testing.T) {
+	data := `
+peering_matrix:
+  dev:
+    - prod
+    - peer: staging
+      name_tag_template: "{{.PeerName}}"
+`
+	var cfg YAMLConfig
+	if err := yaml.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	entries := cfg.PeeringMatrix["dev"]
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Peer != "prod" || entries[1].Peer != "staging" {
+		t.Errorf("unexpected peers: %q, %q", entries[0].Peer, entries[1].Peer)
+	}
+	if entries[1].NameTagTemplate != "{{.PeerName}}" {
+		t.Errorf("unexpected template: %q", entries[1].NameTagTemplate)
+	}
+
+	bad := "peering_matrix:\n  dev:\n    - name_tag_template: x\n"
+	if err := yaml.Unmarshal([]byte(bad), &cfg); err == nil {
+		t.Errorf("expected error for entry without peer")
+	}
+}
+
+// TestMergeDuplicatePairs tests that entries for the same VPC pair become one connection.
+func TestMergeDuplicatePairs(t *testing.T) {
+	peers := []PeerConfig{
+		{SourceName: "hub", Name: "shared-team-a", SourceVpcID: "vpc-1", PeerVpcID: "vpc-2", DestinationCidrs: []string{"10.2.0.0/24"}},
+		{SourceName: "hub", Name: "other", SourceVpcID: "vpc-1", PeerVpcID: "vpc-3"},
+		{SourceName: "hub", Name: "shared-team-b", SourceVpcID: "vpc-1", PeerVpcID: "vpc-2", DestinationCidrs: []string{"10.2.1.0/24", "10.2.0.0/24"}, EnableDNSResolution: true},
+	}
+	merged, err := MergeDuplicatePairs(peers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged) != 2 {
+		t.Fatalf("expected 2 connections, got %d", len(merged))
+	}
+	if merged[0].Name != "shared-team-a" || !merged[0].EnableDNSResolution {
+		t.Errorf("unexpected merged connection: %+v", merged[0])
+	}
+	if got := merged[0].DestinationCidrs; len(got) != 2 || got[0] != "10.2.0.0/24" || got[1] != "10.2.1.0/24" {
+		t.Errorf("unexpected merged CIDRs: %v", got)
+	}
+
+	peers[2].DestinationCidrs = nil
+	if merged, _ := MergeDuplicatePairs(peers); merged[0].DestinationCidrs != nil {
+		t.Errorf("expected whole-VPC routing to win, got %v", merged[0].DestinationCidrs)
+	}
+}
+
+// TestMergeReversedPairs tests that entries for the same VPC pair in opposite directions become one
+// connection, with the settings of the reversed entry applied to the other side.
+func TestMergeReversedPairs(t *testing.T) {
+	main := RoutingConfig{Strategy: RoutingMain}
+	all := RoutingConfig{Strategy: RoutingAll}
+	peers := []PeerConfig{
+		{SourceName: "dev", Name: "prod", SourceVpcID: "vpc-1", PeerVpcID: "vpc-2", SourceRouting: main, PeerRouting: main,
+			DestinationCidrs: []string{"10.2.0.0/24"}, SourceExtraCidrs: []string{"10.9.0.0/16"}},
+		{SourceName: "prod-alias", Name: "dev-alias", SourceVpcID: "vpc-2", PeerVpcID: "vpc-1", SourceRouting: all, PeerRouting: main,
+			DestinationCidrs: []string{"10.1.0.0/24"}, SourceExtraCidrs: []string{"10.8.0.0/16"}},
+	}
+	merged, err := MergeDuplicatePairs(peers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged) != 1 {
+		t.Fatalf("expected 1 connection, got %d", len(merged))
+	}
+	got := merged[0]
+	if got.SourceName != "dev" || got.Name != "prod" || got.SourceVpcID != "vpc-1" {
+		t.Errorf("expected the first entry's direction, got %+v", got)
+	}
+	if got.SourceRouting.Strategy != RoutingMain || got.PeerRouting.Strategy != RoutingAll {
+		t.Errorf("unexpected routing: source %+v, peer %+v", got.SourceRouting, got.PeerRouting)
+	}
+	if got.DestinationCidrs != nil {
+		t.Errorf("expected the reversed entry to route the whole peer CIDR, got %v", got.DestinationCidrs)
+	}
+	if !reflect.DeepEqual(got.PeerExtraCidrs, []string{"10.8.0.0/16"}) || !reflect.DeepEqual(got.SourceExtraCidrs, []string{"10.9.0.0/16"}) {
+		t.Errorf("unexpected extra CIDRs: source %v, peer %v", got.SourceExtraCidrs, got.PeerExtraCidrs)
+	}
+
+	peers[0].SharedPeering, peers[1].SharedPeering = true, true
+	if merged, _ := MergeDuplicatePairs(peers); len(merged) != 2 {
+		t.Errorf("expected connections sharing their peering to stay apart, got %d", len(merged))
+	}
+
+	// A reversed entry with peer_routes: none routes nothing in the first entry's source VPC, so the
+	// first entry's destination CIDRs stay scoped.
+	peers[0].SharedPeering, peers[1].SharedPeering = false, false
+	peers[1].PeerRouting = RoutingConfig{Strategy: RoutingNone}
+	if merged, err = MergeDuplicatePairs(peers); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(merged[0].DestinationCidrs, []string{"10.2.0.0/24"}) {
+		t.Errorf("expected the scoped destination CIDRs to be kept, got %v", merged[0].DestinationCidrs)
+	}
+
+	// The other way around, the first entry routes nothing in its source VPC and the reversed
+	// entry's whole-VPC routing applies.
+	peers[0].SourceRouting, peers[1].PeerRouting = RoutingConfig{Strategy: RoutingNone}, main
+	if merged, err = MergeDuplicatePairs(peers); err != nil {
+		t.Fatal(err)
+	}
+	if merged[0].DestinationCidrs != nil || merged[0].SourceRouting.Strategy != RoutingMain {
+		t.Errorf("expected the reversed entry's routing of the whole peer CIDR, got %v, %+v", merged[0].DestinationCidrs, merged[0].SourceRouting)
+	}
+}
+
+// TestMergeFilteredRouting tests that filtered routing merges into the filter selecting both sets of
+// subnets, and that filters neither covers are rejected.
+func TestMergeFilteredRouting(t *testing.T) {
+	filtered := func(tags map[string]string) RoutingConfig {
+		return RoutingConfig{Strategy: RoutingFiltered, SubnetTags: tags}
+	}
+	tests := []struct {
+		a, b    RoutingConfig
+		want    RoutingConfig
+		wantErr bool
+	}{
+		{filtered(map[string]string{"tier": "app"}), filtered(map[string]string{"tier": "app"}), filtered(map[string]string{"tier": "app"}), false},
+		{filtered(map[string]string{"tier": "app", "zone": "a"}), filtered(map[string]string{"tier": "app"}), filtered(map[string]string{"tier": "app"}), false},
+		{filtered(map[string]string{"tier": "app"}), filtered(map[string]string{"tier": "data"}), RoutingConfig{}, true},
+		{filtered(map[string]string{"tier": "app"}), RoutingConfig{Strategy: RoutingAll}, RoutingConfig{Strategy: RoutingAll}, false},
+	}
+	for _, tt := range tests {
+		peers := []PeerConfig{
+			{SourceName: "dev", Name: "prod", SourceVpcID: "vpc-1", PeerVpcID: "vpc-2", SourceRouting: tt.a},
+			{SourceName: "dev", Name: "prod-b", SourceVpcID: "vpc-1", PeerVpcID: "vpc-2", SourceRouting: tt.b},
+		}
+		merged, err := MergeDuplicatePairs(peers)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("merging %+v and %+v: error = %v", tt.a, tt.b, err)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(merged[0].SourceRouting, tt.want) {
+			t.Errorf("merging %+v and %+v = %+v, want %+v", tt.a, tt.b, merged[0].SourceRouting, tt.want)
+		}
+	}
+}
+
+// TestMergeDuplicatePairsSettings tests how each single-valued setting of merged entries combines:
+// unioned, kept from the entry setting it, or rejected when the entries disagree.
+func TestMergeDuplicatePairsSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    func(*PeerConfig)
+		check   func(PeerConfig) bool
+		wantErr bool
+	}{
+		{"peering id from one entry",
+			func(p *PeerConfig) {}, func(p *PeerConfig) { p.ExternalPeeringID = "pcx-1" },
+			func(p PeerConfig) bool { return p.ExternalPeeringID == "pcx-1" }, false},
+		{"conflicting peering ids",
+			func(p *PeerConfig) { p.ExternalPeeringID = "pcx-1" }, func(p *PeerConfig) { p.ExternalPeeringID = "pcx-2" },
+			nil, true},
+		{"manual acceptance from either entry",
+			func(p *PeerConfig) {}, func(p *PeerConfig) { p.ManualAcceptance = true },
+			func(p PeerConfig) bool { return p.ManualAcceptance }, false},
+		{"manual acceptance of an external peering",
+			func(p *PeerConfig) { p.ExternalPeeringID = "pcx-1" }, func(p *PeerConfig) { p.ManualAcceptance = true },
+			nil, true},
+		{"lifecycle lists unioned",
+			func(p *PeerConfig) { p.Lifecycle.IgnoreChanges = []string{"route_table_id"} },
+			func(p *PeerConfig) {
+				p.Lifecycle = LifecycleConfig{IgnoreChanges: []string{"destination_cidr_block"}, PeeringIgnoreChanges: []string{"tags"}}
+			},
+			func(p PeerConfig) bool {
+				return reflect.DeepEqual(p.Lifecycle, LifecycleConfig{
+					IgnoreChanges:        []string{"route_table_id", "destination_cidr_block"},
+					PeeringIgnoreChanges: []string{"tags"},
+				})
+			}, false},
+		{"lifecycle all wins",
+			func(p *PeerConfig) { p.Lifecycle.IgnoreChanges = []string{"route_table_id"} },
+			func(p *PeerConfig) { p.Lifecycle.IgnoreChanges = []string{LifecycleIgnoreAll} },
+			func(p PeerConfig) bool {
+				return reflect.DeepEqual(p.Lifecycle.IgnoreChanges, []string{LifecycleIgnoreAll})
+			}, false},
+		{"dns profile from one entry",
+			func(p *PeerConfig) {}, func(p *PeerConfig) { p.DNSProfileArn = "arn:profile-1" },
+			func(p PeerConfig) bool { return p.DNSProfileArn == "arn:profile-1" }, false},
+		{"conflicting dns profiles",
+			func(p *PeerConfig) { p.DNSProfileArn = "arn:profile-1" }, func(p *PeerConfig) { p.DNSProfileArn = "arn:profile-2" },
+			nil, true},
+		{"check addresses combined",
+			func(p *PeerConfig) { p.CheckIPs.Source = "10.1.0.10" }, func(p *PeerConfig) { p.CheckIPs.Peer = "10.2.0.10" },
+			func(p PeerConfig) bool { return p.CheckIPs == CheckIPs{Source: "10.1.0.10", Peer: "10.2.0.10"} }, false},
+		{"conflicting check addresses",
+			func(p *PeerConfig) { p.CheckIPs.Source = "10.1.0.10" }, func(p *PeerConfig) { p.CheckIPs.Source = "10.1.0.11" },
+			nil, true},
+		{"cross-region dns acknowledged by the entry enabling it",
+			func(p *PeerConfig) {}, func(p *PeerConfig) { p.EnableDNSResolution, p.CrossRegionDNSAcked = true, true },
+			func(p PeerConfig) bool { return p.EnableDNSResolution && p.CrossRegionDNSAcked }, false},
+		{"cross-region dns acknowledged by another entry",
+			func(p *PeerConfig) { p.CrossRegionDNSAcked = true }, func(p *PeerConfig) { p.EnableDNSResolution = true },
+			func(p PeerConfig) bool { return p.EnableDNSResolution && !p.CrossRegionDNSAcked }, false},
+	}
+	for _, tt := range tests {
+		peers := []PeerConfig{
+			{SourceName: "dev", Name: "prod", SourceVpcID: "vpc-1", PeerVpcID: "vpc-2"},
+			{SourceName: "dev", Name: "prod-b", SourceVpcID: "vpc-1", PeerVpcID: "vpc-2"},
+		}
+		tt.a(&peers[0])
+		tt.b(&peers[1])
+		merged, err := MergeDuplicatePairs(peers)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.wantErr, err)
+			continue
+		}
+		if !tt.wantErr && !tt.check(merged[0]) {
+			t.Errorf("%s: unexpected merged connection %+v", tt.name, merged[0])
+		}
+	}
+
+	// Check addresses of a reversed entry move to the other side.
+	peers := []PeerConfig{
+		{SourceName: "dev", Name: "prod", SourceVpcID: "vpc-1", PeerVpcID: "vpc-2"},
+		{SourceName: "prod", Name: "dev", SourceVpcID: "vpc-2", PeerVpcID: "vpc-1", CheckIPs: CheckIPs{Source: "10.2.0.10"}},
+	}
+	if merged, err := MergeDuplicatePairs(peers); err != nil || merged[0].CheckIPs != (CheckIPs{Peer: "10.2.0.10"}) {
+		t.Errorf("expected the reversed check address on the peer side, got %+v (%v)", merged, err)
+	}
+}
+
+// TestDecommission tests that absent connections keep the peering but drop the selected routes.
+func TestDecommission(t *testing.T) {
+	tests := []struct {
+		entry      MatrixEntry
+		stage      string
+		sourceKept bool
+		peerKept   bool
+		wantErr    bool
+	}{
+		{MatrixEntry{}, "", true, true, false},
+		{MatrixEntry{State: StateAbsent}, DecommissionRoutes, false, false, false},
+		{MatrixEntry{Deprecated: true, Decommission: DecommissionSourceRoutes}, DecommissionSourceRoutes, false, true, false},
+		{MatrixEntry{State: StateAbsent, Decommission: DecommissionPeerRoutes}, DecommissionPeerRoutes, true, false, false},
+		{MatrixEntry{State: "gone"}, "", false, false, true},
+		{MatrixEntry{Decommission: DecommissionRoutes}, "", false, false, true},
+	}
+	for _, tt := range tests {
+		stage, err := tt.entry.DecommissionStage()
+		if (err != nil) != tt.wantErr || stage != tt.stage {
+			t.Errorf("DecommissionStage(%+v) = %q, %v", tt.entry, stage, err)
+			continue
+		}
+		if tt.wantErr || stage == "" {
+			continue
+		}
+
+		main := RoutingConfig{Strategy: RoutingMain}
+		peer := PeerConfig{SourceName: "dev", Name: "prod", Decommission: stage}
+		peer.SourceRouting, peer.PeerRouting = DecommissionRouting(stage, main, main)
+		addresses := ConnectionAddresses(LegacyNamer{}, ConnectionNameContext(0, peer), peer)
+		if _, ok := addresses[KindPeering]; !ok {
+			t.Errorf("stage %s: expected the peering to be kept", stage)
+		}
+		if _, ok := addresses[KindSourceMainRoute]; ok != tt.sourceKept {
+			t.Errorf("stage %s: source route kept = %t, want %t", stage, ok, tt.sourceKept)
+		}
+		if _, ok := addresses[KindPeerMainRoute]; ok != tt.peerKept {
+			t.Errorf("stage %s: peer route kept = %t, want %t", stage, ok, tt.peerKept)
+		}
+	}
+}
+
+// TestDecommissionPeering tests that the peering stage removes the peering ahead of its routes.
+func TestDecommissionPeering(t *testing.T) {
+	cfg := YAMLConfig{Peers: map[string]YAMLPeer{"foo": {VpcID: "vpc-1"}, "bar": {VpcID: "vpc-2"}}}
+	no := false
+	tests := []struct {
+		entry MatrixEntry
+		valid bool
+	}{
+		{MatrixEntry{Peer: "bar", State: StateAbsent, Decommission: DecommissionPeering, PeeringID: "pcx-0abc123"}, true},
+		{MatrixEntry{Peer: "bar", State: StateAbsent, Decommission: DecommissionPeering}, false},
+		{MatrixEntry{Peer: "bar", State: StateAbsent, Decommission: DecommissionPeering, PeeringID: "vpc-0abc123"}, false},
+		{MatrixEntry{Peer: "bar", State: StateAbsent, Decommission: DecommissionPeering, PeeringID: "pcx-0abc123", ManagePeering: &no}, false},
+		{MatrixEntry{Peer: "bar", State: StateAbsent, Decommission: DecommissionPeering, PeeringID: "pcx-0abc123", Acceptance: AcceptanceManual}, false},
+		{MatrixEntry{Peer: "bar", State: StateAbsent, PeeringID: "pcx-0abc123"}, false},
+	}
+	for _, tt := range tests {
+		peer, err := ResolveConnection(cfg, "foo", tt.entry)
+		if (err == nil) != tt.valid {
+			t.Errorf("ResolveConnection(%+v) error = %v, want valid=%v", tt.entry, err, tt.valid)
+			continue
+		}
+		if !tt.valid {
+			continue
+		}
+		if peer.RetiredPeeringID != tt.entry.PeeringID || peer.ExternalPeeringID != "" {
+			t.Errorf("unexpected peering IDs: retired %q, external %q", peer.RetiredPeeringID, peer.ExternalPeeringID)
+		}
+
+		addresses := ConnectionAddresses(LegacyNamer{}, ConnectionNameContext(0, peer), peer)
+		for _, kind := range []string{KindPeering, KindAccepter, KindOptions} {
+			if _, ok := addresses[kind]; ok {
+				t.Errorf("expected %s to be removed, got %v", kind, addresses)
+			}
+		}
+		if _, ok := addresses[KindSourceMainRoute]; !ok {
+			t.Errorf("expected the source routes to be kept, got %v", addresses)
+		}
+		if _, ok := addresses[KindPeerMainRoute]; !ok {
+			t.Errorf("expected the peer routes to be kept, got %v", addresses)
+		}
+	}
+}
+
+// TestExpandMatrixEntries tests that label selectors expand into one connection per matching peer.
+func TestExpandMatrixEntries(t *testing.T) {
+	cfg := YAMLConfig{Peers: map[string]YAMLPeer{
+		"hub":     {Labels: []string{"team-data"}},
+		"etl":     {Labels: []string{"team-data", "prod"}},
+		"lake":    {Labels: []string{"team-data"}},
+		"billing": {Labels: []string{"prod"}},
+	}}
+	entries := []MatrixEntry{
+		{Peer: "lake", DestinationCidrs: []string{"10.0.0.0/24"}},
+		{Peer: "label:team-data", NameTagTemplate: "{{.PeerName}}"},
+		{Peer: "label:prod"},
+	}
+
+	got := ExpandMatrixEntries(cfg, "hub", entries)
+	var names []string
+	for _, e := range got {
+		names = append(names, e.Peer)
+	}
+	if strings.Join(names, ",") != "lake,etl,billing" {
+		t.Fatalf("unexpected expansion: %v", names)
+	}
+	if got[1].NameTagTemplate != "{{.PeerName}}" || len(got[0].DestinationCidrs) != 1 {
+		t.Errorf("expected entry settings to be kept: %+v", got)
+	}
+
+	if got := ExpandMatrixEntries(cfg, "hub", []MatrixEntry{{Peer: "label:team-data,prod"}}); len(got) != 1 || got[0].Peer != "etl" {
+		t.Errorf("expected only etl to carry both labels, got %+v", got)
+	}
+}
+
+// TestValidateSelectorNaming tests that label selectors require a naming pattern without {index}.
+func TestValidateSelectorNaming(t *testing.T) {
+	cfg := YAMLConfig{PeeringMatrix: map[string][]MatrixEntry{"hub": {{Peer: "lake"}, {Peer: "label:team-data"}}}}
+	for _, pattern := range []string{"", "{kind}{index}"} {
+		cfg.Naming.Pattern = pattern
+		if err := ValidateSelectorNaming(cfg); err == nil || !strings.Contains(err.Error(), "label:team-data") {
+			t.Errorf("pattern %q: expected a selector naming error, got %v", pattern, err)
+		}
+	}
+	cfg.Naming.Pattern = "{source}-{peer}-{kind}"
+	if err := ValidateSelectorNaming(cfg); err != nil {
+		t.Errorf("index-free pattern: %v", err)
+	}
+	if err := ValidateSelectorNaming(YAMLConfig{PeeringMatrix: map[string][]MatrixEntry{"hub": {{Peer: "lake"}}}}); err != nil {
+		t.Errorf("no selectors: %v", err)
+	}
+}
+
+// TestSelectorAddressesStable tests that labelling a new peer adds its connection without changing
+// the addresses of the connections a selector already matched.
+func TestSelectorAddressesStable(t *testing.T) {
+	cfg := YAMLConfig{
+		Naming: NamingConfig{Pattern: "{source}-{peer}-{kind}"},
+		Peers: map[string]YAMLPeer{
+			"hub":  {VpcID: "vpc-1", Region: "us-east-1"},
+			"etl":  {VpcID: "vpc-2", Region: "us-east-1", Labels: []string{"team-data"}},
+			"lake": {VpcID: "vpc-3", Region: "us-east-1", Labels: []string{"team-data"}},
+		},
+		PeeringMatrix: map[string][]MatrixEntry{"hub": {{Peer: "label:team-data"}}},
+	}
+	namer := NewNamer(cfg.Naming)
+	before := BuildAddressMap(namer, ConvertToPeerConfigs(cfg, "hub"))
+
+	// "feeds" sorts between etl and lake, shifting lake's index.
+	cfg.Peers["feeds"] = YAMLPeer{VpcID: "vpc-4", Region: "us-east-1", Labels: []string{"team-data"}}
+	after := BuildAddressMap(namer, ConvertToPeerConfigs(cfg, "hub"))
+
+	if len(after) != len(before)+1 {
+		t.Fatalf("expected one new connection, got %v", after)
+	}
+	for key, addresses := range before {
+		if !reflect.DeepEqual(after[key], addresses) {
+			t.Errorf("%s: addresses changed from %v to %v", key, addresses, after[key])
+		}
+	}
+}
+
+// TestApplyPeerDefaults tests that peers inherit peer_defaults field by field, including through
+// anchors and merge keys, and that explicit values win.
+func TestApplyPeerDefaults(t *testing.T) {
+	data := []byte(`
+shared: &shared
+  environment: shared
+peer_defaults:
+  region: us-east-1
+  role_arn: arn:aws:iam::111111111111:role/peering
+  dns_resolution: true
+  labels: [default]
+peers:
+  dev-peer:
+    vpc_id: vpc-1
+  dr-peer:
+    vpc_id: vpc-2
+    region: us-west-2
+    dns_resolution: false
+    labels: []
+  shared-peer:
+    <<: *shared
+    vpc_id: vpc-3
+  empty-peer:
+peering_matrix: {}
+`)
+	var cfg YAMLConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyPeerDefaults(data, &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		region string
+		dns    bool
+		labels int
+		env    string
+	}{
+		{"dev-peer", "us-east-1", true, 1, ""},
+		{"dr-peer", "us-west-2", false, 0, ""},
+		{"shared-peer", "us-east-1", true, 1, "shared"},
+		{"empty-peer", "us-east-1", true, 1, ""},
+	}
+	for _, tt := range tests {
+		p := cfg.Peers[tt.name]
+		if p.Region != tt.region || p.DNSResolution != tt.dns || len(p.Labels) != tt.labels || p.Environment != tt.env {
+			t.Errorf("%s: unexpected peer %+v", tt.name, p)
+		}
+		if p.RoleArn != "arn:aws:iam::111111111111:role/peering" {
+			t.Errorf("%s: role_arn not inherited: %q", tt.name, p.RoleArn)
+		}
+	}
+
+	bad := YAMLConfig{PeerDefaults: &YAMLPeer{VpcID: "vpc-1"}}
+	if err := ApplyPeerDefaults([]byte("peer_defaults: {vpc_id: vpc-1}"), &bad); err == nil {
+		t.Error("expected an error for a defaulted vpc_id")
+	}
+}
@@ -0,0 +1,220 @@
+package peering
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// -------------------------------------------------------------------------------------------------
+// diff-config
+// -------------------------------------------------------------------------------------------------
+
+// Kinds of connection changes between two configs.
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+)
+
+// FieldChange is one resolved setting of a connection that differs between two configs.
+type FieldChange struct {
+	Field  string `json:"field"`  // Setting name, in snake case (e.g. destination_cidrs).
+	Before string `json:"before"` // Value in the old config, "" if unset.
+	After  string `json:"after"`  // Value in the new config, "" if unset.
+}
+
+// ConnectionChange is a connection added, removed, or modified between two configs.
+type ConnectionChange struct {
+	Key    string        `json:"connection"`       // Connection key, <source>/<peer>.
+	Change string        `json:"change"`           // ChangeAdded, ChangeRemoved, or ChangeModified.
+	Fields []FieldChange `json:"fields,omitempty"` // Changed settings of a modified connection, sorted.
+}
+
+// DiffConfigs compares the connections two configs resolve to, so formatting, key order, anchors,
+// peer_defaults, selectors, and schema versions do not show up unless they change a connection.
+// Changes are sorted by connection key.
+func DiffConfigs(before, after YAMLConfig) []ConnectionChange {
+	was, now := connectionSettings(before), connectionSettings(after)
+	keys := make([]string, 0, len(was)+len(now))
+	for key := range was {
+		keys = append(keys, key)
+	}
+	for key := range now {
+		if _, ok := was[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var changes []ConnectionChange
+	for _, key := range keys {
+		a, inOld := was[key]
+		b, inNew := now[key]
+		switch {
+		case !inOld:
+			changes = append(changes, ConnectionChange{Key: key, Change: ChangeAdded})
+		case !inNew:
+			changes = append(changes, ConnectionChange{Key: key, Change: ChangeRemoved})
+		default:
+			fields := diffSettings(a, b)
+			if len(fields) > 0 {
+				changes = append(changes, ConnectionChange{Key: key, Change: ChangeModified, Fields: fields})
+			}
+		}
+	}
+	return changes
+}
+
+// connectionSettings resolves every matrix entry of a config to its settings, keyed by connection.
+func connectionSettings(cfg YAMLConfig) map[string]map[string]string {
+	out := make(map[string]map[string]string)
+	for _, row := range BuildMatrixRows(cfg) {
+		settings := map[string]string{"enabled": fmt.Sprint(!row.Disabled)}
+		if row.Err != nil {
+			settings["error"] = row.Err.Error()
+		}
+		peer := row.Config
+		peer.SourceRegion, peer.PeerRegion = ResolveRegion(peer.SourceRegion), ResolveRegion(peer.PeerRegion)
+		v := reflect.ValueOf(peer)
+		for i := 0; i < v.NumField(); i++ {
+			name := v.Type().Field(i).Name
+			if name == "SourceName" || name == "Name" {
+				continue
+			}
+			if value := formatSetting(v.Field(i)); value != "" {
+				settings[snakeCase(name)] = value
+			}
+		}
+		out[row.Source+"/"+row.Peer] = settings
+	}
+	return out
+}
+
+// formatSetting renders a setting for comparison and display: "" for zero values and empty
+// collections, so an omitted list equals an empty one, and JSON otherwise.
+func formatSetting(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		if v.Len() == 0 {
+			return ""
+		}
+	case reflect.String:
+		return v.String()
+	}
+	if v.IsZero() {
+		return ""
+	}
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return fmt.Sprint(v.Interface())
+	}
+	return string(data)
+}
+
+// diffSettings returns the settings that differ between two resolved connections, sorted by name.
+func diffSettings(before, after map[string]string) []FieldChange {
+	names := make(map[string]bool)
+	for name := range before {
+		names[name] = true
+	}
+	for name := range after {
+		names[name] = true
+	}
+	var fields []FieldChange
+	for name := range names {
+		if before[name] != after[name] {
+			fields = append(fields, FieldChange{Field: name, Before: before[name], After: after[name]})
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+	return fields
+}
+
+// snakeCase converts a Go field name to snake case, keeping acronyms together: SourceVpcID becomes
+// source_vpc_id and DNSProfileArn dns_profile_arn.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// PrintConfigDiff writes the changes one connection per line, prefixed with + (added), - (removed),
+// or ~ (modified) and followed by the changed settings of modified connections.
+func PrintConfigDiff(w io.Writer, changes []ConnectionChange) {
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "No connection changes")
+		return
+	}
+	counts := make(map[string]int)
+	marks := map[string]string{ChangeAdded: "+", ChangeRemoved: "-", ChangeModified: "~"}
+	for _, c := range changes {
+		counts[c.Change]++
+		fmt.Fprintf(w, "%s %s\n", marks[c.Change], c.Key)
+		for _, f := range c.Fields {
+			fmt.Fprintf(w, "    %s: %s -> %s\n", f.Field, orNone(f.Before), orNone(f.After))
+		}
+	}
+	fmt.Fprintf(w, "\n%d added, %d removed, %d modified\n", counts[ChangeAdded], counts[ChangeRemoved], counts[ChangeModified])
+}
+
+// orNone returns "(none)" for an unset setting.
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+// runDiffConfig prints the semantic difference between two configs, connection by connection.
+func runDiffConfig(args []string) error {
+	fs := flag.NewFlagSet("diff-config", flag.ContinueOnError)
+	format := fs.String("format", "text", "output format: text or json")
+	exitCode := fs.Bool("exit-code", false, "exit with 1 when any connection changed")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("expected <old.yaml> <new.yaml>, got %d arguments", fs.NArg())
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q (use text or json)", *format)
+	}
+	before, after := LoadConfig(fs.Arg(0)), LoadConfig(fs.Arg(1))
+
+	var changes []ConnectionChange
+	quietLogs(func() { changes = DiffConfigs(before, after) })
+
+	if *format == "json" {
+		if changes == nil {
+			changes = []ConnectionChange{}
+		}
+		data, err := json.MarshalIndent(changes, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	} else {
+		PrintConfigDiff(os.Stdout, changes)
+	}
+	if *exitCode && len(changes) > 0 {
+		return fmt.Errorf("%d connection(s) changed", len(changes))
+	}
+	return nil
+}
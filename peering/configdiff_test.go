@@ -0,0 +1,91 @@
+package peering
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+// TestDiffConfigs tests that connections are compared by their resolved settings, so formatting
+// and equivalent spellings do not count as changes.
+func TestDiffConfigs(t *testing.T) {
+	parse := func(data string) YAMLConfig {
+		var cfg YAMLConfig
+		if err := yaml.Unmarshal([]byte(data), &cfg); err != nil {
+			t.Fatal(err)
+		}
+		return cfg
+	}
+	before := parse(`
+peers:
+  dev: { vpc_id: vpc-1, region: us-west-2 }
+  prod: { vpc_id: vpc-2 }
+  staging: { vpc_id: vpc-3 }
+peering_matrix:
+  dev: [prod, staging]
+`)
+	after := parse(`
+peers:
+  dev:
+    vpc_id: vpc-1
+  prod:
+    vpc_id: vpc-2
+    dns_resolution: true
+  qa:
+    vpc_id: vpc-4
+peering_matrix:
+  dev:
+    - peer: prod
+      destination_cidrs: [10.2.0.0/16]
+    - qa
+`)
+
+	if changes := DiffConfigs(before, before); len(changes) != 0 {
+		t.Errorf("expected no changes against itself, got %+v", changes)
+	}
+
+	changes := DiffConfigs(before, after)
+	want := []ConnectionChange{
+		{Key: "dev/prod", Change: ChangeModified, Fields: []FieldChange{
+			{Field: "destination_cidrs", Before: "", After: `["10.2.0.0/16"]`},
+			{Field: "enable_dns_resolution", Before: "", After: "true"},
+		}},
+		{Key: "dev/qa", Change: ChangeAdded},
+		{Key: "dev/staging", Change: ChangeRemoved},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("expected %+v, got %+v", want, changes)
+	}
+
+	var buf bytes.Buffer
+	PrintConfigDiff(&buf, changes)
+	for _, line := range []string{
+		"~ dev/prod",
+		"    destination_cidrs: (none) -> [\"10.2.0.0/16\"]",
+		"+ dev/qa",
+		"- dev/staging",
+		"1 added, 1 removed, 1 modified",
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("output does not contain %q:\n%s", line, buf.String())
+		}
+	}
+}
+
+// TestSnakeCase tests the conversion of PeerConfig field names.
+func TestSnakeCase(t *testing.T) {
+	for name, want := range map[string]string{
+		"SourceVpcID":         "source_vpc_id",
+		"DNSProfileArn":       "dns_profile_arn",
+		"EnableDNSResolution": "enable_dns_resolution",
+		"CheckIPs":            "check_i_ps",
+		"Tags":                "tags",
+	} {
+		if got := snakeCase(name); got != want {
+			t.Errorf("snakeCase(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package peering
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Config Verification
+// -------------------------------------------------------------------------------------------------
+
+// Environment variables enabling config verification, also set by --verify-config and
+// --config-sha256.
+const (
+	ConfigVerifyEnvVar         = "CDKTF_CONFIG_VERIFY"          // sha256, cosign, or gpg; no verification if empty.
+	ConfigSHA256EnvVar         = "CDKTF_CONFIG_SHA256"          // Pinned hex digests, comma-separated, documents must match in sha256 mode.
+	ConfigCosignKeyEnvVar      = "CDKTF_CONFIG_COSIGN_KEY"      // Public key cosign verifies with (path, KMS URI, or k8s secret).
+	ConfigGPGKeyringEnvVar     = "CDKTF_CONFIG_GPG_KEYRING"     // Keyring file gpg verifies with instead of the default keyring.
+	ConfigGPGFingerprintEnvVar = "CDKTF_CONFIG_GPG_FINGERPRINT" // Fingerprint of the key signatures must be made with.
+)
+
+// Config verification modes and the suffix of the signature each one expects next to the config.
+const (
+	VerifySHA256 = "sha256" // No file: the digest is pinned in CDKTF_CONFIG_SHA256.
+	VerifyCosign = "cosign" // <config>.sig: cosign sign-blob signature.
+	VerifyGPG    = "gpg"    // <config>.asc: detached, armored GPG signature.
+)
+
+// verifySuffixes maps each verification mode to the suffix of its sidecar file ("" for none).
+var verifySuffixes = map[string]string{VerifySHA256: "", VerifyCosign: ".sig", VerifyGPG: ".asc"}
+
+// signatureVerifiers check a signature over a document; replaced in tests.
+var signatureVerifiers = map[string]func(data, signature []byte) error{
+	VerifyCosign: cosignVerify,
+	VerifyGPG:    gpgVerify,
+}
+
+// ValidateConfigVerify rejects unknown verification modes.
+func ValidateConfigVerify(mode string) error {
+	if _, ok := verifySuffixes[mode]; mode != "" && !ok {
+		return fmt.Errorf("unknown --verify-config %q (use %s, %s, or %s)", mode, VerifySHA256, VerifyCosign, VerifyGPG)
+	}
+	return nil
+}
+
+// VerifyDocument checks a document read from location, before anything is parsed, so a tampered or
+// truncated config is refused: in sha256 mode against the digests pinned in CDKTF_CONFIG_SHA256,
+// otherwise against the signature published next to it (location plus the mode's suffix, read the
+// same way). A checksum published next to the config is not trusted, since whoever can rewrite the
+// config can rewrite it too. The raw bytes are verified: a SOPS-encrypted config is checked as
+// published, before decryption. It does nothing when mode is empty.
+func VerifyDocument(location string, data []byte, mode string, s3 ObjectReader) error {
+	if mode == "" {
+		return nil
+	}
+	if err := ValidateConfigVerify(mode); err != nil {
+		return WithExitCode(ExitConfig, err)
+	}
+	if mode == VerifySHA256 {
+		if err := VerifyChecksum(data, os.Getenv(ConfigSHA256EnvVar)); err != nil {
+			return WithExitCode(ExitConfig, fmt.Errorf("%s failed sha256 verification: %w", location, err))
+		}
+		return nil
+	}
+	sidecar := location + verifySuffixes[mode]
+	proof, err := ReadDocument(sidecar, s3)
+	if err != nil {
+		return WithExitCode(ExitConfig, fmt.Errorf("cannot verify %s: %s: %w", location, sidecar, err))
+	}
+	if err := signatureVerifiers[mode](data, proof); err != nil {
+		return WithExitCode(ExitConfig, fmt.Errorf("%s failed %s verification against %s: %w", location, mode, sidecar, err))
+	}
+	return nil
+}
+
+// VerifyChecksum checks that the SHA-256 digest of data is one of the pinned hex digests,
+// separated by commas or spaces (one per document verified, e.g. the config and its manifests).
+func VerifyChecksum(data []byte, pinned string) error {
+	digests := strings.FieldsFunc(strings.ToLower(pinned), func(r rune) bool { return r == ',' || r == ' ' })
+	if len(digests) == 0 {
+		return fmt.Errorf("no digest is pinned; set %s or --config-sha256 to the sha256 of the reviewed config", ConfigSHA256EnvVar)
+	}
+	digest := sha256.Sum256(data)
+	got := hex.EncodeToString(digest[:])
+	for _, want := range digests {
+		if got == want {
+			return nil
+		}
+	}
+	return fmt.Errorf("sha256 is %s, which is not pinned", got)
+}
+
+// cosignVerify checks a cosign sign-blob signature with the key in CDKTF_CONFIG_COSIGN_KEY. The
+// document is passed on stdin; only the signature is written to a temporary file.
+func cosignVerify(data, signature []byte) error {
+	key := os.Getenv(ConfigCosignKeyEnvVar)
+	if key == "" {
+		return fmt.Errorf("%s is not set", ConfigCosignKeyEnvVar)
+	}
+	return runVerifier(data, signature, func(sigPath string) *exec.Cmd {
+		return exec.Command("cosign", "verify-blob", "--key", key, "--signature", sigPath, "/dev/stdin")
+	})
+}
+
+// gpgVerify checks a detached GPG signature with the keys of CDKTF_CONFIG_GPG_KEYRING only, and, when
+// CDKTF_CONFIG_GPG_FINGERPRINT is set, that the key it names made it. One of them is required: any
+// key in the default keyring is not a trust decision.
+func gpgVerify(data, signature []byte) error {
+	keyring, fingerprint := os.Getenv(ConfigGPGKeyringEnvVar), os.Getenv(ConfigGPGFingerprintEnvVar)
+	if keyring == "" && fingerprint == "" {
+		return fmt.Errorf("set %s or %s to the key configs must be signed with", ConfigGPGKeyringEnvVar, ConfigGPGFingerprintEnvVar)
+	}
+	var status bytes.Buffer
+	err := runVerifier(data, signature, func(sigPath string) *exec.Cmd {
+		args := []string{"--batch", "--status-fd", "1"}
+		if keyring != "" {
+			args = append(args, "--no-default-keyring", "--keyring", keyring)
+		}
+		cmd := exec.Command("gpg", append(args, "--verify", sigPath, "-")...)
+		cmd.Stdout = &status
+		return cmd
+	})
+	if err != nil || fingerprint == "" {
+		return err
+	}
+	return checkGPGSigner(status.String(), fingerprint)
+}
+
+// checkGPGSigner checks that the VALIDSIG line of gpg's status output names the fingerprint, of the
+// signing key or of its primary key; spaces in the fingerprint are ignored.
+func checkGPGSigner(status, fingerprint string) error {
+	want := strings.ToUpper(strings.ReplaceAll(fingerprint, " ", ""))
+	for _, line := range strings.Split(status, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[0] != "[GNUPG:]" || fields[1] != "VALIDSIG" {
+			continue
+		}
+		if fields[2] == want || fields[len(fields)-1] == want {
+			return nil
+		}
+		return fmt.Errorf("signed by key %s, not %s", fields[2], want)
+	}
+	return fmt.Errorf("gpg reported no valid signature")
+}
+
+// runVerifier writes the signature to a temporary file and runs the command built for it with the
+// document on stdin.
+func runVerifier(data, signature []byte, command func(sigPath string) *exec.Cmd) error {
+	f, err := os.CreateTemp("", "config-signature-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(signature); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	var stderr bytes.Buffer
+	cmd := command(f.Name())
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package peering
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestVerifyDocument tests that configs are checked against the pinned digests or the signature next
+// to them, and that a checksum next to them is not trusted.
+func TestVerifyDocument(t *testing.T) {
+	data := []byte("peers: {}\n")
+	objects := fakeObjects{
+		"s3://configs/peering.yaml.sha256": "0000  peering.yaml\n",
+		"s3://configs/peering.yaml.sig":    "good",
+		"s3://configs/tampered.yaml.sig":   "bad",
+		"s3://configs/peering.yaml.asc":    "good",
+	}
+	t.Setenv(ConfigSHA256EnvVar, "")
+	defer func(v map[string]func(data, signature []byte) error) { signatureVerifiers = v }(signatureVerifiers)
+	fake := func(_, signature []byte) error {
+		if string(signature) != "good" {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+	signatureVerifiers = map[string]func(data, signature []byte) error{VerifyCosign: fake, VerifyGPG: fake}
+
+	cases := []struct {
+		location, mode string
+		data           []byte
+		want           string // Error substring, "" for success.
+	}{
+		{"s3://configs/peering.yaml", "", data, ""},
+		{"s3://configs/peering.yaml", VerifySHA256, data, "no digest is pinned"},
+		{"s3://configs/peering.yaml", VerifyCosign, data, ""},
+		{"s3://configs/tampered.yaml", VerifyCosign, data, "invalid signature"},
+		{"s3://configs/peering.yaml", VerifyGPG, data, ""},
+		{"s3://configs/peering.yaml", "md5", data, "unknown --verify-config"},
+	}
+	for _, tc := range cases {
+		err := VerifyDocument(tc.location, tc.data, tc.mode, objects)
+		switch {
+		case tc.want == "" && err != nil:
+			t.Errorf("%s (%s): unexpected error: %v", tc.location, tc.mode, err)
+		case tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)):
+			t.Errorf("%s (%s): expected error containing %q, got %v", tc.location, tc.mode, tc.want, err)
+		case err != nil && ExitCodeOf(err) != ExitConfig:
+			t.Errorf("%s (%s): expected exit code %d, got %d", tc.location, tc.mode, ExitConfig, ExitCodeOf(err))
+		}
+	}
+}
+
+// TestVerifyChecksum tests that documents must match one of the pinned digests.
+func TestVerifyChecksum(t *testing.T) {
+	data := []byte("peers: {}\n")
+	digest := sha256.Sum256(data)
+	sum := hex.EncodeToString(digest[:])
+	other := strings.Repeat("0", 64)
+
+	for _, pinned := range []string{sum, strings.ToUpper(sum), other + "," + sum, other + " " + sum} {
+		if err := VerifyChecksum(data, pinned); err != nil {
+			t.Errorf("pinned %q: unexpected error: %v", pinned, err)
+		}
+	}
+	if err := VerifyChecksum(data[:5], sum); err == nil || !strings.Contains(err.Error(), "not pinned") {
+		t.Errorf("expected a truncated config to be refused, got %v", err)
+	}
+
+	t.Setenv(ConfigSHA256EnvVar, sum)
+	if err := VerifyDocument("s3://configs/peering.yaml", data, VerifySHA256, fakeObjects{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// TestGPGVerifyRequiresKey tests that gpg verification needs a keyring or fingerprint, and that the
+// fingerprint is checked against the signing key gpg reports.
+func TestGPGVerifyRequiresKey(t *testing.T) {
+	t.Setenv(ConfigGPGKeyringEnvVar, "")
+	t.Setenv(ConfigGPGFingerprintEnvVar, "")
+	if err := gpgVerify([]byte("peers: {}\n"), []byte("sig")); err == nil || !strings.Contains(err.Error(), ConfigGPGKeyringEnvVar) {
+		t.Errorf("expected a missing key to be refused, got %v", err)
+	}
+
+	status := "[GNUPG:] NEWSIG\n" +
+		"[GNUPG:] VALIDSIG AAAA1111 2026-01-01 1767225600 0 4 0 1 10 00 BBBB2222\n"
+	for _, fingerprint := range []string{"AAAA1111", "bbbb 2222"} {
+		if err := checkGPGSigner(status, fingerprint); err != nil {
+			t.Errorf("fingerprint %q: unexpected error: %v", fingerprint, err)
+		}
+	}
+	if err := checkGPGSigner(status, "CCCC3333"); err == nil || !strings.Contains(err.Error(), "signed by key AAAA1111") {
+		t.Errorf("expected another signer to be refused, got %v", err)
+	}
+	if err := checkGPGSigner("[GNUPG:] BADSIG AAAA1111\n", "AAAA1111"); err == nil {
+		t.Error("expected a status without VALIDSIG to be refused")
+	}
+}
@@ -0,0 +1,229 @@
+package peering
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Ownership Conflicts
+// -------------------------------------------------------------------------------------------------
+
+// Kinds of AWS objects a state can claim.
+const (
+	ClaimPeering  = "peering"  // A peering connection, by pcx-id.
+	ClaimAccepter = "accepter" // The accepter side of a peering connection, by pcx-id.
+	ClaimOptions  = "options"  // The options of a peering connection, by pcx-id.
+	ClaimRoute    = "route"    // A route, by route table and destination.
+)
+
+// OwnedObject identifies an AWS object independently of the Terraform address managing it.
+type OwnedObject struct {
+	Kind string `json:"kind"` // One of the Claim constants.
+	ID   string `json:"id"`   // pcx-id, or "<route-table-id> <destination>" for routes.
+}
+
+// Claim is a state address that manages an object.
+type Claim struct {
+	State   string `json:"state"`   // Where the state was read from.
+	Address string `json:"address"` // Resource address in that state.
+}
+
+// Conflict is an object managed by this stack's state and by at least one other state.
+type Conflict struct {
+	Object OwnedObject `json:"object"`
+	Claims []Claim     `json:"claims"` // This stack's claims first, then the others' in state order.
+}
+
+// routeDestination returns the destination of a route, whichever attribute carries it.
+func routeDestination(attrs map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if v := stringAttr(attrs, key); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// StateClaims returns every peering and route object a state manages, with the addresses managing
+// it. Routes count whether they are aws_route resources or inline routes of aws_route_table and
+// aws_default_route_table, since either kind removes the other's routes on apply.
+func StateClaims(snap *StateSnapshot) map[OwnedObject][]string {
+	claims := make(map[OwnedObject][]string)
+	claim := func(kind, id, address string) {
+		if id == "" {
+			return
+		}
+		obj := OwnedObject{Kind: kind, ID: id}
+		claims[obj] = append(claims[obj], address)
+	}
+
+	for _, r := range snap.Resources {
+		for _, attrs := range r.Instances {
+			switch r.Type {
+			case "aws_vpc_peering_connection":
+				claim(ClaimPeering, stringAttr(attrs, "id"), r.Address())
+			case "aws_vpc_peering_connection_accepter":
+				claim(ClaimAccepter, stringAttr(attrs, "vpc_peering_connection_id"), r.Address())
+			case "aws_vpc_peering_connection_options":
+				claim(ClaimOptions, stringAttr(attrs, "vpc_peering_connection_id"), r.Address())
+			case "aws_route":
+				dest := routeDestination(attrs, "destination_cidr_block", "destination_ipv6_cidr_block", "destination_prefix_list_id")
+				if dest != "" {
+					claim(ClaimRoute, stringAttr(attrs, "route_table_id")+" "+dest, r.Address())
+				}
+			case "aws_route_table", "aws_default_route_table":
+				routes, _ := attrs["route"].([]interface{})
+				for _, route := range routes {
+					fields, ok := route.(map[string]interface{})
+					if !ok {
+						continue
+					}
+					dest := routeDestination(fields, "cidr_block", "ipv6_cidr_block", "destination_prefix_list_id")
+					if dest != "" {
+						claim(ClaimRoute, stringAttr(attrs, "id")+" "+dest, r.Address())
+					}
+				}
+			}
+		}
+	}
+	return claims
+}
+
+// FindConflicts reports the objects this stack's state manages that any other state also manages,
+// sorted by kind and ID.
+func FindConflicts(ours *StateSnapshot, others []*StateSnapshot) []Conflict {
+	otherClaims := make([]map[OwnedObject][]string, len(others))
+	for i, snap := range others {
+		otherClaims[i] = StateClaims(snap)
+	}
+
+	var conflicts []Conflict
+	for obj, addresses := range StateClaims(ours) {
+		var theirs []Claim
+		for i, claims := range otherClaims {
+			for _, address := range claims[obj] {
+				theirs = append(theirs, Claim{State: others[i].Source, Address: address})
+			}
+		}
+		if len(theirs) == 0 {
+			continue
+		}
+		c := Conflict{Object: obj}
+		for _, address := range addresses {
+			c.Claims = append(c.Claims, Claim{State: ours.Source, Address: address})
+		}
+		c.Claims = append(c.Claims, theirs...)
+		conflicts = append(conflicts, c)
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		a, b := conflicts[i].Object, conflicts[j].Object
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.ID < b.ID
+	})
+	return conflicts
+}
+
+// PrintConflicts writes one block per conflicting object listing every address managing it.
+func PrintConflicts(w io.Writer, conflicts []Conflict) {
+	if len(conflicts) == 0 {
+		fmt.Fprintln(w, "No conflicts.")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, c := range conflicts {
+		fmt.Fprintf(tw, "CONFLICT\t%s %s\n", c.Object.Kind, c.Object.ID)
+		for _, claim := range c.Claims {
+			fmt.Fprintf(tw, "\t%s\t%s\n", claim.Address, claim.State)
+		}
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\n%d object(s) managed by more than one state.\n", len(conflicts))
+}
+
+// readStateArg reads a state from a raw state file, or pulls it from an initialized stack
+// directory through its backend.
+func readStateArg(path string) (*StateSnapshot, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return ReadStateFromTerraform(path)
+	}
+	return ReadStateFile(path)
+}
+
+// -------------------------------------------------------------------------------------------------
+// conflicts
+// -------------------------------------------------------------------------------------------------
+
+// runConflicts compares this stack's state with other Terraform states and fails when any peering
+// or route is managed by both, before two stacks start reverting each other's changes.
+func runConflicts(args []string) error {
+	fs := flag.NewFlagSet("conflicts", flag.ContinueOnError)
+	statePath := fs.String("state", "", "read this raw state file instead of pulling from the backend")
+	dir := fs.String("dir", filepath.Join("cdktf.out", "stacks", StackName), "initialized stack directory to pull state from")
+	format := fs.String("format", "text", "output format: text or json")
+	var others []string
+	fs.Func("other", "another state file or initialized stack directory to compare against (repeatable)", func(path string) error {
+		others = append(others, path)
+		return nil
+	})
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(others) == 0 {
+		return fmt.Errorf("at least one -other state is required")
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q (want text or json)", *format)
+	}
+
+	var ours *StateSnapshot
+	var err error
+	if *statePath != "" {
+		ours, err = ReadStateFile(*statePath)
+	} else {
+		ours, err = ReadStateFromTerraform(*dir)
+	}
+	if err != nil {
+		return err
+	}
+
+	snaps := make([]*StateSnapshot, 0, len(others))
+	for _, path := range others {
+		snap, err := readStateArg(path)
+		if err != nil {
+			return err
+		}
+		snaps = append(snaps, snap)
+	}
+
+	conflicts := FindConflicts(ours, snaps)
+	if *format == "json" {
+		if conflicts == nil {
+			conflicts = []Conflict{}
+		}
+		data, err := json.MarshalIndent(conflicts, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	} else {
+		PrintConflicts(os.Stdout, conflicts)
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%d object(s) managed by more than one state", len(conflicts))
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package peering
+
+import "testing"
+
+// TestFindConflicts tests detection of peerings and routes managed by more than one state.
+func TestFindConflicts(t *testing.T) {
+	ours, err := ParseStateFile([]byte(`{
+  "resources": [
+    {"mode": "managed", "type": "aws_vpc_peering_connection", "name": "VpcPeering0",
+     "instances": [{"attributes": {"id": "pcx-1"}}]},
+    {"mode": "managed", "type": "aws_route", "name": "SourceToPeerAllRoute0",
+     "instances": [
+       {"attributes": {"route_table_id": "rtb-a", "destination_cidr_block": "10.1.0.0/16"}},
+       {"attributes": {"route_table_id": "rtb-b", "destination_cidr_block": "10.1.0.0/16"}}
+     ]},
+    {"mode": "managed", "type": "aws_vpc_peering_connection_options", "name": "VpcPeeringOptions0",
+     "instances": [{"attributes": {"vpc_peering_connection_id": "pcx-1"}}]}
+  ]
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ours.Source = "ours"
+
+	other, err := ParseStateFile([]byte(`{
+  "resources": [
+    {"mode": "managed", "type": "aws_vpc_peering_connection_accepter", "name": "accept",
+     "instances": [{"attributes": {"vpc_peering_connection_id": "pcx-1"}}]},
+    {"mode": "managed", "type": "aws_vpc_peering_connection_options", "name": "options",
+     "instances": [{"attributes": {"vpc_peering_connection_id": "pcx-1"}}]},
+    {"mode": "managed", "type": "aws_route_table", "name": "private",
+     "instances": [{"attributes": {"id": "rtb-b", "route": [
+       {"cidr_block": "10.1.0.0/16", "vpc_peering_connection_id": "pcx-1"},
+       {"cidr_block": "0.0.0.0/0", "nat_gateway_id": "nat-1"}
+     ]}}]},
+    {"mode": "managed", "type": "aws_route", "name": "other",
+     "instances": [{"attributes": {"route_table_id": "rtb-a", "destination_cidr_block": "10.2.0.0/16"}}]}
+  ]
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.Source = "network-core"
+
+	conflicts := FindConflicts(ours, []*StateSnapshot{other})
+	if len(conflicts) != 2 {
+		t.Fatalf("expected options and one route to conflict, got %+v", conflicts)
+	}
+	if got := conflicts[0].Object; got != (OwnedObject{ClaimOptions, "pcx-1"}) {
+		t.Errorf("unexpected first conflict: %+v", got)
+	}
+	route := conflicts[1]
+	if route.Object != (OwnedObject{ClaimRoute, "rtb-b 10.1.0.0/16"}) {
+		t.Errorf("unexpected route conflict: %+v", route.Object)
+	}
+	want := []Claim{
+		{State: "ours", Address: "aws_route.SourceToPeerAllRoute0"},
+		{State: "network-core", Address: "aws_route_table.private"},
+	}
+	if len(route.Claims) != 2 || route.Claims[0] != want[0] || route.Claims[1] != want[1] {
+		t.Errorf("unexpected claims: %+v", route.Claims)
+	}
+
+	if got := FindConflicts(ours, nil); len(got) != 0 {
+		t.Errorf("expected no conflicts without other states, got %+v", got)
+	}
+}
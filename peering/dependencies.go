@@ -0,0 +1,211 @@
+package peering
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Connection Dependencies
+// -------------------------------------------------------------------------------------------------
+
+// ValidateConnectionDependencies checks the depends_on lists of the peering matrix: every entry
+// must name another enabled connection as <source>/<peer>, neither side may connect through
+// lattice (there is no peering to order), and the dependencies must not form a cycle.
+func ValidateConnectionDependencies(cfg YAMLConfig) error {
+	sources := make([]string, 0, len(cfg.PeeringMatrix))
+	for source := range cfg.PeeringMatrix {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	entries := make(map[string]MatrixEntry)
+	var keys []string
+	for _, source := range sources {
+		for _, entry := range expandMatrixEntries(cfg, source, cfg.PeeringMatrix[source], func(string, ...interface{}) {}) {
+			key := source + "/" + entry.Peer
+			entries[key] = entry
+			keys = append(keys, key)
+		}
+	}
+
+	edges := make(map[string][]string)
+	for _, key := range keys {
+		entry := entries[key]
+		for _, dep := range entry.DependsOn {
+			target, ok := entries[dep]
+			switch {
+			case dep == key:
+				return fmt.Errorf("%s depends on itself", key)
+			case !ok:
+				return fmt.Errorf("%s depends on %q, which is not a connection of the peering matrix (want <source>/<peer>)", key, dep)
+			case target.Disabled():
+				return fmt.Errorf("%s depends on %s, which is disabled", key, dep)
+			case entry.ConnectivityMode == ConnectivityLattice || target.ConnectivityMode == ConnectivityLattice:
+				return fmt.Errorf("%s depends on %s, but lattice connections have no peering to order", key, dep)
+			}
+			edges[key] = append(edges[key], dep)
+		}
+	}
+	if cycle := findCycle(keys, edges); cycle != nil {
+		return fmt.Errorf("depends_on forms a cycle: %s", strings.Join(cycle, " -> "))
+	}
+	return nil
+}
+
+// findCycle returns a cycle of the graph, starting and ending with the same node, or nil if it has
+// none. Nodes are visited in the given order, so the cycle reported is stable.
+func findCycle(nodes []string, edges map[string][]string) []string {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(nodes))
+	var path []string
+	var visit func(node string) []string
+	visit = func(node string) []string {
+		state[node] = visiting
+		path = append(path, node)
+		for _, next := range edges[node] {
+			switch state[next] {
+			case visiting:
+				for i, n := range path {
+					if n == next {
+						return append(append([]string(nil), path[i:]...), next)
+					}
+				}
+			case unvisited:
+				if cycle := visit(next); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[node] = done
+		return nil
+	}
+	for _, node := range nodes {
+		if state[node] == unvisited {
+			if cycle := visit(node); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// ConnectionDependencies returns, for each connection of a stack, the indices of the connections of
+// the same stack it depends on. Dependencies in other stacks are left to StackDependencies.
+func ConnectionDependencies(peers []PeerConfig) [][]int {
+	index := make(map[string]int, len(peers))
+	for i, peer := range peers {
+		index[ConnectionKey(peer)] = i
+	}
+	deps := make([][]int, len(peers))
+	for i, peer := range peers {
+		for _, key := range peer.DependsOn {
+			if j, ok := index[key]; ok {
+				deps[i] = append(deps[i], j)
+			}
+		}
+	}
+	return deps
+}
+
+// dependent is a resource or data source whose depends_on can be extended after it is created.
+type dependent interface {
+	DependsOn() *[]*string
+	SetDependsOn(val *[]*string)
+}
+
+// WireConnectionDependencies makes the first construct of every connection with depends_on, its
+// peering or the lookup of an external one, depend on the peering, accepter, and options of each
+// connection it names in the same stack. Everything else of the connection already depends on that
+// first construct.
+func WireConnectionDependencies(connections []ConnectionResources) {
+	peers := make([]PeerConfig, len(connections))
+	for i, c := range connections {
+		peers[i] = c.Peer
+	}
+	for i, deps := range ConnectionDependencies(peers) {
+		if len(deps) == 0 {
+			continue
+		}
+		var first dependent
+		switch p := connections[i].Peering; {
+		case p.Peering != nil:
+			first = p.Peering
+		case p.Data != nil:
+			first = p.Data
+		default:
+			continue
+		}
+		var fqns []*string
+		if existing := first.DependsOn(); existing != nil {
+			fqns = append(fqns, *existing...)
+		}
+		for _, j := range deps {
+			for _, d := range connections[j].Peering.Dependables() {
+				fqns = append(fqns, d.Fqn())
+			}
+		}
+		first.SetDependsOn(&fqns)
+	}
+}
+
+// Dependables returns the constructs that establish the peering: the peering or its lookup, the
+// accepter, and the options of both sides, whichever exist.
+func (p PeeringResources) Dependables() []cdktf.ITerraformDependable {
+	var out []cdktf.ITerraformDependable
+	for _, d := range []cdktf.ITerraformDependable{p.Peering, p.Data, p.Accepter, p.Options, p.AccepterOptions} {
+		if d != nil {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// StackDependencies returns, for each target, the indices of the targets whose stacks must be
+// applied before its own because one of its connections depends on a connection of theirs.
+// Dependencies on connections outside every target, such as another source's when CDKTF_SOURCE
+// selects one, are logged as a reminder to apply that stack first. Stacks depending on each other
+// are an error: split the dependency chain differently or keep the connections in one stack.
+func StackDependencies(targets []synthTarget) (map[int][]int, error) {
+	stackOf := make(map[string]int)
+	for i, t := range targets {
+		for _, peer := range t.Peers {
+			stackOf[ConnectionKey(peer)] = i
+		}
+	}
+
+	deps := make(map[int][]int)
+	names := make([]string, len(targets))
+	edges := make(map[string][]string)
+	for i, t := range targets {
+		names[i] = t.Stack
+		seen := make(map[int]bool)
+		for _, peer := range t.Peers {
+			for _, key := range peer.DependsOn {
+				j, ok := stackOf[key]
+				switch {
+				case !ok:
+					log.Printf("[synth] %s depends on %s, which is not synthesized now; apply its stack first", ConnectionKey(peer), key)
+				case j != i && !seen[j]:
+					seen[j] = true
+					deps[i] = append(deps[i], j)
+					edges[t.Stack] = append(edges[t.Stack], targets[j].Stack)
+				}
+			}
+		}
+		sort.Ints(deps[i])
+	}
+	if cycle := findCycle(names, edges); cycle != nil {
+		return nil, fmt.Errorf("depends_on makes stacks depend on each other: %s", strings.Join(cycle, " -> "))
+	}
+	return deps, nil
+}
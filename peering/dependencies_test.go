@@ -0,0 +1,92 @@
+package peering
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestValidateConnectionDependencies tests that depends_on must name other enabled peering
+// connections of the matrix without forming a cycle.
+func TestValidateConnectionDependencies(t *testing.T) {
+	disabled := false
+	newConfig := func(entries map[string][]MatrixEntry) YAMLConfig {
+		return YAMLConfig{
+			Peers:         map[string]YAMLPeer{"shared": {VpcID: "vpc-1"}, "app": {VpcID: "vpc-2"}, "data": {VpcID: "vpc-3"}, "ops": {VpcID: "vpc-4"}},
+			PeeringMatrix: entries,
+		}
+	}
+
+	valid := newConfig(map[string][]MatrixEntry{
+		"shared": {{Peer: "ops"}},
+		"app":    {{Peer: "shared", DependsOn: []string{"shared/ops"}}, {Peer: "data", DependsOn: []string{"app/shared", "shared/ops"}}},
+	})
+	if err := ValidateConnectionDependencies(valid); err != nil {
+		t.Errorf("valid dependencies: %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		entries map[string][]MatrixEntry
+		want    string
+	}{
+		{"self", map[string][]MatrixEntry{"app": {{Peer: "shared", DependsOn: []string{"app/shared"}}}}, "app/shared depends on itself"},
+		{"unknown", map[string][]MatrixEntry{"app": {{Peer: "shared", DependsOn: []string{"shared"}}}}, `depends on "shared", which is not a connection`},
+		{"disabled", map[string][]MatrixEntry{
+			"app":    {{Peer: "shared", DependsOn: []string{"shared/ops"}}},
+			"shared": {{Peer: "ops", Enabled: &disabled}},
+		}, "which is disabled"},
+		{"lattice", map[string][]MatrixEntry{
+			"app":    {{Peer: "shared", DependsOn: []string{"shared/ops"}}},
+			"shared": {{Peer: "ops", ConnectivityMode: ConnectivityLattice}},
+		}, "lattice connections have no peering to order"},
+		{"cycle", map[string][]MatrixEntry{
+			"app":    {{Peer: "shared", DependsOn: []string{"data/ops"}}},
+			"data":   {{Peer: "ops", DependsOn: []string{"shared/ops"}}},
+			"shared": {{Peer: "ops", DependsOn: []string{"app/shared"}}},
+		}, "cycle: app/shared -> data/ops -> shared/ops -> app/shared"},
+	}
+	for _, tc := range cases {
+		err := ValidateConnectionDependencies(newConfig(tc.entries))
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", tc.name, tc.want, err)
+		}
+	}
+}
+
+// TestConnectionDependencies tests that dependencies are resolved to connections of the same stack.
+func TestConnectionDependencies(t *testing.T) {
+	peers := []PeerConfig{
+		{SourceName: "shared", Name: "ops"},
+		{SourceName: "app", Name: "shared", DependsOn: []string{"shared/ops"}},
+		{SourceName: "app", Name: "data", DependsOn: []string{"app/shared", "other/ops"}},
+	}
+	want := [][]int{nil, {0}, {1}}
+	if got := ConnectionDependencies(peers); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+// TestStackDependencies tests that stacks are ordered by the dependencies between their connections
+// and that stacks depending on each other are rejected.
+func TestStackDependencies(t *testing.T) {
+	targets := []synthTarget{
+		{Stack: "app", Peers: []PeerConfig{
+			{SourceName: "app", Name: "shared", DependsOn: []string{"shared/ops", "app/data"}},
+			{SourceName: "app", Name: "data", DependsOn: []string{"shared/ops", "absent/ops"}},
+		}},
+		{Stack: "shared", Peers: []PeerConfig{{SourceName: "shared", Name: "ops"}}},
+	}
+	deps, err := StackDependencies(targets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[int][]int{0: {1}}; !reflect.DeepEqual(deps, want) {
+		t.Errorf("expected %v, got %v", want, deps)
+	}
+
+	targets[1].Peers[0].DependsOn = []string{"app/data"}
+	if _, err := StackDependencies(targets); err == nil || !strings.Contains(err.Error(), "app -> shared -> app") {
+		t.Errorf("expected an error for stacks depending on each other, got %v", err)
+	}
+}
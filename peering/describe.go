@@ -0,0 +1,158 @@
+package peering
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// -------------------------------------------------------------------------------------------------
+// describe
+// -------------------------------------------------------------------------------------------------
+
+// runDescribe prints everything the tool resolves for a single connection without synthesizing.
+func runDescribe(args []string) error {
+	fs := flag.NewFlagSet("describe", flag.ContinueOnError)
+	placements := placementsFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("expected <source> <peer>, got %d arguments", fs.NArg())
+	}
+
+	cfg, targets := loadSynthTargets(fs.Arg(0), *placements)
+	stack, i, peer, err := findConnection(targets, fs.Arg(0), fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	DescribeConnection(os.Stdout, NewNamer(cfg.Naming), stack, ConnectionNameContext(i, peer), peer)
+	return nil
+}
+
+// DescribeConnection writes a human-readable report of a connection: the stack holding it (when
+// known), both sides, the providers used, peering and DNS options, route targets, and the resource
+// addresses it manages.
+func DescribeConnection(w io.Writer, namer Namer, stack string, ctx NameContext, peer PeerConfig) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	defer tw.Flush()
+
+	sourceRegion := ResolveRegion(peer.SourceRegion)
+	peerRegion := ResolveRegion(peer.PeerRegion)
+	autoAccept := IsAutoAccept(peer)
+
+	fmt.Fprintf(tw, "Connection %s -> %s (index %d)\n", ctx.Source, ctx.Peer, ctx.Index)
+	if stack != "" {
+		fmt.Fprintf(tw, "  Stack:\t%s\n", stack)
+	}
+	fmt.Fprintf(tw, "  Name tag:\t%s\n", ConnectionNameTag(namer, ctx, peer))
+
+	fmt.Fprintf(tw, "\nSource %s\n", ctx.Source)
+	fmt.Fprintf(tw, "  VPC:\t%s\n", peer.SourceVpcID)
+	fmt.Fprintf(tw, "  Region:\t%s\n", sourceRegion)
+	fmt.Fprintf(tw, "  Account:\t%s\n", orUnknown(GetAccountIDFromRoleArn(peer.SourceRoleArn)))
+	fmt.Fprintf(tw, "  Role:\t%s\n", orUnknown(peer.SourceRoleArn))
+	fmt.Fprintf(tw, "  Provider:\taws.%s (%s)\n", namer.ID(ctx, KindSourceProviderAlias), namer.ID(ctx, KindSourceProvider))
+
+	fmt.Fprintf(tw, "\nPeer %s\n", ctx.Peer)
+	fmt.Fprintf(tw, "  VPC:\t%s\n", peer.PeerVpcID)
+	fmt.Fprintf(tw, "  Region:\t%s\n", peerRegion)
+	fmt.Fprintf(tw, "  Account:\t%s\n", orUnknown(PeerAccount(peer)))
+	if peer.ThirdParty {
+		fmt.Fprintf(tw, "  Role:\tnone (third party; nothing is built in this VPC)\n")
+	} else {
+		fmt.Fprintf(tw, "  Role:\t%s\n", orUnknown(peer.PeerRoleArn))
+		fmt.Fprintf(tw, "  Provider:\taws.%s (%s)\n", namer.ID(ctx, KindPeerProviderAlias), namer.ID(ctx, KindPeerProvider))
+	}
+
+	fmt.Fprintf(tw, "\nPeering\n")
+	fmt.Fprintf(tw, "  Cross-region:\t%t\n", sourceRegion != peerRegion)
+	if peer.RetiredPeeringID != "" {
+		fmt.Fprintf(tw, "  Peering:\t%s, removed ahead of its routes\n", peer.RetiredPeeringID)
+	} else if peer.ExternalPeeringID != "" {
+		options := "not managed"
+		if peer.ManageExternalOptions {
+			options = "managed on both sides"
+		}
+		fmt.Fprintf(tw, "  Peering:\t%s, created elsewhere (options %s)\n", peer.ExternalPeeringID, options)
+	} else if peer.PeeringOwner != "" {
+		fmt.Fprintf(tw, "  Peering:\tcreated by the %s stack, looked up by VPC pair\n", peer.PeeringOwner)
+	} else if peer.ManualAcceptance {
+		fmt.Fprintf(tw, "  Acceptance:\tmanual, by the %s stack after approval\n", AcceptStackName)
+	} else if peer.ThirdParty {
+		fmt.Fprintf(tw, "  Acceptance:\tby the owner of account %s\n", PeerAccount(peer))
+	} else if autoAccept {
+		fmt.Fprintf(tw, "  Acceptance:\tauto-accepted by the requester\n")
+	} else {
+		fmt.Fprintf(tw, "  Acceptance:\texplicit accepter in the peer account\n")
+	}
+	fmt.Fprintf(tw, "  DNS resolution:\t%t (both sides)\n", peer.EnableDNSResolution)
+	if peer.RetiredPeeringID != "" {
+		fmt.Fprintf(tw, "  State:\tdecommissioning (peering removed; delete the matrix entry to remove the routes)\n")
+	} else if peer.Decommission != "" {
+		fmt.Fprintf(tw, "  State:\tdecommissioning (%s removed; delete the matrix entry to remove the peering)\n", peer.Decommission)
+	}
+
+	fmt.Fprintf(tw, "\nRoutes\n")
+	fmt.Fprintf(tw, "  Source (%s)\t-> %s\n", describeRouting(peer.SourceRouting), describeDestinations("peer VPC CIDR", peer.DestinationCidrs, peer.SourceExtraCidrs))
+	fmt.Fprintf(tw, "  Peer (%s)\t-> %s\n", describeRouting(peer.PeerRouting), describeDestinations("source VPC CIDR", nil, peer.PeerExtraCidrs))
+
+	fmt.Fprintf(tw, "\nManaged resources\n")
+	addresses := ConnectionAddresses(namer, ctx, peer)
+	list := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		list = append(list, address)
+	}
+	sort.Strings(list)
+	for _, address := range list {
+		fmt.Fprintf(tw, "  %s\n", address)
+	}
+}
+
+// orUnknown substitutes a placeholder for values that could not be resolved.
+func orUnknown(value string) string {
+	if value == "" {
+		return "(unknown)"
+	}
+	return value
+}
+
+// describeDestinations lists the destinations one side routes: the explicit CIDRs, or the other VPC's
+// CIDR without any, followed by the extra CIDRs.
+func describeDestinations(vpcCidr string, cidrs, extra []string) string {
+	var items []string
+	if len(cidrs) == 0 {
+		items = append(items, vpcCidr)
+	}
+	items = append(items, cidrs...)
+	for _, cidr := range extra {
+		items = append(items, cidr+" (extra)")
+	}
+	return strings.Join(items, ", ")
+}
+
+// describeRouting summarizes which route tables a routing config targets.
+func describeRouting(routing RoutingConfig) string {
+	switch routing.Strategy {
+	case RoutingNone:
+		return "no routes"
+	case RoutingAll:
+		return "all route tables"
+	case RoutingFiltered:
+		tags := make([]string, 0, len(routing.SubnetTags))
+		for key, value := range routing.SubnetTags {
+			tags = append(tags, key+"="+value)
+		}
+		sort.Strings(tags)
+		if routing.DedicatedRouteTable {
+			return "main route table + dedicated route table of subnets tagged " + strings.Join(tags, ",")
+		}
+		return "main route table + subnets tagged " + strings.Join(tags, ",")
+	default:
+		return "main route table"
+	}
+}
@@ -0,0 +1,164 @@
+package peering
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// describePeer is a cross-account, cross-region connection routing part of the peer VPC.
+func describePeer() PeerConfig {
+	return PeerConfig{
+		SourceName: "dev", Name: "prod",
+		SourceVpcID: "vpc-1", SourceRegion: "us-east-1", SourceRoleArn: "arn:aws:iam::111111111111:role/Peering",
+		PeerVpcID: "vpc-2", PeerRegion: "us-west-2", PeerRoleArn: "arn:aws:iam::222222222222:role/Peering",
+		EnableDNSResolution: true,
+		DestinationCidrs:    []string{"10.2.0.0/24"},
+		SourceExtraCidrs:    []string{"100.64.0.0/16"},
+		SourceRouting:       RoutingConfig{Strategy: RoutingFiltered, SubnetTags: map[string]string{"tier": "app"}},
+		PeerRouting:         RoutingConfig{Strategy: RoutingMain},
+	}
+}
+
+// TestDescribeConnection tests the full report of a connection: both sides with their accounts and
+// providers, acceptance and DNS, the destinations of each side, and the sorted resource addresses.
+func TestDescribeConnection(t *testing.T) {
+	peer := describePeer()
+	var out bytes.Buffer
+	DescribeConnection(&out, LegacyNamer{}, StackName, ConnectionNameContext(2, peer), peer)
+
+	want := `Connection dev -> prod (index 2)
+  Stack:     cdktf-vpc-peering-module
+  Name tag:  Connection to prod
+
+Source dev
+  VPC:       vpc-1
+  Region:    us-east-1
+  Account:   111111111111
+  Role:      arn:aws:iam::111111111111:role/Peering
+  Provider:  aws.source2 (SourceAWS2)
+
+Peer prod
+  VPC:       vpc-2
+  Region:    us-west-2
+  Account:   222222222222
+  Role:      arn:aws:iam::222222222222:role/Peering
+  Provider:  aws.peer2 (PeerAWS2)
+
+Peering
+  Cross-region:    true
+  Acceptance:      explicit accepter in the peer account
+  DNS resolution:  true (both sides)
+
+Routes
+  Source (main route table + subnets tagged tier=app)  -> 10.2.0.0/24, 100.64.0.0/16 (extra)
+  Peer (main route table)                              -> source VPC CIDR
+
+Managed resources
+  aws_route.PeerToPeerMainRoute2
+  aws_route.SourceSubnetToPeerRoute_prod_eachkey_2Route_100_64_0_0_16
+  aws_route.SourceSubnetToPeerRoute_prod_eachkey_2Route_10_2_0_0_24
+  aws_route.SourceToPeerMainRoute2_100_64_0_0_16
+  aws_route.SourceToPeerMainRoute2_10_2_0_0_24
+  aws_vpc_peering_connection.VpcPeering2
+  aws_vpc_peering_connection_accepter.VpcPeeringAccepter2
+  aws_vpc_peering_connection_options.VpcPeeringAccepterOptions2
+  aws_vpc_peering_connection_options.VpcPeeringOptions2
+`
+	if got := out.String(); got != want {
+		t.Errorf("unexpected report:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// TestDescribeConnectionVariants tests how the report describes peerings the stack does not create,
+// decommissioning connections, and values that could not be resolved.
+func TestDescribeConnectionVariants(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*PeerConfig)
+		want   []string
+	}{
+		{"external", func(p *PeerConfig) { p.ExternalPeeringID, p.ManageExternalOptions = "pcx-0abc", true },
+			[]string{"Peering:         pcx-0abc, created elsewhere (options managed on both sides)"}},
+		{"owned", func(p *PeerConfig) { p.PeeringOwner = "prod" },
+			[]string{"Peering:         created by the prod stack, looked up by VPC pair"}},
+		{"manual", func(p *PeerConfig) { p.ManualAcceptance = true },
+			[]string{"Acceptance:      manual, by the " + AcceptStackName + " stack after approval"}},
+		{"same account", func(p *PeerConfig) { p.PeerRoleArn, p.PeerRegion = p.SourceRoleArn, p.SourceRegion },
+			[]string{"Cross-region:    false", "Acceptance:      auto-accepted by the requester"}},
+		{"decommissioning", func(p *PeerConfig) { p.Decommission = DecommissionRoutes },
+			[]string{"State:           decommissioning (" + DecommissionRoutes + " removed; delete the matrix entry to remove the peering)"}},
+		{"unknown role", func(p *PeerConfig) { p.SourceRoleArn = "" },
+			[]string{"Account:   (unknown)", "Role:      (unknown)"}},
+		{"whole VPC", func(p *PeerConfig) { p.DestinationCidrs, p.SourceExtraCidrs = nil, nil },
+			[]string{"-> peer VPC CIDR\n"}},
+	}
+	for _, tt := range tests {
+		peer := describePeer()
+		tt.modify(&peer)
+		var out bytes.Buffer
+		DescribeConnection(&out, LegacyNamer{}, "", ConnectionNameContext(0, peer), peer)
+		for _, want := range tt.want {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("%s: report missing %q:\n%s", tt.name, want, out.String())
+			}
+		}
+	}
+}
+
+// TestDescribeIndex tests that describe numbers a connection as synth does: by its position among
+// the connections of the stack holding it, in matrix order.
+func TestDescribeIndex(t *testing.T) {
+	cfg := YAMLConfig{
+		Peers: map[string]YAMLPeer{
+			"dev": {VpcID: "vpc-1"}, "prod": {VpcID: "vpc-2"}, "qa": {VpcID: "vpc-3", Region: "eu-west-1"},
+			"ops": {VpcID: "vpc-4", Region: "eu-west-1"},
+		},
+		PeeringMatrix: map[string][]MatrixEntry{
+			"qa":  {{Peer: "ops"}},
+			"dev": {{Peer: "prod"}, {Peer: "qa"}, {Peer: "ops"}},
+		},
+	}
+	targets := []synthTarget{{Stack: StackName, Source: "dev", Peers: ConvertToPeerConfigs(cfg, "dev")}}
+	stack, i, peer, err := findConnection(targets, "dev", "ops")
+	if err != nil || stack != StackName || i != 2 || peer.PeerVpcID != "vpc-4" {
+		t.Errorf("findConnection = %s, %d, %+v, %v", stack, i, peer, err)
+	}
+	if _, _, _, err := findConnection(targets, "dev", "dev"); err == nil {
+		t.Error("expected a connection missing from the matrix to be reported")
+	}
+
+	// Partitioned by region, qa and ops share the eu-west-1 stack and are numbered within it, while
+	// prod is alone in the stack of the default region.
+	cfg.StackPartitioning = StackPartitionByRegion
+	placed, err := PlaceTargets(cfg, targets, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		peer  string
+		stack string
+		index int
+	}{
+		{"prod", StackName + "-" + DefaultRegion, 0},
+		{"qa", StackName + "-eu-west-1", 0},
+		{"ops", StackName + "-eu-west-1", 1},
+	} {
+		stack, i, _, err := findConnection(placed, "dev", tt.peer)
+		if err != nil || stack != tt.stack || i != tt.index {
+			t.Errorf("findConnection(%s) after partitioning = %s, %d, %v; want %s, %d", tt.peer, stack, i, err, tt.stack, tt.index)
+		}
+	}
+
+	// Without a source filter, sources follow in name order rather than map order, so the indices of
+	// a stack holding several sources are the same on every run.
+	for run := 0; run < 5; run++ {
+		var keys []string
+		for _, p := range ConvertToPeerConfigs(cfg, "") {
+			keys = append(keys, ConnectionKey(p))
+		}
+		if got := strings.Join(keys, ","); got != "dev/prod,dev/qa,dev/ops,qa/ops" {
+			t.Fatalf("run %d: unexpected connection order %s", run, got)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package peering
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Accepter-Side Connection Details
+// -------------------------------------------------------------------------------------------------
+
+// AccepterDetailsConfig publishes the details of every connection in the accepter's account as
+// well, so the teams operating peer accounts can discover them without access to this tool's state.
+type AccepterDetailsConfig struct {
+	SSMPrefix string `yaml:"ssm_prefix,omitempty"` // Path of the per-connection SSM parameters in the accepter account (disabled if empty).
+	Tags      bool   `yaml:"tags,omitempty"`       // Tag the accepter side with the connection key and the requester's account and region.
+}
+
+// Validate rejects SSM prefixes that do not form a parameter path.
+func (c AccepterDetailsConfig) Validate() error {
+	if c.SSMPrefix != "" && (!strings.HasPrefix(c.SSMPrefix, "/") || strings.HasSuffix(c.SSMPrefix, "/")) {
+		return fmt.Errorf("accepter_details.ssm_prefix %q must start with / and not end with /", c.SSMPrefix)
+	}
+	return nil
+}
+
+// AccepterDetailsParameter returns the name of the SSM parameter holding the details of a
+// connection.
+func AccepterDetailsParameter(prefix, source, peer string) string {
+	return fmt.Sprintf("%s/%s/%s", prefix, source, peer)
+}
+
+// AccepterDetailTags returns the tags that let the accepter account find a connection with the
+// Resource Groups Tagging API: its key and the requester's account (when the role names it) and
+// region. The peering tags already carry both VPC IDs.
+func AccepterDetailTags(source, target string, sourcePeer YAMLPeer) map[string]string {
+	tags := map[string]string{
+		"ConnectionKey": source + "/" + target,
+		"SourceRegion":  ResolveRegion(sourcePeer.Region),
+	}
+	if account := GetAccountIDFromRoleArn(sourcePeer.RoleArn); account != "" {
+		tags["SourceAccountId"] = account
+	}
+	return tags
+}
+
+// AddAccepterDetails writes an SSM parameter per connection through its peer provider, holding the
+// same details as the connections output, at "<ssm_prefix>/<source>/<peer>".
+func AddAccepterDetails(stack cdktf.TerraformStack, namer Namer, connections []ConnectionResources, cfg AccepterDetailsConfig) {
+	if cfg.SSMPrefix == "" {
+		return
+	}
+	for i, c := range connections {
+		if c.Peer.ThirdParty {
+			continue
+		}
+		ctx := ConnectionNameContext(i, c.Peer)
+		param := cdktf.NewTerraformResource(stack, jsii.String(namer.ID(ctx, KindAccepterDetailsParam)), &cdktf.TerraformResourceConfig{
+			TerraformResourceType: jsii.String("aws_ssm_parameter"),
+			Provider:              c.Core.PeerProvider,
+		})
+		param.AddOverride(jsii.String("name"), AccepterDetailsParameter(cfg.SSMPrefix, c.Peer.SourceName, ctx.Peer))
+		param.AddOverride(jsii.String("description"), fmt.Sprintf("VPC peering %s, managed from the requester's account", ConnectionKey(c.Peer)))
+		param.AddOverride(jsii.String("type"), "String")
+		param.AddOverride(jsii.String("value"), cdktf.Fn_Jsonencode(ConnectionDetails(c)))
+	}
+}
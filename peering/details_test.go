@@ -0,0 +1,38 @@
+package peering
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestAccepterDetailTags tests the tags marking a connection on the accepter side.
+func TestAccepterDetailTags(t *testing.T) {
+	got := AccepterDetailTags("dev", "prod", YAMLPeer{Region: "eu-west-1", RoleArn: "arn:aws:iam::111111111111:role/peering"})
+	want := map[string]string{"ConnectionKey": "dev/prod", "SourceRegion": "eu-west-1", "SourceAccountId": "111111111111"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if _, ok := AccepterDetailTags("dev", "prod", YAMLPeer{Region: "eu-west-1"})["SourceAccountId"]; ok {
+		t.Errorf("expected no account tag without a role")
+	}
+}
+
+// TestAccepterDetailsPolicy tests that only accepter roles may write the detail parameters.
+func TestAccepterDetailsPolicy(t *testing.T) {
+	peers := []PeerConfig{{
+		SourceName: "dev", Name: "prod",
+		SourceVpcID: "vpc-1", SourceRoleArn: "arn:aws:iam::111111111111:role/peering",
+		PeerVpcID: "vpc-2", PeerRoleArn: "arn:aws:iam::222222222222:role/peering",
+	}}
+	if err := (AccepterDetailsConfig{SSMPrefix: "/peering/"}).Validate(); err == nil {
+		t.Errorf("expected a trailing slash to be rejected")
+	}
+	policies := RolePolicies(peers, PolicyOptions{AccepterDetails: AccepterDetailsConfig{SSMPrefix: "/peering/connections"}})
+	if findStatement(policies["arn:aws:iam::111111111111:role/peering"], "PublishConnectionDetails") != nil {
+		t.Errorf("requester role must not publish connection details")
+	}
+	s := findStatement(policies["arn:aws:iam::222222222222:role/peering"], "PublishConnectionDetails")
+	if s == nil || s.Resource[0] != "arn:aws:ssm:*:222222222222:parameter/peering/connections/*" {
+		t.Errorf("unexpected accepter statement: %+v", s)
+	}
+}
@@ -0,0 +1,145 @@
+package peering
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// -------------------------------------------------------------------------------------------------
+// VPC Discovery
+// -------------------------------------------------------------------------------------------------
+
+// DiscoveryResourceExplorer discovers VPCs with AWS Resource Explorer.
+const DiscoveryResourceExplorer = "resource_explorer"
+
+// DiscoveryConfig declares peers by query instead of by VPC ID, for VPCs that come and go such as
+// ephemeral preview environments. Matching VPCs are resolved when the config is loaded, across the
+// accounts and regions the Resource Explorer view covers, and become peers matrix entries select by
+// label.
+type DiscoveryConfig struct {
+	Mode    string                    `yaml:"mode"`               // resource_explorer.
+	Region  string                    `yaml:"region,omitempty"`   // Region of the aggregator index (default region if empty).
+	RoleArn string                    `yaml:"role_arn,omitempty"` // Role to search with (ambient credentials if empty).
+	ViewArn string                    `yaml:"view_arn,omitempty"` // View to search (the region's default view if empty).
+	Queries map[string]DiscoveryQuery `yaml:"queries"`            // Queries by name; the name prefixes the discovered peers.
+}
+
+// DiscoveryQuery selects VPCs and describes the peers they become.
+type DiscoveryQuery struct {
+	Query string   `yaml:"query"`          // Resource Explorer query string, e.g. "tag:env=preview"; resourcetype:ec2:vpc is added.
+	Peer  YAMLPeer `yaml:"peer,omitempty"` // Fields of every discovered peer; "{account}" in role_arn is replaced.
+}
+
+// Validate checks the mode and that every query has a query string.
+func (c DiscoveryConfig) Validate() error {
+	if c.Mode != DiscoveryResourceExplorer {
+		return fmt.Errorf("unknown discovery.mode %q (want %s)", c.Mode, DiscoveryResourceExplorer)
+	}
+	if len(c.Queries) == 0 {
+		return fmt.Errorf("discovery.queries must declare at least one query")
+	}
+	for name, q := range c.Queries {
+		if strings.TrimSpace(q.Query) == "" {
+			return fmt.Errorf("discovery.queries.%s.query is required", name)
+		}
+		if q.Peer.VpcID != "" || q.Peer.Cidr != "" || len(q.Peer.Cidrs) > 0 || q.Peer.MainRouteTableID != "" || len(q.Peer.RouteTableIDs) > 0 {
+			return fmt.Errorf("discovery.queries.%s.peer cannot set VPC-specific fields (vpc_id, cidr, cidrs, route tables)", name)
+		}
+	}
+	return nil
+}
+
+// DiscoveredVpc is a VPC found by a discovery query.
+type DiscoveredVpc struct {
+	VpcID     string // VPC ID.
+	Region    string // Region of the VPC.
+	AccountID string // Account owning the VPC.
+}
+
+// VpcDiscovery finds VPCs by query. Implemented by the AWS CLI and by fakes in tests.
+type VpcDiscovery interface {
+	DiscoverVpcs(viewArn, query string) ([]DiscoveredVpc, error)
+}
+
+// DiscoverVpcs searches Resource Explorer for the VPCs matching a query.
+func (c *AWSCLI) DiscoverVpcs(viewArn, query string) ([]DiscoveredVpc, error) {
+	var out struct {
+		Resources []struct {
+			Arn             string `json:"Arn"`
+			OwningAccountID string `json:"OwningAccountId"`
+			Region          string `json:"Region"`
+		} `json:"Resources"`
+	}
+	args := []string{"resource-explorer-2", "search", "--query-string", "resourcetype:ec2:vpc " + query}
+	if viewArn != "" {
+		args = append(args, "--view-arn", viewArn)
+	}
+	if err := c.Run(&out, args...); err != nil {
+		return nil, err
+	}
+	vpcs := make([]DiscoveredVpc, 0, len(out.Resources))
+	for _, r := range out.Resources {
+		vpcs = append(vpcs, DiscoveredVpc{VpcID: r.Arn[strings.LastIndex(r.Arn, "/")+1:], Region: r.Region, AccountID: r.OwningAccountID})
+	}
+	return vpcs, nil
+}
+
+// DiscoveredPeerName returns the name of the peer a query discovers for a VPC.
+func DiscoveredPeerName(query, vpcID string) string {
+	return query + "-" + vpcID
+}
+
+// ApplyDiscovery adds a peer for every VPC the discovery queries match, named
+// "<query>-<vpc-id>", with the query's peer fields, the VPC's ID and region, and the labels
+// "discovered" and the query name, so matrix entries select them with label:<query>. A region in the
+// query's peer fields limits it to that region. VPCs already declared as peers are skipped; a
+// discovered name that is already taken is an error.
+func ApplyDiscovery(cfg *YAMLConfig, discovery VpcDiscovery) error {
+	declared := make(map[string]string, len(cfg.Peers))
+	for name, peer := range cfg.Peers {
+		declared[peer.VpcID] = name
+	}
+	if cfg.Peers == nil {
+		cfg.Peers = make(map[string]YAMLPeer)
+	}
+
+	names := make([]string, 0, len(cfg.Discovery.Queries))
+	for name := range cfg.Discovery.Queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		q := cfg.Discovery.Queries[name]
+		vpcs, err := discovery.DiscoverVpcs(cfg.Discovery.ViewArn, q.Query)
+		if err != nil {
+			return fmt.Errorf("discovery query %q: %w", name, err)
+		}
+		added := 0
+		for _, vpc := range vpcs {
+			if q.Peer.Region != "" && q.Peer.Region != vpc.Region {
+				continue
+			}
+			if existing, ok := declared[vpc.VpcID]; ok {
+				log.Printf("[config] Discovery query %q matched %s, already declared as %q; keeping the declaration", name, vpc.VpcID, existing)
+				continue
+			}
+			peerName := DiscoveredPeerName(name, vpc.VpcID)
+			if _, taken := cfg.Peers[peerName]; taken {
+				return fmt.Errorf("discovery query %q would add peer %q, which is already declared", name, peerName)
+			}
+			peer := q.Peer
+			peer.VpcID = vpc.VpcID
+			peer.Region = vpc.Region
+			peer.RoleArn = strings.ReplaceAll(peer.RoleArn, "{account}", vpc.AccountID)
+			peer.Labels = append([]string{"discovered", name}, q.Peer.Labels...)
+			cfg.Peers[peerName] = peer
+			declared[vpc.VpcID] = peerName
+			added++
+		}
+		log.Printf("[config] Discovery query %q matched %d VPC(s), adding %d peer(s)", name, len(vpcs), added)
+	}
+	return nil
+}
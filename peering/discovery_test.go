@@ -0,0 +1,64 @@
+package peering
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeDiscovery returns fixed VPCs per query string.
+type fakeDiscovery map[string][]DiscoveredVpc
+
+func (f fakeDiscovery) DiscoverVpcs(_, query string) ([]DiscoveredVpc, error) { return f[query], nil }
+
+// TestApplyDiscovery tests that discovered VPCs become labelled peers and declared VPCs are kept.
+func TestApplyDiscovery(t *testing.T) {
+	cfg := YAMLConfig{
+		Peers: map[string]YAMLPeer{"hub": {VpcID: "vpc-hub", Region: "us-east-1"}},
+		Discovery: &DiscoveryConfig{Mode: DiscoveryResourceExplorer, Queries: map[string]DiscoveryQuery{
+			"preview": {Query: "tag:env=preview", Peer: YAMLPeer{RoleArn: "arn:aws:iam::{account}:role/peering", Labels: []string{"ephemeral"}, Region: "us-east-1"}},
+		}},
+	}
+	discovery := fakeDiscovery{"tag:env=preview": {
+		{VpcID: "vpc-1", Region: "us-east-1", AccountID: "111111111111"},
+		{VpcID: "vpc-hub", Region: "us-east-1", AccountID: "111111111111"},
+		{VpcID: "vpc-2", Region: "eu-west-1", AccountID: "222222222222"},
+	}}
+	if err := ApplyDiscovery(&cfg, discovery); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]YAMLPeer{
+		"hub": {VpcID: "vpc-hub", Region: "us-east-1"},
+		"preview-vpc-1": {VpcID: "vpc-1", Region: "us-east-1", RoleArn: "arn:aws:iam::111111111111:role/peering",
+			Labels: []string{"discovered", "preview", "ephemeral"}},
+	}
+	if !reflect.DeepEqual(cfg.Peers, want) {
+		t.Errorf("expected %+v, got %+v", want, cfg.Peers)
+	}
+
+	cfg.Peers = map[string]YAMLPeer{"preview-vpc-1": {VpcID: "vpc-other"}}
+	if err := ApplyDiscovery(&cfg, discovery); err == nil || !strings.Contains(err.Error(), "already declared") {
+		t.Errorf("expected a name conflict, got %v", err)
+	}
+}
+
+// TestDiscoveryConfigValidate tests the discovery settings checks.
+func TestDiscoveryConfigValidate(t *testing.T) {
+	query := map[string]DiscoveryQuery{"preview": {Query: "tag:env=preview"}}
+	tests := []struct {
+		name    string
+		cfg     DiscoveryConfig
+		wantErr bool
+	}{
+		{"valid", DiscoveryConfig{Mode: DiscoveryResourceExplorer, Queries: query}, false},
+		{"unknown mode", DiscoveryConfig{Mode: "ipam", Queries: query}, true},
+		{"no queries", DiscoveryConfig{Mode: DiscoveryResourceExplorer}, true},
+		{"empty query", DiscoveryConfig{Mode: DiscoveryResourceExplorer, Queries: map[string]DiscoveryQuery{"p": {}}}, true},
+		{"vpc field", DiscoveryConfig{Mode: DiscoveryResourceExplorer, Queries: map[string]DiscoveryQuery{"p": {Query: "x", Peer: YAMLPeer{Cidr: "10.0.0.0/16"}}}}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package peering
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// -------------------------------------------------------------------------------------------------
+// DNS Resolution Policy
+// -------------------------------------------------------------------------------------------------
+
+// DNSPolicyConfig derives DNS resolution from VPC tags instead of per-peer flags, so it follows VPCs
+// as they are reclassified.
+type DNSPolicyConfig struct {
+	DefaultForTag map[string]string `yaml:"default_for_tag,omitempty"` // Enable DNS resolution toward peer VPCs carrying all these tags.
+}
+
+// Validate checks the policy's tag keys.
+func (p DNSPolicyConfig) Validate() error {
+	for key := range p.DefaultForTag {
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf("dns_resolution_policy.default_for_tag has an empty tag key")
+		}
+	}
+	return nil
+}
+
+// dnsResolutionTags returns the tags the peer VPC of a connection must carry for DNS resolution, or
+// nil when the peer enables it itself: dns_resolution: true wins over the policy. Third-party VPCs
+// cannot be looked up, so the policy does not apply to them.
+func dnsResolutionTags(cfg YAMLConfig, peer YAMLPeer) map[string]string {
+	if peer.DNSResolution || peer.ThirdParty() || len(cfg.DNSPolicy.DefaultForTag) == 0 {
+		return nil
+	}
+	return cfg.DNSPolicy.DefaultForTag
+}
+
+// DNSResolutionTagExpr returns an HCL expression that is true when the VPC read by the data source
+// carries every tag with its value, e.g.
+// ${alltrue([lookup(data.aws_vpc.PeerVpcData0.tags, "Environment", "") == "prod"])}.
+func DNSResolutionTagExpr(vpcDataID string, tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	terms := make([]string, 0, len(keys))
+	for _, key := range keys {
+		terms = append(terms, fmt.Sprintf("lookup(data.aws_vpc.%s.tags, %q, \"\") == %q", vpcDataID, key, tags[key]))
+	}
+	return fmt.Sprintf("${alltrue([%s])}", strings.Join(terms, ", "))
+}
+
+// dnsResolutionValue returns the allow_remote_vpc_dns_resolution of a connection: true or false
+// when the config fixes it, or the expression reading the peer VPC's tags at plan time.
+func dnsResolutionValue(peer PeerConfig, core PeerCoreResources) interface{} {
+	if peer.EnableDNSResolution || len(peer.DNSResolutionTags) == 0 || core.PeerVpcData == nil {
+		return peer.EnableDNSResolution
+	}
+	return DNSResolutionTagExpr(*core.PeerVpcData.FriendlyUniqueId(), peer.DNSResolutionTags)
+}
@@ -0,0 +1,51 @@
+package peering
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDNSResolutionTagExpr(t *testing.T) {
+	got := DNSResolutionTagExpr("PeerVpcData0", map[string]string{"Tier": "core", "Environment": "prod"})
+	want := `${alltrue([lookup(data.aws_vpc.PeerVpcData0.tags, "Environment", "") == "prod", lookup(data.aws_vpc.PeerVpcData0.tags, "Tier", "") == "core"])}`
+	if got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestDNSResolutionTags(t *testing.T) {
+	cfg := YAMLConfig{DNSPolicy: DNSPolicyConfig{DefaultForTag: map[string]string{"Environment": "prod"}}}
+	if tags := dnsResolutionTags(cfg, YAMLPeer{}); tags["Environment"] != "prod" {
+		t.Errorf("expected the policy's tags, got %v", tags)
+	}
+	if tags := dnsResolutionTags(cfg, YAMLPeer{DNSResolution: true}); tags != nil {
+		t.Errorf("expected dns_resolution: true to win over the policy, got %v", tags)
+	}
+	if tags := dnsResolutionTags(YAMLConfig{}, YAMLPeer{}); tags != nil {
+		t.Errorf("expected no tags without a policy, got %v", tags)
+	}
+}
+
+func TestDNSPolicyValidate(t *testing.T) {
+	if err := (DNSPolicyConfig{DefaultForTag: map[string]string{"Environment": "prod"}}).Validate(); err != nil {
+		t.Errorf("expected a valid policy, got %v", err)
+	}
+	if err := (DNSPolicyConfig{DefaultForTag: map[string]string{" ": "prod"}}).Validate(); err == nil {
+		t.Error("expected an empty tag key to be rejected")
+	}
+}
+
+func TestCheckOfflineDNSPolicy(t *testing.T) {
+	peer := PeerConfig{
+		SourceName: "dev", Name: "prod",
+		SourceCidr: "10.0.0.0/16", SourceMainRouteTableID: "rtb-1",
+		PeerCidr: "10.1.0.0/16", PeerMainRouteTableID: "rtb-2",
+		SourceRouting:     RoutingConfig{Strategy: RoutingMain},
+		PeerRouting:       RoutingConfig{Strategy: RoutingMain},
+		DNSResolutionTags: map[string]string{"Environment": "prod"},
+	}
+	err := CheckOffline([]PeerConfig{peer})
+	if err == nil || !strings.Contains(err.Error(), "dns_resolution_policy") {
+		t.Errorf("expected the tag lookup to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package peering
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Route 53 Profiles
+// -------------------------------------------------------------------------------------------------
+
+// dnsProfileArnPattern matches Route 53 Profile ARNs, capturing the region and the profile ID.
+var dnsProfileArnPattern = regexp.MustCompile(`^arn:aws[a-z-]*:route53profiles:([a-z0-9-]+):\d{12}:profile/(rp-[0-9a-z]+)$`)
+
+// ValidateDNSProfile checks that a connection's dns_profile_arn names a Route 53 Profile in the
+// region of both VPCs, since profiles associate only with VPCs in their own region.
+func ValidateDNSProfile(arn, sourceRegion, peerRegion string) error {
+	m := dnsProfileArnPattern.FindStringSubmatch(arn)
+	if m == nil {
+		return fmt.Errorf("dns_profile_arn %q is not a Route 53 Profile ARN (arn:aws:route53profiles:<region>:<account>:profile/rp-...)", arn)
+	}
+	for _, region := range []string{ResolveRegion(sourceRegion), ResolveRegion(peerRegion)} {
+		if region != m[1] {
+			return fmt.Errorf("dns_profile_arn is in %s and cannot be associated with a VPC in %s", m[1], region)
+		}
+	}
+	return nil
+}
+
+// DNSProfileID returns the profile ID of a Route 53 Profile ARN.
+func DNSProfileID(arn string) string {
+	return arn[strings.LastIndex(arn, "/")+1:]
+}
+
+// CheckDNSProfiles rejects VPCs that connections associate with different profiles: a VPC has at
+// most one Route 53 Profile.
+func CheckDNSProfiles(peers []PeerConfig) error {
+	profiles := make(map[string]string)
+	keys := make(map[string]string)
+	for _, peer := range peers {
+		if peer.DNSProfileArn == "" {
+			continue
+		}
+		for _, vpc := range []string{peer.SourceVpcID, peer.PeerVpcID} {
+			if prev, ok := profiles[vpc]; ok && prev != peer.DNSProfileArn {
+				return fmt.Errorf("VPC %s would be associated with Route 53 Profile %s by %s and with %s by %s; a VPC has one profile",
+					vpc, DNSProfileID(prev), keys[vpc], DNSProfileID(peer.DNSProfileArn), ConnectionKey(peer))
+			}
+			profiles[vpc] = peer.DNSProfileArn
+			keys[vpc] = ConnectionKey(peer)
+		}
+	}
+	return nil
+}
+
+// AddDNSProfiles associates the Route 53 Profile of each connection with both of its VPCs, through
+// the provider of each side, so the profile follows the connection. A VPC shared by several
+// connections is associated once, by the first of them. The profile must be shared through RAM with
+// the accounts that do not own it.
+func AddDNSProfiles(stack cdktf.TerraformStack, namer Namer, connections []ConnectionResources) {
+	associated := make(map[string]bool)
+	for i, c := range connections {
+		if c.Peer.DNSProfileArn == "" {
+			continue
+		}
+		ctx := ConnectionNameContext(i, c.Peer)
+		sides := []struct {
+			kind     string
+			name     string
+			vpcID    string
+			provider cdktf.TerraformProvider
+		}{
+			{KindSourceDNSProfile, c.Peer.SourceName, c.Peer.SourceVpcID, c.Core.SourceProvider},
+			{KindPeerDNSProfile, ctx.Peer, c.Peer.PeerVpcID, c.Core.PeerProvider},
+		}
+		for _, side := range sides {
+			if associated[side.vpcID] {
+				continue
+			}
+			associated[side.vpcID] = true
+			association := cdktf.NewTerraformResource(stack, jsii.String(namer.ID(ctx, side.kind)), &cdktf.TerraformResourceConfig{
+				TerraformResourceType: jsii.String("aws_route53profiles_association"),
+				Provider:              side.provider,
+			})
+			association.AddOverride(jsii.String("name"), side.name+"-"+DNSProfileID(c.Peer.DNSProfileArn))
+			association.AddOverride(jsii.String("profile_id"), DNSProfileID(c.Peer.DNSProfileArn))
+			association.AddOverride(jsii.String("resource_id"), side.vpcID)
+		}
+	}
+}
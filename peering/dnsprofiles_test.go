@@ -0,0 +1,68 @@
+package peering
+
+import (
+	"strings"
+	"testing"
+)
+
+const testProfileArn = "arn:aws:route53profiles:us-east-1:333333333333:profile/rp-0123456789abcdef"
+
+// TestValidateDNSProfile tests the profile ARN and region checks.
+func TestValidateDNSProfile(t *testing.T) {
+	tests := []struct {
+		name                     string
+		arn                      string
+		sourceRegion, peerRegion string
+		wantErr                  string
+	}{
+		{"same region", testProfileArn, "us-east-1", "us-east-1", ""},
+		{"not an ARN", "rp-0123456789abcdef", "us-east-1", "us-east-1", "not a Route 53 Profile ARN"},
+		{"other region", testProfileArn, "us-east-1", "eu-west-1", "VPC in eu-west-1"},
+	}
+	for _, tt := range tests {
+		err := ValidateDNSProfile(tt.arn, tt.sourceRegion, tt.peerRegion)
+		if (err == nil) != (tt.wantErr == "") || (err != nil && !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: expected error %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+	if got := DNSProfileID(testProfileArn); got != "rp-0123456789abcdef" {
+		t.Errorf("unexpected profile ID %q", got)
+	}
+}
+
+// TestCheckDNSProfiles tests that a VPC may only be associated with one profile.
+func TestCheckDNSProfiles(t *testing.T) {
+	other := "arn:aws:route53profiles:us-east-1:333333333333:profile/rp-fedcba9876543210"
+	peers := []PeerConfig{
+		{SourceName: "dev", Name: "prod", SourceVpcID: "vpc-dev", PeerVpcID: "vpc-prod", DNSProfileArn: testProfileArn},
+		{SourceName: "dev", Name: "shared", SourceVpcID: "vpc-dev", PeerVpcID: "vpc-shared", DNSProfileArn: testProfileArn},
+		{SourceName: "qa", Name: "prod", SourceVpcID: "vpc-qa", PeerVpcID: "vpc-prod"},
+	}
+	if err := CheckDNSProfiles(peers); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	peers[2].DNSProfileArn = other
+	if err := CheckDNSProfiles(peers); err == nil || !strings.Contains(err.Error(), "VPC vpc-prod") {
+		t.Errorf("expected a conflict on vpc-prod, got %v", err)
+	}
+}
+
+// TestRolePoliciesDNSProfiles tests that both roles of a connection with a profile may associate it.
+func TestRolePoliciesDNSProfiles(t *testing.T) {
+	peers := []PeerConfig{{
+		SourceName: "dev", Name: "prod",
+		SourceVpcID: "vpc-1", SourceRoleArn: "arn:aws:iam::111111111111:role/peering",
+		PeerVpcID: "vpc-2", PeerRoleArn: "arn:aws:iam::222222222222:role/peering",
+		DNSProfileArn: testProfileArn,
+	}}
+	for roleArn, doc := range RolePolicies(peers, PolicyOptions{}) {
+		s := findStatement(doc, "AssociateDNSProfiles")
+		if s == nil {
+			t.Errorf("%s: missing AssociateDNSProfiles", roleArn)
+			continue
+		}
+		if s.Resource[0] != testProfileArn {
+			t.Errorf("%s: unexpected resources %v", roleArn, s.Resource)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+//go:build e2e
+
+package peering
+
+import (
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// e2eRegion is the region both VPCs are created in, so the peering is auto-accepted.
+const e2eRegion = "us-east-1"
+
+// localstackEndpoint returns the LocalStack URL, skipping the test when nothing listens there.
+func localstackEndpoint(t *testing.T) string {
+	endpoint := os.Getenv("LOCALSTACK_ENDPOINT")
+	if endpoint == "" {
+		endpoint = "http://localhost:4566"
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		t.Fatalf("invalid LOCALSTACK_ENDPOINT: %v", err)
+	}
+	conn, err := net.Dial("tcp", u.Host)
+	if err != nil {
+		t.Skipf("LocalStack not reachable at %s: %v", endpoint, err)
+	}
+	conn.Close()
+	return endpoint
+}
+
+// createVpc creates a VPC in LocalStack and returns its ID.
+func createVpc(t *testing.T, cli *AWSCLI, cidr string) string {
+	var out struct {
+		Vpc struct {
+			VpcID string `json:"VpcId"`
+		} `json:"Vpc"`
+	}
+	if err := cli.Run(&out, "ec2", "create-vpc", "--cidr-block", cidr); err != nil {
+		t.Fatal(err)
+	}
+	return out.Vpc.VpcID
+}
+
+// terraform runs a terraform command in the synthesized stack directory.
+func terraform(t *testing.T, dir string, args ...string) error {
+	cmd := exec.Command("terraform", args...)
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// TestLocalStackPeering synthesizes a two-VPC stack, applies it against LocalStack, and asserts the
+// peering is active and both main route tables route the opposite VPC through it.
+func TestLocalStackPeering(t *testing.T) {
+	endpoint := localstackEndpoint(t)
+	cli, err := NewAWSCLI(e2eRegion, "", ProviderSettings{EndpointURL: endpoint})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sourceCidr, peerCidr := "10.10.0.0/16", "10.20.0.0/16"
+	sourceVpc := createVpc(t, cli, sourceCidr)
+	peerVpc := createVpc(t, cli, peerCidr)
+
+	role := "arn:aws:iam::000000000000:role/e2e"
+	cfg := YAMLConfig{
+		Peers: map[string]YAMLPeer{
+			"source": {VpcID: sourceVpc, Region: e2eRegion, RoleArn: role},
+			"peer":   {VpcID: peerVpc, Region: e2eRegion, RoleArn: role},
+		},
+		PeeringMatrix: map[string][]MatrixEntry{"source": {{Peer: "peer"}}},
+	}
+	peers := ConvertToPeerConfigs(cfg, "source")
+
+	outdir := t.TempDir()
+	app := cdktf.NewApp(&cdktf.AppConfig{Outdir: jsii.String(outdir)})
+	NewMyStack(app, StackName, "source", peers, StackOptions{Provider: ProviderSettings{EndpointURL: endpoint}})
+	app.Synth()
+
+	stackDir := filepath.Join(outdir, "stacks", StackName)
+	if err := terraform(t, stackDir, "init", "-input=false"); err != nil {
+		t.Fatalf("terraform init: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := terraform(t, stackDir, "destroy", "-auto-approve", "-input=false"); err != nil {
+			t.Errorf("terraform destroy: %v", err)
+		}
+	})
+	if err := terraform(t, stackDir, "apply", "-auto-approve", "-input=false"); err != nil {
+		t.Fatalf("terraform apply: %v", err)
+	}
+
+	var peerings struct {
+		VpcPeeringConnections []struct {
+			VpcPeeringConnectionID string `json:"VpcPeeringConnectionId"`
+			Status                 struct {
+				Code string `json:"Code"`
+			} `json:"Status"`
+		} `json:"VpcPeeringConnections"`
+	}
+	if err := cli.Run(&peerings, "ec2", "describe-vpc-peering-connections",
+		"--filters", "Name=requester-vpc-info.vpc-id,Values="+sourceVpc, "Name=accepter-vpc-info.vpc-id,Values="+peerVpc); err != nil {
+		t.Fatal(err)
+	}
+	if len(peerings.VpcPeeringConnections) != 1 {
+		t.Fatalf("expected 1 peering, got %d", len(peerings.VpcPeeringConnections))
+	}
+	pcx := peerings.VpcPeeringConnections[0]
+	if pcx.Status.Code != "active" {
+		t.Errorf("expected active peering, got %q", pcx.Status.Code)
+	}
+
+	for vpc, dest := range map[string]string{sourceVpc: peerCidr, peerVpc: sourceCidr} {
+		var tables struct {
+			RouteTables []struct {
+				Routes []struct {
+					DestinationCidrBlock   string `json:"DestinationCidrBlock"`
+					VpcPeeringConnectionID string `json:"VpcPeeringConnectionId"`
+				} `json:"Routes"`
+			} `json:"RouteTables"`
+		}
+		if err := cli.Run(&tables, "ec2", "describe-route-tables",
+			"--filters", "Name=vpc-id,Values="+vpc, "Name=association.main,Values=true"); err != nil {
+			t.Fatal(err)
+		}
+		found := false
+		for _, rt := range tables.RouteTables {
+			for _, r := range rt.Routes {
+				found = found || (r.DestinationCidrBlock == dest && r.VpcPeeringConnectionID == pcx.VpcPeeringConnectionID)
+			}
+		}
+		if !found {
+			t.Errorf("main route table of %s has no route to %s via %s", vpc, dest, pcx.VpcPeeringConnectionID)
+		}
+	}
+}
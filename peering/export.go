@@ -0,0 +1,237 @@
+package peering
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Catalog Export
+// -------------------------------------------------------------------------------------------------
+
+// catalogVpc is a VPC node of the exported graph.
+type catalogVpc struct {
+	Name    string // Peer name in the config.
+	VpcID   string // VPC ID.
+	Region  string // Resolved region.
+	Account string // Account ID, empty if unknown.
+}
+
+// catalogGraph is the peering matrix as VPC nodes linked by peering edges.
+type catalogGraph struct {
+	Vpcs  []catalogVpc   // Nodes, by name.
+	Edges []PeerConfig   // Connections, in conversion order.
+	index map[string]int // Position of each VPC by name.
+}
+
+// buildCatalogGraph collects every VPC taking part in a connection.
+func buildCatalogGraph(peers []PeerConfig) catalogGraph {
+	g := catalogGraph{index: make(map[string]int)}
+	add := func(v catalogVpc) {
+		if _, ok := g.index[v.Name]; !ok {
+			g.index[v.Name] = len(g.Vpcs)
+			g.Vpcs = append(g.Vpcs, v)
+		}
+	}
+	for _, peer := range peers {
+		add(catalogVpc{peer.SourceName, peer.SourceVpcID, ResolveRegion(peer.SourceRegion), GetAccountIDFromRoleArn(peer.SourceRoleArn)})
+		add(catalogVpc{ConnectionNameContext(0, peer).Peer, peer.PeerVpcID, ResolveRegion(peer.PeerRegion), PeerAccount(peer)})
+		g.Edges = append(g.Edges, peer)
+	}
+	sort.SliceStable(g.Vpcs, func(i, j int) bool { return g.Vpcs[i].Name < g.Vpcs[j].Name })
+	for i, v := range g.Vpcs {
+		g.index[v.Name] = i
+	}
+	return g
+}
+
+// invalidEntityChars matches characters Backstage does not allow in entity names.
+var invalidEntityChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// entityName sanitizes a name for Backstage (at most 63 characters of [A-Za-z0-9._-]).
+func entityName(name string) string {
+	name = invalidEntityChars.ReplaceAllString(name, "-")
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	return name
+}
+
+// BackstageEntity is a Backstage catalog entity.
+type BackstageEntity struct {
+	APIVersion string                 `yaml:"apiVersion" json:"apiVersion"`
+	Kind       string                 `yaml:"kind" json:"kind"`
+	Metadata   BackstageMetadata      `yaml:"metadata" json:"metadata"`
+	Spec       map[string]interface{} `yaml:"spec" json:"spec"`
+}
+
+// BackstageMetadata is the metadata block of a Backstage entity.
+type BackstageMetadata struct {
+	Name        string            `yaml:"name" json:"name"`
+	Description string            `yaml:"description,omitempty" json:"description,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty" json:"annotations,omitempty"`
+}
+
+// BackstageEntities returns one Resource of type vpc per VPC and one of type vpc-peering per
+// connection, which depends on both of its VPCs so the catalog graph links them.
+func BackstageEntities(peers []PeerConfig, owner, system string) []BackstageEntity {
+	g := buildCatalogGraph(peers)
+	base := func(resourceType, lifecycle string) map[string]interface{} {
+		spec := map[string]interface{}{"type": resourceType, "owner": owner, "lifecycle": lifecycle}
+		if system != "" {
+			spec["system"] = system
+		}
+		return spec
+	}
+
+	var entities []BackstageEntity
+	for _, v := range g.Vpcs {
+		entities = append(entities, BackstageEntity{
+			APIVersion: "backstage.io/v1alpha1",
+			Kind:       "Resource",
+			Metadata: BackstageMetadata{
+				Name:        entityName(v.Name),
+				Description: fmt.Sprintf("VPC %s in %s", v.VpcID, v.Region),
+				Annotations: map[string]string{"aws.amazon.com/vpc-id": v.VpcID, "aws.amazon.com/region": v.Region, "aws.amazon.com/account-id": v.Account},
+			},
+			Spec: base("vpc", "production"),
+		})
+	}
+	for _, peer := range g.Edges {
+		lifecycle := "production"
+		if peer.Decommission != "" {
+			lifecycle = "deprecated"
+		}
+		source, target := peer.SourceName, ConnectionNameContext(0, peer).Peer
+		spec := base("vpc-peering", lifecycle)
+		spec["dependsOn"] = []string{"resource:" + entityName(source), "resource:" + entityName(target)}
+		entities = append(entities, BackstageEntity{
+			APIVersion: "backstage.io/v1alpha1",
+			Kind:       "Resource",
+			Metadata: BackstageMetadata{
+				Name:        entityName(source + "--" + target),
+				Description: fmt.Sprintf("VPC peering %s to %s", source, target),
+			},
+			Spec: spec,
+		})
+	}
+	return entities
+}
+
+// ServiceNowPayload is an Identification and Reconciliation (IRE) payload of CIs and relations.
+type ServiceNowPayload struct {
+	Items     []ServiceNowItem     `json:"items"`
+	Relations []ServiceNowRelation `json:"relations"`
+}
+
+// ServiceNowItem is a CI record.
+type ServiceNowItem struct {
+	ClassName string            `json:"className"`
+	Values    map[string]string `json:"values"`
+}
+
+// ServiceNowRelation links two items by index.
+type ServiceNowRelation struct {
+	Parent int    `json:"parent"`
+	Child  int    `json:"child"`
+	Type   string `json:"type"`
+}
+
+// ServiceNowRecords returns one network CI per VPC and a Connects to relation per connection, from
+// the source VPC to the peer VPC.
+func ServiceNowRecords(peers []PeerConfig) ServiceNowPayload {
+	g := buildCatalogGraph(peers)
+	payload := ServiceNowPayload{Items: []ServiceNowItem{}, Relations: []ServiceNowRelation{}}
+	for _, v := range g.Vpcs {
+		payload.Items = append(payload.Items, ServiceNowItem{
+			ClassName: "cmdb_ci_network",
+			Values:    map[string]string{"name": v.Name, "object_id": v.VpcID, "location": v.Region, "account_id": v.Account},
+		})
+	}
+	for _, peer := range g.Edges {
+		payload.Relations = append(payload.Relations, ServiceNowRelation{
+			Parent: g.index[peer.SourceName],
+			Child:  g.index[ConnectionNameContext(0, peer).Peer],
+			Type:   "Connects to::Connected by",
+		})
+	}
+	return payload
+}
+
+// WriteBackstage writes entities as a multi-document catalog-info YAML file.
+func WriteBackstage(w io.Writer, entities []BackstageEntity) error {
+	for i, e := range entities {
+		data, err := yaml.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			fmt.Fprintln(w, "---")
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// -------------------------------------------------------------------------------------------------
+// export
+// -------------------------------------------------------------------------------------------------
+
+// runExport converts the peering matrix into catalog entities, CMDB records, or the project
+// definitions of a Terraform orchestrator.
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", "backstage", "output format: backstage (catalog-info YAML), servicenow (IRE JSON), atlantis (atlantis.yaml), or spacelift (.spacelift/config.yml)")
+	owner := fs.String("owner", "network", "Backstage owner of the exported entities")
+	system := fs.String("system", "", "Backstage system the entities belong to (optional)")
+	outdir := fs.String("outdir", DefaultSourcesOutdir, "atlantis, spacelift: directory holding one CDKTF_OUTDIR per source")
+	workflow := fs.String("workflow", "", "atlantis: workflow of the projects (optional)")
+	prefix := fs.String("prefix", "vpc-peering-", "spacelift: prefix of the stack IDs")
+	out := fs.String("o", "", "write to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	_, peers := loadSourcePeers(sourceArg(fs))
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	switch *format {
+	case "backstage":
+		return WriteBackstage(w, BackstageEntities(peers, *owner, *system))
+	case "servicenow":
+		data, err := json.MarshalIndent(ServiceNowRecords(peers), "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	case "atlantis":
+		return writeYAML(w, AtlantisProjects(SourceStacks(peers, *outdir), ConfigPath(), *workflow))
+	case "spacelift":
+		cfg, err := SpaceliftStacks(SourceStacks(peers, *outdir), *prefix)
+		if err != nil {
+			return err
+		}
+		return writeYAML(w, cfg)
+	default:
+		return fmt.Errorf("unknown format %q (use backstage, servicenow, atlantis, or spacelift)", *format)
+	}
+}
@@ -0,0 +1,88 @@
+package peering
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// exportPeers is a hub with two spokes, one being decommissioned.
+var exportPeers = []PeerConfig{
+	{SourceName: "hub", Name: "spoke-a", SourceVpcID: "vpc-1", PeerVpcID: "vpc-2", SourceRoleArn: "arn:aws:iam::111111111111:role/r", PeerRoleArn: "arn:aws:iam::222222222222:role/r"},
+	{SourceName: "hub", Name: "spoke b", SourceVpcID: "vpc-1", PeerVpcID: "vpc-3", Decommission: DecommissionRoutes},
+}
+
+// TestBackstageEntities tests that VPCs become vpc resources linked through peering resources.
+func TestBackstageEntities(t *testing.T) {
+	entities := BackstageEntities(exportPeers, "network", "")
+	if len(entities) != 5 {
+		t.Fatalf("expected 3 VPCs and 2 peerings, got %d entities", len(entities))
+	}
+	peering := entities[4]
+	if peering.Metadata.Name != "hub--spoke-b" || peering.Spec["lifecycle"] != "deprecated" {
+		t.Errorf("unexpected peering entity: %+v", peering)
+	}
+	deps, _ := peering.Spec["dependsOn"].([]string)
+	if len(deps) != 2 || deps[0] != "resource:hub" || deps[1] != "resource:spoke-b" {
+		t.Errorf("unexpected dependsOn: %v", deps)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteBackstage(&buf, entities); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(buf.String(), "\n---\n"); got != 4 {
+		t.Errorf("expected 4 document separators, got %d", got)
+	}
+}
+
+// TestServiceNowRecords tests that relations point from the source VPC item to the peer VPC item.
+func TestServiceNowRecords(t *testing.T) {
+	payload := ServiceNowRecords(exportPeers)
+	if len(payload.Items) != 3 || len(payload.Relations) != 2 {
+		t.Fatalf("unexpected payload: %+v", payload)
+	}
+	for _, rel := range payload.Relations {
+		if payload.Items[rel.Parent].Values["name"] != "hub" {
+			t.Errorf("relation parent = %v, want hub", payload.Items[rel.Parent].Values)
+		}
+	}
+	if child := payload.Items[payload.Relations[0].Child].Values; child["object_id"] != "vpc-2" || child["account_id"] != "222222222222" {
+		t.Errorf("unexpected child item: %v", child)
+	}
+}
+
+// TestOrchestratorManifests tests the Atlantis projects and Spacelift stacks of each source.
+func TestOrchestratorManifests(t *testing.T) {
+	peers := append([]PeerConfig{{SourceName: "Edge_1", Name: "hub", SourceVpcID: "vpc-4", PeerVpcID: "vpc-1"}}, exportPeers...)
+	stacks := SourceStacks(peers, "out")
+	if len(stacks) != 2 || stacks[0].Source != "Edge_1" || stacks[1].Dir != "out/hub/stacks/"+StackName {
+		t.Fatalf("unexpected source stacks: %+v", stacks)
+	}
+
+	var buf bytes.Buffer
+	if err := writeYAML(&buf, AtlantisProjects(stacks, "peering.yaml", "cdktf")); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"version: 3",
+		"- name: hub\n  dir: out/hub/stacks/" + StackName + "\n  workflow: cdktf\n",
+		"- '*.tf*'\n    - ../../../../peering.yaml\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected atlantis.yaml to contain %q, got\n%s", want, buf.String())
+		}
+	}
+
+	cfg, err := SpaceliftStacks(stacks, "vpc-peering-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	edge, ok := cfg.Stacks["vpc-peering-edge-1"]
+	if !ok || edge.ProjectRoot != "out/Edge_1/stacks/"+StackName || edge.Environment["CDKTF_SOURCE"] != "Edge_1" || edge.Environment["CDKTF_OUTDIR"] != "out/Edge_1" {
+		t.Errorf("unexpected spacelift stacks: %+v", cfg.Stacks)
+	}
+	if _, err := SpaceliftStacks(append(stacks, SourceStack{Source: "edge-1"}), "vpc-peering-"); err == nil {
+		t.Error("expected colliding stack IDs to be rejected")
+	}
+}
@@ -0,0 +1,137 @@
+package peering
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Exit Codes and Error Output
+// -------------------------------------------------------------------------------------------------
+
+// Exit codes by failure class, so automation wrapping the tool can branch on the kind of failure.
+const (
+	ExitFailure    = 1 // Unclassified failure.
+	ExitConfig     = 2 // The config cannot be found, read, decrypted, or parsed.
+	ExitValidation = 3 // The config is read but invalid, or selects nothing.
+	ExitSynth      = 4 // Building or synthesizing the app failed.
+	ExitAWS        = 5 // An AWS API call failed.
+)
+
+// failureClasses names the class of each exit code in JSON error output.
+var failureClasses = map[int]string{
+	ExitFailure:    "error",
+	ExitConfig:     "config",
+	ExitValidation: "validation",
+	ExitSynth:      "synth",
+	ExitAWS:        "aws",
+}
+
+// Error formats selected with --error-format.
+const (
+	ErrorFormatText = "text"
+	ErrorFormatJSON = "json"
+)
+
+// ErrorFormatEnvVar carries --error-format to subcommands and the synths they start.
+const ErrorFormatEnvVar = "CDKTF_ERROR_FORMAT"
+
+// ExitError is an error with the exit code of its failure class.
+type ExitError struct {
+	Code int   // Exit code.
+	Err  error // The failure.
+}
+
+// Error returns the message of the failure.
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the failure.
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// WithExitCode classifies an error with an exit code.
+func WithExitCode(code int, err error) error {
+	return &ExitError{Code: code, Err: err}
+}
+
+// AWSAPIError is a failed AWS CLI call.
+type AWSAPIError struct {
+	Command string // Service and operation, e.g. "ec2 describe-vpcs".
+	Err     error  // The process error.
+	Stderr  string // What the CLI printed.
+}
+
+// Error returns the command, the process error, and the CLI's message.
+func (e *AWSAPIError) Error() string {
+	return fmt.Sprintf("aws %s: %v: %s", e.Command, e.Err, e.Stderr)
+}
+
+// ExitCodeOf returns the exit code of an error: its own for classified errors, ExitSynth for synth
+// failures, ExitAWS for failed AWS calls, and ExitFailure otherwise.
+func ExitCodeOf(err error) int {
+	var exitErr *ExitError
+	var synth *SynthFailure
+	var aws *AWSAPIError
+	switch {
+	case errors.As(err, &exitErr):
+		return exitErr.Code
+	case errors.As(err, &synth):
+		return ExitSynth
+	case errors.As(err, &aws):
+		return ExitAWS
+	}
+	return ExitFailure
+}
+
+// FailureReport is the JSON document written for a failure under --error-format json.
+type FailureReport struct {
+	Class    string `json:"class"`          // config, validation, synth, aws, or error.
+	ExitCode int    `json:"exit_code"`      // Process exit code.
+	Message  string `json:"message"`        // What failed.
+	Kind     string `json:"kind,omitempty"` // Synth failures: what went wrong, e.g. "version skew".
+	Hint     string `json:"hint,omitempty"` // Synth failures: what to do about it.
+}
+
+// NewFailureReport describes an error for JSON output.
+func NewFailureReport(err error) FailureReport {
+	code := ExitCodeOf(err)
+	report := FailureReport{Class: failureClasses[code], ExitCode: code, Message: err.Error()}
+	var synth *SynthFailure
+	if errors.As(err, &synth) {
+		report.Kind, report.Hint = synth.Kind, synth.Hint
+	}
+	return report
+}
+
+// ValidateErrorFormat rejects formats other than text and json.
+func ValidateErrorFormat(format string) error {
+	if format != "" && format != ErrorFormatText && format != ErrorFormatJSON {
+		return fmt.Errorf("unknown --error-format %q (use %s or %s)", format, ErrorFormatText, ErrorFormatJSON)
+	}
+	return nil
+}
+
+// Fail reports an error and exits with its code: to the log as before, or as a single-line
+// FailureReport on stderr when CDKTF_ERROR_FORMAT is json.
+func Fail(err error) {
+	code := ExitCodeOf(err)
+	if os.Getenv(ErrorFormatEnvVar) == ErrorFormatJSON {
+		data, _ := json.Marshal(NewFailureReport(err))
+		fmt.Fprintln(os.Stderr, string(data))
+	} else {
+		log.Print(err)
+	}
+	recordFailure(code)
+	os.Exit(code)
+}
+
+// Failf reports a formatted error with an exit code and exits.
+func Failf(code int, format string, args ...interface{}) {
+	Fail(WithExitCode(code, fmt.Errorf(format, args...)))
+}
@@ -0,0 +1,41 @@
+package peering
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// TestExitCodeOf tests that errors map to the exit code of their class through wrapping.
+func TestExitCodeOf(t *testing.T) {
+	aws := &AWSAPIError{Command: "ec2 describe-vpcs", Err: errors.New("exit status 254"), Stderr: "AccessDenied"}
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"plain", errors.New("boom"), ExitFailure},
+		{"classified", WithExitCode(ExitValidation, errors.New("bad")), ExitValidation},
+		{"wrapped aws", fmt.Errorf("failed to assume role: %w", aws), ExitAWS},
+		{"classification wins", WithExitCode(ExitConfig, fmt.Errorf("read: %w", aws)), ExitConfig},
+		{"synth", fmt.Errorf("synth: %w", ClassifySynthError(errors.New("broken pipe"))), ExitSynth},
+	}
+	for _, tt := range tests {
+		if got := ExitCodeOf(tt.err); got != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, got)
+		}
+	}
+}
+
+// TestNewFailureReport tests the JSON report of a synth failure and of an AWS error.
+func TestNewFailureReport(t *testing.T) {
+	report := NewFailureReport(ClassifySynthError(errors.New("Error: Could not find assembly")))
+	if report.Class != "synth" || report.ExitCode != ExitSynth || report.Kind != "provider bindings missing" || report.Hint == "" {
+		t.Errorf("unexpected synth report %+v", report)
+	}
+	aws := &AWSAPIError{Command: "ec2 describe-vpcs", Err: errors.New("exit status 254"), Stderr: "AccessDenied"}
+	want := FailureReport{Class: "aws", ExitCode: ExitAWS, Message: "aws ec2 describe-vpcs: exit status 254: AccessDenied"}
+	if got := NewFailureReport(aws); got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
@@ -0,0 +1,208 @@
+package peering
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Change Freezes
+// -------------------------------------------------------------------------------------------------
+
+// EnforceFreezeEnvVar makes plan-summary refuse plans changing frozen connections, like
+// -enforce-freeze.
+const EnforceFreezeEnvVar = "CDKTF_ENFORCE_FREEZE"
+
+// freezeDateLayout is the layout of freeze bounds given as dates rather than timestamps.
+const freezeDateLayout = "2006-01-02"
+
+// FreezeWindow is a period during which a connection must not change. Bounds are RFC 3339 timestamps
+// or dates (UTC), and a date as to includes the whole day. Windows of the config's freezes and of the
+// freeze calendar name the connections they cover; a matrix entry's freeze covers that entry.
+type FreezeWindow struct {
+	From        string   `yaml:"from"`                  // Start of the freeze.
+	To          string   `yaml:"to"`                    // End of the freeze.
+	Reason      string   `yaml:"reason,omitempty"`      // Why changes are frozen, shown with every finding.
+	Connections []string `yaml:"connections,omitempty"` // Connection keys or patterns (e.g. "prod-*/*"); every connection if empty.
+}
+
+// FreezeCalendar is the document freeze_calendar points at, shared by several configs.
+type FreezeCalendar struct {
+	Freezes []FreezeWindow `yaml:"freezes"` // Freeze windows, with the connections each covers.
+}
+
+// parseFreezeTime parses a freeze bound; end moves a date to the end of its day.
+func parseFreezeTime(value string, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(freezeDateLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q (want YYYY-MM-DD or RFC 3339)", value)
+	}
+	if end {
+		t = t.Add(24 * time.Hour)
+	}
+	return t, nil
+}
+
+// Bounds returns the start and end of the window.
+func (w FreezeWindow) Bounds() (time.Time, time.Time, error) {
+	from, err := parseFreezeTime(w.From, false)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("from: %w", err)
+	}
+	to, err := parseFreezeTime(w.To, true)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("to: %w", err)
+	}
+	if !to.After(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("to %s is not after from %s", w.To, w.From)
+	}
+	return from, to, nil
+}
+
+// Validate checks the bounds and connection patterns of the window.
+func (w FreezeWindow) Validate() error {
+	if _, _, err := w.Bounds(); err != nil {
+		return err
+	}
+	for _, pattern := range w.Connections {
+		if _, err := path.Match(pattern, ""); err != nil || !strings.Contains(pattern, "/") {
+			return fmt.Errorf("invalid connection pattern %q (want <source>/<peer>, * allowed)", pattern)
+		}
+	}
+	return nil
+}
+
+// Active reports whether now falls within the window.
+func (w FreezeWindow) Active(now time.Time) bool {
+	from, to, err := w.Bounds()
+	return err == nil && !now.Before(from) && now.Before(to)
+}
+
+// Covers reports whether the window applies to a connection.
+func (w FreezeWindow) Covers(key string) bool {
+	if len(w.Connections) == 0 {
+		return true
+	}
+	for _, pattern := range w.Connections {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// String describes the window for findings, e.g. "2026-12-20 to 2027-01-04 (holiday freeze)".
+func (w FreezeWindow) String() string {
+	s := w.From + " to " + w.To
+	if w.Reason != "" {
+		s += " (" + w.Reason + ")"
+	}
+	return s
+}
+
+// ValidateFreezes checks the config's freezes and every matrix entry's freeze.
+func ValidateFreezes(cfg YAMLConfig) error {
+	for i, w := range cfg.Freezes {
+		if err := w.Validate(); err != nil {
+			return fmt.Errorf("freezes[%d]: %w", i, err)
+		}
+	}
+	for _, source := range sortedMatrixSources(cfg) {
+		for _, entry := range cfg.PeeringMatrix[source] {
+			if entry.Freeze == nil {
+				continue
+			}
+			if len(entry.Freeze.Connections) > 0 {
+				return fmt.Errorf("%q -> %q: freeze.connections is only for freezes and freeze_calendar", source, entry.Peer)
+			}
+			if err := entry.Freeze.Validate(); err != nil {
+				return fmt.Errorf("%q -> %q: freeze: %w", source, entry.Peer, err)
+			}
+		}
+	}
+	return nil
+}
+
+// sortedMatrixSources returns the sources of the peering matrix in name order.
+func sortedMatrixSources(cfg YAMLConfig) []string {
+	sources := make([]string, 0, len(cfg.PeeringMatrix))
+	for source := range cfg.PeeringMatrix {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+// ConnectionFreezes returns the windows freezing a connection: its entry's own and those of the
+// config's freezes that cover its key.
+func ConnectionFreezes(cfg YAMLConfig, key string, entry MatrixEntry) []FreezeWindow {
+	var out []FreezeWindow
+	if entry.Freeze != nil {
+		out = append(out, *entry.Freeze)
+	}
+	for _, w := range cfg.Freezes {
+		if w.Covers(key) {
+			w.Connections = nil
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// ActiveFreeze returns the first of a connection's freezes in effect at now.
+func ActiveFreeze(peer PeerConfig, now time.Time) (FreezeWindow, bool) {
+	for _, w := range peer.Freezes {
+		if w.Active(now) {
+			return w, true
+		}
+	}
+	return FreezeWindow{}, false
+}
+
+// ApplyFreezeCalendar appends the windows of the freeze_calendar document, read like a peer
+// manifest (a path, or an https:// or s3:// URL), to the config's freezes.
+func ApplyFreezeCalendar(cfg *YAMLConfig, s3 ObjectReader) error {
+	if cfg.FreezeCalendar == "" {
+		return nil
+	}
+	data, err := ReadDocument(cfg.FreezeCalendar, s3)
+	if err == nil {
+		err = VerifyDocument(cfg.FreezeCalendar, data, os.Getenv(ConfigVerifyEnvVar), s3)
+	}
+	if err != nil {
+		return fmt.Errorf("freeze calendar %s: %w", cfg.FreezeCalendar, err)
+	}
+	var calendar FreezeCalendar
+	if err := yaml.Unmarshal(data, &calendar); err != nil {
+		return WithExitCode(ExitConfig, fmt.Errorf("freeze calendar %s: %w", cfg.FreezeCalendar, err))
+	}
+	cfg.Freezes = append(cfg.Freezes, calendar.Freezes...)
+	log.Printf("[config] Freeze calendar %s: %d window(s)", cfg.FreezeCalendar, len(calendar.Freezes))
+	return nil
+}
+
+// FrozenChanges returns a finding for every connection of a plan summary frozen at now, sorted by
+// connection.
+func FrozenChanges(summary PlanSummary, peers []PeerConfig, now time.Time) []string {
+	byKey := make(map[string]PeerConfig, len(peers))
+	for _, peer := range peers {
+		byKey[ConnectionKey(peer)] = peer
+	}
+	var findings []string
+	for _, cp := range summary.Connections {
+		if w, ok := ActiveFreeze(byKey[cp.Key], now); ok {
+			findings = append(findings, fmt.Sprintf("this change touches %s, which is frozen %s", cp.Key, w))
+		}
+	}
+	return findings
+}
@@ -0,0 +1,82 @@
+package peering
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestFreezeWindowActive tests date and timestamp bounds, with a date as to covering its whole day.
+func TestFreezeWindowActive(t *testing.T) {
+	w := FreezeWindow{From: "2026-12-20", To: "2027-01-04"}
+	cases := map[string]bool{
+		"2026-12-19T23:59:59Z": false,
+		"2026-12-20T00:00:00Z": true,
+		"2027-01-04T23:00:00Z": true,
+		"2027-01-05T00:00:00Z": false,
+	}
+	for at, want := range cases {
+		now, _ := time.Parse(time.RFC3339, at)
+		if got := w.Active(now); got != want {
+			t.Errorf("%s: expected active %v, got %v", at, want, got)
+		}
+	}
+	w = FreezeWindow{From: "2026-11-26T17:00:00-08:00", To: "2026-11-27T09:00:00-08:00"}
+	if now, _ := time.Parse(time.RFC3339, "2026-11-27T02:00:00Z"); !w.Active(now) {
+		t.Errorf("expected %s to be active at %s", w, now)
+	}
+}
+
+// TestConnectionFreezes tests that a connection gets its entry's freeze and the config freezes
+// covering it.
+func TestConnectionFreezes(t *testing.T) {
+	holiday := FreezeWindow{From: "2026-12-20", To: "2027-01-04", Reason: "holiday"}
+	prod := FreezeWindow{From: "2026-11-01", To: "2026-11-02", Connections: []string{"*/prod-*"}}
+	cfg := YAMLConfig{Freezes: []FreezeWindow{holiday, prod}}
+	own := FreezeWindow{From: "2026-10-01", To: "2026-10-02"}
+
+	got := ConnectionFreezes(cfg, "dev-peer/prod-peer", MatrixEntry{Peer: "prod-peer", Freeze: &own})
+	prod.Connections = nil
+	if want := []FreezeWindow{own, holiday, prod}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if got := ConnectionFreezes(cfg, "dev-peer/qa-peer", MatrixEntry{Peer: "qa-peer"}); !reflect.DeepEqual(got, []FreezeWindow{holiday}) {
+		t.Errorf("expected only the holiday freeze, got %v", got)
+	}
+}
+
+// TestValidateFreezes tests that malformed windows are rejected.
+func TestValidateFreezes(t *testing.T) {
+	cases := map[string]YAMLConfig{
+		"is not after": {Freezes: []FreezeWindow{{From: "2026-12-20", To: "2026-12-19"}}},
+		"invalid time": {Freezes: []FreezeWindow{{From: "20 Dec", To: "2026-12-21"}}},
+		"pattern":      {Freezes: []FreezeWindow{{From: "2026-12-20", To: "2026-12-21", Connections: []string{"prod-peer"}}}},
+		"only for": {PeeringMatrix: map[string][]MatrixEntry{"dev-peer": {{Peer: "prod-peer",
+			Freeze: &FreezeWindow{From: "2026-12-20", To: "2026-12-21", Connections: []string{"*/*"}}}}}},
+	}
+	for want, cfg := range cases {
+		if err := ValidateFreezes(cfg); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got %v", want, err)
+		}
+	}
+}
+
+// TestFrozenChanges tests that only changed connections under an active freeze are reported.
+func TestFrozenChanges(t *testing.T) {
+	freeze := []FreezeWindow{{From: "2026-12-20", To: "2027-01-04", Reason: "holiday"}}
+	peers := []PeerConfig{
+		{SourceName: "dev-peer", Name: "prod-peer", Freezes: freeze},
+		{SourceName: "dev-peer", Name: "qa-peer", Freezes: freeze},
+		{SourceName: "dev-peer", Name: "stage-peer"},
+	}
+	summary := PlanSummary{Connections: []ConnectionPlan{{Key: "dev-peer/prod-peer"}, {Key: "dev-peer/stage-peer"}}}
+	now, _ := time.Parse(time.RFC3339, "2026-12-24T12:00:00Z")
+	want := []string{"this change touches dev-peer/prod-peer, which is frozen 2026-12-20 to 2027-01-04 (holiday)"}
+	if got := FrozenChanges(summary, peers, now); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if got := FrozenChanges(summary, peers, now.AddDate(0, 1, 0)); len(got) != 0 {
+		t.Errorf("expected no findings after the freeze, got %v", got)
+	}
+}
@@ -0,0 +1,492 @@
+package peering
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// -------------------------------------------------------------------------------------------------
+// HCL Rendering
+// -------------------------------------------------------------------------------------------------
+
+// jsonField is one member of a JSON object, kept in document order.
+type jsonField struct {
+	Key   string
+	Value interface{}
+}
+
+// jsonObject is a JSON object decoded in document order, so the HCL follows the synthesized JSON.
+type jsonObject []jsonField
+
+// decodeOrdered decodes the next JSON value, returning objects as jsonObject and numbers as
+// json.Number.
+func decodeOrdered(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		var obj jsonObject
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, jsonField{Key: key.(string), Value: value})
+		}
+		_, err := dec.Token()
+		return obj, err
+	case json.Delim('['):
+		list := []interface{}{}
+		for dec.More() {
+			value, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err := dec.Token()
+		return list, err
+	}
+	return tok, nil
+}
+
+// hclBlockTypes are the keys that Terraform JSON uses for nested blocks rather than attributes in
+// the bodies this tool synthesizes.
+var hclBlockTypes = map[string]bool{
+	"lifecycle": true, "assume_role": true, "endpoints": true, "default_tags": true, "ignore_tags": true,
+	"filter": true, "timeouts": true, "requester": true, "accepter": true, "connection": true,
+	"precondition": true, "postcondition": true, "assert": true, "validation": true, "required_providers": true,
+	"cloud": true,
+}
+
+// hclReferenceAttrs lists, per block type, the attributes holding references or type expressions
+// that Terraform JSON writes as plain strings. provider and depends_on are references everywhere.
+var hclReferenceAttrs = map[string]map[string]bool{
+	"variable":  {"type": true},
+	"moved":     {"from": true, "to": true},
+	"import":    {"to": true},
+	"removed":   {"from": true},
+	"lifecycle": {"ignore_changes": true, "replace_triggered_by": true},
+}
+
+// isReference reports whether an attribute of a block type is written without quotes.
+func isReference(kind, key string) bool {
+	if kind == "locals" {
+		return false
+	}
+	return key == "provider" || key == "depends_on" || hclReferenceAttrs[kind][key]
+}
+
+// hclIdentifier matches names that need no quotes as attribute names or map keys.
+var hclIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// hclWriter renders Terraform JSON bodies as HCL, indenting and aligning them as terraform fmt does.
+type hclWriter struct {
+	buf bytes.Buffer
+}
+
+// ConvertToHCL renders a synthesized Terraform JSON document as HCL. The cdktf "//" metadata is
+// dropped, except each block's construct path, which is kept as a comment for reviewers.
+func ConvertToHCL(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	doc, err := decodeOrdered(dec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Terraform JSON: %w", err)
+	}
+	root, ok := doc.(jsonObject)
+	if !ok {
+		return nil, fmt.Errorf("Terraform JSON is not an object")
+	}
+
+	w := &hclWriter{}
+	for _, section := range root {
+		switch section.Key {
+		case "//":
+			continue
+		case "terraform", "locals":
+			w.blocks(0, section.Key, nil, section.Value)
+		case "provider", "variable", "output", "check":
+			for _, named := range asObject(section.Value) {
+				w.blocks(0, section.Key, []string{named.Key}, named.Value)
+			}
+		case "resource", "data":
+			for _, typed := range asObject(section.Value) {
+				for _, named := range asObject(typed.Value) {
+					w.blocks(0, section.Key, []string{typed.Key, named.Key}, named.Value)
+				}
+			}
+		default: // moved, import, removed, and other unlabeled top-level blocks.
+			w.blocks(0, section.Key, nil, section.Value)
+		}
+	}
+	return bytes.TrimLeft(w.buf.Bytes(), "\n"), nil
+}
+
+// asObject returns a value as an object, or nil.
+func asObject(v interface{}) jsonObject {
+	obj, _ := v.(jsonObject)
+	return obj
+}
+
+// blocks writes one block per object in value (a list of bodies or a single body).
+func (w *hclWriter) blocks(indent int, kind string, labels []string, value interface{}) {
+	bodies, ok := value.([]interface{})
+	if !ok {
+		bodies = []interface{}{value}
+	}
+	for _, body := range bodies {
+		obj := asObject(body)
+		pad := strings.Repeat("  ", indent)
+		if indent == 0 {
+			w.buf.WriteString("\n")
+		}
+		if path := metadataPath(obj); path != "" {
+			fmt.Fprintf(&w.buf, "%s# %s\n", pad, path)
+		}
+		w.buf.WriteString(pad + kind)
+		for _, label := range labels {
+			w.buf.WriteString(" " + quoteHCL(label))
+		}
+		w.buf.WriteString(" {\n")
+		w.body(indent+1, kind, obj)
+		w.buf.WriteString(pad + "}\n")
+	}
+}
+
+// metadataPath returns the construct path cdktf records in a block's "//" member.
+func metadataPath(obj jsonObject) string {
+	for _, f := range obj {
+		if f.Key != "//" {
+			continue
+		}
+		for _, m := range asObject(f.Value) {
+			if m.Key != "metadata" {
+				continue
+			}
+			for _, p := range asObject(m.Value) {
+				if path, ok := p.Value.(string); ok && p.Key == "path" {
+					return path
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// isBlock reports whether a member of a body of the given kind is a nested block.
+func isBlock(kind string, f jsonField) bool {
+	switch {
+	case kind == "terraform" && f.Key == "backend":
+		return true
+	case kind == "check" && f.Key == "data":
+		return true
+	case f.Key == "provisioner":
+		return true
+	case !hclBlockTypes[f.Key]:
+		return false
+	}
+	switch v := f.Value.(type) {
+	case jsonObject:
+		return true
+	case []interface{}:
+		for _, item := range v {
+			if _, ok := item.(jsonObject); !ok {
+				return false
+			}
+		}
+		return len(v) > 0
+	}
+	return false
+}
+
+// body writes the attributes and nested blocks of a block, aligning the "=" of adjacent single-line
+// attributes.
+func (w *hclWriter) body(indent int, kind string, obj jsonObject) {
+	pad := strings.Repeat("  ", indent)
+	type attr struct{ key, value string }
+	var run []attr
+	flush := func() {
+		width := 0
+		for _, a := range run {
+			if !strings.Contains(a.value, "\n") {
+				width = max(width, len(a.key))
+			}
+		}
+		for _, a := range run {
+			key := a.key
+			if !strings.Contains(a.value, "\n") {
+				key += strings.Repeat(" ", width-len(a.key))
+			}
+			fmt.Fprintf(&w.buf, "%s%s = %s\n", pad, key, a.value)
+		}
+		run = nil
+	}
+
+	for _, f := range obj {
+		if f.Key == "//" {
+			continue
+		}
+		if !isBlock(kind, f) {
+			var value string
+			if isReference(kind, f.Key) {
+				value = renderReference(f.Value, indent)
+			} else {
+				value = renderValue(f.Value, indent)
+			}
+			run = append(run, attr{attrName(f.Key), value})
+			continue
+		}
+
+		flush()
+		switch {
+		case f.Key == "backend" && kind == "terraform":
+			for _, typed := range asObject(f.Value) {
+				w.blocks(indent, f.Key, []string{typed.Key}, typed.Value)
+			}
+		case f.Key == "data" && kind == "check":
+			for _, typed := range asObject(f.Value) {
+				for _, named := range asObject(typed.Value) {
+					w.blocks(indent, "data", []string{typed.Key, named.Key}, named.Value)
+				}
+			}
+		case f.Key == "provisioner":
+			items, ok := f.Value.([]interface{})
+			if !ok {
+				items = []interface{}{f.Value}
+			}
+			for _, item := range items {
+				for _, typed := range asObject(item) {
+					w.blocks(indent, "provisioner", []string{typed.Key}, typed.Value)
+				}
+			}
+		default:
+			w.blocks(indent, f.Key, nil, f.Value)
+		}
+	}
+	flush()
+}
+
+// attrName returns an attribute name or map key, quoted unless it is an identifier.
+func attrName(key string) string {
+	if hclIdentifier.MatchString(key) {
+		return key
+	}
+	return quoteHCL(key)
+}
+
+// renderReference renders a reference or type expression written as a string (or list of strings)
+// in Terraform JSON without quotes.
+func renderReference(v interface{}, indent int) string {
+	switch v := v.(type) {
+	case string:
+		if expr, ok := wholeInterpolation(v); ok {
+			return expr
+		}
+		return v
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, renderReference(item, indent+1))
+		}
+		return renderList(items, indent)
+	}
+	return renderValue(v, indent)
+}
+
+// renderValue renders an attribute value: templates as quoted strings or, when a string is a
+// single interpolation, as the bare expression.
+func renderValue(v interface{}, indent int) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return fmt.Sprint(v)
+	case json.Number:
+		return v.String()
+	case string:
+		if expr, ok := wholeInterpolation(v); ok {
+			return expr
+		}
+		return quoteHCL(v)
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, renderValue(item, indent+1))
+		}
+		return renderList(items, indent)
+	case jsonObject:
+		if len(v) == 0 {
+			return "{}"
+		}
+		pad := strings.Repeat("  ", indent)
+		width := 0
+		for _, f := range v {
+			width = max(width, len(attrName(f.Key)))
+		}
+		var b strings.Builder
+		b.WriteString("{\n")
+		for _, f := range v {
+			key := attrName(f.Key)
+			fmt.Fprintf(&b, "%s  %s%s = %s\n", pad, key, strings.Repeat(" ", width-len(key)), renderValue(f.Value, indent+1))
+		}
+		b.WriteString(pad + "}")
+		return b.String()
+	}
+	return fmt.Sprintf("%q", fmt.Sprint(v))
+}
+
+// renderList renders list items on one line when they fit, else one per line.
+func renderList(items []string, indent int) string {
+	line := "[" + strings.Join(items, ", ") + "]"
+	if len(line) <= 80 && !strings.Contains(line, "\n") {
+		return line
+	}
+	pad := strings.Repeat("  ", indent)
+	var b strings.Builder
+	b.WriteString("[\n")
+	for _, item := range items {
+		fmt.Fprintf(&b, "%s  %s,\n", pad, item)
+	}
+	b.WriteString(pad + "]")
+	return b.String()
+}
+
+// scanInterpolation returns the index just past the "}" closing the interpolation whose expression
+// starts at i, skipping braces inside nested quoted strings and templates. It returns -1 if the
+// interpolation is not closed.
+func scanInterpolation(s string, i int) int {
+	depth := 0
+	for i < len(s) {
+		switch s[i] {
+		case '"':
+			if i = scanQuoted(s, i+1); i < 0 {
+				return -1
+			}
+			continue
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return i + 1
+			}
+			depth--
+		}
+		i++
+	}
+	return -1
+}
+
+// scanQuoted returns the index just past the closing quote of a string literal inside an
+// expression, starting after its opening quote.
+func scanQuoted(s string, i int) int {
+	for i < len(s) {
+		switch {
+		case s[i] == '\\':
+			i += 2
+			continue
+		case s[i] == '"':
+			return i + 1
+		case strings.HasPrefix(s[i:], "$${"):
+			i += 3
+			continue
+		case strings.HasPrefix(s[i:], "${"):
+			if i = scanInterpolation(s, i+2); i < 0 {
+				return -1
+			}
+			continue
+		}
+		i++
+	}
+	return -1
+}
+
+// wholeInterpolation returns the expression of a string that consists of exactly one
+// interpolation, such as "${aws_vpc.a.id}".
+func wholeInterpolation(s string) (string, bool) {
+	if !strings.HasPrefix(s, "${") {
+		return "", false
+	}
+	if end := scanInterpolation(s, 2); end == len(s) {
+		return strings.TrimSpace(s[2 : end-1]), true
+	}
+	return "", false
+}
+
+// quoteHCL renders a Terraform template as an HCL quoted string. Literal parts are escaped;
+// interpolations and directives are copied unchanged, since they are already HCL expressions.
+func quoteHCL(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); {
+		switch {
+		case strings.HasPrefix(s[i:], "$${"), strings.HasPrefix(s[i:], "%%{"):
+			b.WriteString(s[i : i+3])
+			i += 3
+		case strings.HasPrefix(s[i:], "${"), strings.HasPrefix(s[i:], "%{"):
+			end := scanInterpolation(s, i+2)
+			if end < 0 {
+				end = len(s)
+			}
+			b.WriteString(s[i:end])
+			i = end
+		default:
+			switch c := s[i]; c {
+			case '"', '\\':
+				b.WriteByte('\\')
+				b.WriteByte(c)
+			case '\n':
+				b.WriteString(`\n`)
+			case '\r':
+				b.WriteString(`\r`)
+			case '\t':
+				b.WriteString(`\t`)
+			default:
+				b.WriteByte(c)
+			}
+			i++
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// -------------------------------------------------------------------------------------------------
+// Post-Synth Conversion
+// -------------------------------------------------------------------------------------------------
+
+// hclOutDir returns where the HCL of a synthesized stack is written. It is kept apart from the stack
+// directory, since Terraform would load the .tf files next to cdk.tf.json as duplicates.
+func hclOutDir(stack string) string {
+	return filepath.Join(synthOutdir(), "hcl", stack)
+}
+
+// EmitHCL converts the synthesized JSON of a stack into <outdir>/hcl/<stack>/main.tf and returns
+// the path written.
+func EmitHCL(stack string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(stackOutDir(stack), "cdk.tf.json"))
+	if err != nil {
+		return "", err
+	}
+	hcl, err := ConvertToHCL(data)
+	if err != nil {
+		return "", fmt.Errorf("stack %s: %w", stack, err)
+	}
+	dir := hclOutDir(stack)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "main.tf")
+	return path, os.WriteFile(path, hcl, 0o644)
+}
@@ -0,0 +1,122 @@
+package peering
+
+import "testing"
+
+// hclInput is a trimmed synthesized stack covering the block and expression shapes cdktf emits.
+const hclInput = `{
+  "//": {"metadata": {"backend": "local", "stackName": "s", "version": "0.20.0"}},
+  "terraform": {
+    "backend": {"local": {"path": "terraform.tfstate"}},
+    "required_providers": {"aws": {"source": "aws", "version": "5.72.1"}},
+    "required_version": ">= 1.5"
+  },
+  "provider": {"aws": [
+    {"alias": "source0", "region": "us-east-1", "assume_role": [{"role_arn": "${var.role_arn_dev}"}]}
+  ]},
+  "variable": {"role_arn_dev": {"type": "string", "sensitive": true}},
+  "resource": {
+    "aws_vpc_peering_connection": {"VpcPeering0": {
+      "//": {"metadata": {"path": "s/VpcPeering0", "uniqueId": "VpcPeering0"}},
+      "provider": "aws.source0",
+      "vpc_id": "vpc-1",
+      "auto_accept": true,
+      "tags": {"Name": "Connection to \"prod\"", "kubernetes.io/role": "x"}
+    }},
+    "aws_route": {"SourceToPeerAllRoute0": {
+      "for_each": "${toset(data.aws_route_tables.RT0.ids)}",
+      "route_table_id": "${each.value}",
+      "destination_cidr_block": "${data.aws_vpc.PeerVpcData0.cidr_block}",
+      "vpc_peering_connection_id": "${aws_vpc_peering_connection.VpcPeering0.id}",
+      "depends_on": ["aws_vpc_peering_connection.VpcPeering0"]
+    }},
+    "aws_ssm_parameter": {"P0": {"type": "String", "value": "${jsonencode([\"a\"])}-${each.key}\n"}}
+  },
+  "moved": [{"from": "aws_route.Old0", "to": "aws_route.New0"}]
+}`
+
+// hclWant is the expected rendering of hclInput.
+const hclWant = `terraform {
+  backend "local" {
+    path = "terraform.tfstate"
+  }
+  required_providers {
+    aws = {
+      source  = "aws"
+      version = "5.72.1"
+    }
+  }
+  required_version = ">= 1.5"
+}
+
+provider "aws" {
+  alias  = "source0"
+  region = "us-east-1"
+  assume_role {
+    role_arn = var.role_arn_dev
+  }
+}
+
+variable "role_arn_dev" {
+  type      = string
+  sensitive = true
+}
+
+# s/VpcPeering0
+resource "aws_vpc_peering_connection" "VpcPeering0" {
+  provider    = aws.source0
+  vpc_id      = "vpc-1"
+  auto_accept = true
+  tags = {
+    Name                 = "Connection to \"prod\""
+    "kubernetes.io/role" = "x"
+  }
+}
+
+resource "aws_route" "SourceToPeerAllRoute0" {
+  for_each                  = toset(data.aws_route_tables.RT0.ids)
+  route_table_id            = each.value
+  destination_cidr_block    = data.aws_vpc.PeerVpcData0.cidr_block
+  vpc_peering_connection_id = aws_vpc_peering_connection.VpcPeering0.id
+  depends_on                = [aws_vpc_peering_connection.VpcPeering0]
+}
+
+resource "aws_ssm_parameter" "P0" {
+  type  = "String"
+  value = "${jsonencode(["a"])}-${each.key}\n"
+}
+
+moved {
+  from = aws_route.Old0
+  to   = aws_route.New0
+}
+`
+
+// TestConvertToHCL tests blocks, labels, references, templates, and alignment of the rendering.
+func TestConvertToHCL(t *testing.T) {
+	got, err := ConvertToHCL([]byte(hclInput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != hclWant {
+		t.Errorf("unexpected HCL:\n%s\nwant:\n%s", got, hclWant)
+	}
+}
+
+// TestQuoteHCL tests that only the literal parts of templates are escaped.
+func TestQuoteHCL(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{`plain`, `"plain"`},
+		{`a "b" \c`, `"a \"b\" \\c"`},
+		{`${lookup(var.m, "k", "}")}-x`, `"${lookup(var.m, "k", "}")}-x"`},
+		{`$${literal} 100%`, `"$${literal} 100%"`},
+		{`%{ if var.on }on%{ endif }`, `"%{ if var.on }on%{ endif }"`},
+	}
+	for _, tt := range tests {
+		if got := quoteHCL(tt.in); got != tt.want {
+			t.Errorf("quoteHCL(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+	if expr, ok := wholeInterpolation(`${a}-${b}`); ok {
+		t.Errorf("wholeInterpolation treated two interpolations as one: %q", expr)
+	}
+}
@@ -0,0 +1,1466 @@
+package peering
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	dataawsroutetable "cdk.tf/go/stack/generated/hashicorp/aws/dataawsroutetable"
+	dataawsroutetables "cdk.tf/go/stack/generated/hashicorp/aws/dataawsroutetables"
+	dataawssubnets "cdk.tf/go/stack/generated/hashicorp/aws/dataawssubnets"
+	dataawsvpc "cdk.tf/go/stack/generated/hashicorp/aws/dataawsvpc"
+	awsprovider "cdk.tf/go/stack/generated/hashicorp/aws/provider"
+	awsroute "cdk.tf/go/stack/generated/hashicorp/aws/route"
+	vpcpeeringconnection "cdk.tf/go/stack/generated/hashicorp/aws/vpcpeeringconnection"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+	"gopkg.in/yaml.v2"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Struct Definitions
+// -------------------------------------------------------------------------------------------------
+
+// PeerCoreResources holds the core AWS resources for a peer in a VPC peering relationship.
+type PeerCoreResources struct {
+	SourceProvider cdktf.TerraformProvider
+	PeerProvider   cdktf.TerraformProvider
+	SourceVpcData  dataawsvpc.DataAwsVpc
+	PeerVpcData    dataawsvpc.DataAwsVpc
+	SourceMainRt   dataawsroutetable.DataAwsRouteTable
+	PeerMainRt     dataawsroutetable.DataAwsRouteTable
+	SourceCidr     *string // Primary CIDR of the source VPC: pinned, or read from SourceVpcData.
+	PeerCidr       *string // Primary CIDR of the peer VPC: pinned, or read from PeerVpcData.
+	SourceMainRtID *string // Main route table of the source VPC: declared, or read from SourceMainRt.
+	PeerMainRtID   *string // Main route table of the peer VPC: declared, or read from PeerMainRt.
+}
+
+// PeerConfig defines the configuration for a single VPC peering connection.
+type PeerConfig struct {
+	SourceVpcID             string            // VPC ID of the source.
+	SourceRegion            string            // AWS region of the source.
+	SourceRoleArn           string            // IAM role ARN for the source.
+	PeerVpcID               string            // VPC ID of the peer.
+	PeerRegion              string            // AWS region of the peer.
+	PeerRoleArn             string            // IAM role ARN for the peer.
+	PeerAccountID           string            // Peer account resolved with STS (derived from PeerRoleArn if empty).
+	ThirdParty              bool              // The peer account grants no role: only the requester side is built.
+	SourceName              string            // Logical name of the source peer.
+	Name                    string            // Logical name for this peering.
+	SourceEnv               string            // Environment label of the source.
+	PeerEnv                 string            // Environment label of the peer.
+	NameTagTemplate         string            // Go template for the peering Name tag (namer default if empty).
+	DestinationCidrs        []string          // Peer-side CIDRs routed from the source (peer VPC CIDR if empty).
+	SourceExtraCidrs        []string          // Extra destinations routed from the source in addition.
+	PeerExtraCidrs          []string          // Extra destinations routed from the peer in addition.
+	SourceCidrs             []string          // Declared CIDR blocks of the source VPC (unknown if empty).
+	PeerCidrs               []string          // Declared CIDR blocks of the peer VPC (unknown if empty).
+	SourceCidr              string            // Pinned primary CIDR of the source VPC (looked up if empty).
+	PeerCidr                string            // Pinned primary CIDR of the peer VPC (looked up if empty).
+	SourceMainRouteTableID  string            // Declared main route table of the source VPC (looked up if empty).
+	PeerMainRouteTableID    string            // Declared main route table of the peer VPC (looked up if empty).
+	SourceRouteTableIDs     []string          // Declared route tables of the source VPC (looked up if empty).
+	PeerRouteTableIDs       []string          // Declared route tables of the peer VPC (looked up if empty).
+	EnableDNSResolution     bool              // Enables DNS resolution across the peering.
+	DNSResolutionTags       map[string]string // Otherwise enables it when the peer VPC carries all these tags at plan time.
+	HasExtraPeerRouteTables bool              // Legacy flag: adds subnet routes on both sides.
+	SourceRouting           RoutingConfig     // Route management for the source VPC.
+	PeerRouting             RoutingConfig     // Route management for the peer VPC.
+	Decommission            string            // Part removed ahead of deleting the connection ("" while active).
+	RetiredPeeringID        string            // pcx-id the routes keep once decommission: peering removed the peering.
+	CheckIPs                CheckIPs          // Representative addresses for connectivity checks.
+	Tags                    map[string]string // Tags for both sides of the peering (config-level merged with per-connection).
+	RequesterTags           map[string]string // Overrides for the requester side.
+	AccepterTags            map[string]string // Overrides for the accepter side.
+	ExternalPeeringID       string            // pcx-id of a peering created elsewhere; only routes are managed.
+	ManageExternalOptions   bool              // Manage the options of the external peering as well.
+	ManualAcceptance        bool              // The peering is accepted by the accept stack after approval.
+	PeeringOwner            string            // Source whose stack creates the peering when both sources list each other ("" if this one).
+	SharedPeering           bool              // Both sources list the connection; PeeringOwner tells which one creates the peering.
+	Lifecycle               LifecycleConfig   // lifecycle ignore_changes of the routes and peering.
+	CrossRegionDNSAcked     bool              // The caveats of DNS resolution across regions are acknowledged.
+	Lattice                 bool              // Connected through the VPC Lattice service network: no peering or routes.
+	DNSProfileArn           string            // Route 53 Profile associated with both VPCs (none if empty).
+	DependsOn               []string          // Keys of the connections whose peering and options are established first.
+	Freezes                 []FreezeWindow    // Change freezes covering the connection.
+	Inspection              *InspectionConfig // GWLB endpoints the routes go through (straight to the peering if nil).
+}
+
+// YAMLPeer represents a peer entry in the YAML file.
+type YAMLPeer struct {
+	VpcID               string         `yaml:"vpc_id"`                          // VPC ID.
+	Region              string         `yaml:"region"`                          // AWS region.
+	RoleArn             string         `yaml:"role_arn"`                        // IAM role ARN.
+	RoleArns            []RoleChoice   `yaml:"role_arns,omitempty"`             // Candidate roles by region or in fallback order, instead of role_arn.
+	DNSResolution       bool           `yaml:"dns_resolution"`                  // Enables DNS resolution.
+	HasAdditionalRoutes bool           `yaml:"has_additional_routes,omitempty"` // Version 1 only: enables additional subnet routes.
+	Environment         string         `yaml:"environment,omitempty"`           // Environment label (e.g. prod, staging).
+	Routes              *RoutingConfig `yaml:"routes,omitempty"`                // Default route management for this VPC.
+	Labels              []string       `yaml:"labels,omitempty"`                // Labels matrix selectors match (e.g. team-data).
+	Cidrs               []string       `yaml:"cidrs,omitempty"`                 // IPv4 CIDR blocks of the VPC, for offline validation.
+	Cidr                string         `yaml:"cidr,omitempty"`                  // Primary IPv4 CIDR of the VPC, routed to without a lookup.
+	MainRouteTableID    string         `yaml:"main_route_table_id,omitempty"`   // Main route table of the VPC, used without a lookup.
+	RouteTableIDs       []string       `yaml:"route_table_ids,omitempty"`       // Every route table of the VPC, used by all routing without a lookup.
+	AccountID           string         `yaml:"account_id,omitempty"`            // Account owning the VPC; without a role, a third party's VPC.
+}
+
+// YAMLConfig holds the structure of the YAML configuration file.
+type YAMLConfig struct {
+	Version              int                      `yaml:"version,omitempty"`                 // Schema version (1 if absent).
+	PeerDefaults         *YAMLPeer                `yaml:"peer_defaults,omitempty"`           // Fields every peer inherits unless it sets them.
+	Peers                map[string]YAMLPeer      `yaml:"peers"`                             // Map of peer names to YAMLPeer definitions.
+	PeeringMatrix        map[string][]MatrixEntry `yaml:"peering_matrix"`                    // Map of source peer names to lists of target entries.
+	DNSResolution        map[string]bool          `yaml:"dns_resolution,omitempty"`          // Version 1 only: map of peer names to DNS resolution flags (never applied).
+	AdditionalRoutes     map[string][]string      `yaml:"additional_routes,omitempty"`       // Version 1 only: map of peer names to additional route lists (never applied).
+	Naming               NamingConfig             `yaml:"naming,omitempty"`                  // Optional resource naming strategy.
+	NameTagTemplate      string                   `yaml:"name_tag_template,omitempty"`       // Optional Go template for peering Name tags.
+	Provider             ProviderSettings         `yaml:"provider,omitempty"`                // Optional settings applied to every AWS provider.
+	ConnectivityChecks   bool                     `yaml:"connectivity_checks,omitempty"`     // Emit a Terraform check block per connection.
+	Tags                 map[string]string        `yaml:"tags,omitempty"`                    // Tags for every peering, on both sides.
+	DNSPolicy            DNSPolicyConfig          `yaml:"dns_resolution_policy,omitempty"`   // DNS resolution derived from peer VPC tags.
+	Aspects              AspectsConfig            `yaml:"aspects,omitempty"`                 // Built-in aspects applied to every resource.
+	ResolveAccountIDs    bool                     `yaml:"resolve_account_ids,omitempty"`     // Look up unparseable peer accounts with STS at synth.
+	Inventory            InventoryConfig          `yaml:"inventory,omitempty"`               // Where to write the connection inventory on apply.
+	RouteProvenance      ProvenanceConfig         `yaml:"route_provenance,omitempty"`        // How routes this tool manages are marked in AWS.
+	RoleArnVariables     bool                     `yaml:"role_arn_variables,omitempty"`      // Assume roles through sensitive variables instead of literal ARNs.
+	Terraform            TerraformSettings        `yaml:"terraform,omitempty"`               // Terraform and AWS provider version constraints.
+	Rollout              RolloutConfig            `yaml:"rollout,omitempty"`                 // Stage routes to new destinations across applies.
+	CidrVariables        bool                     `yaml:"cidr_variables,omitempty"`          // Route destination_cidrs through per-connection variables.
+	OwnedPeerings        OwnedPeeringsConfig      `yaml:"owned_peerings,omitempty"`          // How stacks share peerings both sources list.
+	IPAM                 *IPAMConfig              `yaml:"ipam,omitempty"`                    // Fill in peer VPC IDs and CIDRs from AWS IPAM.
+	Discovery            *DiscoveryConfig         `yaml:"discovery,omitempty"`               // Peers declared by query and resolved at load.
+	PeerManifests        []string                 `yaml:"peer_manifests,omitempty"`          // Peers documents of other teams (paths, https:// or s3:// URLs), merged at load.
+	Freezes              []FreezeWindow           `yaml:"freezes,omitempty"`                 // Change freezes and the connections they cover.
+	FreezeCalendar       string                   `yaml:"freeze_calendar,omitempty"`         // Shared document of further freezes (path, https:// or s3:// URL).
+	AccepterDetails      AccepterDetailsConfig    `yaml:"accepter_details,omitempty"`        // Connection details published in accepter accounts.
+	Lattice              *LatticeConfig           `yaml:"lattice,omitempty"`                 // Service network of connections with connectivity_mode: lattice.
+	MaxProvidersPerStack int                      `yaml:"max_providers_per_stack,omitempty"` // Split stacks needing more AWS providers (0 for no limit).
+	StackPartitioning    string                   `yaml:"stack_partitioning,omitempty"`      // Split each stack by_region or by_peer_account (none by default).
+	AllowedRegions       []string                 `yaml:"allowed_regions,omitempty"`         // Regions accepted beyond the built-in list, e.g. ones launched since the release.
+	SubnetRouteKeys      string                   `yaml:"subnet_route_keys,omitempty"`       // Key filtered subnet routes by route_table (default) or subnet.
+}
+
+// NamingConfig selects the naming strategy for construct IDs and Name tags.
+type NamingConfig struct {
+	Pattern string `yaml:"pattern,omitempty"` // Pattern with {source}, {peer}, {kind}, and {index} tokens.
+}
+
+// PeeringResources holds the resources related to a single VPC peering connection.
+type PeeringResources struct {
+	Peering         vpcpeeringconnection.VpcPeeringConnection // The VPC peering connection resource (nil for external peerings).
+	Accepter        cdktf.TerraformResource                   // The accepter resource (if cross-account/region).
+	Options         cdktf.TerraformResource                   // Requester-side options, set through the source provider (nil for external peerings unless managed).
+	AccepterOptions cdktf.TerraformResource                   // Accepter-side options, set through the peer provider (nil when Options is).
+	Data            cdktf.TerraformDataSource                 // Lookup of an external peering (nil when the stack creates it).
+	OwnerParam      cdktf.TerraformResource                   // SSM parameter publishing the pcx-id of a shared peering the stack owns (nil unless looked up by ssm).
+	OwnerLookup     cdktf.TerraformDataSource                 // Remote state or SSM parameter holding the owner's pcx-id (nil unless the owner's stack is another one).
+	RetiredID       *string                                   // pcx-id of a peering removed ahead of its routes (nil unless decommission: peering).
+	DependsOn       []cdktf.ITerraformDependable              // List of dependencies for downstream resources.
+}
+
+// PeeringID returns the ID of the peering, created, looked up, or removed ahead of its routes.
+func (p PeeringResources) PeeringID() *string {
+	if p.RetiredID != nil {
+		return p.RetiredID
+	}
+	if p.Peering == nil {
+		return p.Data.GetStringAttribute(jsii.String("id"))
+	}
+	return p.Peering.Id()
+}
+
+// AcceptStatus returns the accept status of the peering.
+func (p PeeringResources) AcceptStatus() *string {
+	if p.RetiredID != nil {
+		return jsii.String("deleted")
+	}
+	if p.Peering == nil {
+		return p.Data.GetStringAttribute(jsii.String("status"))
+	}
+	return p.Peering.AcceptStatus()
+}
+
+// PeerOwnerID returns the account owning the peer VPC, or nil once the peering is removed.
+func (p PeeringResources) PeerOwnerID() *string {
+	if p.RetiredID != nil {
+		return nil
+	}
+	if p.Peering == nil {
+		return p.Data.GetStringAttribute(jsii.String("peer_owner_id"))
+	}
+	return p.Peering.PeerOwnerId()
+}
+
+// -------------------------------------------------------------------------------------------------
+// Interfaces for Resource Creation (for testability)
+// -------------------------------------------------------------------------------------------------
+
+// AwsProviderFactory defines an interface for creating AWS providers.
+type AwsProviderFactory interface {
+	Create(stack constructs.Construct, name, alias, region, roleArn string) awsprovider.AwsProvider
+}
+
+// DataAwsVpcFactory defines an interface for creating AWS VPC data sources.
+type DataAwsVpcFactory interface {
+	Create(stack constructs.Construct, name, vpcID string, provider awsprovider.AwsProvider) dataawsvpc.DataAwsVpc
+}
+
+// DataAwsRouteTableFactory defines an interface for creating main route table data sources.
+type DataAwsRouteTableFactory interface {
+	Create(stack constructs.Construct, name, vpcID string, provider awsprovider.AwsProvider) dataawsroutetable.DataAwsRouteTable
+}
+
+// RealAwsProviderFactory is the production implementation of AwsProviderFactory.
+type RealAwsProviderFactory struct {
+	Settings ProviderSettings  // Retry, endpoint, and proxy settings applied to every provider.
+	RoleRefs map[string]string // Role ARN -> variable reference assumed instead of the literal ARN (optional).
+}
+
+// Create creates a new AWS provider resource.
+func (f *RealAwsProviderFactory) Create(stack constructs.Construct, name, alias, region, roleArn string) awsprovider.AwsProvider {
+	if ref, ok := f.RoleRefs[roleArn]; ok {
+		roleArn = ref
+	}
+	cfg := &awsprovider.AwsProviderConfig{
+		Region: jsii.String(region),
+		Alias:  jsii.String(alias),
+		AssumeRole: &[]*awsprovider.AwsProviderAssumeRole{{
+			RoleArn: jsii.String(roleArn),
+		}},
+	}
+	f.Settings.Apply(cfg)
+	return awsprovider.NewAwsProvider(stack, jsii.String(name), cfg)
+}
+
+// RealDataAwsVpcFactory is the production implementation of DataAwsVpcFactory.
+type RealDataAwsVpcFactory struct{}
+
+// Create creates a new AWS VPC data source.
+func (f *RealDataAwsVpcFactory) Create(stack constructs.Construct, name, vpcID string, provider awsprovider.AwsProvider) dataawsvpc.DataAwsVpc {
+	return dataawsvpc.NewDataAwsVpc(stack, jsii.String(name), &dataawsvpc.DataAwsVpcConfig{
+		Id:       jsii.String(vpcID),
+		Provider: provider,
+	})
+}
+
+// RealDataAwsRouteTableFactory is the production implementation of DataAwsRouteTableFactory.
+type RealDataAwsRouteTableFactory struct{}
+
+// Create creates a new main route table data source.
+func (f *RealDataAwsRouteTableFactory) Create(stack constructs.Construct, name, vpcID string, provider awsprovider.AwsProvider) dataawsroutetable.DataAwsRouteTable {
+	return dataawsroutetable.NewDataAwsRouteTable(stack, jsii.String(name), &dataawsroutetable.DataAwsRouteTableConfig{
+		VpcId:    jsii.String(vpcID),
+		Provider: provider,
+		Filter: &[]*dataawsroutetable.DataAwsRouteTableFilter{{
+			Name:   jsii.String("association.main"),
+			Values: jsii.Strings("true"),
+		}},
+	})
+}
+
+// -------------------------------------------------------------------------------------------------
+// YAML Config Loading and Conversion
+// -------------------------------------------------------------------------------------------------
+
+// LoadConfig loads and parses the YAML configuration file at the given path, decrypting it when it
+// is SOPS-encrypted and resolving its secret references, migrates it to the current schema version,
+// and, when configured, adds discovered peers and fills in peers from IPAM. It exits with ExitConfig
+// if the file cannot be read, decrypted, resolved, parsed, migrated, or completed (ExitValidation for
+// ClassicLink options, unknown regions, invalid discovery or IPAM settings, and peer role_arns that
+// are not role ARNs).
+func LoadConfig(path string) YAMLConfig {
+	data, err := ReadConfigFile(path)
+	if err != nil {
+		Failf(ExitConfig, "failed to read config file: %v", err)
+	}
+	if data, err = ResolveSecrets(data, SecretResolvers); err != nil {
+		Failf(ExitConfig, "failed to resolve secret references: %v", err)
+	}
+	cfg, err := parseConfig(data, path)
+	if err != nil {
+		Fail(err)
+	}
+	if len(cfg.PeerManifests) > 0 {
+		cli, err := NewAWSCLI("", "", cfg.Provider)
+		if err != nil {
+			Fail(err)
+		}
+		if err := ApplyPeerManifests(&cfg, cli); err != nil {
+			Fail(err)
+		}
+	}
+	if err := ApplyFreezeCalendar(&cfg, configObjectReader()); err != nil {
+		Fail(err)
+	}
+	if cfg.Discovery != nil {
+		if err := cfg.Discovery.Validate(); err != nil {
+			Failf(ExitValidation, "invalid discovery settings: %v", err)
+		}
+		cli, err := NewAWSCLI(cfg.Discovery.Region, cfg.Discovery.RoleArn, cfg.Provider)
+		if err != nil {
+			Fail(err)
+		}
+		if err := ApplyDiscovery(&cfg, cli); err != nil {
+			Fail(err)
+		}
+	}
+	if cfg.IPAM != nil {
+		if err := cfg.IPAM.Validate(); err != nil {
+			Failf(ExitValidation, "invalid ipam settings: %v", err)
+		}
+		cli, err := NewAWSCLI(cfg.IPAM.Region, cfg.IPAM.RoleArn, cfg.Provider)
+		if err != nil {
+			Fail(err)
+		}
+		if err := ApplyIPAM(&cfg, cli); err != nil {
+			Fail(err)
+		}
+	}
+	if err := ApplyRoleArns(&cfg); err != nil {
+		Failf(ExitValidation, "invalid role ARNs: %v", err)
+	}
+	if err := ValidatePeerRoleArns(cfg); err != nil {
+		Failf(ExitValidation, "invalid role ARNs: %v", err)
+	}
+	if err := ValidateThirdPartyPeers(cfg); err != nil {
+		Failf(ExitValidation, "invalid account IDs: %v", err)
+	}
+	return cfg
+}
+
+// parseConfig parses a config document, applies its peer_defaults, migrates it to the current schema
+// version, and checks its peering options and regions. Errors carry the exit code LoadConfig exits
+// with; path only names the document in the migration notice.
+func parseConfig(data []byte, path string) (YAMLConfig, error) {
+	var cfg YAMLConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return YAMLConfig{}, WithExitCode(ExitConfig, fmt.Errorf("failed to parse yaml: %w", err))
+	}
+	if err := ApplyPeerDefaults(data, &cfg); err != nil {
+		return YAMLConfig{}, WithExitCode(ExitConfig, fmt.Errorf("failed to apply peer_defaults: %w", err))
+	}
+	if err := CheckClassicLinkOptions(data); err != nil {
+		return YAMLConfig{}, WithExitCode(ExitValidation, fmt.Errorf("invalid peering options: %w", err))
+	}
+	from, err := MigrateConfig(&cfg)
+	if err != nil {
+		return YAMLConfig{}, WithExitCode(ExitConfig, fmt.Errorf("failed to migrate config: %w", err))
+	}
+	if from != CurrentConfigVersion {
+		log.Printf("[config] Migrated %s from version %d to %d in memory; run migrate-config to update the file", path, from, CurrentConfigVersion)
+	}
+	if err := ValidateRegions(cfg); err != nil {
+		return YAMLConfig{}, WithExitCode(ExitValidation, fmt.Errorf("invalid regions: %w", err))
+	}
+	return cfg, nil
+}
+
+// ConvertToPeerConfigs converts a YAMLConfig and optional source filter into a slice of PeerConfig structs.
+// Disabled entries are validated like the others but left out, and listed in a summary. It panics if
+// required peer config entries are missing.
+func ConvertToPeerConfigs(cfg YAMLConfig, sourceFilter string) []PeerConfig {
+	var peerConfigs []PeerConfig
+	log.Printf("[convert] Applying source filter: %q", sourceFilter)
+	for _, dup := range DuplicateVpcIDs(cfg) {
+		log.Printf("[convert] WARNING: VPC %s is registered under several peer names: %s", dup.VpcID, strings.Join(dup.Peers, ", "))
+	}
+
+	// Sort sources so connection indices, and therefore construct IDs, are stable between runs. Map
+	// iteration order is random, so a synth of several sources in one stack otherwise numbered them
+	// differently on every run; a source filter leaves a single source, whose indices follow its
+	// matrix order either way.
+	sources := make([]string, 0, len(cfg.PeeringMatrix))
+	for source := range cfg.PeeringMatrix {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	owners, err := PeeringOwners(cfg)
+	if err != nil {
+		Fail(WithExitCode(ExitValidation, err))
+	}
+	if err := ValidateConnectionDependencies(cfg); err != nil {
+		Fail(WithExitCode(ExitValidation, err))
+	}
+	if err := ValidateFreezes(cfg); err != nil {
+		Fail(WithExitCode(ExitValidation, err))
+	}
+	if err := ValidateSelectorNaming(cfg); err != nil {
+		Fail(WithExitCode(ExitValidation, err))
+	}
+	now := time.Now()
+
+	var skipped, lattice, forgotten []string
+	for _, source := range sources {
+		targets := cfg.PeeringMatrix[source]
+		if sourceFilter != "" && source != sourceFilter {
+			continue
+		}
+		log.Printf("[convert] Considering source: %q", source)
+
+		if _, ok := cfg.Peers[source]; !ok {
+			Failf(ExitValidation, "missing source peer config for %q", source)
+		}
+		for _, entry := range ExpandMatrixEntries(cfg, source, targets) {
+			forget, err := entry.ForgetOnRemove()
+			if err != nil {
+				Failf(ExitValidation, "invalid state for %q -> %q: %v", source, entry.Peer, err)
+			}
+			if forget {
+				forgotten = append(forgotten, source+"/"+entry.Peer)
+				continue
+			}
+			peer, err := ResolveConnection(cfg, source, entry)
+			if err != nil {
+				Fail(WithExitCode(ExitValidation, err))
+			}
+			if entry.Disabled() {
+				skipped = append(skipped, ConnectionKey(peer))
+				continue
+			}
+			if peer.Lattice {
+				lattice = append(lattice, ConnectionKey(peer))
+				continue
+			}
+			if msg := CrossRegionDNSWarning(peer); msg != "" {
+				log.Printf("[convert] WARNING: %s: %s", ConnectionKey(peer), msg)
+			}
+			if w, ok := ActiveFreeze(peer, now); ok {
+				log.Printf("[convert] WARNING: %s is frozen %s; plan-summary -enforce-freeze refuses plans changing it", ConnectionKey(peer), w)
+			}
+			if owner, ok := owners[peerPair(source, entry.Peer)]; ok {
+				if peer, err = ApplyPeeringOwner(peer, owner); err != nil {
+					Fail(WithExitCode(ExitValidation, err))
+				}
+			}
+			peerConfigs = append(peerConfigs, peer)
+		}
+	}
+	if len(skipped) > 0 {
+		log.Printf("[convert] Skipping %d disabled connection(s): %s", len(skipped), strings.Join(skipped, ", "))
+	}
+	if len(forgotten) > 0 {
+		log.Printf("[convert] Forgetting %d connection(s) without destroying them: %s", len(forgotten), strings.Join(forgotten, ", "))
+	}
+	if len(lattice) > 0 {
+		log.Printf("[convert] Connecting %d connection(s) through the lattice service network instead: %s", len(lattice), strings.Join(lattice, ", "))
+	}
+	peerConfigs, err = MergeDuplicatePairs(peerConfigs)
+	if err != nil {
+		Fail(WithExitCode(ExitValidation, err))
+	}
+	if err := CheckDNSProfiles(peerConfigs); err != nil {
+		Fail(WithExitCode(ExitValidation, err))
+	}
+	log.Printf("[convert] Returning %d peer configs", len(peerConfigs))
+	return peerConfigs
+}
+
+// pcxIDPattern matches VPC peering connection IDs.
+var pcxIDPattern = regexp.MustCompile(`^pcx-[0-9a-f]+$`)
+
+// ResolveConnection converts one matrix entry of a source into a PeerConfig, validating its peers,
+// Name tag template, destination CIDRs, routing, and state.
+func ResolveConnection(cfg YAMLConfig, source string, entry MatrixEntry) (PeerConfig, error) {
+	target := entry.Peer
+	sourcePeer, ok := cfg.Peers[source]
+	if !ok {
+		return PeerConfig{}, fmt.Errorf("missing source peer config for %q", source)
+	}
+	peerPeer, ok := cfg.Peers[target]
+	if !ok {
+		return PeerConfig{}, fmt.Errorf("missing peer config for %q", target)
+	}
+	if sourcePeer.VpcID == peerPeer.VpcID {
+		return PeerConfig{}, fmt.Errorf("%q -> %q would peer VPC %s with itself", source, target, sourcePeer.VpcID)
+	}
+	sourcePartition := SidePartition(sourcePeer.RoleArn, sourcePeer.Region)
+	if peerPartition := SidePartition(peerPeer.RoleArn, peerPeer.Region); sourcePartition != peerPartition {
+		return PeerConfig{}, fmt.Errorf(
+			"%q -> %q spans partitions %s and %s: AWS supports neither VPC peering nor transit gateway peering "+
+				"across partitions; connect them with a VPN or Direct Connect instead",
+			source, target, sourcePartition, peerPartition,
+		)
+	}
+	if err := ValidateConnectivityMode(cfg, entry); err != nil {
+		return PeerConfig{}, fmt.Errorf("%q -> %q: %w", source, target, err)
+	}
+	if err := ValidateThirdPartyConnection(entry, sourcePeer, peerPeer); err != nil {
+		return PeerConfig{}, fmt.Errorf("%q -> %q: %w", source, target, err)
+	}
+	if entry.ConnectivityMode == ConnectivityLattice {
+		return PeerConfig{SourceVpcID: sourcePeer.VpcID, SourceRegion: sourcePeer.Region, SourceRoleArn: sourcePeer.RoleArn,
+			PeerVpcID: peerPeer.VpcID, PeerRegion: peerPeer.Region, PeerRoleArn: peerPeer.RoleArn,
+			SourceName: source, Name: target, Lattice: true}, nil
+	}
+
+	nameTagTemplate := cfg.NameTagTemplate
+	if entry.NameTagTemplate != "" {
+		nameTagTemplate = entry.NameTagTemplate
+	}
+	if err := ValidateNameTagTemplate(nameTagTemplate); err != nil {
+		return PeerConfig{}, fmt.Errorf("invalid name tag template for %q -> %q: %w", source, target, err)
+	}
+	for _, cidr := range entry.DestinationCidrs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return PeerConfig{}, fmt.Errorf("invalid destination CIDR for %q -> %q: %w", source, target, err)
+		}
+	}
+	if entry.DNSProfileArn != "" {
+		if err := ValidateDNSProfile(entry.DNSProfileArn, sourcePeer.Region, peerPeer.Region); err != nil {
+			return PeerConfig{}, fmt.Errorf("%q -> %q: %w", source, target, err)
+		}
+	}
+	sourceExtra, peerExtra, err := SplitExtraRoutes(entry.ExtraRoutes)
+	if err != nil {
+		return PeerConfig{}, fmt.Errorf("%q -> %q: %w", source, target, err)
+	}
+	conflicts := PeerConfig{SourceName: source, Name: target, DestinationCidrs: entry.DestinationCidrs,
+		SourceExtraCidrs: sourceExtra, PeerExtraCidrs: peerExtra}
+	for name, p := range map[string]YAMLPeer{source: sourcePeer, target: peerPeer} {
+		if err := ValidateRouteTableIDs(p); err != nil {
+			return PeerConfig{}, fmt.Errorf("peer %q: %w", name, err)
+		}
+		if err := ValidatePinnedCidr(p); err != nil {
+			return PeerConfig{}, fmt.Errorf("peer %q: %w", name, err)
+		}
+	}
+	if err := CheckLocalCidrs(conflicts, sourcePeer.KnownCidrs(), peerPeer.KnownCidrs()); err != nil {
+		return PeerConfig{}, fmt.Errorf("%q -> %q: %w", source, target, err)
+	}
+	for _, cidr := range entry.DestinationCidrs {
+		for _, extra := range sourceExtra {
+			if cidr == extra {
+				return PeerConfig{}, fmt.Errorf("%q -> %q lists %s in both destination_cidrs and extra_routes", source, target, cidr)
+			}
+		}
+	}
+	destinations := entry.DestinationCidrs
+	if entry.PreferOver != "" {
+		if destinations, err = PreferredDestinations(entry.PreferOver, entry.DestinationCidrs, peerPeer.KnownCidrs()); err != nil {
+			return PeerConfig{}, fmt.Errorf("%q -> %q: %w", source, target, err)
+		}
+		log.Printf("[convert] WARNING: %q -> %q routes %s instead of the whole blocks so they win over the %s route "+
+			"by prefix length; this adds %d route(s) per route table, and the halves must be revisited if the CIDRs change",
+			source, target, strings.Join(destinations, ", "), entry.PreferOver, len(destinations)/2)
+	}
+
+	decommission, err := entry.DecommissionStage()
+	if err != nil {
+		return PeerConfig{}, fmt.Errorf("invalid state for %q -> %q: %w", source, target, err)
+	}
+	externalPeering := entry.ManagePeering != nil && !*entry.ManagePeering
+	retiring := decommission == DecommissionPeering
+	switch {
+	case retiring && externalPeering:
+		return PeerConfig{}, fmt.Errorf("%q -> %q sets decommission: peering on a peering created elsewhere, which this tool does not remove", source, target)
+	case retiring && !pcxIDPattern.MatchString(entry.PeeringID):
+		return PeerConfig{}, fmt.Errorf("%q -> %q sets decommission: peering and needs peering_id: pcx-..., the peering its routes keep "+
+			"pointing at (state-report lists it), got %q", source, target, entry.PeeringID)
+	case retiring && entry.Acceptance == AcceptanceManual:
+		return PeerConfig{}, fmt.Errorf("%q -> %q sets decommission: peering with acceptance: manual, whose routes the %s stack manages; "+
+			"use a routes stage", source, target, AcceptStackName)
+	case externalPeering && !pcxIDPattern.MatchString(entry.PeeringID):
+		return PeerConfig{}, fmt.Errorf("%q -> %q sets manage_peering: false and needs peering_id: pcx-..., got %q", source, target, entry.PeeringID)
+	case externalPeering && entry.ManageRoutes != nil && !*entry.ManageRoutes:
+		return PeerConfig{}, fmt.Errorf("%q -> %q manages neither the peering nor its routes", source, target)
+	case !externalPeering && ((entry.PeeringID != "" && !retiring) || entry.ManageOptions):
+		return PeerConfig{}, fmt.Errorf("%q -> %q sets peering_id or manage_options, which require manage_peering: false", source, target)
+	case entry.Acceptance != "" && entry.Acceptance != AcceptanceAuto && entry.Acceptance != AcceptanceManual:
+		return PeerConfig{}, fmt.Errorf("%q -> %q has unknown acceptance %q (want %s or %s)", source, target, entry.Acceptance, AcceptanceAuto, AcceptanceManual)
+	case externalPeering && entry.Acceptance == AcceptanceManual:
+		return PeerConfig{}, fmt.Errorf("%q -> %q sets acceptance: manual on a peering created elsewhere", source, target)
+	case entry.Owner != "" && entry.Owner != source && entry.Owner != target:
+		return PeerConfig{}, fmt.Errorf("%q -> %q names owner %q, which must be %q or %q", source, target, entry.Owner, source, target)
+	case externalPeering && entry.Owner != "":
+		return PeerConfig{}, fmt.Errorf("%q -> %q sets owner on a peering created elsewhere", source, target)
+	}
+	if entry.ManageRoutes != nil && !*entry.ManageRoutes && (entry.SourceRoutes != nil || entry.PeerRoutes != nil) {
+		return PeerConfig{}, fmt.Errorf("%q -> %q sets manage_routes: false together with source_routes or peer_routes", source, target)
+	}
+	sourceRouting := ResolveRouting(legacyRouting(peerPeer.HasAdditionalRoutes, "cdktf-source-main-rt"), entry.SourceRoutes, sourcePeer.Routes)
+	peerRouting := ResolveRouting(legacyRouting(peerPeer.HasAdditionalRoutes, "cdktf-peer-main-rt"), entry.PeerRoutes, peerPeer.Routes)
+	sourceRouting = sourceRouting.withSubnetRouteKeys(cfg.SubnetRouteKeys)
+	peerRouting = peerRouting.withSubnetRouteKeys(cfg.SubnetRouteKeys)
+	if err := sourceRouting.Validate(); err != nil {
+		return PeerConfig{}, fmt.Errorf("invalid source_routes for %q -> %q: %w", source, target, err)
+	}
+	if err := peerRouting.Validate(); err != nil {
+		return PeerConfig{}, fmt.Errorf("invalid peer_routes for %q -> %q: %w", source, target, err)
+	}
+	if entry.Inspection != nil {
+		if entry.ManageRoutes != nil && !*entry.ManageRoutes {
+			return PeerConfig{}, fmt.Errorf("%q -> %q sets via_inspection, which needs manage_routes", source, target)
+		}
+		if err := ValidateInspection(*entry.Inspection, sourcePeer, peerPeer, sourceRouting, peerRouting); err != nil {
+			return PeerConfig{}, fmt.Errorf("%q -> %q: %w", source, target, err)
+		}
+	}
+	if peerPeer.ThirdParty() {
+		// The owner of a third-party VPC routes back on their own.
+		peerRouting = RoutingConfig{Strategy: RoutingNone}
+	}
+	if entry.ManageRoutes != nil && !*entry.ManageRoutes {
+		log.Printf("[convert] Routes of %q -> %q are managed elsewhere: creating the peering only", source, target)
+		sourceRouting, peerRouting = RoutingConfig{Strategy: RoutingNone}, RoutingConfig{Strategy: RoutingNone}
+	}
+	if retiring {
+		log.Printf("[convert] Decommissioning %q -> %q: removing the peering, keeping the routes on %s", source, target, entry.PeeringID)
+	} else if decommission != "" {
+		log.Printf("[convert] Decommissioning %q -> %q: removing %s, keeping the peering", source, target, decommission)
+		sourceRouting, peerRouting = DecommissionRouting(decommission, sourceRouting, peerRouting)
+	}
+
+	var lifecycle LifecycleConfig
+	if entry.Lifecycle != nil {
+		lifecycle = *entry.Lifecycle
+		if err := lifecycle.Validate(); err != nil {
+			return PeerConfig{}, fmt.Errorf("%q -> %q: %w", source, target, err)
+		}
+		if ignored := lifecycle.describe(); ignored != "" {
+			log.Printf("[convert] WARNING: %q -> %q ignores changes to %s; Terraform will not revert manual edits "+
+				"to them until lifecycle is removed", source, target, ignored)
+		}
+	}
+
+	accepterTags := entry.AccepterTags
+	if cfg.AccepterDetails.Tags {
+		accepterTags = mergeTags(entry.AccepterTags, AccepterDetailTags(source, target, sourcePeer))
+	}
+
+	var checkIPs CheckIPs
+	if entry.CheckIPs != nil {
+		checkIPs = *entry.CheckIPs
+		for _, ip := range []string{checkIPs.Source, checkIPs.Peer} {
+			if ip != "" && net.ParseIP(ip) == nil {
+				return PeerConfig{}, fmt.Errorf("invalid check_ips address for %q -> %q: %q", source, target, ip)
+			}
+		}
+	}
+
+	externalID, retiredID := entry.PeeringID, ""
+	if retiring {
+		externalID, retiredID = "", entry.PeeringID
+	}
+	return PeerConfig{
+		SourceVpcID:             sourcePeer.VpcID,
+		SourceRegion:            sourcePeer.Region,
+		SourceRoleArn:           sourcePeer.RoleArn,
+		PeerVpcID:               peerPeer.VpcID,
+		PeerRegion:              peerPeer.Region,
+		PeerRoleArn:             peerPeer.RoleArn,
+		PeerAccountID:           peerPeer.AccountID,
+		ThirdParty:              peerPeer.ThirdParty(),
+		SourceName:              source,
+		Name:                    target,
+		SourceEnv:               sourcePeer.Environment,
+		PeerEnv:                 peerPeer.Environment,
+		NameTagTemplate:         nameTagTemplate,
+		DestinationCidrs:        destinations,
+		SourceExtraCidrs:        sourceExtra,
+		PeerExtraCidrs:          peerExtra,
+		SourceCidrs:             sourcePeer.KnownCidrs(),
+		PeerCidrs:               peerPeer.KnownCidrs(),
+		SourceCidr:              sourcePeer.Cidr,
+		PeerCidr:                peerPeer.Cidr,
+		SourceMainRouteTableID:  sourcePeer.MainRouteTableID,
+		PeerMainRouteTableID:    peerPeer.MainRouteTableID,
+		SourceRouteTableIDs:     sourcePeer.RouteTableIDs,
+		PeerRouteTableIDs:       peerPeer.RouteTableIDs,
+		EnableDNSResolution:     peerPeer.DNSResolution,
+		DNSResolutionTags:       dnsResolutionTags(cfg, peerPeer),
+		HasExtraPeerRouteTables: peerPeer.HasAdditionalRoutes,
+		SourceRouting:           sourceRouting,
+		PeerRouting:             peerRouting,
+		Decommission:            decommission,
+		RetiredPeeringID:        retiredID,
+		CheckIPs:                checkIPs,
+		Tags:                    mergeTags(entry.Tags, cfg.Tags),
+		RequesterTags:           entry.RequesterTags,
+		AccepterTags:            accepterTags,
+		ExternalPeeringID:       externalID,
+		ManageExternalOptions:   entry.ManageOptions,
+		ManualAcceptance:        entry.Acceptance == AcceptanceManual,
+		Lifecycle:               lifecycle,
+		CrossRegionDNSAcked:     entry.CrossRegionDNS,
+		DNSProfileArn:           entry.DNSProfileArn,
+		DependsOn:               entry.DependsOn,
+		Freezes:                 ConnectionFreezes(cfg, source+"/"+target, entry),
+		Inspection:              entry.Inspection,
+	}, nil
+}
+
+// -------------------------------------------------------------------------------------------------
+// ARN and Account Helpers
+// -------------------------------------------------------------------------------------------------
+
+// DefaultRegion is the AWS region used for peers that do not declare one.
+const DefaultRegion = "us-west-2"
+
+// ResolveRegion returns the given region, or DefaultRegion when it is empty.
+func ResolveRegion(region string) string {
+	if region == "" {
+		return DefaultRegion
+	}
+	return region
+}
+
+// GetAccountIDFromRoleArn extracts the AWS account ID from a role ARN string in any partition.
+// It returns the account ID as a string, or an empty string if not found.
+func GetAccountIDFromRoleArn(roleArn string) string {
+	re := regexp.MustCompile(`^arn:aws[a-z-]*:iam::(\d+):`)
+	matches := re.FindStringSubmatch(roleArn)
+	if len(matches) == 2 {
+		return matches[1]
+	}
+	return ""
+}
+
+// roleNamePattern matches IAM role names.
+var roleNamePattern = regexp.MustCompile(`^[\w+=,.@-]{1,64}$`)
+
+// ValidateRoleArn checks that a value is an IAM role ARN, arn:<partition>:iam::<account>:role/[path/]name,
+// explaining what is wrong with values that are not: other ARN types users paste by mistake (users,
+// instance profiles, assumed-role sessions), unknown partitions, and truncated copies.
+func ValidateRoleArn(arn string) error {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" {
+		return fmt.Errorf("%q is not an ARN (want arn:aws:iam::<account>:role/<name>); is it truncated?", arn)
+	}
+	partition, service, region, account, resource := parts[1], parts[2], parts[3], parts[4], parts[5]
+	switch partition {
+	case PartitionAWS, PartitionChina, PartitionGovCloud, PartitionISO, PartitionISOB:
+	default:
+		return fmt.Errorf("%q has unknown partition %q", arn, partition)
+	}
+	switch {
+	case service == "sts" && strings.HasPrefix(resource, "assumed-role/"):
+		return fmt.Errorf("%q is an assumed-role session, not a role; use arn:%s:iam::%s:role/%s",
+			arn, partition, account, strings.Split(strings.TrimPrefix(resource, "assumed-role/"), "/")[0])
+	case service != "iam":
+		return fmt.Errorf("%q is a %s ARN, not an IAM role", arn, service)
+	case region != "":
+		return fmt.Errorf("%q sets region %q; IAM ARNs have none", arn, region)
+	case len(account) != 12 || strings.Trim(account, "0123456789") != "":
+		return fmt.Errorf("%q has account %q, which is not 12 digits; is it truncated?", arn, account)
+	}
+	kind := resource
+	if i := strings.Index(resource, "/"); i >= 0 {
+		kind = resource[:i]
+	}
+	switch kind {
+	case "role":
+	case "user", "group", "policy":
+		return fmt.Errorf("%q is an IAM %s ARN, not a role", arn, kind)
+	case "instance-profile":
+		return fmt.Errorf("%q is an instance profile ARN; use the ARN of the role it contains", arn)
+	default:
+		return fmt.Errorf("%q is not a role ARN (resource %q)", arn, resource)
+	}
+	name := resource[strings.LastIndex(resource, "/")+1:]
+	if !roleNamePattern.MatchString(name) {
+		return fmt.Errorf("%q has invalid role name %q; is it truncated?", arn, name)
+	}
+	return nil
+}
+
+// ValidatePeerRoleArns checks the role_arn of every peer that sets one, naming the peer in the error.
+func ValidatePeerRoleArns(cfg YAMLConfig) error {
+	for _, name := range sortedPeerNames(cfg) {
+		if arn := cfg.Peers[name].RoleArn; arn != "" {
+			if err := ValidateRoleArn(arn); err != nil {
+				return fmt.Errorf("peer %q: role_arn %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// AWS partitions, as they appear in ARNs. VPCs in different partitions cannot be connected with
+// either VPC peering or transit gateway peering.
+const (
+	PartitionAWS      = "aws"        // Commercial regions.
+	PartitionChina    = "aws-cn"     // China regions.
+	PartitionGovCloud = "aws-us-gov" // AWS GovCloud (US) regions.
+	PartitionISO      = "aws-iso"    // US ISO regions.
+	PartitionISOB     = "aws-iso-b"  // US ISOB regions.
+	PartitionISOE     = "aws-iso-e"  // EU ISOE regions.
+	PartitionISOF     = "aws-iso-f"  // US ISOF regions.
+	PartitionEUSC     = "aws-eusc"   // AWS European Sovereign Cloud regions.
+)
+
+// partitionPattern captures the partition of an ARN.
+var partitionPattern = regexp.MustCompile(`^arn:(aws[a-z-]*):`)
+
+// RegionPartition returns the partition a region belongs to.
+func RegionPartition(region string) string {
+	switch {
+	case strings.HasPrefix(region, "cn-"):
+		return PartitionChina
+	case strings.HasPrefix(region, "us-gov-"):
+		return PartitionGovCloud
+	case strings.HasPrefix(region, "us-isob-"):
+		return PartitionISOB
+	case strings.HasPrefix(region, "us-isof-"):
+		return PartitionISOF
+	case strings.HasPrefix(region, "eu-isoe-"):
+		return PartitionISOE
+	case strings.HasPrefix(region, "eusc-"):
+		return PartitionEUSC
+	case strings.HasPrefix(region, "us-iso-"):
+		return PartitionISO
+	default:
+		return PartitionAWS
+	}
+}
+
+// SidePartition returns the partition of one side of a connection: the partition of its role ARN,
+// or of its region when the role is absent or not an ARN.
+func SidePartition(roleArn, region string) string {
+	if m := partitionPattern.FindStringSubmatch(roleArn); m != nil {
+		return m[1]
+	}
+	return RegionPartition(ResolveRegion(region))
+}
+
+// -------------------------------------------------------------------------------------------------
+// AWS Provider and Data Source Creation (via interfaces)
+// -------------------------------------------------------------------------------------------------
+
+// vpcCidr returns the primary CIDR of one side of a connection and the VPC data source it is read
+// from. A pinned CIDR is used literally and needs no data source, so plans do not refresh the VPC;
+// with verify, the data source is kept with a postcondition failing the plan on a mismatch.
+func vpcCidr(vpcFactory DataAwsVpcFactory, stack cdktf.TerraformStack, name, vpcID, pinned string, provider awsprovider.AwsProvider, verify bool) (*string, dataawsvpc.DataAwsVpc) {
+	if pinned != "" && !verify {
+		return jsii.String(pinned), nil
+	}
+	data := vpcFactory.Create(stack, name, vpcID, provider)
+	if pinned == "" {
+		return data.CidrBlock(), data
+	}
+	data.AddOverride(jsii.String("lifecycle.postcondition"), []map[string]interface{}{{
+		"condition":     fmt.Sprintf("${self.cidr_block == %q}", pinned),
+		"error_message": fmt.Sprintf("The primary CIDR of %s no longer matches its pinned cidr %s; update the peering config.", vpcID, pinned),
+	}})
+	return jsii.String(pinned), data
+}
+
+// mainRouteTable returns the ID of a VPC's main route table and the data source it is read from,
+// which is only created when the ID is not declared.
+func mainRouteTable(rtFactory DataAwsRouteTableFactory, stack cdktf.TerraformStack, name, vpcID, declared string, provider awsprovider.AwsProvider) (*string, dataawsroutetable.DataAwsRouteTable) {
+	if declared != "" {
+		return jsii.String(declared), nil
+	}
+	data := rtFactory.Create(stack, name, vpcID, provider)
+	return data.Id(), data
+}
+
+// vpcCidrExpr returns an HCL expression for the primary CIDR of one side's VPC, for blocks written
+// as raw Terraform: the pinned CIDR, or the attribute of the side's VPC data source.
+func vpcCidrExpr(namer Namer, ctx NameContext, vpcKind, pinned string) string {
+	if pinned != "" {
+		return fmt.Sprintf("%q", pinned)
+	}
+	return fmt.Sprintf("data.aws_vpc.%s.cidr_block", namer.ID(ctx, vpcKind))
+}
+
+// SetupPeerCoreResources creates all core AWS provider and data source resources for a peer.
+// Uses factories for testability. VPCs with a pinned CIDR are only looked up when verifyCidrs is set,
+// and main route tables with a declared ID never are.
+func SetupPeerCoreResources(
+	providerFactory AwsProviderFactory,
+	vpcFactory DataAwsVpcFactory,
+	rtFactory DataAwsRouteTableFactory,
+	stack cdktf.TerraformStack,
+	namer Namer,
+	ctx NameContext,
+	peer PeerConfig,
+	sourceRegion, peerRegion string,
+	verifyCidrs bool,
+) PeerCoreResources {
+	sourceProviderName := namer.ID(ctx, KindSourceProvider)
+	sourceProviderAlias := namer.ID(ctx, KindSourceProviderAlias)
+	peerProviderName := namer.ID(ctx, KindPeerProvider)
+	peerProviderAlias := namer.ID(ctx, KindPeerProviderAlias)
+	sourceProvider := providerFactory.Create(stack, sourceProviderName, sourceProviderAlias, sourceRegion, peer.SourceRoleArn)
+	var peerProvider awsprovider.AwsProvider
+	if !peer.ThirdParty {
+		peerProvider = providerFactory.Create(stack, peerProviderName, peerProviderAlias, peerRegion, peer.PeerRoleArn)
+	}
+
+	sourceVpcName := namer.ID(ctx, KindSourceVpc)
+	peerVpcName := namer.ID(ctx, KindPeerVpc)
+	sourceCidr, sourceVpcData := vpcCidr(vpcFactory, stack, sourceVpcName, peer.SourceVpcID, peer.SourceCidr, sourceProvider, verifyCidrs)
+	peerCidr, peerVpcData := vpcCidr(vpcFactory, stack, peerVpcName, peer.PeerVpcID, peer.PeerCidr, peerProvider, verifyCidrs && !peer.ThirdParty)
+	if peerVpcData == nil && !peer.EnableDNSResolution && len(peer.DNSResolutionTags) > 0 {
+		// The DNS resolution policy reads the peer VPC's tags even when its CIDR is pinned.
+		peerVpcData = vpcFactory.Create(stack, peerVpcName, peer.PeerVpcID, peerProvider)
+	}
+
+	sourceMainRtName := namer.ID(ctx, KindSourceMainRt)
+	peerMainRtName := namer.ID(ctx, KindPeerMainRt)
+	sourceMainRtID, sourceMainRt := mainRouteTable(rtFactory, stack, sourceMainRtName, peer.SourceVpcID, peer.SourceMainRouteTableID, sourceProvider)
+	var peerMainRtID *string
+	var peerMainRt dataawsroutetable.DataAwsRouteTable
+	if peer.ThirdParty {
+		// Nothing is looked up or routed in a third party's VPC.
+		peerMainRtID = jsii.String(peer.PeerMainRouteTableID)
+	} else {
+		peerMainRtID, peerMainRt = mainRouteTable(rtFactory, stack, peerMainRtName, peer.PeerVpcID, peer.PeerMainRouteTableID, peerProvider)
+	}
+
+	return PeerCoreResources{
+		SourceProvider: sourceProvider,
+		PeerProvider:   peerProvider,
+		SourceVpcData:  sourceVpcData,
+		PeerVpcData:    peerVpcData,
+		SourceMainRt:   sourceMainRt,
+		PeerMainRt:     peerMainRt,
+		SourceCidr:     sourceCidr,
+		PeerCidr:       peerCidr,
+		SourceMainRtID: sourceMainRtID,
+		PeerMainRtID:   peerMainRtID,
+	}
+}
+
+// -------------------------------------------------------------------------------------------------
+// Output and Route Helpers
+// -------------------------------------------------------------------------------------------------
+
+// ConnectionsOutputID is the ID of the aggregated output mapping each connection key to its
+// details, for consumption by other tooling.
+const ConnectionsOutputID = "connections"
+
+// PeeringSummaryOutputID is the ID of the output holding the JSON-encoded summary of every
+// connection, read with terraform output -json peering_summary.
+const PeeringSummaryOutputID = "peering_summary"
+
+// AddOutputs creates Terraform outputs for each connection's peering ID and accept status, main route
+// table IDs, per-direction DNS resolution flags, peer owner account, and requester/accepter CIDRs, plus
+// the aggregated connections map and the JSON peering summary.
+func AddOutputs(stack cdktf.TerraformStack, namer Namer, connections []ConnectionResources) {
+	aggregated := make(map[string]interface{}, len(connections))
+	for i, c := range connections {
+		ctx := ConnectionNameContext(i, c.Peer)
+		for _, v := range connectionValues(c) {
+			cdktf.NewTerraformOutput(stack, jsii.String(namer.ID(ctx, v.kind)), &cdktf.TerraformOutputConfig{
+				Value: v.value,
+			})
+		}
+		aggregated[ConnectionKey(c.Peer)] = ConnectionDetails(c)
+	}
+	cdktf.NewTerraformOutput(stack, jsii.String(ConnectionsOutputID), &cdktf.TerraformOutputConfig{
+		Value: aggregated,
+	})
+	cdktf.NewTerraformOutput(stack, jsii.String(PeeringSummaryOutputID), &cdktf.TerraformOutputConfig{
+		Value: cdktf.Fn_Jsonencode(PeeringSummary(*stack.Node().Id(), connections)),
+	})
+}
+
+// PeeringSummary returns the summary of a stack's connections in connection key order: the details
+// of the connections output plus each connection's key, routed CIDRs, and flags.
+func PeeringSummary(stackName string, connections []ConnectionResources) map[string]interface{} {
+	sorted := append([]ConnectionResources(nil), connections...)
+	sort.SliceStable(sorted, func(i, j int) bool { return ConnectionKey(sorted[i].Peer) < ConnectionKey(sorted[j].Peer) })
+
+	items := make([]interface{}, 0, len(sorted))
+	for _, c := range sorted {
+		item := ConnectionDetails(c)
+		for key, value := range ConnectionSummary(c.Peer) {
+			item[key] = value
+		}
+		items = append(items, item)
+	}
+	return map[string]interface{}{
+		"stack":       stackName,
+		"connections": items,
+	}
+}
+
+// ConnectionSummary returns what the config says about a connection beyond its live details: its
+// key, the CIDRs each side routes (an empty destination_cidrs means the whole peer VPC), the routing
+// strategy of each side, and its flags.
+func ConnectionSummary(peer PeerConfig) map[string]interface{} {
+	orEmpty := func(values []string) []string {
+		if values == nil {
+			return []string{}
+		}
+		return values
+	}
+	return map[string]interface{}{
+		"key":                ConnectionKey(peer),
+		"destination_cidrs":  orEmpty(peer.DestinationCidrs),
+		"source_extra_cidrs": orEmpty(peer.SourceExtraCidrs),
+		"peer_extra_cidrs":   orEmpty(peer.PeerExtraCidrs),
+		"source_routing":     peer.SourceRouting.Strategy,
+		"peer_routing":       peer.PeerRouting.Strategy,
+		"flags": map[string]bool{
+			"dns_resolution":    peer.EnableDNSResolution,
+			"manual_acceptance": peer.ManualAcceptance,
+			"external_peering":  peer.ExternalPeeringID != "",
+			"shared_peering":    peer.SharedPeering,
+			"decommissioning":   peer.Decommission != "",
+			"inspected":         peer.Inspection != nil,
+		},
+	}
+}
+
+// connectionValue is one per-connection output, with its key in the aggregated details ("" when
+// only kept as a standalone output for compatibility).
+type connectionValue struct {
+	kind  string
+	key   string
+	value interface{}
+}
+
+// connectionValues returns the per-connection outputs in order.
+func connectionValues(c ConnectionResources) []connectionValue {
+	// Options of external peerings are read from the lookup unless this stack manages them; peerings
+	// awaiting manual acceptance and those with third parties have none set.
+	requesterDNS := dnsResolutionValue(c.Peer, c.Core)
+	accepterDNS := requesterDNS
+	if IsRequesterOnly(c.Peer) || c.Peer.ThirdParty || c.Peering.RetiredID != nil {
+		requesterDNS, accepterDNS = false, false
+	} else if c.Peering.Options == nil {
+		requesterDNS = fmt.Sprintf("${try(data.aws_vpc_peering_connection.%s.requester.allow_remote_vpc_dns_resolution, false)}",
+			*c.Peering.Data.FriendlyUniqueId())
+		accepterDNS = fmt.Sprintf("${try(data.aws_vpc_peering_connection.%s.accepter.allow_remote_vpc_dns_resolution, false)}",
+			*c.Peering.Data.FriendlyUniqueId())
+	}
+	peerOwner := c.Peering.PeerOwnerID()
+	if peerOwner == nil {
+		peerOwner = jsii.String(PeerAccount(c.Peer))
+	}
+
+	return []connectionValue{
+		{KindOutputPeeringID, "peering_id", c.Peering.PeeringID()},
+		{KindOutputAcceptStatus, "accept_status", c.Peering.AcceptStatus()},
+		{KindOutputSourceMainRt, "requester_main_route_table_id", c.Core.SourceMainRtID},
+		{KindOutputPeerMainRt, "accepter_main_route_table_id", c.Core.PeerMainRtID},
+		{KindOutputDNSResolution, "", requesterDNS},
+		{KindOutputRequesterDNS, "requester_dns_resolution", requesterDNS},
+		{KindOutputAccepterDNS, "accepter_dns_resolution", accepterDNS},
+		{KindOutputPeerOwner, "peer_owner_id", peerOwner},
+		{KindOutputRequesterCidr, "requester_cidr", c.Core.SourceCidr},
+		{KindOutputAccepterCidr, "accepter_cidr", c.Core.PeerCidr},
+	}
+}
+
+// ConnectionDetails returns the details of one connection as the aggregated connections output and
+// the inventory document present them. Values not known until apply are tokens.
+func ConnectionDetails(c ConnectionResources) map[string]interface{} {
+	details := map[string]interface{}{
+		"source":           c.Peer.SourceName,
+		"peer":             ConnectionNameContext(0, c.Peer).Peer,
+		"requester_vpc_id": c.Peer.SourceVpcID,
+		"accepter_vpc_id":  c.Peer.PeerVpcID,
+		"requester_region": ResolveRegion(c.Peer.SourceRegion),
+		"accepter_region":  ResolveRegion(c.Peer.PeerRegion),
+	}
+	for _, v := range connectionValues(c) {
+		if v.key != "" {
+			details[v.key] = v.value
+		}
+	}
+	return details
+}
+
+// RouteTarget is a destination CIDR paired with the construct ID of the route that sends it
+// through the peering.
+type RouteTarget struct {
+	ID   string  // Construct ID of the route resource.
+	Cidr *string // Destination CIDR block (literal or token).
+}
+
+// RouteTargets builds one RouteTarget per explicit CIDR, suffixing the kind's construct ID with the
+// CIDR so adding or removing a CIDR never renames the others. Without explicit CIDRs a single target
+// for the fallback (usually the opposing VPC CIDR) keeps the unsuffixed ID. Extra CIDRs are routed
+// in addition to either, with suffixed IDs.
+func RouteTargets(namer Namer, ctx NameContext, kind string, cidrs, extra []string, fallback *string) []RouteTarget {
+	base := namer.ID(ctx, kind)
+	targets := make([]RouteTarget, 0, len(cidrs)+len(extra)+1)
+	if len(cidrs) == 0 {
+		targets = append(targets, RouteTarget{ID: base, Cidr: fallback})
+	}
+	for _, cidr := range append(append([]string(nil), cidrs...), extra...) {
+		id := CidrRouteID(base, cidr)
+		claimDerivedID(namer, id, ctx, kind+":"+cidr)
+		targets = append(targets, RouteTarget{ID: id, Cidr: jsii.String(cidr)})
+	}
+	return targets
+}
+
+// CidrRouteID derives the construct ID of a CIDR-specific route from the kind's base ID.
+func CidrRouteID(base, cidr string) string {
+	return base + "_" + invalidIDChars.ReplaceAllString(cidr, "_")
+}
+
+// SubnetRouteTablesLocal returns the name of the local holding the set of route table IDs that
+// CreateRouteTableKeyedSubnetRoutes routes through, for the route table data source of the given
+// Terraform name.
+func SubnetRouteTablesLocal(routeTableResourceName string) string {
+	return routeTableResourceName + "Ids"
+}
+
+// CreateSubnetRoutes creates routes for each subnet in a VPC using a TerraformIterator escape hatch,
+// one route resource per target, keyed by subnet ID.
+func CreateSubnetRoutes(
+	stack cdktf.TerraformStack,
+	routeTableResourceName string,
+	targets []RouteTarget,
+	subnetIDs *[]*string,
+	provider cdktf.TerraformProvider,
+	peeringID *string,
+	dependsOn []cdktf.ITerraformDependable,
+) SideResources {
+	iterator := cdktf.TerraformIterator_FromList(subnetIDs)
+	routeTables := dataawsroutetable.NewDataAwsRouteTable(stack, jsii.String(routeTableResourceName), &dataawsroutetable.DataAwsRouteTableConfig{
+		ForEach:  iterator,
+		SubnetId: jsii.String("${each.value}"),
+		Provider: provider,
+	})
+	res := SideResources{DataSources: []cdktf.TerraformDataSource{routeTables}}
+	for _, target := range targets {
+		res.Routes = append(res.Routes, awsroute.NewRoute(stack, jsii.String(target.ID), &awsroute.RouteConfig{
+			ForEach:                iterator,
+			RouteTableId:           jsii.String("${data.aws_route_table." + *routeTables.FriendlyUniqueId() + "[each.key].id}"),
+			DestinationCidrBlock:   target.Cidr,
+			VpcPeeringConnectionId: peeringID,
+			Provider:               provider,
+			DependsOn:              &dependsOn,
+		}))
+		res.Info = append(res.Info, RouteInfo{
+			Cidr:    target.Cidr,
+			ForEach: "toset(keys(data.aws_route_table." + *routeTables.FriendlyUniqueId() + "))",
+			Tables:  "data.aws_route_table." + *routeTables.FriendlyUniqueId(),
+		})
+	}
+	return res
+}
+
+// CreateRouteTableKeyedSubnetRoutes creates routes to each target in the route tables the subnets of
+// subnetIDs, an HCL expression of a list of subnet IDs, are explicitly associated with, for filtered
+// routing with key_by_route_table. A single aws_route_tables data source finds the tables of every
+// subnet, and a local flattens its result into the set of table IDs the routes iterate over, keyed by
+// table ID, so a table shared by several subnets gets one route per target. The data source is
+// skipped when no subnet matches, since EC2 rejects a filter without values.
+func CreateRouteTableKeyedSubnetRoutes(
+	stack cdktf.TerraformStack,
+	routeTableResourceName string,
+	targets []RouteTarget,
+	subnetIDs string,
+	provider cdktf.TerraformProvider,
+	peeringID *string,
+	dependsOn []cdktf.ITerraformDependable,
+) SideResources {
+	routeTables := dataawsroutetables.NewDataAwsRouteTables(stack, jsii.String(routeTableResourceName), &dataawsroutetables.DataAwsRouteTablesConfig{
+		Provider: provider,
+	})
+	routeTables.AddOverride(jsii.String("count"), fmt.Sprintf("${length(%s) > 0 ? 1 : 0}", subnetIDs))
+	routeTables.AddOverride(jsii.String("filter"), []map[string]interface{}{{
+		"name":   "association.subnet-id",
+		"values": "${" + subnetIDs + "}",
+	}})
+	name := *routeTables.FriendlyUniqueId()
+	tableIDs := cdktf.NewTerraformLocal(stack, jsii.String(SubnetRouteTablesLocal(name)),
+		fmt.Sprintf("${toset(flatten(data.aws_route_tables.%s[*].ids))}", name))
+
+	iterator := cdktf.TerraformIterator_FromList(tableIDs.AsList())
+	res := SideResources{DataSources: []cdktf.TerraformDataSource{routeTables}}
+	for _, target := range targets {
+		res.Routes = append(res.Routes, awsroute.NewRoute(stack, jsii.String(target.ID), &awsroute.RouteConfig{
+			ForEach:                iterator,
+			RouteTableId:           jsii.String("${each.value}"),
+			DestinationCidrBlock:   target.Cidr,
+			VpcPeeringConnectionId: peeringID,
+			Provider:               provider,
+			DependsOn:              &dependsOn,
+		}))
+		res.Info = append(res.Info, RouteInfo{
+			Cidr:    target.Cidr,
+			ForEach: "local." + *tableIDs.FriendlyUniqueId(),
+		})
+	}
+	return res
+}
+
+// CreateRoute creates a route in a given route table for a VPC peering connection.
+func CreateRoute(
+	stack cdktf.TerraformStack,
+	name string,
+	routeTableID *string,
+	destCidr *string,
+	peeringID *string,
+	provider cdktf.TerraformProvider,
+	dependsOn []cdktf.ITerraformDependable,
+) awsroute.Route {
+	return awsroute.NewRoute(stack, jsii.String(name), &awsroute.RouteConfig{
+		RouteTableId:           routeTableID,
+		DestinationCidrBlock:   destCidr,
+		VpcPeeringConnectionId: peeringID,
+		Provider:               provider,
+		DependsOn:              &dependsOn,
+	})
+}
+
+// LookupSubnets looks up the subnets of a VPC carrying the given tags.
+func LookupSubnets(
+	stack cdktf.TerraformStack,
+	name string,
+	vpcID string,
+	provider cdktf.TerraformProvider,
+	subnetTags map[string]string,
+) dataawssubnets.DataAwsSubnets {
+	filters := []*dataawssubnets.DataAwsSubnetsFilter{{
+		Name:   jsii.String("vpc-id"),
+		Values: jsii.Strings(vpcID),
+	}}
+	tagKeys := make([]string, 0, len(subnetTags))
+	for key := range subnetTags {
+		tagKeys = append(tagKeys, key)
+	}
+	sort.Strings(tagKeys)
+	for _, key := range tagKeys {
+		filters = append(filters, &dataawssubnets.DataAwsSubnetsFilter{
+			Name:   jsii.String("tag:" + key),
+			Values: jsii.Strings(subnetTags[key]),
+		})
+	}
+
+	return dataawssubnets.NewDataAwsSubnets(stack, jsii.String(name), &dataawssubnets.DataAwsSubnetsConfig{
+		Provider: provider,
+		Filter:   &filters,
+	})
+}
+
+// CreateFilteredSubnetRoutes creates subnet routes for subnets matching a tag filter, keyed by subnet
+// ID or, with byRouteTable, by route table ID.
+func CreateFilteredSubnetRoutes(
+	stack cdktf.TerraformStack,
+	targets []RouteTarget,
+	subnetResourceName string,
+	vpcID string,
+	provider cdktf.TerraformProvider,
+	subnetTags map[string]string,
+	routeTableResourceName string,
+	byRouteTable bool,
+	peeringID *string,
+	dependsOn []cdktf.ITerraformDependable,
+) SideResources {
+	subnets := LookupSubnets(stack, subnetResourceName, vpcID, provider, subnetTags)
+	res := SideResources{DataSources: []cdktf.TerraformDataSource{subnets}}
+	if subnets.Ids() != nil {
+		var routes SideResources
+		if byRouteTable {
+			subnetIDs := fmt.Sprintf("data.aws_subnets.%s.ids", *subnets.FriendlyUniqueId())
+			routes = CreateRouteTableKeyedSubnetRoutes(stack, routeTableResourceName, targets, subnetIDs, provider, peeringID, dependsOn)
+		} else {
+			routes = CreateSubnetRoutes(stack, routeTableResourceName, targets, subnets.Ids(), provider, peeringID, dependsOn)
+		}
+		res.Routes = routes.Routes
+		res.Info = routes.Info
+		res.DataSources = append(res.DataSources, routes.DataSources...)
+	}
+	return res
+}
+
+// -------------------------------------------------------------------------------------------------
+// Core Resource and Peering Logic
+// -------------------------------------------------------------------------------------------------
+
+// CreatePeeringResources creates the VPC peering connection, conditional accepter, and options resources.
+func CreatePeeringResources(
+	stack cdktf.TerraformStack,
+	namer Namer,
+	ctx NameContext,
+	peer PeerConfig,
+	core PeerCoreResources,
+	peerOwnerID string,
+	autoAccept bool,
+	peerRegion string,
+) PeeringResources {
+	if peer.LooksUpPeering() {
+		return LookupExternalPeering(stack, namer, ctx, peer, core)
+	}
+	if peer.RetiredPeeringID != "" {
+		// The peering leaves the stack, so the apply deletes it; the routes keep its pcx-id.
+		return PeeringResources{RetiredID: jsii.String(peer.RetiredPeeringID)}
+	}
+
+	peeringConfig := &vpcpeeringconnection.VpcPeeringConnectionConfig{
+		VpcId:       jsii.String(peer.SourceVpcID),
+		PeerVpcId:   jsii.String(peer.PeerVpcID),
+		PeerOwnerId: jsii.String(peerOwnerID),
+		Provider:    core.SourceProvider,
+		AutoAccept:  jsii.Bool(autoAccept),
+		Tags:        stringPtrMap(PeeringTags(namer, ctx, peer, SideRequester)),
+	}
+	if core.SourceProvider != core.PeerProvider {
+		peeringConfig.PeerRegion = jsii.String(peerRegion)
+	}
+
+	peering := vpcpeeringconnection.NewVpcPeeringConnection(
+		stack,
+		jsii.String(namer.ID(ctx, KindPeering)),
+		peeringConfig,
+	)
+	if IsRequesterOnly(peer) || peer.ThirdParty {
+		return PeeringResources{Peering: peering, DependsOn: []cdktf.ITerraformDependable{peering}}
+	}
+
+	var accepter cdktf.TerraformResource
+	if !autoAccept {
+		accepter = cdktf.NewTerraformResource(stack, jsii.String(namer.ID(ctx, KindAccepter)), &cdktf.TerraformResourceConfig{
+			TerraformResourceType: jsii.String("aws_vpc_peering_connection_accepter"),
+			Provider:              core.PeerProvider,
+			DependsOn:             &[]cdktf.ITerraformDependable{peering},
+		})
+		accepter.AddOverride(jsii.String("vpc_peering_connection_id"), peering.Id())
+		accepter.AddOverride(jsii.String("auto_accept"), true)
+		accepter.AddOverride(jsii.String("tags"), PeeringTags(namer, ctx, peer, SideAccepter))
+	}
+
+	var optionsDependsOn []cdktf.ITerraformDependable
+	optionsDependsOn = append(optionsDependsOn, peering)
+	if accepter != nil {
+		optionsDependsOn = append(optionsDependsOn, accepter)
+	}
+	dns := dnsResolutionValue(peer, core)
+	opts := createPeeringOptions(stack, namer.ID(ctx, KindOptions), SideRequester, core.SourceProvider, peering.Id(), dns, optionsDependsOn)
+	accepterOpts := createPeeringOptions(stack, namer.ID(ctx, KindAccepterOptions), SideAccepter, core.PeerProvider, peering.Id(), dns, optionsDependsOn)
+
+	var dependsOn []cdktf.ITerraformDependable
+	dependsOn = append(dependsOn, peering)
+	if !autoAccept && accepter != nil {
+		dependsOn = append(dependsOn, accepter)
+	}
+
+	return PeeringResources{
+		Peering:         peering,
+		Accepter:        accepter,
+		Options:         opts,
+		AccepterOptions: accepterOpts,
+		DependsOn:       dependsOn,
+	}
+}
+
+// createPeeringOptions creates the options of one side of a peering. Each side's options can only
+// be changed by the account and region owning that side's VPC, so each gets its own resource
+// through that side's provider; the other side's block is left unset and therefore unmanaged. DNS
+// resolution is the only option set: the ClassicLink options are gone (see classicLinkOptions).
+func createPeeringOptions(
+	stack cdktf.TerraformStack,
+	id string,
+	side string,
+	provider cdktf.TerraformProvider,
+	peeringID *string,
+	dnsResolution interface{},
+	dependsOn []cdktf.ITerraformDependable,
+) cdktf.TerraformResource {
+	config := &cdktf.TerraformResourceConfig{
+		TerraformResourceType: jsii.String("aws_vpc_peering_connection_options"),
+		Provider:              provider,
+	}
+	if len(dependsOn) > 0 {
+		config.DependsOn = &dependsOn
+	}
+	opts := cdktf.NewTerraformResource(stack, jsii.String(id), config)
+	opts.AddOverride(jsii.String("vpc_peering_connection_id"), peeringID)
+	opts.AddOverride(jsii.String(side+".allow_remote_vpc_dns_resolution"), dnsResolution)
+	return opts
+}
+
+// LookupExternalPeering reads a peering created elsewhere so routes can be sent through it: by
+// pcx-id, or by VPC pair when the stack of another source owns it. Its options are managed, on both
+// sides, only when the connection asks for it. Peerings requested by the main stack for manual
+// acceptance are accepted here first.
+func LookupExternalPeering(
+	stack cdktf.TerraformStack,
+	namer Namer,
+	ctx NameContext,
+	peer PeerConfig,
+	core PeerCoreResources,
+) PeeringResources {
+	data := cdktf.NewTerraformDataSource(stack, jsii.String(namer.ID(ctx, KindPeeringData)), &cdktf.TerraformResourceConfig{
+		TerraformResourceType: jsii.String("aws_vpc_peering_connection"),
+		Provider:              core.SourceProvider,
+	})
+	if peer.ExternalPeeringID != "" {
+		data.AddOverride(jsii.String("id"), peer.ExternalPeeringID)
+	} else {
+		// The owner's stack requested the peering from its VPC, the peer VPC of this connection.
+		data.AddOverride(jsii.String("vpc_id"), peer.PeerVpcID)
+		data.AddOverride(jsii.String("peer_vpc_id"), peer.SourceVpcID)
+		data.AddOverride(jsii.String("status"), "active")
+	}
+
+	res := PeeringResources{Data: data}
+	if peer.ManualAcceptance {
+		accepter := cdktf.NewTerraformResource(stack, jsii.String(namer.ID(ctx, KindAccepter)), &cdktf.TerraformResourceConfig{
+			TerraformResourceType: jsii.String("aws_vpc_peering_connection_accepter"),
+			Provider:              core.PeerProvider,
+		})
+		accepter.AddOverride(jsii.String("vpc_peering_connection_id"), res.PeeringID())
+		accepter.AddOverride(jsii.String("auto_accept"), true)
+		accepter.AddOverride(jsii.String("tags"), PeeringTags(namer, ctx, peer, SideAccepter))
+		res.Accepter = accepter
+		res.DependsOn = []cdktf.ITerraformDependable{accepter}
+	}
+	if peer.ManageExternalOptions {
+		dns := dnsResolutionValue(peer, core)
+		res.Options = createPeeringOptions(stack, namer.ID(ctx, KindOptions), SideRequester, core.SourceProvider, res.PeeringID(), dns, res.DependsOn)
+		res.AccepterOptions = createPeeringOptions(stack, namer.ID(ctx, KindAccepterOptions), SideAccepter, core.PeerProvider, res.PeeringID(), dns, res.DependsOn)
+	}
+	return res
+}
+
+// CreateBiDirectionalSubnetRoutes creates all main and subnet route table entries required for bi-directional routing between two VPCs in a peering relationship.
+// Each side is routed independently according to its RoutingConfig.
+func CreateBiDirectionalSubnetRoutes(
+	stack cdktf.TerraformStack,
+	namer Namer,
+	ctx NameContext,
+	peer PeerConfig,
+	core PeerCoreResources,
+	peeringRes PeeringResources,
+) RouteResources {
+	source := CreateSideRoutes(
+		stack,
+		namer,
+		ctx,
+		sourceSide,
+		peer.SourceRouting,
+		peer.SourceVpcID,
+		core.SourceMainRtID,
+		peer.SourceRouteTableIDs,
+		core.SourceProvider,
+		peer.DestinationCidrs,
+		peer.SourceExtraCidrs,
+		core.PeerCidr,
+		peeringRes,
+	)
+
+	peerRoutes := CreateSideRoutes(
+		stack,
+		namer,
+		ctx,
+		peerSide,
+		peer.PeerRouting,
+		peer.PeerVpcID,
+		core.PeerMainRtID,
+		peer.PeerRouteTableIDs,
+		core.PeerProvider,
+		nil,
+		peer.PeerExtraCidrs,
+		core.SourceCidr,
+		peeringRes,
+	)
+	routes := RouteResources{Source: source, Peer: peerRoutes}
+	ApplyInspection(stack, namer, ctx, peer, core, peeringRes, &routes)
+	return routes
+}
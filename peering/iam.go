@@ -0,0 +1,447 @@
+package peering
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Least-Privilege Role Policies
+// -------------------------------------------------------------------------------------------------
+
+// PolicyDocument is an IAM policy document.
+type PolicyDocument struct {
+	Version   string            `json:"Version"`
+	Statement []PolicyStatement `json:"Statement"`
+}
+
+// PolicyStatement is a single IAM policy statement.
+type PolicyStatement struct {
+	Sid       string                         `json:"Sid"`
+	Effect    string                         `json:"Effect"`
+	Principal map[string][]string            `json:"Principal,omitempty"`
+	Action    []string                       `json:"Action"`
+	Resource  []string                       `json:"Resource,omitempty"`
+	Condition map[string]map[string][]string `json:"Condition,omitempty"`
+}
+
+// PolicyOptions enables statements for optional features: flow logs and hosted zones, which the
+// peering stack itself does not manage but roles in the same accounts commonly need, and the route
+// provenance records the stack writes when the config enables them.
+type PolicyOptions struct {
+	FlowLogs        bool                  // Create and delete VPC flow logs on the configured VPCs.
+	Route53         bool                  // Associate the configured VPCs with private hosted zones across accounts.
+	Quotas          bool                  // Read the VPC quotas quota-check compares the config with.
+	Provenance      ProvenanceConfig      // Tag route tables and write SSM parameters as route_provenance configures.
+	OwnedPeerings   OwnedPeeringsConfig   // Publish and read the pcx-ids of peerings both sources list, as owned_peerings configures.
+	AccepterDetails AccepterDetailsConfig // Write connection details to SSM in accepter accounts, as accepter_details configures.
+}
+
+// roleUsage collects what the tool does with one assumed role across all connections.
+type roleUsage struct {
+	requesterVpcs map[string]bool // VPC ARNs the role requests peerings from.
+	accepterVpcs  map[string]bool // Peer VPC ARNs of peerings the role requests.
+	acceptedVpcs  map[string]bool // VPC ARNs the role accepts peerings into.
+	routedVpcs    map[string]bool // VPC ARNs whose route tables the role changes.
+	optionVpcs    map[string]bool // Requester VPC ARNs of external peerings whose options the role changes.
+	accOptionVpcs map[string]bool // Accepter VPC ARNs of peerings whose accepter options the role changes.
+	dedicatedVpcs map[string]bool // VPC ARNs the role creates dedicated subnet route tables in.
+	sharedVpcs    map[string]bool // Owner VPC ARNs of peerings both sources list, whose pcx-id the role shares.
+	detailVpcs    map[string]bool // Accepter VPC ARNs of connections whose details the role publishes.
+	dnsProfiles   map[string]bool // Route 53 Profile ARNs the role associates its VPCs with.
+}
+
+// vpcArn returns the ARN of a VPC. Accounts that cannot be derived from the role use a wildcard.
+func vpcArn(region, account, vpcID string) string {
+	if account == "" {
+		account = "*"
+	}
+	region = ResolveRegion(region)
+	return fmt.Sprintf("arn:%s:ec2:%s:%s:vpc/%s", RegionPartition(region), region, account, vpcID)
+}
+
+// RolePolicies returns the least-privilege policy of every role the config assumes, keyed by role
+// ARN. Requester roles may create, modify, and delete peerings from their VPCs; accepter roles (and
+// requester roles of auto-accepted peerings) may accept into theirs; both may change routes in the
+// route tables of their VPCs. Describe calls used by the data sources cannot be scoped.
+func RolePolicies(peers []PeerConfig, opts PolicyOptions) map[string]PolicyDocument {
+	usage := make(map[string]*roleUsage)
+	use := func(roleArn string) *roleUsage {
+		u, ok := usage[roleArn]
+		if !ok {
+			u = &roleUsage{map[string]bool{}, map[string]bool{}, map[string]bool{}, map[string]bool{}, map[string]bool{}, map[string]bool{}, map[string]bool{}, map[string]bool{}, map[string]bool{}, map[string]bool{}}
+			usage[roleArn] = u
+		}
+		return u
+	}
+
+	for _, peer := range peers {
+		if peer.SourceRoleArn == "" || (peer.PeerRoleArn == "" && !peer.ThirdParty) {
+			continue
+		}
+		source := vpcArn(peer.SourceRegion, GetAccountIDFromRoleArn(peer.SourceRoleArn), peer.SourceVpcID)
+		target := vpcArn(peer.PeerRegion, PeerAccount(peer), peer.PeerVpcID)
+
+		requester := use(peer.SourceRoleArn)
+		if peer.ThirdParty {
+			// Only the request and the source routes; the third party accepts.
+			requester.requesterVpcs[source] = true
+			requester.accepterVpcs[target] = true
+			requester.routedVpcs[source] = true
+			continue
+		}
+		use(peer.PeerRoleArn).detailVpcs[target] = true
+		if peer.DNSProfileArn != "" {
+			requester.dnsProfiles[peer.DNSProfileArn] = true
+			use(peer.PeerRoleArn).dnsProfiles[peer.DNSProfileArn] = true
+		}
+		if peer.SourceRouting.DedicatedRouteTable {
+			requester.dedicatedVpcs[source] = true
+		}
+		if peer.PeerRouting.DedicatedRouteTable {
+			use(peer.PeerRoleArn).dedicatedVpcs[target] = true
+		}
+		if peer.SharedPeering && peer.PeeringOwner == "" {
+			requester.sharedVpcs[source] = true
+		} else if peer.SharedPeering {
+			use(peer.PeerRoleArn).sharedVpcs[target] = true
+		}
+		if peer.LooksUpPeering() {
+			requester.routedVpcs[source] = true
+			if peer.ManageExternalOptions {
+				requester.optionVpcs[source] = true
+				use(peer.PeerRoleArn).accOptionVpcs[target] = true
+			}
+			if peer.PeeringOwner == "" {
+				use(peer.PeerRoleArn).routedVpcs[target] = true
+			}
+			continue
+		}
+		requester.requesterVpcs[source] = true
+		requester.accepterVpcs[target] = true
+		requester.routedVpcs[source] = true
+		if IsAutoAccept(peer) && !peer.ManualAcceptance {
+			requester.acceptedVpcs[target] = true
+		} else {
+			use(peer.PeerRoleArn).acceptedVpcs[target] = true
+		}
+		use(peer.PeerRoleArn).accOptionVpcs[target] = true
+		use(peer.PeerRoleArn).routedVpcs[target] = true
+	}
+
+	policies := make(map[string]PolicyDocument, len(usage))
+	for roleArn, u := range usage {
+		policies[roleArn] = u.policy(roleArn, opts)
+	}
+	return policies
+}
+
+// policy renders the statements a role needs.
+func (u *roleUsage) policy(roleArn string, opts PolicyOptions) PolicyDocument {
+	statements := []PolicyStatement{{
+		Sid:    "DescribeNetwork",
+		Effect: "Allow",
+		Action: []string{
+			"ec2:DescribeRouteTables",
+			"ec2:DescribeSubnets",
+			"ec2:DescribeVpcAttribute",
+			"ec2:DescribeVpcPeeringConnections",
+			"ec2:DescribeVpcs",
+		},
+		Resource: []string{"*"},
+	}}
+
+	arnPrefix := "arn:" + SidePartition(roleArn, "") + ":"
+	peeringArn := arnPrefix + "ec2:*:*:vpc-peering-connection/*"
+	if len(u.requesterVpcs) > 0 {
+		statements = append(statements,
+			PolicyStatement{
+				Sid:      "RequestPeeringFromOwnVpcs",
+				Effect:   "Allow",
+				Action:   []string{"ec2:CreateVpcPeeringConnection"},
+				Resource: sortedKeys(u.requesterVpcs),
+			},
+			PolicyStatement{
+				Sid:       "RequestPeeringToConfiguredVpcs",
+				Effect:    "Allow",
+				Action:    []string{"ec2:CreateVpcPeeringConnection"},
+				Resource:  []string{peeringArn},
+				Condition: map[string]map[string][]string{"ArnEquals": {"ec2:AccepterVpc": sortedKeys(u.accepterVpcs)}},
+			},
+			PolicyStatement{
+				Sid:       "ManageRequestedPeerings",
+				Effect:    "Allow",
+				Action:    []string{"ec2:DeleteVpcPeeringConnection", "ec2:ModifyVpcPeeringConnectionOptions", "ec2:CreateTags", "ec2:DeleteTags"},
+				Resource:  []string{peeringArn},
+				Condition: map[string]map[string][]string{"ArnEquals": {"ec2:RequesterVpc": sortedKeys(u.requesterVpcs)}},
+			},
+		)
+	}
+	if len(u.optionVpcs) > 0 {
+		statements = append(statements, PolicyStatement{
+			Sid:       "ModifyExternalPeeringOptions",
+			Effect:    "Allow",
+			Action:    []string{"ec2:ModifyVpcPeeringConnectionOptions"},
+			Resource:  []string{peeringArn},
+			Condition: map[string]map[string][]string{"ArnEquals": {"ec2:RequesterVpc": sortedKeys(u.optionVpcs)}},
+		})
+	}
+	if len(u.accOptionVpcs) > 0 {
+		statements = append(statements, PolicyStatement{
+			Sid:       "ModifyAccepterOptions",
+			Effect:    "Allow",
+			Action:    []string{"ec2:ModifyVpcPeeringConnectionOptions"},
+			Resource:  []string{peeringArn},
+			Condition: map[string]map[string][]string{"ArnEquals": {"ec2:AccepterVpc": sortedKeys(u.accOptionVpcs)}},
+		})
+	}
+	if len(u.acceptedVpcs) > 0 {
+		statements = append(statements, PolicyStatement{
+			Sid:       "AcceptPeeringsIntoOwnVpcs",
+			Effect:    "Allow",
+			Action:    []string{"ec2:AcceptVpcPeeringConnection", "ec2:DeleteVpcPeeringConnection", "ec2:CreateTags", "ec2:DeleteTags"},
+			Resource:  []string{peeringArn},
+			Condition: map[string]map[string][]string{"ArnEquals": {"ec2:AccepterVpc": sortedKeys(u.acceptedVpcs)}},
+		})
+	}
+	if len(u.routedVpcs) > 0 {
+		statements = append(statements, PolicyStatement{
+			Sid:       "ManagePeeringRoutes",
+			Effect:    "Allow",
+			Action:    []string{"ec2:CreateRoute", "ec2:DeleteRoute", "ec2:ReplaceRoute"},
+			Resource:  []string{arnPrefix + "ec2:*:*:route-table/*"},
+			Condition: map[string]map[string][]string{"ArnEquals": {"ec2:Vpc": sortedKeys(u.routedVpcs)}},
+		})
+	}
+	if len(u.dedicatedVpcs) > 0 {
+		statements = append(statements,
+			PolicyStatement{
+				Sid:      "CreateDedicatedRouteTables",
+				Effect:   "Allow",
+				Action:   []string{"ec2:CreateRouteTable"},
+				Resource: sortedKeys(u.dedicatedVpcs),
+			},
+			PolicyStatement{
+				Sid:    "ManageDedicatedRouteTables",
+				Effect: "Allow",
+				Action: []string{
+					"ec2:AssociateRouteTable",
+					"ec2:CreateRouteTable",
+					"ec2:CreateTags",
+					"ec2:DeleteRouteTable",
+					"ec2:DisassociateRouteTable",
+				},
+				Resource:  []string{arnPrefix + "ec2:*:*:route-table/*", arnPrefix + "ec2:*:*:subnet/*"},
+				Condition: map[string]map[string][]string{"ArnEquals": {"ec2:Vpc": sortedKeys(u.dedicatedVpcs)}},
+			},
+		)
+	}
+	if opts.Provenance.TagRouteTables && len(u.routedVpcs) > 0 {
+		statements = append(statements, PolicyStatement{
+			Sid:       "TagManagedRouteTables",
+			Effect:    "Allow",
+			Action:    []string{"ec2:CreateTags", "ec2:DeleteTags", "ec2:DescribeTags"},
+			Resource:  []string{arnPrefix + "ec2:*:*:route-table/*"},
+			Condition: map[string]map[string][]string{"ArnEquals": {"ec2:Vpc": sortedKeys(u.routedVpcs)}},
+		})
+	}
+	if opts.Provenance.SSMPrefix != "" && len(u.routedVpcs) > 0 {
+		account := GetAccountIDFromRoleArn(roleArn)
+		if account == "" {
+			account = "*"
+		}
+		statements = append(statements, PolicyStatement{
+			Sid:    "RecordManagedRoutes",
+			Effect: "Allow",
+			Action: []string{
+				"ssm:AddTagsToResource",
+				"ssm:DeleteParameter",
+				"ssm:GetParameter",
+				"ssm:GetParameters",
+				"ssm:ListTagsForResource",
+				"ssm:PutParameter",
+			},
+			Resource: []string{arnPrefix + "ssm:*:" + account + ":parameter" + opts.Provenance.SSMPrefix + "/*"},
+		})
+	}
+	if opts.OwnedPeerings.Lookup == OwnedLookupSSM && len(u.sharedVpcs) > 0 {
+		account := GetAccountIDFromRoleArn(roleArn)
+		if account == "" {
+			account = "*"
+		}
+		statements = append(statements, PolicyStatement{
+			Sid:    "ShareOwnedPeeringIDs",
+			Effect: "Allow",
+			Action: []string{
+				"ssm:AddTagsToResource",
+				"ssm:DeleteParameter",
+				"ssm:GetParameter",
+				"ssm:GetParameters",
+				"ssm:ListTagsForResource",
+				"ssm:PutParameter",
+			},
+			Resource: []string{arnPrefix + "ssm:*:" + account + ":parameter" + opts.OwnedPeerings.SSMPrefix + "/*"},
+		})
+	}
+	if opts.AccepterDetails.SSMPrefix != "" && len(u.detailVpcs) > 0 {
+		account := GetAccountIDFromRoleArn(roleArn)
+		if account == "" {
+			account = "*"
+		}
+		statements = append(statements, PolicyStatement{
+			Sid:    "PublishConnectionDetails",
+			Effect: "Allow",
+			Action: []string{
+				"ssm:AddTagsToResource",
+				"ssm:DeleteParameter",
+				"ssm:GetParameter",
+				"ssm:GetParameters",
+				"ssm:ListTagsForResource",
+				"ssm:PutParameter",
+			},
+			Resource: []string{arnPrefix + "ssm:*:" + account + ":parameter" + opts.AccepterDetails.SSMPrefix + "/*"},
+		})
+	}
+	if opts.FlowLogs && len(u.routedVpcs) > 0 {
+		account := GetAccountIDFromRoleArn(roleArn)
+		if account == "" {
+			account = "*"
+		}
+		statements = append(statements,
+			PolicyStatement{
+				Sid:      "ManageFlowLogs",
+				Effect:   "Allow",
+				Action:   []string{"ec2:CreateFlowLogs", "ec2:DeleteFlowLogs", "ec2:DescribeFlowLogs"},
+				Resource: append(sortedKeys(u.routedVpcs), arnPrefix+"ec2:*:"+account+":vpc-flow-log/*"),
+			},
+			PolicyStatement{
+				Sid:      "ManageFlowLogGroups",
+				Effect:   "Allow",
+				Action:   []string{"logs:CreateLogGroup", "logs:DeleteLogGroup", "logs:DescribeLogGroups", "logs:PutRetentionPolicy"},
+				Resource: []string{arnPrefix + "logs:*:" + account + ":log-group:*"},
+			},
+			PolicyStatement{
+				Sid:       "PassFlowLogsRole",
+				Effect:    "Allow",
+				Action:    []string{"iam:PassRole"},
+				Resource:  []string{arnPrefix + "iam::" + account + ":role/*"},
+				Condition: map[string]map[string][]string{"StringEquals": {"iam:PassedToService": {"vpc-flow-logs.amazonaws.com"}}},
+			},
+		)
+	}
+	if opts.Route53 {
+		statements = append(statements, PolicyStatement{
+			Sid:    "AssociatePrivateHostedZones",
+			Effect: "Allow",
+			Action: []string{
+				"route53:AssociateVPCWithHostedZone",
+				"route53:CreateVPCAssociationAuthorization",
+				"route53:DeleteVPCAssociationAuthorization",
+				"route53:DisassociateVPCFromHostedZone",
+				"route53:GetHostedZone",
+				"route53:ListHostedZonesByVPC",
+			},
+			Resource: []string{"*"},
+		})
+	}
+	if len(u.dnsProfiles) > 0 {
+		account := GetAccountIDFromRoleArn(roleArn)
+		if account == "" {
+			account = "*"
+		}
+		statements = append(statements, PolicyStatement{
+			Sid:    "AssociateDNSProfiles",
+			Effect: "Allow",
+			Action: []string{
+				"route53profiles:AssociateProfile",
+				"route53profiles:DisassociateProfile",
+				"route53profiles:GetProfileAssociation",
+				"route53profiles:ListProfileAssociations",
+			},
+			Resource: append(sortedKeys(u.dnsProfiles), arnPrefix+"route53profiles:*:"+account+":profile-association/*"),
+		})
+	}
+	if opts.Quotas {
+		account := GetAccountIDFromRoleArn(roleArn)
+		if account == "" {
+			account = "*"
+		}
+		statements = append(statements, PolicyStatement{
+			Sid:    "ReadVpcQuotas",
+			Effect: "Allow",
+			Action: []string{"servicequotas:GetServiceQuota"},
+			Resource: []string{
+				arnPrefix + "servicequotas:*:" + account + ":" + QuotaServiceVpc + "/" + QuotaPeeringsPerVpc,
+				arnPrefix + "servicequotas:*:" + account + ":" + QuotaServiceVpc + "/" + QuotaRoutesPerTable,
+			},
+		})
+	}
+	return PolicyDocument{Version: "2012-10-17", Statement: statements}
+}
+
+// sortedKeys returns the keys of a set in order.
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// RoleNameAndPath splits a role ARN into its name and path ("/" when none).
+func RoleNameAndPath(roleArn string) (string, string) {
+	i := strings.Index(roleArn, ":role/")
+	if i < 0 {
+		return "", "/"
+	}
+	full := roleArn[i+len(":role/"):]
+	j := strings.LastIndex(full, "/")
+	if j < 0 {
+		return full, "/"
+	}
+	return full[j+1:], "/" + full[:j+1]
+}
+
+// -------------------------------------------------------------------------------------------------
+// iam-policy
+// -------------------------------------------------------------------------------------------------
+
+// runIAMPolicy prints the policies keyed by role ARN, or the bare document of a single role, for
+// review or for provisioning roles outside the bootstrap stacks.
+func runIAMPolicy(args []string) error {
+	fs := flag.NewFlagSet("iam-policy", flag.ContinueOnError)
+	role := fs.String("role", "", "print only the policy document of this role ARN")
+	flowLogs := fs.Bool("flow-logs", false, "also grant managing VPC flow logs")
+	route53 := fs.Bool("route53", false, "also grant private hosted zone associations")
+	quotas := fs.Bool("quotas", false, "also grant reading the VPC quotas quota-check compares")
+	out := fs.String("o", "", "write the policies to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, peers := loadSourcePeers(sourceArg(fs))
+	policies := RolePolicies(peers, PolicyOptions{FlowLogs: *flowLogs, Route53: *route53, Quotas: *quotas, Provenance: cfg.RouteProvenance, OwnedPeerings: cfg.OwnedPeerings, AccepterDetails: cfg.AccepterDetails})
+
+	var v any = policies
+	if *role != "" {
+		doc, ok := policies[*role]
+		if !ok {
+			return fmt.Errorf("role %q is not assumed by any connection", *role)
+		}
+		v = doc
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if *out != "" {
+		return os.WriteFile(*out, append(data, '\n'), 0o644)
+	}
+	fmt.Println(string(data))
+	return nil
+}
@@ -0,0 +1,189 @@
+package peering
+
+import (
+	"reflect"
+	"testing"
+)
+
+// findStatement returns the statement with the given Sid, or nil.
+func findStatement(doc PolicyDocument, sid string) *PolicyStatement {
+	for i := range doc.Statement {
+		if doc.Statement[i].Sid == sid {
+			return &doc.Statement[i]
+		}
+	}
+	return nil
+}
+
+// TestRolePolicies tests that each role is granted only what its side of each connection needs.
+func TestRolePolicies(t *testing.T) {
+	peers := []PeerConfig{
+		{
+			SourceName: "dev", Name: "prod",
+			SourceVpcID: "vpc-1", SourceRegion: "us-east-1", SourceRoleArn: "arn:aws:iam::111111111111:role/peering",
+			PeerVpcID: "vpc-2", PeerRegion: "us-west-2", PeerRoleArn: "arn:aws:iam::222222222222:role/ops/peering",
+		},
+		{
+			SourceName: "dev", Name: "shared",
+			SourceVpcID: "vpc-1", SourceRegion: "us-east-1", SourceRoleArn: "arn:aws:iam::111111111111:role/peering",
+			PeerVpcID: "vpc-3", PeerRegion: "us-east-1", PeerRoleArn: "arn:aws:iam::111111111111:role/peering",
+		},
+	}
+	policies := RolePolicies(peers, PolicyOptions{})
+	if len(policies) != 2 {
+		t.Fatalf("expected 2 roles, got %d", len(policies))
+	}
+
+	source := policies["arn:aws:iam::111111111111:role/peering"]
+	tests := []struct {
+		doc       PolicyDocument
+		sid       string
+		condition []string // Expected ARNs of the statement's only condition, nil when absent.
+		missing   bool
+	}{
+		{source, "RequestPeeringFromOwnVpcs", nil, false},
+		{source, "RequestPeeringToConfiguredVpcs", []string{
+			"arn:aws:ec2:us-east-1:111111111111:vpc/vpc-3",
+			"arn:aws:ec2:us-west-2:222222222222:vpc/vpc-2",
+		}, false},
+		{source, "AcceptPeeringsIntoOwnVpcs", []string{"arn:aws:ec2:us-east-1:111111111111:vpc/vpc-3"}, false},
+		{source, "ManagePeeringRoutes", []string{
+			"arn:aws:ec2:us-east-1:111111111111:vpc/vpc-1",
+			"arn:aws:ec2:us-east-1:111111111111:vpc/vpc-3",
+		}, false},
+		{policies["arn:aws:iam::222222222222:role/ops/peering"], "RequestPeeringFromOwnVpcs", nil, true},
+		{policies["arn:aws:iam::222222222222:role/ops/peering"], "AcceptPeeringsIntoOwnVpcs", []string{"arn:aws:ec2:us-west-2:222222222222:vpc/vpc-2"}, false},
+		{policies["arn:aws:iam::222222222222:role/ops/peering"], "ModifyAccepterOptions", []string{"arn:aws:ec2:us-west-2:222222222222:vpc/vpc-2"}, false},
+	}
+	for _, tt := range tests {
+		s := findStatement(tt.doc, tt.sid)
+		if tt.missing {
+			if s != nil {
+				t.Errorf("%s: expected no statement", tt.sid)
+			}
+			continue
+		}
+		if s == nil {
+			t.Errorf("%s: missing statement", tt.sid)
+			continue
+		}
+		if tt.condition == nil {
+			continue
+		}
+		var got []string
+		for _, values := range s.Condition["ArnEquals"] {
+			got = values
+		}
+		if !reflect.DeepEqual(got, tt.condition) {
+			t.Errorf("%s: condition = %v, want %v", tt.sid, got, tt.condition)
+		}
+	}
+}
+
+// TestRoleNameAndPath tests splitting role ARNs into name and path.
+func TestRoleNameAndPath(t *testing.T) {
+	tests := []struct {
+		arn, name, path string
+	}{
+		{"arn:aws:iam::111111111111:role/peering", "peering", "/"},
+		{"arn:aws:iam::111111111111:role/ops/net/peering", "peering", "/ops/net/"},
+	}
+	for _, tt := range tests {
+		name, path := RoleNameAndPath(tt.arn)
+		if name != tt.name || path != tt.path {
+			t.Errorf("RoleNameAndPath(%q) = %q, %q; want %q, %q", tt.arn, name, path, tt.name, tt.path)
+		}
+	}
+}
+
+// TestTrustPolicy tests the assume-role policy of bootstrapped roles.
+func TestTrustPolicy(t *testing.T) {
+	doc := TrustPolicy(BootstrapOptions{TrustedPrincipals: []string{"arn:aws:iam::999999999999:role/ci"}, ExternalID: "x", TagSession: true})
+	s := doc.Statement[0]
+	if !reflect.DeepEqual(s.Action, []string{"sts:AssumeRole", "sts:TagSession"}) {
+		t.Errorf("unexpected actions: %v", s.Action)
+	}
+	if got := s.Condition["StringEquals"]["sts:ExternalId"]; !reflect.DeepEqual(got, []string{"x"}) {
+		t.Errorf("unexpected external ID condition: %v", got)
+	}
+	if s.Resource != nil {
+		t.Errorf("trust policy must not set Resource: %v", s.Resource)
+	}
+}
+
+// TestRolePoliciesOptions tests that optional features add their statements only when enabled.
+func TestRolePoliciesOptions(t *testing.T) {
+	peers := []PeerConfig{{
+		SourceName: "dev", Name: "prod",
+		SourceVpcID: "vpc-1", SourceRoleArn: "arn:aws:iam::111111111111:role/peering",
+		PeerVpcID: "vpc-2", PeerRoleArn: "arn:aws:iam::222222222222:role/peering",
+	}}
+	tests := []struct {
+		opts PolicyOptions
+		sid  string
+		want bool
+	}{
+		{PolicyOptions{}, "ManageFlowLogs", false},
+		{PolicyOptions{}, "AssociatePrivateHostedZones", false},
+		{PolicyOptions{FlowLogs: true}, "ManageFlowLogs", true},
+		{PolicyOptions{FlowLogs: true}, "PassFlowLogsRole", true},
+		{PolicyOptions{Route53: true}, "AssociatePrivateHostedZones", true},
+		{PolicyOptions{}, "ReadVpcQuotas", false},
+		{PolicyOptions{Quotas: true}, "ReadVpcQuotas", true},
+		{PolicyOptions{}, "TagManagedRouteTables", false},
+		{PolicyOptions{Provenance: ProvenanceConfig{TagRouteTables: true}}, "TagManagedRouteTables", true},
+		{PolicyOptions{Provenance: ProvenanceConfig{SSMPrefix: "/peering"}}, "RecordManagedRoutes", true},
+		{PolicyOptions{}, "AssociateDNSProfiles", false},
+	}
+	for _, tt := range tests {
+		for roleArn, doc := range RolePolicies(peers, tt.opts) {
+			if got := findStatement(doc, tt.sid) != nil; got != tt.want {
+				t.Errorf("%s %+v: has %s = %v, want %v", roleArn, tt.opts, tt.sid, got, tt.want)
+			}
+		}
+	}
+}
+
+// TestRolePoliciesExternalPeering tests that route-only connections grant no peering management.
+func TestRolePoliciesExternalPeering(t *testing.T) {
+	peers := []PeerConfig{{
+		SourceName: "dev", Name: "partner",
+		SourceVpcID: "vpc-1", SourceRoleArn: "arn:aws:iam::111111111111:role/peering",
+		PeerVpcID: "vpc-2", PeerRoleArn: "arn:aws:iam::222222222222:role/peering",
+		ExternalPeeringID: "pcx-0abc", ManageExternalOptions: true,
+	}}
+	policies := RolePolicies(peers, PolicyOptions{})
+	for roleArn, doc := range policies {
+		for _, sid := range []string{"RequestPeeringFromOwnVpcs", "AcceptPeeringsIntoOwnVpcs"} {
+			if findStatement(doc, sid) != nil {
+				t.Errorf("%s: unexpected %s for an external peering", roleArn, sid)
+			}
+		}
+		if findStatement(doc, "ManagePeeringRoutes") == nil {
+			t.Errorf("%s: expected ManagePeeringRoutes", roleArn)
+		}
+	}
+	if findStatement(policies["arn:aws:iam::111111111111:role/peering"], "ModifyExternalPeeringOptions") == nil {
+		t.Error("expected the requester role to modify the external peering's options")
+	}
+	if findStatement(policies["arn:aws:iam::222222222222:role/peering"], "ModifyExternalPeeringOptions") != nil {
+		t.Error("unexpected options statement for the peer role")
+	}
+}
+
+// TestRolePoliciesPartition tests that resource ARNs follow the partition of the role and region.
+func TestRolePoliciesPartition(t *testing.T) {
+	peers := []PeerConfig{{
+		SourceName: "dev", Name: "prod",
+		SourceVpcID: "vpc-1", SourceRegion: "us-gov-west-1", SourceRoleArn: "arn:aws-us-gov:iam::111111111111:role/peering",
+		PeerVpcID: "vpc-2", PeerRegion: "us-gov-west-1", PeerRoleArn: "arn:aws-us-gov:iam::222222222222:role/peering",
+	}}
+	doc := RolePolicies(peers, PolicyOptions{})["arn:aws-us-gov:iam::111111111111:role/peering"]
+	request := findStatement(doc, "RequestPeeringFromOwnVpcs")
+	if request == nil || request.Resource[0] != "arn:aws-us-gov:ec2:us-gov-west-1:111111111111:vpc/vpc-1" {
+		t.Errorf("unexpected requester VPC ARN: %+v", request)
+	}
+	if routes := findStatement(doc, "ManagePeeringRoutes"); routes == nil || routes.Resource[0] != "arn:aws-us-gov:ec2:*:*:route-table/*" {
+		t.Errorf("unexpected route table ARN: %+v", routes)
+	}
+}
@@ -0,0 +1,250 @@
+package peering
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Import Blocks for Existing Routes
+// -------------------------------------------------------------------------------------------------
+
+// ImportBlock is a single Terraform import block.
+type ImportBlock struct {
+	To       string `json:"to"`       // Resource address, including the for_each key if any.
+	ID       string `json:"id"`       // Provider import ID (route_table_id + "_" + destination CIDR).
+	Provider string `json:"provider"` // Provider reference, e.g. "aws.source0".
+}
+
+// LookupFactory returns a network lookup for a region, using the given role when set.
+type LookupFactory func(region, roleArn string) (VpcNetworkLookup, error)
+
+// routeImportSide describes one side of a connection for import planning.
+type routeImportSide struct {
+	side     routeSide
+	routing  RoutingConfig
+	vpcID    string
+	region   string
+	roleArn  string
+	alias    string
+	cidrs    []string
+	extra    []string // Extra CIDRs routed in addition.
+	fallback string   // VPC ID whose CIDR is routed when no explicit CIDRs are set.
+	pinned   string   // Pinned CIDR of the fallback VPC, routed without a lookup.
+}
+
+// PlanRouteImports finds routes that already exist in the route tables the stack would manage and
+// returns import blocks adopting them, so the first apply does not fail with RouteAlreadyExists.
+func PlanRouteImports(namer Namer, peers []PeerConfig, connect LookupFactory) ([]ImportBlock, error) {
+	lookups := make(map[string]VpcNetworkLookup)
+	lookup := func(region, roleArn string) (VpcNetworkLookup, error) {
+		key := ResolveRegion(region) + "|" + roleArn
+		if lk, ok := lookups[key]; ok {
+			return lk, nil
+		}
+		lk, err := connect(region, roleArn)
+		if err != nil {
+			return nil, err
+		}
+		lookups[key] = lk
+		return lk, nil
+	}
+
+	var imports []ImportBlock
+	for i, peer := range peers {
+		ctx := ConnectionNameContext(i, peer)
+		sides := []routeImportSide{
+			{
+				side: sourceSide, routing: peer.SourceRouting, vpcID: peer.SourceVpcID,
+				region: peer.SourceRegion, roleArn: peer.SourceRoleArn, alias: namer.ID(ctx, KindSourceProviderAlias),
+				cidrs: peer.DestinationCidrs, extra: peer.SourceExtraCidrs, fallback: peer.PeerVpcID, pinned: peer.PeerCidr,
+			},
+			{
+				side: peerSide, routing: peer.PeerRouting, vpcID: peer.PeerVpcID,
+				region: peer.PeerRegion, roleArn: peer.PeerRoleArn, alias: namer.ID(ctx, KindPeerProviderAlias),
+				extra: peer.PeerExtraCidrs, fallback: peer.SourceVpcID, pinned: peer.SourceCidr,
+			},
+		}
+
+		for _, s := range sides {
+			if s.routing.Strategy == RoutingNone {
+				continue
+			}
+			lk, err := lookup(s.region, s.roleArn)
+			if err != nil {
+				return nil, err
+			}
+			var fallback *string
+			if len(s.cidrs) == 0 && s.pinned != "" {
+				fallback = jsii.String(s.pinned)
+			} else if len(s.cidrs) == 0 {
+				other := sides[0]
+				if s.side == sourceSide {
+					other = sides[1]
+				}
+				olk, err := lookup(other.region, other.roleArn)
+				if err != nil {
+					return nil, err
+				}
+				cidr, err := olk.VpcCidr(s.fallback)
+				if err != nil {
+					return nil, err
+				}
+				fallback = jsii.String(cidr)
+			}
+			blocks, err := planSideImports(namer, ctx, s, lk, fallback)
+			if err != nil {
+				return nil, fmt.Errorf("connection %s: %w", ConnectionKey(peer), err)
+			}
+			imports = append(imports, blocks...)
+		}
+	}
+	return imports, nil
+}
+
+// planSideImports matches the routes one side would create against the live route tables.
+func planSideImports(namer Namer, ctx NameContext, s routeImportSide, lk VpcNetworkLookup, fallback *string) ([]ImportBlock, error) {
+	tables, err := lk.RouteTables(s.vpcID)
+	if err != nil {
+		return nil, err
+	}
+	var main *RouteTable
+	bySubnet := make(map[string]*RouteTable)
+	for i := range tables {
+		if tables[i].Main {
+			main = &tables[i]
+		}
+		for _, subnet := range tables[i].SubnetIDs {
+			bySubnet[subnet] = &tables[i]
+		}
+	}
+
+	var subnets []string
+	if s.routing.Strategy == RoutingFiltered && !s.routing.DedicatedRouteTable {
+		if subnets, err = lk.Subnets(s.vpcID, s.routing.SubnetTags); err != nil {
+			return nil, err
+		}
+	}
+
+	provider := "aws." + s.alias
+	var imports []ImportBlock
+	add := func(to string, table *RouteTable, cidr string) {
+		if table != nil && hasDestination(*table, cidr) {
+			imports = append(imports, ImportBlock{To: "aws_route." + to, ID: table.ID + "_" + cidr, Provider: provider})
+		}
+	}
+
+	for _, kind := range s.side.routeKinds(s.routing) {
+		for _, target := range RouteTargets(namer, ctx, kind, s.cidrs, s.extra, fallback) {
+			cidr := *target.Cidr
+			switch kind {
+			case s.side.AllRoute:
+				for i := range tables {
+					add(fmt.Sprintf("%s[%q]", target.ID, tables[i].ID), &tables[i], cidr)
+				}
+			case s.side.SubnetRoute:
+				// A dedicated route table is created by the stack, so none of its routes exist yet.
+				if s.routing.DedicatedRouteTable {
+					continue
+				}
+				// Subnets without an explicit association use the main table, which the main
+				// route already covers; each table is imported at most once.
+				claimed := map[string]bool{}
+				if main != nil {
+					claimed[main.ID] = true
+				}
+				for _, subnet := range subnets {
+					table := bySubnet[subnet]
+					if table == nil || claimed[table.ID] {
+						continue
+					}
+					claimed[table.ID] = true
+					key := subnet
+					if s.routing.KeyByRouteTable {
+						key = table.ID
+					}
+					add(fmt.Sprintf("%s[%q]", target.ID, key), table, cidr)
+				}
+			default:
+				add(target.ID, main, cidr)
+			}
+		}
+	}
+	return imports, nil
+}
+
+// hasDestination reports whether a route table already routes the CIDR.
+func hasDestination(table RouteTable, cidr string) bool {
+	for _, dest := range table.Destinations {
+		if dest == cidr {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadImports reads an import file written by the import-routes command.
+func LoadImports(path string) ([]ImportBlock, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var imports []ImportBlock
+	if err := json.Unmarshal(data, &imports); err != nil {
+		return nil, fmt.Errorf("failed to parse imports %s: %w", path, err)
+	}
+	return imports, nil
+}
+
+// AddImportBlocks writes the imports into the stack as top-level Terraform import blocks.
+func AddImportBlocks(stack cdktf.TerraformStack, imports []ImportBlock) {
+	if len(imports) == 0 {
+		return
+	}
+	sort.Slice(imports, func(i, j int) bool { return imports[i].To < imports[j].To })
+	blocks := make([]map[string]string, 0, len(imports))
+	for _, imp := range imports {
+		blocks = append(blocks, map[string]string{"to": imp.To, "id": imp.ID, "provider": imp.Provider})
+	}
+	stack.AddOverride(jsii.String("import"), blocks)
+}
+
+// -------------------------------------------------------------------------------------------------
+// import-routes
+// -------------------------------------------------------------------------------------------------
+
+// runImportRoutes queries the route tables of every connection and prints or writes import blocks
+// for routes that already exist. Passing the file to synth via CDKTF_IMPORTS adopts them.
+func runImportRoutes(args []string) error {
+	fs := flag.NewFlagSet("import-routes", flag.ContinueOnError)
+	out := fs.String("o", "", "write the import blocks to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, peers := loadSourcePeers(sourceArg(fs))
+	imports, err := PlanRouteImports(NewNamer(cfg.Naming), peers, func(region, roleArn string) (VpcNetworkLookup, error) {
+		return NewAWSCLI(region, roleArn, cfg.Provider)
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("[import] Found %d existing routes to import", len(imports))
+
+	data, err := json.MarshalIndent(imports, "", "  ")
+	if err != nil {
+		return err
+	}
+	if *out != "" {
+		return os.WriteFile(*out, append(data, '\n'), 0o644)
+	}
+	fmt.Println(string(data))
+	return nil
+}
@@ -0,0 +1,62 @@
+package peering
+
+import "testing"
+
+// fakeLookup serves fixed network details for import planning tests.
+type fakeLookup struct {
+	cidrs   map[string]string
+	tables  []RouteTable
+	subnets []string
+}
+
+func (f fakeLookup) VpcCidr(vpcID string) (string, error)                { return f.cidrs[vpcID], nil }
+func (f fakeLookup) RouteTables(string) ([]RouteTable, error)            { return f.tables, nil }
+func (f fakeLookup) Subnets(string, map[string]string) ([]string, error) { return f.subnets, nil }
+
+// TestPlanRouteImports tests which existing routes are adopted with import blocks.
+func TestPlanRouteImports(t *testing.T) {
+	lk := fakeLookup{
+		cidrs: map[string]string{"vpc-src": "10.0.0.0/16", "vpc-peer": "10.1.0.0/16"},
+		tables: []RouteTable{
+			{ID: "rtb-main", Main: true, Destinations: []string{"10.1.0.0/16", "10.0.0.0/16"}},
+			{ID: "rtb-private", SubnetIDs: []string{"subnet-a", "subnet-b"}, Destinations: []string{"10.1.0.0/16"}},
+		},
+		subnets: []string{"subnet-a", "subnet-b", "subnet-c"},
+	}
+	peers := []PeerConfig{{
+		SourceName:    "dev",
+		Name:          "prod",
+		SourceVpcID:   "vpc-src",
+		PeerVpcID:     "vpc-peer",
+		SourceRouting: RoutingConfig{Strategy: RoutingFiltered, SubnetTags: map[string]string{"Tier": "private"}},
+		PeerRouting:   RoutingConfig{Strategy: RoutingMain},
+	}}
+
+	imports, err := PlanRouteImports(LegacyNamer{}, peers, func(string, string) (VpcNetworkLookup, error) { return lk, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ImportBlock{
+		{To: "aws_route.SourceToPeerMainRoute0", ID: "rtb-main_10.1.0.0/16", Provider: "aws.source0"},
+		{To: `aws_route.SourceSubnetToPeerRoute_prod_eachkey_0Route["subnet-a"]`, ID: "rtb-private_10.1.0.0/16", Provider: "aws.source0"},
+		{To: "aws_route.PeerToPeerMainRoute0", ID: "rtb-main_10.0.0.0/16", Provider: "aws.peer0"},
+	}
+	if len(imports) != len(want) {
+		t.Fatalf("expected %d imports, got %d: %v", len(want), len(imports), imports)
+	}
+	for i := range want {
+		if imports[i] != want[i] {
+			t.Errorf("import %d: expected %+v, got %+v", i, want[i], imports[i])
+		}
+	}
+
+	// key_by_route_table imports the subnet route under its table's key.
+	peers[0].SourceRouting.KeyByRouteTable = true
+	imports, err = PlanRouteImports(LegacyNamer{}, peers, func(string, string) (VpcNetworkLookup, error) { return lk, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `aws_route.SourceSubnetToPeerRoute_prod_eachkey_0Route["rtb-private"]`; len(imports) != 3 || imports[1].To != want {
+		t.Errorf("expected %s, got %v", want, imports)
+	}
+}
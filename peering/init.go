@@ -0,0 +1,228 @@
+package peering
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Starter Config
+// -------------------------------------------------------------------------------------------------
+
+// InitPeer is one of the two peers of a starter config.
+type InitPeer struct {
+	Name    string // Logical peer name.
+	VpcID   string // VPC ID.
+	Region  string // Region of the VPC.
+	RoleArn string // Role the tool assumes in the VPC's account.
+}
+
+// InitAnswers are the choices a starter config is tailored to.
+type InitAnswers struct {
+	Region      string      // Default region, written to peer_defaults.
+	Peers       [2]InitPeer // The first connection: Peers[0] requests a peering with Peers[1].
+	StateBucket string      // S3 state bucket (local state, with a commented example, if empty).
+}
+
+// DefaultInitAnswers are the answers used when a prompt is left empty, or for every prompt under
+// init -defaults.
+var DefaultInitAnswers = InitAnswers{
+	Region: DefaultRegion,
+	Peers: [2]InitPeer{
+		{Name: "dev-peer", VpcID: "vpc-0aaa1111aaa1111aa", Region: DefaultRegion, RoleArn: "arn:aws:iam::111111111111:role/vpc-peering"},
+		{Name: "prod-peer", VpcID: "vpc-0bbb2222bbb2222bb", Region: DefaultRegion, RoleArn: "arn:aws:iam::222222222222:role/vpc-peering"},
+	},
+}
+
+// initVpcIDPattern matches VPC IDs.
+var initVpcIDPattern = regexp.MustCompile(`^vpc-[0-9a-f]+$`)
+
+// AskInitAnswers prompts for the starter config answers on in, showing each default in brackets.
+// Each peer's region defaults to the default region just entered. Input ending early keeps the
+// remaining defaults.
+func AskInitAnswers(in io.Reader, out io.Writer) (InitAnswers, error) {
+	scanner := bufio.NewScanner(in)
+	ask := func(question, def string) string {
+		if def == "" {
+			fmt.Fprintf(out, "%s: ", question)
+		} else {
+			fmt.Fprintf(out, "%s [%s]: ", question, def)
+		}
+		if !scanner.Scan() {
+			return def
+		}
+		if answer := strings.TrimSpace(scanner.Text()); answer != "" {
+			return answer
+		}
+		return def
+	}
+
+	a := DefaultInitAnswers
+	a.Region = ask("Default region", a.Region)
+	for i, label := range []string{"First peer (requester)", "Second peer (accepter)"} {
+		p := &a.Peers[i]
+		p.Name = ask(label+" name", p.Name)
+		p.VpcID = ask("  VPC ID of "+p.Name, p.VpcID)
+		p.Region = ask("  Region of "+p.Name, a.Region)
+		p.RoleArn = ask("  Role ARN the tool assumes for "+p.Name, p.RoleArn)
+	}
+	a.StateBucket = ask("S3 bucket for Terraform state (empty for local state)", "")
+	if err := scanner.Err(); err != nil {
+		return InitAnswers{}, err
+	}
+	return a, a.Validate()
+}
+
+// Validate rejects answers that would make an invalid config: equal or empty names, malformed or
+// equal VPC IDs, and roles that are not role ARNs.
+func (a InitAnswers) Validate() error {
+	first, second := a.Peers[0], a.Peers[1]
+	switch {
+	case first.Name == "" || second.Name == "" || first.Name == second.Name:
+		return fmt.Errorf("the two peers need distinct names, got %q and %q", first.Name, second.Name)
+	case first.VpcID == second.VpcID:
+		return fmt.Errorf("the two peers need distinct VPCs, got %s twice", first.VpcID)
+	}
+	for _, p := range a.Peers {
+		if !initVpcIDPattern.MatchString(p.VpcID) {
+			return fmt.Errorf("%s: %q is not a VPC ID (vpc-...)", p.Name, p.VpcID)
+		}
+		if err := ValidateRoleArn(p.RoleArn); err != nil {
+			return fmt.Errorf("%s: %v", p.Name, err)
+		}
+	}
+	return nil
+}
+
+// starterConfigTemplate is the commented starter peering.yaml.
+var starterConfigTemplate = template.Must(template.New("peering.yaml").Parse(`# VPC peering config, generated by "init". Run "go run . lint" after every change.
+version: 3
+
+# Fields every peer inherits unless it sets them itself.
+peer_defaults:
+  region: {{.Region}}
+  dns_resolution: true   # resolve the other VPC's private DNS hostnames
+
+# Every VPC taking part in a peering, by logical name.
+peers:
+{{- range .Peers}}
+  {{.Name}}:
+    vpc_id: {{.VpcID}}
+{{- if ne .Region $.Region}}
+    region: {{.Region}}
+{{- end}}
+    role_arn: "{{.RoleArn}}"   # assumed for everything done in this VPC's account
+{{- end}}
+
+# Which peers each source peers with. The source requests the peering; the target accepts it.
+peering_matrix:
+  {{(index .Peers 0).Name}}:
+    - {{(index .Peers 1).Name}}
+
+# Tags added to every peering, on both sides.
+tags:
+  ManagedBy: vpc-peering-tool
+
+terraform:
+  required_version: ">= 1.5, < 2.0"
+{{- if .StateBucket}}
+  s3_backend:
+    bucket: {{.StateBucket}}
+    key: vpc-peering/{stack}.tfstate
+    region: {{.Region}}
+{{- else}}
+  # State stays in a local file per stack. To keep it in S3 instead:
+  # s3_backend:
+  #   bucket: my-terraform-state
+  #   key: vpc-peering/{stack}.tfstate
+  #   region: {{.Region}}
+  #   dynamodb_table: terraform-locks
+{{- end}}
+`))
+
+// StarterConfig renders the starter peering.yaml for the answers.
+func StarterConfig(a InitAnswers) (string, error) {
+	var buf bytes.Buffer
+	if err := starterConfigTemplate.Execute(&buf, a); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// starterCdktfJSON is the example cdktf.json: the Go app with AWS provider bindings generated into
+// generated/ by "make get".
+const starterCdktfJSON = `{
+  "language": "go",
+  "app": "go run .",
+  "codeMakerOutput": "generated",
+  "sendCrashReports": "false",
+  "terraformProviders": [
+    "hashicorp/aws@~> 5.0"
+  ],
+  "terraformModules": [],
+  "context": {}
+}
+`
+
+// -------------------------------------------------------------------------------------------------
+// init
+// -------------------------------------------------------------------------------------------------
+
+// runInit writes a starter peering.yaml and cdktf.json tailored by prompts. Existing files are
+// kept unless -force is given.
+func runInit(args []string) error {
+	fs := flag.NewFlagSet("init", flag.ContinueOnError)
+	dir := fs.String("o", ".", "directory to write peering.yaml and cdktf.json to")
+	defaults := fs.Bool("defaults", false, "use the default answers without prompting")
+	force := fs.Bool("force", false, "overwrite existing files")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+
+	files := map[string]string{ConfigFileName: "", "cdktf.json": starterCdktfJSON}
+	if !*force {
+		for name := range files {
+			if _, err := os.Stat(filepath.Join(*dir, name)); err == nil {
+				return fmt.Errorf("%s already exists; pass -force to overwrite it", filepath.Join(*dir, name))
+			}
+		}
+	}
+
+	answers := DefaultInitAnswers
+	if !*defaults {
+		var err error
+		if answers, err = AskInitAnswers(os.Stdin, os.Stdout); err != nil {
+			return WithExitCode(ExitValidation, err)
+		}
+	}
+	config, err := StarterConfig(answers)
+	if err != nil {
+		return err
+	}
+	files[ConfigFileName] = config
+
+	if err := os.MkdirAll(*dir, 0o755); err != nil {
+		return err
+	}
+	for _, name := range []string{ConfigFileName, "cdktf.json"} {
+		path := filepath.Join(*dir, name)
+		if err := os.WriteFile(path, []byte(files[name]), 0o644); err != nil {
+			return err
+		}
+		log.Printf("[init] Wrote %s", path)
+	}
+	log.Printf("[init] Next: make get, then go run . lint and make synth")
+	return nil
+}
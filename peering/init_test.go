@@ -0,0 +1,70 @@
+package peering
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestAskInitAnswers tests that answers override defaults and empty answers keep them.
+func TestAskInitAnswers(t *testing.T) {
+	in := strings.NewReader(strings.Join([]string{
+		"us-east-1",
+		"app", "vpc-0123", "", "arn:aws:iam::333333333333:role/peering",
+		"", "", "eu-west-1", "",
+		"acme-state",
+	}, "\n"))
+	a, err := AskInitAnswers(in, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := InitAnswers{
+		Region: "us-east-1",
+		Peers: [2]InitPeer{
+			{Name: "app", VpcID: "vpc-0123", Region: "us-east-1", RoleArn: "arn:aws:iam::333333333333:role/peering"},
+			{Name: "prod-peer", VpcID: "vpc-0bbb2222bbb2222bb", Region: "eu-west-1", RoleArn: "arn:aws:iam::222222222222:role/vpc-peering"},
+		},
+		StateBucket: "acme-state",
+	}
+	if a != want {
+		t.Errorf("expected %+v, got %+v", want, a)
+	}
+
+	if _, err := AskInitAnswers(strings.NewReader("\nsame\n\n\n\nsame\n"), &bytes.Buffer{}); err == nil {
+		t.Error("expected an error for two peers with the same name")
+	}
+}
+
+// TestStarterConfig tests that starter configs load and resolve without errors, with and without
+// an S3 backend.
+func TestStarterConfig(t *testing.T) {
+	withBucket := DefaultInitAnswers
+	withBucket.StateBucket = "acme-state"
+	withBucket.Peers[1].Region = "eu-west-1"
+	for _, answers := range []InitAnswers{DefaultInitAnswers, withBucket} {
+		config, err := StarterConfig(answers)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		path := filepath.Join(t.TempDir(), ConfigFileName)
+		if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cfg := LoadConfig(path)
+		if err := cfg.Terraform.Validate(); err != nil {
+			t.Errorf("invalid terraform settings: %v", err)
+		}
+		if (cfg.Terraform.S3Backend != nil) != (answers.StateBucket != "") {
+			t.Errorf("expected an S3 backend only with a bucket, got %+v", cfg.Terraform.S3Backend)
+		}
+		rows := BuildMatrixRows(cfg)
+		if len(rows) != 1 || rows[0].Err != nil {
+			t.Fatalf("expected one valid connection, got %+v", rows)
+		}
+		if got := rows[0].Config.PeerRegion; got != answers.Peers[1].Region {
+			t.Errorf("expected the accepter in %s, got %s", answers.Peers[1].Region, got)
+		}
+	}
+}
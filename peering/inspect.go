@@ -0,0 +1,220 @@
+package peering
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// -------------------------------------------------------------------------------------------------
+// inspect
+// -------------------------------------------------------------------------------------------------
+
+// InspectNode is one construct of a built stack, or a resource the stack adds as a raw override.
+type InspectNode struct {
+	ID       string         `json:"id"`                 // Construct ID, or logical name of an override.
+	Path     string         `json:"path"`               // Construct path, e.g. peering/Peering0.
+	Kind     string         `json:"kind"`               // stack, provider, resource, data, output, override, or construct.
+	Type     string         `json:"type,omitempty"`     // Terraform type of providers, resources, and data sources.
+	Provider string         `json:"provider,omitempty"` // Provider of resources and data sources, e.g. aws.source_dev.
+	Children []*InspectNode `json:"children,omitempty"` // Constructs below this one, in creation order.
+}
+
+// BuildInspectTree nests constructs listed in preorder under their parents by path. Constructs
+// whose parent is not listed become roots.
+func BuildInspectTree(nodes []*InspectNode) []*InspectNode {
+	byPath := make(map[string]*InspectNode, len(nodes))
+	var roots []*InspectNode
+	for _, n := range nodes {
+		byPath[n.Path] = n
+		var parent *InspectNode
+		if i := strings.LastIndex(n.Path, "/"); i >= 0 {
+			parent = byPath[n.Path[:i]]
+		}
+		if parent != nil {
+			parent.Children = append(parent.Children, n)
+		} else {
+			roots = append(roots, n)
+		}
+	}
+	return roots
+}
+
+// providerRef returns the provider reference of a resource or data source, "aws.<alias>" or the
+// type alone for a default provider.
+func providerRef(p cdktf.TerraformProvider) string {
+	if p == nil {
+		return ""
+	}
+	ref := *p.TerraformResourceType()
+	if alias := p.Alias(); alias != nil && *alias != "" {
+		ref += "." + *alias
+	}
+	return ref
+}
+
+// inspectConstruct describes one construct: its kind, Terraform type, and provider, and the
+// logical ID it synthesizes to, if any.
+func inspectConstruct(c constructs.IConstruct) (*InspectNode, string) {
+	n := &InspectNode{ID: *c.Node().Id(), Path: *c.Node().Path(), Kind: "construct"}
+	switch {
+	case *cdktf.TerraformStack_IsStack(c):
+		n.Kind = "stack"
+	case *cdktf.TerraformProvider_IsTerraformProvider(c):
+		if p, ok := c.(cdktf.TerraformProvider); ok {
+			n.Kind, n.Type = "provider", *p.TerraformResourceType()
+			if alias := p.Alias(); alias != nil {
+				n.Provider = n.Type + "." + *alias
+			}
+		}
+	case *cdktf.TerraformResource_IsTerraformResource(c):
+		if r, ok := c.(cdktf.TerraformResource); ok {
+			n.Kind, n.Type, n.Provider = "resource", *r.TerraformResourceType(), providerRef(r.Provider())
+			return n, "resource." + n.Type + "." + *r.FriendlyUniqueId()
+		}
+	case *cdktf.TerraformDataSource_IsTerraformDataSource(c):
+		if d, ok := c.(cdktf.TerraformDataSource); ok {
+			n.Kind, n.Type, n.Provider = "data", *d.TerraformResourceType(), providerRef(d.Provider())
+			return n, "data." + n.Type + "." + *d.FriendlyUniqueId()
+		}
+	case *cdktf.TerraformOutput_IsTerraformOutput(c):
+		n.Kind = "output"
+	}
+	return n, ""
+}
+
+// OverrideNodes lists the resources and data sources of a synthesized stack document that no
+// construct accounts for, which the stack added as raw overrides, sorted by address.
+func OverrideNodes(stackPath string, doc map[string]interface{}, known map[string]bool) []*InspectNode {
+	var nodes []*InspectNode
+	for _, block := range []string{"resource", "data"} {
+		byType, _ := doc[block].(map[string]interface{})
+		for typ, entries := range byType {
+			byName, _ := entries.(map[string]interface{})
+			for name, body := range byName {
+				if known[block+"."+typ+"."+name] {
+					continue
+				}
+				n := &InspectNode{ID: name, Path: stackPath + "/" + name, Kind: "override", Type: typ}
+				if attrs, ok := body.(map[string]interface{}); ok {
+					n.Provider, _ = attrs["provider"].(string)
+				}
+				if block == "data" {
+					n.Type = "data." + typ
+				}
+				nodes = append(nodes, n)
+			}
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Type != nodes[j].Type {
+			return nodes[i].Type < nodes[j].Type
+		}
+		return nodes[i].ID < nodes[j].ID
+	})
+	return nodes
+}
+
+// InspectApp walks the constructs of an app in preorder and returns them as a tree, with the raw
+// overrides of each stack appended to it. A stack that cannot be rendered lists its constructs only.
+func InspectApp(app cdktf.App) []*InspectNode {
+	var nodes []*InspectNode
+	known := make(map[string]bool)
+	var stacks []cdktf.TerraformStack
+	for _, c := range *app.Node().FindAll(constructs.ConstructOrder_PREORDER) {
+		if *c.Node().Path() == "" {
+			continue
+		}
+		n, logicalID := inspectConstruct(c)
+		nodes = append(nodes, n)
+		if logicalID != "" {
+			known[logicalID] = true
+		}
+		if s, ok := c.(cdktf.TerraformStack); ok && n.Kind == "stack" {
+			stacks = append(stacks, s)
+		}
+	}
+	roots := BuildInspectTree(nodes)
+	for _, s := range stacks {
+		doc := renderStack(s)
+		for _, root := range roots {
+			if root.Path == *s.Node().Path() {
+				root.Children = append(root.Children, OverrideNodes(root.Path, doc, known)...)
+			}
+		}
+	}
+	return roots
+}
+
+// renderStack returns the Terraform document of a stack, or nil when rendering fails (e.g. on the
+// construct error inspect is being used to debug).
+func renderStack(s cdktf.TerraformStack) (doc map[string]interface{}) {
+	defer func() {
+		if recover() != nil {
+			doc = nil
+		}
+	}()
+	doc, _ = s.ToTerraform().(map[string]interface{})
+	return doc
+}
+
+// PrintInspectTree writes the tree one construct per line, indented by depth.
+func PrintInspectTree(w io.Writer, nodes []*InspectNode, depth int) {
+	for _, n := range nodes {
+		line := strings.Repeat("  ", depth) + n.ID + "  " + n.Kind
+		if n.Type != "" {
+			line += " " + n.Type
+		}
+		if n.Provider != "" {
+			line += "  (" + n.Provider + ")"
+		}
+		fmt.Fprintln(w, line)
+		PrintInspectTree(w, n.Children, depth+1)
+	}
+}
+
+// runInspect builds the stack of a source without synthesizing it and prints every construct with
+// its Terraform type and provider. When building fails, such as on a construct ID collision, the
+// constructs created until then are printed before the error.
+func runInspect(args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	format := fs.String("format", "tree", "output format: tree or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *format != "tree" && *format != "json" {
+		return fmt.Errorf("unknown format %q (want tree or json)", *format)
+	}
+	source := sourceArg(fs)
+	cfg, peers := loadSourcePeers(source)
+
+	app := cdktf.NewApp(nil)
+	buildErr := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recoveredFailure(r)
+			}
+		}()
+		NewMyStack(app, StackName, source, peers, StackOptionsFor(cfg))
+		return nil
+	}()
+
+	tree := InspectApp(app)
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(tree); err != nil {
+			return err
+		}
+	} else {
+		PrintInspectTree(os.Stdout, tree, 0)
+	}
+	return buildErr
+}
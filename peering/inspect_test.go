@@ -0,0 +1,58 @@
+package peering
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestBuildInspectTree tests that constructs nest under their parents and print indented.
+func TestBuildInspectTree(t *testing.T) {
+	nodes := []*InspectNode{
+		{ID: "peering", Path: "peering", Kind: "stack"},
+		{ID: "SourceProvider0", Path: "peering/SourceProvider0", Kind: "provider", Type: "aws", Provider: "aws.source_dev"},
+		{ID: "Peering0", Path: "peering/Peering0", Kind: "resource", Type: "aws_vpc_peering_connection", Provider: "aws.source_dev"},
+		{ID: "Default", Path: "peering/Peering0/Default", Kind: "construct"},
+		{ID: "Orphan", Path: "missing/Orphan", Kind: "construct"},
+	}
+	roots := BuildInspectTree(nodes)
+	if len(roots) != 2 || len(roots[0].Children) != 2 || len(roots[0].Children[1].Children) != 1 {
+		t.Fatalf("unexpected tree shape: %d roots", len(roots))
+	}
+
+	var buf bytes.Buffer
+	PrintInspectTree(&buf, roots[:1], 0)
+	want := `peering  stack
+  SourceProvider0  provider aws  (aws.source_dev)
+  Peering0  resource aws_vpc_peering_connection  (aws.source_dev)
+    Default  construct
+`
+	if buf.String() != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, buf.String())
+	}
+}
+
+// TestOverrideNodes tests that only resources without a construct are listed, sorted.
+func TestOverrideNodes(t *testing.T) {
+	doc := map[string]interface{}{
+		"resource": map[string]interface{}{
+			"aws_vpc_peering_connection": map[string]interface{}{"Peering0": map[string]interface{}{}},
+			"aws_route_table": map[string]interface{}{
+				"CustomRouteTable": map[string]interface{}{"provider": "aws.source_dev"},
+			},
+		},
+		"data": map[string]interface{}{
+			"aws_ssm_parameter": map[string]interface{}{"CustomParameter": map[string]interface{}{"provider": "aws.peer_prod"}},
+		},
+	}
+	known := map[string]bool{"resource.aws_vpc_peering_connection.Peering0": true}
+	got := OverrideNodes("peering", doc, known)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 overrides, got %d", len(got))
+	}
+	if got[0].ID != "CustomRouteTable" || got[0].Type != "aws_route_table" || got[0].Provider != "aws.source_dev" || got[0].Path != "peering/CustomRouteTable" {
+		t.Errorf("unexpected override %+v", *got[0])
+	}
+	if got[1].Type != "data.aws_ssm_parameter" || got[1].Kind != "override" {
+		t.Errorf("unexpected override %+v", *got[1])
+	}
+}
@@ -0,0 +1,164 @@
+package peering
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Inspection Routing
+// -------------------------------------------------------------------------------------------------
+
+// vpceIDPattern matches VPC endpoint IDs.
+var vpceIDPattern = regexp.MustCompile(`^vpce-[0-9a-f]+$`)
+
+// InspectionSide names the Gateway Load Balancer endpoint one VPC sends peered traffic through and
+// the route table of the subnets holding it.
+type InspectionSide struct {
+	ApplianceRouteTable string `yaml:"appliance_route_table"` // Route table of the endpoint's subnets, routed to the peering.
+	EndpointID          string `yaml:"endpoint_id"`           // GWLB endpoint the VPC's other route tables send peered traffic to.
+}
+
+// Validate checks the IDs of the side.
+func (s InspectionSide) Validate() error {
+	if !routeTableIDPattern.MatchString(s.ApplianceRouteTable) {
+		return fmt.Errorf("appliance_route_table must be a route table ID (rtb-...), got %q", s.ApplianceRouteTable)
+	}
+	if !vpceIDPattern.MatchString(s.EndpointID) {
+		return fmt.Errorf("endpoint_id must be a VPC endpoint ID (vpce-...), got %q", s.EndpointID)
+	}
+	return nil
+}
+
+// InspectionConfig routes a connection through inspection appliances instead of straight to the
+// peering: the routes of each inspected side target its GWLB endpoint, and its appliance route table
+// sends the inspected traffic on through the peering.
+//
+// A peering cannot be given an ingress route table, so traffic arriving from the peer is delivered
+// straight to its destination. Each direction is therefore inspected on the side it leaves, and both
+// sides must be inspected for a stateful appliance to see both halves of a flow; the endpoints must
+// belong to the same GWLB, whose flow stickiness sends both halves to the same appliance.
+type InspectionConfig struct {
+	InspectionSide `yaml:",inline"` // The source VPC's endpoint.
+	Peer           *InspectionSide  `yaml:"peer,omitempty"`      // The peer VPC's endpoint, inspecting the return path.
+	Stateless      bool             `yaml:"stateless,omitempty"` // Inspect the source side only, for appliances that do not track flows.
+}
+
+// Validate checks both sides and refuses a single inspected side unless the appliance is stateless,
+// as a stateful appliance would drop flows whose return path bypasses it.
+func (c InspectionConfig) Validate() error {
+	if err := c.InspectionSide.Validate(); err != nil {
+		return err
+	}
+	switch {
+	case c.Peer == nil && !c.Stateless:
+		return fmt.Errorf("via_inspection needs the peer VPC's endpoint under peer: return traffic bypasses an appliance " +
+			"on the source side only, and stateful appliances drop such flows; set stateless: true if the appliance does not track them")
+	case c.Peer != nil && c.Stateless:
+		return fmt.Errorf("via_inspection sets both peer and stateless; stateless is only for inspecting the source side alone")
+	case c.Peer != nil:
+		if err := c.Peer.Validate(); err != nil {
+			return fmt.Errorf("peer: %w", err)
+		}
+	}
+	return nil
+}
+
+// ValidateInspection checks a connection's inspection against the tables it routes: the routes to
+// the endpoint must not land in the appliance route table, whose routes go to the peering.
+func ValidateInspection(c InspectionConfig, sourcePeer, peerPeer YAMLPeer, sourceRouting, peerRouting RoutingConfig) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	for _, s := range []struct {
+		name    string
+		side    *InspectionSide
+		peer    YAMLPeer
+		routing RoutingConfig
+	}{{"source", &c.InspectionSide, sourcePeer, sourceRouting}, {"peer", c.Peer, peerPeer, peerRouting}} {
+		if s.side == nil {
+			continue
+		}
+		if s.routing.Strategy == RoutingNone {
+			return fmt.Errorf("via_inspection routes the %s side, whose routing is none", s.name)
+		}
+		if s.routing.Strategy != RoutingAll && s.peer.MainRouteTableID == s.side.ApplianceRouteTable {
+			return fmt.Errorf("the %s main route table %s is the appliance route table; route the workloads with filtered "+
+				"or all routing instead", s.name, s.side.ApplianceRouteTable)
+		}
+	}
+	return nil
+}
+
+// ApplyInspection points the routes of every inspected side of a connection at its endpoint and
+// routes the side's destinations through the peering in its appliance route table. The appliance
+// table is left out of the for_each sets of the side's routes, so inspected traffic is not sent
+// back to the endpoint: sets of route table IDs drop it, and sets of subnet IDs drop the subnets
+// associated with it.
+func ApplyInspection(
+	stack cdktf.TerraformStack,
+	namer Namer,
+	ctx NameContext,
+	peer PeerConfig,
+	core PeerCoreResources,
+	peeringRes PeeringResources,
+	routes *RouteResources,
+) {
+	if peer.Inspection == nil {
+		return
+	}
+	inspectSide(stack, namer, ctx, sourceSide, &peer.Inspection.InspectionSide, peer.SourceRouting, core.SourceProvider,
+		peer.DestinationCidrs, peer.SourceExtraCidrs, core.PeerCidr, peeringRes, &routes.Source)
+	inspectSide(stack, namer, ctx, peerSide, peer.Inspection.Peer, peer.PeerRouting, core.PeerProvider,
+		nil, peer.PeerExtraCidrs, core.SourceCidr, peeringRes, &routes.Peer)
+}
+
+// inspectSide applies the inspection of one side to its routes.
+func inspectSide(
+	stack cdktf.TerraformStack,
+	namer Namer,
+	ctx NameContext,
+	side routeSide,
+	inspection *InspectionSide,
+	routing RoutingConfig,
+	provider cdktf.TerraformProvider,
+	cidrs []string,
+	extra []string,
+	fallback *string,
+	peeringRes PeeringResources,
+	res *SideResources,
+) {
+	if inspection == nil || routing.Strategy == RoutingNone {
+		return
+	}
+	for i, route := range res.Routes {
+		route.ResetVpcPeeringConnectionId()
+		route.AddOverride(jsii.String("vpc_endpoint_id"), inspection.EndpointID)
+		info := &res.Info[i]
+		switch {
+		case info.ForEach == "":
+			continue
+		case info.Tables != "":
+			info.ForEach = fmt.Sprintf("toset([for subnet in %s : subnet if %s[subnet].id != %q])",
+				info.ForEach, info.Tables, inspection.ApplianceRouteTable)
+		default:
+			info.ForEach = fmt.Sprintf("setsubtract(%s, [%q])", info.ForEach, inspection.ApplianceRouteTable)
+		}
+		route.AddOverride(jsii.String("for_each"), "${"+info.ForEach+"}")
+	}
+	for _, target := range RouteTargets(namer, ctx, side.ApplianceRoute, cidrs, extra, fallback) {
+		res.Routes = append(res.Routes, CreateRoute(
+			stack,
+			target.ID,
+			jsii.String(inspection.ApplianceRouteTable),
+			target.Cidr,
+			peeringRes.PeeringID(),
+			provider,
+			peeringRes.DependsOn,
+		))
+		res.Info = append(res.Info, RouteInfo{Cidr: target.Cidr})
+	}
+}
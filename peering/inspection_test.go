@@ -0,0 +1,162 @@
+package peering
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+	"gopkg.in/yaml.v2"
+)
+
+// TestInspectionConfigValidate tests the IDs of each side and the refusal of a source-only
+// inspection with a stateful appliance.
+func TestInspectionConfigValidate(t *testing.T) {
+	source := InspectionSide{ApplianceRouteTable: "rtb-0a1", EndpointID: "vpce-0a1"}
+	peer := &InspectionSide{ApplianceRouteTable: "rtb-0b1", EndpointID: "vpce-0b1"}
+	tests := []struct {
+		name    string
+		cfg     InspectionConfig
+		wantErr string
+	}{
+		{"both sides", InspectionConfig{InspectionSide: source, Peer: peer}, ""},
+		{"stateless source only", InspectionConfig{InspectionSide: source, Stateless: true}, ""},
+		{"stateful source only", InspectionConfig{InspectionSide: source}, "needs the peer VPC's endpoint"},
+		{"stateless with peer", InspectionConfig{InspectionSide: source, Peer: peer, Stateless: true}, "both peer and stateless"},
+		{"bad table", InspectionConfig{InspectionSide: InspectionSide{ApplianceRouteTable: "tbl", EndpointID: "vpce-0a1"}, Stateless: true}, "appliance_route_table"},
+		{"bad peer endpoint", InspectionConfig{InspectionSide: source, Peer: &InspectionSide{ApplianceRouteTable: "rtb-0b1", EndpointID: "eni-1"}}, "peer: endpoint_id"},
+	}
+	for _, tt := range tests {
+		err := tt.cfg.Validate()
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: expected an error with %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+// TestValidateInspection tests that the routes to an endpoint never land in its appliance table.
+func TestValidateInspection(t *testing.T) {
+	cfg := InspectionConfig{InspectionSide: InspectionSide{ApplianceRouteTable: "rtb-0a1", EndpointID: "vpce-0a1"}, Stateless: true}
+	main := RoutingConfig{Strategy: RoutingMain}
+	all := RoutingConfig{Strategy: RoutingAll}
+	if err := ValidateInspection(cfg, YAMLPeer{MainRouteTableID: "rtb-0a2"}, YAMLPeer{}, main, main); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ValidateInspection(cfg, YAMLPeer{MainRouteTableID: "rtb-0a1"}, YAMLPeer{}, main, main); err == nil {
+		t.Error("expected the appliance table as main route table to be rejected")
+	}
+	if err := ValidateInspection(cfg, YAMLPeer{MainRouteTableID: "rtb-0a1"}, YAMLPeer{}, all, main); err != nil {
+		t.Errorf("expected all routing to leave the appliance table out, got %v", err)
+	}
+	if err := ValidateInspection(cfg, YAMLPeer{}, YAMLPeer{}, RoutingConfig{Strategy: RoutingNone}, main); err == nil {
+		t.Error("expected an inspected side without routing to be rejected")
+	}
+}
+
+// TestInspectionYAML tests the inline source side of via_inspection.
+func TestInspectionYAML(t *testing.T) {
+	var entry MatrixEntry
+	doc := "peer: prod\nvia_inspection:\n  appliance_route_table: rtb-0a1\n  endpoint_id: vpce-0a1\n  peer:\n    appliance_route_table: rtb-0b1\n    endpoint_id: vpce-0b1\n"
+	if err := yaml.Unmarshal([]byte(doc), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Inspection == nil || entry.Inspection.ApplianceRouteTable != "rtb-0a1" || entry.Inspection.Peer == nil ||
+		entry.Inspection.Peer.EndpointID != "vpce-0b1" {
+		t.Errorf("unexpected via_inspection: %+v", entry.Inspection)
+	}
+}
+
+// TestInspectionAddresses tests that the appliance routes of inspected sides are recorded, per
+// destination CIDR like the other routes.
+func TestInspectionAddresses(t *testing.T) {
+	peer := PeerConfig{
+		SourceName:       "dev",
+		Name:             "prod",
+		DestinationCidrs: []string{"10.1.0.0/24"},
+		SourceRouting:    RoutingConfig{Strategy: RoutingMain},
+		PeerRouting:      RoutingConfig{Strategy: RoutingMain},
+		Inspection:       &InspectionConfig{InspectionSide: InspectionSide{ApplianceRouteTable: "rtb-0a1", EndpointID: "vpce-0a1"}, Stateless: true},
+	}
+	addresses := ConnectionAddresses(LegacyNamer{}, ConnectionNameContext(0, peer), peer)
+	if got := addresses[KindSourceApplianceRoute+":10.1.0.0/24"]; got != "aws_route.SourceApplianceToPeerRoute0_10_1_0_0_24" {
+		t.Errorf("expected the source appliance route, got %q in %v", got, addresses)
+	}
+	if _, ok := addresses[KindPeerApplianceRoute]; ok {
+		t.Error("unexpected appliance route on the uninspected peer side")
+	}
+}
+
+// TestApplyInspection tests that inspected routes target the endpoint, leave the appliance route
+// table out of their for_each sets, and that the appliance table routes through the peering.
+func TestApplyInspection(t *testing.T) {
+	stack := cdktf.NewTerraformStack(cdktf.Testing_App(nil), jsii.String("peering"))
+	peering := cdktf.NewTerraformDataSource(stack, jsii.String("Peering"), &cdktf.TerraformResourceConfig{
+		TerraformResourceType: jsii.String("aws_vpc_peering_connection"),
+	})
+	peeringRes := PeeringResources{Data: peering}
+	peer := PeerConfig{
+		SourceName:       "dev",
+		Name:             "prod",
+		DestinationCidrs: []string{"10.1.0.0/24"},
+		SourceRouting:    RoutingConfig{Strategy: RoutingFiltered},
+		PeerRouting:      RoutingConfig{Strategy: RoutingFiltered, KeyByRouteTable: true},
+		Inspection: &InspectionConfig{
+			InspectionSide: InspectionSide{ApplianceRouteTable: "rtb-0a1", EndpointID: "vpce-0a1"},
+			Peer:           &InspectionSide{ApplianceRouteTable: "rtb-0b1", EndpointID: "vpce-0b1"},
+		},
+	}
+	core := PeerCoreResources{SourceCidr: jsii.String("10.0.0.0/16"), PeerCidr: jsii.String("10.1.0.0/16")}
+	routes := RouteResources{
+		Source: CreateSubnetRoutes(stack, "SourceSubnetRt", []RouteTarget{{ID: "SourceRoute", Cidr: jsii.String("10.1.0.0/24")}},
+			jsii.Strings("subnet-1", "subnet-2"), nil, peeringRes.PeeringID(), nil),
+		Peer: CreateRouteTableKeyedSubnetRoutes(stack, "PeerSubnetRt", []RouteTarget{{ID: "PeerRoute", Cidr: jsii.String("10.0.0.0/16")}},
+			`["subnet-3"]`, nil, peeringRes.PeeringID(), nil),
+	}
+	ApplyInspection(stack, LegacyNamer{}, ConnectionNameContext(0, peer), peer, core, peeringRes, &routes)
+
+	var synthesized struct {
+		Resource struct {
+			Route map[string]map[string]interface{} `json:"aws_route"`
+		} `json:"resource"`
+	}
+	if err := json.Unmarshal([]byte(*cdktf.Testing_Synth(stack, nil)), &synthesized); err != nil {
+		t.Fatal(err)
+	}
+	got := synthesized.Resource.Route
+
+	tests := []struct {
+		id       string
+		endpoint string
+		forEach  string
+	}{
+		{"SourceRoute", "vpce-0a1", `${toset([for subnet in toset(keys(data.aws_route_table.SourceSubnetRt)) : subnet if data.aws_route_table.SourceSubnetRt[subnet].id != "rtb-0a1"])}`},
+		{"PeerRoute", "vpce-0b1", `${setsubtract(local.` + SubnetRouteTablesLocal("PeerSubnetRt") + `, ["rtb-0b1"])}`},
+	}
+	for _, tt := range tests {
+		route := got[tt.id]
+		if route["vpc_endpoint_id"] != tt.endpoint || route["vpc_peering_connection_id"] != nil {
+			t.Errorf("%s: expected the route to target %s only, got %v", tt.id, tt.endpoint, route)
+		}
+		if route["for_each"] != tt.forEach {
+			t.Errorf("%s: unexpected for_each %v", tt.id, route["for_each"])
+		}
+	}
+
+	for _, tt := range []struct{ id, table string }{
+		{"SourceApplianceToPeerRoute0_10_1_0_0_24", "rtb-0a1"},
+		{"PeerApplianceToSourceRoute0", "rtb-0b1"},
+	} {
+		route, ok := got[tt.id]
+		if !ok {
+			t.Errorf("expected the appliance route %s, got %v", tt.id, got)
+			continue
+		}
+		if route["route_table_id"] != tt.table || route["vpc_peering_connection_id"] == nil || route["vpc_endpoint_id"] != nil {
+			t.Errorf("%s: expected a route through the peering in %s, got %v", tt.id, tt.table, route)
+		}
+	}
+}
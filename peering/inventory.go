@@ -0,0 +1,134 @@
+package peering
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+
+	awsprovider "cdk.tf/go/stack/generated/hashicorp/aws/provider"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Connection Inventory
+// -------------------------------------------------------------------------------------------------
+
+// InventoryConfig selects where the connection inventory is written on apply. The document is
+// written by Terraform resources, so it always reflects the applied state.
+type InventoryConfig struct {
+	S3       *S3InventorySink       `yaml:"s3,omitempty"`       // Write one JSON object per stack.
+	DynamoDB *DynamoDBInventorySink `yaml:"dynamodb,omitempty"` // Write one item per connection.
+}
+
+// S3InventorySink writes the inventory document as a JSON object.
+type S3InventorySink struct {
+	Bucket  string `yaml:"bucket"`             // Bucket name.
+	Key     string `yaml:"key,omitempty"`      // Object key; "{source}" expands to the stack's source (default vpc-peering/{source}.json).
+	Region  string `yaml:"region,omitempty"`   // Bucket region (default region if empty).
+	RoleArn string `yaml:"role_arn,omitempty"` // Role to write with (ambient credentials if empty).
+}
+
+// DynamoDBInventorySink writes one item per connection, keyed by connection key.
+type DynamoDBInventorySink struct {
+	Table   string `yaml:"table"`              // Table name.
+	HashKey string `yaml:"hash_key,omitempty"` // String hash key attribute (default "id").
+	Region  string `yaml:"region,omitempty"`   // Table region (default region if empty).
+	RoleArn string `yaml:"role_arn,omitempty"` // Role to write with (ambient credentials if empty).
+}
+
+// Validate reports incomplete sinks.
+func (c InventoryConfig) Validate() error {
+	if c.S3 != nil && c.S3.Bucket == "" {
+		return fmt.Errorf("inventory.s3.bucket is required")
+	}
+	if c.DynamoDB != nil && c.DynamoDB.Table == "" {
+		return fmt.Errorf("inventory.dynamodb.table is required")
+	}
+	return nil
+}
+
+// InventoryKey returns the S3 object key for a source.
+func (s S3InventorySink) InventoryKey(sourceID string) string {
+	key := s.Key
+	if key == "" {
+		key = "vpc-peering/{source}.json"
+	}
+	if sourceID == "" {
+		sourceID = "all"
+	}
+	return strings.ReplaceAll(key, "{source}", sourceID)
+}
+
+// InventoryDocument returns the inventory of a stack: every managed connection with its details,
+// in connection key order.
+func InventoryDocument(stackName, sourceID string, connections []ConnectionResources) map[string]interface{} {
+	sorted := append([]ConnectionResources(nil), connections...)
+	sort.SliceStable(sorted, func(i, j int) bool { return ConnectionKey(sorted[i].Peer) < ConnectionKey(sorted[j].Peer) })
+
+	items := make([]interface{}, 0, len(sorted))
+	for _, c := range sorted {
+		details := ConnectionDetails(c)
+		details["key"] = ConnectionKey(c.Peer)
+		items = append(items, details)
+	}
+	return map[string]interface{}{
+		"stack":       stackName,
+		"source":      sourceID,
+		"connections": items,
+	}
+}
+
+// standaloneProvider creates a provider outside any connection, such as the one an inventory sink
+// writes with, using ambient credentials when no role is given.
+func standaloneProvider(stack cdktf.TerraformStack, id, region, roleArn string, settings ProviderSettings) awsprovider.AwsProvider {
+	cfg := &awsprovider.AwsProviderConfig{
+		Region: jsii.String(ResolveRegion(region)),
+		Alias:  jsii.String(id),
+	}
+	if roleArn != "" {
+		cfg.AssumeRole = &[]*awsprovider.AwsProviderAssumeRole{{RoleArn: jsii.String(roleArn)}}
+	}
+	settings.Apply(cfg)
+	return awsprovider.NewAwsProvider(stack, jsii.String(id), cfg)
+}
+
+// AddInventory writes the connection inventory to the configured sinks.
+func AddInventory(stack cdktf.TerraformStack, namer Namer, sourceID string, connections []ConnectionResources, cfg InventoryConfig, settings ProviderSettings) {
+	stackName := *stack.Node().Id()
+	if s := cfg.S3; s != nil {
+		provider := standaloneProvider(stack, "inventory_s3", s.Region, s.RoleArn, settings)
+		object := cdktf.NewTerraformResource(stack, jsii.String("InventoryObject"), &cdktf.TerraformResourceConfig{
+			TerraformResourceType: jsii.String("aws_s3_object"),
+			Provider:              provider,
+		})
+		object.AddOverride(jsii.String("bucket"), s.Bucket)
+		object.AddOverride(jsii.String("key"), s.InventoryKey(sourceID))
+		object.AddOverride(jsii.String("content_type"), "application/json")
+		object.AddOverride(jsii.String("content"), cdktf.Fn_Jsonencode(InventoryDocument(stackName, sourceID, connections)))
+	}
+
+	if d := cfg.DynamoDB; d != nil {
+		hashKey := d.HashKey
+		if hashKey == "" {
+			hashKey = "id"
+		}
+		provider := standaloneProvider(stack, "inventory_dynamodb", d.Region, d.RoleArn, settings)
+		for i, c := range connections {
+			details := ConnectionDetails(c)
+			item := cdktf.NewTerraformResource(stack, jsii.String(namer.ID(ConnectionNameContext(i, c.Peer), KindInventoryItem)), &cdktf.TerraformResourceConfig{
+				TerraformResourceType: jsii.String("aws_dynamodb_table_item"),
+				Provider:              provider,
+			})
+			item.AddOverride(jsii.String("table_name"), d.Table)
+			item.AddOverride(jsii.String("hash_key"), hashKey)
+			item.AddOverride(jsii.String("item"), cdktf.Fn_Jsonencode(map[string]interface{}{
+				hashKey:      map[string]interface{}{"S": ConnectionKey(c.Peer)},
+				"stack":      map[string]interface{}{"S": stackName},
+				"peering_id": map[string]interface{}{"S": details["peering_id"]},
+				"document":   map[string]interface{}{"S": cdktf.Fn_Jsonencode(details)},
+			}))
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package peering
+
+import "testing"
+
+// TestInventoryConfig tests sink validation and S3 key expansion.
+func TestInventoryConfig(t *testing.T) {
+	if err := (InventoryConfig{S3: &S3InventorySink{}}).Validate(); err == nil {
+		t.Error("expected an error for an S3 sink without bucket")
+	}
+	if err := (InventoryConfig{DynamoDB: &DynamoDBInventorySink{}}).Validate(); err == nil {
+		t.Error("expected an error for a DynamoDB sink without table")
+	}
+	if err := (InventoryConfig{}).Validate(); err != nil {
+		t.Errorf("unexpected error without sinks: %v", err)
+	}
+
+	tests := []struct {
+		key, source, want string
+	}{
+		{"", "dev-peer", "vpc-peering/dev-peer.json"},
+		{"", "", "vpc-peering/all.json"},
+		{"cmdb/{source}/peerings.json", "dev-peer", "cmdb/dev-peer/peerings.json"},
+	}
+	for _, tt := range tests {
+		if got := (S3InventorySink{Bucket: "b", Key: tt.key}).InventoryKey(tt.source); got != tt.want {
+			t.Errorf("InventoryKey(%q, %q) = %q, want %q", tt.key, tt.source, got, tt.want)
+		}
+	}
+}
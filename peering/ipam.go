@@ -0,0 +1,159 @@
+package peering
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strings"
+)
+
+// -------------------------------------------------------------------------------------------------
+// IPAM Integration
+// -------------------------------------------------------------------------------------------------
+
+// DefaultIPAMTagKey is the VPC tag that names the peer a VPC belongs to when ipam.tag_key is unset.
+const DefaultIPAMTagKey = "peering:peer"
+
+// IPAMConfig fills in the VPC IDs and CIDRs of peers from the VPCs AWS IPAM monitors, matched to
+// peers by a tag, instead of copying them into the config by hand.
+type IPAMConfig struct {
+	ScopeID string `yaml:"scope_id"`           // IPAM scope whose VPC CIDRs are read, e.g. the private default scope.
+	Region  string `yaml:"region,omitempty"`   // Home region of the IPAM (default region if empty).
+	RoleArn string `yaml:"role_arn,omitempty"` // Role to read IPAM with (ambient credentials if empty).
+	TagKey  string `yaml:"tag_key,omitempty"`  // VPC tag holding the peer name (default peering:peer).
+}
+
+// Validate requires the scope.
+func (c IPAMConfig) Validate() error {
+	if !strings.HasPrefix(c.ScopeID, "ipam-scope-") {
+		return fmt.Errorf("ipam.scope_id must be an IPAM scope ID (ipam-scope-...), got %q", c.ScopeID)
+	}
+	return nil
+}
+
+// tagKey returns the tag naming a VPC's peer.
+func (c IPAMConfig) tagKey() string {
+	if c.TagKey == "" {
+		return DefaultIPAMTagKey
+	}
+	return c.TagKey
+}
+
+// IPAMVpc is a VPC as IPAM records it.
+type IPAMVpc struct {
+	VpcID     string            // VPC ID.
+	Region    string            // Region of the VPC.
+	AccountID string            // Account owning the VPC.
+	Cidrs     []string          // IPv4 CIDR blocks of the VPC, sorted.
+	Tags      map[string]string // Tags of the VPC.
+}
+
+// IPAMLookup lists the VPCs of an IPAM scope. Implemented by the AWS CLI and by fakes in tests.
+type IPAMLookup interface {
+	IPAMVpcs(scopeID string) ([]IPAMVpc, error)
+}
+
+// IPAMVpcs lists the VPCs of an IPAM scope with their IPv4 CIDRs. IPAM returns one record per CIDR,
+// which are grouped by VPC.
+func (c *AWSCLI) IPAMVpcs(scopeID string) ([]IPAMVpc, error) {
+	var out struct {
+		IpamResourceCidrs []struct {
+			ResourceID      string `json:"ResourceId"`
+			ResourceCidr    string `json:"ResourceCidr"`
+			ResourceRegion  string `json:"ResourceRegion"`
+			ResourceOwnerID string `json:"ResourceOwnerId"`
+			ResourceTags    []struct {
+				Key   string `json:"Key"`
+				Value string `json:"Value"`
+			} `json:"ResourceTags"`
+		} `json:"IpamResourceCidrs"`
+	}
+	if err := c.Run(&out, "ec2", "get-ipam-resource-cidrs", "--ipam-scope-id", scopeID, "--resource-type", "vpc"); err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*IPAMVpc)
+	var ids []string
+	for _, r := range out.IpamResourceCidrs {
+		vpc, ok := byID[r.ResourceID]
+		if !ok {
+			vpc = &IPAMVpc{VpcID: r.ResourceID, Region: r.ResourceRegion, AccountID: r.ResourceOwnerID, Tags: make(map[string]string)}
+			byID[r.ResourceID] = vpc
+			ids = append(ids, r.ResourceID)
+		}
+		for _, tag := range r.ResourceTags {
+			vpc.Tags[tag.Key] = tag.Value
+		}
+		if ip, _, err := net.ParseCIDR(r.ResourceCidr); err == nil && ip.To4() != nil {
+			vpc.Cidrs = append(vpc.Cidrs, r.ResourceCidr)
+		}
+	}
+	sort.Strings(ids)
+	vpcs := make([]IPAMVpc, 0, len(ids))
+	for _, id := range ids {
+		sort.Strings(byID[id].Cidrs)
+		vpcs = append(vpcs, *byID[id])
+	}
+	return vpcs, nil
+}
+
+// ApplyIPAM fills in every peer from the VPC IPAM records for it: the VPC tagged with the peer's
+// name, or the peer's own vpc_id when it sets one. Peers get the VPC ID, all IPv4 CIDRs as cidrs,
+// the CIDR as cidr when the VPC has only one (IPAM does not mark the primary), and the region when
+// unset; values in the config are kept. A peer whose config contradicts IPAM (another VPC, region,
+// or account) is an error, as is a tag naming a peer on several VPCs. Peers IPAM does not know are
+// left as configured.
+func ApplyIPAM(cfg *YAMLConfig, lookup IPAMLookup) error {
+	vpcs, err := lookup.IPAMVpcs(cfg.IPAM.ScopeID)
+	if err != nil {
+		return fmt.Errorf("failed to read IPAM scope %s: %w", cfg.IPAM.ScopeID, err)
+	}
+	byID := make(map[string]IPAMVpc, len(vpcs))
+	byPeer := make(map[string]IPAMVpc)
+	for _, vpc := range vpcs {
+		byID[vpc.VpcID] = vpc
+		name, ok := vpc.Tags[cfg.IPAM.tagKey()]
+		if !ok {
+			continue
+		}
+		if prev, dup := byPeer[name]; dup {
+			return fmt.Errorf("VPCs %s and %s are both tagged %s=%s", prev.VpcID, vpc.VpcID, cfg.IPAM.tagKey(), name)
+		}
+		byPeer[name] = vpc
+	}
+
+	filled := 0
+	for _, name := range sortedPeerNames(*cfg) {
+		peer := cfg.Peers[name]
+		vpc, tagged := byPeer[name]
+		switch {
+		case tagged && peer.VpcID != "" && peer.VpcID != vpc.VpcID:
+			return fmt.Errorf("peer %q sets vpc_id %s, but IPAM tags %s with %s=%s", name, peer.VpcID, vpc.VpcID, cfg.IPAM.tagKey(), name)
+		case !tagged && peer.VpcID != "":
+			vpc, tagged = byID[peer.VpcID]
+		}
+		if !tagged {
+			continue
+		}
+		if peer.Region != "" && peer.Region != vpc.Region {
+			return fmt.Errorf("peer %q is in region %s, but IPAM has %s in %s", name, peer.Region, vpc.VpcID, vpc.Region)
+		}
+		if account := GetAccountIDFromRoleArn(peer.RoleArn); account != "" && vpc.AccountID != "" && account != vpc.AccountID {
+			return fmt.Errorf("peer %q assumes a role in account %s, but IPAM has %s in account %s", name, account, vpc.VpcID, vpc.AccountID)
+		}
+		peer.VpcID = vpc.VpcID
+		if peer.Region == "" {
+			peer.Region = vpc.Region
+		}
+		if len(peer.Cidrs) == 0 {
+			peer.Cidrs = vpc.Cidrs
+		}
+		if peer.Cidr == "" && len(vpc.Cidrs) == 1 {
+			peer.Cidr = vpc.Cidrs[0]
+		}
+		cfg.Peers[name] = peer
+		filled++
+	}
+	log.Printf("[config] Filled %d peer(s) from IPAM scope %s", filled, cfg.IPAM.ScopeID)
+	return nil
+}
@@ -0,0 +1,61 @@
+package peering
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeIPAM returns fixed VPCs for any scope.
+type fakeIPAM []IPAMVpc
+
+func (f fakeIPAM) IPAMVpcs(string) ([]IPAMVpc, error) { return f, nil }
+
+// TestApplyIPAM tests filling peers from tagged VPCs and from their own vpc_id.
+func TestApplyIPAM(t *testing.T) {
+	lookup := fakeIPAM{
+		{VpcID: "vpc-1", Region: "us-east-1", AccountID: "111111111111", Cidrs: []string{"10.0.0.0/16"}, Tags: map[string]string{"peering:peer": "dev"}},
+		{VpcID: "vpc-2", Region: "us-west-2", AccountID: "222222222222", Cidrs: []string{"10.1.0.0/16", "100.64.0.0/16"}},
+	}
+	cfg := YAMLConfig{
+		IPAM: &IPAMConfig{ScopeID: "ipam-scope-1"},
+		Peers: map[string]YAMLPeer{
+			"dev":   {RoleArn: "arn:aws:iam::111111111111:role/r"},
+			"prod":  {VpcID: "vpc-2", Region: "us-west-2"},
+			"other": {VpcID: "vpc-9", Cidr: "10.9.0.0/16"},
+		},
+	}
+	if err := ApplyIPAM(&cfg, lookup); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]YAMLPeer{
+		"dev":   {VpcID: "vpc-1", Region: "us-east-1", RoleArn: "arn:aws:iam::111111111111:role/r", Cidrs: []string{"10.0.0.0/16"}, Cidr: "10.0.0.0/16"},
+		"prod":  {VpcID: "vpc-2", Region: "us-west-2", Cidrs: []string{"10.1.0.0/16", "100.64.0.0/16"}},
+		"other": {VpcID: "vpc-9", Cidr: "10.9.0.0/16"},
+	}
+	if !reflect.DeepEqual(cfg.Peers, want) {
+		t.Errorf("expected %+v, got %+v", want, cfg.Peers)
+	}
+}
+
+// TestApplyIPAMConflicts tests that configs contradicting IPAM are rejected.
+func TestApplyIPAMConflicts(t *testing.T) {
+	dev := IPAMVpc{VpcID: "vpc-1", Region: "us-east-1", AccountID: "111111111111", Tags: map[string]string{"team": "dev"}}
+	tests := []struct {
+		name   string
+		lookup fakeIPAM
+		peer   YAMLPeer
+		want   string
+	}{
+		{"other vpc", fakeIPAM{dev}, YAMLPeer{VpcID: "vpc-2"}, "sets vpc_id vpc-2"},
+		{"region", fakeIPAM{dev}, YAMLPeer{Region: "eu-west-1"}, "is in region eu-west-1"},
+		{"account", fakeIPAM{dev}, YAMLPeer{RoleArn: "arn:aws:iam::999999999999:role/r"}, "account 999999999999"},
+		{"duplicate tag", fakeIPAM{dev, {VpcID: "vpc-3", Tags: map[string]string{"team": "dev"}}}, YAMLPeer{}, "are both tagged team=dev"},
+	}
+	for _, tt := range tests {
+		cfg := YAMLConfig{IPAM: &IPAMConfig{ScopeID: "ipam-scope-1", TagKey: "team"}, Peers: map[string]YAMLPeer{"dev": tt.peer}}
+		if err := ApplyIPAM(&cfg, tt.lookup); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
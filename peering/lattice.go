@@ -0,0 +1,186 @@
+package peering
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// -------------------------------------------------------------------------------------------------
+// VPC Lattice Connectivity
+// -------------------------------------------------------------------------------------------------
+
+// Connectivity modes of a matrix entry.
+const (
+	ConnectivityPeering = "peering" // VPC peering with routes (default).
+	ConnectivityLattice = "lattice" // Association of both VPCs with the VPC Lattice service network.
+)
+
+// Auth types of a service network the stack creates.
+const (
+	LatticeAuthNone   = "NONE"
+	LatticeAuthAWSIAM = "AWS_IAM"
+)
+
+// LatticeConfig names the VPC Lattice service network that connections with connectivity_mode:
+// lattice associate their VPCs with: an existing one, shared with the peer accounts through RAM, or
+// one the stack creates and shares itself.
+type LatticeConfig struct {
+	ServiceNetwork string `yaml:"service_network,omitempty"` // ARN (or ID, in the same account) of an existing service network.
+	Name           string `yaml:"name,omitempty"`            // Name of the service network to create when service_network is empty.
+	AuthType       string `yaml:"auth_type,omitempty"`       // NONE (default) or AWS_IAM, for a created service network.
+	Region         string `yaml:"region,omitempty"`          // Region of the service network (default region if empty).
+	RoleArn        string `yaml:"role_arn,omitempty"`        // Role of the account owning the service network (ambient credentials if empty).
+}
+
+// Validate requires exactly one of service_network and name, and a known auth type.
+func (c LatticeConfig) Validate() error {
+	switch {
+	case (c.ServiceNetwork == "") == (c.Name == ""):
+		return fmt.Errorf("lattice needs exactly one of service_network (existing) and name (created)")
+	case c.AuthType != "" && c.Name == "":
+		return fmt.Errorf("lattice.auth_type applies only to a service network the stack creates (name)")
+	case c.AuthType != "" && c.AuthType != LatticeAuthNone && c.AuthType != LatticeAuthAWSIAM:
+		return fmt.Errorf("unknown lattice.auth_type %q (want %s or %s)", c.AuthType, LatticeAuthNone, LatticeAuthAWSIAM)
+	}
+	return nil
+}
+
+// ValidateConnectivityMode rejects unknown modes, and lattice entries the config has no service
+// network for or that set options only peerings have.
+func ValidateConnectivityMode(cfg YAMLConfig, entry MatrixEntry) error {
+	switch entry.ConnectivityMode {
+	case "", ConnectivityPeering:
+		return nil
+	case ConnectivityLattice:
+	default:
+		return fmt.Errorf("unknown connectivity_mode %q (want %s or %s)", entry.ConnectivityMode, ConnectivityPeering, ConnectivityLattice)
+	}
+	if cfg.Lattice == nil {
+		return fmt.Errorf("connectivity_mode: lattice needs a top-level lattice block naming the service network")
+	}
+	if len(entry.DestinationCidrs) > 0 || len(entry.ExtraRoutes) > 0 || entry.PreferOver != "" || entry.SourceRoutes != nil ||
+		entry.PeerRoutes != nil || entry.Inspection != nil || entry.ManagePeering != nil || entry.PeeringID != "" || entry.Acceptance != "" || entry.Owner != "" {
+		return fmt.Errorf("connectivity_mode: lattice creates no peering or routes; remove the peering and routing options")
+	}
+	return nil
+}
+
+// LatticeAssociation is a VPC associated with the service network.
+type LatticeAssociation struct {
+	Peer    string // Logical name of the peer owning the VPC.
+	VpcID   string // VPC ID.
+	Region  string // Region of the VPC, which is the service network's.
+	RoleArn string // Role of the VPC's account.
+}
+
+// LatticeAssociations returns the VPCs the enabled lattice connections of the selected sources
+// associate with the service network, once per VPC, sorted by peer name. Every VPC must be in the
+// service network's region, since associations are regional.
+func LatticeAssociations(cfg YAMLConfig, sourceFilter string) ([]LatticeAssociation, error) {
+	byVpc := make(map[string]LatticeAssociation)
+	for source, targets := range cfg.PeeringMatrix {
+		if sourceFilter != "" && source != sourceFilter {
+			continue
+		}
+		for _, entry := range ExpandMatrixEntries(cfg, source, targets) {
+			if entry.ConnectivityMode != ConnectivityLattice || entry.Disabled() {
+				continue
+			}
+			if cfg.Lattice == nil {
+				return nil, fmt.Errorf("%q -> %q: connectivity_mode: lattice needs a top-level lattice block", source, entry.Peer)
+			}
+			for _, name := range []string{source, entry.Peer} {
+				peer := cfg.Peers[name]
+				if ResolveRegion(peer.Region) != ResolveRegion(cfg.Lattice.Region) {
+					return nil, fmt.Errorf("%q -> %q: peer %q is in %s, but the service network is in %s",
+						source, entry.Peer, name, ResolveRegion(peer.Region), ResolveRegion(cfg.Lattice.Region))
+				}
+				if prev, ok := byVpc[peer.VpcID]; !ok || name < prev.Peer {
+					byVpc[peer.VpcID] = LatticeAssociation{Peer: name, VpcID: peer.VpcID, Region: ResolveRegion(peer.Region), RoleArn: peer.RoleArn}
+				}
+			}
+		}
+	}
+	associations := make([]LatticeAssociation, 0, len(byVpc))
+	for _, a := range byVpc {
+		associations = append(associations, a)
+	}
+	sort.Slice(associations, func(i, j int) bool { return associations[i].Peer < associations[j].Peer })
+	return associations, nil
+}
+
+// LatticeShareAccounts returns the accounts a created service network is shared with: those of the
+// associated VPCs other than the network's own, sorted. VPCs whose role names no account are
+// associated with ambient credentials and need no share.
+func LatticeShareAccounts(cfg LatticeConfig, associations []LatticeAssociation) []string {
+	owner := GetAccountIDFromRoleArn(cfg.RoleArn)
+	accounts := make(map[string]bool)
+	for _, a := range associations {
+		if account := GetAccountIDFromRoleArn(a.RoleArn); account != "" && account != owner {
+			accounts[account] = true
+		}
+	}
+	return sortedKeys(accounts)
+}
+
+// AddLattice associates the VPCs with the service network, each through a provider in its own
+// account. A service network given by name is created first and shared through RAM with the
+// accounts of the associated VPCs; an existing one must already be shared with them.
+func AddLattice(stack cdktf.TerraformStack, cfg *LatticeConfig, associations []LatticeAssociation, settings ProviderSettings) {
+	if cfg == nil || len(associations) == 0 {
+		return
+	}
+	var identifier interface{} = cfg.ServiceNetwork
+	var dependsOn []cdktf.ITerraformDependable
+	if cfg.Name != "" {
+		provider := standaloneProvider(stack, "lattice_network", cfg.Region, cfg.RoleArn, settings)
+		network := cdktf.NewTerraformResource(stack, jsii.String("LatticeServiceNetwork"), &cdktf.TerraformResourceConfig{
+			TerraformResourceType: jsii.String("aws_vpclattice_service_network"),
+			Provider:              provider,
+		})
+		network.AddOverride(jsii.String("name"), cfg.Name)
+		if cfg.AuthType != "" {
+			network.AddOverride(jsii.String("auth_type"), cfg.AuthType)
+		}
+		identifier = network.GetStringAttribute(jsii.String("arn"))
+
+		if accounts := LatticeShareAccounts(*cfg, associations); len(accounts) > 0 {
+			share := cdktf.NewTerraformResource(stack, jsii.String("LatticeShare"), &cdktf.TerraformResourceConfig{
+				TerraformResourceType: jsii.String("aws_ram_resource_share"),
+				Provider:              provider,
+			})
+			share.AddOverride(jsii.String("name"), cfg.Name)
+			shared := cdktf.NewTerraformResource(stack, jsii.String("LatticeShareNetwork"), &cdktf.TerraformResourceConfig{
+				TerraformResourceType: jsii.String("aws_ram_resource_association"),
+				Provider:              provider,
+			})
+			shared.AddOverride(jsii.String("resource_arn"), network.GetStringAttribute(jsii.String("arn")))
+			shared.AddOverride(jsii.String("resource_share_arn"), share.GetStringAttribute(jsii.String("arn")))
+			dependsOn = append(dependsOn, shared)
+			for _, account := range accounts {
+				principal := cdktf.NewTerraformResource(stack, jsii.String("LatticeShare-"+account), &cdktf.TerraformResourceConfig{
+					TerraformResourceType: jsii.String("aws_ram_principal_association"),
+					Provider:              provider,
+				})
+				principal.AddOverride(jsii.String("principal"), account)
+				principal.AddOverride(jsii.String("resource_share_arn"), share.GetStringAttribute(jsii.String("arn")))
+				dependsOn = append(dependsOn, principal)
+			}
+		}
+	}
+
+	for _, a := range associations {
+		provider := standaloneProvider(stack, "lattice_"+a.Peer, a.Region, a.RoleArn, settings)
+		association := cdktf.NewTerraformResource(stack, jsii.String("LatticeAssociation-"+a.Peer), &cdktf.TerraformResourceConfig{
+			TerraformResourceType: jsii.String("aws_vpclattice_service_network_vpc_association"),
+			Provider:              provider,
+			DependsOn:             &dependsOn,
+		})
+		association.AddOverride(jsii.String("vpc_identifier"), a.VpcID)
+		association.AddOverride(jsii.String("service_network_identifier"), identifier)
+		association.AddOverride(jsii.String("tags"), map[string]string{"Name": "lattice-" + a.Peer, "Peer": a.Peer})
+	}
+}
@@ -0,0 +1,100 @@
+package peering
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// latticeConfig returns a config connecting dev and prod through the service network, and peering
+// dev with shared.
+func latticeConfig() YAMLConfig {
+	return YAMLConfig{
+		Peers: map[string]YAMLPeer{
+			"dev":    {VpcID: "vpc-dev", Region: "us-east-1", RoleArn: "arn:aws:iam::111111111111:role/peering", Cidr: "10.0.0.0/16"},
+			"prod":   {VpcID: "vpc-prod", Region: "us-east-1", RoleArn: "arn:aws:iam::222222222222:role/peering", Cidr: "10.0.0.0/16"},
+			"shared": {VpcID: "vpc-shared", Region: "us-east-1", RoleArn: "arn:aws:iam::111111111111:role/peering", Cidr: "10.1.0.0/16"},
+		},
+		PeeringMatrix: map[string][]MatrixEntry{
+			"dev":  {{Peer: "prod", ConnectivityMode: ConnectivityLattice}, {Peer: "shared"}},
+			"prod": {{Peer: "dev", ConnectivityMode: ConnectivityLattice}},
+		},
+		Lattice: &LatticeConfig{Name: "internal", Region: "us-east-1", RoleArn: "arn:aws:iam::111111111111:role/network"},
+	}
+}
+
+// TestLatticeConnections tests that lattice entries are kept out of the peerings and associate each
+// VPC once.
+func TestLatticeConnections(t *testing.T) {
+	cfg := latticeConfig()
+	peers := ConvertToPeerConfigs(cfg, "")
+	if len(peers) != 1 || peers[0].Name != "shared" {
+		t.Fatalf("expected only the dev/shared peering, got %+v", peers)
+	}
+
+	associations, err := LatticeAssociations(cfg, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []LatticeAssociation{
+		{Peer: "dev", VpcID: "vpc-dev", Region: "us-east-1", RoleArn: "arn:aws:iam::111111111111:role/peering"},
+		{Peer: "prod", VpcID: "vpc-prod", Region: "us-east-1", RoleArn: "arn:aws:iam::222222222222:role/peering"},
+	}
+	if !reflect.DeepEqual(associations, want) {
+		t.Errorf("expected %+v, got %+v", want, associations)
+	}
+	if got := LatticeShareAccounts(*cfg.Lattice, associations); !reflect.DeepEqual(got, []string{"222222222222"}) {
+		t.Errorf("expected the share with 222222222222 only, got %v", got)
+	}
+
+	cfg.Lattice.Region = "eu-west-1"
+	if _, err := LatticeAssociations(cfg, "dev"); err == nil || !strings.Contains(err.Error(), "service network is in eu-west-1") {
+		t.Errorf("expected a region mismatch, got %v", err)
+	}
+}
+
+// TestValidateConnectivityMode tests the checks on matrix entries choosing a connectivity mode.
+func TestValidateConnectivityMode(t *testing.T) {
+	cfg := latticeConfig()
+	tests := []struct {
+		name    string
+		entry   MatrixEntry
+		wantErr bool
+	}{
+		{"default", MatrixEntry{Peer: "prod"}, false},
+		{"lattice", MatrixEntry{Peer: "prod", ConnectivityMode: ConnectivityLattice}, false},
+		{"unknown", MatrixEntry{Peer: "prod", ConnectivityMode: "tgw"}, true},
+		{"routing options", MatrixEntry{Peer: "prod", ConnectivityMode: ConnectivityLattice, DestinationCidrs: []string{"10.0.0.0/24"}}, true},
+	}
+	for _, tt := range tests {
+		if err := ValidateConnectivityMode(cfg, tt.entry); (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+
+	cfg.Lattice = nil
+	if err := ValidateConnectivityMode(cfg, MatrixEntry{Peer: "prod", ConnectivityMode: ConnectivityLattice}); err == nil {
+		t.Error("expected an error without a lattice block")
+	}
+}
+
+// TestLatticeConfigValidate tests the service network settings checks.
+func TestLatticeConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     LatticeConfig
+		wantErr bool
+	}{
+		{"existing", LatticeConfig{ServiceNetwork: "sn-0123456789abcdef0"}, false},
+		{"created", LatticeConfig{Name: "internal", AuthType: LatticeAuthAWSIAM}, false},
+		{"neither", LatticeConfig{}, true},
+		{"both", LatticeConfig{ServiceNetwork: "sn-0123456789abcdef0", Name: "internal"}, true},
+		{"auth on existing", LatticeConfig{ServiceNetwork: "sn-0123456789abcdef0", AuthType: LatticeAuthNone}, true},
+		{"unknown auth", LatticeConfig{Name: "internal", AuthType: "OIDC"}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package peering_test
+
+import (
+	"testing"
+
+	"cdk.tf/go/stack/peering"
+)
+
+// TestLibraryUse tests building connections through the package's exported API, the way another
+// module importing it does.
+func TestLibraryUse(t *testing.T) {
+	cfg, err := peering.FromYAML([]byte(`
+peers:
+  dev-peer:
+    vpc_id: vpc-0aaa1111aaa1111aa
+    region: us-east-1
+    role_arn: arn:aws:iam::111111111111:role/vpc-peering
+  prod-peer:
+    vpc_id: vpc-0bbb2222bbb2222bb
+    region: us-west-2
+    role_arn: arn:aws:iam::222222222222:role/vpc-peering
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peer, err := peering.NewConnection("dev-peer", "prod-peer").
+		WithDNS().
+		WithExtraRoutes(peering.ExtraRoute{Cidr: "100.64.0.0/16"}).
+		Build(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key := peering.ConnectionKey(peer); key != "dev-peer/prod-peer" || !peer.EnableDNSResolution {
+		t.Errorf("unexpected connection %s: %+v", key, peer)
+	}
+	if peer.PeerVpcID != "vpc-0bbb2222bbb2222bb" || len(peer.SourceExtraCidrs) != 1 {
+		t.Errorf("expected the peer VPC and the extra route, got %+v", peer)
+	}
+
+	if err := peering.NewConnection("dev-peer", "prod-peer").AddTo(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if peers := peering.ConvertToPeerConfigs(cfg, "dev-peer"); len(peers) != 1 {
+		t.Errorf("expected the added connection to convert, got %+v", peers)
+	}
+	if opts := peering.StackOptionsFor(cfg); opts.Namer == nil {
+		t.Error("expected stack options with a namer")
+	}
+}
@@ -0,0 +1,130 @@
+package peering
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Per-Connection Lifecycle
+// -------------------------------------------------------------------------------------------------
+
+// LifecycleIgnoreAll makes Terraform ignore every attribute of a resource after creating it.
+const LifecycleIgnoreAll = "all"
+
+// LifecycleConfig passes lifecycle ignore_changes through to the routes and the peering of one
+// connection, so an emergency manual edit is not reverted by the next apply until operators remove
+// it again.
+type LifecycleConfig struct {
+	IgnoreChanges        []string `yaml:"ignore_changes,omitempty"`         // Route attributes Terraform leaves alone, or all.
+	PeeringIgnoreChanges []string `yaml:"peering_ignore_changes,omitempty"` // Peering and accepter attributes Terraform leaves alone, or all.
+}
+
+// Attributes each lifecycle list may name. Terraform rejects ignore_changes entries a resource type
+// does not have, so they are checked at synth rather than at plan.
+var (
+	routeLifecycleAttrs   = map[string]bool{"destination_cidr_block": true, "route_table_id": true, "vpc_peering_connection_id": true}
+	peeringLifecycleAttrs = map[string]bool{"auto_accept": true, "tags": true}
+)
+
+// tableLifecycleAttrs lists, per dedicated route table resource type, the route attributes it shares.
+// A route list applies to those resources through them only.
+var tableLifecycleAttrs = map[string]map[string]bool{
+	"aws_route_table":             {},
+	"aws_route_table_association": {"route_table_id": true},
+}
+
+// describe names what ignores changes, e.g. "routes (destination_cidr_block)", or "" for nothing.
+func (c LifecycleConfig) describe() string {
+	var parts []string
+	if len(c.IgnoreChanges) > 0 {
+		parts = append(parts, "routes ("+strings.Join(c.IgnoreChanges, ", ")+")")
+	}
+	if len(c.PeeringIgnoreChanges) > 0 {
+		parts = append(parts, "the peering ("+strings.Join(c.PeeringIgnoreChanges, ", ")+")")
+	}
+	return strings.Join(parts, " and ")
+}
+
+// Validate checks both lists against the attributes of the resources they apply to.
+func (c LifecycleConfig) Validate() error {
+	for _, list := range []struct {
+		field   string
+		attrs   []string
+		allowed map[string]bool
+	}{
+		{"ignore_changes", c.IgnoreChanges, routeLifecycleAttrs},
+		{"peering_ignore_changes", c.PeeringIgnoreChanges, peeringLifecycleAttrs},
+	} {
+		for _, attr := range list.attrs {
+			if attr == LifecycleIgnoreAll {
+				if len(list.attrs) > 1 {
+					return fmt.Errorf("lifecycle.%s lists %s with other attributes", list.field, LifecycleIgnoreAll)
+				}
+				continue
+			}
+			if !list.allowed[attr] {
+				return fmt.Errorf("lifecycle.%s names unknown attribute %q (want %s or %s)",
+					list.field, attr, strings.Join(sortedKeys(list.allowed), ", "), LifecycleIgnoreAll)
+			}
+		}
+	}
+	return nil
+}
+
+// ignoreChangesValue returns the ignore_changes value of a list: the all keyword alone, or the
+// attribute names.
+func ignoreChangesValue(attrs []string) interface{} {
+	if len(attrs) == 1 && attrs[0] == LifecycleIgnoreAll {
+		return LifecycleIgnoreAll
+	}
+	return attrs
+}
+
+// tableIgnoreChanges returns the ignore_changes value a route list gives a dedicated route table
+// resource: all for all, else the attributes the resource shares with routes, or nil for none.
+func tableIgnoreChanges(resourceType string, attrs []string) interface{} {
+	if len(attrs) == 1 && attrs[0] == LifecycleIgnoreAll {
+		return LifecycleIgnoreAll
+	}
+	var shared []string
+	for _, attr := range attrs {
+		if tableLifecycleAttrs[resourceType][attr] {
+			shared = append(shared, attr)
+		}
+	}
+	if len(shared) == 0 {
+		return nil
+	}
+	return shared
+}
+
+// ApplyLifecycle sets lifecycle ignore_changes on the routes and dedicated route tables of both sides
+// and on the peering and its accepter of every connection that configures it.
+func ApplyLifecycle(connections []ConnectionResources) {
+	for _, c := range connections {
+		lc := c.Peer.Lifecycle
+		if len(lc.IgnoreChanges) > 0 {
+			for _, side := range []SideResources{c.Routes.Source, c.Routes.Peer} {
+				for _, route := range side.Routes {
+					route.AddOverride(jsii.String("lifecycle.ignore_changes"), ignoreChangesValue(lc.IgnoreChanges))
+				}
+				for _, table := range side.Tables {
+					if value := tableIgnoreChanges(*table.TerraformResourceType(), lc.IgnoreChanges); value != nil {
+						table.AddOverride(jsii.String("lifecycle.ignore_changes"), value)
+					}
+				}
+			}
+		}
+		if len(lc.PeeringIgnoreChanges) > 0 {
+			if c.Peering.Peering != nil {
+				c.Peering.Peering.AddOverride(jsii.String("lifecycle.ignore_changes"), ignoreChangesValue(lc.PeeringIgnoreChanges))
+			}
+			if c.Peering.Accepter != nil {
+				c.Peering.Accepter.AddOverride(jsii.String("lifecycle.ignore_changes"), ignoreChangesValue(lc.PeeringIgnoreChanges))
+			}
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package peering
+
+import "testing"
+
+// TestLifecycleConfigValidate tests the attributes each ignore_changes list accepts.
+func TestLifecycleConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     LifecycleConfig
+		wantErr bool
+	}{
+		{"empty", LifecycleConfig{}, false},
+		{"route attribute", LifecycleConfig{IgnoreChanges: []string{"destination_cidr_block"}}, false},
+		{"all", LifecycleConfig{IgnoreChanges: []string{"all"}, PeeringIgnoreChanges: []string{"all"}}, false},
+		{"peering tags", LifecycleConfig{PeeringIgnoreChanges: []string{"tags", "auto_accept"}}, false},
+		{"all with others", LifecycleConfig{IgnoreChanges: []string{"all", "route_table_id"}}, true},
+		{"route attribute on peering", LifecycleConfig{PeeringIgnoreChanges: []string{"destination_cidr_block"}}, true},
+		{"tags on routes", LifecycleConfig{IgnoreChanges: []string{"tags"}}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+// TestLifecycleDescribe tests the summary logged for a connection ignoring changes.
+func TestLifecycleDescribe(t *testing.T) {
+	cfg := LifecycleConfig{IgnoreChanges: []string{"destination_cidr_block"}, PeeringIgnoreChanges: []string{"tags"}}
+	if got, want := cfg.describe(), "routes (destination_cidr_block) and the peering (tags)"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if got := (LifecycleConfig{}).describe(); got != "" {
+		t.Errorf("expected nothing, got %q", got)
+	}
+}
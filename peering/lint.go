@@ -0,0 +1,327 @@
+package peering
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Config Linting
+// -------------------------------------------------------------------------------------------------
+
+// Diagnostic severities, most severe first.
+const (
+	SeverityError   = "error"   // Synth fails or AWS rejects the connection.
+	SeverityWarning = "warning" // Likely a mistake, but synth succeeds.
+	SeverityInfo    = "info"    // Worth knowing; no action needed.
+)
+
+// severityRank orders severities for sorting.
+var severityRank = map[string]int{SeverityError: 0, SeverityWarning: 1, SeverityInfo: 2}
+
+// Diagnostic is a single lint finding.
+type Diagnostic struct {
+	Severity string `json:"severity"` // One of the Severity constants.
+	Rule     string `json:"rule"`     // Name of the rule that produced it.
+	Subject  string `json:"subject"`  // Peer name or connection key it is about.
+	Message  string `json:"message"`  // Human-readable explanation.
+}
+
+// LintRule checks one aspect of a config.
+type LintRule struct {
+	Name  string                            // Rule name shown with each diagnostic.
+	Check func(cfg YAMLConfig) []Diagnostic // Returns the rule's findings; must not modify cfg.
+}
+
+// LintRules returns every built-in rule.
+func LintRules() []LintRule {
+	return []LintRule{
+		{Name: "invalid-connection", Check: lintConnections},
+		{Name: "duplicate-vpc", Check: lintDuplicateVpcs},
+		{Name: "unused-peer", Check: lintUnusedPeers},
+		{Name: "unknown-region", Check: lintRegions},
+		{Name: "invalid-role-arn", Check: lintRoleArns},
+		{Name: "resource-budget", Check: lintResourceBudgets},
+		{Name: "construct-id-collision", Check: lintConstructIDs},
+		{Name: "cross-region-dns", Check: lintCrossRegionDNS},
+	}
+}
+
+// Lint runs the rules concurrently and returns their diagnostics, most severe first.
+func Lint(cfg YAMLConfig, rules []LintRule) []Diagnostic {
+	results := make([][]Diagnostic, len(rules))
+	var wg sync.WaitGroup
+	for i, rule := range rules {
+		wg.Add(1)
+		go func(i int, rule LintRule) {
+			defer wg.Done()
+			for _, d := range rule.Check(cfg) {
+				d.Rule = rule.Name
+				results[i] = append(results[i], d)
+			}
+		}(i, rule)
+	}
+	wg.Wait()
+
+	var diagnostics []Diagnostic
+	for _, r := range results {
+		diagnostics = append(diagnostics, r...)
+	}
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i], diagnostics[j]
+		if severityRank[a.Severity] != severityRank[b.Severity] {
+			return severityRank[a.Severity] < severityRank[b.Severity]
+		}
+		if a.Subject != b.Subject {
+			return a.Subject < b.Subject
+		}
+		return a.Rule < b.Rule
+	})
+	return diagnostics
+}
+
+// sortedPeerNames returns the peer names in order.
+func sortedPeerNames(cfg YAMLConfig) []string {
+	names := make([]string, 0, len(cfg.Peers))
+	for name := range cfg.Peers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lintConnections reports every matrix entry that would fail synth, and notes disabled entries,
+// which are validated all the same.
+func lintConnections(cfg YAMLConfig) []Diagnostic {
+	var out []Diagnostic
+	for _, row := range BuildMatrixRows(cfg) {
+		switch {
+		case row.Err != nil:
+			out = append(out, Diagnostic{Severity: SeverityError, Subject: row.Source + "/" + row.Peer, Message: row.Err.Error()})
+		case row.Disabled:
+			out = append(out, Diagnostic{Severity: SeverityInfo, Subject: row.Source + "/" + row.Peer, Message: "disabled (enabled: false); not synthesized"})
+		}
+	}
+	return out
+}
+
+// lintDuplicateVpcs reports VPC IDs registered under several peer names. Peering a VPC with itself
+// is already an invalid connection.
+func lintDuplicateVpcs(cfg YAMLConfig) []Diagnostic {
+	var out []Diagnostic
+	for _, dup := range DuplicateVpcIDs(cfg) {
+		out = append(out, Diagnostic{Severity: SeverityWarning, Subject: strings.Join(dup.Peers, ", "),
+			Message: fmt.Sprintf("VPC %s is registered under several peer names", dup.VpcID)})
+	}
+	return out
+}
+
+// lintUnusedPeers reports peers that no matrix entry uses as source or target.
+func lintUnusedPeers(cfg YAMLConfig) []Diagnostic {
+	used := make(map[string]bool)
+	for source, entries := range cfg.PeeringMatrix {
+		used[source] = true
+		for _, entry := range ExpandMatrixEntries(cfg, source, entries) {
+			used[entry.Peer] = true
+		}
+	}
+	var out []Diagnostic
+	for _, name := range sortedPeerNames(cfg) {
+		if !used[name] {
+			out = append(out, Diagnostic{Severity: SeverityWarning, Subject: name, Message: "peer is never referenced in peering_matrix"})
+		}
+	}
+	return out
+}
+
+// lintRegions reports malformed regions and regions this release does not know, unless allowed, as
+// errors with the closest known name. Unset regions use the default.
+func lintRegions(cfg YAMLConfig) []Diagnostic {
+	var out []Diagnostic
+	for _, name := range sortedPeerNames(cfg) {
+		if err := ValidateRegion(cfg.Peers[name].Region, cfg.AllowedRegions); err != nil {
+			out = append(out, Diagnostic{Severity: SeverityError, Subject: name, Message: err.Error()})
+		}
+	}
+	return out
+}
+
+// CrossRegionDNSWarning returns the caveats of enabling DNS resolution on an inter-region peering,
+// or "" for intra-region peerings, peerings without DNS resolution, and acknowledged ones.
+func CrossRegionDNSWarning(peer PeerConfig) string {
+	sourceRegion, peerRegion := ResolveRegion(peer.SourceRegion), ResolveRegion(peer.PeerRegion)
+	if !peer.EnableDNSResolution || sourceRegion == peerRegion || peer.CrossRegionDNSAcked {
+		return ""
+	}
+	return fmt.Sprintf("dns_resolution across regions (%s to %s) only resolves the other VPC's public EC2 and "+
+		"endpoint hostnames to private IPs; private hosted zones still need associating with both VPCs, and "+
+		"security groups cannot reference groups across the peering (set acknowledge_cross_region_dns to silence)",
+		sourceRegion, peerRegion)
+}
+
+// lintCrossRegionDNS warns about inter-region connections that enable DNS resolution.
+func lintCrossRegionDNS(cfg YAMLConfig) []Diagnostic {
+	var out []Diagnostic
+	for _, row := range BuildMatrixRows(cfg) {
+		if row.Err != nil || row.Disabled {
+			continue
+		}
+		if msg := CrossRegionDNSWarning(row.Config); msg != "" {
+			out = append(out, Diagnostic{Severity: SeverityWarning, Subject: row.Source + "/" + row.Peer, Message: msg})
+		}
+	}
+	return out
+}
+
+// lintRoleArns reports role ARNs that are not valid IAM role ARNs, valid ones in another partition
+// than the peer's region, and valid ones whose account the tool cannot derive.
+func lintRoleArns(cfg YAMLConfig) []Diagnostic {
+	var out []Diagnostic
+	for _, name := range sortedPeerNames(cfg) {
+		arn := cfg.Peers[name].RoleArn
+		invalid := ValidateRoleArn(arn)
+		switch {
+		case arn == "":
+			out = append(out, Diagnostic{Severity: SeverityWarning, Subject: name, Message: "no role_arn; the ambient credentials are used"})
+		case invalid != nil:
+			out = append(out, Diagnostic{Severity: SeverityError, Subject: name, Message: invalid.Error()})
+		case SidePartition(arn, "") != RegionPartition(ResolveRegion(cfg.Peers[name].Region)):
+			region := ResolveRegion(cfg.Peers[name].Region)
+			out = append(out, Diagnostic{Severity: SeverityError, Subject: name, Message: fmt.Sprintf(
+				"role ARN is in partition %s but region %s is in %s", SidePartition(arn, ""), region, RegionPartition(region))})
+		case GetAccountIDFromRoleArn(arn) == "" && !cfg.ResolveAccountIDs:
+			out = append(out, Diagnostic{Severity: SeverityWarning, Subject: name,
+				Message: "the account of this role ARN cannot be derived; set resolve_account_ids"})
+		}
+	}
+	return out
+}
+
+// PrintDiagnostics writes one line per diagnostic followed by a count per severity.
+func PrintDiagnostics(w io.Writer, diagnostics []Diagnostic) {
+	counts := make(map[string]int)
+	for _, d := range diagnostics {
+		counts[d.Severity]++
+		fmt.Fprintf(w, "%-7s  %-18s  %s: %s\n", d.Severity, d.Rule, d.Subject, d.Message)
+	}
+	fmt.Fprintf(w, "%d errors, %d warnings, %d info\n", counts[SeverityError], counts[SeverityWarning], counts[SeverityInfo])
+}
+
+// -------------------------------------------------------------------------------------------------
+// lint
+// -------------------------------------------------------------------------------------------------
+
+// runLint lints the config and fails when any error is found.
+func runLint(args []string) error {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	path := fs.String("f", "", "config file to lint (default: --config, $CDKTF_PEERING_CONFIG, or the search paths)")
+	format := fs.String("format", "text", "output format: text or json")
+	lookup := fs.Bool("lookup", false, "look up the CIDRs of VPCs without declared cidrs in AWS and check routes against them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q (use text or json)", *format)
+	}
+	if *path == "" {
+		*path = ConfigPath()
+	}
+	cfg := LoadConfig(*path)
+
+	rules := LintRules()
+	if *lookup {
+		rules = append(rules, lookupCidrsRule(cfg.Provider))
+	}
+	var diagnostics []Diagnostic
+	quietLogs(func() { diagnostics = Lint(cfg, rules) })
+
+	if *format == "json" {
+		data, err := json.MarshalIndent(diagnostics, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	} else {
+		PrintDiagnostics(os.Stdout, diagnostics)
+	}
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			return WithExitCode(ExitValidation, fmt.Errorf("config has errors"))
+		}
+	}
+	return nil
+}
+
+// lookupCidrsRule returns the local-cidr-conflict rule looking up CIDRs with the AWS CLI, with one
+// client per region and role.
+func lookupCidrsRule(settings ProviderSettings) LintRule {
+	clients := make(map[string]CidrLookup)
+	return lintLocalCidrs(func(region, roleArn string) (CidrLookup, error) {
+		key := region + "|" + roleArn
+		if c, ok := clients[key]; ok {
+			return c, nil
+		}
+		c, err := NewAWSCLI(region, roleArn, settings)
+		if err != nil {
+			return nil, err
+		}
+		clients[key] = c
+		return c, nil
+	})
+}
+
+// lintConstructIDs reports construct IDs that two resources would share in the stack of a source,
+// which would fail synth. Configs with an invalid naming pattern are left to synth.
+func lintConstructIDs(cfg YAMLConfig) []Diagnostic {
+	if cfg.Naming.Pattern != "" && ValidateNamingPattern(cfg.Naming.Pattern) != nil {
+		return nil
+	}
+	namer := NewNamer(cfg.Naming)
+	bySource := make(map[string][]PeerConfig)
+	var sources []string
+	for _, row := range BuildMatrixRows(cfg) {
+		if row.Err != nil || row.Disabled || row.Config.Lattice {
+			continue
+		}
+		if _, ok := bySource[row.Source]; !ok {
+			sources = append(sources, row.Source)
+		}
+		bySource[row.Source] = append(bySource[row.Source], row.Config)
+	}
+	sort.Strings(sources)
+	var out []Diagnostic
+	for _, source := range sources {
+		for _, err := range CheckConstructIDs(namer, bySource[source]) {
+			out = append(out, Diagnostic{Severity: SeverityError, Subject: source, Message: err.Error()})
+		}
+	}
+	return out
+}
+
+// lintResourceBudgets reports VPCs whose peerings or peering routes approach or exceed the AWS
+// quotas, across every source.
+func lintResourceBudgets(cfg YAMLConfig) []Diagnostic {
+	peers, err := peeringConnections(cfg)
+	if err != nil {
+		return []Diagnostic{{Severity: SeverityError, Subject: "peering_matrix", Message: err.Error()}}
+	}
+	return ResourceBudgets(peers)
+}
+
+// peeringConnections returns the valid, enabled peering connections of every source, with
+// connections both sources list merged.
+func peeringConnections(cfg YAMLConfig) ([]PeerConfig, error) {
+	var peers []PeerConfig
+	for _, row := range BuildMatrixRows(cfg) {
+		if row.Err == nil && !row.Disabled && !row.Config.Lattice {
+			peers = append(peers, row.Config)
+		}
+	}
+	return MergeDuplicatePairs(peers)
+}
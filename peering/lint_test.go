@@ -0,0 +1,90 @@
+package peering
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+// TestLint tests that each rule reports its finding with the right severity.
+func TestLint(t *testing.T) {
+	data := `
+peers:
+  dev:
+    vpc_id: vpc-1
+    region: us-east-1
+    role_arn: arn:aws:iam::111111111111:role/peering
+  prod:
+    vpc_id: vpc-2
+    region: us-west-2
+    role_arn: arn:aws:iam::222222222222:role/peering
+    dns_resolution: true
+  prod-alias:
+    vpc_id: vpc-2
+    region: us-west-2
+    role_arn: arn:aws:iam::222222222222:role/peering
+  mars:
+    vpc_id: vpc-3
+    region: mars-north-1
+    role_arn: not-an-arn
+  gov:
+    vpc_id: vpc-4
+    region: us-east-1
+    role_arn: arn:aws-us-gov:iam::333333333333:role/peering
+peering_matrix:
+  dev:
+    - prod
+    - dev
+    - missing
+    - gov
+  prod:
+    - prod-alias
+`
+	var cfg YAMLConfig
+	if err := yaml.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	diagnostics := Lint(cfg, LintRules())
+
+	want := []Diagnostic{
+		{SeverityError, "invalid-connection", "dev/dev", `"dev" -> "dev" would peer VPC vpc-1 with itself`},
+		{SeverityError, "invalid-connection", "dev/gov", `"dev" -> "gov" spans partitions aws and aws-us-gov: AWS supports neither VPC peering nor transit gateway peering across partitions; connect them with a VPN or Direct Connect instead`},
+		{SeverityError, "invalid-connection", "dev/missing", `missing peer config for "missing"`},
+		{SeverityError, "invalid-role-arn", "gov", "role ARN is in partition aws-us-gov but region us-east-1 is in aws"},
+		{SeverityError, "invalid-role-arn", "mars", `"not-an-arn" is not an ARN (want arn:aws:iam::<account>:role/<name>); is it truncated?`},
+		{SeverityError, "unknown-region", "mars", `unknown region "mars-north-1" (list it in allowed_regions if AWS launched it after this release)`},
+		{SeverityError, "invalid-connection", "prod/prod-alias", `"prod" -> "prod-alias" would peer VPC vpc-2 with itself`},
+		{SeverityWarning, "cross-region-dns", "dev/prod", "dns_resolution across regions (us-east-1 to us-west-2) only resolves the other VPC's public EC2 and " +
+			"endpoint hostnames to private IPs; private hosted zones still need associating with both VPCs, and " +
+			"security groups cannot reference groups across the peering (set acknowledge_cross_region_dns to silence)"},
+		{SeverityWarning, "unused-peer", "mars", "peer is never referenced in peering_matrix"},
+		{SeverityWarning, "duplicate-vpc", "prod, prod-alias", "VPC vpc-2 is registered under several peer names"},
+	}
+	if len(diagnostics) != len(want) {
+		t.Fatalf("expected %d diagnostics, got %d: %+v", len(want), len(diagnostics), diagnostics)
+	}
+	for i := range want {
+		if diagnostics[i] != want[i] {
+			t.Errorf("diagnostic %d = %+v, want %+v", i, diagnostics[i], want[i])
+		}
+	}
+}
+
+// TestCrossRegionDNSWarning tests when DNS resolution across a peering is warned about.
+func TestCrossRegionDNSWarning(t *testing.T) {
+	tests := []struct {
+		name string
+		peer PeerConfig
+		want bool
+	}{
+		{"cross-region", PeerConfig{SourceRegion: "us-east-1", PeerRegion: "eu-west-1", EnableDNSResolution: true}, true},
+		{"same region", PeerConfig{SourceRegion: "us-east-1", PeerRegion: "us-east-1", EnableDNSResolution: true}, false},
+		{"no DNS", PeerConfig{SourceRegion: "us-east-1", PeerRegion: "eu-west-1"}, false},
+		{"acknowledged", PeerConfig{SourceRegion: "us-east-1", PeerRegion: "eu-west-1", EnableDNSResolution: true, CrossRegionDNSAcked: true}, false},
+	}
+	for _, tt := range tests {
+		if got := CrossRegionDNSWarning(tt.peer) != ""; got != tt.want {
+			t.Errorf("%s: expected warning %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package peering
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Orchestrator Manifests
+// -------------------------------------------------------------------------------------------------
+
+// DefaultSourcesOutdir is where per-source synths are written for orchestrators, one
+// CDKTF_OUTDIR per source.
+const DefaultSourcesOutdir = "cdktf.out/sources"
+
+// SourceStack is the synthesized stack of one source.
+type SourceStack struct {
+	Source string // Source peer, synthesized with CDKTF_SOURCE.
+	Outdir string // CDKTF_OUTDIR of the source's synth.
+	Dir    string // Stack directory inside Outdir, where Terraform runs.
+}
+
+// SourceStacks returns the stack of every source with connections, sorted by source, each
+// synthesized into its own directory under base.
+func SourceStacks(peers []PeerConfig, base string) []SourceStack {
+	seen := make(map[string]bool)
+	var stacks []SourceStack
+	for _, peer := range peers {
+		if seen[peer.SourceName] {
+			continue
+		}
+		seen[peer.SourceName] = true
+		outdir := filepath.ToSlash(filepath.Join(base, peer.SourceName))
+		stacks = append(stacks, SourceStack{
+			Source: peer.SourceName,
+			Outdir: outdir,
+			Dir:    filepath.ToSlash(filepath.Join(outdir, "stacks", StackName)),
+		})
+	}
+	sort.Slice(stacks, func(i, j int) bool { return stacks[i].Source < stacks[j].Source })
+	return stacks
+}
+
+// AtlantisConfig is a repo-level atlantis.yaml.
+type AtlantisConfig struct {
+	Version  int               `yaml:"version"`
+	Projects []AtlantisProject `yaml:"projects"`
+}
+
+// AtlantisProject is one project of an atlantis.yaml.
+type AtlantisProject struct {
+	Name     string           `yaml:"name"`
+	Dir      string           `yaml:"dir"`
+	Workflow string           `yaml:"workflow,omitempty"`
+	Autoplan AtlantisAutoplan `yaml:"autoplan"`
+}
+
+// AtlantisAutoplan sets when Atlantis plans a project on its own.
+type AtlantisAutoplan struct {
+	Enabled      bool     `yaml:"enabled"`
+	WhenModified []string `yaml:"when_modified"`
+}
+
+// AtlantisProjects returns a project per source stack. Besides the synthesized files, a change to
+// the config plans every project; when_modified paths are relative to the project directory.
+func AtlantisProjects(stacks []SourceStack, configPath, workflow string) AtlantisConfig {
+	cfg := AtlantisConfig{Version: 3, Projects: []AtlantisProject{}}
+	for _, s := range stacks {
+		watch := []string{"*.tf*"}
+		if rel, err := relPath(s.Dir, configPath); err == nil && !IsRemoteConfig(configPath) {
+			watch = append(watch, filepath.ToSlash(rel))
+		}
+		cfg.Projects = append(cfg.Projects, AtlantisProject{
+			Name:     s.Source,
+			Dir:      s.Dir,
+			Workflow: workflow,
+			Autoplan: AtlantisAutoplan{Enabled: true, WhenModified: watch},
+		})
+	}
+	return cfg
+}
+
+// relPath returns target relative to dir, resolving both against the working directory first so
+// relative and absolute paths mix.
+func relPath(dir, target string) (string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Rel(absDir, absTarget)
+}
+
+// SpaceliftConfig is a .spacelift/config.yml runtime configuration.
+type SpaceliftConfig struct {
+	Version string                    `yaml:"version"`
+	Stacks  map[string]SpaceliftStack `yaml:"stacks"`
+}
+
+// SpaceliftStack is the runtime configuration of one Spacelift stack.
+type SpaceliftStack struct {
+	ProjectRoot string            `yaml:"project_root"`
+	Environment map[string]string `yaml:"environment"`
+}
+
+// invalidSlugChars matches characters Spacelift does not allow in stack IDs.
+var invalidSlugChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// SpaceliftStackID returns the stack ID of a source: the prefix and source, lowercased, with other
+// characters replaced by dashes.
+func SpaceliftStackID(prefix, source string) string {
+	return strings.Trim(invalidSlugChars.ReplaceAllString(strings.ToLower(prefix+source), "-"), "-")
+}
+
+// SpaceliftStacks returns a stack per source, keyed by stack ID, whose environment selects the
+// source and output directory for synth hooks.
+func SpaceliftStacks(stacks []SourceStack, prefix string) (SpaceliftConfig, error) {
+	cfg := SpaceliftConfig{Version: "1", Stacks: make(map[string]SpaceliftStack, len(stacks))}
+	owners := make(map[string]string)
+	for _, s := range stacks {
+		id := SpaceliftStackID(prefix, s.Source)
+		if other, ok := owners[id]; ok {
+			return cfg, fmt.Errorf("sources %s and %s map to the same Spacelift stack ID %s", other, s.Source, id)
+		}
+		owners[id] = s.Source
+		cfg.Stacks[id] = SpaceliftStack{
+			ProjectRoot: s.Dir,
+			Environment: map[string]string{"CDKTF_SOURCE": s.Source, "CDKTF_OUTDIR": s.Outdir},
+		}
+	}
+	return cfg, nil
+}
+
+// writeYAML marshals a document to w.
+func writeYAML(w io.Writer, doc interface{}) error {
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
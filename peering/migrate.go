@@ -0,0 +1,195 @@
+package peering
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Config Versions
+// -------------------------------------------------------------------------------------------------
+
+// CurrentConfigVersion is the schema version produced by MigrateConfig.
+//
+//	1 - implicit (no version field); has_additional_routes and the top-level dns_resolution and
+//	    additional_routes maps.
+//	2 - routing is explicit through routes, source_routes, and peer_routes.
+//	3 - filtered subnet routes are keyed by route table unless subnet_route_keys is subnet.
+const CurrentConfigVersion = 3
+
+// configMigrations upgrades a config from the keyed version to the next one.
+var configMigrations = map[int]func(*YAMLConfig) error{
+	1: migrateV1ToV2,
+	2: migrateV2ToV3,
+}
+
+// MigrateConfig upgrades cfg in place to CurrentConfigVersion and returns the version it started
+// from. Configs newer than this tool understands are rejected.
+func MigrateConfig(cfg *YAMLConfig) (int, error) {
+	if cfg.Version == 0 {
+		cfg.Version = 1
+	}
+	from := cfg.Version
+	if from > CurrentConfigVersion {
+		return from, fmt.Errorf("config version %d is newer than the supported version %d", from, CurrentConfigVersion)
+	}
+	if from == CurrentConfigVersion {
+		return from, validateCurrentConfig(*cfg)
+	}
+
+	for cfg.Version < CurrentConfigVersion {
+		if err := configMigrations[cfg.Version](cfg); err != nil {
+			return from, fmt.Errorf("version %d to %d: %w", cfg.Version, cfg.Version+1, err)
+		}
+		cfg.Version++
+	}
+	return from, validateCurrentConfig(*cfg)
+}
+
+// validateCurrentConfig rejects settings that only older schema versions accept.
+func validateCurrentConfig(cfg YAMLConfig) error {
+	for name, peer := range cfg.Peers {
+		if peer.HasAdditionalRoutes {
+			return fmt.Errorf("peer %q: has_additional_routes is not supported in version %d; use routes", name, CurrentConfigVersion)
+		}
+	}
+	if len(cfg.DNSResolution) > 0 || len(cfg.AdditionalRoutes) > 0 {
+		return fmt.Errorf("top-level dns_resolution and additional_routes are not supported in version %d", CurrentConfigVersion)
+	}
+	return ValidateSubnetRouteKeys(cfg.SubnetRouteKeys)
+}
+
+// migrateV1ToV2 replaces has_additional_routes with the explicit routing blocks that reproduce it,
+// only where no explicit block already takes precedence, and drops the unused top-level maps.
+func migrateV1ToV2(cfg *YAMLConfig) error {
+	sources := make([]string, 0, len(cfg.PeeringMatrix))
+	for source := range cfg.PeeringMatrix {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	for _, source := range sources {
+		entries := cfg.PeeringMatrix[source]
+		for i := range entries {
+			entry := &entries[i]
+			if labels, ok := entry.SelectorLabels(); ok {
+				for _, name := range ExpandMatrixEntries(*cfg, source, []MatrixEntry{*entry}) {
+					if cfg.Peers[name.Peer].HasAdditionalRoutes {
+						return fmt.Errorf("peer %q selected by label %v uses has_additional_routes; set routes on it before migrating", name.Peer, labels)
+					}
+				}
+				continue
+			}
+			target, ok := cfg.Peers[entry.Peer]
+			if !ok || !target.HasAdditionalRoutes || (entry.ManageRoutes != nil && !*entry.ManageRoutes) {
+				continue
+			}
+			if entry.SourceRoutes == nil && cfg.Peers[source].Routes == nil {
+				routing := legacyRouting(true, "cdktf-source-main-rt")
+				entry.SourceRoutes = &routing
+			}
+			if entry.PeerRoutes == nil && target.Routes == nil {
+				routing := legacyRouting(true, "cdktf-peer-main-rt")
+				entry.PeerRoutes = &routing
+			}
+		}
+	}
+
+	for name, peer := range cfg.Peers {
+		peer.HasAdditionalRoutes = false
+		cfg.Peers[name] = peer
+	}
+
+	if len(cfg.DNSResolution) > 0 || len(cfg.AdditionalRoutes) > 0 {
+		log.Printf("[config] Dropping top-level dns_resolution/additional_routes, which were never applied; set dns_resolution on peers instead")
+		cfg.DNSResolution = nil
+		cfg.AdditionalRoutes = nil
+	}
+	return nil
+}
+
+// migrateV2ToV3 keeps the subnet-keyed routes of version 2, whose addresses moving to route table
+// keys would make the next apply delete and recreate every filtered subnet route. The setting covers
+// routing from peer manifests and discovery too, which the migration cannot see.
+func migrateV2ToV3(cfg *YAMLConfig) error {
+	if cfg.SubnetRouteKeys == "" {
+		cfg.SubnetRouteKeys = SubnetRouteKeysSubnet
+		log.Printf("[config] Keeping filtered subnet routes keyed by subnet (subnet_route_keys: %s); see the README to key them by route table", SubnetRouteKeysSubnet)
+	}
+	return nil
+}
+
+// -------------------------------------------------------------------------------------------------
+// migrate-config
+// -------------------------------------------------------------------------------------------------
+
+// runMigrateConfig rewrites a config file in the current schema version, keeping a backup of the
+// original. Comments are not preserved. SOPS-encrypted files are only printed, since rewriting them
+// would store the decrypted values.
+func runMigrateConfig(args []string) error {
+	fs := flag.NewFlagSet("migrate-config", flag.ContinueOnError)
+	path := fs.String("f", "", "config file to migrate (default: --config, $CDKTF_PEERING_CONFIG, or the search paths)")
+	dryRun := fs.Bool("n", false, "print the migrated config instead of rewriting the file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *path == "" {
+		*path = ConfigPath()
+	}
+	if IsRemoteConfig(*path) && !*dryRun {
+		return fmt.Errorf("%s is remote and cannot be rewritten; print the migrated config with -n and publish it in place", *path)
+	}
+	s3 := configObjectReader()
+	data, err := ReadDocument(*path, s3)
+	if err != nil {
+		return err
+	}
+	if err := VerifyDocument(*path, data, os.Getenv(ConfigVerifyEnvVar), s3); err != nil {
+		return err
+	}
+	encrypted := IsSopsEncrypted(data)
+	if data, err = DecryptConfig(*path, data, sopsDecrypter(*path, data)); err != nil {
+		return err
+	}
+	var cfg YAMLConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", *path, err)
+	}
+	from, err := MigrateConfig(&cfg)
+	if err != nil {
+		return err
+	}
+
+	out, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	if *dryRun {
+		fmt.Print(string(out))
+		return nil
+	}
+	if from == CurrentConfigVersion {
+		log.Printf("[config] %s is already at version %d", *path, CurrentConfigVersion)
+		return nil
+	}
+
+	if encrypted {
+		return fmt.Errorf("%s is SOPS-encrypted and would be rewritten in plaintext; print the migrated config with -n and replace the file through sops edit", *path)
+	}
+
+	backup := *path + ".bak"
+	if err := os.WriteFile(backup, data, 0o644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(*path, out, 0o644); err != nil {
+		return err
+	}
+	log.Printf("[config] Migrated %s from version %d to %d (original saved as %s)", *path, from, CurrentConfigVersion, backup)
+	return nil
+}
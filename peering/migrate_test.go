@@ -0,0 +1,86 @@
+package peering
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestMigrateConfigV1 tests that migrating a version 1 config keeps the routing it produced.
+func TestMigrateConfigV1(t *testing.T) {
+	newConfig := func() YAMLConfig {
+		return YAMLConfig{
+			Peers: map[string]YAMLPeer{
+				"dev":     {VpcID: "vpc-1"},
+				"prod":    {VpcID: "vpc-2", HasAdditionalRoutes: true},
+				"staging": {VpcID: "vpc-3", HasAdditionalRoutes: true, Routes: &RoutingConfig{Strategy: RoutingAll}},
+			},
+			PeeringMatrix: map[string][]MatrixEntry{"dev": {{Peer: "prod"}, {Peer: "staging"}}},
+			DNSResolution: map[string]bool{"dev": true},
+		}
+	}
+	// Versions before 3 keyed filtered subnet routes by subnet.
+	legacy := newConfig()
+	legacy.SubnetRouteKeys = SubnetRouteKeysSubnet
+	before := ConvertToPeerConfigs(legacy, "")
+
+	cfg := newConfig()
+	from, err := MigrateConfig(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from != 1 || cfg.Version != CurrentConfigVersion {
+		t.Errorf("expected migration from 1 to %d, got %d to %d", CurrentConfigVersion, from, cfg.Version)
+	}
+	if cfg.Peers["prod"].HasAdditionalRoutes || cfg.DNSResolution != nil {
+		t.Errorf("expected version 1 settings to be removed: %+v", cfg)
+	}
+	if cfg.PeeringMatrix["dev"][1].PeerRoutes != nil {
+		t.Errorf("expected peer routes to stay on the staging peer default")
+	}
+
+	after := ConvertToPeerConfigs(cfg, "")
+	for i := range before {
+		if !reflect.DeepEqual(before[i].SourceRouting, after[i].SourceRouting) || !reflect.DeepEqual(before[i].PeerRouting, after[i].PeerRouting) {
+			t.Errorf("connection %d routing changed: %+v/%+v -> %+v/%+v", i,
+				before[i].SourceRouting, before[i].PeerRouting, after[i].SourceRouting, after[i].PeerRouting)
+		}
+	}
+
+	if _, err := MigrateConfig(&YAMLConfig{Version: CurrentConfigVersion + 1}); err == nil {
+		t.Errorf("expected error for a newer config version")
+	}
+	current := YAMLConfig{Version: CurrentConfigVersion, Peers: map[string]YAMLPeer{"x": {HasAdditionalRoutes: true}}}
+	if _, err := MigrateConfig(&current); err == nil {
+		t.Errorf("expected error for has_additional_routes in the current version")
+	}
+}
+
+// TestMigrateConfigV2 tests that migrating a version 2 config keeps its subnet-keyed routes, and that
+// version 3 keys filtered subnet routes by route table unless told otherwise.
+func TestMigrateConfigV2(t *testing.T) {
+	filtered := &RoutingConfig{Strategy: RoutingFiltered, SubnetTags: map[string]string{"tier": "app"}}
+	cfg := YAMLConfig{
+		Version:       2,
+		Peers:         map[string]YAMLPeer{"dev": {VpcID: "vpc-1", Routes: filtered}, "prod": {VpcID: "vpc-2"}},
+		PeeringMatrix: map[string][]MatrixEntry{"dev": {{Peer: "prod"}}},
+	}
+	if _, err := MigrateConfig(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.SubnetRouteKeys != SubnetRouteKeysSubnet {
+		t.Errorf("expected subnet_route_keys: %s after migrating, got %q", SubnetRouteKeysSubnet, cfg.SubnetRouteKeys)
+	}
+	if got := ConvertToPeerConfigs(cfg, "")[0].SourceRouting; got.KeyByRouteTable {
+		t.Errorf("expected subnet-keyed routes to be kept, got %+v", got)
+	}
+
+	cfg.SubnetRouteKeys = ""
+	if got := ConvertToPeerConfigs(cfg, "")[0].SourceRouting; !got.KeyByRouteTable {
+		t.Errorf("expected route table keys by default, got %+v", got)
+	}
+
+	cfg.SubnetRouteKeys = "subnets"
+	if _, err := MigrateConfig(&cfg); err == nil {
+		t.Error("expected an unknown subnet_route_keys to be rejected")
+	}
+}
@@ -0,0 +1,402 @@
+package peering
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Resource Kinds
+// -------------------------------------------------------------------------------------------------
+
+// Resource kinds identify the role a construct plays within a single connection. They are passed
+// to a Namer and are available as the {kind} token in naming patterns.
+const (
+	KindSourceProvider       = "source-provider"
+	KindSourceProviderAlias  = "source-alias"
+	KindPeerProvider         = "peer-provider"
+	KindPeerProviderAlias    = "peer-alias"
+	KindSourceVpc            = "source-vpc"
+	KindPeerVpc              = "peer-vpc"
+	KindSourceMainRt         = "source-main-rt"
+	KindPeerMainRt           = "peer-main-rt"
+	KindPeering              = "peering"
+	KindAccepter             = "accepter"
+	KindOptions              = "options"
+	KindAccepterOptions      = "accepter-options"
+	KindSourceMainRoute      = "source-main-route"
+	KindPeerMainRoute        = "peer-main-route"
+	KindSourceSubnets        = "source-subnets"
+	KindPeerSubnets          = "peer-subnets"
+	KindSourceSubnetRt       = "source-subnet-rt"
+	KindPeerSubnetRt         = "peer-subnet-rt"
+	KindSourceSubnetRoute    = "source-subnet-route"
+	KindPeerSubnetRoute      = "peer-subnet-route"
+	KindSourceRouteTables    = "source-route-tables"
+	KindPeerRouteTables      = "peer-route-tables"
+	KindSourceAllRoute       = "source-all-route"
+	KindPeerAllRoute         = "peer-all-route"
+	KindOutputPeeringID      = "output-peering-id"
+	KindOutputSourceMainRt   = "output-source-main-rt"
+	KindOutputPeerMainRt     = "output-peer-main-rt"
+	KindOutputDNSResolution  = "output-dns-resolution"
+	KindOutputAcceptStatus   = "output-accept-status"
+	KindOutputRequesterDNS   = "output-requester-dns"
+	KindOutputAccepterDNS    = "output-accepter-dns"
+	KindOutputPeerOwner      = "output-peer-owner"
+	KindOutputRequesterCidr  = "output-requester-cidr"
+	KindOutputAccepterCidr   = "output-accepter-cidr"
+	KindConnectivityCheck    = "connectivity-check"
+	KindInventoryItem        = "inventory-item"
+	KindPeeringData          = "peering-data"
+	KindSourceRtTag          = "source-rt-tag"
+	KindPeerRtTag            = "peer-rt-tag"
+	KindSourceRoutesParam    = "source-routes-param"
+	KindPeerRoutesParam      = "peer-routes-param"
+	KindSourceDedicatedRt    = "source-dedicated-rt"
+	KindPeerDedicatedRt      = "peer-dedicated-rt"
+	KindSourceRtAssociation  = "source-rt-association"
+	KindPeerRtAssociation    = "peer-rt-association"
+	KindOwnedPeeringParam    = "owned-peering-param"
+	KindAccepterDetailsParam = "accepter-details-param"
+	KindSourceDNSProfile     = "source-dns-profile"
+	KindPeerDNSProfile       = "peer-dns-profile"
+	KindSourceApplianceRoute = "source-appliance-route"
+	KindPeerApplianceRoute   = "peer-appliance-route"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Namer Interface
+// -------------------------------------------------------------------------------------------------
+
+// NameContext carries the connection metadata a Namer may use to build names.
+type NameContext struct {
+	Index  int    // Position of the connection in the stack.
+	Source string // Logical name of the source peer.
+	Peer   string // Logical name of the target peer.
+}
+
+// Namer decides the construct ID of every resource and the Name tag of every connection.
+type Namer interface {
+	ID(ctx NameContext, kind string) string
+	NameTag(ctx NameContext) string
+}
+
+// ConnectionNameContext builds the NameContext for the connection at index i, falling back to the
+// peer VPC ID when the connection has no logical name.
+func ConnectionNameContext(i int, peer PeerConfig) NameContext {
+	name := peer.Name
+	if name == "" {
+		name = peer.PeerVpcID
+	}
+	return NameContext{Index: i, Source: peer.SourceName, Peer: name}
+}
+
+// NewNamer returns the Namer selected by the naming config, defaulting to the LegacyNamer.
+func NewNamer(cfg NamingConfig) Namer {
+	if cfg.Pattern == "" {
+		return LegacyNamer{}
+	}
+	if err := ValidateNamingPattern(cfg.Pattern); err != nil {
+		Failf(ExitValidation, "invalid naming pattern: %v", err)
+	}
+	return PatternNamer{Pattern: cfg.Pattern}
+}
+
+// -------------------------------------------------------------------------------------------------
+// Legacy Namer
+// -------------------------------------------------------------------------------------------------
+
+// legacyIDFormats maps each kind to the index-based construct ID used before naming was pluggable.
+var legacyIDFormats = map[string]string{
+	KindSourceProvider:       "SourceAWS%d",
+	KindSourceProviderAlias:  "source%d",
+	KindPeerProvider:         "PeerAWS%d",
+	KindPeerProviderAlias:    "peer%d",
+	KindSourceVpc:            "SourceVpcData%d",
+	KindPeerVpc:              "PeerVpcData%d",
+	KindSourceMainRt:         "SourceMainRouteTable%d",
+	KindPeerMainRt:           "PeerMainRouteTable%d",
+	KindPeering:              "VpcPeering%d",
+	KindAccepter:             "VpcPeeringAccepter%d",
+	KindOptions:              "VpcPeeringOptions%d",
+	KindAccepterOptions:      "VpcPeeringAccepterOptions%d",
+	KindSourceMainRoute:      "SourceToPeerMainRoute%d",
+	KindPeerMainRoute:        "PeerToPeerMainRoute%d",
+	KindSourceSubnets:        "SourceSubnets%d",
+	KindPeerSubnets:          "PeerSubnets%d",
+	KindSourceRouteTables:    "SourceRouteTables%d",
+	KindPeerRouteTables:      "PeerRouteTables%d",
+	KindSourceAllRoute:       "SourceToPeerAllRoute%d",
+	KindPeerAllRoute:         "PeerToSourceAllRoute%d",
+	KindOutputPeeringID:      "VpcPeeringConnectionId_%d",
+	KindOutputSourceMainRt:   "SourceMainRouteTableId_%d",
+	KindOutputPeerMainRt:     "PeerMainRouteTableId_%d",
+	KindOutputDNSResolution:  "DnsResolutionEnabled_%d",
+	KindOutputAcceptStatus:   "AcceptStatus_%d",
+	KindOutputRequesterDNS:   "RequesterDnsResolution_%d",
+	KindOutputAccepterDNS:    "AccepterDnsResolution_%d",
+	KindOutputPeerOwner:      "PeerOwnerId_%d",
+	KindOutputRequesterCidr:  "RequesterCidr_%d",
+	KindOutputAccepterCidr:   "AccepterCidr_%d",
+	KindConnectivityCheck:    "ConnectivityCheck%d",
+	KindInventoryItem:        "InventoryItem%d",
+	KindPeeringData:          "VpcPeeringData%d",
+	KindSourceRtTag:          "SourceRouteTableTag%d",
+	KindPeerRtTag:            "PeerRouteTableTag%d",
+	KindSourceRoutesParam:    "SourceRoutesParameter%d",
+	KindPeerRoutesParam:      "PeerRoutesParameter%d",
+	KindSourceDedicatedRt:    "SourceDedicatedRouteTable%d",
+	KindPeerDedicatedRt:      "PeerDedicatedRouteTable%d",
+	KindSourceRtAssociation:  "SourceRouteTableAssociation%d",
+	KindPeerRtAssociation:    "PeerRouteTableAssociation%d",
+	KindOwnedPeeringParam:    "OwnedPeeringParameter%d",
+	KindAccepterDetailsParam: "AccepterDetailsParameter%d",
+	KindSourceDNSProfile:     "SourceDnsProfileAssociation%d",
+	KindPeerDNSProfile:       "PeerDnsProfileAssociation%d",
+	KindSourceApplianceRoute: "SourceApplianceToPeerRoute%d",
+	KindPeerApplianceRoute:   "PeerApplianceToSourceRoute%d",
+}
+
+// LegacyNamer reproduces the original index-based construct IDs so existing state keeps matching.
+type LegacyNamer struct{}
+
+// ID returns the legacy construct ID for the given kind.
+func (LegacyNamer) ID(ctx NameContext, kind string) string {
+	switch kind {
+	case KindSourceSubnetRt:
+		return fmt.Sprintf("SourceSubnetToPeerRoute_%s_eachkey_%dRouteTable", ctx.Peer, ctx.Index)
+	case KindSourceSubnetRoute:
+		return fmt.Sprintf("SourceSubnetToPeerRoute_%s_eachkey_%dRoute", ctx.Peer, ctx.Index)
+	case KindPeerSubnetRt:
+		return fmt.Sprintf("PeerSubnetToSourceRoute_%s_eachkey_%dRouteTable", ctx.Peer, ctx.Index)
+	case KindPeerSubnetRoute:
+		return fmt.Sprintf("PeerSubnetToSourceRoute_%s_eachkey_%dRoute", ctx.Peer, ctx.Index)
+	}
+	format, ok := legacyIDFormats[kind]
+	if !ok {
+		Failf(ExitSynth, "no legacy name registered for resource kind %q", kind)
+	}
+	return fmt.Sprintf(format, ctx.Index)
+}
+
+// NameTag returns the legacy "Connection to <peer>" Name tag.
+func (LegacyNamer) NameTag(ctx NameContext) string {
+	return fmt.Sprintf("Connection to %s", ctx.Peer)
+}
+
+// -------------------------------------------------------------------------------------------------
+// Pattern Namer
+// -------------------------------------------------------------------------------------------------
+
+// invalidIDChars matches characters Terraform does not accept in resource names.
+var invalidIDChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// terraformName returns the name CDKTF gives a construct ID directly under a stack in the
+// synthesized Terraform, for references built as text: path separators become "--" and characters
+// Terraform does not accept are dropped. Constructs at hand should use FriendlyUniqueId instead.
+func terraformName(id string) string {
+	return invalidIDChars.ReplaceAllString(strings.ReplaceAll(id, "/", "--"), "")
+}
+
+// PatternNamer builds names from a pattern with {source}, {peer}, {kind}, and {index} tokens.
+type PatternNamer struct {
+	Pattern string
+}
+
+// ValidateNamingPattern ensures a pattern can produce unique IDs for every resource in a stack. IDs
+// need {source} as well as {peer}, since several sources can be synthesized into one stack.
+func ValidateNamingPattern(pattern string) error {
+	for _, token := range []string{"{source}", "{peer}", "{kind}"} {
+		if !strings.Contains(pattern, token) {
+			return fmt.Errorf("pattern %q must contain %s", pattern, token)
+		}
+	}
+	return nil
+}
+
+// ID expands the pattern for the given kind and replaces characters Terraform would reject.
+func (n PatternNamer) ID(ctx NameContext, kind string) string {
+	id := invalidIDChars.ReplaceAllString(n.expand(ctx, kind), "_")
+	if id != "" && id[0] >= '0' && id[0] <= '9' {
+		id = "_" + id
+	}
+	return id
+}
+
+// NameTag expands the pattern using the peering kind.
+func (n PatternNamer) NameTag(ctx NameContext) string {
+	return n.expand(ctx, KindPeering)
+}
+
+// expand substitutes all tokens in the pattern.
+func (n PatternNamer) expand(ctx NameContext, kind string) string {
+	return strings.NewReplacer(
+		"{source}", ctx.Source,
+		"{peer}", ctx.Peer,
+		"{kind}", kind,
+		"{index}", fmt.Sprint(ctx.Index),
+	).Replace(n.Pattern)
+}
+
+// -------------------------------------------------------------------------------------------------
+// Construct ID Registry
+// -------------------------------------------------------------------------------------------------
+
+// idClaim is the resource a construct ID was issued for.
+type idClaim struct {
+	ctx  NameContext // Connection of the resource.
+	kind string      // Resource kind, "<kind>:<cidr>" for CIDR-specific routes.
+}
+
+// IDRegistry is a Namer that records every construct ID the wrapped Namer issues within one stack
+// and rejects an ID issued for two different resources. User-controlled names feed into IDs, and
+// without the registry a collision surfaces as a jsii error naming neither connection.
+type IDRegistry struct {
+	Namer
+	claims map[string]idClaim
+}
+
+// NewIDRegistry wraps a Namer with an empty registry.
+func NewIDRegistry(namer Namer) *IDRegistry {
+	return &IDRegistry{Namer: namer, claims: make(map[string]idClaim)}
+}
+
+// ID returns the wrapped Namer's ID after claiming it, stopping the build on a collision.
+func (r *IDRegistry) ID(ctx NameContext, kind string) string {
+	id := r.Namer.ID(ctx, kind)
+	if err := r.Claim(id, ctx, kind); err != nil {
+		Fail(WithExitCode(ExitValidation, err))
+	}
+	return id
+}
+
+// Claim records id as issued for a resource of a connection. Issuing it again for the same
+// resource, as references to it do, is not a collision; issuing it for any other is.
+func (r *IDRegistry) Claim(id string, ctx NameContext, kind string) error {
+	prev, ok := r.claims[id]
+	if !ok {
+		r.claims[id] = idClaim{ctx: ctx, kind: kind}
+		return nil
+	}
+	if prev.ctx.Index == ctx.Index && prev.kind == kind {
+		return nil
+	}
+	return fmt.Errorf("construct ID %q is issued for both %s of %s -> %s and %s of %s -> %s; rename a peer or add {index} to naming.pattern",
+		id, prev.kind, prev.ctx.Source, prev.ctx.Peer, kind, ctx.Source, ctx.Peer)
+}
+
+// claimDerivedID claims an ID built from a Namer's ID, such as a CIDR-specific route's, when the
+// namer is an IDRegistry.
+func claimDerivedID(namer Namer, id string, ctx NameContext, kind string) {
+	if r, ok := namer.(*IDRegistry); ok {
+		if err := r.Claim(id, ctx, kind); err != nil {
+			Fail(WithExitCode(ExitValidation, err))
+		}
+	}
+}
+
+// CheckConstructIDs reports the construct IDs of managed resources that two resources of a stack of
+// the given connections would share, without building the stack.
+func CheckConstructIDs(namer Namer, peers []PeerConfig) []error {
+	registry := NewIDRegistry(namer)
+	var errs []error
+	for i, peer := range RequesterOnlyPeers(peers) {
+		ctx := ConnectionNameContext(i, peer)
+		addresses := ConnectionAddresses(namer, ctx, peer)
+		kinds := make([]string, 0, len(addresses))
+		for kind := range addresses {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+		for _, kind := range kinds {
+			id := addresses[kind][strings.Index(addresses[kind], ".")+1:]
+			if err := registry.Claim(id, ctx, kind); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs
+}
+
+// -------------------------------------------------------------------------------------------------
+// Name Tag Templates
+// -------------------------------------------------------------------------------------------------
+
+// NameTagData is the connection metadata available to name tag templates.
+type NameTagData struct {
+	Index           int    // Position of the connection in the stack.
+	SourceName      string // Logical name of the source peer.
+	PeerName        string // Logical name of the target peer.
+	SourceVpcID     string // VPC ID of the source.
+	PeerVpcID       string // VPC ID of the peer.
+	SourceRegion    string // Resolved AWS region of the source.
+	PeerRegion      string // Resolved AWS region of the peer.
+	SourceAccountID string // Account ID parsed from the source role ARN.
+	PeerAccountID   string // Account ID parsed from the peer role ARN.
+	SourceEnv       string // Environment label of the source.
+	PeerEnv         string // Environment label of the peer.
+	Env             string // Environment label of the peer, falling back to the source.
+}
+
+// NewNameTagData collects the template metadata for a connection.
+func NewNameTagData(ctx NameContext, peer PeerConfig) NameTagData {
+	env := peer.PeerEnv
+	if env == "" {
+		env = peer.SourceEnv
+	}
+	return NameTagData{
+		Index:           ctx.Index,
+		SourceName:      ctx.Source,
+		PeerName:        ctx.Peer,
+		SourceVpcID:     peer.SourceVpcID,
+		PeerVpcID:       peer.PeerVpcID,
+		SourceRegion:    ResolveRegion(peer.SourceRegion),
+		PeerRegion:      ResolveRegion(peer.PeerRegion),
+		SourceAccountID: GetAccountIDFromRoleArn(peer.SourceRoleArn),
+		PeerAccountID:   PeerAccount(peer),
+		SourceEnv:       peer.SourceEnv,
+		PeerEnv:         peer.PeerEnv,
+		Env:             env,
+	}
+}
+
+// ValidateNameTagTemplate parses the template and executes it against empty metadata so unknown
+// fields are reported at conversion time rather than mid-synth. An empty template is valid.
+func ValidateNameTagTemplate(text string) error {
+	if text == "" {
+		return nil
+	}
+	_, err := RenderNameTag(text, NameTagData{})
+	return err
+}
+
+// RenderNameTag executes a name tag template against the given metadata.
+func RenderNameTag(text string, data NameTagData) (string, error) {
+	tmpl, err := template.New("name_tag").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// ConnectionNameTag returns the Name tag for a connection, rendering its template when one is
+// configured and deferring to the namer otherwise.
+func ConnectionNameTag(namer Namer, ctx NameContext, peer PeerConfig) string {
+	if peer.NameTagTemplate == "" {
+		return namer.NameTag(ctx)
+	}
+	tag, err := RenderNameTag(peer.NameTagTemplate, NewNameTagData(ctx, peer))
+	if err != nil {
+		Failf(ExitValidation, "failed to render name tag for %q: %v", ctx.Peer, err)
+	}
+	return tag
+}
@@ -0,0 +1,137 @@
+package peering
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestLegacyNamer tests that the legacy namer reproduces the original construct IDs.
+func TestLegacyNamer(t *testing.T) {
+	ctx := NameContext{Index: 2, Source: "dev", Peer: "prod"}
+	tests := []struct {
+		kind     string
+		expected string
+	}{
+		{KindSourceProvider, "SourceAWS2"},
+		{KindPeerProviderAlias, "peer2"},
+		{KindPeering, "VpcPeering2"},
+		{KindPeerMainRoute, "PeerToPeerMainRoute2"},
+		{KindSourceSubnetRoute, "SourceSubnetToPeerRoute_prod_eachkey_2Route"},
+		{KindPeerSubnetRt, "PeerSubnetToSourceRoute_prod_eachkey_2RouteTable"},
+		{KindOutputPeeringID, "VpcPeeringConnectionId_2"},
+		{KindOutputAccepterDNS, "AccepterDnsResolution_2"},
+	}
+	for _, tt := range tests {
+		if got := (LegacyNamer{}).ID(ctx, tt.kind); got != tt.expected {
+			t.Errorf("LegacyNamer.ID(%q) = %q, want %q", tt.kind, got, tt.expected)
+		}
+	}
+	if got := (LegacyNamer{}).NameTag(ctx); got != "Connection to prod" {
+		t.Errorf("LegacyNamer.NameTag() = %q", got)
+	}
+}
+
+// TestPatternNamer tests token expansion and sanitization of pattern-based names.
+func TestPatternNamer(t *testing.T) {
+	n := PatternNamer{Pattern: "{source}-{peer}-{kind}"}
+	ctx := NameContext{Index: 0, Source: "dev.east", Peer: "prod"}
+	if got := n.ID(ctx, KindPeering); got != "dev_east-prod-peering" {
+		t.Errorf("PatternNamer.ID() = %q", got)
+	}
+	if got := n.NameTag(ctx); got != "dev.east-prod-peering" {
+		t.Errorf("PatternNamer.NameTag() = %q", got)
+	}
+	if got := (PatternNamer{Pattern: "{index}-{kind}"}).ID(ctx, KindAccepter); got != "_0-accepter" {
+		t.Errorf("PatternNamer.ID() with leading digit = %q", got)
+	}
+}
+
+// TestValidateNamingPattern tests rejection of patterns that cannot produce unique IDs.
+func TestValidateNamingPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		valid   bool
+	}{
+		{"{source}-{peer}-{kind}", true},
+		{"{kind}_{source}_{peer}_{index}", true},
+		{"{source}-{peer}", false},
+		{"{source}-{kind}", false},
+		{"{peer}-{kind}", false},
+		{"{index}_{kind}", false},
+	}
+	for _, tt := range tests {
+		err := ValidateNamingPattern(tt.pattern)
+		if (err == nil) != tt.valid {
+			t.Errorf("ValidateNamingPattern(%q) error = %v, want valid=%v", tt.pattern, err, tt.valid)
+		}
+	}
+}
+
+// TestConnectionNameTag tests template rendering and the namer fallback for Name tags.
+func TestConnectionNameTag(t *testing.T) {
+	peer := PeerConfig{
+		SourceName:      "dev",
+		Name:            "prod",
+		PeerEnv:         "production",
+		NameTagTemplate: "{{.SourceName}}<->{{.PeerName}} ({{.Env}})",
+	}
+	ctx := ConnectionNameContext(0, peer)
+	if got := ConnectionNameTag(LegacyNamer{}, ctx, peer); got != "dev<->prod (production)" {
+		t.Errorf("ConnectionNameTag() = %q", got)
+	}
+
+	peer.NameTagTemplate = ""
+	if got := ConnectionNameTag(LegacyNamer{}, ctx, peer); got != "Connection to prod" {
+		t.Errorf("ConnectionNameTag() without template = %q", got)
+	}
+}
+
+// TestValidateNameTagTemplate tests that unknown fields and syntax errors are rejected.
+func TestValidateNameTagTemplate(t *testing.T) {
+	if err := ValidateNameTagTemplate("{{.PeerName}}-{{.PeerAccountID}}"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ValidateNameTagTemplate("{{.Team}}"); err == nil {
+		t.Errorf("expected error for unknown field")
+	}
+	if err := ValidateNameTagTemplate("{{.PeerName"); err == nil {
+		t.Errorf("expected error for malformed template")
+	}
+}
+
+// TestIDRegistry tests that an ID may be reissued for its own resource but not for another one.
+func TestIDRegistry(t *testing.T) {
+	r := NewIDRegistry(PatternNamer{Pattern: "{peer}-{kind}"})
+	dev := NameContext{Index: 0, Source: "dev", Peer: "shared"}
+	stage := NameContext{Index: 1, Source: "stage", Peer: "shared"}
+	if err := r.Claim("shared-peering", dev, KindPeering); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.Claim("shared-peering", dev, KindPeering); err != nil {
+		t.Errorf("expected a reference to the same resource to pass, got %v", err)
+	}
+	err := r.Claim("shared-peering", stage, KindPeering)
+	want := `construct ID "shared-peering" is issued for both peering of dev -> shared and peering of stage -> shared`
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("expected an error containing %q, got %v", want, err)
+	}
+	if got := r.ID(dev, KindAccepter); got != "shared-accepter" {
+		t.Errorf("expected the wrapped namer's ID, got %q", got)
+	}
+}
+
+// TestCheckConstructIDs tests that peer names sanitized to the same ID are reported per resource.
+func TestCheckConstructIDs(t *testing.T) {
+	peers := []PeerConfig{
+		{SourceName: "dev", Name: "prod.east", SourceRegion: "us-east-1", PeerRegion: "us-east-1"},
+		{SourceName: "dev", Name: "prod_east", SourceRegion: "us-east-1", PeerRegion: "us-east-1"},
+	}
+	if errs := CheckConstructIDs(PatternNamer{Pattern: "{source}-{peer}-{kind}"}, peers); len(errs) == 0 {
+		t.Error("expected collisions between prod.east and prod_east")
+	} else if !strings.Contains(errs[0].Error(), "dev -> prod.east") || !strings.Contains(errs[0].Error(), "dev -> prod_east") {
+		t.Errorf("expected both connections in %v", errs[0])
+	}
+	if errs := CheckConstructIDs(LegacyNamer{}, peers); len(errs) != 0 {
+		t.Errorf("expected no collisions with index-based IDs, got %v", errs)
+	}
+}
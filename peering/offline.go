@@ -0,0 +1,91 @@
+package peering
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Offline Synth
+// -------------------------------------------------------------------------------------------------
+
+// routeTableIDPattern matches route table IDs.
+var routeTableIDPattern = regexp.MustCompile(`^rtb-[0-9a-f]+$`)
+
+// ValidateRouteTableIDs checks the route table IDs declared for a peer's VPC, which must include
+// the main route table when both are declared.
+func ValidateRouteTableIDs(p YAMLPeer) error {
+	if p.MainRouteTableID != "" && !routeTableIDPattern.MatchString(p.MainRouteTableID) {
+		return fmt.Errorf("invalid main_route_table_id %q", p.MainRouteTableID)
+	}
+	seen := make(map[string]bool, len(p.RouteTableIDs))
+	for _, id := range p.RouteTableIDs {
+		if !routeTableIDPattern.MatchString(id) {
+			return fmt.Errorf("invalid route table ID %q in route_table_ids", id)
+		}
+		if seen[id] {
+			return fmt.Errorf("route table %s is listed twice in route_table_ids", id)
+		}
+		seen[id] = true
+	}
+	if p.MainRouteTableID != "" && len(p.RouteTableIDs) > 0 && !seen[p.MainRouteTableID] {
+		return fmt.Errorf("route_table_ids does not include the main route table %s", p.MainRouteTableID)
+	}
+	return nil
+}
+
+// offlineSide checks that one side of a connection declares everything its stack would otherwise
+// look up, returning what is missing.
+func offlineSide(name, cidr, mainRouteTableID string, tableIDs []string, routing RoutingConfig) []string {
+	var missing []string
+	if cidr == "" {
+		missing = append(missing, fmt.Sprintf("peer %q needs cidr", name))
+	}
+	if mainRouteTableID == "" {
+		missing = append(missing, fmt.Sprintf("peer %q needs main_route_table_id", name))
+	}
+	switch routing.Strategy {
+	case RoutingAll:
+		if len(tableIDs) == 0 {
+			missing = append(missing, fmt.Sprintf("peer %q needs route_table_ids for all routing", name))
+		}
+	case RoutingFiltered:
+		missing = append(missing, fmt.Sprintf("peer %q uses filtered routing, which looks up subnets by tag; use main or all", name))
+	}
+	return missing
+}
+
+// CheckOffline verifies that a stack of the given connections can be synthesized without any data
+// source: every VPC declares its CIDR and main route table, all routing declares the route tables,
+// and no connection uses filtered routing or a peering created elsewhere. It lists every problem at
+// once, each reported a single time.
+func CheckOffline(peers []PeerConfig) error {
+	seen := make(map[string]bool)
+	var problems []string
+	add := func(found ...string) {
+		for _, p := range found {
+			if !seen[p] {
+				seen[p] = true
+				problems = append(problems, p)
+			}
+		}
+	}
+	for _, peer := range RequesterOnlyPeers(peers) {
+		if peer.ExternalPeeringID != "" {
+			add(fmt.Sprintf("%s looks up the peering %s created elsewhere", ConnectionKey(peer), peer.ExternalPeeringID))
+		}
+		if peer.PeeringOwner != "" {
+			add(fmt.Sprintf("%s looks up the peering created by %s", ConnectionKey(peer), peer.PeeringOwner))
+		}
+		if !peer.EnableDNSResolution && len(peer.DNSResolutionTags) > 0 {
+			add(fmt.Sprintf("%s reads the tags of peer %q for dns_resolution_policy; set dns_resolution on the peer", ConnectionKey(peer), peer.Name))
+		}
+		add(offlineSide(peer.SourceName, peer.SourceCidr, peer.SourceMainRouteTableID, peer.SourceRouteTableIDs, peer.SourceRouting)...)
+		add(offlineSide(ConnectionNameContext(0, peer).Peer, peer.PeerCidr, peer.PeerMainRouteTableID, peer.PeerRouteTableIDs, peer.PeerRouting)...)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("cannot synthesize offline:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package peering
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestValidateRouteTableIDs tests the checks on declared route table IDs.
+func TestValidateRouteTableIDs(t *testing.T) {
+	tests := []struct {
+		name string
+		peer YAMLPeer
+		err  string
+	}{
+		{"none", YAMLPeer{}, ""},
+		{"main and all", YAMLPeer{MainRouteTableID: "rtb-1", RouteTableIDs: []string{"rtb-2", "rtb-1"}}, ""},
+		{"invalid main", YAMLPeer{MainRouteTableID: "rt-1"}, "invalid main_route_table_id"},
+		{"invalid table", YAMLPeer{RouteTableIDs: []string{"rtb-1", "subnet-1"}}, `invalid route table ID "subnet-1"`},
+		{"duplicate", YAMLPeer{RouteTableIDs: []string{"rtb-1", "rtb-1"}}, "listed twice"},
+		{"main missing", YAMLPeer{MainRouteTableID: "rtb-1", RouteTableIDs: []string{"rtb-2"}}, "does not include the main route table rtb-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRouteTableIDs(tt.peer)
+			if (tt.err == "") != (err == nil) || (err != nil && !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("expected error %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+// TestCheckOffline tests that offline synth lists every lookup that is not declared.
+func TestCheckOffline(t *testing.T) {
+	complete := PeerConfig{
+		SourceName: "dev", Name: "prod",
+		SourceCidr: "10.0.0.0/16", SourceMainRouteTableID: "rtb-1",
+		PeerCidr: "10.1.0.0/16", PeerMainRouteTableID: "rtb-2", PeerRouteTableIDs: []string{"rtb-2", "rtb-3"},
+		SourceRouting: RoutingConfig{Strategy: RoutingMain},
+		PeerRouting:   RoutingConfig{Strategy: RoutingAll},
+	}
+	if err := CheckOffline([]PeerConfig{complete}); err != nil {
+		t.Errorf("expected a fully declared connection to pass, got %v", err)
+	}
+
+	qa := PeerConfig{
+		SourceName: "dev", Name: "qa",
+		SourceCidr: "10.0.0.0/16",
+		PeerCidr:   "10.2.0.0/16", PeerMainRouteTableID: "rtb-4",
+		SourceRouting:     RoutingConfig{Strategy: RoutingFiltered, SubnetTags: map[string]string{"tier": "app"}},
+		PeerRouting:       RoutingConfig{Strategy: RoutingAll},
+		ExternalPeeringID: "pcx-1",
+	}
+	err := CheckOffline([]PeerConfig{complete, qa, {SourceName: "dev", Name: "ops", SourceRouting: RoutingConfig{Strategy: RoutingMain}}})
+	if err == nil {
+		t.Fatal("expected missing declarations to be reported")
+	}
+	for _, want := range []string{
+		"dev/qa looks up the peering pcx-1",
+		`peer "dev" needs main_route_table_id`,
+		`peer "dev" uses filtered routing`,
+		`peer "qa" needs route_table_ids for all routing`,
+		`peer "ops" needs cidr`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+	if strings.Count(err.Error(), `peer "dev" needs main_route_table_id`) != 1 {
+		t.Errorf("expected each problem once, got %v", err)
+	}
+}
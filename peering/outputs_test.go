@@ -0,0 +1,104 @@
+package peering
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// outputConnection returns a connection whose peering is looked up, so its outputs synthesize
+// without provider bindings.
+func outputConnection(stack cdktf.TerraformStack, source, peer string) ConnectionResources {
+	data := cdktf.NewTerraformDataSource(stack, jsii.String("Lookup"+source+peer), &cdktf.TerraformResourceConfig{
+		TerraformResourceType: jsii.String("aws_vpc_peering_connection"),
+	})
+	return ConnectionResources{
+		Peer: PeerConfig{SourceName: source, Name: peer, SourceVpcID: "vpc-1", PeerVpcID: "vpc-2", ExternalPeeringID: "pcx-0abc123"},
+		Core: PeerCoreResources{
+			SourceCidr:     jsii.String("10.0.0.0/16"),
+			PeerCidr:       jsii.String("10.1.0.0/16"),
+			SourceMainRtID: jsii.String("rtb-1"),
+			PeerMainRtID:   jsii.String("rtb-2"),
+		},
+		Peering: PeeringResources{Data: data},
+	}
+}
+
+// TestAddOutputs tests the synthesized per-connection outputs and the keys of the aggregated
+// connections output.
+func TestAddOutputs(t *testing.T) {
+	stack := cdktf.NewTerraformStack(cdktf.Testing_App(nil), jsii.String("peering"))
+	AddOutputs(stack, LegacyNamer{}, []ConnectionResources{
+		outputConnection(stack, "dev", "prod"),
+		outputConnection(stack, "dev", "qa"),
+	})
+
+	var synthesized struct {
+		Output map[string]struct {
+			Value interface{} `json:"value"`
+		} `json:"output"`
+	}
+	if err := json.Unmarshal([]byte(*cdktf.Testing_Synth(stack, nil)), &synthesized); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for name := range synthesized.Output {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	want := []string{
+		"AcceptStatus_0", "AcceptStatus_1",
+		"AccepterCidr_0", "AccepterCidr_1",
+		"AccepterDnsResolution_0", "AccepterDnsResolution_1",
+		"DnsResolutionEnabled_0", "DnsResolutionEnabled_1",
+		"PeerMainRouteTableId_0", "PeerMainRouteTableId_1",
+		"PeerOwnerId_0", "PeerOwnerId_1",
+		"RequesterCidr_0", "RequesterCidr_1",
+		"RequesterDnsResolution_0", "RequesterDnsResolution_1",
+		"SourceMainRouteTableId_0", "SourceMainRouteTableId_1",
+		"VpcPeeringConnectionId_0", "VpcPeeringConnectionId_1",
+		ConnectionsOutputID, PeeringSummaryOutputID,
+	}
+	sort.Strings(want)
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("unexpected outputs:\n got %v\nwant %v", names, want)
+	}
+
+	if got := synthesized.Output["RequesterCidr_1"].Value; got != "10.0.0.0/16" {
+		t.Errorf("expected the requester CIDR, got %v", got)
+	}
+
+	connections, ok := synthesized.Output[ConnectionsOutputID].Value.(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected the connections output to be a map, got %v", synthesized.Output[ConnectionsOutputID].Value)
+	}
+	var keys []string
+	for key := range connections {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if strings.Join(keys, ",") != "dev/prod,dev/qa" {
+		t.Fatalf("unexpected connection keys: %v", keys)
+	}
+
+	details, _ := connections["dev/qa"].(map[string]interface{})
+	var fields []string
+	for field := range details {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	wantFields := "accept_status,accepter_cidr,accepter_dns_resolution,accepter_main_route_table_id,accepter_region," +
+		"accepter_vpc_id,peer,peer_owner_id,peering_id,requester_cidr,requester_dns_resolution," +
+		"requester_main_route_table_id,requester_region,requester_vpc_id,source"
+	if strings.Join(fields, ",") != wantFields {
+		t.Errorf("unexpected connection fields: %v", fields)
+	}
+	if details["peer"] != "qa" || details["accepter_main_route_table_id"] != "rtb-2" {
+		t.Errorf("unexpected details for dev/qa: %v", details)
+	}
+}
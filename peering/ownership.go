@@ -0,0 +1,231 @@
+package peering
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Peering Ownership
+// -------------------------------------------------------------------------------------------------
+
+// LooksUpPeering reports whether a connection routes through a peering its stack does not create:
+// one created elsewhere (peering_id) or one owned by the stack of the other source.
+func (p PeerConfig) LooksUpPeering() bool {
+	return p.ExternalPeeringID != "" || p.PeeringOwner != ""
+}
+
+// peerPair returns the key of a pair of peers, independent of direction.
+func peerPair(a, b string) string {
+	if b < a {
+		a, b = b, a
+	}
+	return a + "|" + b
+}
+
+// PeeringOwners finds the pairs of sources whose matrix entries list each other, which would both
+// create the same peering, and returns the source owning each pair's peering keyed by peerPair: the
+// one named by owner, or else the first source in name order. Only present, enabled entries of
+// peerings this tool creates are considered; entries that fail to resolve are reported by
+// ConvertToPeerConfigs instead.
+func PeeringOwners(cfg YAMLConfig) (map[string]string, error) {
+	type direction struct{ source, peer string }
+	entries := make(map[direction]MatrixEntry)
+	var directions []direction
+	for source, targets := range cfg.PeeringMatrix {
+		for _, entry := range expandMatrixEntries(cfg, source, targets, func(string, ...interface{}) {}) {
+			stage, err := entry.DecommissionStage()
+			if err != nil || stage != "" || entry.Disabled() || (entry.ManagePeering != nil && !*entry.ManagePeering) {
+				continue
+			}
+			d := direction{source, entry.Peer}
+			entries[d] = entry
+			directions = append(directions, d)
+		}
+	}
+	sort.Slice(directions, func(i, j int) bool {
+		if directions[i].source != directions[j].source {
+			return directions[i].source < directions[j].source
+		}
+		return directions[i].peer < directions[j].peer
+	})
+
+	owners := make(map[string]string)
+	for _, d := range directions {
+		source, entry := d.source, entries[d]
+		reverse, ok := entries[direction{entry.Peer, source}]
+		if !ok || source > entry.Peer {
+			continue
+		}
+		owner := source
+		switch {
+		case entry.Owner != "" && reverse.Owner != "" && entry.Owner != reverse.Owner:
+			return nil, fmt.Errorf("%q -> %q and %q -> %q name different owners, %q and %q",
+				source, entry.Peer, entry.Peer, source, entry.Owner, reverse.Owner)
+		case entry.Owner != "":
+			owner = entry.Owner
+		case reverse.Owner != "":
+			owner = reverse.Owner
+		}
+		owners[peerPair(source, entry.Peer)] = owner
+	}
+	return owners, nil
+}
+
+// ApplyPeeringOwner splits a connection listed by both of its sources between their stacks. Each
+// stack routes only its own VPC; the owner's stack creates the peering and its options, and the
+// other looks the peering up by VPC pair.
+func ApplyPeeringOwner(peer PeerConfig, owner string) (PeerConfig, error) {
+	if len(peer.PeerExtraCidrs) > 0 {
+		return peer, fmt.Errorf("%q -> %q routes extra_routes in %q, whose own entry routes that VPC; move them there with side: source",
+			peer.SourceName, peer.Name, peer.Name)
+	}
+	peer.PeerRouting = RoutingConfig{Strategy: RoutingNone}
+	peer.SharedPeering = true
+	if owner == peer.SourceName {
+		log.Printf("[convert] %q -> %q is also listed by %q: creating the peering and routing %q only", peer.SourceName, peer.Name, peer.Name, peer.SourceName)
+		return peer, nil
+	}
+	if peer.ManualAcceptance {
+		return peer, fmt.Errorf("%q -> %q sets acceptance on a peering owned by %q; set it on that entry", peer.SourceName, peer.Name, owner)
+	}
+	log.Printf("[convert] %q -> %q is owned by %q: looking up its peering and routing %q only", peer.SourceName, peer.Name, owner, peer.SourceName)
+	peer.PeeringOwner = owner
+	return peer, nil
+}
+
+// Ways the stack of a source looks up a peering whose owner is another source's stack.
+const (
+	OwnedLookupVpc         = "vpc"          // Match the VPC pair and active status (default).
+	OwnedLookupRemoteState = "remote_state" // Read the pcx-id from the connections output of the owner's state.
+	OwnedLookupSSM         = "ssm"          // Read the pcx-id from an SSM parameter the owner's stack writes.
+)
+
+// OwnedPeeringsConfig selects how the stacks of sources that list each other share the pcx-id of
+// their peering.
+type OwnedPeeringsConfig struct {
+	Lookup      string            `yaml:"lookup,omitempty"`       // vpc (default), remote_state, or ssm.
+	RemoteState RemoteStateConfig `yaml:"remote_state,omitempty"` // State of the owner's stack, for remote_state.
+	SSMPrefix   string            `yaml:"ssm_prefix,omitempty"`   // Parameter path prefix, for ssm.
+}
+
+// RemoteStateConfig locates the state of another source's stack. "{source}" in config values is
+// replaced by the owning source, so one block covers every per-source state.
+type RemoteStateConfig struct {
+	Backend string            `yaml:"backend,omitempty"` // Backend type, e.g. s3 or remote.
+	Config  map[string]string `yaml:"config,omitempty"`  // Backend settings, e.g. bucket, key, and region.
+}
+
+// Validate checks the lookup and the settings it needs.
+func (c OwnedPeeringsConfig) Validate() error {
+	switch c.Lookup {
+	case "", OwnedLookupVpc:
+	case OwnedLookupRemoteState:
+		if c.RemoteState.Backend == "" {
+			return fmt.Errorf("owned_peerings.remote_state.backend is required for the remote_state lookup")
+		}
+	case OwnedLookupSSM:
+		if !strings.HasPrefix(c.SSMPrefix, "/") || strings.HasSuffix(c.SSMPrefix, "/") {
+			return fmt.Errorf("owned_peerings.ssm_prefix %q must start with / and not end with /", c.SSMPrefix)
+		}
+	default:
+		return fmt.Errorf("unknown owned_peerings.lookup %q (want vpc, remote_state, or ssm)", c.Lookup)
+	}
+	return nil
+}
+
+// OwnedPeeringParameter returns the SSM parameter holding the pcx-id of a peering the owner's stack
+// creates toward peer.
+func OwnedPeeringParameter(prefix, owner, peer string) string {
+	return prefix + "/" + owner + "/" + peer
+}
+
+// RemoteState returns the terraform_remote_state data source reading the state of the owner's
+// stack, named after the owner.
+func (c RemoteStateConfig) RemoteState(owner string) (string, map[string]interface{}) {
+	config := make(map[string]string, len(c.Config))
+	for key, value := range c.Config {
+		config[key] = strings.ReplaceAll(value, "{source}", owner)
+	}
+	return "owner_" + invalidIDChars.ReplaceAllString(owner, "_"), map[string]interface{}{
+		"backend": c.Backend,
+		"config":  config,
+	}
+}
+
+// ownerConnection returns the index of the owner's side of a shared connection among connections,
+// or -1 when the owner's stack is another one.
+func ownerConnection(connections []ConnectionResources, peer PeerConfig) int {
+	for j, c := range connections {
+		if c.Peer.SourceName == peer.PeeringOwner && c.Peer.Name == peer.SourceName && c.Peering.Peering != nil {
+			return j
+		}
+	}
+	return -1
+}
+
+// WireOwnedPeerings connects the two stacks of every connection both of its sources list. The
+// owner's stack publishes the pcx-id when the lookup is ssm; the other stack reads it by the
+// configured lookup, or references the peering directly when both are in the same stack (a synth
+// without CDKTF_SOURCE).
+func WireOwnedPeerings(stack cdktf.TerraformStack, namer Namer, connections []ConnectionResources, cfg OwnedPeeringsConfig) {
+	remoteStates := make(map[string]cdktf.TerraformDataSource)
+	for i := range connections {
+		c := &connections[i]
+		ctx := ConnectionNameContext(i, c.Peer)
+		if c.Peer.SharedPeering && c.Peer.PeeringOwner == "" && c.Peering.Peering != nil && cfg.Lookup == OwnedLookupSSM {
+			param := cdktf.NewTerraformResource(stack, jsii.String(namer.ID(ctx, KindOwnedPeeringParam)), &cdktf.TerraformResourceConfig{
+				TerraformResourceType: jsii.String("aws_ssm_parameter"),
+				Provider:              c.Core.SourceProvider,
+			})
+			param.AddOverride(jsii.String("name"), OwnedPeeringParameter(cfg.SSMPrefix, c.Peer.SourceName, c.Peer.Name))
+			param.AddOverride(jsii.String("description"), fmt.Sprintf("pcx-id of the peering %s owns toward %s", c.Peer.SourceName, c.Peer.Name))
+			param.AddOverride(jsii.String("type"), "String")
+			param.AddOverride(jsii.String("value"), c.Peering.Peering.Id())
+			c.Peering.OwnerParam = param
+		}
+		if c.Peer.PeeringOwner == "" || c.Peering.Data == nil {
+			continue
+		}
+
+		var id *string
+		if j := ownerConnection(connections, c.Peer); j >= 0 {
+			id = connections[j].Peering.Peering.Id()
+		} else {
+			switch cfg.Lookup {
+			case OwnedLookupRemoteState:
+				name, body := cfg.RemoteState.RemoteState(c.Peer.PeeringOwner)
+				state, ok := remoteStates[name]
+				if !ok {
+					state = cdktf.NewTerraformDataSource(stack, jsii.String(name), &cdktf.TerraformResourceConfig{
+						TerraformResourceType: jsii.String("terraform_remote_state"),
+					})
+					for attr, value := range body {
+						state.AddOverride(jsii.String(attr), value)
+					}
+					remoteStates[name] = state
+				}
+				c.Peering.OwnerLookup = state
+				id = jsii.String(fmt.Sprintf("${data.terraform_remote_state.%s.outputs.%s[%q].peering_id}",
+					*state.FriendlyUniqueId(), ConnectionsOutputID, c.Peer.PeeringOwner+"/"+c.Peer.SourceName))
+			case OwnedLookupSSM:
+				// Read through the peer provider, which assumes the owner's role in its region.
+				param := cdktf.NewTerraformDataSource(stack, jsii.String(namer.ID(ctx, KindOwnedPeeringParam)), &cdktf.TerraformResourceConfig{
+					TerraformResourceType: jsii.String("aws_ssm_parameter"),
+					Provider:              c.Core.PeerProvider,
+				})
+				param.AddOverride(jsii.String("name"), OwnedPeeringParameter(cfg.SSMPrefix, c.Peer.PeeringOwner, c.Peer.SourceName))
+				c.Peering.OwnerLookup = param
+				id = param.GetStringAttribute(jsii.String("value"))
+			default:
+				continue
+			}
+		}
+		c.Peering.Data.AddOverride(jsii.String("id"), id)
+	}
+}
@@ -0,0 +1,197 @@
+package peering
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// TestPeeringOwners tests which source owns a peering both of its sources list.
+func TestPeeringOwners(t *testing.T) {
+	unmanaged := false
+	cfg := YAMLConfig{
+		Peers: map[string]YAMLPeer{"a": {VpcID: "vpc-1"}, "b": {VpcID: "vpc-2"}, "c": {VpcID: "vpc-3"}, "d": {VpcID: "vpc-4"}},
+		PeeringMatrix: map[string][]MatrixEntry{
+			"a": {{Peer: "b"}, {Peer: "c"}, {Peer: "d", ManagePeering: &unmanaged, PeeringID: "pcx-1"}},
+			"b": {{Peer: "a"}},
+			"c": {{Peer: "a", Owner: "c"}},
+			"d": {{Peer: "a"}},
+		},
+	}
+	owners, err := PeeringOwners(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"a|b": "a", "a|c": "c"}
+	if !reflect.DeepEqual(owners, want) {
+		t.Errorf("expected %v, got %v", want, owners)
+	}
+
+	cfg.PeeringMatrix["a"][1].Owner = "a"
+	if _, err := PeeringOwners(cfg); err == nil {
+		t.Error("expected an error for entries naming different owners")
+	}
+}
+
+// TestApplyPeeringOwner tests how a connection listed by both sources is split between their stacks.
+func TestApplyPeeringOwner(t *testing.T) {
+	peer := PeerConfig{SourceName: "b", Name: "a", SourceRouting: RoutingConfig{Strategy: RoutingAll}, PeerRouting: RoutingConfig{Strategy: RoutingMain}}
+	owned, err := ApplyPeeringOwner(peer, "b")
+	if err != nil || owned.PeeringOwner != "" || owned.PeerRouting.Strategy != RoutingNone || owned.SourceRouting.Strategy != RoutingAll {
+		t.Errorf("expected the owner to create the peering and route its own VPC, got %+v, %v", owned, err)
+	}
+	other, err := ApplyPeeringOwner(peer, "a")
+	if err != nil || other.PeeringOwner != "a" || !other.LooksUpPeering() || other.PeerRouting.Strategy != RoutingNone {
+		t.Errorf("expected the other source to look up the peering, got %+v, %v", other, err)
+	}
+
+	manual := peer
+	manual.ManualAcceptance = true
+	if _, err := ApplyPeeringOwner(manual, "a"); err == nil {
+		t.Error("expected an error for acceptance set on the entry that does not own the peering")
+	}
+	extra := peer
+	extra.PeerExtraCidrs = []string{"100.64.0.0/16"}
+	if _, err := ApplyPeeringOwner(extra, "b"); err == nil {
+		t.Error("expected an error for extra routes in the other source's VPC")
+	}
+}
+
+// TestConvertToPeerConfigsOwnership tests that a per-source synth of the non-owning source looks up
+// the peering.
+func TestConvertToPeerConfigsOwnership(t *testing.T) {
+	cfg := YAMLConfig{
+		Peers: map[string]YAMLPeer{"a": {VpcID: "vpc-1"}, "b": {VpcID: "vpc-2"}},
+		PeeringMatrix: map[string][]MatrixEntry{
+			"a": {{Peer: "b"}},
+			"b": {{Peer: "a"}},
+		},
+	}
+	peers := ConvertToPeerConfigs(cfg, "b")
+	if len(peers) != 1 || peers[0].PeeringOwner != "a" {
+		t.Fatalf("expected b/a to be owned by a, got %+v", peers)
+	}
+	addresses := ConnectionAddresses(LegacyNamer{}, ConnectionNameContext(0, peers[0]), peers[0])
+	if _, ok := addresses[KindPeering]; ok {
+		t.Errorf("expected no peering for the non-owning source, got %v", addresses)
+	}
+	if _, ok := addresses[KindPeerMainRoute]; ok {
+		t.Errorf("expected no routes in the owner's VPC, got %v", addresses)
+	}
+}
+
+// TestOwnedPeeringsConfig tests the lookup settings and the remote state of an owner.
+func TestOwnedPeeringsConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     OwnedPeeringsConfig
+		wantErr bool
+	}{
+		{"default", OwnedPeeringsConfig{}, false},
+		{"unknown", OwnedPeeringsConfig{Lookup: "dns"}, true},
+		{"remote state without backend", OwnedPeeringsConfig{Lookup: OwnedLookupRemoteState}, true},
+		{"ssm", OwnedPeeringsConfig{Lookup: OwnedLookupSSM, SSMPrefix: "/peering/pcx"}, false},
+		{"ssm without prefix", OwnedPeeringsConfig{Lookup: OwnedLookupSSM}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+
+	rs := RemoteStateConfig{Backend: "s3", Config: map[string]string{"bucket": "state", "key": "peering/{source}.tfstate"}}
+	name, body := rs.RemoteState("prod-peer")
+	want := map[string]interface{}{"backend": "s3", "config": map[string]string{"bucket": "state", "key": "peering/prod-peer.tfstate"}}
+	if name != "owner_prod-peer" || !reflect.DeepEqual(body, want) {
+		t.Errorf("unexpected remote state %s: %v", name, body)
+	}
+	if got := OwnedPeeringParameter("/peering/pcx", "a", "b"); got != "/peering/pcx/a/b" {
+		t.Errorf("unexpected parameter %s", got)
+	}
+}
+
+// TestRolePoliciesSharedPeering tests that the owner's role may publish and read the pcx-id.
+func TestRolePoliciesSharedPeering(t *testing.T) {
+	owner := "arn:aws:iam::111111111111:role/peering"
+	peers := []PeerConfig{{
+		SourceName: "b", Name: "a", PeeringOwner: "a", SharedPeering: true,
+		SourceVpcID: "vpc-2", SourceRoleArn: "arn:aws:iam::222222222222:role/peering",
+		PeerVpcID: "vpc-1", PeerRoleArn: owner,
+	}}
+	opts := PolicyOptions{OwnedPeerings: OwnedPeeringsConfig{Lookup: OwnedLookupSSM, SSMPrefix: "/peering/pcx"}}
+	policies := RolePolicies(peers, opts)
+	if findStatement(policies[owner], "ShareOwnedPeeringIDs") == nil {
+		t.Error("expected the owner's role to share the pcx-id")
+	}
+	if findStatement(policies["arn:aws:iam::222222222222:role/peering"], "ShareOwnedPeeringIDs") != nil {
+		t.Error("unexpected SSM access for the other source's role")
+	}
+	if findStatement(policies[owner], "ManagePeeringRoutes") != nil {
+		t.Error("unexpected route changes in the owner's VPC from the other source's stack")
+	}
+}
+
+// TestWireOwnedPeerings tests that the owner's pcx-id is read through data source constructs, one
+// remote state per owner shared by its connections, and one SSM parameter per connection.
+func TestWireOwnedPeerings(t *testing.T) {
+	newConnections := func(stack cdktf.TerraformStack) []ConnectionResources {
+		var connections []ConnectionResources
+		for i, source := range []string{"dev", "stage"} {
+			peer := PeerConfig{SourceName: source, Name: "prod", PeeringOwner: "prod", SharedPeering: true}
+			data := cdktf.NewTerraformDataSource(stack, jsii.String(LegacyNamer{}.ID(ConnectionNameContext(i, peer), KindPeeringData)), &cdktf.TerraformResourceConfig{
+				TerraformResourceType: jsii.String("aws_vpc_peering_connection"),
+			})
+			connections = append(connections, ConnectionResources{Peer: peer, Peering: PeeringResources{Data: data}})
+		}
+		return connections
+	}
+	type document struct {
+		Data map[string]map[string]map[string]interface{} `json:"data"`
+	}
+	synth := func(stack cdktf.TerraformStack) document {
+		var doc document
+		if err := json.Unmarshal([]byte(*cdktf.Testing_Synth(stack, nil)), &doc); err != nil {
+			t.Fatal(err)
+		}
+		return doc
+	}
+
+	stack := cdktf.NewTerraformStack(cdktf.Testing_App(nil), jsii.String("peering"))
+	connections := newConnections(stack)
+	WireOwnedPeerings(stack, LegacyNamer{}, connections, OwnedPeeringsConfig{
+		Lookup:      OwnedLookupRemoteState,
+		RemoteState: RemoteStateConfig{Backend: "s3", Config: map[string]string{"key": "{source}.tfstate"}},
+	})
+	if connections[0].Peering.OwnerLookup == nil || connections[0].Peering.OwnerLookup != connections[1].Peering.OwnerLookup {
+		t.Fatal("expected both connections to record the owner's remote state")
+	}
+	doc := synth(stack)
+	if states := doc.Data["terraform_remote_state"]; len(states) != 1 || states["owner_prod"]["backend"] != "s3" {
+		t.Errorf("expected one remote state for the owner, got %v", states)
+	}
+	for _, c := range connections {
+		want := `${data.terraform_remote_state.owner_prod.outputs.connections["prod/` + c.Peer.SourceName + `"].peering_id}`
+		if got := doc.Data["aws_vpc_peering_connection"][*c.Peering.Data.FriendlyUniqueId()]["id"]; got != want {
+			t.Errorf("%s: peering id = %v, want %s", c.Peer.SourceName, got, want)
+		}
+	}
+
+	stack = cdktf.NewTerraformStack(cdktf.Testing_App(nil), jsii.String("peering"))
+	connections = newConnections(stack)
+	WireOwnedPeerings(stack, LegacyNamer{}, connections, OwnedPeeringsConfig{Lookup: OwnedLookupSSM, SSMPrefix: "/peering/pcx"})
+	doc = synth(stack)
+	params := doc.Data["aws_ssm_parameter"]
+	if len(params) != 2 {
+		t.Fatalf("expected a parameter lookup per connection, got %v", params)
+	}
+	lookup := *connections[0].Peering.OwnerLookup.FriendlyUniqueId()
+	if params[lookup]["name"] != "/peering/pcx/prod/dev" {
+		t.Errorf("unexpected parameter lookup: %v", params[lookup])
+	}
+	if got, want := doc.Data["aws_vpc_peering_connection"][*connections[0].Peering.Data.FriendlyUniqueId()]["id"], "${data.aws_ssm_parameter."+lookup+".value}"; got != want {
+		t.Errorf("peering id = %v, want %s", got, want)
+	}
+}
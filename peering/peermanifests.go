@@ -0,0 +1,78 @@
+package peering
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Peer Manifests
+// -------------------------------------------------------------------------------------------------
+
+// PeerManifest is a peers document another team publishes for its VPCs, listed in peer_manifests. It
+// has the shape of the config's peers section:
+//
+//	peers:
+//	  team-x-prod: { vpc_id: vpc-0abc, region: us-east-1, role_arn: "arn:aws:iam::...:role/Peering" }
+type PeerManifest struct {
+	Peers map[string]YAMLPeer `yaml:"peers"` // Map of peer names to YAMLPeer definitions.
+}
+
+// ObjectReader reads S3 objects by s3:// URL. Implemented by the AWS CLI and by fakes in tests.
+type ObjectReader interface {
+	ReadObject(url string) ([]byte, error)
+}
+
+// ReadObject downloads an S3 object.
+func (c *AWSCLI) ReadObject(url string) ([]byte, error) {
+	return c.Output("s3", "cp", url, "-")
+}
+
+// ApplyPeerManifests adds the peers of every manifest in peer_manifests, so VPCs owned by other teams
+// are referenced rather than copied. Manifest peers are taken as published: peer_defaults do not apply
+// to them. A peer the config declares itself keeps its declaration, which is logged; two manifests
+// declaring the same peer, or a manifest peer without vpc_id, are errors.
+func ApplyPeerManifests(cfg *YAMLConfig, s3 ObjectReader) error {
+	if cfg.Peers == nil {
+		cfg.Peers = make(map[string]YAMLPeer)
+	}
+	from := make(map[string]string)
+	for _, url := range cfg.PeerManifests {
+		data, err := ReadDocument(url, s3)
+		if err == nil {
+			err = VerifyDocument(url, data, os.Getenv(ConfigVerifyEnvVar), s3)
+		}
+		if err != nil {
+			return fmt.Errorf("peer manifest %s: %w", url, err)
+		}
+		var manifest PeerManifest
+		if err := yaml.Unmarshal(data, &manifest); err != nil {
+			return WithExitCode(ExitConfig, fmt.Errorf("peer manifest %s: %w", url, err))
+		}
+		added := 0
+		for _, name := range sortedPeerNames(YAMLConfig{Peers: manifest.Peers}) {
+			peer := manifest.Peers[name]
+			if previous, ok := from[name]; ok {
+				return WithExitCode(ExitValidation, fmt.Errorf("peer %q is declared by both peer manifests %s and %s", name, previous, url))
+			}
+			from[name] = url
+			if peer.VpcID == "" {
+				return WithExitCode(ExitValidation, fmt.Errorf("peer manifest %s: peer %q has no vpc_id", url, name))
+			}
+			if err := ValidateRegion(peer.Region, cfg.AllowedRegions); err != nil {
+				return WithExitCode(ExitValidation, fmt.Errorf("peer manifest %s: peer %q: %w", url, name, err))
+			}
+			if _, declared := cfg.Peers[name]; declared {
+				log.Printf("[config] Peer manifest %s declares %q, which the config declares too; keeping the config's declaration", url, name)
+				continue
+			}
+			cfg.Peers[name] = peer
+			added++
+		}
+		log.Printf("[config] Peer manifest %s: added %d of %d peer(s)", url, added, len(manifest.Peers))
+	}
+	return nil
+}
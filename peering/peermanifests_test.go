@@ -0,0 +1,67 @@
+package peering
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeObjects returns fixed S3 objects by URL.
+type fakeObjects map[string]string
+
+func (f fakeObjects) ReadObject(url string) ([]byte, error) { return []byte(f[url]), nil }
+
+// TestApplyPeerManifests tests that manifest peers from S3 and local files are merged and the
+// config's own declarations are kept.
+func TestApplyPeerManifests(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "peers.yaml")
+	if err := os.WriteFile(path, []byte("peers:\n  team-y: { vpc_id: vpc-y, region: eu-west-1 }\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	objects := fakeObjects{"s3://manifests/team-x/peers.yaml": `
+peers:
+  team-x-prod: { vpc_id: vpc-x, region: us-east-1, role_arn: "arn:aws:iam::111111111111:role/Peering" }
+  hub: { vpc_id: vpc-other }
+`}
+	cfg := YAMLConfig{
+		Peers:         map[string]YAMLPeer{"hub": {VpcID: "vpc-hub"}},
+		PeerManifests: []string{"s3://manifests/team-x/peers.yaml", path},
+	}
+	if err := ApplyPeerManifests(&cfg, objects); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]YAMLPeer{
+		"hub":         {VpcID: "vpc-hub"},
+		"team-x-prod": {VpcID: "vpc-x", Region: "us-east-1", RoleArn: "arn:aws:iam::111111111111:role/Peering"},
+		"team-y":      {VpcID: "vpc-y", Region: "eu-west-1"},
+	}
+	if !reflect.DeepEqual(cfg.Peers, want) {
+		t.Errorf("expected %v, got %v", want, cfg.Peers)
+	}
+}
+
+// TestApplyPeerManifestsErrors tests that conflicting and incomplete manifests are rejected.
+func TestApplyPeerManifestsErrors(t *testing.T) {
+	objects := fakeObjects{
+		"s3://m/a.yaml":   "peers:\n  shared: { vpc_id: vpc-a }\n",
+		"s3://m/b.yaml":   "peers:\n  shared: { vpc_id: vpc-b }\n",
+		"s3://m/bad.yaml": "peers:\n  novpc: { region: us-east-1 }\n",
+	}
+	cases := map[string]struct {
+		manifests []string
+		want      string
+	}{
+		"duplicate": {[]string{"s3://m/a.yaml", "s3://m/b.yaml"}, "declared by both"},
+		"no vpc":    {[]string{"s3://m/bad.yaml"}, "has no vpc_id"},
+		"scheme":    {[]string{"ftp://example.com/peers.yaml"}, "unsupported scheme"},
+	}
+	for name, tc := range cases {
+		cfg := YAMLConfig{PeerManifests: tc.manifests}
+		err := ApplyPeerManifests(&cfg, objects)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: expected error containing %q, got %v", name, tc.want, err)
+		}
+	}
+}
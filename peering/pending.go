@@ -0,0 +1,218 @@
+package peering
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Pending Peering Requests
+// -------------------------------------------------------------------------------------------------
+
+// PeeringRequestLifetime is how long AWS keeps a peering request in pending-acceptance before it
+// expires.
+const PeeringRequestLifetime = 7 * 24 * time.Hour
+
+// PeeringRequest is a peering request awaiting acceptance.
+type PeeringRequest struct {
+	PeeringID     string    // pcx-id of the request.
+	AccepterVpcID string    // VPC the request was sent to.
+	Expires       time.Time // When AWS expires the request.
+}
+
+// PendingClient lists peering requests in one account and region. Implemented by the AWS CLI and
+// by fakes in tests.
+type PendingClient interface {
+	// PendingPeerings returns the requests from a VPC that are awaiting acceptance.
+	PendingPeerings(requesterVpcID string) ([]PeeringRequest, error)
+}
+
+// PendingPeering is a managed connection whose peering request has not been accepted yet.
+type PendingPeering struct {
+	Connection string         // Connection key.
+	Accepter   string         // Account that has to accept, empty if unknown.
+	Request    PeeringRequest // The pending request.
+}
+
+// Remaining returns the time left before the request expires.
+func (p PendingPeering) Remaining(now time.Time) time.Duration {
+	return p.Request.Expires.Sub(now)
+}
+
+// Age returns how long the request has been pending.
+func (p PendingPeering) Age(now time.Time) time.Duration {
+	return PeeringRequestLifetime - p.Remaining(now)
+}
+
+// FindPendingPeerings lists the connections whose peering request is stuck in pending-acceptance,
+// reading the requests with the source side's credentials. Peerings created elsewhere are skipped,
+// since this tool did not request them.
+func FindPendingPeerings(peers []PeerConfig, connect func(region, roleArn string) (PendingClient, error)) ([]PendingPeering, error) {
+	requests := make(map[string][]PeeringRequest)
+	var pending []PendingPeering
+	for _, peer := range peers {
+		if peer.LooksUpPeering() {
+			continue
+		}
+		key := ResolveRegion(peer.SourceRegion) + "|" + peer.SourceVpcID
+		if _, ok := requests[key]; !ok {
+			client, err := connect(peer.SourceRegion, peer.SourceRoleArn)
+			if err != nil {
+				return nil, err
+			}
+			found, err := client.PendingPeerings(peer.SourceVpcID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list peering requests of %s: %w", peer.SourceVpcID, err)
+			}
+			requests[key] = found
+		}
+		for _, req := range requests[key] {
+			if req.AccepterVpcID == peer.PeerVpcID {
+				pending = append(pending, PendingPeering{Connection: ConnectionKey(peer), Accepter: PeerAccount(peer), Request: req})
+			}
+		}
+	}
+	return pending, nil
+}
+
+// formatDays renders a duration as days and hours, e.g. "2d05h".
+func formatDays(d time.Duration) string {
+	if d < 0 {
+		return "expired"
+	}
+	hours := int(d.Hours())
+	return fmt.Sprintf("%dd%02dh", hours/24, hours%24)
+}
+
+// PrintPendingPeerings writes the pending requests as a table.
+func PrintPendingPeerings(w io.Writer, pending []PendingPeering, now time.Time) {
+	if len(pending) == 0 {
+		fmt.Fprintln(w, "No peering requests pending acceptance.")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CONNECTION\tPEERING\tACCEPTER ACCOUNT\tAGE\tREMAINING\tEXPIRES")
+	for _, p := range pending {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", p.Connection, p.Request.PeeringID, orUnknown(p.Accepter),
+			formatDays(p.Age(now)), formatDays(p.Remaining(now)), p.Request.Expires.UTC().Format(time.RFC3339))
+	}
+	tw.Flush()
+}
+
+// PendingNotification returns the subject and body of the notification about pending requests.
+func PendingNotification(pending []PendingPeering, now time.Time) (string, string) {
+	subject := fmt.Sprintf("%d VPC peering request(s) awaiting acceptance", len(pending))
+	var body strings.Builder
+	fmt.Fprintf(&body, "These peering requests expire unless accepted (requests expire %s after creation):\n\n",
+		formatDays(PeeringRequestLifetime))
+	PrintPendingPeerings(&body, pending, now)
+	return subject, body.String()
+}
+
+// -------------------------------------------------------------------------------------------------
+// AWS CLI Implementation
+// -------------------------------------------------------------------------------------------------
+
+// PendingPeerings returns the requests from a VPC in pending-acceptance.
+func (c *AWSCLI) PendingPeerings(requesterVpcID string) ([]PeeringRequest, error) {
+	var out struct {
+		VpcPeeringConnections []struct {
+			VpcPeeringConnectionID string `json:"VpcPeeringConnectionId"`
+			ExpirationTime         string `json:"ExpirationTime"`
+			AccepterVpcInfo        struct {
+				VpcID string `json:"VpcId"`
+			} `json:"AccepterVpcInfo"`
+		} `json:"VpcPeeringConnections"`
+	}
+	if err := c.Run(&out, "ec2", "describe-vpc-peering-connections", "--filters",
+		"Name=requester-vpc-info.vpc-id,Values="+requesterVpcID,
+		"Name=status-code,Values=pending-acceptance"); err != nil {
+		return nil, err
+	}
+	requests := make([]PeeringRequest, 0, len(out.VpcPeeringConnections))
+	for _, pcx := range out.VpcPeeringConnections {
+		expires, err := time.Parse(time.RFC3339, pcx.ExpirationTime)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected expiration time of %s: %w", pcx.VpcPeeringConnectionID, err)
+		}
+		requests = append(requests, PeeringRequest{PeeringID: pcx.VpcPeeringConnectionID, AccepterVpcID: pcx.AccepterVpcInfo.VpcID, Expires: expires})
+	}
+	return requests, nil
+}
+
+// Publish sends a message to an SNS topic.
+func (c *AWSCLI) Publish(topicArn, subject, message string) error {
+	var out struct {
+		MessageID string `json:"MessageId"`
+	}
+	return c.Run(&out, "sns", "publish", "--topic-arn", topicArn, "--subject", subject, "--message", message)
+}
+
+// -------------------------------------------------------------------------------------------------
+// pending-report
+// -------------------------------------------------------------------------------------------------
+
+// runPendingReport lists the managed connections whose peering request awaits acceptance, and
+// optionally notifies an SNS topic about them.
+func runPendingReport(args []string) error {
+	fs := flag.NewFlagSet("pending-report", flag.ContinueOnError)
+	topic := fs.String("sns", "", "ARN of an SNS topic to notify when requests are pending")
+	within := fs.Duration("within", 0, "only report requests expiring within this duration (0 for all)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, peers := loadSourcePeers(sourceArg(fs))
+	clients := make(map[string]PendingClient)
+	connect := func(region, roleArn string) (PendingClient, error) {
+		key := region + "|" + roleArn
+		if c, ok := clients[key]; ok {
+			return c, nil
+		}
+		c, err := NewAWSCLI(region, roleArn, cfg.Provider)
+		if err != nil {
+			return nil, err
+		}
+		clients[key] = c
+		return c, nil
+	}
+
+	pending, err := FindPendingPeerings(peers, connect)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if *within > 0 {
+		var soon []PendingPeering
+		for _, p := range pending {
+			if p.Remaining(now) <= *within {
+				soon = append(soon, p)
+			}
+		}
+		pending = soon
+	}
+	PrintPendingPeerings(os.Stdout, pending, now)
+
+	if *topic == "" || len(pending) == 0 {
+		return nil
+	}
+	parts := strings.Split(*topic, ":")
+	if len(parts) != 6 {
+		return fmt.Errorf("invalid SNS topic ARN %q", *topic)
+	}
+	sns, err := NewAWSCLI(parts[3], "", cfg.Provider)
+	if err != nil {
+		return err
+	}
+	subject, body := PendingNotification(pending, now)
+	if err := sns.Publish(*topic, subject, body); err != nil {
+		return fmt.Errorf("failed to notify %s: %w", *topic, err)
+	}
+	fmt.Printf("Notified %s\n", *topic)
+	return nil
+}
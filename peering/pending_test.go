@@ -0,0 +1,56 @@
+package peering
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakePending returns fixed requests per requester VPC and counts the lookups.
+type fakePending struct {
+	requests map[string][]PeeringRequest
+	calls    *int
+}
+
+func (f fakePending) PendingPeerings(vpcID string) ([]PeeringRequest, error) {
+	*f.calls++
+	return f.requests[vpcID], nil
+}
+
+// TestFindPendingPeerings tests that pending requests are matched to connections by accepter VPC.
+func TestFindPendingPeerings(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	calls := 0
+	client := fakePending{calls: &calls, requests: map[string][]PeeringRequest{
+		"vpc-1": {
+			{PeeringID: "pcx-1", AccepterVpcID: "vpc-2", Expires: now.Add(36 * time.Hour)},
+			{PeeringID: "pcx-9", AccepterVpcID: "vpc-9", Expires: now.Add(time.Hour)},
+		},
+	}}
+	peers := []PeerConfig{
+		{SourceName: "dev", Name: "prod", SourceVpcID: "vpc-1", PeerVpcID: "vpc-2", PeerRoleArn: "arn:aws:iam::222222222222:role/r"},
+		{SourceName: "dev", Name: "qa", SourceVpcID: "vpc-1", PeerVpcID: "vpc-3"},
+		{SourceName: "dev", Name: "ext", SourceVpcID: "vpc-1", PeerVpcID: "vpc-9", ExternalPeeringID: "pcx-9"},
+	}
+	pending, err := FindPendingPeerings(peers, func(string, string) (PendingClient, error) { return client, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Errorf("expected one lookup per requester VPC, got %d", calls)
+	}
+	if len(pending) != 1 || pending[0].Connection != "dev/prod" || pending[0].Accepter != "222222222222" {
+		t.Fatalf("unexpected pending peerings: %+v", pending)
+	}
+	if got := formatDays(pending[0].Age(now)); got != "5d12h" {
+		t.Errorf("age = %s, want 5d12h", got)
+	}
+	if got := formatDays(pending[0].Remaining(now)); got != "1d12h" {
+		t.Errorf("remaining = %s, want 1d12h", got)
+	}
+
+	subject, body := PendingNotification(pending, now)
+	if subject != "1 VPC peering request(s) awaiting acceptance" || !strings.Contains(body, "pcx-1") {
+		t.Errorf("unexpected notification %q:\n%s", subject, body)
+	}
+}
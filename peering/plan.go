@@ -0,0 +1,333 @@
+package peering
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Terraform Plan Reading
+// -------------------------------------------------------------------------------------------------
+
+// Plan change actions, as summarized per resource.
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionReplace = "replace"
+)
+
+// PlanChange is a single resource change from a Terraform JSON plan.
+type PlanChange struct {
+	Address string                 // Full address, including any instance key.
+	Type    string                 // Terraform resource type.
+	Name    string                 // Terraform resource name (logical ID).
+	Action  string                 // One of the Action constants.
+	Before  map[string]interface{} // Attributes before the change (nil on create).
+	After   map[string]interface{} // Known attributes after the change (nil on delete).
+}
+
+// ResourceAddress returns the address without instance keys.
+func (c PlanChange) ResourceAddress() string {
+	return c.Type + "." + c.Name
+}
+
+// rawPlan mirrors the parts of the "terraform show -json" plan format that are read.
+type rawPlan struct {
+	ResourceChanges []struct {
+		Address string `json:"address"`
+		Module  string `json:"module_address"`
+		Mode    string `json:"mode"`
+		Type    string `json:"type"`
+		Name    string `json:"name"`
+		Change  struct {
+			Actions []string               `json:"actions"`
+			Before  map[string]interface{} `json:"before"`
+			After   map[string]interface{} `json:"after"`
+		} `json:"change"`
+	} `json:"resource_changes"`
+}
+
+// planAction collapses Terraform's action list into one action, or "" for no-op and read.
+func planAction(actions []string) string {
+	switch {
+	case len(actions) == 2:
+		return ActionReplace
+	case len(actions) == 1 && (actions[0] == ActionCreate || actions[0] == ActionUpdate || actions[0] == ActionDelete):
+		return actions[0]
+	}
+	return ""
+}
+
+// ParsePlanJSON decodes a JSON plan, keeping changes to managed root-module resources.
+func ParsePlanJSON(data []byte) ([]PlanChange, error) {
+	var raw rawPlan
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse plan: %w", err)
+	}
+	var changes []PlanChange
+	for _, rc := range raw.ResourceChanges {
+		action := planAction(rc.Change.Actions)
+		if rc.Mode != "managed" || rc.Module != "" || action == "" {
+			continue
+		}
+		changes = append(changes, PlanChange{
+			Address: rc.Address,
+			Type:    rc.Type,
+			Name:    rc.Name,
+			Action:  action,
+			Before:  rc.Change.Before,
+			After:   rc.Change.After,
+		})
+	}
+	return changes, nil
+}
+
+// ReadPlan reads a plan given either as JSON or as a binary plan file, which is converted with
+// "terraform show -json" in the stack directory.
+func ReadPlan(path, dir string) ([]PlanChange, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		cmd := exec.Command("terraform", "show", "-json", abs)
+		cmd.Dir = dir
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		if data, err = cmd.Output(); err != nil {
+			return nil, fmt.Errorf("terraform show -json %s in %s: %v: %s", path, dir, err, strings.TrimSpace(stderr.String()))
+		}
+	}
+	return ParsePlanJSON(data)
+}
+
+// -------------------------------------------------------------------------------------------------
+// Plan Summary
+// -------------------------------------------------------------------------------------------------
+
+// ConnectionPlan summarizes the planned changes of one configured connection.
+type ConnectionPlan struct {
+	Key           string         // Connection key (source/peer).
+	Counts        map[string]int // Number of resource changes per action.
+	AddedRoutes   []string       // "<vpc> <cidr>" of routes the plan creates.
+	RemovedRoutes []string       // "<vpc> <cidr>" of routes the plan destroys.
+}
+
+// PlanSummary groups the changes of a plan by connection.
+type PlanSummary struct {
+	Connections []ConnectionPlan // Connections with at least one change, by key.
+	Unmatched   []PlanChange     // Changes to resources no configured connection accounts for.
+	Warnings    []string         // Changes that remove connectivity.
+}
+
+// planOwner identifies the connection and VPC a managed address belongs to.
+type planOwner struct {
+	key string
+	vpc string // VPC whose route table holds the route; empty for non-route resources.
+}
+
+// BuildPlanSummary matches plan changes to the expected addresses of every connection.
+func BuildPlanSummary(namer Namer, peers []PeerConfig, changes []PlanChange) PlanSummary {
+	owners := make(map[string]planOwner)
+	for i, peer := range peers {
+		key := ConnectionKey(peer)
+		for kind, address := range ConnectionAddresses(namer, ConnectionNameContext(i, peer), peer) {
+			owner := planOwner{key: key}
+			switch {
+			case strings.HasPrefix(kind, "source-"):
+				owner.vpc = peer.SourceVpcID
+			case strings.HasPrefix(kind, "peer-"):
+				owner.vpc = peer.PeerVpcID
+			}
+			owners[address] = owner
+		}
+	}
+
+	byKey := make(map[string]*ConnectionPlan)
+	var summary PlanSummary
+	removed := make(map[string][]string) // Removed CIDRs by VPC (or route table when unmatched).
+	for _, c := range changes {
+		owner, ok := owners[c.ResourceAddress()]
+		if c.Type == "aws_vpc_peering_connection" && (c.Action == ActionDelete || c.Action == ActionReplace) {
+			verb, of := "destroys", owner.key
+			if c.Action == ActionReplace {
+				verb = "replaces"
+			}
+			if !ok {
+				of = "a connection no longer in config"
+			}
+			summary.Warnings = append(summary.Warnings, fmt.Sprintf(
+				"this change %s peering connection %s of %s", verb, orUnknown(stringAttr(c.Before, "id")), of))
+		}
+		if !ok {
+			summary.Unmatched = append(summary.Unmatched, c)
+			// Routes of removed connections are only known by their route table.
+			if c.Action == ActionDelete && c.Type == "aws_route" {
+				table := stringAttr(c.Before, "route_table_id")
+				removed[table] = append(removed[table], stringAttr(c.Before, "destination_cidr_block"))
+			}
+			continue
+		}
+		cp, ok := byKey[owner.key]
+		if !ok {
+			cp = &ConnectionPlan{Key: owner.key, Counts: make(map[string]int)}
+			byKey[owner.key] = cp
+		}
+		cp.Counts[c.Action]++
+
+		switch {
+		case c.Type == "aws_route" && c.Action == ActionCreate:
+			cp.AddedRoutes = append(cp.AddedRoutes, owner.vpc+" "+stringAttr(c.After, "destination_cidr_block"))
+		case c.Type == "aws_route" && c.Action == ActionDelete:
+			cidr := stringAttr(c.Before, "destination_cidr_block")
+			cp.RemovedRoutes = append(cp.RemovedRoutes, owner.vpc+" "+cidr)
+			removed[owner.vpc] = append(removed[owner.vpc], cidr)
+		}
+	}
+
+	for owner, cidrs := range removed {
+		sort.Strings(cidrs)
+		summary.Warnings = append(summary.Warnings, fmt.Sprintf("this change removes routes for %s (%s)", orUnknown(owner), strings.Join(cidrs, ", ")))
+	}
+	sort.Strings(summary.Warnings)
+
+	for _, cp := range byKey {
+		sort.Strings(cp.AddedRoutes)
+		sort.Strings(cp.RemovedRoutes)
+		summary.Connections = append(summary.Connections, *cp)
+	}
+	sort.Slice(summary.Connections, func(i, j int) bool { return summary.Connections[i].Key < summary.Connections[j].Key })
+	return summary
+}
+
+// PrintPlanSummary writes the summary as a table followed by route changes and warnings.
+func PrintPlanSummary(w io.Writer, summary PlanSummary) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CONNECTION\tCREATE\tUPDATE\tREPLACE\tDESTROY")
+	for _, cp := range summary.Connections {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\n", cp.Key,
+			cp.Counts[ActionCreate], cp.Counts[ActionUpdate], cp.Counts[ActionReplace], cp.Counts[ActionDelete])
+	}
+	tw.Flush()
+
+	for _, cp := range summary.Connections {
+		for _, route := range cp.AddedRoutes {
+			fmt.Fprintf(w, "\n+ route   %s (%s)", route, cp.Key)
+		}
+		for _, route := range cp.RemovedRoutes {
+			fmt.Fprintf(w, "\n- route   %s (%s)", route, cp.Key)
+		}
+	}
+	for _, c := range summary.Unmatched {
+		fmt.Fprintf(w, "\nNOT IN CONFIG   %s %s", c.Action, c.Address)
+	}
+	for _, warning := range summary.Warnings {
+		fmt.Fprintf(w, "\nWARNING   %s", warning)
+	}
+	fmt.Fprintln(w)
+}
+
+// PrintPlanComment writes the summary as Markdown for posting on a merge request.
+func PrintPlanComment(w io.Writer, summary PlanSummary) {
+	fmt.Fprintln(w, "### VPC peering plan")
+	fmt.Fprintln(w)
+	if len(summary.Connections) == 0 && len(summary.Unmatched) == 0 {
+		fmt.Fprintln(w, "No changes to peering connections or routes.")
+		return
+	}
+
+	for _, warning := range summary.Warnings {
+		fmt.Fprintf(w, "> :warning: **%s**\n", warning)
+	}
+	if len(summary.Warnings) > 0 {
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintln(w, "| Connection | Create | Update | Replace | Destroy |")
+	fmt.Fprintln(w, "|---|---:|---:|---:|---:|")
+	for _, cp := range summary.Connections {
+		fmt.Fprintf(w, "| `%s` | %d | %d | %d | %d |\n", cp.Key,
+			cp.Counts[ActionCreate], cp.Counts[ActionUpdate], cp.Counts[ActionReplace], cp.Counts[ActionDelete])
+	}
+
+	var routes []string
+	for _, cp := range summary.Connections {
+		for _, route := range cp.AddedRoutes {
+			routes = append(routes, fmt.Sprintf("+ %s (%s)", route, cp.Key))
+		}
+		for _, route := range cp.RemovedRoutes {
+			routes = append(routes, fmt.Sprintf("- %s (%s)", route, cp.Key))
+		}
+	}
+	if len(routes) > 0 {
+		fmt.Fprintln(w, "\n<details><summary>Route changes</summary>\n\n```diff")
+		for _, route := range routes {
+			fmt.Fprintln(w, route)
+		}
+		fmt.Fprintln(w, "```\n</details>")
+	}
+
+	if len(summary.Unmatched) > 0 {
+		fmt.Fprintln(w, "\n**Not in config:**")
+		for _, c := range summary.Unmatched {
+			fmt.Fprintf(w, "- %s `%s`\n", c.Action, c.Address)
+		}
+	}
+}
+
+// -------------------------------------------------------------------------------------------------
+// plan-summary
+// -------------------------------------------------------------------------------------------------
+
+// runPlanSummary summarizes a saved plan per connection, as a table or as a merge request comment.
+func runPlanSummary(args []string) error {
+	fs := flag.NewFlagSet("plan-summary", flag.ContinueOnError)
+	dir := fs.String("dir", filepath.Join("cdktf.out", "stacks", StackName), "initialized stack directory the plan was made in")
+	planPath := fs.String("plan", "", "saved plan file or its \"terraform show -json\" output (default <dir>/plan)")
+	format := fs.String("format", "text", "output format: text or comment (Markdown)")
+	enforceFreeze := fs.Bool("enforce-freeze", os.Getenv(EnforceFreezeEnvVar) != "", "fail when the plan changes a connection under a change freeze")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *format != "text" && *format != "comment" {
+		return fmt.Errorf("unknown format %q (use text or comment)", *format)
+	}
+	if *planPath == "" {
+		*planPath = filepath.Join(*dir, "plan")
+	}
+
+	cfg, peers := loadSourcePeers(sourceArg(fs))
+	changes, err := ReadPlan(*planPath, *dir)
+	if err != nil {
+		return err
+	}
+
+	summary := BuildPlanSummary(NewNamer(cfg.Naming), peers, changes)
+	frozen := FrozenChanges(summary, peers, time.Now())
+	summary.Warnings = append(summary.Warnings, frozen...)
+	sort.Strings(summary.Warnings)
+	if *format == "comment" {
+		PrintPlanComment(os.Stdout, summary)
+	} else {
+		PrintPlanSummary(os.Stdout, summary)
+	}
+	if *enforceFreeze && len(frozen) > 0 {
+		return WithExitCode(ExitValidation, fmt.Errorf("%d frozen connection(s) changed", len(frozen)))
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package peering
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestBuildPlanSummary tests grouping plan changes by connection and warning on removed routes.
+func TestBuildPlanSummary(t *testing.T) {
+	plan := []byte(`{
+  "format_version": "1.2",
+  "resource_changes": [
+    {"address": "aws_route.SourceToPeerMainRoute0", "mode": "managed", "type": "aws_route", "name": "SourceToPeerMainRoute0",
+     "change": {"actions": ["create"], "before": null, "after": {"destination_cidr_block": "10.2.0.0/16"}}},
+    {"address": "aws_route.PeerToPeerMainRoute0", "mode": "managed", "type": "aws_route", "name": "PeerToPeerMainRoute0",
+     "change": {"actions": ["delete"], "before": {"destination_cidr_block": "10.1.0.0/16", "route_table_id": "rtb-2"}, "after": null}},
+    {"address": "aws_vpc_peering_connection.VpcPeering0", "mode": "managed", "type": "aws_vpc_peering_connection", "name": "VpcPeering0",
+     "change": {"actions": ["no-op"], "before": {"id": "pcx-1"}, "after": {"id": "pcx-1"}}},
+    {"address": "aws_vpc_peering_connection.VpcPeering7", "mode": "managed", "type": "aws_vpc_peering_connection", "name": "VpcPeering7",
+     "change": {"actions": ["delete"], "before": {"id": "pcx-old"}, "after": null}},
+    {"address": "data.aws_vpc.SourceVpc0", "mode": "data", "type": "aws_vpc", "name": "SourceVpc0",
+     "change": {"actions": ["read"], "before": null, "after": {}}}
+  ]
+}`)
+	changes, err := ParsePlanJSON(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 changes, got %d", len(changes))
+	}
+
+	peers := []PeerConfig{{SourceName: "dev", Name: "prod", SourceVpcID: "vpc-1", PeerVpcID: "vpc-2"}}
+	summary := BuildPlanSummary(LegacyNamer{}, peers, changes)
+
+	if len(summary.Connections) != 1 {
+		t.Fatalf("expected 1 connection, got %d", len(summary.Connections))
+	}
+	cp := summary.Connections[0]
+	if cp.Counts[ActionCreate] != 1 || cp.Counts[ActionDelete] != 1 {
+		t.Errorf("unexpected counts: %v", cp.Counts)
+	}
+	if len(cp.AddedRoutes) != 1 || cp.AddedRoutes[0] != "vpc-1 10.2.0.0/16" {
+		t.Errorf("unexpected added routes: %v", cp.AddedRoutes)
+	}
+	if len(summary.Unmatched) != 1 || summary.Unmatched[0].Address != "aws_vpc_peering_connection.VpcPeering7" {
+		t.Errorf("unexpected unmatched changes: %v", summary.Unmatched)
+	}
+	if len(summary.Warnings) != 2 ||
+		summary.Warnings[0] != "this change destroys peering connection pcx-old of a connection no longer in config" ||
+		summary.Warnings[1] != "this change removes routes for vpc-2 (10.1.0.0/16)" {
+		t.Errorf("unexpected warnings: %v", summary.Warnings)
+	}
+
+	var buf bytes.Buffer
+	PrintPlanComment(&buf, summary)
+	for _, want := range []string{"| `dev/prod` | 1 | 0 | 0 | 1 |", ":warning: **this change removes routes for vpc-2", "- vpc-2 10.1.0.0/16 (dev/prod)"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("comment missing %q:\n%s", want, buf.String())
+		}
+	}
+}
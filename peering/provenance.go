@@ -0,0 +1,189 @@
+package peering
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Route Provenance
+// -------------------------------------------------------------------------------------------------
+
+// ProvenanceTagPrefix prefixes the route table tag keys marking routes this tool manages. The key
+// ends in the connection key, since one route table commonly carries routes of several connections.
+const ProvenanceTagPrefix = "cdktf-peering-managed/"
+
+// ProvenanceConfig records which routes this tool owns. aws_route has no tags, so ownership is
+// marked on the route tables themselves and, optionally, in SSM parameters.
+type ProvenanceConfig struct {
+	TagRouteTables bool   `yaml:"tag_route_tables,omitempty"` // Tag every managed route table with the connection name.
+	SSMPrefix      string `yaml:"ssm_prefix,omitempty"`       // Path of the per-table SSM parameters listing managed destinations (disabled if empty).
+}
+
+// Enabled reports whether any provenance record is written.
+func (c ProvenanceConfig) Enabled() bool {
+	return c.TagRouteTables || c.SSMPrefix != ""
+}
+
+// Validate rejects SSM prefixes that do not form a parameter path.
+func (c ProvenanceConfig) Validate() error {
+	if c.SSMPrefix != "" && (!strings.HasPrefix(c.SSMPrefix, "/") || strings.HasSuffix(c.SSMPrefix, "/")) {
+		return fmt.Errorf("route_provenance.ssm_prefix %q must start with / and not end with /", c.SSMPrefix)
+	}
+	return nil
+}
+
+// ProvenanceTagKey returns the route table tag key of a connection.
+func ProvenanceTagKey(peer PeerConfig) string {
+	return ProvenanceTagPrefix + ConnectionKey(peer)
+}
+
+// routeTablesExpr returns an expression for the IDs of the route tables one side of a connection
+// routes through, or "" when the side has no routing. Declared IDs are used literally.
+func routeTablesExpr(namer Namer, ctx NameContext, side routeSide, routing RoutingConfig, mainID string, tableIDs []string) string {
+	mainRt := fmt.Sprintf("[data.aws_route_table.%s.id]", namer.ID(ctx, side.MainRt))
+	if mainID != "" {
+		mainRt = fmt.Sprintf("[%q]", mainID)
+	}
+	switch routing.Strategy {
+	case RoutingNone:
+		return ""
+	case RoutingAll:
+		if len(tableIDs) > 0 {
+			return hclStringList(tableIDs)
+		}
+		return fmt.Sprintf("data.aws_route_tables.%s.ids", namer.ID(ctx, side.RouteTables))
+	case RoutingFiltered:
+		if routing.DedicatedRouteTable {
+			return fmt.Sprintf("concat(%s, [aws_route_table.%s.id])", mainRt, terraformName(namer.ID(ctx, side.DedicatedRt)))
+		}
+		if routing.KeyByRouteTable {
+			return fmt.Sprintf("concat(%s, tolist(local.%s))", mainRt, SubnetRouteTablesLocal(terraformName(namer.ID(ctx, side.SubnetRt))))
+		}
+		return fmt.Sprintf("concat(%s, values(data.aws_route_table.%s)[*].id)", mainRt, terraformName(namer.ID(ctx, side.SubnetRt)))
+	default:
+		return mainRt
+	}
+}
+
+// destinationsExpr returns the HCL list of the destinations one side routes: the explicit CIDRs, or
+// the other VPC's CIDR expression without any, followed by the extra CIDRs.
+func destinationsExpr(vpcCidr string, cidrs, extra []string) string {
+	var items []string
+	if len(cidrs) == 0 {
+		items = append(items, vpcCidr)
+	}
+	for _, cidr := range append(append([]string(nil), cidrs...), extra...) {
+		items = append(items, fmt.Sprintf("%q", cidr))
+	}
+	return "[" + strings.Join(items, ", ") + "]"
+}
+
+// hclStringList returns the HCL list literal of strings.
+func hclStringList(values []string) string {
+	items := make([]string, 0, len(values))
+	for _, v := range values {
+		items = append(items, fmt.Sprintf("%q", v))
+	}
+	return "[" + strings.Join(items, ", ") + "]"
+}
+
+// RouteProvenance builds the provenance resources of one connection, keyed by resource type and
+// then by name. Each routed side gets an aws_ec2_tag per route table, tagging it with the
+// connection's Name tag, and an SSM parameter per route table at
+// "<ssm_prefix>/<route-table-id>/<source>/<peer>" holding the JSON list of destinations routed
+// through the peering.
+func RouteProvenance(namer Namer, ctx NameContext, peer PeerConfig, cfg ProvenanceConfig) map[string]map[string]interface{} {
+	out := map[string]map[string]interface{}{}
+	if !cfg.Enabled() {
+		return out
+	}
+	nameTag := ConnectionNameTag(namer, ctx, peer)
+
+	sourceDestinations := destinationsExpr(vpcCidrExpr(namer, ctx, KindPeerVpc, peer.PeerCidr), peer.DestinationCidrs, peer.SourceExtraCidrs)
+	peerDestinations := destinationsExpr(vpcCidrExpr(namer, ctx, KindSourceVpc, peer.SourceCidr), nil, peer.PeerExtraCidrs)
+
+	for _, s := range []struct {
+		side         routeSide
+		routing      RoutingConfig
+		destinations string
+		mainID       string
+		tableIDs     []string
+	}{
+		{sourceSide, peer.SourceRouting, sourceDestinations, peer.SourceMainRouteTableID, peer.SourceRouteTableIDs},
+		{peerSide, peer.PeerRouting, peerDestinations, peer.PeerMainRouteTableID, peer.PeerRouteTableIDs},
+	} {
+		tables := routeTablesExpr(namer, ctx, s.side, s.routing, s.mainID, s.tableIDs)
+		if tables == "" {
+			continue
+		}
+		forEach := fmt.Sprintf("${toset(%s)}", tables)
+		provider := "aws." + namer.ID(ctx, s.side.ProviderAlias)
+
+		if cfg.TagRouteTables {
+			if out["aws_ec2_tag"] == nil {
+				out["aws_ec2_tag"] = map[string]interface{}{}
+			}
+			out["aws_ec2_tag"][namer.ID(ctx, s.side.RtTag)] = map[string]interface{}{
+				"for_each":    forEach,
+				"resource_id": "${each.value}",
+				"key":         ProvenanceTagKey(peer),
+				"value":       nameTag,
+				"provider":    provider,
+			}
+		}
+		if cfg.SSMPrefix != "" {
+			if out["aws_ssm_parameter"] == nil {
+				out["aws_ssm_parameter"] = map[string]interface{}{}
+			}
+			out["aws_ssm_parameter"][namer.ID(ctx, s.side.RoutesParam)] = map[string]interface{}{
+				"for_each":    forEach,
+				"name":        fmt.Sprintf("%s/${each.value}/%s", cfg.SSMPrefix, ConnectionKey(peer)),
+				"description": fmt.Sprintf("Destinations routed through %s", nameTag),
+				"type":        "String",
+				"value":       fmt.Sprintf("${jsonencode(%s)}", s.destinations),
+				"provider":    provider,
+			}
+		}
+	}
+	return out
+}
+
+// AddRouteProvenance creates the provenance resources of every connection through the provider of
+// the side they describe, and records them on the connection.
+func AddRouteProvenance(stack cdktf.TerraformStack, namer Namer, connections []ConnectionResources, cfg ProvenanceConfig) {
+	for i := range connections {
+		c := &connections[i]
+		providers := make(map[string]cdktf.TerraformProvider)
+		for _, p := range []cdktf.TerraformProvider{c.Core.SourceProvider, c.Core.PeerProvider} {
+			if p != nil {
+				providers[providerRef(p)] = p
+			}
+		}
+		byType := RouteProvenance(namer, ConnectionNameContext(i, c.Peer), c.Peer, cfg)
+		for _, resourceType := range []string{"aws_ec2_tag", "aws_ssm_parameter"} {
+			names := make([]string, 0, len(byType[resourceType]))
+			for name := range byType[resourceType] {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				body := byType[resourceType][name].(map[string]interface{})
+				res := cdktf.NewTerraformResource(stack, jsii.String(name), &cdktf.TerraformResourceConfig{
+					TerraformResourceType: jsii.String(resourceType),
+					Provider:              providers[body["provider"].(string)],
+				})
+				for attr, value := range body {
+					if attr != "provider" {
+						res.AddOverride(jsii.String(attr), value)
+					}
+				}
+				c.Provenance = append(c.Provenance, res)
+			}
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package peering
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// TestProvenanceConfig tests SSM prefix validation.
+func TestProvenanceConfig(t *testing.T) {
+	tests := []struct {
+		prefix string
+		valid  bool
+	}{
+		{"", true},
+		{"/cdktf-peering", true},
+		{"/org/peering", true},
+		{"cdktf-peering", false},
+		{"/cdktf-peering/", false},
+	}
+	for _, tt := range tests {
+		err := ProvenanceConfig{SSMPrefix: tt.prefix}.Validate()
+		if (err == nil) != tt.valid {
+			t.Errorf("Validate(%q) error = %v, want valid=%v", tt.prefix, err, tt.valid)
+		}
+	}
+}
+
+// TestRouteProvenance tests the tag and SSM parameter resources emitted per routed side.
+func TestRouteProvenance(t *testing.T) {
+	peer := PeerConfig{
+		SourceName:       "dev",
+		Name:             "prod",
+		DestinationCidrs: []string{"10.1.0.0/24"},
+		SourceRouting:    RoutingConfig{Strategy: RoutingFiltered, SubnetTags: map[string]string{"tier": "app"}},
+		PeerRouting:      RoutingConfig{Strategy: RoutingAll},
+	}
+	ctx := ConnectionNameContext(0, peer)
+
+	if got := RouteProvenance(LegacyNamer{}, ctx, peer, ProvenanceConfig{}); len(got) != 0 {
+		t.Errorf("expected no resources when disabled, got %v", got)
+	}
+
+	got := RouteProvenance(LegacyNamer{}, ctx, peer, ProvenanceConfig{TagRouteTables: true, SSMPrefix: "/peering"})
+	tags, params := got["aws_ec2_tag"], got["aws_ssm_parameter"]
+	if len(tags) != 2 || len(params) != 2 {
+		t.Fatalf("expected a tag and a parameter per side, got %v", got)
+	}
+
+	sourceTag := tags["SourceRouteTableTag0"].(map[string]interface{})
+	if sourceTag["key"] != "cdktf-peering-managed/dev/prod" || sourceTag["value"] != "Connection to prod" {
+		t.Errorf("unexpected source tag: %v", sourceTag)
+	}
+	if want := "${toset(concat([data.aws_route_table.SourceMainRouteTable0.id], values(data.aws_route_table.SourceSubnetToPeerRoute_prod_eachkey_0RouteTable)[*].id))}"; sourceTag["for_each"] != want {
+		t.Errorf("source tag for_each = %v, want %s", sourceTag["for_each"], want)
+	}
+	if sourceTag["provider"] != "aws.source0" {
+		t.Errorf("source tag provider = %v", sourceTag["provider"])
+	}
+
+	peerParam := params["PeerRoutesParameter0"].(map[string]interface{})
+	if peerParam["for_each"] != "${toset(data.aws_route_tables.PeerRouteTables0.ids)}" {
+		t.Errorf("peer parameter for_each = %v", peerParam["for_each"])
+	}
+	if peerParam["name"] != "/peering/${each.value}/dev/prod" || peerParam["value"] != "${jsonencode([data.aws_vpc.SourceVpcData0.cidr_block])}" {
+		t.Errorf("unexpected peer parameter: %v", peerParam)
+	}
+	sourceParam := params["SourceRoutesParameter0"].(map[string]interface{})
+	if !strings.Contains(sourceParam["value"].(string), `"10.1.0.0/24"`) {
+		t.Errorf("expected destination CIDRs in source parameter, got %v", sourceParam["value"])
+	}
+
+	peer.SourceRouting.KeyByRouteTable = true
+	got = RouteProvenance(LegacyNamer{}, ctx, peer, ProvenanceConfig{TagRouteTables: true})
+	sourceTag = got["aws_ec2_tag"]["SourceRouteTableTag0"].(map[string]interface{})
+	if want := "${toset(concat([data.aws_route_table.SourceMainRouteTable0.id], tolist(local.SourceSubnetToPeerRoute_prod_eachkey_0RouteTableIds)))}"; sourceTag["for_each"] != want {
+		t.Errorf("key_by_route_table source tag for_each = %v, want %s", sourceTag["for_each"], want)
+	}
+
+	peer.PeerRouting = RoutingConfig{Strategy: RoutingNone}
+	got = RouteProvenance(LegacyNamer{}, ctx, peer, ProvenanceConfig{TagRouteTables: true})
+	if _, ok := got["aws_ec2_tag"]["PeerRouteTableTag0"]; ok || len(got["aws_ssm_parameter"]) != 0 {
+		t.Errorf("expected only the source tag, got %v", got)
+	}
+}
+
+// TestRouteProvenanceDeclared tests that declared route tables and CIDRs replace the lookups.
+func TestRouteProvenanceDeclared(t *testing.T) {
+	peer := PeerConfig{
+		SourceName:             "dev",
+		Name:                   "prod",
+		SourceCidr:             "10.0.0.0/16",
+		SourceMainRouteTableID: "rtb-1",
+		PeerRouteTableIDs:      []string{"rtb-2", "rtb-3"},
+		SourceRouting:          RoutingConfig{Strategy: RoutingMain},
+		PeerRouting:            RoutingConfig{Strategy: RoutingAll},
+	}
+	got := RouteProvenance(LegacyNamer{}, ConnectionNameContext(0, peer), peer, ProvenanceConfig{SSMPrefix: "/peering"})
+	params := got["aws_ssm_parameter"]
+	if source := params["SourceRoutesParameter0"].(map[string]interface{}); source["for_each"] != `${toset(["rtb-1"])}` {
+		t.Errorf("source parameter for_each = %v", source["for_each"])
+	}
+	peerParam := params["PeerRoutesParameter0"].(map[string]interface{})
+	if peerParam["for_each"] != `${toset(["rtb-2", "rtb-3"])}` || peerParam["value"] != `${jsonencode(["10.0.0.0/16"])}` {
+		t.Errorf("unexpected peer parameter: %v", peerParam)
+	}
+}
+
+// TestAddRouteProvenance tests that the provenance resources are constructs, recorded on their
+// connection, that the aspects reach.
+func TestAddRouteProvenance(t *testing.T) {
+	peer := PeerConfig{
+		SourceName:    "dev",
+		Name:          "prod",
+		SourceRouting: RoutingConfig{Strategy: RoutingMain},
+		PeerRouting:   RoutingConfig{Strategy: RoutingNone},
+	}
+	stack := cdktf.NewTerraformStack(cdktf.Testing_App(nil), jsii.String("peering"))
+	connections := []ConnectionResources{{Peer: peer}}
+	AddRouteProvenance(stack, LegacyNamer{}, connections, ProvenanceConfig{TagRouteTables: true, SSMPrefix: "/peering"})
+	if len(connections[0].Provenance) != 2 {
+		t.Fatalf("expected a tag and a parameter recorded, got %d resources", len(connections[0].Provenance))
+	}
+	AddAspects(stack, AspectsConfig{EnforceTags: map[string]string{"CostCenter": "42"}}.Build())
+
+	var synthesized struct {
+		Resource map[string]map[string]map[string]interface{} `json:"resource"`
+	}
+	if err := json.Unmarshal([]byte(*cdktf.Testing_Synth(stack, nil)), &synthesized); err != nil {
+		t.Fatal(err)
+	}
+	param := synthesized.Resource["aws_ssm_parameter"]["SourceRoutesParameter0"]
+	if param["name"] != "/peering/${each.value}/dev/prod" {
+		t.Errorf("unexpected parameter: %v", param)
+	}
+	if tags, _ := param["tags"].(map[string]interface{}); tags["CostCenter"] != "42" {
+		t.Errorf("expected enforced tags on the parameter, got %v", param["tags"])
+	}
+	if tag := synthesized.Resource["aws_ec2_tag"]["SourceRouteTableTag0"]; tag["key"] != "cdktf-peering-managed/dev/prod" || tag["tags"] != nil {
+		t.Errorf("unexpected route table tag: %v", tag)
+	}
+}
@@ -0,0 +1,169 @@
+package peering
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	awsprovider "cdk.tf/go/stack/generated/hashicorp/aws/provider"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Provider Settings
+// -------------------------------------------------------------------------------------------------
+
+// ProviderSettings holds AWS provider options applied to every provider of the stack, for restricted
+// networks and test environments.
+type ProviderSettings struct {
+	MaxRetries           *int               `yaml:"max_retries,omitempty"`             // Maximum API retries before failing.
+	CustomEndpoints      map[string]string  `yaml:"custom_endpoints,omitempty"`        // Service name to endpoint URL (ec2, sts, iam, route53).
+	HTTPProxy            string             `yaml:"http_proxy,omitempty"`              // Proxy for HTTP and HTTPS requests.
+	SkipMetadataAPICheck *bool              `yaml:"skip_metadata_api_check,omitempty"` // Skip the EC2 instance metadata API.
+	EndpointURL          string             `yaml:"endpoint_url,omitempty"`            // Single endpoint for every service (e.g. LocalStack).
+	AssumeRole           AssumeRoleSettings `yaml:"assume_role,omitempty"`             // Session settings for every assumed role.
+}
+
+// AssumeRoleSettings makes assumed-role sessions attributable in CloudTrail. Tag values may reference
+// environment variables (e.g. "${CHANGE_TICKET}"), expanded when the stack is synthesized.
+type AssumeRoleSettings struct {
+	SessionName       string            `yaml:"session_name,omitempty"`        // Session name shown in CloudTrail.
+	Tags              map[string]string `yaml:"tags,omitempty"`                // Session tags (e.g. team, change-ticket).
+	TransitiveTagKeys []string          `yaml:"transitive_tag_keys,omitempty"` // Tags kept across role chaining.
+}
+
+// SessionTags returns the session tags with environment variables expanded.
+func (a AssumeRoleSettings) SessionTags() map[string]string {
+	if len(a.Tags) == 0 {
+		return nil
+	}
+	tags := make(map[string]string, len(a.Tags))
+	for key, value := range a.Tags {
+		tags[key] = os.ExpandEnv(value)
+	}
+	return tags
+}
+
+// Validate checks the tag limits STS enforces and that transitive keys name session tags.
+func (a AssumeRoleSettings) Validate() error {
+	if len(a.Tags) > 50 {
+		return fmt.Errorf("at most 50 session tags are allowed, got %d", len(a.Tags))
+	}
+	for key, value := range a.SessionTags() {
+		if key == "" || len(key) > 128 {
+			return fmt.Errorf("session tag key %q must be 1-128 characters", key)
+		}
+		if len(value) > 256 {
+			return fmt.Errorf("session tag %q value must be at most 256 characters", key)
+		}
+	}
+	for _, key := range a.TransitiveTagKeys {
+		if _, ok := a.Tags[key]; !ok {
+			return fmt.Errorf("transitive tag key %q is not a session tag", key)
+		}
+	}
+	if len(a.SessionName) > 64 {
+		return fmt.Errorf("session_name must be at most 64 characters")
+	}
+	return nil
+}
+
+// localCredential is the static access key and secret used against a local endpoint, which accepts
+// any credentials.
+const localCredential = "test"
+
+// endpointSetters assigns a custom endpoint to the matching provider endpoints field.
+var endpointSetters = map[string]func(*awsprovider.AwsProviderEndpoints, *string){
+	"ec2":     func(e *awsprovider.AwsProviderEndpoints, url *string) { e.Ec2 = url },
+	"sts":     func(e *awsprovider.AwsProviderEndpoints, url *string) { e.Sts = url },
+	"iam":     func(e *awsprovider.AwsProviderEndpoints, url *string) { e.Iam = url },
+	"route53": func(e *awsprovider.AwsProviderEndpoints, url *string) { e.Route53 = url },
+}
+
+// Validate checks retry counts, endpoint URLs, and endpoint service names.
+func (s ProviderSettings) Validate() error {
+	if err := s.AssumeRole.Validate(); err != nil {
+		return fmt.Errorf("assume_role: %w", err)
+	}
+	if s.EndpointURL != "" {
+		if u, err := url.Parse(s.EndpointURL); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("endpoint_url %q is not an absolute URL", s.EndpointURL)
+		}
+	}
+	if s.MaxRetries != nil && *s.MaxRetries < 0 {
+		return fmt.Errorf("max_retries must not be negative, got %d", *s.MaxRetries)
+	}
+	for service, url := range s.CustomEndpoints {
+		if _, ok := endpointSetters[service]; !ok {
+			return fmt.Errorf("unsupported custom endpoint service %q (want one of %s)", service, strings.Join(endpointServices(), ", "))
+		}
+		if url == "" {
+			return fmt.Errorf("custom endpoint for %s is empty", service)
+		}
+	}
+	return nil
+}
+
+// endpointServices lists the services that accept custom endpoints, sorted.
+func endpointServices() []string {
+	services := make([]string, 0, len(endpointSetters))
+	for service := range endpointSetters {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	return services
+}
+
+// Apply copies the settings onto a provider config. An endpoint URL sends every supported service
+// there with static credentials and account lookups disabled; custom endpoints still take precedence.
+func (s ProviderSettings) Apply(cfg *awsprovider.AwsProviderConfig) {
+	if s.MaxRetries != nil {
+		cfg.MaxRetries = jsii.Number(float64(*s.MaxRetries))
+	}
+	if s.EndpointURL != "" || len(s.CustomEndpoints) > 0 {
+		endpoints := &awsprovider.AwsProviderEndpoints{}
+		if s.EndpointURL != "" {
+			for _, set := range endpointSetters {
+				set(endpoints, jsii.String(s.EndpointURL))
+			}
+		}
+		for service, url := range s.CustomEndpoints {
+			endpointSetters[service](endpoints, jsii.String(url))
+		}
+		cfg.Endpoints = &[]*awsprovider.AwsProviderEndpoints{endpoints}
+	}
+	if s.EndpointURL != "" {
+		cfg.AccessKey = jsii.String(localCredential)
+		cfg.SecretKey = jsii.String(localCredential)
+		cfg.SkipCredentialsValidation = jsii.Bool(true)
+		cfg.SkipRequestingAccountId = jsii.Bool(true)
+		cfg.SkipMetadataApiCheck = jsii.String("true")
+	}
+	if s.HTTPProxy != "" {
+		cfg.HttpProxy = jsii.String(s.HTTPProxy)
+		cfg.HttpsProxy = jsii.String(s.HTTPProxy)
+	}
+	if s.SkipMetadataAPICheck != nil {
+		cfg.SkipMetadataApiCheck = jsii.String(fmt.Sprint(*s.SkipMetadataAPICheck))
+	}
+	if roles, ok := cfg.AssumeRole.(*[]*awsprovider.AwsProviderAssumeRole); ok {
+		for _, role := range *roles {
+			s.AssumeRole.apply(role)
+		}
+	}
+}
+
+// apply copies the session settings onto one assume_role block.
+func (a AssumeRoleSettings) apply(role *awsprovider.AwsProviderAssumeRole) {
+	if a.SessionName != "" {
+		role.SessionName = jsii.String(a.SessionName)
+	}
+	if tags := a.SessionTags(); tags != nil {
+		role.Tags = stringPtrMap(tags)
+	}
+	if len(a.TransitiveTagKeys) > 0 {
+		role.TransitiveTagKeys = jsii.Strings(a.TransitiveTagKeys...)
+	}
+}
@@ -0,0 +1,87 @@
+package peering
+
+import (
+	"testing"
+
+	awsprovider "cdk.tf/go/stack/generated/hashicorp/aws/provider"
+)
+
+// TestProviderSettings tests validation and application of provider-level settings.
+func TestProviderSettings(t *testing.T) {
+	retries := 10
+	skip := true
+	s := ProviderSettings{
+		MaxRetries:           &retries,
+		CustomEndpoints:      map[string]string{"ec2": "http://localhost:4566"},
+		HTTPProxy:            "http://proxy:3128",
+		SkipMetadataAPICheck: &skip,
+	}
+	if err := s.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cfg := &awsprovider.AwsProviderConfig{}
+	s.Apply(cfg)
+	if *cfg.MaxRetries != 10 || *cfg.HttpProxy != "http://proxy:3128" || *cfg.SkipMetadataApiCheck != "true" {
+		t.Errorf("settings not applied: %+v", cfg)
+	}
+	endpoints := *cfg.Endpoints.(*[]*awsprovider.AwsProviderEndpoints)
+	if *endpoints[0].Ec2 != "http://localhost:4566" {
+		t.Errorf("unexpected ec2 endpoint: %v", endpoints[0].Ec2)
+	}
+
+	s.CustomEndpoints["lambda"] = "http://localhost:4566"
+	if err := s.Validate(); err == nil {
+		t.Errorf("expected error for unsupported endpoint service")
+	}
+}
+
+// TestProviderSettingsEndpointURL tests that a single endpoint covers every service and disables
+// account lookups, while custom endpoints still win.
+func TestProviderSettingsEndpointURL(t *testing.T) {
+	s := ProviderSettings{
+		EndpointURL:     "http://localhost:4566",
+		CustomEndpoints: map[string]string{"sts": "http://sts.local:4566"},
+	}
+	if err := s.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cfg := &awsprovider.AwsProviderConfig{}
+	s.Apply(cfg)
+	endpoints := (*cfg.Endpoints.(*[]*awsprovider.AwsProviderEndpoints))[0]
+	if *endpoints.Ec2 != "http://localhost:4566" || *endpoints.Sts != "http://sts.local:4566" {
+		t.Errorf("unexpected endpoints: ec2 %q, sts %q", *endpoints.Ec2, *endpoints.Sts)
+	}
+	if *cfg.AccessKey != localCredential || *cfg.SkipRequestingAccountId.(*bool) != true {
+		t.Errorf("expected static credentials and skipped account lookup: %+v", cfg)
+	}
+
+	if err := (ProviderSettings{EndpointURL: "localhost"}).Validate(); err == nil {
+		t.Errorf("expected error for relative endpoint URL")
+	}
+}
+
+// TestAssumeRoleSettings tests session tags on assumed roles.
+func TestAssumeRoleSettings(t *testing.T) {
+	t.Setenv("CHANGE_TICKET", "CHG-42")
+	s := ProviderSettings{AssumeRole: AssumeRoleSettings{
+		SessionName:       "vpc-peering",
+		Tags:              map[string]string{"team": "network", "change-ticket": "${CHANGE_TICKET}"},
+		TransitiveTagKeys: []string{"team"},
+	}}
+	if err := s.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	role := &awsprovider.AwsProviderAssumeRole{}
+	s.Apply(&awsprovider.AwsProviderConfig{AssumeRole: &[]*awsprovider.AwsProviderAssumeRole{role}})
+	if *role.SessionName != "vpc-peering" || *(*role.Tags)["change-ticket"] != "CHG-42" || *(*role.TransitiveTagKeys)[0] != "team" {
+		t.Errorf("session settings not applied: %+v", role)
+	}
+
+	s.AssumeRole.TransitiveTagKeys = []string{"owner"}
+	if err := s.Validate(); err == nil {
+		t.Errorf("expected error for a transitive key that is not a session tag")
+	}
+}
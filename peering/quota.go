@@ -0,0 +1,302 @@
+package peering
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Service Quota Preflight
+// -------------------------------------------------------------------------------------------------
+
+// Service Quotas codes of the VPC quotas the connections consume.
+const (
+	QuotaServiceVpc       = "vpc"
+	QuotaPeeringsPerVpc   = "L-7E9ECCDB" // Active VPC peering connections per VPC.
+	QuotaRoutesPerTable   = "L-93826ACB" // Routes per route table.
+	quotaPeeringsName     = "peerings per VPC"
+	quotaRoutesName       = "routes per route table"
+	quotaIncreaseTemplate = "aws service-quotas request-service-quota-increase --service-code %s --quota-code %s --desired-value %d --region %s"
+)
+
+// QuotaClient reads quotas and current usage in one account and region. Implemented by the AWS CLI
+// and by fakes in tests.
+type QuotaClient interface {
+	// ServiceQuota returns the applied value of a quota.
+	ServiceQuota(serviceCode, quotaCode string) (float64, error)
+	// PeeredVpcs returns the VPCs a VPC has active or pending peerings with.
+	PeeredVpcs(vpcID string) ([]string, error)
+	// VpcCidr and RouteTables are shared with VpcNetworkLookup.
+	VpcCidr(vpcID string) (string, error)
+	RouteTables(vpcID string) ([]RouteTable, error)
+}
+
+// QuotaUsage compares one quota of one VPC with what it will hold after apply.
+type QuotaUsage struct {
+	Subject  string // Peer name of the VPC.
+	VpcID    string // VPC ID.
+	Account  string // Account of the VPC, empty if unknown.
+	Region   string // Region of the VPC.
+	Quota    string // Human-readable quota name.
+	Code     string // Service Quotas code.
+	Applied  int    // Applied quota value.
+	Planned  int    // Usage after apply: existing objects plus those the config adds.
+	Maximum  int    // Highest value AWS raises the quota to.
+	Existing int    // Usage today.
+}
+
+// NeedsIncrease reports whether the config exceeds the applied quota.
+func (u QuotaUsage) NeedsIncrease() bool {
+	return u.Planned > u.Applied
+}
+
+// IncreaseCommand returns the CLI call requesting the quota increase the config needs.
+func (u QuotaUsage) IncreaseCommand() string {
+	return fmt.Sprintf(quotaIncreaseTemplate, QuotaServiceVpc, u.Code, u.Planned, u.Region)
+}
+
+// quotaVpc is one VPC the connections touch, with the credentials to inspect it.
+type quotaVpc struct {
+	name, region, roleArn, account string
+	peers                          map[string]bool // VPCs the config peers it with.
+	routedTo                       []string        // VPC IDs whose CIDR its tables route to, or "cidr:<cidr>".
+}
+
+// CheckQuotas compares, for every VPC of the connections, the peerings and the routes of its fullest
+// route table after apply with the applied Service Quotas values. Existing peerings and routes count
+// once whether or not the config manages them, so re-checking after apply gives the same answer.
+func CheckQuotas(peers []PeerConfig, connect func(region, roleArn string) (QuotaClient, error)) ([]QuotaUsage, error) {
+	vpcs := make(map[string]*quotaVpc)
+	side := func(name, vpcID, region, roleArn, account string) *quotaVpc {
+		if vpcs[vpcID] == nil {
+			vpcs[vpcID] = &quotaVpc{name: name, region: ResolveRegion(region), roleArn: roleArn, account: account, peers: map[string]bool{}}
+		}
+		return vpcs[vpcID]
+	}
+	for _, peer := range peers {
+		source := side(peer.SourceName, peer.SourceVpcID, peer.SourceRegion, peer.SourceRoleArn, GetAccountIDFromRoleArn(peer.SourceRoleArn))
+		source.peers[peer.PeerVpcID] = true
+		if !peer.ThirdParty {
+			// A third party's VPC cannot be inspected; its quotas are its owner's to check.
+			target := side(ConnectionNameContext(0, peer).Peer, peer.PeerVpcID, peer.PeerRegion, peer.PeerRoleArn, PeerAccount(peer))
+			target.peers[peer.SourceVpcID] = true
+		}
+
+		if peer.SourceRouting.Strategy != RoutingNone {
+			if len(peer.DestinationCidrs) == 0 {
+				source.routedTo = append(source.routedTo, peer.PeerVpcID)
+			}
+			for _, cidr := range append(append([]string(nil), peer.DestinationCidrs...), peer.SourceExtraCidrs...) {
+				source.routedTo = append(source.routedTo, "cidr:"+cidr)
+			}
+		}
+		if peer.PeerRouting.Strategy != RoutingNone {
+			target := vpcs[peer.PeerVpcID]
+			target.routedTo = append(target.routedTo, peer.SourceVpcID)
+			for _, cidr := range peer.PeerExtraCidrs {
+				target.routedTo = append(target.routedTo, "cidr:"+cidr)
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(vpcs))
+	for id := range vpcs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var usages []QuotaUsage
+	for _, id := range ids {
+		v := vpcs[id]
+		client, err := connect(v.region, v.roleArn)
+		if err != nil {
+			return nil, err
+		}
+		usage := func(name, code string, existing, planned, maximum int) (QuotaUsage, error) {
+			applied, err := client.ServiceQuota(QuotaServiceVpc, code)
+			if err != nil {
+				return QuotaUsage{}, err
+			}
+			return QuotaUsage{Subject: v.name, VpcID: id, Account: v.account, Region: v.region, Quota: name, Code: code,
+				Applied: int(applied), Planned: planned, Maximum: maximum, Existing: existing}, nil
+		}
+
+		peered, err := client.PeeredVpcs(id)
+		if err != nil {
+			return nil, err
+		}
+		all := make(map[string]bool, len(peered)+len(v.peers))
+		for _, other := range peered {
+			all[other] = true
+		}
+		for other := range v.peers {
+			all[other] = true
+		}
+		u, err := usage(quotaPeeringsName, QuotaPeeringsPerVpc, len(peered), len(all), PeeringsPerVpcMax)
+		if err != nil {
+			return nil, err
+		}
+		usages = append(usages, u)
+
+		if len(v.routedTo) == 0 {
+			continue
+		}
+		destinations := make(map[string]bool)
+		for _, to := range v.routedTo {
+			if cidr, ok := strings.CutPrefix(to, "cidr:"); ok {
+				destinations[cidr] = true
+				continue
+			}
+			other, err := connect(vpcs[to].region, vpcs[to].roleArn)
+			if err != nil {
+				return nil, err
+			}
+			cidr, err := other.VpcCidr(to)
+			if err != nil {
+				return nil, err
+			}
+			destinations[cidr] = true
+		}
+		tables, err := client.RouteTables(id)
+		if err != nil {
+			return nil, err
+		}
+		existing, planned := 0, 0
+		for _, table := range tables {
+			routes := make(map[string]bool, len(table.Destinations)+len(destinations))
+			for _, dest := range table.Destinations {
+				routes[dest] = true
+			}
+			existing = max(existing, len(routes))
+			for dest := range destinations {
+				routes[dest] = true
+			}
+			planned = max(planned, len(routes))
+		}
+		if u, err = usage(quotaRoutesName, QuotaRoutesPerTable, existing, planned, RoutesPerTableMax); err != nil {
+			return nil, err
+		}
+		usages = append(usages, u)
+	}
+	return usages, nil
+}
+
+// PrintQuotaUsages writes the usages as a table, followed by the increase requests needed before
+// rollout.
+func PrintQuotaUsages(w io.Writer, usages []QuotaUsage) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VPC\tACCOUNT\tREGION\tQUOTA\tEXISTING\tPLANNED\tAPPLIED\tSTATUS")
+	var increases []QuotaUsage
+	for _, u := range usages {
+		status := "OK"
+		switch {
+		case u.Planned > u.Maximum:
+			status = fmt.Sprintf("OVER MAXIMUM %d", u.Maximum)
+		case u.NeedsIncrease():
+			status = "INCREASE"
+			increases = append(increases, u)
+		}
+		fmt.Fprintf(tw, "%s (%s)\t%s\t%s\t%s\t%d\t%d\t%d\t%s\n",
+			u.Subject, u.VpcID, orUnknown(u.Account), u.Region, u.Quota, u.Existing, u.Planned, u.Applied, status)
+	}
+	tw.Flush()
+
+	if len(increases) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\nQuota increases needed before rollout:")
+	for _, u := range increases {
+		fmt.Fprintf(w, "  # %s in account %s\n  %s\n", u.Subject, orUnknown(u.Account), u.IncreaseCommand())
+	}
+}
+
+// -------------------------------------------------------------------------------------------------
+// AWS CLI Implementation
+// -------------------------------------------------------------------------------------------------
+
+// ServiceQuota returns the applied value of a quota in the client's account and region.
+func (c *AWSCLI) ServiceQuota(serviceCode, quotaCode string) (float64, error) {
+	var out struct {
+		Quota struct {
+			Value float64 `json:"Value"`
+		} `json:"Quota"`
+	}
+	if err := c.Run(&out, "service-quotas", "get-service-quota", "--service-code", serviceCode, "--quota-code", quotaCode); err != nil {
+		return 0, err
+	}
+	return out.Quota.Value, nil
+}
+
+// PeeredVpcs returns the VPCs a VPC has active, pending, or provisioning peerings with, on either
+// side.
+func (c *AWSCLI) PeeredVpcs(vpcID string) ([]string, error) {
+	others := make(map[string]bool)
+	for _, filter := range []string{"requester-vpc-info.vpc-id", "accepter-vpc-info.vpc-id"} {
+		var out struct {
+			VpcPeeringConnections []struct {
+				AccepterVpcInfo struct {
+					VpcID string `json:"VpcId"`
+				} `json:"AccepterVpcInfo"`
+				RequesterVpcInfo struct {
+					VpcID string `json:"VpcId"`
+				} `json:"RequesterVpcInfo"`
+			} `json:"VpcPeeringConnections"`
+		}
+		if err := c.Run(&out, "ec2", "describe-vpc-peering-connections", "--filters",
+			"Name="+filter+",Values="+vpcID,
+			"Name=status-code,Values=active,pending-acceptance,provisioning"); err != nil {
+			return nil, err
+		}
+		for _, pcx := range out.VpcPeeringConnections {
+			others[pcx.AccepterVpcInfo.VpcID] = true
+			others[pcx.RequesterVpcInfo.VpcID] = true
+		}
+	}
+	delete(others, vpcID)
+	return sortedKeys(others), nil
+}
+
+// -------------------------------------------------------------------------------------------------
+// quota-check
+// -------------------------------------------------------------------------------------------------
+
+// runQuotaCheck reads the applied VPC quotas of every account and region the connections touch and
+// fails when the config needs increases, listing the requests to file before rollout.
+func runQuotaCheck(args []string) error {
+	fs := flag.NewFlagSet("quota-check", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, peers := loadSourcePeers(sourceArg(fs))
+	clients := make(map[string]QuotaClient)
+	connect := func(region, roleArn string) (QuotaClient, error) {
+		key := region + "|" + roleArn
+		if c, ok := clients[key]; ok {
+			return c, nil
+		}
+		c, err := NewAWSCLI(region, roleArn, cfg.Provider)
+		if err != nil {
+			return nil, err
+		}
+		clients[key] = c
+		return c, nil
+	}
+
+	usages, err := CheckQuotas(peers, connect)
+	if err != nil {
+		return err
+	}
+	PrintQuotaUsages(os.Stdout, usages)
+	for _, u := range usages {
+		if u.NeedsIncrease() {
+			return fmt.Errorf("the config exceeds applied service quotas")
+		}
+	}
+	return nil
+}
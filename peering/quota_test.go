@@ -0,0 +1,74 @@
+package peering
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// fakeQuotas serves fixed quotas, peerings, CIDRs, and route tables.
+type fakeQuotas struct {
+	quotas map[string]float64
+	peered map[string][]string
+	cidrs  map[string]string
+	tables map[string][]RouteTable
+}
+
+func (f fakeQuotas) ServiceQuota(_, code string) (float64, error)   { return f.quotas[code], nil }
+func (f fakeQuotas) PeeredVpcs(vpcID string) ([]string, error)      { return f.peered[vpcID], nil }
+func (f fakeQuotas) VpcCidr(vpcID string) (string, error)           { return f.cidrs[vpcID], nil }
+func (f fakeQuotas) RouteTables(vpcID string) ([]RouteTable, error) { return f.tables[vpcID], nil }
+
+// TestCheckQuotas tests that existing and configured peerings and routes are unioned per VPC and
+// compared with the applied quotas.
+func TestCheckQuotas(t *testing.T) {
+	client := fakeQuotas{
+		quotas: map[string]float64{QuotaPeeringsPerVpc: 2, QuotaRoutesPerTable: 50},
+		peered: map[string][]string{"vpc-1": {"vpc-2", "vpc-9"}, "vpc-2": {"vpc-1"}},
+		cidrs:  map[string]string{"vpc-1": "10.1.0.0/16", "vpc-2": "10.2.0.0/16", "vpc-3": "10.3.0.0/16"},
+		tables: map[string][]RouteTable{
+			"vpc-1": {{ID: "rtb-1", Main: true, Destinations: []string{"10.1.0.0/16", "10.2.0.0/16"}}},
+			"vpc-2": {{ID: "rtb-2", Main: true, Destinations: []string{"10.2.0.0/16"}}},
+		},
+	}
+	routed := RoutingConfig{Strategy: RoutingMain}
+	peers := []PeerConfig{
+		// Already peered and routed: adds nothing.
+		{SourceName: "hub", Name: "a", SourceVpcID: "vpc-1", PeerVpcID: "vpc-2", SourceRouting: routed, PeerRouting: routed},
+		// New: a third peering for vpc-1 and a route toward vpc-3.
+		{SourceName: "hub", Name: "b", SourceVpcID: "vpc-1", PeerVpcID: "vpc-3", SourceRouting: routed,
+			PeerRouting: RoutingConfig{Strategy: RoutingNone}},
+	}
+
+	usages, err := CheckQuotas(peers, func(string, string) (QuotaClient, error) { return client, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	type row struct {
+		vpc, quota                 string
+		existing, planned, applied int
+		increase                   bool
+	}
+	want := []row{
+		{"vpc-1", quotaPeeringsName, 2, 3, 2, true},
+		{"vpc-1", quotaRoutesName, 2, 3, 50, false},
+		{"vpc-2", quotaPeeringsName, 1, 1, 2, false},
+		{"vpc-2", quotaRoutesName, 1, 2, 50, false},
+		{"vpc-3", quotaPeeringsName, 0, 1, 2, false},
+	}
+	if len(usages) != len(want) {
+		t.Fatalf("got %d usages, want %d: %+v", len(usages), len(want), usages)
+	}
+	for i, u := range usages {
+		got := row{u.VpcID, u.Quota, u.Existing, u.Planned, u.Applied, u.NeedsIncrease()}
+		if got != want[i] {
+			t.Errorf("usage %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+
+	var out bytes.Buffer
+	PrintQuotaUsages(&out, usages)
+	if !strings.Contains(out.String(), "--quota-code L-7E9ECCDB --desired-value 3 --region "+DefaultRegion) {
+		t.Errorf("missing increase request in:\n%s", out.String())
+	}
+}
@@ -0,0 +1,167 @@
+package peering
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Region Validation
+// -------------------------------------------------------------------------------------------------
+
+// awsRegions lists the regions of every partition known when this release was made. A region
+// missing from it is rejected unless the config lists it in allowed_regions, so the list must grow
+// with every region AWS launches: `make check-regions` compares it with the regions the account's
+// EC2 endpoint reports (aws ec2 describe-regions --all-regions) and names the missing ones. Regions
+// of the other partitions are added by hand from AWS's published region list.
+var awsRegions = map[string]bool{
+	"af-south-1": true, "ap-east-1": true, "ap-east-2": true, "ap-northeast-1": true, "ap-northeast-2": true,
+	"ap-northeast-3": true, "ap-south-1": true, "ap-south-2": true, "ap-southeast-1": true, "ap-southeast-2": true,
+	"ap-southeast-3": true, "ap-southeast-4": true, "ap-southeast-5": true, "ap-southeast-6": true, "ap-southeast-7": true,
+	"ca-central-1": true, "ca-west-1": true, "eu-central-1": true, "eu-central-2": true, "eu-north-1": true,
+	"eu-south-1": true, "eu-south-2": true, "eu-west-1": true, "eu-west-2": true, "eu-west-3": true,
+	"il-central-1": true, "me-central-1": true, "me-south-1": true, "mx-central-1": true, "sa-east-1": true,
+	"us-east-1": true, "us-east-2": true, "us-west-1": true, "us-west-2": true,
+	"us-gov-east-1": true, "us-gov-west-1": true, "cn-north-1": true, "cn-northwest-1": true,
+	"us-iso-east-1": true, "us-iso-west-1": true, "us-isob-east-1": true, "us-isof-east-1": true,
+	"us-isof-south-1": true, "eu-isoe-west-1": true, "eusc-de-east-1": true,
+}
+
+// regionPattern matches well-formed region names, such as us-east-1, us-isof-south-1, or
+// eusc-de-east-1.
+var regionPattern = regexp.MustCompile(`^[a-z]{2,4}(-[a-z]+)+-[0-9]+$`)
+
+// maxRegionSuggestionDistance is the largest edit distance at which a valid region is suggested
+// for an unknown one.
+const maxRegionSuggestionDistance = 3
+
+// ValidateRegion rejects a region that is not a well-formed region name, or one this release does
+// not know and allowed (the config's allowed_regions) does not list, which is a typo or a region
+// launched since. It suggests the closest known name when one is near. An empty region is valid:
+// the default region is used.
+func ValidateRegion(region string, allowed []string) error {
+	if region == "" || awsRegions[region] {
+		return nil
+	}
+	suggestion := ClosestRegion(region)
+	if !regionPattern.MatchString(region) {
+		if suggestion != "" {
+			return fmt.Errorf("invalid region %q (did you mean %q?)", region, suggestion)
+		}
+		return fmt.Errorf("invalid region %q (want a region name such as us-east-1)", region)
+	}
+	for _, a := range allowed {
+		if region == a {
+			return nil
+		}
+	}
+	hint := "list it in allowed_regions if AWS launched it after this release"
+	if suggestion != "" {
+		hint = fmt.Sprintf("did you mean %q? %s", suggestion, hint)
+	}
+	return fmt.Errorf("unknown region %q (%s)", region, hint)
+}
+
+// ClosestRegion returns the known region with the smallest edit distance to name, the first in
+// name order on ties, or "" when none is within maxRegionSuggestionDistance.
+func ClosestRegion(name string) string {
+	regions := sortedKeys(awsRegions)
+	best, bestDistance := "", maxRegionSuggestionDistance+1
+	for _, region := range regions {
+		if d := editDistance(strings.ToLower(name), region); d < bestDistance {
+			best, bestDistance = region, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between two strings.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// minInt returns the smallest of its arguments.
+func minInt(first int, rest ...int) int {
+	for _, v := range rest {
+		if v < first {
+			first = v
+		}
+	}
+	return first
+}
+
+// ConfiguredRegions returns every region the config sets, keyed by the path of its field (e.g.
+// "peers.dev.region").
+func ConfiguredRegions(cfg YAMLConfig) map[string]string {
+	regions := make(map[string]string)
+	for name, peer := range cfg.Peers {
+		regions["peers."+name+".region"] = peer.Region
+	}
+	if cfg.Discovery != nil {
+		regions["discovery.region"] = cfg.Discovery.Region
+		for name, q := range cfg.Discovery.Queries {
+			regions["discovery.queries."+name+".peer.region"] = q.Peer.Region
+		}
+	}
+	if cfg.IPAM != nil {
+		regions["ipam.region"] = cfg.IPAM.Region
+	}
+	if cfg.Inventory.S3 != nil {
+		regions["inventory.s3.region"] = cfg.Inventory.S3.Region
+	}
+	if cfg.Inventory.DynamoDB != nil {
+		regions["inventory.dynamodb.region"] = cfg.Inventory.DynamoDB.Region
+	}
+	if cfg.Lattice != nil {
+		regions["lattice.region"] = cfg.Lattice.Region
+	}
+	if cfg.Terraform.S3Backend != nil {
+		regions["terraform.s3_backend.region"] = cfg.Terraform.S3Backend.Region
+	}
+	return regions
+}
+
+// ValidateRegions checks every region the config sets, reporting all malformed or unknown ones in
+// field order, and that allowed_regions lists well-formed region names.
+func ValidateRegions(cfg YAMLConfig) error {
+	for _, region := range cfg.AllowedRegions {
+		if !regionPattern.MatchString(region) {
+			return fmt.Errorf("allowed_regions: %q is not a region name (e.g. us-east-1)", region)
+		}
+	}
+	regions := ConfiguredRegions(cfg)
+	fields := make([]string, 0, len(regions))
+	for field := range regions {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	var problems []string
+	for _, field := range fields {
+		if err := ValidateRegion(regions[field], cfg.AllowedRegions); err != nil {
+			problems = append(problems, field+": "+err.Error())
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
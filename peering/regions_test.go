@@ -0,0 +1,116 @@
+package peering
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestValidateRegion tests that malformed regions and unknown ones not allowed are rejected with the
+// closest known name.
+func TestValidateRegion(t *testing.T) {
+	hint := "list it in allowed_regions if AWS launched it after this release"
+	tests := []struct {
+		region  string
+		allowed []string
+		want    string
+	}{
+		{"", nil, ""},
+		{"us-west-2", nil, ""},
+		{"us-iso-east-1", nil, ""},
+		{"us-isof-south-1", nil, ""},
+		{"eusc-de-east-1", nil, ""},
+		{"us-wes-2", nil, `unknown region "us-wes-2" (did you mean "us-west-2"? ` + hint + `)`},
+		{"mars-north-1", nil, `unknown region "mars-north-1" (` + hint + `)`},
+		{"ap-east-3", []string{"ap-east-3"}, ""},
+		{"eu-west1", nil, `invalid region "eu-west1" (did you mean "eu-west-1"?)`},
+		{"eu-west1", []string{"eu-west1"}, `invalid region "eu-west1" (did you mean "eu-west-1"?)`},
+		{"US-EAST-1", nil, `invalid region "US-EAST-1" (did you mean "us-east-1"?)`},
+		{"westeurope", nil, `invalid region "westeurope" (want a region name such as us-east-1)`},
+	}
+	for _, tt := range tests {
+		err := ValidateRegion(tt.region, tt.allowed)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tt.want {
+			t.Errorf("ValidateRegion(%q, %v) = %q, want %q", tt.region, tt.allowed, got, tt.want)
+		}
+	}
+}
+
+// TestValidateRegions tests that every configured region is checked and reported by field.
+func TestValidateRegions(t *testing.T) {
+	cfg := YAMLConfig{
+		Peers:     map[string]YAMLPeer{"dev": {Region: "us-east-1"}, "prod": {Region: "us-west2"}, "qa": {Region: "us-wes-2"}},
+		IPAM:      &IPAMConfig{ScopeID: "ipam-scope-1", Region: "EU-CENTRAL-1"},
+		Terraform: TerraformSettings{S3Backend: &S3BackendSettings{Region: "us-east-1"}},
+	}
+	want := `ipam.region: invalid region "EU-CENTRAL-1" (did you mean "eu-central-1"?); ` +
+		`peers.prod.region: invalid region "us-west2" (did you mean "us-west-2"?); ` +
+		`peers.qa.region: unknown region "us-wes-2" (did you mean "us-west-2"? list it in allowed_regions if AWS launched it after this release)`
+	if err := ValidateRegions(cfg); err == nil || err.Error() != want {
+		t.Errorf("expected %q, got %v", want, err)
+	}
+
+	cfg.Peers["prod"], cfg.Peers["qa"] = YAMLPeer{}, YAMLPeer{Region: "us-west-2"}
+	cfg.IPAM.Region = ""
+	if err := ValidateRegions(cfg); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// TestAllowedRegions tests that allowed_regions accepts regions missing from the built-in list and
+// only region names.
+func TestAllowedRegions(t *testing.T) {
+	cfg := YAMLConfig{Peers: map[string]YAMLPeer{"dev": {Region: "ap-east-3"}}}
+	if err := ValidateRegions(cfg); err == nil || !strings.Contains(err.Error(), `did you mean "ap-east-1"?`) {
+		t.Errorf("expected an unknown region error with a hint, got %v", err)
+	}
+	cfg.AllowedRegions = []string{"ap-east-3"}
+	if err := ValidateRegions(cfg); err != nil {
+		t.Errorf("expected the allowed region to be accepted, got %v", err)
+	}
+	cfg.AllowedRegions = []string{"ap-east3"}
+	if err := ValidateRegions(cfg); err == nil || !strings.Contains(err.Error(), "not a region name") {
+		t.Errorf("expected a malformed allowed region to be rejected, got %v", err)
+	}
+}
+
+// TestAWSRegions tests that the built-in regions are well-formed, and, when AWS_REGIONS holds the
+// regions AWS reports (make check-regions), that none of them is missing.
+func TestAWSRegions(t *testing.T) {
+	for region := range awsRegions {
+		if !regionPattern.MatchString(region) {
+			t.Errorf("built-in region %q is not well-formed", region)
+		}
+	}
+	reported := strings.Fields(os.Getenv("AWS_REGIONS"))
+	if len(reported) == 0 {
+		t.Skip("AWS_REGIONS is not set; run make check-regions to compare with the regions AWS reports")
+	}
+	for _, region := range reported {
+		if !awsRegions[region] {
+			t.Errorf("region %q is missing from awsRegions in regions.go", region)
+		}
+	}
+}
+
+// TestRegionPartition tests the partition of regions outside the commercial partition.
+func TestRegionPartition(t *testing.T) {
+	for region, want := range map[string]string{
+		"us-east-1":       PartitionAWS,
+		"cn-north-1":      PartitionChina,
+		"us-gov-west-1":   PartitionGovCloud,
+		"us-iso-east-1":   PartitionISO,
+		"us-isob-east-1":  PartitionISOB,
+		"us-isof-south-1": PartitionISOF,
+		"eu-isoe-west-1":  PartitionISOE,
+		"eusc-de-east-1":  PartitionEUSC,
+	} {
+		if got := RegionPartition(region); got != want {
+			t.Errorf("RegionPartition(%q) = %q, want %q", region, got, want)
+		}
+	}
+}
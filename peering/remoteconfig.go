@@ -0,0 +1,89 @@
+package peering
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Remote Configs
+// -------------------------------------------------------------------------------------------------
+
+// configHTTPClient fetches https:// configs; replaced in tests.
+var configHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// IsRemoteConfig reports whether a config location is a URL rather than a local path.
+func IsRemoteConfig(location string) bool {
+	return strings.Contains(location, "://")
+}
+
+// ReadDocument reads a YAML document from a local path, an https:// URL, or an s3:// URL, which is
+// downloaded with s3 (the AWS CLI signs its requests with SigV4 from the ambient credentials). Plain
+// http:// is refused: configs name accounts and roles.
+func ReadDocument(location string, s3 ObjectReader) ([]byte, error) {
+	switch {
+	case strings.HasPrefix(location, "s3://"):
+		return s3.ReadObject(location)
+	case strings.HasPrefix(location, "https://"):
+		return fetchHTTPS(location)
+	case IsRemoteConfig(location):
+		return nil, WithExitCode(ExitConfig, fmt.Errorf("unsupported scheme (use https://, s3://, or a local path)"))
+	}
+	data, err := os.ReadFile(location)
+	if err != nil {
+		return nil, WithExitCode(ExitConfig, err)
+	}
+	return data, nil
+}
+
+// fetchHTTPS downloads a document, failing on any status but 200.
+func fetchHTTPS(url string) ([]byte, error) {
+	resp, err := configHTTPClient.Get(url)
+	if err != nil {
+		return nil, WithExitCode(ExitConfig, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, WithExitCode(ExitConfig, fmt.Errorf("GET %s: %s", url, resp.Status))
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, WithExitCode(ExitConfig, err)
+	}
+	return data, nil
+}
+
+// configObjectReader returns the reader of s3:// configs: the AWS CLI with ambient credentials in the
+// default region, as the config's provider settings are not known before it is read. A missing CLI
+// is reported when the object is read.
+func configObjectReader() ObjectReader {
+	return &AWSCLI{Region: ResolveRegion(""), Env: os.Environ()}
+}
+
+// sopsDecrypter returns the function decrypting a config read from location: sops reads local files
+// itself and is given remote ones on stdin, so their plaintext never touches the disk either.
+func sopsDecrypter(location string, data []byte) func(string) ([]byte, error) {
+	if !IsRemoteConfig(location) {
+		return sopsDecrypt
+	}
+	return func(string) ([]byte, error) {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command("sops", "--decrypt", "--input-type", "yaml", "--output-type", "yaml", "/dev/stdin")
+		cmd.Stdin = bytes.NewReader(data)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return nil, fmt.Errorf("%w: %s", err, msg)
+			}
+			return nil, err
+		}
+		return stdout.Bytes(), nil
+	}
+}
@@ -0,0 +1,36 @@
+package peering
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestReadDocument tests that configs are fetched over HTTPS and from S3 and other schemes refused.
+func TestReadDocument(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/peering.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("peers: {}\n"))
+	}))
+	defer server.Close()
+	defer func(c *http.Client) { configHTTPClient = c }(configHTTPClient)
+	configHTTPClient = server.Client()
+
+	if data, err := ReadDocument(server.URL+"/peering.yaml", nil); err != nil || string(data) != "peers: {}\n" {
+		t.Errorf("unexpected https result %q, %v", data, err)
+	}
+	if _, err := ReadDocument(server.URL+"/missing.yaml", nil); err == nil || ExitCodeOf(err) != ExitConfig {
+		t.Errorf("expected a config error for a missing document, got %v", err)
+	}
+	objects := fakeObjects{"s3://configs/peering.yaml": "peers: {}\n"}
+	if data, err := ReadDocument("s3://configs/peering.yaml", objects); err != nil || string(data) != "peers: {}\n" {
+		t.Errorf("unexpected s3 result %q, %v", data, err)
+	}
+	if _, err := ReadDocument("http://example.com/peering.yaml", nil); err == nil || !strings.Contains(err.Error(), "unsupported scheme") {
+		t.Errorf("expected plain http to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package peering
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Removed Blocks
+// -------------------------------------------------------------------------------------------------
+
+// What happens to the resources of a matrix entry set to state: absent.
+const (
+	OnRemoveDestroy = "destroy" // Staged decommissioning: routes are destroyed, then the peering (default).
+	OnRemoveForget  = "forget"  // Every resource is removed from state at once and left in AWS.
+)
+
+// ForgetOnRemove reports whether the entry's resources are to be forgotten rather than destroyed:
+// on_remove: forget, which requires state: absent and replaces its decommission stages.
+func (e MatrixEntry) ForgetOnRemove() (bool, error) {
+	switch e.OnRemove {
+	case "", OnRemoveDestroy:
+		return false, nil
+	case OnRemoveForget:
+	default:
+		return false, fmt.Errorf("unknown on_remove %q (want %s or %s)", e.OnRemove, OnRemoveDestroy, OnRemoveForget)
+	}
+	if e.State != StateAbsent && !e.Deprecated {
+		return false, fmt.Errorf("on_remove: forget requires state: absent")
+	}
+	if e.Decommission != "" {
+		return false, fmt.Errorf("on_remove: forget removes the whole connection at once and excludes decommission")
+	}
+	return true, nil
+}
+
+// ForgottenConnections returns the keys of the connections whose resources are forgotten, sorted,
+// for one source or, with an empty filter, every source.
+func ForgottenConnections(cfg YAMLConfig, sourceFilter string) ([]string, error) {
+	var keys []string
+	for _, source := range sortedMatrixSources(cfg) {
+		if sourceFilter != "" && source != sourceFilter {
+			continue
+		}
+		for _, entry := range expandMatrixEntries(cfg, source, cfg.PeeringMatrix[source], func(string, ...interface{}) {}) {
+			forget, err := entry.ForgetOnRemove()
+			if err != nil {
+				return nil, fmt.Errorf("%q -> %q: %w", source, entry.Peer, err)
+			}
+			if forget {
+				keys = append(keys, source+"/"+entry.Peer)
+			}
+		}
+	}
+	return keys, nil
+}
+
+// ForgottenAddresses returns the recorded addresses of the forgotten connections a previous address
+// map holds. Connections are only ever forgotten by the stack whose map records them.
+func ForgottenAddresses(previous AddressMap, keys []string) AddressMap {
+	out := make(AddressMap)
+	for _, key := range keys {
+		if addresses, ok := previous[key]; ok {
+			out[key] = addresses
+		}
+	}
+	return out
+}
+
+// AddRemovedBlocks writes a Terraform removed block with destroy = false for every address of the
+// forgotten connections, so the next apply drops them from state and leaves them in AWS for the tool
+// taking them over. Resources with count or for_each are removed with all their instances.
+func AddRemovedBlocks(stack cdktf.TerraformStack, forget AddressMap) {
+	var addresses []string
+	for _, kinds := range forget {
+		for _, address := range kinds {
+			addresses = append(addresses, address)
+		}
+	}
+	if len(addresses) == 0 {
+		return
+	}
+	sort.Strings(addresses)
+	blocks := make([]map[string]interface{}, 0, len(addresses))
+	for _, address := range addresses {
+		blocks = append(blocks, map[string]interface{}{"from": address, "lifecycle": map[string]bool{"destroy": false}})
+	}
+	stack.AddOverride(jsii.String("removed"), blocks)
+}
+
+// ForgetTargets returns, per stack, the addresses of the forgotten connections it last recorded: in
+// the address map given with CDKTF_MOVED_FROM, or else in the addresses.json of its previous synth.
+// A forgotten connection of a synthesized source that no stack recorded is an error, as its
+// addresses, and therefore its removed blocks, cannot be known.
+func ForgetTargets(cfg YAMLConfig, targets []synthTarget, movedFrom AddressMap) ([]AddressMap, error) {
+	forgets := make([]AddressMap, len(targets))
+	found := make(map[string]bool)
+	var required []string
+	for i, t := range targets {
+		keys, err := ForgottenConnections(cfg, t.Source)
+		if err != nil {
+			return nil, WithExitCode(ExitValidation, err)
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		required = append(required, keys...)
+		previous := movedFrom
+		if previous == nil {
+			path := filepath.Join(stackOutDir(t.Stack), AddressesFile)
+			if previous, err = LoadAddressMap(path); err != nil && !os.IsNotExist(err) {
+				return nil, WithExitCode(ExitConfig, err)
+			}
+		}
+		forgets[i] = ForgottenAddresses(previous, keys)
+		for key := range forgets[i] {
+			found[key] = true
+		}
+	}
+	for _, key := range required {
+		if !found[key] {
+			return nil, WithExitCode(ExitValidation, fmt.Errorf("%s has on_remove: forget, but no recorded addresses; "+
+				"synthesize it once before forgetting it, or pass its address map with CDKTF_MOVED_FROM", key))
+		}
+	}
+	return forgets, nil
+}
@@ -0,0 +1,68 @@
+package peering
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestForgetOnRemove tests the on_remove values and their requirements.
+func TestForgetOnRemove(t *testing.T) {
+	cases := []struct {
+		entry MatrixEntry
+		want  bool
+		err   string
+	}{
+		{MatrixEntry{Peer: "p"}, false, ""},
+		{MatrixEntry{Peer: "p", State: StateAbsent, OnRemove: OnRemoveDestroy}, false, ""},
+		{MatrixEntry{Peer: "p", State: StateAbsent, OnRemove: OnRemoveForget}, true, ""},
+		{MatrixEntry{Peer: "p", Deprecated: true, OnRemove: OnRemoveForget}, true, ""},
+		{MatrixEntry{Peer: "p", OnRemove: OnRemoveForget}, false, "requires state: absent"},
+		{MatrixEntry{Peer: "p", State: StateAbsent, OnRemove: OnRemoveForget, Decommission: DecommissionSourceRoutes}, false, "excludes decommission"},
+		{MatrixEntry{Peer: "p", OnRemove: "keep"}, false, "unknown on_remove"},
+	}
+	for _, tc := range cases {
+		got, err := tc.entry.ForgetOnRemove()
+		if got != tc.want || (err == nil) != (tc.err == "") || (err != nil && !strings.Contains(err.Error(), tc.err)) {
+			t.Errorf("%+v: expected %v, %q; got %v, %v", tc.entry, tc.want, tc.err, got, err)
+		}
+	}
+}
+
+// TestForgetTargets tests that forgotten connections get the addresses their stack last recorded.
+func TestForgetTargets(t *testing.T) {
+	outdir := t.TempDir()
+	t.Setenv("CDKTF_OUTDIR", outdir)
+	previous := AddressMap{
+		"dev-peer/prod-peer": {KindPeering: "aws_vpc_peering_connection.VpcPeering0"},
+		"dev-peer/qa-peer":   {KindPeering: "aws_vpc_peering_connection.VpcPeering1"},
+	}
+	if err := os.MkdirAll(stackOutDir(StackName), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteAddressMap(filepath.Join(stackOutDir(StackName), AddressesFile), previous); err != nil {
+		t.Fatal(err)
+	}
+	cfg := YAMLConfig{
+		Peers: map[string]YAMLPeer{"dev-peer": {}, "prod-peer": {}, "qa-peer": {}},
+		PeeringMatrix: map[string][]MatrixEntry{"dev-peer": {
+			{Peer: "prod-peer", State: StateAbsent, OnRemove: OnRemoveForget},
+			{Peer: "qa-peer"},
+		}},
+	}
+	targets := []synthTarget{{Stack: StackName}}
+	forgets, err := ForgetTargets(cfg, targets, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []AddressMap{{"dev-peer/prod-peer": previous["dev-peer/prod-peer"]}}
+	if !reflect.DeepEqual(forgets, want) {
+		t.Errorf("expected %v, got %v", want, forgets)
+	}
+
+	if _, err := ForgetTargets(cfg, targets, AddressMap{}); err == nil || !strings.Contains(err.Error(), "no recorded addresses") {
+		t.Errorf("expected an error for a connection without recorded addresses, got %v", err)
+	}
+}
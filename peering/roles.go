@@ -0,0 +1,113 @@
+package peering
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Role Fallback
+// -------------------------------------------------------------------------------------------------
+
+// RoleChoice is one candidate of a peer's role_arns: a role, optionally only for VPCs in one region.
+// In YAML it is either the bare ARN or a mapping with arn and region.
+type RoleChoice struct {
+	Arn    string `yaml:"arn"`              // IAM role ARN.
+	Region string `yaml:"region,omitempty"` // Region of the VPCs the role is for (any region if empty).
+}
+
+// UnmarshalYAML accepts both the bare ARN and the mapping form of a role choice.
+func (c *RoleChoice) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var arn string
+	if err := unmarshal(&arn); err == nil {
+		*c = RoleChoice{Arn: arn}
+		return nil
+	}
+	type plain RoleChoice
+	return unmarshal((*plain)(c))
+}
+
+// RoleCandidates returns the roles that may be assumed for the peer's VPC, in the order they are
+// tried: the role_arns entries for the VPC's region or for any region, in list order, or else
+// role_arn alone.
+func (p YAMLPeer) RoleCandidates() []string {
+	if len(p.RoleArns) == 0 {
+		if p.RoleArn == "" {
+			return nil
+		}
+		return []string{p.RoleArn}
+	}
+	region := ResolveRegion(p.Region)
+	var out []string
+	seen := make(map[string]bool)
+	for _, c := range p.RoleArns {
+		if (c.Region == "" || c.Region == region) && !seen[c.Arn] {
+			seen[c.Arn] = true
+			out = append(out, c.Arn)
+		}
+	}
+	return out
+}
+
+// ApplyRoleArns validates the role_arns of every peer and sets its role_arn to the first candidate
+// for its region, so everything downstream sees a single role. SelectRoleArns later moves to a
+// fallback when the first cannot be assumed. Setting both role_arn and role_arns is an error.
+func ApplyRoleArns(cfg *YAMLConfig) error {
+	for _, name := range sortedPeerNames(*cfg) {
+		peer := cfg.Peers[name]
+		if len(peer.RoleArns) == 0 {
+			continue
+		}
+		if peer.RoleArn != "" {
+			return fmt.Errorf("peer %q sets both role_arn and role_arns; keep one", name)
+		}
+		for i, c := range peer.RoleArns {
+			if err := ValidateRoleArn(c.Arn); err != nil {
+				return fmt.Errorf("peer %q: role_arns[%d] %w", name, i, err)
+			}
+			if err := ValidateRegion(c.Region, cfg.AllowedRegions); err != nil {
+				return fmt.Errorf("peer %q: role_arns[%d]: %w", name, i, err)
+			}
+		}
+		candidates := peer.RoleCandidates()
+		if len(candidates) == 0 {
+			return fmt.Errorf("peer %q: no role_arns entry applies to region %s", name, ResolveRegion(peer.Region))
+		}
+		peer.RoleArn = candidates[0]
+		cfg.Peers[name] = peer
+	}
+	return nil
+}
+
+// SelectRoleArns checks, for every peer with more than one candidate role, which one can be assumed,
+// by resolving its account with STS in the peer's region, and keeps the first that can. Falling back
+// is logged so the rotation can be completed; peers none of whose roles can be assumed are an error.
+// Peers with a single role are not checked.
+func SelectRoleArns(cfg *YAMLConfig, resolver AccountResolver) error {
+	for _, name := range sortedPeerNames(*cfg) {
+		peer := cfg.Peers[name]
+		candidates := peer.RoleCandidates()
+		if len(candidates) < 2 {
+			continue
+		}
+		peer.RoleArn = ""
+		var failures []string
+		for _, arn := range candidates {
+			if _, err := resolver.AccountID(ResolveRegion(peer.Region), arn); err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", arn, err))
+				continue
+			}
+			if len(failures) > 0 {
+				log.Printf("[roles] WARNING: peer %q cannot assume %s; falling back to %s", name, strings.Join(failures, "; "), arn)
+			}
+			peer.RoleArn = arn
+			break
+		}
+		if peer.RoleArn == "" {
+			return WithExitCode(ExitAWS, fmt.Errorf("peer %q: none of role_arns can be assumed: %s", name, strings.Join(failures, "; ")))
+		}
+		cfg.Peers[name] = peer
+	}
+	return nil
+}
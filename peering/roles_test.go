@@ -0,0 +1,94 @@
+package peering
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// assumableRoles is an AccountResolver that can only assume the roles it lists.
+type assumableRoles map[string]bool
+
+// AccountID implements AccountResolver.
+func (r assumableRoles) AccountID(region, roleArn string) (string, error) {
+	if !r[roleArn] {
+		return "", errors.New("AccessDenied")
+	}
+	return GetAccountIDFromRoleArn(roleArn), nil
+}
+
+const (
+	legacyRole = "arn:aws:iam::111111111111:role/legacy-peering"
+	newRole    = "arn:aws:iam::111111111111:role/vpc-peering"
+	euRole     = "arn:aws:iam::111111111111:role/vpc-peering-eu"
+)
+
+// TestApplyRoleArns tests that role_arns, from peer_defaults or the peer, are narrowed to the
+// region of the peer's VPC and the first candidate becomes its role_arn.
+func TestApplyRoleArns(t *testing.T) {
+	cfg, err := FromYAML([]byte(`
+peer_defaults:
+  role_arns:
+    - ` + newRole + `
+    - { arn: ` + euRole + `, region: eu-west-1 }
+peers:
+  us: { vpc_id: vpc-1, region: us-east-1 }
+  eu: { vpc_id: vpc-2, region: eu-west-1, role_arns: [` + euRole + `, ` + legacyRole + `] }
+  pinned: { vpc_id: vpc-3, role_arn: ` + legacyRole + ` }
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"us": newRole, "eu": euRole, "pinned": legacyRole} {
+		if got := cfg.Peers[name].RoleArn; got != want {
+			t.Errorf("%s: expected role %s, got %s", name, want, got)
+		}
+	}
+	if got := cfg.Peers["eu"].RoleCandidates(); len(got) != 2 || got[1] != legacyRole {
+		t.Errorf("expected the peer's own role_arns, got %v", got)
+	}
+
+	for _, peer := range []YAMLPeer{
+		{VpcID: "vpc-1", RoleArn: newRole, RoleArns: []RoleChoice{{Arn: legacyRole}}},
+		{VpcID: "vpc-1", RoleArns: []RoleChoice{{Arn: "not-an-arn"}}},
+		{VpcID: "vpc-1", RoleArns: []RoleChoice{{Arn: euRole, Region: "eu-west-1"}}},
+	} {
+		cfg := YAMLConfig{Peers: map[string]YAMLPeer{"dev": peer}}
+		if err := ApplyRoleArns(&cfg); err == nil {
+			t.Errorf("expected an error for %+v", peer)
+		}
+	}
+}
+
+// TestSelectRoleArns tests the ordered fallback to the first role that can be assumed.
+func TestSelectRoleArns(t *testing.T) {
+	newConfig := func() YAMLConfig {
+		cfg := YAMLConfig{Peers: map[string]YAMLPeer{
+			"dev":    {VpcID: "vpc-1", RoleArns: []RoleChoice{{Arn: newRole}, {Arn: legacyRole}}},
+			"single": {VpcID: "vpc-2", RoleArns: []RoleChoice{{Arn: euRole}}},
+		}}
+		if err := ApplyRoleArns(&cfg); err != nil {
+			t.Fatal(err)
+		}
+		return cfg
+	}
+
+	cfg := newConfig()
+	if err := SelectRoleArns(&cfg, assumableRoles{newRole: true, legacyRole: true}); err != nil || cfg.Peers["dev"].RoleArn != newRole {
+		t.Errorf("expected the primary role, got %s, %v", cfg.Peers["dev"].RoleArn, err)
+	}
+
+	cfg = newConfig()
+	if err := SelectRoleArns(&cfg, assumableRoles{legacyRole: true}); err != nil || cfg.Peers["dev"].RoleArn != legacyRole {
+		t.Errorf("expected the fallback role, got %s, %v", cfg.Peers["dev"].RoleArn, err)
+	}
+	if cfg.Peers["single"].RoleArn != euRole {
+		t.Errorf("expected a single role to be kept unchecked, got %s", cfg.Peers["single"].RoleArn)
+	}
+
+	cfg = newConfig()
+	err := SelectRoleArns(&cfg, assumableRoles{})
+	if err == nil || ExitCodeOf(err) != ExitAWS || !strings.Contains(err.Error(), legacyRole) {
+		t.Errorf("expected an AWS error naming every role, got %v", err)
+	}
+}
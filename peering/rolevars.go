@@ -0,0 +1,113 @@
+package peering
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Role Variables
+// -------------------------------------------------------------------------------------------------
+
+// RoleVariablePrefix prefixes the names of the Terraform variables carrying role ARNs.
+const RoleVariablePrefix = "role_arn_"
+
+// RoleVariables names one sensitive Terraform variable per distinct role ARN the connections assume,
+// after the first peer (in name order) that uses the role, and returns role ARN -> variable name.
+// Connections without a role use the ambient credentials and get no variable.
+func RoleVariables(peers []PeerConfig) map[string]string {
+	type use struct{ peer, roleArn string }
+	var uses []use
+	for _, peer := range peers {
+		uses = append(uses,
+			use{peer.SourceName, peer.SourceRoleArn},
+			use{ConnectionNameContext(0, peer).Peer, peer.PeerRoleArn},
+		)
+	}
+	sort.SliceStable(uses, func(i, j int) bool { return uses[i].peer < uses[j].peer })
+
+	vars := make(map[string]string)
+	taken := make(map[string]bool)
+	for _, u := range uses {
+		if u.roleArn == "" || vars[u.roleArn] != "" {
+			continue
+		}
+		base := RoleVariablePrefix + invalidIDChars.ReplaceAllString(u.peer, "_")
+		name := base
+		for i := 2; taken[name]; i++ {
+			name = fmt.Sprintf("%s_%d", base, i)
+		}
+		taken[name] = true
+		vars[u.roleArn] = name
+	}
+	return vars
+}
+
+// RoleVariableValues returns the values of the role variables as a tfvars document (variable name ->
+// role ARN).
+func RoleVariableValues(peers []PeerConfig) map[string]string {
+	values := make(map[string]string)
+	for roleArn, name := range RoleVariables(peers) {
+		values[name] = roleArn
+	}
+	return values
+}
+
+// AddRoleVariables declares the role variables as sensitive variables without defaults, so the
+// synthesized stack holds no role ARNs, and returns role ARN -> variable reference for the
+// providers.
+func AddRoleVariables(stack cdktf.TerraformStack, peers []PeerConfig) map[string]string {
+	refs := make(map[string]string)
+	vars := RoleVariables(peers)
+	for _, roleArn := range sortedStringKeys(vars) {
+		name := vars[roleArn]
+		cdktf.NewTerraformVariable(stack, jsii.String(name), &cdktf.TerraformVariableConfig{
+			Type:        jsii.String("string"),
+			Description: jsii.String("ARN of a role the peering providers assume"),
+			Sensitive:   jsii.Bool(true),
+		})
+		refs[roleArn] = "${var." + name + "}"
+	}
+	return refs
+}
+
+// sortedStringKeys returns the keys of a string map in order.
+func sortedStringKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// -------------------------------------------------------------------------------------------------
+// role-vars
+// -------------------------------------------------------------------------------------------------
+
+// runRoleVars prints the values of the role variables as a tfvars JSON document, to be kept outside
+// the repository (e.g. as a CI secret) and passed to plan and apply.
+func runRoleVars(args []string) error {
+	fs := flag.NewFlagSet("role-vars", flag.ContinueOnError)
+	out := fs.String("o", "", "write the tfvars JSON to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	_, peers := loadSourcePeers(sourceArg(fs))
+	data, err := json.MarshalIndent(RoleVariableValues(peers), "", "  ")
+	if err != nil {
+		return err
+	}
+	if *out != "" {
+		return os.WriteFile(*out, append(data, '\n'), 0o600)
+	}
+	fmt.Println(string(data))
+	return nil
+}
@@ -0,0 +1,30 @@
+package peering
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestRoleVariables tests that every distinct role gets one variable named after its first peer.
+func TestRoleVariables(t *testing.T) {
+	peers := []PeerConfig{
+		{SourceName: "prod", Name: "shared", SourceRoleArn: "arn:aws:iam::111111111111:role/a", PeerRoleArn: "arn:aws:iam::222222222222:role/b"},
+		{SourceName: "prod", Name: "dev.east", SourceRoleArn: "arn:aws:iam::111111111111:role/a", PeerRoleArn: "arn:aws:iam::333333333333:role/c"},
+		{SourceName: "dev.east", Name: "ambient", SourceRoleArn: "arn:aws:iam::444444444444:role/d"},
+	}
+
+	want := map[string]string{
+		"arn:aws:iam::111111111111:role/a": "role_arn_prod",
+		"arn:aws:iam::222222222222:role/b": "role_arn_shared",
+		"arn:aws:iam::333333333333:role/c": "role_arn_dev_east",
+		"arn:aws:iam::444444444444:role/d": "role_arn_dev_east_2",
+	}
+	if got := RoleVariables(peers); !reflect.DeepEqual(got, want) {
+		t.Errorf("RoleVariables() = %v, want %v", got, want)
+	}
+
+	values := RoleVariableValues(peers)
+	if values["role_arn_shared"] != "arn:aws:iam::222222222222:role/b" || len(values) != len(want) {
+		t.Errorf("RoleVariableValues() = %v", values)
+	}
+}
@@ -0,0 +1,141 @@
+package peering
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strings"
+
+	awsroute "cdk.tf/go/stack/generated/hashicorp/aws/route"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Staged Rollouts
+// -------------------------------------------------------------------------------------------------
+
+// RolloutVariable is the Terraform variable selecting how many rollout batches to apply.
+const RolloutVariable = "rollout_batch"
+
+// RolloutConfig stages the routes to new destinations across sequential applies, so a bad CIDR
+// reaches a few route tables before all of them. Only routes to the listed CIDRs are staged: a
+// route held back by the gate is destroyed if it already exists, so the list names the
+// destinations being added and is removed once the rollout is complete.
+type RolloutConfig struct {
+	Cidrs     []string `yaml:"cidrs,omitempty"`      // Destinations being rolled out.
+	BatchSize int      `yaml:"batch_size,omitempty"` // Route resources per batch.
+	Batch     int      `yaml:"batch,omitempty"`      // Default of the rollout_batch variable: batches applied (1 if unset).
+}
+
+// Enabled reports whether a rollout is configured.
+func (c RolloutConfig) Enabled() bool {
+	return len(c.Cidrs) > 0 || c.BatchSize != 0 || c.Batch != 0
+}
+
+// Validate requires the rolled out CIDRs and a positive batch size when a rollout is configured.
+func (c RolloutConfig) Validate() error {
+	if !c.Enabled() {
+		return nil
+	}
+	if len(c.Cidrs) == 0 {
+		return fmt.Errorf("rollout.cidrs must list the destinations being rolled out")
+	}
+	if _, err := parseCidrs(c.Cidrs); err != nil {
+		return fmt.Errorf("rollout.cidrs: %w", err)
+	}
+	if c.BatchSize < 1 {
+		return fmt.Errorf("rollout.batch_size must be at least 1, got %d", c.BatchSize)
+	}
+	if c.Batch < 0 {
+		return fmt.Errorf("rollout.batch must not be negative, got %d", c.Batch)
+	}
+	return nil
+}
+
+// DefaultBatch returns the default of the rollout_batch variable.
+func (c RolloutConfig) DefaultBatch() int {
+	if c.Batch == 0 {
+		return 1
+	}
+	return c.Batch
+}
+
+// canonicalCidr returns a CIDR block in the form net.IPNet prints, or the input when it is not one
+// (such as a token for a VPC CIDR looked up at plan time).
+func canonicalCidr(cidr string) string {
+	if _, n, err := net.ParseCIDR(cidr); err == nil {
+		return n.String()
+	}
+	return cidr
+}
+
+// RolloutBatches assigns a batch, from 1, to every route going to a rolled out CIDR, batch_size
+// route resources at a time in stack order. Other routes get 0 and are never gated.
+func RolloutBatches(routes []RouteInfo, cfg RolloutConfig) []int {
+	staged := make(map[string]bool, len(cfg.Cidrs))
+	for _, cidr := range cfg.Cidrs {
+		staged[canonicalCidr(cidr)] = true
+	}
+	batches := make([]int, len(routes))
+	n := 0
+	for i, r := range routes {
+		if r.Cidr == nil || !staged[canonicalCidr(*r.Cidr)] {
+			continue
+		}
+		batches[i] = n/cfg.BatchSize + 1
+		n++
+	}
+	return batches
+}
+
+// rolloutGate returns the override gating a route on the rollout_batch variable: a count for single
+// routes and an emptied for_each set for for_each routes.
+func rolloutGate(info RouteInfo, batch int) (string, string) {
+	cond := fmt.Sprintf("var.%s >= %d", RolloutVariable, batch)
+	if info.ForEach == "" {
+		return "count", fmt.Sprintf("${%s ? 1 : 0}", cond)
+	}
+	return "for_each", fmt.Sprintf("${%s ? %s : toset([])}", cond, info.ForEach)
+}
+
+// ApplyRollout gates the routes to the rolled out CIDRs on the rollout_batch variable, so applying
+// with rollout_batch=N creates the first N batches. It does nothing unless a rollout is configured.
+func ApplyRollout(stack cdktf.TerraformStack, connections []ConnectionResources, cfg RolloutConfig) {
+	if !cfg.Enabled() {
+		return
+	}
+	var routes []awsroute.Route
+	var infos []RouteInfo
+	for _, c := range connections {
+		for _, side := range []SideResources{c.Routes.Source, c.Routes.Peer} {
+			routes = append(routes, side.Routes...)
+			infos = append(infos, side.Info...)
+		}
+	}
+	batches := RolloutBatches(infos, cfg)
+	total, staged := 0, 0
+	for i, batch := range batches {
+		if batch == 0 {
+			continue
+		}
+		key, value := rolloutGate(infos[i], batch)
+		routes[i].AddOverride(jsii.String(key), value)
+		staged++
+		if batch > total {
+			total = batch
+		}
+	}
+	if staged == 0 {
+		log.Printf("[rollout] No route goes to %s; nothing is staged", strings.Join(cfg.Cidrs, ", "))
+		return
+	}
+
+	cdktf.NewTerraformVariable(stack, jsii.String(RolloutVariable), &cdktf.TerraformVariableConfig{
+		Type:        jsii.String("number"),
+		Description: jsii.String(fmt.Sprintf("Rollout batches to apply, of %d", total)),
+		Default:     jsii.Number(float64(cfg.DefaultBatch())),
+	})
+	log.Printf("[rollout] Staging %d route resource(s) to %s in %d batch(es); %s defaults to %d",
+		staged, strings.Join(cfg.Cidrs, ", "), total, RolloutVariable, cfg.DefaultBatch())
+}
@@ -0,0 +1,61 @@
+package peering
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/jsii-runtime-go"
+)
+
+// TestRolloutBatches tests that only routes to rolled out CIDRs are batched, in stack order.
+func TestRolloutBatches(t *testing.T) {
+	routes := []RouteInfo{
+		{Cidr: jsii.String("${TfToken[TOKEN.1]}")},
+		{Cidr: jsii.String("100.64.0.0/16")},
+		{Cidr: jsii.String("10.0.0.0/16")},
+		{Cidr: jsii.String("100.64.0.0/16"), ForEach: "toset([\"rtb-1\"])"},
+		{Cidr: jsii.String("100.64.0.0/16")},
+		{Cidr: jsii.String("100.64.0.0/16")},
+	}
+	cfg := RolloutConfig{Cidrs: []string{"100.64.1.0/16"}, BatchSize: 2}
+	want := []int{0, 1, 0, 1, 2, 2}
+	if got := RolloutBatches(routes, cfg); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+// TestRolloutGate tests the count and for_each overrides of gated routes.
+func TestRolloutGate(t *testing.T) {
+	key, value := rolloutGate(RouteInfo{}, 2)
+	if key != "count" || value != "${var.rollout_batch >= 2 ? 1 : 0}" {
+		t.Errorf("unexpected single route gate %s = %s", key, value)
+	}
+	key, value = rolloutGate(RouteInfo{ForEach: "toset(data.aws_route_tables.rts.ids)"}, 3)
+	if key != "for_each" || value != "${var.rollout_batch >= 3 ? toset(data.aws_route_tables.rts.ids) : toset([])}" {
+		t.Errorf("unexpected for_each route gate %s = %s", key, value)
+	}
+}
+
+// TestRolloutConfigValidate tests the rollout settings checks.
+func TestRolloutConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     RolloutConfig
+		wantErr bool
+	}{
+		{"unset", RolloutConfig{}, false},
+		{"valid", RolloutConfig{Cidrs: []string{"100.64.0.0/16"}, BatchSize: 5, Batch: 2}, false},
+		{"no cidrs", RolloutConfig{BatchSize: 5}, true},
+		{"invalid cidr", RolloutConfig{Cidrs: []string{"100.64.0.0"}, BatchSize: 5}, true},
+		{"no batch size", RolloutConfig{Cidrs: []string{"100.64.0.0/16"}}, true},
+		{"negative batch", RolloutConfig{Cidrs: []string{"100.64.0.0/16"}, BatchSize: 5, Batch: -1}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+	if (RolloutConfig{}).DefaultBatch() != 1 || (RolloutConfig{Batch: 3}).DefaultBatch() != 3 {
+		t.Error("unexpected default batch")
+	}
+}
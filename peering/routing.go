@@ -0,0 +1,407 @@
+package peering
+
+import (
+	"fmt"
+	"net"
+
+	dataawsroutetables "cdk.tf/go/stack/generated/hashicorp/aws/dataawsroutetables"
+	awsroute "cdk.tf/go/stack/generated/hashicorp/aws/route"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Routing Strategies
+// -------------------------------------------------------------------------------------------------
+
+// Routing strategies decide which route tables of one side of a connection receive peering routes.
+const (
+	RoutingNone     = "none"     // No route tables; routes are managed elsewhere or decommissioned.
+	RoutingMain     = "main"     // Only the main route table.
+	RoutingAll      = "all"      // Every route table in the VPC, including the main one.
+	RoutingFiltered = "filtered" // The main route table plus the tables of subnets matching tags.
+)
+
+// RoutingConfig configures route management for one side of a connection.
+type RoutingConfig struct {
+	Strategy            string            `yaml:"strategy"`                        // main, all, or filtered.
+	SubnetTags          map[string]string `yaml:"subnet_tags,omitempty"`           // Tags selecting subnets for the filtered strategy.
+	DedicatedRouteTable bool              `yaml:"dedicated_route_table,omitempty"` // Filtered only: move the matching subnets onto a route table the stack creates.
+	KeyByRouteTable     bool              `yaml:"key_by_route_table,omitempty"`    // Filtered only: key subnet routes by route table ID even with subnet_route_keys: subnet.
+}
+
+// Keys of the routes filtered routing adds to the route tables of matching subnets, set for the
+// whole config by subnet_route_keys.
+const (
+	SubnetRouteKeysRouteTable = "route_table" // One route per route table, keyed by its ID (default).
+	SubnetRouteKeysSubnet     = "subnet"      // One route per subnet, keyed by its ID, as before version 3.
+)
+
+// ValidateSubnetRouteKeys rejects unknown subnet_route_keys values.
+func ValidateSubnetRouteKeys(keys string) error {
+	if keys == "" || keys == SubnetRouteKeysRouteTable || keys == SubnetRouteKeysSubnet {
+		return nil
+	}
+	return fmt.Errorf("unknown subnet_route_keys %q (want %s or %s)", keys, SubnetRouteKeysRouteTable, SubnetRouteKeysSubnet)
+}
+
+// withSubnetRouteKeys keys the subnet routes of filtered routing by route table unless keys selects
+// subnet keys. A dedicated route table is a single table, whose routes need no keys.
+func (r RoutingConfig) withSubnetRouteKeys(keys string) RoutingConfig {
+	if r.Strategy == RoutingFiltered && !r.DedicatedRouteTable && keys != SubnetRouteKeysSubnet {
+		r.KeyByRouteTable = true
+	}
+	return r
+}
+
+// Sides of a connection an extra route can be added to.
+const (
+	ExtraRouteSource = "source" // Route tables of the source VPC (default).
+	ExtraRoutePeer   = "peer"   // Route tables of the peer VPC.
+)
+
+// ExtraRoute sends a destination beyond the other VPC's primary CIDR through the peering, in
+// addition to the connection's regular routes, in the route tables its side's routing selects.
+type ExtraRoute struct {
+	Cidr string `yaml:"cidr"`           // Destination CIDR block.
+	Side string `yaml:"side,omitempty"` // source (default) or peer.
+}
+
+// SplitExtraRoutes validates extra routes and returns their CIDRs per side, rejecting CIDRs listed
+// twice for one side.
+func SplitExtraRoutes(routes []ExtraRoute) (source, peer []string, err error) {
+	seen := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		if _, _, err := net.ParseCIDR(route.Cidr); err != nil {
+			return nil, nil, fmt.Errorf("invalid extra route: %w", err)
+		}
+		side := route.Side
+		if side == "" {
+			side = ExtraRouteSource
+		}
+		if seen[side+"|"+route.Cidr] {
+			return nil, nil, fmt.Errorf("extra route %s is listed twice for the %s side", route.Cidr, side)
+		}
+		seen[side+"|"+route.Cidr] = true
+		switch side {
+		case ExtraRouteSource:
+			source = append(source, route.Cidr)
+		case ExtraRoutePeer:
+			peer = append(peer, route.Cidr)
+		default:
+			return nil, nil, fmt.Errorf("unknown extra route side %q (want source or peer)", route.Side)
+		}
+	}
+	return source, peer, nil
+}
+
+// routingRank orders strategies from narrowest to broadest, for merging connections.
+var routingRank = map[string]int{RoutingNone: -1, RoutingMain: 0, RoutingFiltered: 1, RoutingAll: 2}
+
+// Validate checks that the strategy is known and that filtered routing selects subnets.
+func (r RoutingConfig) Validate() error {
+	if _, ok := routingRank[r.Strategy]; !ok {
+		return fmt.Errorf("unknown routing strategy %q (want none, main, all, or filtered)", r.Strategy)
+	}
+	if r.Strategy == RoutingFiltered && len(r.SubnetTags) == 0 {
+		return fmt.Errorf("filtered routing requires subnet_tags")
+	}
+	if r.DedicatedRouteTable && r.Strategy != RoutingFiltered {
+		return fmt.Errorf("dedicated_route_table requires filtered routing")
+	}
+	if r.KeyByRouteTable && (r.Strategy != RoutingFiltered || r.DedicatedRouteTable) {
+		return fmt.Errorf("key_by_route_table requires filtered routing without dedicated_route_table")
+	}
+	return nil
+}
+
+// legacyRouting reproduces has_additional_routes: main-table routes, plus routes for the tables of
+// subnets carrying the given marker tag when additional routes are enabled.
+func legacyRouting(additional bool, markerTag string) RoutingConfig {
+	if !additional {
+		return RoutingConfig{Strategy: RoutingMain}
+	}
+	return RoutingConfig{Strategy: RoutingFiltered, SubnetTags: map[string]string{markerTag: ""}}
+}
+
+// ResolveRouting picks the first explicitly configured routing block, falling back to the legacy
+// has_additional_routes behaviour.
+func ResolveRouting(fallback RoutingConfig, candidates ...*RoutingConfig) RoutingConfig {
+	for _, c := range candidates {
+		if c != nil {
+			return *c
+		}
+	}
+	return fallback
+}
+
+// broaderRouting returns whichever routing config covers more route tables. Of two filtered
+// configs, the one whose subnet tags are a subset of the other's selects every subnet both do; when
+// neither is, no single filter covers both and the configs cannot be merged.
+func broaderRouting(a, b RoutingConfig) (RoutingConfig, error) {
+	if a.Strategy == RoutingFiltered && b.Strategy == RoutingFiltered {
+		switch {
+		case tagsSubset(a.SubnetTags, b.SubnetTags):
+			return a, nil
+		case tagsSubset(b.SubnetTags, a.SubnetTags):
+			return b, nil
+		}
+		return a, fmt.Errorf("filtered routing by subnet_tags %v and %v selects subnets no single filter covers; "+
+			"use the same subnet_tags in both entries, or all", a.SubnetTags, b.SubnetTags)
+	}
+	if routingRank[b.Strategy] > routingRank[a.Strategy] {
+		return b, nil
+	}
+	return a, nil
+}
+
+// tagsSubset reports whether every tag of a is also in b with the same value.
+func tagsSubset(a, b map[string]string) bool {
+	for k, v := range a {
+		if w, ok := b[k]; !ok || w != v {
+			return false
+		}
+	}
+	return true
+}
+
+// -------------------------------------------------------------------------------------------------
+// Per-Side Route Creation
+// -------------------------------------------------------------------------------------------------
+
+// routeSide names the resource kinds used for one side of a connection.
+type routeSide struct {
+	MainRt         string
+	MainRoute      string
+	Subnets        string
+	SubnetRt       string
+	SubnetRoute    string
+	RouteTables    string
+	AllRoute       string
+	RtTag          string
+	RoutesParam    string
+	ProviderAlias  string
+	DedicatedRt    string
+	RtAssociation  string
+	ApplianceRoute string
+}
+
+var (
+	sourceSide = routeSide{
+		MainRt:         KindSourceMainRt,
+		MainRoute:      KindSourceMainRoute,
+		Subnets:        KindSourceSubnets,
+		SubnetRt:       KindSourceSubnetRt,
+		SubnetRoute:    KindSourceSubnetRoute,
+		RouteTables:    KindSourceRouteTables,
+		AllRoute:       KindSourceAllRoute,
+		RtTag:          KindSourceRtTag,
+		RoutesParam:    KindSourceRoutesParam,
+		ProviderAlias:  KindSourceProviderAlias,
+		DedicatedRt:    KindSourceDedicatedRt,
+		RtAssociation:  KindSourceRtAssociation,
+		ApplianceRoute: KindSourceApplianceRoute,
+	}
+	peerSide = routeSide{
+		MainRt:         KindPeerMainRt,
+		MainRoute:      KindPeerMainRoute,
+		Subnets:        KindPeerSubnets,
+		SubnetRt:       KindPeerSubnetRt,
+		SubnetRoute:    KindPeerSubnetRoute,
+		RouteTables:    KindPeerRouteTables,
+		AllRoute:       KindPeerAllRoute,
+		RtTag:          KindPeerRtTag,
+		RoutesParam:    KindPeerRoutesParam,
+		ProviderAlias:  KindPeerProviderAlias,
+		DedicatedRt:    KindPeerDedicatedRt,
+		RtAssociation:  KindPeerRtAssociation,
+		ApplianceRoute: KindPeerApplianceRoute,
+	}
+)
+
+// routeKinds lists the managed route kinds a routing config creates on one side.
+func (s routeSide) routeKinds(routing RoutingConfig) []string {
+	switch routing.Strategy {
+	case RoutingNone:
+		return nil
+	case RoutingAll:
+		return []string{s.AllRoute}
+	case RoutingFiltered:
+		return []string{s.MainRoute, s.SubnetRoute}
+	default:
+		return []string{s.MainRoute}
+	}
+}
+
+// managedRouteKinds lists routeKinds plus, when the side is inspected, its appliance route.
+func (s routeSide) managedRouteKinds(routing RoutingConfig, inspected bool) []string {
+	kinds := s.routeKinds(routing)
+	if inspected && len(kinds) > 0 {
+		kinds = append(kinds, s.ApplianceRoute)
+	}
+	return kinds
+}
+
+// CreateSideRoutes creates the routes of one side of a connection according to its routing config,
+// sending each destination CIDR (or the fallback VPC CIDR) and each extra CIDR through the peering.
+// All routing uses the declared route table IDs when given, and otherwise looks up the VPC's tables.
+func CreateSideRoutes(
+	stack cdktf.TerraformStack,
+	namer Namer,
+	ctx NameContext,
+	side routeSide,
+	routing RoutingConfig,
+	vpcID string,
+	mainRouteTableID *string,
+	routeTableIDs []string,
+	provider cdktf.TerraformProvider,
+	cidrs []string,
+	extra []string,
+	fallback *string,
+	peeringRes PeeringResources,
+) SideResources {
+	var res SideResources
+	if routing.Strategy == RoutingNone {
+		return res
+	}
+	if routing.Strategy == RoutingAll {
+		var ids *[]*string
+		forEach := fmt.Sprintf("toset(data.aws_route_tables.%s.ids)", namer.ID(ctx, side.RouteTables))
+		if len(routeTableIDs) > 0 {
+			ids = jsii.Strings(routeTableIDs...)
+			forEach = fmt.Sprintf("toset(%s)", hclStringList(routeTableIDs))
+		} else {
+			tables := dataawsroutetables.NewDataAwsRouteTables(stack, jsii.String(namer.ID(ctx, side.RouteTables)), &dataawsroutetables.DataAwsRouteTablesConfig{
+				VpcId:    jsii.String(vpcID),
+				Provider: provider,
+			})
+			res.DataSources = append(res.DataSources, tables)
+			ids = tables.Ids()
+		}
+		iterator := cdktf.TerraformIterator_FromList(ids)
+		for _, target := range RouteTargets(namer, ctx, side.AllRoute, cidrs, extra, fallback) {
+			res.Routes = append(res.Routes, awsroute.NewRoute(stack, jsii.String(target.ID), &awsroute.RouteConfig{
+				ForEach:                iterator,
+				RouteTableId:           jsii.String("${each.value}"),
+				DestinationCidrBlock:   target.Cidr,
+				VpcPeeringConnectionId: peeringRes.PeeringID(),
+				Provider:               provider,
+				DependsOn:              &peeringRes.DependsOn,
+			}))
+			res.Info = append(res.Info, RouteInfo{Cidr: target.Cidr, ForEach: forEach})
+		}
+		return res
+	}
+
+	for _, target := range RouteTargets(namer, ctx, side.MainRoute, cidrs, extra, fallback) {
+		res.Routes = append(res.Routes, CreateRoute(
+			stack,
+			target.ID,
+			mainRouteTableID,
+			target.Cidr,
+			peeringRes.PeeringID(),
+			provider,
+			peeringRes.DependsOn,
+		))
+		res.Info = append(res.Info, RouteInfo{Cidr: target.Cidr})
+	}
+
+	if routing.Strategy == RoutingFiltered && routing.DedicatedRouteTable {
+		isolated := CreateDedicatedSubnetRoutes(
+			stack,
+			namer,
+			ctx,
+			side,
+			RouteTargets(namer, ctx, side.SubnetRoute, cidrs, extra, fallback),
+			vpcID,
+			provider,
+			routing.SubnetTags,
+			peeringRes,
+		)
+		res.Routes = append(res.Routes, isolated.Routes...)
+		res.Info = append(res.Info, isolated.Info...)
+		res.DataSources = append(res.DataSources, isolated.DataSources...)
+		res.Tables = append(res.Tables, isolated.Tables...)
+	} else if routing.Strategy == RoutingFiltered {
+		filtered := CreateFilteredSubnetRoutes(
+			stack,
+			RouteTargets(namer, ctx, side.SubnetRoute, cidrs, extra, fallback),
+			namer.ID(ctx, side.Subnets),
+			vpcID,
+			provider,
+			routing.SubnetTags,
+			namer.ID(ctx, side.SubnetRt),
+			routing.KeyByRouteTable,
+			peeringRes.PeeringID(),
+			peeringRes.DependsOn,
+		)
+		res.Routes = append(res.Routes, filtered.Routes...)
+		res.Info = append(res.Info, filtered.Info...)
+		res.DataSources = append(res.DataSources, filtered.DataSources...)
+	}
+	return res
+}
+
+// CreateDedicatedRouteTable creates the route table and subnet associations that move the subnets
+// one side selects onto a table of their own. The table starts with only the VPC's local route, so
+// the subnets reach nothing but the VPC and the peering routes added to it.
+func CreateDedicatedRouteTable(
+	stack cdktf.TerraformStack,
+	namer Namer,
+	ctx NameContext,
+	side routeSide,
+	vpcID string,
+	provider cdktf.TerraformProvider,
+	subnets cdktf.TerraformDataSource,
+) (table, associations cdktf.TerraformResource) {
+	table = cdktf.NewTerraformResource(stack, jsii.String(namer.ID(ctx, side.DedicatedRt)), &cdktf.TerraformResourceConfig{
+		TerraformResourceType: jsii.String("aws_route_table"),
+		Provider:              provider,
+	})
+	table.AddOverride(jsii.String("vpc_id"), vpcID)
+	table.AddOverride(jsii.String("tags"), map[string]string{"Name": namer.NameTag(ctx) + " (dedicated)"})
+
+	associations = cdktf.NewTerraformResource(stack, jsii.String(namer.ID(ctx, side.RtAssociation)), &cdktf.TerraformResourceConfig{
+		TerraformResourceType: jsii.String("aws_route_table_association"),
+		Provider:              provider,
+	})
+	associations.AddOverride(jsii.String("for_each"), fmt.Sprintf("${toset(data.aws_subnets.%s.ids)}", *subnets.FriendlyUniqueId()))
+	associations.AddOverride(jsii.String("subnet_id"), "${each.value}")
+	associations.AddOverride(jsii.String("route_table_id"), table.GetStringAttribute(jsii.String("id")))
+	return table, associations
+}
+
+// CreateDedicatedSubnetRoutes looks up the subnets matching a tag filter, associates them with a
+// route table of their own, and routes each target through the peering in that table.
+func CreateDedicatedSubnetRoutes(
+	stack cdktf.TerraformStack,
+	namer Namer,
+	ctx NameContext,
+	side routeSide,
+	targets []RouteTarget,
+	vpcID string,
+	provider cdktf.TerraformProvider,
+	subnetTags map[string]string,
+	peeringRes PeeringResources,
+) SideResources {
+	subnets := LookupSubnets(stack, namer.ID(ctx, side.Subnets), vpcID, provider, subnetTags)
+	table, associations := CreateDedicatedRouteTable(stack, namer, ctx, side, vpcID, provider, subnets)
+	res := SideResources{
+		DataSources: []cdktf.TerraformDataSource{subnets},
+		Tables:      []cdktf.TerraformResource{table, associations},
+	}
+	tableID := table.GetStringAttribute(jsii.String("id"))
+	for _, target := range targets {
+		res.Routes = append(res.Routes, CreateRoute(
+			stack,
+			target.ID,
+			tableID,
+			target.Cidr,
+			peeringRes.PeeringID(),
+			provider,
+			peeringRes.DependsOn,
+		))
+		res.Info = append(res.Info, RouteInfo{Cidr: target.Cidr})
+	}
+	return res
+}
@@ -0,0 +1,138 @@
+package peering
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// TestRoutingConfigValidate tests the strategy checks, dedicated route tables included.
+func TestRoutingConfigValidate(t *testing.T) {
+	tags := map[string]string{"tier": "isolated"}
+	tests := []struct {
+		name    string
+		routing RoutingConfig
+		wantErr bool
+	}{
+		{"main", RoutingConfig{Strategy: RoutingMain}, false},
+		{"unknown", RoutingConfig{Strategy: "some"}, true},
+		{"filtered without tags", RoutingConfig{Strategy: RoutingFiltered}, true},
+		{"dedicated", RoutingConfig{Strategy: RoutingFiltered, SubnetTags: tags, DedicatedRouteTable: true}, false},
+		{"dedicated without filter", RoutingConfig{Strategy: RoutingAll, DedicatedRouteTable: true}, true},
+		{"keyed by route table", RoutingConfig{Strategy: RoutingFiltered, SubnetTags: tags, KeyByRouteTable: true}, false},
+		{"keyed by route table without filter", RoutingConfig{Strategy: RoutingMain, KeyByRouteTable: true}, true},
+		{"keyed by route table and dedicated", RoutingConfig{Strategy: RoutingFiltered, SubnetTags: tags, DedicatedRouteTable: true, KeyByRouteTable: true}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.routing.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+// TestDedicatedRouteTable tests the table and associations of a dedicated side, that they are
+// constructs the aspects and lifecycle reach, and that its addresses and provenance follow the new
+// table instead of the subnets' current ones.
+func TestDedicatedRouteTable(t *testing.T) {
+	peer := PeerConfig{
+		SourceName:    "dev",
+		Name:          "prod",
+		SourceRouting: RoutingConfig{Strategy: RoutingFiltered, SubnetTags: map[string]string{"tier": "isolated"}, DedicatedRouteTable: true},
+		PeerRouting:   RoutingConfig{Strategy: RoutingMain},
+		Lifecycle:     LifecycleConfig{IgnoreChanges: []string{"route_table_id"}},
+	}
+	ctx := ConnectionNameContext(0, peer)
+	stack := cdktf.NewTerraformStack(cdktf.Testing_App(nil), jsii.String("peering"))
+	side := CreateDedicatedSubnetRoutes(stack, LegacyNamer{}, ctx, sourceSide,
+		[]RouteTarget{{ID: "SourceToPeerRoute0", Cidr: jsii.String("10.1.0.0/16")}}, "vpc-1", nil, peer.SourceRouting.SubnetTags,
+		PeeringResources{RetiredID: jsii.String("pcx-0abc123")})
+	if len(side.Tables) != 2 || len(side.Routes) != 1 {
+		t.Fatalf("expected a table, its associations, and a route, got %d tables and %d routes", len(side.Tables), len(side.Routes))
+	}
+	ApplyLifecycle([]ConnectionResources{{Peer: peer, Routes: RouteResources{Source: side}}})
+	AddAspects(stack, AspectsConfig{EnforceTags: map[string]string{"CostCenter": "42"}, NamePrefix: "acme-"}.Build())
+
+	var synthesized struct {
+		Resource map[string]map[string]map[string]interface{} `json:"resource"`
+	}
+	if err := json.Unmarshal([]byte(*cdktf.Testing_Synth(stack, nil)), &synthesized); err != nil {
+		t.Fatal(err)
+	}
+	table := synthesized.Resource["aws_route_table"]["SourceDedicatedRouteTable0"]
+	tags, _ := table["tags"].(map[string]interface{})
+	if table["vpc_id"] != "vpc-1" || tags["CostCenter"] != "42" || tags["Name"] != "acme-"+(LegacyNamer{}).NameTag(ctx)+" (dedicated)" {
+		t.Errorf("unexpected route table: %v", table)
+	}
+	if _, ok := table["lifecycle"]; ok {
+		t.Errorf("expected route_table_id not to reach the route table, got %v", table["lifecycle"])
+	}
+	assoc := synthesized.Resource["aws_route_table_association"]["SourceRouteTableAssociation0"]
+	if assoc["for_each"] != "${toset(data.aws_subnets.SourceSubnets0.ids)}" || assoc["route_table_id"] != "${aws_route_table.SourceDedicatedRouteTable0.id}" {
+		t.Errorf("unexpected association: %v", assoc)
+	}
+	if lc, _ := assoc["lifecycle"].(map[string]interface{}); !reflect.DeepEqual(lc["ignore_changes"], []interface{}{"route_table_id"}) {
+		t.Errorf("expected the association to ignore route_table_id, got %v", assoc["lifecycle"])
+	}
+	if route := synthesized.Resource["aws_route"]["SourceToPeerRoute0"]; route["route_table_id"] != "${aws_route_table.SourceDedicatedRouteTable0.id}" {
+		t.Errorf("expected the route in the dedicated table, got %v", route["route_table_id"])
+	}
+
+	addresses := ConnectionAddresses(LegacyNamer{}, ctx, peer)
+	if addresses[KindSourceDedicatedRt] != "aws_route_table.SourceDedicatedRouteTable0" ||
+		addresses[KindSourceRtAssociation] != "aws_route_table_association.SourceRouteTableAssociation0" {
+		t.Errorf("expected the dedicated table and associations to be recorded, got %v", addresses)
+	}
+	if _, ok := addresses[KindPeerDedicatedRt]; ok {
+		t.Error("unexpected dedicated table on the peer side")
+	}
+
+	want := "concat([data.aws_route_table.SourceMainRouteTable0.id], [aws_route_table.SourceDedicatedRouteTable0.id])"
+	if expr := routeTablesExpr(LegacyNamer{}, ctx, sourceSide, peer.SourceRouting, "", nil); expr != want {
+		t.Errorf("expected %s, got %s", want, expr)
+	}
+}
+
+// TestCreateRouteTableKeyedSubnetRoutes tests that the local and the routes reference the route
+// tables lookup by its Terraform name when the legacy name carries characters CDKTF drops.
+func TestCreateRouteTableKeyedSubnetRoutes(t *testing.T) {
+	stack := cdktf.NewTerraformStack(cdktf.Testing_App(nil), jsii.String("peering"))
+	ctx := NameContext{Index: 0, Source: "dev", Peer: "prod.east"}
+	name := LegacyNamer{}.ID(ctx, KindSourceSubnetRt)
+	CreateRouteTableKeyedSubnetRoutes(stack, name, []RouteTarget{{ID: "Route", Cidr: jsii.String("10.1.0.0/16")}},
+		`["subnet-1"]`, nil, jsii.String("pcx-0abc123"), nil)
+
+	var synthesized struct {
+		Data struct {
+			RouteTables map[string]interface{} `json:"aws_route_tables"`
+		} `json:"data"`
+		Locals   map[string]string                 `json:"locals"`
+		Resource map[string]map[string]interface{} `json:"resource"`
+	}
+	if err := json.Unmarshal([]byte(*cdktf.Testing_Synth(stack, nil)), &synthesized); err != nil {
+		t.Fatal(err)
+	}
+
+	tfName := terraformName(name)
+	if tfName != "SourceSubnetToPeerRoute_prodeast_eachkey_0RouteTable" {
+		t.Fatalf("unexpected Terraform name %q", tfName)
+	}
+	if _, ok := synthesized.Data.RouteTables[tfName]; !ok {
+		t.Fatalf("expected the route tables lookup as %s, got %v", tfName, synthesized.Data.RouteTables)
+	}
+	local := SubnetRouteTablesLocal(tfName)
+	if want := "${toset(flatten(data.aws_route_tables." + tfName + "[*].ids))}"; synthesized.Locals[local] != want {
+		t.Errorf("local %s = %q, want %q (locals %v)", local, synthesized.Locals[local], want, synthesized.Locals)
+	}
+	route := synthesized.Resource["aws_route"]["Route"].(map[string]interface{})
+	if want := "${toset(local." + local + ")}"; route["for_each"] != want {
+		t.Errorf("route for_each = %v, want %s", route["for_each"], want)
+	}
+
+	keyed := RoutingConfig{Strategy: RoutingFiltered, KeyByRouteTable: true}
+	if got, want := routeTablesExpr(LegacyNamer{}, ctx, sourceSide, keyed, "rtb-1", nil), `concat(["rtb-1"], tolist(local.`+local+`))`; got != want {
+		t.Errorf("provenance route tables = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,259 @@
+package peering
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Runbooks
+// -------------------------------------------------------------------------------------------------
+
+// Runbook is everything the runbook of one source covers: the connections of its stack, with their
+// stack indices, and the connections of other stacks that peer with it.
+type Runbook struct {
+	Source  string       // Source peer the runbook is for.
+	Peer    YAMLPeer     // The source peer's config.
+	Own     []PeerConfig // Connections of the source's stack, in stack order.
+	Inbound []PeerConfig // Connections of other sources that target this one.
+	Namer   Namer        // Naming strategy of the stacks.
+}
+
+// BuildRunbook collects the runbook of a source from every resolved connection of the config. The
+// connections come sorted by source, so the source's own keep the indices of its CDKTF_SOURCE stack.
+func BuildRunbook(cfg YAMLConfig, source string, all []PeerConfig) Runbook {
+	rb := Runbook{Source: source, Peer: cfg.Peers[source], Namer: NewNamer(cfg.Naming)}
+	for _, peer := range all {
+		switch {
+		case peer.SourceName == source:
+			rb.Own = append(rb.Own, peer)
+		case ConnectionNameContext(0, peer).Peer == source:
+			rb.Inbound = append(rb.Inbound, peer)
+		}
+	}
+	return rb
+}
+
+// runbookRow is one table row of Markdown.
+func runbookRow(w io.Writer, cells ...string) {
+	fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+}
+
+// code wraps a value in backticks, or returns a dash for empty values.
+func code(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return "`" + value + "`"
+}
+
+// acceptanceMode describes how a connection's peering is accepted.
+func acceptanceMode(peer PeerConfig) string {
+	switch {
+	case peer.ExternalPeeringID != "":
+		return "created elsewhere (" + peer.ExternalPeeringID + ")"
+	case peer.PeeringOwner != "":
+		return "owned by " + code(peer.PeeringOwner) + ", looked up by VPC pair"
+	case peer.ManualAcceptance:
+		return "manual (" + AcceptStackName + ")"
+	case IsAutoAccept(peer):
+		return "auto-accepted by the requester"
+	default:
+		return "accepter in the peer account"
+	}
+}
+
+// connectionState describes whether a connection is active or being decommissioned.
+func connectionState(peer PeerConfig) string {
+	if peer.Decommission != "" {
+		return "decommissioning (" + peer.Decommission + " removed)"
+	}
+	return "present"
+}
+
+// routeDestinations describes where one side of a connection routes to.
+func routeDestinations(peer PeerConfig, sourceSide bool) string {
+	if !sourceSide {
+		return describeDestinations("VPC CIDR of "+peer.SourceName, nil, peer.PeerExtraCidrs)
+	}
+	return describeDestinations("VPC CIDR of "+ConnectionNameContext(0, peer).Peer, peer.DestinationCidrs, peer.SourceExtraCidrs)
+}
+
+// WriteRunbook renders the runbook of a source as Markdown.
+func WriteRunbook(w io.Writer, rb Runbook) {
+	stackCmd := fmt.Sprintf("CDKTF_SOURCE=%s", rb.Source)
+	fmt.Fprintf(w, "# Runbook: %s\n\n", rb.Source)
+	fmt.Fprintf(w, "_Generated by `go run . docs` from the peering config. Do not edit by hand; regenerate instead._\n\n")
+	fmt.Fprintf(w, "VPC %s in %s, account %s, role %s. Its own connections are synthesized with\n",
+		code(rb.Peer.VpcID), code(ResolveRegion(rb.Peer.Region)), code(GetAccountIDFromRoleArn(rb.Peer.RoleArn)), code(rb.Peer.RoleArn))
+	fmt.Fprintf(w, "`%s make synth` into `%s`.\n\n", stackCmd, stackOutDir(StackName))
+
+	fmt.Fprintf(w, "## Connections\n\n")
+	if len(rb.Own)+len(rb.Inbound) == 0 {
+		fmt.Fprintf(w, "None.\n\n")
+	} else {
+		runbookRow(w, "Connection", "Side", "Peer VPC", "Region", "Acceptance", "DNS resolution", "State", "Managed by")
+		runbookRow(w, "---", "---", "---", "---", "---", "---", "---", "---")
+		for _, peer := range rb.Own {
+			runbookRow(w, code(ConnectionKey(peer)), "requester", code(peer.PeerVpcID), code(ResolveRegion(peer.PeerRegion)),
+				acceptanceMode(peer), fmt.Sprint(peer.EnableDNSResolution), connectionState(peer), "this stack")
+		}
+		for _, peer := range rb.Inbound {
+			runbookRow(w, code(ConnectionKey(peer)), "accepter", code(peer.SourceVpcID), code(ResolveRegion(peer.SourceRegion)),
+				acceptanceMode(peer), fmt.Sprint(peer.EnableDNSResolution), connectionState(peer),
+				fmt.Sprintf("[%s](%s.md)", peer.SourceName, peer.SourceName))
+		}
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintf(w, "## Accounts and roles\n\n")
+	type identity struct{ name, region, account, role string }
+	identities := map[string]identity{rb.Source: {rb.Source, ResolveRegion(rb.Peer.Region), GetAccountIDFromRoleArn(rb.Peer.RoleArn), rb.Peer.RoleArn}}
+	for _, peer := range rb.Own {
+		name := ConnectionNameContext(0, peer).Peer
+		identities[name] = identity{name, ResolveRegion(peer.PeerRegion), PeerAccount(peer), peer.PeerRoleArn}
+	}
+	for _, peer := range rb.Inbound {
+		identities[peer.SourceName] = identity{peer.SourceName, ResolveRegion(peer.SourceRegion), GetAccountIDFromRoleArn(peer.SourceRoleArn), peer.SourceRoleArn}
+	}
+	names := make([]string, 0, len(identities))
+	for name := range identities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	runbookRow(w, "Peer", "Region", "Account", "Role")
+	runbookRow(w, "---", "---", "---", "---")
+	for _, name := range names {
+		id := identities[name]
+		runbookRow(w, name, code(id.region), code(id.account), code(id.role))
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "## Routes\n\n")
+	runbookRow(w, "Connection", "VPC", "Route tables", "Destination")
+	runbookRow(w, "---", "---", "---", "---")
+	for _, peer := range rb.Own {
+		routing := RequesterOnlyPeers([]PeerConfig{peer})[0]
+		runbookRow(w, code(ConnectionKey(peer)), rb.Source, describeRouting(routing.SourceRouting), routeDestinations(peer, true))
+		runbookRow(w, code(ConnectionKey(peer)), ConnectionNameContext(0, peer).Peer, describeRouting(routing.PeerRouting), routeDestinations(peer, false))
+	}
+	for _, peer := range rb.Inbound {
+		routing := RequesterOnlyPeers([]PeerConfig{peer})[0]
+		runbookRow(w, code(ConnectionKey(peer)), rb.Source, describeRouting(routing.PeerRouting), routeDestinations(peer, false))
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "## Managed resources\n\n")
+	if len(rb.Own) == 0 {
+		fmt.Fprintf(w, "None in this stack.\n\n")
+	}
+	routeTargets := make(map[string][]string)
+	for i, peer := range rb.Own {
+		addresses := ConnectionAddresses(rb.Namer, ConnectionNameContext(i, peer), peer)
+		list := make([]string, 0, len(addresses))
+		for _, address := range addresses {
+			list = append(list, address)
+			if strings.HasPrefix(address, "aws_route.") {
+				routeTargets[ConnectionKey(peer)] = append(routeTargets[ConnectionKey(peer)], address)
+			}
+		}
+		sort.Strings(list)
+		fmt.Fprintf(w, "%s:\n\n", code(ConnectionKey(peer)))
+		for _, address := range list {
+			fmt.Fprintf(w, "- `%s`\n", address)
+		}
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintf(w, "## Rollback\n\n")
+	fmt.Fprintf(w, "1. Revert the config change, run `%s make synth plan` to confirm the plan only undoes it, then `make deploy`.\n", stackCmd)
+	fmt.Fprintf(w, "2. To drain a connection without deleting the peering, set `state: absent` on its entry under `%s` in `peering_matrix` and apply; this removes its routes only.\n", rb.Source)
+	fmt.Fprintf(w, "3. To remove a connection entirely, delete its entry and apply. Connections marked as managed by another stack are rolled back in that stack's runbook.\n")
+	fmt.Fprintf(w, "4. In an emergency, remove one connection's routes directly:\n\n")
+	keys := make([]string, 0, len(routeTargets))
+	for key := range routeTargets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if len(keys) == 0 {
+		fmt.Fprintf(w, "   No routes are managed by this stack.\n")
+	}
+	for _, key := range keys {
+		targets := routeTargets[key]
+		sort.Strings(targets)
+		fmt.Fprintf(w, "   ```sh\n   # %s\n   terraform -chdir=%s apply -destroy", key, stackOutDir(StackName))
+		for _, target := range targets {
+			fmt.Fprintf(w, " -target=%s", target)
+		}
+		fmt.Fprintf(w, "\n   ```\n")
+	}
+}
+
+// -------------------------------------------------------------------------------------------------
+// docs
+// -------------------------------------------------------------------------------------------------
+
+// runDocs renders a runbook per peer with connections into a directory, or checks that the committed runbooks are
+// current so they cannot drift from the config.
+func runDocs(args []string) error {
+	fs := flag.NewFlagSet("docs", flag.ContinueOnError)
+	dir := fs.String("o", "runbooks", "directory to write <source>.md runbooks to (- for stdout)")
+	check := fs.Bool("check", false, "fail if the runbooks in the directory are missing or out of date instead of writing them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, all := loadSourcePeers("")
+	var sources []string
+	if source := sourceArg(fs); source != "" {
+		if _, ok := cfg.Peers[source]; !ok {
+			return fmt.Errorf("unknown peer %q", source)
+		}
+		sources = append(sources, source)
+	} else {
+		for name := range cfg.Peers {
+			sources = append(sources, name)
+		}
+		sort.Strings(sources)
+	}
+
+	var stale []string
+	for _, source := range sources {
+		rb := BuildRunbook(cfg, source, all)
+		if len(rb.Own)+len(rb.Inbound) == 0 {
+			continue
+		}
+		var buf bytes.Buffer
+		WriteRunbook(&buf, rb)
+		if *dir == "-" {
+			os.Stdout.Write(buf.Bytes())
+			continue
+		}
+
+		path := filepath.Join(*dir, source+".md")
+		if *check {
+			if current, err := os.ReadFile(path); err != nil || !bytes.Equal(current, buf.Bytes()) {
+				stale = append(stale, path)
+			}
+			continue
+		}
+		if err := os.MkdirAll(*dir, 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+			return err
+		}
+		log.Printf("[docs] Wrote %s", path)
+	}
+	if len(stale) > 0 {
+		return fmt.Errorf("runbooks out of date (run docs to regenerate): %s", strings.Join(stale, ", "))
+	}
+	return nil
+}
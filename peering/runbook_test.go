@@ -0,0 +1,53 @@
+package peering
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestBuildRunbook tests that a peer's runbook holds its own connections and those targeting it.
+func TestBuildRunbook(t *testing.T) {
+	all := []PeerConfig{
+		{SourceName: "dev", Name: "hub", SourceVpcID: "vpc-1", PeerVpcID: "vpc-2"},
+		{SourceName: "hub", Name: "prod", SourceVpcID: "vpc-2", PeerVpcID: "vpc-3"},
+		{SourceName: "hub", Name: "qa", SourceVpcID: "vpc-2", PeerVpcID: "vpc-4"},
+	}
+	rb := BuildRunbook(YAMLConfig{}, "hub", all)
+	if len(rb.Own) != 2 || rb.Own[0].Name != "prod" || rb.Own[1].Name != "qa" {
+		t.Errorf("unexpected own connections: %+v", rb.Own)
+	}
+	if len(rb.Inbound) != 1 || rb.Inbound[0].SourceName != "dev" {
+		t.Errorf("unexpected inbound connections: %+v", rb.Inbound)
+	}
+}
+
+// TestWriteRunbook tests the sections and rollback commands of a rendered runbook.
+func TestWriteRunbook(t *testing.T) {
+	peers := []PeerConfig{
+		{SourceName: "dev", Name: "prod", SourceVpcID: "vpc-1", PeerVpcID: "vpc-2", SourceRegion: "us-east-1", PeerRegion: "us-west-2",
+			SourceRoleArn: "arn:aws:iam::111111111111:role/r", PeerRoleArn: "arn:aws:iam::222222222222:role/r"},
+		{SourceName: "dev", Name: "qa", SourceVpcID: "vpc-1", PeerVpcID: "vpc-3", ManualAcceptance: true},
+	}
+	var buf bytes.Buffer
+	WriteRunbook(&buf, Runbook{Source: "dev", Own: peers, Namer: LegacyNamer{}})
+	out := buf.String()
+
+	for _, want := range []string{
+		"# Runbook: dev",
+		"| `dev/prod` | requester | `vpc-2` | `us-west-2` | accepter in the peer account |",
+		"| `dev/qa` | requester | `vpc-3` |",
+		"manual (" + AcceptStackName + ")",
+		"| prod | `us-west-2` | `222222222222` | `arn:aws:iam::222222222222:role/r` |",
+		"- `aws_vpc_peering_connection_accepter.VpcPeeringAccepter0`",
+		"# dev/prod\n   terraform -chdir=",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("runbook misses %q:\n%s", want, out)
+		}
+	}
+	// The manually accepted connection routes nothing until the accept stack takes it over.
+	if strings.Contains(out, "# dev/qa") {
+		t.Errorf("unexpected route rollback for a connection awaiting acceptance:\n%s", out)
+	}
+}
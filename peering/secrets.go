@@ -0,0 +1,178 @@
+package peering
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Secret References
+// -------------------------------------------------------------------------------------------------
+
+// secretRefPattern matches a secret reference in a config value: ${<store>:<path>} or
+// ${<store>:<path>#<key>}.
+var secretRefPattern = regexp.MustCompile(`\$\{([a-z][a-z0-9-]*):([^}#]+)(?:#([^}]+))?\}`)
+
+// SecretResolver reads a secret from one store: the value at path, or the field key of the
+// structured secret at path when key is set. Implemented by the store CLIs and by fakes in tests.
+type SecretResolver interface {
+	Resolve(path, key string) (string, error)
+}
+
+// SecretResolvers are the stores config values can reference, keyed by the name used in
+// references. Supporting another store means adding its resolver here.
+var SecretResolvers = map[string]SecretResolver{
+	"vault":       vaultResolver{},
+	"aws-secrets": secretsManagerResolver{},
+}
+
+// ResolveSecrets replaces the secret references in the string values of a config document with
+// the secrets they name, so role ARNs and external IDs can live in a secrets store instead of the
+// repo. A reference can be a whole value or part of one; each distinct reference is read once.
+// Documents without references are returned unchanged. The secrets only live in memory.
+func ResolveSecrets(data []byte, resolvers map[string]SecretResolver) ([]byte, error) {
+	if !secretRefPattern.Match(data) {
+		return data, nil
+	}
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	cache := make(map[string]string)
+	resolve := func(value string) (string, error) {
+		var firstErr error
+		resolved := secretRefPattern.ReplaceAllStringFunc(value, func(ref string) string {
+			if firstErr != nil {
+				return ref
+			}
+			if secret, ok := cache[ref]; ok {
+				return secret
+			}
+			m := secretRefPattern.FindStringSubmatch(ref)
+			resolver, ok := resolvers[m[1]]
+			if !ok {
+				firstErr = fmt.Errorf("%s: unknown secret store %q", ref, m[1])
+				return ref
+			}
+			secret, err := resolver.Resolve(m[2], m[3])
+			if err != nil {
+				firstErr = fmt.Errorf("%s: %w", ref, err)
+				return ref
+			}
+			cache[ref] = secret
+			return secret
+		})
+		return resolved, firstErr
+	}
+	resolved, err := resolveSecretValues(doc, resolve)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(resolved)
+}
+
+// resolveSecretValues walks a decoded YAML document, passing every string value (not map keys)
+// through resolve.
+func resolveSecretValues(node interface{}, resolve func(string) (string, error)) (interface{}, error) {
+	switch v := node.(type) {
+	case string:
+		return resolve(v)
+	case map[interface{}]interface{}:
+		for key, value := range v {
+			resolved, err := resolveSecretValues(value, resolve)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = resolved
+		}
+	case []interface{}:
+		for i, value := range v {
+			resolved, err := resolveSecretValues(value, resolve)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = resolved
+		}
+	}
+	return node, nil
+}
+
+// runSecretCommand runs a store CLI and returns its output, with its error message on failure.
+func runSecretCommand(name string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s %s: %w: %s", name, args[0], err, msg)
+		}
+		return nil, fmt.Errorf("%s %s: %w", name, args[0], err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// secretField returns the field key of a JSON object secret, or the whole secret when key is
+// empty.
+func secretField(secret, key string) (string, error) {
+	if key == "" {
+		return secret, nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object, so it has no key %q", key)
+	}
+	value, ok := fields[key]
+	if !ok {
+		return "", fmt.Errorf("secret has no key %q", key)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(value), nil
+}
+
+// vaultResolver reads KV secrets with the vault binary, which finds the server and token the way
+// it does on the command line (VAULT_ADDR, VAULT_TOKEN or ~/.vault-token). Paths are those of
+// "vault kv get", e.g. secret/peering for a KV v2 mount named secret.
+type vaultResolver struct{}
+
+// Resolve returns a field of the secret at path; Vault secrets are always key/value maps.
+func (vaultResolver) Resolve(path, key string) (string, error) {
+	if key == "" {
+		return "", fmt.Errorf("vault references need a key, as in ${vault:%s#<key>}", path)
+	}
+	out, err := runSecretCommand("vault", "kv", "get", "-field="+key, path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
+
+// secretsManagerResolver reads secrets from AWS Secrets Manager with the aws CLI and the ambient
+// credentials. The region is that of the name when it is an ARN, else AWS_REGION, else
+// DefaultRegion.
+type secretsManagerResolver struct{}
+
+// Resolve returns the SecretString of the named secret, or one key of it when it holds JSON.
+func (secretsManagerResolver) Resolve(name, key string) (string, error) {
+	region := os.Getenv("AWS_REGION")
+	if parts := strings.Split(name, ":"); len(parts) > 3 && parts[0] == "arn" {
+		region = parts[3]
+	}
+	var out struct {
+		SecretString string `json:"SecretString"`
+	}
+	cli := &AWSCLI{Region: ResolveRegion(region), Env: os.Environ()}
+	if err := cli.Run(&out, "secretsmanager", "get-secret-value", "--secret-id", name); err != nil {
+		return "", err
+	}
+	return secretField(out.SecretString, key)
+}
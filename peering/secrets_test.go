@@ -0,0 +1,101 @@
+package peering
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+// fakeSecrets resolves references from a map keyed by "path#key", counting the reads.
+type fakeSecrets struct {
+	values map[string]string
+	reads  int
+}
+
+func (f *fakeSecrets) Resolve(path, key string) (string, error) {
+	f.reads++
+	value, ok := f.values[path+"#"+key]
+	if !ok {
+		return "", fmt.Errorf("no secret %s#%s", path, key)
+	}
+	return value, nil
+}
+
+// TestResolveSecrets tests that references in string values are replaced, whole or embedded, and
+// that each reference is read once.
+func TestResolveSecrets(t *testing.T) {
+	vault := &fakeSecrets{values: map[string]string{
+		"secret/peering#dev_role": "arn:aws:iam::111111111111:role/r",
+		"secret/peering#account":  "222222222222",
+	}}
+	resolvers := map[string]SecretResolver{"vault": vault}
+	data := `peers:
+  dev:
+    vpc_id: vpc-1
+    role_arn: ${vault:secret/peering#dev_role}
+  prod:
+    vpc_id: vpc-2
+    role_arn: ${vault:secret/peering#dev_role}
+    tags:
+      - arn:aws:iam::${vault:secret/peering#account}:root
+`
+	out, err := ResolveSecrets([]byte(data), resolvers)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var cfg YAMLConfig
+	if err := yaml.Unmarshal(out, &cfg); err != nil {
+		t.Fatalf("resolved config does not parse: %v", err)
+	}
+	if cfg.Peers["dev"].RoleArn != "arn:aws:iam::111111111111:role/r" || cfg.Peers["prod"].RoleArn != "arn:aws:iam::111111111111:role/r" {
+		t.Errorf("role ARNs were not resolved:\n%s", out)
+	}
+	if !strings.Contains(string(out), "arn:aws:iam::222222222222:root") {
+		t.Errorf("embedded reference was not resolved:\n%s", out)
+	}
+	if vault.reads != 2 {
+		t.Errorf("expected 2 reads, got %d", vault.reads)
+	}
+
+	plain := "peers:\n  dev:\n    vpc_id: vpc-1\n"
+	if out, err := ResolveSecrets([]byte(plain), resolvers); err != nil || string(out) != plain {
+		t.Errorf("expected a config without references unchanged, got %q, %v", out, err)
+	}
+}
+
+// TestResolveSecretsErrors tests that unknown stores and failed reads name the reference.
+func TestResolveSecretsErrors(t *testing.T) {
+	resolvers := map[string]SecretResolver{"vault": &fakeSecrets{}}
+	for _, tc := range []struct {
+		data, want string
+	}{
+		{"role_arn: ${keychain:peering#role}", `unknown secret store "keychain"`},
+		{"role_arn: ${vault:secret/peering#role}", "${vault:secret/peering#role}: no secret secret/peering#role"},
+	} {
+		if _, err := ResolveSecrets([]byte(tc.data), resolvers); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", tc.data, tc.want, err)
+		}
+	}
+}
+
+// TestSecretField tests reading one key of a JSON secret.
+func TestSecretField(t *testing.T) {
+	secret := `{"role_arn":"arn:aws:iam::111111111111:role/r","port":5432}`
+	for _, tc := range []struct {
+		secret, key, want string
+		wantErr           bool
+	}{
+		{secret, "", secret, false},
+		{secret, "role_arn", "arn:aws:iam::111111111111:role/r", false},
+		{secret, "port", "5432", false},
+		{secret, "external_id", "", true},
+		{"plain", "role_arn", "", true},
+	} {
+		got, err := secretField(tc.secret, tc.key)
+		if got != tc.want || (err != nil) != tc.wantErr {
+			t.Errorf("%s#%s: expected %q (error %v), got %q, %v", tc.secret, tc.key, tc.want, tc.wantErr, got, err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package peering
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// -------------------------------------------------------------------------------------------------
+// SOPS-Encrypted Configs
+// -------------------------------------------------------------------------------------------------
+
+// IsSopsEncrypted reports whether a YAML document carries the sops metadata block that SOPS adds
+// when it encrypts a file, wholly or only some of its values (e.g. with encrypted_regex: ^role_arn$).
+func IsSopsEncrypted(data []byte) bool {
+	var doc struct {
+		Sops map[string]interface{} `yaml:"sops"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return false
+	}
+	_, ok := doc.Sops["mac"]
+	return ok
+}
+
+// DecryptConfig returns the plaintext of a config file, calling decrypt for files encrypted with
+// SOPS and returning the data of other files unchanged.
+func DecryptConfig(path string, data []byte, decrypt func(path string) ([]byte, error)) ([]byte, error) {
+	if !IsSopsEncrypted(data) {
+		return data, nil
+	}
+	plain, err := decrypt(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s with sops: %w", path, err)
+	}
+	return plain, nil
+}
+
+// sopsDecrypt decrypts a file with the sops binary, which finds the age, KMS, or other keys the
+// file was encrypted for the way it does on the command line (SOPS_AGE_KEY_FILE, AWS credentials).
+func sopsDecrypt(path string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("sops", "--decrypt", "--input-type", "yaml", "--output-type", "yaml", path)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+// ReadConfigFile reads a config file, or fetches it from an https:// or s3:// URL, verifying it
+// when CDKTF_CONFIG_VERIFY is set and decrypting it when it is SOPS-encrypted. The plaintext only
+// lives in memory.
+func ReadConfigFile(path string) ([]byte, error) {
+	s3 := configObjectReader()
+	data, err := ReadDocument(path, s3)
+	if err != nil {
+		return nil, err
+	}
+	if err := VerifyDocument(path, data, os.Getenv(ConfigVerifyEnvVar), s3); err != nil {
+		return nil, err
+	}
+	return DecryptConfig(path, data, sopsDecrypter(path, data))
+}
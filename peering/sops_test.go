@@ -0,0 +1,52 @@
+package peering
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// sopsConfig is a config whose role ARNs were encrypted with encrypted_regex: ^role_arn$.
+const sopsConfig = `peers:
+  dev:
+    vpc_id: vpc-1
+    role_arn: ENC[AES256_GCM,data:abc=,iv:def=,tag:ghi=,type:str]
+sops:
+  age:
+    - recipient: age1qqq
+  mac: ENC[AES256_GCM,data:jkl=,iv:mno=,tag:pqr=,type:str]
+  encrypted_regex: ^role_arn$
+  version: 3.9.0
+`
+
+// TestDecryptConfig tests that only SOPS-encrypted configs are passed to the decrypter.
+func TestDecryptConfig(t *testing.T) {
+	plain := "peers:\n  dev:\n    vpc_id: vpc-1\n    role_arn: arn:aws:iam::111111111111:role/r\n"
+	decrypt := func(path string) ([]byte, error) {
+		if path != "peering.yaml" {
+			t.Errorf("unexpected path %s", path)
+		}
+		return []byte(plain), nil
+	}
+
+	for _, tc := range []struct {
+		name, data, want string
+	}{
+		{"encrypted", sopsConfig, plain},
+		{"plaintext", plain, plain},
+		{"sops peer name", "peers:\n  sops:\n    vpc_id: vpc-1\n", "peers:\n  sops:\n    vpc_id: vpc-1\n"},
+		{"invalid yaml", "peers: [", "peers: ["},
+	} {
+		got, err := DecryptConfig("peering.yaml", []byte(tc.data), decrypt)
+		if err != nil || string(got) != tc.want {
+			t.Errorf("%s: expected %q, got %q, %v", tc.name, tc.want, got, err)
+		}
+	}
+
+	_, err := DecryptConfig("peering.yaml", []byte(sopsConfig), func(string) ([]byte, error) {
+		return nil, errors.New("no key")
+	})
+	if err == nil || !strings.Contains(err.Error(), "failed to decrypt peering.yaml") {
+		t.Errorf("expected a decryption error, got %v", err)
+	}
+}
@@ -0,0 +1,363 @@
+package peering
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Source Selection
+// -------------------------------------------------------------------------------------------------
+
+// IsSourcePattern reports whether a CDKTF_SOURCE value selects several sources, with a
+// comma-separated list or a glob, rather than naming one.
+func IsSourcePattern(value string) bool {
+	return strings.ContainsAny(value, ",*?[")
+}
+
+// MatchSources returns the matrix sources selected by a comma-separated list of names and globs
+// (e.g. "prod-*,shared-services"), sorted. Every item must match a source, so a typo is an error
+// rather than a stack that silently goes missing.
+func MatchSources(cfg YAMLConfig, value string) ([]string, error) {
+	sources := make([]string, 0, len(cfg.PeeringMatrix))
+	for source := range cfg.PeeringMatrix {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	matched := make(map[string]bool)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		found := false
+		for _, source := range sources {
+			ok, err := path.Match(item, source)
+			if err != nil {
+				return nil, fmt.Errorf("invalid source pattern %q: %w", item, err)
+			}
+			if ok {
+				matched[source] = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%q matches no source in peering_matrix", item)
+		}
+	}
+
+	var out []string
+	for _, source := range sources {
+		if matched[source] {
+			out = append(out, source)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("%q selects no source", value)
+	}
+	return out, nil
+}
+
+// SourceStackName returns the stack ID of a source synthesized together with others: the main
+// stack name suffixed with the source, so each gets its own directory under cdktf.out/stacks.
+func SourceStackName(source string) string {
+	return StackName + "-" + source
+}
+
+// Stack partitioning modes: how every stack is divided into stacks with state of their own.
+const (
+	StackPartitionNone          = "none"            // One stack per source selection (default).
+	StackPartitionByRegion      = "by_region"       // One stack per peer region.
+	StackPartitionByPeerAccount = "by_peer_account" // One stack per peer account.
+)
+
+// ValidateStackPartitioning rejects unknown stack_partitioning modes.
+func ValidateStackPartitioning(mode string) error {
+	switch mode {
+	case "", StackPartitionNone, StackPartitionByRegion, StackPartitionByPeerAccount:
+		return nil
+	}
+	return fmt.Errorf("unknown stack_partitioning %q (want %s, %s, or %s)",
+		mode, StackPartitionByRegion, StackPartitionByPeerAccount, StackPartitionNone)
+}
+
+// partitionKey returns the part of the stack a connection belongs to under a partitioning mode.
+// Connections requested as same-account share the "same-account" part.
+func partitionKey(peer PeerConfig, mode string) string {
+	if mode == StackPartitionByRegion {
+		return ResolveRegion(peer.PeerRegion)
+	}
+	if account := PeerAccount(peer); account != "" {
+		return account
+	}
+	return "same-account"
+}
+
+// SelectTargets returns the stacks a synth of sourceID builds before they are partitioned and
+// split: one for a single source ("" matches all sources), or one per source a pattern matches, each
+// with its connections and the VPCs it associates with the lattice service network.
+func SelectTargets(cfg YAMLConfig, sourceID string) ([]synthTarget, error) {
+	targets := []synthTarget{{Stack: StackName, Source: sourceID}}
+	if IsSourcePattern(sourceID) {
+		sources, err := MatchSources(cfg, sourceID)
+		if err != nil {
+			return nil, WithExitCode(ExitValidation, fmt.Errorf("CDKTF_SOURCE: %w", err))
+		}
+		log.Printf("[config] CDKTF_SOURCE %q matches %d source(s): %s", sourceID, len(sources), strings.Join(sources, ", "))
+		targets = targets[:0]
+		for _, source := range sources {
+			targets = append(targets, synthTarget{Stack: SourceStackName(source), Source: source})
+		}
+	}
+	if cfg.Lattice != nil {
+		if err := cfg.Lattice.Validate(); err != nil {
+			return nil, WithExitCode(ExitValidation, fmt.Errorf("invalid lattice settings: %w", err))
+		}
+	}
+	associated := make(map[string]string)
+	for i := range targets {
+		targets[i].Peers = ConvertToPeerConfigs(cfg, targets[i].Source)
+		associations, err := LatticeAssociations(cfg, targets[i].Source)
+		if err != nil {
+			return nil, WithExitCode(ExitValidation, err)
+		}
+		// A VPC is associated once, by the first stack that connects it.
+		for _, a := range associations {
+			if stack, ok := associated[a.VpcID]; ok {
+				log.Printf("[convert] %s is associated with the service network by stack %s", a.Peer, stack)
+				continue
+			}
+			associated[a.VpcID] = targets[i].Stack
+			targets[i].Lattice = append(targets[i].Lattice, a)
+		}
+		if len(targets[i].Peers) == 0 && len(targets[i].Lattice) == 0 {
+			return nil, WithExitCode(ExitValidation, fmt.Errorf("no peers matched for source: %s", targets[i].Source))
+		}
+	}
+	return targets, nil
+}
+
+// PlaceTargets partitions the stacks by stack_partitioning and splits them by
+// max_providers_per_stack, keeping connections in the parts placed records. Synth and the commands
+// that report addresses place them alike, so both number connections within the same stacks. Peer
+// accounts must be resolved first, as they decide the parts of by_peer_account.
+func PlaceTargets(cfg YAMLConfig, targets []synthTarget, placed Placements) ([]synthTarget, error) {
+	if err := ValidateStackPartitioning(cfg.StackPartitioning); err != nil {
+		return nil, WithExitCode(ExitValidation, err)
+	}
+	targets = PartitionTargets(targets, cfg.StackPartitioning)
+	targets, err := SplitTargets(targets, cfg.MaxProvidersPerStack, placed)
+	if err != nil {
+		return nil, WithExitCode(ExitValidation, err)
+	}
+	return targets, nil
+}
+
+// PartitionTargets divides every stack into one stack per peer region or peer account, named
+// "<stack>-<region or account>" and sorted by that suffix, keeping the connection order within each.
+// Every part is suffixed, even a stack's only one, so stack names stay put as connections come and
+// go. Lattice associations stay with the first part.
+func PartitionTargets(targets []synthTarget, mode string) []synthTarget {
+	if mode == "" || mode == StackPartitionNone {
+		return targets
+	}
+	var out []synthTarget
+	for _, t := range targets {
+		groups := make(map[string][]PeerConfig)
+		for _, peer := range t.Peers {
+			key := partitionKey(peer, mode)
+			groups[key] = append(groups[key], peer)
+		}
+		keys := make([]string, 0, len(groups))
+		for key := range groups {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		if len(keys) == 0 {
+			out = append(out, t)
+			continue
+		}
+		for i, key := range keys {
+			part := synthTarget{Stack: t.Stack + "-" + key, Source: t.Source, Peers: groups[key]}
+			if i == 0 {
+				part.Lattice = t.Lattice
+			}
+			out = append(out, part)
+		}
+		log.Printf("[config] Stack %s is split %s into %d stack(s)", t.Stack, strings.ReplaceAll(mode, "_", " "), len(keys))
+	}
+	return out
+}
+
+// SplitStackName returns the stack ID of part n (from 1) of a stack split by
+// max_providers_per_stack: the stack's own ID for the first part, so existing connections keep
+// their state, and the ID suffixed with "-part<n>" for the others.
+func SplitStackName(stack string, part int) string {
+	if part == 1 {
+		return stack
+	}
+	return fmt.Sprintf("%s-part%d", stack, part)
+}
+
+// splitPart returns the part number of a stack ID SplitStackName derived from stack, or 0 if it is
+// not one.
+func splitPart(stack, id string) int {
+	if id == stack {
+		return 1
+	}
+	suffix, ok := strings.CutPrefix(id, stack+"-part")
+	n, err := strconv.Atoi(suffix)
+	if !ok || err != nil || n < 2 {
+		return 0
+	}
+	return n
+}
+
+// PlacementsEnvVar names the placements file of synths that split stacks by max_providers_per_stack;
+// it is the default of --placements.
+const PlacementsEnvVar = "CDKTF_PLACEMENTS"
+
+// PlacementsFile is the name of the placements a pooled synth of one source writes into its outdir,
+// for the pool to merge into the placements file.
+const PlacementsFile = "placements.json"
+
+// Placements records the stack every connection of a split stack was synthesized into, by connection
+// key. It lives in a file committed with the config rather than in cdktf.out, which `make build`
+// cleans and CI synthesizes into from a fresh checkout.
+type Placements map[string]string
+
+// LoadPlacements reads a placements file. A missing file is an error: without the record, the parts
+// would be refilled in connection order, moving connections away from their state.
+func LoadPlacements(path string) (Placements, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("placements file %s does not exist; create it containing {} before the first split "+
+			"and commit it with the config", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var p Placements
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse placements file %s: %w", path, err)
+	}
+	if p == nil {
+		p = make(Placements)
+	}
+	return p, nil
+}
+
+// PlacementsFor loads the placements file a config splitting stacks by max_providers_per_stack
+// needs, or returns nil when it splits none.
+func PlacementsFor(cfg YAMLConfig, path string) (Placements, error) {
+	if cfg.MaxProvidersPerStack == 0 {
+		return nil, nil
+	}
+	if path == "" {
+		return nil, WithExitCode(ExitValidation, fmt.Errorf("max_providers_per_stack needs --placements (or %s), a file "+
+			"committed with the config that records the stack of every connection, so synths from a clean checkout keep "+
+			"connections in their stacks", PlacementsEnvVar))
+	}
+	placed, err := LoadPlacements(path)
+	if err != nil {
+		return nil, WithExitCode(ExitConfig, err)
+	}
+	return placed, nil
+}
+
+// WritePlacements writes a placements file as indented JSON.
+func WritePlacements(path string, p Placements) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Record sets the stack of every connection of the targets. Connections no longer configured keep
+// their records, which no split reads.
+func (p Placements) Record(targets []synthTarget) {
+	for _, t := range targets {
+		for _, peer := range t.Peers {
+			p[ConnectionKey(peer)] = t.Stack
+		}
+	}
+}
+
+// SplitTargets splits every stack whose connections need more than maxProviders AWS providers, two
+// per connection, into parts of at most maxProviders/2 connections. A connection stays in the part
+// placed records it in (see Placements) while that part has room, so inserting connections or
+// crossing the limit never moves existing ones into another stack's state; the others fill the
+// parts with room in connection order, adding parts as needed. Connections keep their order within
+// a part, and lattice associations stay with the first part. A maxProviders of 0 leaves the stacks as
+// they are.
+func SplitTargets(targets []synthTarget, maxProviders int, placed Placements) ([]synthTarget, error) {
+	if maxProviders == 0 {
+		return targets, nil
+	}
+	if maxProviders < 2 {
+		return nil, fmt.Errorf("max_providers_per_stack must be at least 2, the providers of one connection; got %d", maxProviders)
+	}
+	perStack := maxProviders / 2
+	var out []synthTarget
+	for _, t := range targets {
+		assigned := make([]int, len(t.Peers))
+		sizes := make(map[int]int)
+		for i, peer := range t.Peers {
+			key := ConnectionKey(peer)
+			part := splitPart(t.Stack, placed[key])
+			if part > 0 && sizes[part] < perStack {
+				assigned[i] = part
+				sizes[part]++
+			} else if part > 0 {
+				log.Printf("[config] WARNING: %s no longer fits in stack %s; it moves to another part and is recreated there "+
+					"unless its state is moved", key, placed[key])
+			}
+		}
+		for i := range t.Peers {
+			if assigned[i] != 0 {
+				continue
+			}
+			part := 1
+			for sizes[part] >= perStack {
+				part++
+			}
+			assigned[i] = part
+			sizes[part]++
+		}
+
+		parts := make([]int, 0, len(sizes))
+		for part := range sizes {
+			parts = append(parts, part)
+		}
+		sort.Ints(parts)
+		if len(parts) <= 1 && (len(parts) == 0 || parts[0] == 1) {
+			out = append(out, t)
+			continue
+		}
+		if len(t.Peers) > perStack {
+			log.Printf("[config] Stack %s needs %d AWS providers, above max_providers_per_stack (%d); splitting it into %d stacks",
+				t.Stack, 2*len(t.Peers), maxProviders, len(parts))
+		}
+		for i, part := range parts {
+			split := synthTarget{Stack: SplitStackName(t.Stack, part), Source: t.Source}
+			for j, peer := range t.Peers {
+				if assigned[j] == part {
+					split.Peers = append(split.Peers, peer)
+				}
+			}
+			if i == 0 {
+				split.Lattice = t.Lattice
+			}
+			out = append(out, split)
+		}
+	}
+	return out, nil
+}
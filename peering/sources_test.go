@@ -0,0 +1,197 @@
+package peering
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestMatchSources tests selecting sources by comma-separated names and globs.
+func TestMatchSources(t *testing.T) {
+	cfg := YAMLConfig{PeeringMatrix: map[string][]MatrixEntry{
+		"prod-east":       nil,
+		"prod-west":       nil,
+		"shared-services": nil,
+		"dev":             nil,
+	}}
+	tests := []struct {
+		value   string
+		want    []string
+		wantErr bool
+	}{
+		{"prod-*", []string{"prod-east", "prod-west"}, false},
+		{"shared-services, prod-*", []string{"prod-east", "prod-west", "shared-services"}, false},
+		{"dev,dev,d?v", []string{"dev"}, false},
+		{"prod-*,stage-*", nil, true},
+		{"prod-[", nil, true},
+		{",", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := MatchSources(cfg, tt.value)
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: expected %v (error %v), got %v, %v", tt.value, tt.want, tt.wantErr, got, err)
+		}
+	}
+}
+
+// TestIsSourcePattern tests that only lists and globs select several sources.
+func TestIsSourcePattern(t *testing.T) {
+	for value, want := range map[string]bool{"": false, "dev": false, "prod-*": true, "dev,prod": true, "prod-[ew]*": true} {
+		if got := IsSourcePattern(value); got != want {
+			t.Errorf("%q: expected %v, got %v", value, want, got)
+		}
+	}
+}
+
+// TestSplitTargets tests splitting stacks over the provider limit into parts that keep the first
+// stack's name, and leaving stacks within it alone.
+func TestSplitTargets(t *testing.T) {
+	peers := make([]PeerConfig, 5)
+	for i := range peers {
+		peers[i] = PeerConfig{SourceName: "hub", Name: fmt.Sprintf("spoke%d", i)}
+	}
+	lattice := []LatticeAssociation{{Peer: "hub", VpcID: "vpc-1"}}
+	targets := []synthTarget{
+		{Stack: "main-hub", Source: "hub", Peers: peers, Lattice: lattice},
+		{Stack: "main-dev", Source: "dev", Peers: peers[:2]},
+	}
+
+	got, err := SplitTargets(targets, 4, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		stack string
+		peers int
+	}{{"main-hub", 2}, {"main-hub-part2", 2}, {"main-hub-part3", 1}, {"main-dev", 2}}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d stacks, got %d: %+v", len(want), len(got), got)
+	}
+	for i, w := range want {
+		if got[i].Stack != w.stack || len(got[i].Peers) != w.peers {
+			t.Errorf("stack %d = %s with %d peers, want %s with %d", i, got[i].Stack, len(got[i].Peers), w.stack, w.peers)
+		}
+	}
+	if got[1].Peers[0].Name != "spoke2" || len(got[0].Lattice) != 1 || len(got[1].Lattice) != 0 {
+		t.Errorf("unexpected split: %+v", got)
+	}
+
+	if same, _ := SplitTargets(targets, 0, nil); !reflect.DeepEqual(same, targets) {
+		t.Errorf("expected no split without a limit, got %+v", same)
+	}
+	if _, err := SplitTargets(targets, 1, nil); err == nil {
+		t.Error("expected an error for a limit below one connection")
+	}
+}
+
+// TestSplitTargetsStable tests that connections stay in the part their previous synth placed them
+// in when a connection is inserted before them, and that new connections fill parts with room.
+func TestSplitTargetsStable(t *testing.T) {
+	peer := func(name string) PeerConfig { return PeerConfig{SourceName: "hub", Name: name} }
+	placed := Placements{
+		"hub/a": "main-hub", "hub/b": "main-hub",
+		"hub/c": "main-hub-part2", "hub/d": "main-hub-part2",
+		"hub/e": "main-hub-part3",
+	}
+	// "new" is inserted first and "gone" is no longer listed, freeing room in part 1.
+	placed["hub/gone"] = "main-hub"
+	delete(placed, "hub/b")
+	targets := []synthTarget{{Stack: "main-hub", Source: "hub", Peers: []PeerConfig{peer("new"), peer("a"), peer("c"), peer("d"), peer("e"), peer("b")}}}
+
+	got, err := SplitTargets(targets, 4, placed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stacks := make(map[string][]string)
+	for _, split := range got {
+		for _, p := range split.Peers {
+			stacks[split.Stack] = append(stacks[split.Stack], p.Name)
+		}
+	}
+	want := map[string][]string{
+		"main-hub":       {"new", "a"},
+		"main-hub-part2": {"c", "d"},
+		"main-hub-part3": {"e", "b"},
+	}
+	if !reflect.DeepEqual(stacks, want) {
+		t.Errorf("expected %v, got %v", want, stacks)
+	}
+
+	// A stack within the limit whose connections all sit in a later part keeps that part.
+	got, _ = SplitTargets([]synthTarget{{Stack: "main-hub", Peers: []PeerConfig{peer("c")}}}, 4, placed)
+	if len(got) != 1 || got[0].Stack != "main-hub-part2" {
+		t.Errorf("expected c to stay in main-hub-part2, got %+v", got)
+	}
+}
+
+// TestPlacements tests that a missing placements file is an error, and that recorded placements
+// are written and read back.
+func TestPlacements(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "placements.json")
+	if _, err := LoadPlacements(path); err == nil {
+		t.Fatal("expected an error for a missing placements file")
+	}
+	if err := os.WriteFile(path, []byte("{}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	placed, err := LoadPlacements(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	placed["hub/gone"] = "main-hub"
+	placed.Record([]synthTarget{
+		{Stack: "main-hub", Peers: []PeerConfig{{SourceName: "hub", Name: "a"}}},
+		{Stack: "main-hub-part2", Peers: []PeerConfig{{SourceName: "hub", Name: "c"}}},
+	})
+	if err := WritePlacements(path, placed); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadPlacements(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Placements{"hub/a": "main-hub", "hub/c": "main-hub-part2", "hub/gone": "main-hub"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+// TestPartitionTargets tests dividing stacks by peer region and by peer account.
+func TestPartitionTargets(t *testing.T) {
+	peers := []PeerConfig{
+		{SourceName: "hub", Name: "a", PeerRegion: "us-west-2", PeerRoleArn: "arn:aws:iam::222222222222:role/peering"},
+		{SourceName: "hub", Name: "b", PeerRegion: "us-east-1"},
+		{SourceName: "hub", Name: "c", PeerRegion: "us-west-2", PeerRoleArn: "arn:aws:iam::111111111111:role/peering"},
+	}
+	targets := []synthTarget{{Stack: "main", Source: "hub", Peers: peers, Lattice: []LatticeAssociation{{Peer: "hub"}}}}
+
+	stacks := func(got []synthTarget) map[string][]string {
+		out := make(map[string][]string)
+		for _, target := range got {
+			for _, peer := range target.Peers {
+				out[target.Stack] = append(out[target.Stack], peer.Name)
+			}
+		}
+		return out
+	}
+	byRegion := PartitionTargets(targets, StackPartitionByRegion)
+	if want := map[string][]string{"main-us-east-1": {"b"}, "main-us-west-2": {"a", "c"}}; !reflect.DeepEqual(stacks(byRegion), want) {
+		t.Errorf("by_region: got %v, want %v", stacks(byRegion), want)
+	}
+	if byRegion[0].Stack != "main-us-east-1" || len(byRegion[0].Lattice) != 1 || len(byRegion[1].Lattice) != 0 {
+		t.Errorf("by_region: expected sorted stacks with the lattice associations in the first, got %+v", byRegion)
+	}
+	byAccount := PartitionTargets(targets, StackPartitionByPeerAccount)
+	want := map[string][]string{"main-111111111111": {"c"}, "main-222222222222": {"a"}, "main-same-account": {"b"}}
+	if !reflect.DeepEqual(stacks(byAccount), want) {
+		t.Errorf("by_peer_account: got %v, want %v", stacks(byAccount), want)
+	}
+	if got := PartitionTargets(targets, StackPartitionNone); !reflect.DeepEqual(got, targets) {
+		t.Errorf("none: expected the stacks unchanged, got %+v", got)
+	}
+	if ValidateStackPartitioning("by_az") == nil || ValidateStackPartitioning("") != nil {
+		t.Error("expected only unknown modes to be rejected")
+	}
+}
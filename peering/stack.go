@@ -0,0 +1,141 @@
+package peering
+
+import (
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+
+	dataawsroutetable "cdk.tf/go/stack/generated/hashicorp/aws/dataawsroutetable"
+	dataawsvpc "cdk.tf/go/stack/generated/hashicorp/aws/dataawsvpc"
+	awsroute "cdk.tf/go/stack/generated/hashicorp/aws/route"
+	vpcpeeringconnection "cdk.tf/go/stack/generated/hashicorp/aws/vpcpeeringconnection"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Stack Resources
+// -------------------------------------------------------------------------------------------------
+
+// SideResources holds the routes created on one side of a connection, the route tables created for
+// them, and the data sources that look up their route tables.
+type SideResources struct {
+	Routes      []awsroute.Route            // Route resources, for_each ones included.
+	Info        []RouteInfo                 // Destination and for_each set of each route, parallel to Routes.
+	DataSources []cdktf.TerraformDataSource // Route table and subnet lookups backing the routes.
+	Tables      []cdktf.TerraformResource   // Dedicated route table and its subnet associations, if any.
+}
+
+// RouteInfo describes a route resource in the terms its config was built from, which the resource
+// does not expose once created.
+type RouteInfo struct {
+	Cidr    *string // Destination CIDR; a token for the fallback VPC CIDR unless it is pinned.
+	ForEach string  // HCL expression of the set a for_each route iterates over, or "" for a single route.
+	Tables  string  // Address of the aws_route_table data source a set of subnet IDs indexes, "" for a set of table IDs.
+}
+
+// RouteResources holds the routes of both sides of a connection.
+type RouteResources struct {
+	Source SideResources // Routes in the source VPC.
+	Peer   SideResources // Routes in the peer VPC.
+}
+
+// ConnectionResources holds every construct created for one connection.
+type ConnectionResources struct {
+	Peer    PeerConfig        // The connection's resolved configuration.
+	Core    PeerCoreResources // Providers, VPC data sources, and main route table data sources.
+	Peering PeeringResources  // Peering, accepter, and options resources.
+	Routes  RouteResources    // Routes on both sides.
+
+	Provenance []cdktf.TerraformResource // Route table tags and SSM parameters recording the routes.
+}
+
+// PeeringStack is the stack built by NewMyStack together with typed access to its constructs, so
+// library consumers can add escape hatches, outputs, or aspects without rebuilding it.
+type PeeringStack struct {
+	Stack       cdktf.TerraformStack  // The underlying CDKTF stack.
+	Connections []ConnectionResources // One entry per connection, in stack order.
+}
+
+// Peerings returns the peering connections the stack creates; external peerings have none.
+func (s PeeringStack) Peerings() []vpcpeeringconnection.VpcPeeringConnection {
+	out := make([]vpcpeeringconnection.VpcPeeringConnection, 0, len(s.Connections))
+	for _, c := range s.Connections {
+		if c.Peering.Peering != nil {
+			out = append(out, c.Peering.Peering)
+		}
+	}
+	return out
+}
+
+// Accepters returns the accepter resources; auto-accepted connections have none.
+func (s PeeringStack) Accepters() []cdktf.TerraformResource {
+	var out []cdktf.TerraformResource
+	for _, c := range s.Connections {
+		if c.Peering.Accepter != nil {
+			out = append(out, c.Peering.Accepter)
+		}
+	}
+	return out
+}
+
+// Routes returns every route resource on both sides of every connection.
+func (s PeeringStack) Routes() []awsroute.Route {
+	var out []awsroute.Route
+	for _, c := range s.Connections {
+		out = append(out, c.Routes.Source.Routes...)
+		out = append(out, c.Routes.Peer.Routes...)
+	}
+	return out
+}
+
+// RouteTables returns the dedicated route tables and subnet associations on both sides of every
+// connection.
+func (s PeeringStack) RouteTables() []cdktf.TerraformResource {
+	var out []cdktf.TerraformResource
+	for _, c := range s.Connections {
+		out = append(out, c.Routes.Source.Tables...)
+		out = append(out, c.Routes.Peer.Tables...)
+	}
+	return out
+}
+
+// Providers returns the source and peer provider of every connection; connections to third-party
+// VPCs have no peer provider.
+func (s PeeringStack) Providers() []cdktf.TerraformProvider {
+	out := make([]cdktf.TerraformProvider, 0, 2*len(s.Connections))
+	for _, c := range s.Connections {
+		out = append(out, c.Core.SourceProvider)
+		if c.Core.PeerProvider != nil {
+			out = append(out, c.Core.PeerProvider)
+		}
+	}
+	return out
+}
+
+// DataSources returns every data source: VPCs and main route tables first (unless pinned or
+// declared), then external peering lookups, the lookups of their owner's pcx-id, and the lookups
+// behind each side's routes.
+func (s PeeringStack) DataSources() []cdktf.TerraformDataSource {
+	var out []cdktf.TerraformDataSource
+	seen := make(map[cdktf.TerraformDataSource]bool)
+	for _, c := range s.Connections {
+		for _, vpc := range []dataawsvpc.DataAwsVpc{c.Core.SourceVpcData, c.Core.PeerVpcData} {
+			if vpc != nil {
+				out = append(out, vpc)
+			}
+		}
+		for _, rt := range []dataawsroutetable.DataAwsRouteTable{c.Core.SourceMainRt, c.Core.PeerMainRt} {
+			if rt != nil {
+				out = append(out, rt)
+			}
+		}
+		if c.Peering.Data != nil {
+			out = append(out, c.Peering.Data)
+		}
+		// Connections to the same owner share its remote state.
+		if lookup := c.Peering.OwnerLookup; lookup != nil && !seen[lookup] {
+			seen[lookup] = true
+			out = append(out, lookup)
+		}
+		out = append(out, c.Routes.Source.DataSources...)
+		out = append(out, c.Routes.Peer.DataSources...)
+	}
+	return out
+}
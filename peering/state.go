@@ -0,0 +1,420 @@
+package peering
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Terraform State Reading
+// -------------------------------------------------------------------------------------------------
+
+// StateResource is a managed resource read from Terraform state, with one attribute map per
+// instance (several for for_each resources).
+type StateResource struct {
+	Type      string                   // Terraform resource type.
+	Name      string                   // Terraform resource name (logical ID).
+	Instances []map[string]interface{} // Attributes of each instance.
+}
+
+// Address returns the resource address without instance keys.
+func (r StateResource) Address() string {
+	return r.Type + "." + r.Name
+}
+
+// StateSnapshot is the subset of a Terraform state this tool reports on.
+type StateSnapshot struct {
+	Source    string          // Where the state was read from.
+	Serial    int64           // State serial, incremented on every write.
+	Lineage   string          // State lineage identifier.
+	Modified  time.Time       // Modification time of the state file, if known.
+	Resources []StateResource // Managed resources in the root module.
+}
+
+// rawState mirrors the parts of the Terraform state file format (version 4) that are read.
+type rawState struct {
+	Serial    int64  `json:"serial"`
+	Lineage   string `json:"lineage"`
+	Resources []struct {
+		Module    string `json:"module"`
+		Mode      string `json:"mode"`
+		Type      string `json:"type"`
+		Name      string `json:"name"`
+		Instances []struct {
+			Attributes map[string]interface{} `json:"attributes"`
+		} `json:"instances"`
+	} `json:"resources"`
+}
+
+// ParseStateFile decodes a raw Terraform state file, keeping managed root-module resources.
+func ParseStateFile(data []byte) (*StateSnapshot, error) {
+	var raw rawState
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse state: %w", err)
+	}
+	snap := &StateSnapshot{Serial: raw.Serial, Lineage: raw.Lineage}
+	for _, r := range raw.Resources {
+		if r.Mode != "managed" || r.Module != "" {
+			continue
+		}
+		res := StateResource{Type: r.Type, Name: r.Name}
+		for _, inst := range r.Instances {
+			res.Instances = append(res.Instances, inst.Attributes)
+		}
+		snap.Resources = append(snap.Resources, res)
+	}
+	return snap, nil
+}
+
+// ReadStateFile reads a raw Terraform state file from disk.
+func ReadStateFile(path string) (*StateSnapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	snap, err := ParseStateFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	snap.Source = path
+	if info, err := os.Stat(path); err == nil {
+		snap.Modified = info.ModTime()
+	}
+	return snap, nil
+}
+
+// ReadStateFromTerraform pulls the state of an initialized stack directory through its configured
+// backend with "terraform state pull", so remote backends work as well as local ones.
+func ReadStateFromTerraform(dir string) (*StateSnapshot, error) {
+	cmd := exec.Command("terraform", "state", "pull")
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("terraform state pull in %s: %v: %s", dir, err, strings.TrimSpace(stderr.String()))
+	}
+	snap, err := ParseStateFile(out)
+	if err != nil {
+		return nil, err
+	}
+	snap.Source = dir + " (terraform state pull)"
+	return snap, nil
+}
+
+// -------------------------------------------------------------------------------------------------
+// State Report
+// -------------------------------------------------------------------------------------------------
+
+// ConnectionState summarizes the live state of one configured connection.
+type ConnectionState struct {
+	Key          string    // Connection key (source/peer).
+	PeeringID    string    // Live pcx-id, empty if the peering is not in state.
+	AcceptStatus string    // Accept status recorded for the peering.
+	Routes       int       // Number of route instances in state for this connection.
+	Missing      []string  // Configured addresses that are absent from state.
+	Fingerprint  string    // Digest of the connection's resources in state.
+	Modified     time.Time // When the fingerprint was first seen, from the state history (zero if unknown).
+	Serial       int64     // State serial the fingerprint was first seen at (0 if unknown).
+}
+
+// StateReport cross-references a state snapshot with the configured connections.
+type StateReport struct {
+	Snapshot    *StateSnapshot
+	Connections []ConnectionState
+	Unmanaged   []string // Addresses in state that no configured connection accounts for.
+}
+
+// BuildStateReport matches state resources to the expected addresses of every connection.
+func BuildStateReport(expected AddressMap, snap *StateSnapshot) StateReport {
+	byAddress := make(map[string]StateResource, len(snap.Resources))
+	for _, r := range snap.Resources {
+		byAddress[r.Address()] = r
+	}
+
+	claimed := make(map[string]bool)
+	keys := make([]string, 0, len(expected))
+	for key := range expected {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	report := StateReport{Snapshot: snap}
+	for _, key := range keys {
+		cs := ConnectionState{Key: key}
+		present := make(map[string][]map[string]interface{})
+		for kind, address := range expected[key] {
+			claimed[address] = true
+			res, ok := byAddress[address]
+			if !ok || len(res.Instances) == 0 {
+				cs.Missing = append(cs.Missing, address)
+				continue
+			}
+			present[address] = res.Instances
+			switch {
+			case kind == KindPeering:
+				cs.PeeringID = stringAttr(res.Instances[0], "id")
+				cs.AcceptStatus = stringAttr(res.Instances[0], "accept_status")
+			case res.Type == "aws_route":
+				cs.Routes += len(res.Instances)
+			}
+		}
+		sort.Strings(cs.Missing)
+		cs.Fingerprint = fingerprint(present)
+		report.Connections = append(report.Connections, cs)
+	}
+
+	for address := range byAddress {
+		if !claimed[address] {
+			report.Unmanaged = append(report.Unmanaged, address)
+		}
+	}
+	sort.Strings(report.Unmanaged)
+	return report
+}
+
+// fingerprint digests the state instances of a connection; encoding/json sorts map keys, so equal
+// state gives an equal digest.
+func fingerprint(instances map[string][]map[string]interface{}) string {
+	data, _ := json.Marshal(instances)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// stringAttr reads a string attribute from a state instance.
+func stringAttr(attrs map[string]interface{}, key string) string {
+	if v, ok := attrs[key].(string); ok {
+		return v
+	}
+	return ""
+}
+
+// PrintStateReport writes the report as a table followed by any missing or unmanaged resources.
+func PrintStateReport(w io.Writer, report StateReport) {
+	snap := report.Snapshot
+	fmt.Fprintf(w, "State: %s (serial %d", snap.Source, snap.Serial)
+	if !snap.Modified.IsZero() {
+		fmt.Fprintf(w, ", last modified %s", snap.Modified.Format(time.RFC3339))
+	}
+	fmt.Fprintf(w, ")\n\n")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CONNECTION\tPEERING\tSTATUS\tROUTES\tMISSING\tLAST MODIFIED")
+	for _, cs := range report.Connections {
+		modified := ""
+		if !cs.Modified.IsZero() {
+			modified = fmt.Sprintf("%s (serial %d)", cs.Modified.Format(time.RFC3339), cs.Serial)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%s\n", cs.Key, orUnknown(cs.PeeringID), orUnknown(cs.AcceptStatus), cs.Routes,
+			len(cs.Missing), orUnknown(modified))
+	}
+	tw.Flush()
+
+	for _, cs := range report.Connections {
+		for _, address := range cs.Missing {
+			fmt.Fprintf(w, "\nMISSING   %s (%s)", address, cs.Key)
+		}
+	}
+	for _, address := range report.Unmanaged {
+		fmt.Fprintf(w, "\nNOT IN CONFIG   %s", address)
+	}
+	fmt.Fprintln(w)
+}
+
+// -------------------------------------------------------------------------------------------------
+// State History
+// -------------------------------------------------------------------------------------------------
+
+// StateHistoryEntry records when a connection's resources in state last changed.
+type StateHistoryEntry struct {
+	Fingerprint string    `json:"fingerprint"` // Digest of the connection's resources in state.
+	Serial      int64     `json:"serial"`      // State serial the fingerprint was first seen at.
+	Modified    time.Time `json:"modified"`    // When the fingerprint was first seen.
+}
+
+// StateHistory maps connection keys to their last recorded change.
+type StateHistory map[string]StateHistoryEntry
+
+// ReadStateHistory reads a state history file; a missing file is an empty history.
+func ReadStateHistory(path string) (StateHistory, error) {
+	history := StateHistory{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return history, nil
+}
+
+// WriteStateHistory writes a state history file.
+func WriteStateHistory(path string, history StateHistory) error {
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// TrackModified fills in when each connection last changed and returns the updated history. A
+// connection whose fingerprint differs from the recorded one changed at this snapshot: at the state
+// file's modification time when known, else at observed. Connections seen for the first time get no
+// modification time, since their earlier history is unknown, but are recorded for the next run.
+func (r *StateReport) TrackModified(history StateHistory, observed time.Time) StateHistory {
+	at := observed
+	if !r.Snapshot.Modified.IsZero() {
+		at = r.Snapshot.Modified
+	}
+	updated := make(StateHistory, len(history))
+	for key, entry := range history {
+		updated[key] = entry
+	}
+	for i := range r.Connections {
+		cs := &r.Connections[i]
+		entry, seen := history[cs.Key]
+		switch {
+		case !seen:
+			updated[cs.Key] = StateHistoryEntry{Fingerprint: cs.Fingerprint, Serial: r.Snapshot.Serial}
+		case entry.Fingerprint != cs.Fingerprint:
+			updated[cs.Key] = StateHistoryEntry{Fingerprint: cs.Fingerprint, Serial: r.Snapshot.Serial, Modified: at.UTC()}
+		}
+		cs.Modified, cs.Serial = updated[cs.Key].Modified, updated[cs.Key].Serial
+		if cs.Modified.IsZero() {
+			cs.Serial = 0
+		}
+	}
+	return updated
+}
+
+// -------------------------------------------------------------------------------------------------
+// state-report
+// -------------------------------------------------------------------------------------------------
+
+// SynthesizedAddressMaps returns the AddressMap every stack in the manifest of the synth in outdir
+// last wrote, by stack. Stacks without an addresses.json manage no connections and are left out.
+func SynthesizedAddressMaps(outdir string) (map[string]AddressMap, error) {
+	manifest, err := os.ReadFile(filepath.Join(outdir, "manifest.json"))
+	if err != nil {
+		return nil, err
+	}
+	stacks, err := manifestStacks(manifest)
+	if err != nil {
+		return nil, err
+	}
+	maps := make(map[string]AddressMap, len(stacks))
+	for _, stack := range stacks {
+		addresses, err := LoadAddressMap(filepath.Join(outdir, "stacks", stack, AddressesFile))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		maps[stack] = addresses
+	}
+	return maps, nil
+}
+
+// runStateReport reads the state of every synthesized stack holding connections configured for
+// the source (or of one given state file or stack directory) and reports it against them.
+func runStateReport(args []string) error {
+	fs := flag.NewFlagSet("state-report", flag.ContinueOnError)
+	statePath := fs.String("state", "", "read this raw state file instead of pulling from the backend")
+	dir := fs.String("dir", "", "initialized stack directory to pull state from (default every stack of the last synth)")
+	historyPath := fs.String("history", "", "file tracking when each connection last changed (default none)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, peers := loadSourcePeers(sourceArg(fs))
+	var reports []StateReport
+	if *statePath != "" || *dir != "" {
+		var snap *StateSnapshot
+		var err error
+		if *statePath != "" {
+			snap, err = ReadStateFile(*statePath)
+		} else {
+			snap, err = ReadStateFromTerraform(*dir)
+		}
+		if err != nil {
+			return err
+		}
+		reports = append(reports, BuildStateReport(BuildAddressMap(NewNamer(cfg.Naming), peers), snap))
+	} else {
+		stacks, err := SynthesizedAddressMaps(synthOutdir())
+		if err != nil {
+			return WithExitCode(ExitConfig, fmt.Errorf("no synth to report on (run synth first, or pass -state or -dir): %w", err))
+		}
+		names := make([]string, 0, len(stacks))
+		for stack := range stacks {
+			names = append(names, stack)
+		}
+		sort.Strings(names)
+
+		// Each stack's addresses.json holds the addresses its connections were synthesized at, which
+		// depend on their position in that stack; the config only decides which connections count.
+		unplaced := make(map[string]bool, len(peers))
+		for _, peer := range peers {
+			unplaced[ConnectionKey(peer)] = true
+		}
+		for _, stack := range names {
+			expected := make(AddressMap)
+			for key, addresses := range stacks[stack] {
+				if unplaced[key] {
+					expected[key] = addresses
+					delete(unplaced, key)
+				}
+			}
+			if len(expected) == 0 {
+				continue
+			}
+			snap, err := ReadStateFromTerraform(stackOutDir(stack))
+			if err != nil {
+				return err
+			}
+			reports = append(reports, BuildStateReport(expected, snap))
+		}
+		for _, peer := range peers {
+			if key := ConnectionKey(peer); unplaced[key] {
+				log.Printf("[state] WARNING: %s is in no synthesized stack; run synth to report on it", key)
+			}
+		}
+	}
+
+	if *historyPath != "" {
+		history, err := ReadStateHistory(*historyPath)
+		if err != nil {
+			return err
+		}
+		for i := range reports {
+			history = reports[i].TrackModified(history, time.Now())
+		}
+		if err := WriteStateHistory(*historyPath, history); err != nil {
+			return err
+		}
+	}
+	for i, report := range reports {
+		if i > 0 {
+			fmt.Fprintln(os.Stdout)
+		}
+		PrintStateReport(os.Stdout, report)
+	}
+	return nil
+}
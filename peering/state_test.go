@@ -0,0 +1,124 @@
+package peering
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestBuildStateReport tests matching state resources against the configured connections.
+func TestBuildStateReport(t *testing.T) {
+	state := []byte(`{
+  "version": 4,
+  "serial": 12,
+  "lineage": "abc",
+  "resources": [
+    {"mode": "managed", "type": "aws_vpc_peering_connection", "name": "VpcPeering0",
+     "instances": [{"attributes": {"id": "pcx-0123", "accept_status": "active"}}]},
+    {"mode": "managed", "type": "aws_route", "name": "SourceToPeerMainRoute0",
+     "instances": [{"attributes": {"id": "r-1"}}]},
+    {"mode": "managed", "type": "aws_route", "name": "PeerToPeerMainRoute0",
+     "instances": [{"attributes": {"id": "r-2"}}]},
+    {"mode": "data", "type": "aws_vpc", "name": "SourceVpc0",
+     "instances": [{"attributes": {"id": "vpc-1"}}]},
+    {"mode": "managed", "type": "aws_vpc_peering_connection", "name": "VpcPeering7",
+     "instances": [{"attributes": {"id": "pcx-old"}}]}
+  ]
+}`)
+	snap, err := ParseStateFile(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Serial != 12 || len(snap.Resources) != 4 {
+		t.Fatalf("unexpected snapshot: serial %d, %d resources", snap.Serial, len(snap.Resources))
+	}
+
+	peers := []PeerConfig{{SourceName: "dev", Name: "prod"}}
+	report := BuildStateReport(BuildAddressMap(LegacyNamer{}, peers), snap)
+
+	cs := report.Connections[0]
+	if cs.PeeringID != "pcx-0123" || cs.AcceptStatus != "active" || cs.Routes != 2 {
+		t.Errorf("unexpected connection state: %+v", cs)
+	}
+	if len(cs.Missing) != 2 || cs.Missing[0] != "aws_vpc_peering_connection_options.VpcPeeringAccepterOptions0" ||
+		cs.Missing[1] != "aws_vpc_peering_connection_options.VpcPeeringOptions0" {
+		t.Errorf("unexpected missing resources: %v", cs.Missing)
+	}
+	if len(report.Unmanaged) != 1 || report.Unmanaged[0] != "aws_vpc_peering_connection.VpcPeering7" {
+		t.Errorf("unexpected unmanaged resources: %v", report.Unmanaged)
+	}
+}
+
+// TestTrackModified tests that a connection's modification time moves only when its state changes.
+func TestTrackModified(t *testing.T) {
+	snapshot := func(serial int64, peeringID string) *StateSnapshot {
+		return &StateSnapshot{Serial: serial, Resources: []StateResource{
+			{Type: "aws_vpc_peering_connection", Name: "VpcPeering0", Instances: []map[string]interface{}{{"id": peeringID}}},
+		}}
+	}
+	expected := BuildAddressMap(LegacyNamer{}, []PeerConfig{{SourceName: "dev", Name: "prod"}})
+	first := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	report := BuildStateReport(expected, snapshot(1, "pcx-1"))
+	history := report.TrackModified(StateHistory{}, first)
+	if cs := report.Connections[0]; !cs.Modified.IsZero() || history["dev/prod"].Fingerprint != cs.Fingerprint {
+		t.Fatalf("expected a first sighting to be recorded without a time, got %+v, %+v", cs, history)
+	}
+
+	report = BuildStateReport(expected, snapshot(2, "pcx-1"))
+	history = report.TrackModified(history, first.Add(time.Hour))
+	if cs := report.Connections[0]; !cs.Modified.IsZero() {
+		t.Errorf("expected unchanged state to keep the unknown time, got %v", cs.Modified)
+	}
+
+	changed := first.Add(2 * time.Hour)
+	report = BuildStateReport(expected, snapshot(3, "pcx-2"))
+	history = report.TrackModified(history, changed)
+	if cs := report.Connections[0]; !cs.Modified.Equal(changed) || cs.Serial != 3 {
+		t.Errorf("expected the change at serial 3, got %v (serial %d)", cs.Modified, cs.Serial)
+	}
+
+	report = BuildStateReport(expected, snapshot(4, "pcx-2"))
+	report.TrackModified(history, changed.Add(time.Hour))
+	if cs := report.Connections[0]; !cs.Modified.Equal(changed) || cs.Serial != 3 {
+		t.Errorf("expected the recorded change to be kept, got %v (serial %d)", cs.Modified, cs.Serial)
+	}
+
+	var out strings.Builder
+	PrintStateReport(&out, report)
+	if !strings.Contains(out.String(), "2026-01-01T02:00:00Z (serial 3)") {
+		t.Errorf("expected the last modification in the report, got:\n%s", out.String())
+	}
+}
+
+// TestSynthesizedAddressMaps tests reading the address map of every stack in a synth's manifest.
+func TestSynthesizedAddressMaps(t *testing.T) {
+	outdir := t.TempDir()
+	if _, err := SynthesizedAddressMaps(outdir); !os.IsNotExist(err) {
+		t.Fatalf("expected a missing manifest to be reported, got %v", err)
+	}
+
+	manifest := `{"version": "0.20.0", "stacks": {"peering-dev": {}, "peering-dev-part2": {}, "lattice": {}}}`
+	if err := os.WriteFile(filepath.Join(outdir, "manifest.json"), []byte(manifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for stack, peer := range map[string]string{"peering-dev": "prod", "peering-dev-part2": "qa"} {
+		if err := os.MkdirAll(filepath.Join(outdir, "stacks", stack), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		m := BuildAddressMap(LegacyNamer{}, []PeerConfig{{SourceName: "dev", Name: peer}})
+		if err := WriteAddressMap(filepath.Join(outdir, "stacks", stack, AddressesFile), m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	maps, err := SynthesizedAddressMaps(outdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(maps) != 2 || maps["peering-dev"]["dev/prod"] == nil || maps["peering-dev-part2"]["dev/qa"] == nil {
+		t.Errorf("expected the address maps of both peering stacks, got %v", maps)
+	}
+}
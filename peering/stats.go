@@ -0,0 +1,198 @@
+package peering
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Stack Statistics
+// -------------------------------------------------------------------------------------------------
+
+// RegionStats counts what a stack puts into one region.
+type RegionStats struct {
+	Connections int // Connections with at least one side in the region.
+	Providers   int // Providers configured for the region.
+	Resources   int // Estimated managed resources created in the region.
+}
+
+// StackStats summarizes the size of a stack.
+type StackStats struct {
+	Connections int                     // Connections in the stack.
+	Providers   int                     // AWS providers, two per connection.
+	Resources   int                     // Estimated managed resources (for_each blocks count once).
+	Regions     map[string]*RegionStats // Breakdown by region.
+}
+
+// kindRegionIsPeer reports whether a resource kind is created through the peer's provider.
+func kindRegionIsPeer(kind string) bool {
+	return kind == KindAccepter || kind == KindAccepterOptions || strings.HasPrefix(kind, "peer-")
+}
+
+// ComputeStats estimates the size of a stack from its connections, using the same resource
+// addresses as the addresses command.
+func ComputeStats(namer Namer, peers []PeerConfig) StackStats {
+	stats := StackStats{Connections: len(peers), Regions: map[string]*RegionStats{}}
+	region := func(name string) *RegionStats {
+		if stats.Regions[name] == nil {
+			stats.Regions[name] = &RegionStats{}
+		}
+		return stats.Regions[name]
+	}
+
+	for i, peer := range peers {
+		sourceRegion := ResolveRegion(peer.SourceRegion)
+		peerRegion := ResolveRegion(peer.PeerRegion)
+		region(sourceRegion).Connections++
+		if peerRegion != sourceRegion {
+			region(peerRegion).Connections++
+		}
+		region(sourceRegion).Providers++
+		region(peerRegion).Providers++
+		stats.Providers += 2
+
+		for key := range ConnectionAddresses(namer, ConnectionNameContext(i, peer), peer) {
+			kind, _, _ := strings.Cut(key, ":")
+			if kindRegionIsPeer(kind) {
+				region(peerRegion).Resources++
+			} else {
+				region(sourceRegion).Resources++
+			}
+			stats.Resources++
+		}
+	}
+	return stats
+}
+
+// PrintStats writes the summary line and the per-region breakdown.
+func PrintStats(w io.Writer, s StackStats) {
+	fmt.Fprintf(w, "%d connection(s), %d provider(s), ~%d resource(s)\n", s.Connections, s.Providers, s.Resources)
+	regions := make([]string, 0, len(s.Regions))
+	for name := range s.Regions {
+		regions = append(regions, name)
+	}
+	sort.Strings(regions)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  REGION\tCONNECTIONS\tPROVIDERS\tRESOURCES")
+	for _, name := range regions {
+		r := s.Regions[name]
+		fmt.Fprintf(tw, "  %s\t%d\t%d\t%d\n", name, r.Connections, r.Providers, r.Resources)
+	}
+	tw.Flush()
+}
+
+// -------------------------------------------------------------------------------------------------
+// Resource Budgets
+// -------------------------------------------------------------------------------------------------
+
+// AWS quotas and practical limits the connections are checked against.
+const (
+	PeeringsPerVpcQuota     = 50   // Default quota of active peerings per VPC.
+	PeeringsPerVpcMax       = 125  // Highest value the peerings quota can be raised to.
+	RoutesPerTableQuota     = 50   // Default quota of non-propagated routes per route table.
+	RoutesPerTableMax       = 1000 // Highest value the routes quota can be raised to.
+	StackResourcesPractical = 1000 // Resources above which plans and applies of one stack get slow.
+	StackProvidersPractical = 100  // AWS provider instances above which plans of one stack get slow.
+)
+
+// checkQuota returns a diagnostic when a count exceeds an AWS maximum (error), a default quota
+// (warning), or 80% of the default quota (info).
+func checkQuota(subject string, n int, what string, quota, limit int) []Diagnostic {
+	switch {
+	case n > limit:
+		return []Diagnostic{{Severity: SeverityError, Subject: subject,
+			Message: fmt.Sprintf("%d %s, above the AWS maximum of %d", n, what, limit)}}
+	case n > quota:
+		return []Diagnostic{{Severity: SeverityWarning, Subject: subject,
+			Message: fmt.Sprintf("%d %s, above the default quota of %d; request an increase (up to %d) before apply", n, what, quota, limit)}}
+	case n*5 >= quota*4:
+		return []Diagnostic{{Severity: SeverityInfo, Subject: subject,
+			Message: fmt.Sprintf("%d %s, approaching the default quota of %d", n, what, quota)}}
+	}
+	return nil
+}
+
+// VpcUsage is what the connections put on one VPC, counted against the AWS quotas.
+type VpcUsage struct {
+	Name     string // Peer name the VPC is first seen under.
+	Peerings int    // Peerings of the VPC, once per VPC pair.
+	Routes   int    // Estimated peering routes per route table.
+}
+
+// CountVpcUsage counts the peerings and estimated routes per route table of every VPC, keyed by VPC
+// ID. Peerings count once per VPC pair, whichever side declares them; a route table gets one route
+// per destination of every routed connection of its VPC.
+func CountVpcUsage(peers []PeerConfig) map[string]*VpcUsage {
+	usage := make(map[string]*VpcUsage)
+	pairs := make(map[string]map[string]bool)
+	vpc := func(id, name string) *VpcUsage {
+		if usage[id] == nil {
+			usage[id] = &VpcUsage{Name: name}
+			pairs[id] = map[string]bool{}
+		}
+		return usage[id]
+	}
+
+	for _, peer := range peers {
+		source := vpc(peer.SourceVpcID, peer.SourceName)
+		target := vpc(peer.PeerVpcID, ConnectionNameContext(0, peer).Peer)
+		pairs[peer.SourceVpcID][peer.PeerVpcID] = true
+		pairs[peer.PeerVpcID][peer.SourceVpcID] = true
+
+		if peer.SourceRouting.Strategy != RoutingNone {
+			source.Routes += max(len(peer.DestinationCidrs), 1) + len(peer.SourceExtraCidrs)
+		}
+		if peer.PeerRouting.Strategy != RoutingNone {
+			target.Routes += 1 + len(peer.PeerExtraCidrs)
+		}
+	}
+	for id, u := range usage {
+		u.Peerings = len(pairs[id])
+	}
+	return usage
+}
+
+// ResourceBudgets checks every VPC's peering count and estimated routes per route table against the
+// AWS quotas, as counted by CountVpcUsage.
+func ResourceBudgets(peers []PeerConfig) []Diagnostic {
+	usage := CountVpcUsage(peers)
+	vpcs := make([]string, 0, len(usage))
+	for vpc := range usage {
+		vpcs = append(vpcs, vpc)
+	}
+	sort.Strings(vpcs)
+
+	var out []Diagnostic
+	for _, vpc := range vpcs {
+		subject := fmt.Sprintf("%s (%s)", usage[vpc].Name, vpc)
+		out = append(out, checkQuota(subject, usage[vpc].Peerings, "peerings", PeeringsPerVpcQuota, PeeringsPerVpcMax)...)
+		out = append(out, checkQuota(subject, usage[vpc].Routes, "peering routes per route table", RoutesPerTableQuota, RoutesPerTableMax)...)
+	}
+	return out
+}
+
+// StackBudget warns when one stack holds more resources than plans and applies handle comfortably.
+func StackBudget(s StackStats) []Diagnostic {
+	if s.Resources <= StackResourcesPractical {
+		return nil
+	}
+	return []Diagnostic{{Severity: SeverityWarning, Subject: StackName, Message: fmt.Sprintf(
+		"~%d resources in one stack, above the practical limit of %d; split it per source with CDKTF_SOURCE",
+		s.Resources, StackResourcesPractical)}}
+}
+
+// ProviderBudget warns when a stack configures more AWS providers than Terraform handles
+// comfortably: every provider instance is a plugin configured, and its credentials checked, on
+// every plan.
+func ProviderBudget(stack string, s StackStats) []Diagnostic {
+	if s.Providers <= StackProvidersPractical {
+		return nil
+	}
+	return []Diagnostic{{Severity: SeverityWarning, Subject: stack, Message: fmt.Sprintf(
+		"%d AWS providers in one stack, above the practical limit of %d; set max_providers_per_stack to split it",
+		s.Providers, StackProvidersPractical)}}
+}
@@ -0,0 +1,101 @@
+package peering
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestComputeStats tests the connection, provider, resource, and per-region counts of a stack.
+func TestComputeStats(t *testing.T) {
+	mainRouting := RoutingConfig{Strategy: RoutingMain}
+	peers := []PeerConfig{
+		// Same region: peering, options and a main route on each side.
+		{SourceName: "a", Name: "b", SourceRegion: "us-east-1", PeerRegion: "us-east-1", SourceRouting: mainRouting, PeerRouting: mainRouting},
+		// Cross region: adds the accepter in the peer region; two destination CIDRs split the source route.
+		{SourceName: "a", Name: "c", SourceRegion: "us-east-1", PeerRegion: "eu-west-1", SourceRouting: mainRouting, PeerRouting: mainRouting,
+			DestinationCidrs: []string{"10.1.0.0/24", "10.1.1.0/24"}},
+	}
+
+	s := ComputeStats(LegacyNamer{}, peers)
+	if s.Connections != 2 || s.Providers != 4 || s.Resources != 12 {
+		t.Fatalf("got %d connections, %d providers, %d resources; want 2, 4, 12", s.Connections, s.Providers, s.Resources)
+	}
+	east, west := s.Regions["us-east-1"], s.Regions["eu-west-1"]
+	if *east != (RegionStats{Connections: 2, Providers: 3, Resources: 9}) {
+		t.Errorf("us-east-1 = %+v", *east)
+	}
+	if *west != (RegionStats{Connections: 1, Providers: 1, Resources: 3}) {
+		t.Errorf("eu-west-1 = %+v", *west)
+	}
+}
+
+// TestResourceBudgets tests the quota thresholds for peerings per VPC and routes per route table.
+func TestResourceBudgets(t *testing.T) {
+	hub := func(n int, peerRouting string) []PeerConfig {
+		var peers []PeerConfig
+		for i := 0; i < n; i++ {
+			peers = append(peers, PeerConfig{
+				SourceName: "hub", SourceVpcID: "vpc-hub",
+				Name: fmt.Sprintf("spoke%d", i), PeerVpcID: fmt.Sprintf("vpc-%d", i),
+				SourceRouting: RoutingConfig{Strategy: RoutingNone}, PeerRouting: RoutingConfig{Strategy: peerRouting},
+			})
+		}
+		return peers
+	}
+
+	tests := []struct {
+		name  string
+		peers []PeerConfig
+		want  []string // "<severity>: <message prefix>" for the hub, in order.
+	}{
+		{"below", hub(39, RoutingNone), nil},
+		{"approaching", hub(40, RoutingNone), []string{"info: 40 peerings"}},
+		{"above quota", hub(51, RoutingNone), []string{"warning: 51 peerings"}},
+		{"above maximum", hub(126, RoutingNone), []string{"error: 126 peerings"}},
+		// Spokes route back to the hub, but each spoke's own table holds a single route.
+		{"spoke routes", hub(10, RoutingMain), nil},
+		// Declaring every pair from both sides still counts one peering per pair.
+		{"both directions", append(hub(45, RoutingNone), reverse(hub(45, RoutingNone))...), []string{"info: 45 peerings"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, d := range ResourceBudgets(tt.peers) {
+				if strings.HasPrefix(d.Subject, "hub ") {
+					got = append(got, d.Severity+": "+d.Message)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !strings.HasPrefix(got[i], tt.want[i]) {
+					t.Errorf("got %q, want prefix %q", got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// reverse swaps the sides of every connection.
+func reverse(peers []PeerConfig) []PeerConfig {
+	out := make([]PeerConfig, 0, len(peers))
+	for _, p := range peers {
+		out = append(out, PeerConfig{SourceName: p.Name, SourceVpcID: p.PeerVpcID, Name: p.SourceName, PeerVpcID: p.SourceVpcID,
+			SourceRouting: p.PeerRouting, PeerRouting: p.SourceRouting})
+	}
+	return out
+}
+
+// TestProviderBudget tests the warning for stacks with too many AWS providers.
+func TestProviderBudget(t *testing.T) {
+	if got := ProviderBudget("main", StackStats{Providers: StackProvidersPractical}); len(got) != 0 {
+		t.Errorf("expected no warning at the limit, got %v", got)
+	}
+	got := ProviderBudget("main", StackStats{Providers: 240})
+	if len(got) != 1 || got[0].Subject != "main" || !strings.Contains(got[0].Message, "240 AWS providers") {
+		t.Errorf("unexpected diagnostics: %v", got)
+	}
+}
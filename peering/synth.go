@@ -0,0 +1,206 @@
+package peering
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Synth Failures
+// -------------------------------------------------------------------------------------------------
+
+// SynthRetryEnvVar is set in the environment of a synth retried after a transient failure, so it
+// is retried only once.
+const SynthRetryEnvVar = "CDKTF_SYNTH_RETRY"
+
+// SynthFailure is a failure of building or synthesizing the app, classified from the error jsii
+// raised so it can be reported with what to do about it instead of a stack trace. Every synth
+// failure exits with ExitSynth; the kind tells a broken environment from a broken config.
+type SynthFailure struct {
+	Kind  string // What went wrong, in a few words.
+	Hint  string // What to do about it.
+	Retry bool   // Whether the failure is transient and worth one more attempt.
+	Err   error  // The raised error.
+}
+
+// Error returns the kind and the first line of the raised error, without the JavaScript stack.
+func (f *SynthFailure) Error() string {
+	return fmt.Sprintf("%s: %s", f.Kind, firstLine(f.Err.Error()))
+}
+
+// Unwrap returns the raised error.
+func (f *SynthFailure) Unwrap() error {
+	return f.Err
+}
+
+// synthFailureClasses maps patterns of jsii and provider binding errors to failure classes, in
+// order of precedence; messages are matched lowercased. Patterns are anchored to the messages jsii
+// and CDKTF raise, so provider and validation errors mentioning the same words stay unclassified.
+var synthFailureClasses = []struct {
+	patterns []*regexp.Regexp
+	failure  SynthFailure
+}{
+	{
+		synthPatterns(`"node": executable file not found`, `failed to start jsii`, `node: not found`),
+		SynthFailure{Kind: "jsii runtime unavailable",
+			Hint: "install Node.js 20 (as in the Dockerfile) and make sure node is on PATH"},
+	},
+	{
+		synthPatterns(`broken pipe`, `unexpected eof`, `kernel process exited`, `signal: killed`),
+		SynthFailure{Kind: "jsii runtime crashed", Retry: true,
+			Hint: "the Node.js process behind jsii died; check memory limits (NODE_OPTIONS=--max-old-space-size) if it keeps happening"},
+	},
+	{
+		synthPatterns(`jsii version mismatch`, `requires jsii`, `incompatible jsii`, `unsupported jsii`,
+			`typeerror: [\w.$]+ is not a function`),
+		SynthFailure{Kind: "version skew",
+			Hint: "the cdktf library, jsii runtime, and generated bindings are out of sync; align cdktf in go.mod with the cdktf CLI and rerun make get"},
+	},
+	{
+		synthPatterns(`could not (find|load) assembly`, `(could not|cannot) find module '@cdktf/`, `no registered type`,
+			`unknown type:? @cdktf/`),
+		SynthFailure{Kind: "provider bindings missing",
+			Hint: "run make get (cdktf get) to generate the AWS provider bindings, then go mod tidy"},
+	},
+	{
+		synthPatterns(`already a construct with name`, `validation failed with the following errors`, `invalid construct id`),
+		SynthFailure{Kind: "construct rejected",
+			Hint: "two resources got the same construct ID or a construct failed validation; rerun with --debug to print the construct tree"},
+	},
+}
+
+// synthPatterns compiles the patterns of a failure class.
+func synthPatterns(patterns ...string) []*regexp.Regexp {
+	out := make([]*regexp.Regexp, len(patterns))
+	for i, p := range patterns {
+		out[i] = regexp.MustCompile(p)
+	}
+	return out
+}
+
+// ClassifySynthError returns the failure class of an error raised while building or synthesizing
+// the app, or an unclassified failure.
+func ClassifySynthError(err error) *SynthFailure {
+	msg := strings.ToLower(err.Error())
+	for _, class := range synthFailureClasses {
+		for _, pattern := range class.patterns {
+			if pattern.MatchString(msg) {
+				failure := class.failure
+				failure.Err = err
+				return &failure
+			}
+		}
+	}
+	return &SynthFailure{Kind: "synth failed", Err: err,
+		Hint: "rerun with --debug for the full error and the construct tree"}
+}
+
+// firstLine returns the first non-empty line of a message.
+func firstLine(msg string) string {
+	for _, line := range strings.Split(msg, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return msg
+}
+
+// recoveredFailure classifies a value recovered from a jsii panic.
+func recoveredFailure(r interface{}) *SynthFailure {
+	cause, ok := r.(error)
+	if !ok {
+		cause = fmt.Errorf("%v", r)
+	}
+	return ClassifySynthError(cause)
+}
+
+// RunSynth builds the app with build and synthesizes it, turning the panics jsii raises into a
+// classified SynthFailure. With debug, the construct tree built so far is written to the log
+// before returning the failure.
+func RunSynth(app cdktf.App, build func(), debug bool) (err error) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		failure := recoveredFailure(r)
+		if debug {
+			log.Printf("[synth] %v", failure.Err)
+			DumpConstructTree(log.Writer(), app)
+		}
+		err = failure
+	}()
+	build()
+	app.Synth()
+	return nil
+}
+
+// DumpConstructTree writes the path of every construct of the app, indented by depth. A jsii
+// runtime that has died cannot be walked; that is reported instead of the tree.
+func DumpConstructTree(w io.Writer, app cdktf.App) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(w, "[synth] Cannot read the construct tree: %v\n", firstLine(fmt.Sprint(r)))
+		}
+	}()
+	fmt.Fprintln(w, "[synth] Construct tree:")
+	for _, c := range *app.Node().FindAll(constructs.ConstructOrder_PREORDER) {
+		path := *c.Node().Path()
+		if path == "" {
+			continue
+		}
+		depth := strings.Count(path, "/")
+		fmt.Fprintf(w, "  %s%s\n", strings.Repeat("  ", depth), *c.Node().Id())
+	}
+}
+
+// ExitSynthFailure reports a failed synth and exits with ExitSynth. A transient failure is retried
+// once in a fresh process, since a jsii runtime that has died cannot be restarted in this one.
+func ExitSynthFailure(err error) {
+	var failure *SynthFailure
+	if !errors.As(err, &failure) {
+		Fail(WithExitCode(ExitSynth, fmt.Errorf("synth failed: %w", err)))
+	}
+	if failure.Retry && os.Getenv(SynthRetryEnvVar) == "" {
+		log.Printf("[synth] %v; retrying once", failure)
+		os.Exit(retrySynth())
+	}
+	if os.Getenv(ErrorFormatEnvVar) == ErrorFormatJSON {
+		Fail(failure)
+	}
+	log.Printf("synth failed: %v", failure)
+	log.Printf("hint: %s", failure.Hint)
+	recordFailure(ExitSynth)
+	os.Exit(ExitSynth)
+}
+
+// retrySynth reruns this process with the same arguments and SynthRetryEnvVar set, returning its
+// exit code.
+func retrySynth() int {
+	exe, err := os.Executable()
+	if err != nil {
+		log.Printf("[synth] Cannot retry: %v", err)
+		return ExitSynth
+	}
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), SynthRetryEnvVar+"=1")
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		log.Printf("[synth] Retry failed: %v", err)
+		return ExitSynth
+	}
+	return 0
+}
@@ -0,0 +1,57 @@
+package peering
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestClassifySynthError tests that jsii errors map to failure classes, all exiting with ExitSynth.
+func TestClassifySynthError(t *testing.T) {
+	tests := []struct {
+		msg      string
+		wantKind string
+		retry    bool
+	}{
+		{`exec: "node": executable file not found in $PATH`, "jsii runtime unavailable", false},
+		{"write |1: broken pipe", "jsii runtime crashed", true},
+		{"Error: Could not find assembly: @cdktf/provider-aws", "provider bindings missing", false},
+		{"Error: jsii version mismatch: runtime 1.90.0, assembly compiled with 1.106.0", "version skew", false},
+		{"TypeError: this.node.addValidation is not a function", "version skew", false},
+		{"Error: There is already a Construct with name 'Peering0' in TerraformStack [peering]", "construct rejected", false},
+		{"Error: something else", "synth failed", false},
+		{`Error: Unsupported attribute "vpc_peering_connection_id"`, "synth failed", false},
+		{"Error: Incompatible provider version", "synth failed", false},
+		{"TypeError: Cannot read properties of undefined (reading 'id')", "synth failed", false},
+		{"failed to load peering.yaml: yaml: line 3: validation failed for field region", "synth failed", false},
+		{"Error: Could not find module assembly.yaml", "synth failed", false},
+		{`aws sts get-caller-identity: exec: "aws": executable file not found in $PATH`, "synth failed", false},
+		{"Error: Could not load assembly @cdktf/provider-aws", "provider bindings missing", false},
+		{"Error: Cannot find module '@cdktf/provider-aws'", "provider bindings missing", false},
+		{"Error: Validation failed with the following errors:\n  [peering/Route] bad", "construct rejected", false},
+	}
+	for _, tt := range tests {
+		got := ClassifySynthError(errors.New(tt.msg))
+		if got.Kind != tt.wantKind || got.Retry != tt.retry {
+			t.Errorf("%q: expected %q (retry %v), got %q (%v)", tt.msg, tt.wantKind, tt.retry, got.Kind, got.Retry)
+		}
+		if code := ExitCodeOf(got); code != ExitSynth {
+			t.Errorf("%q: expected exit code %d, got %d", tt.msg, ExitSynth, code)
+		}
+		if got.Hint == "" {
+			t.Errorf("%q: expected a hint", tt.msg)
+		}
+	}
+}
+
+// TestSynthFailureError tests that failures print without the JavaScript stack.
+func TestSynthFailureError(t *testing.T) {
+	cause := errors.New("\nError: There is already a Construct with name 'Peering0'\n    at new Node (/tmp/jsii-kernel/lib/construct.js:56:13)\n")
+	failure := ClassifySynthError(cause)
+	want := "construct rejected: Error: There is already a Construct with name 'Peering0'"
+	if failure.Error() != want {
+		t.Errorf("expected %q, got %q", want, failure.Error())
+	}
+	if !errors.Is(failure, cause) {
+		t.Error("expected the failure to wrap the raised error")
+	}
+}
@@ -0,0 +1,394 @@
+package peering
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Synth Pool
+// -------------------------------------------------------------------------------------------------
+
+// SynthJobsEnvVar sets the default of --jobs.
+const SynthJobsEnvVar = "CDKTF_SYNTH_JOBS"
+
+// SynthWorkerEnvVar is set in the environment of the synth of one source started by a synth pool,
+// so it synthesizes its source instead of starting a pool of its own.
+const SynthWorkerEnvVar = "CDKTF_SYNTH_WORKER"
+
+// sourceLogTail is the number of log lines the summary shows for each failed source.
+const sourceLogTail = 10
+
+// SourceResult is the outcome of synthesizing one source in its own process.
+type SourceResult struct {
+	Source   string        // Matrix source synthesized.
+	Code     int           // Exit code of the synth; 0 on success.
+	Duration time.Duration // Time from starting the synth to collecting its output.
+	Log      string        // File the synth's output was written to.
+	Tail     []string      // Last lines of the log, shown for failures.
+}
+
+// SynthJobsFromEnv returns the default of --jobs: CDKTF_SYNTH_JOBS, or 1.
+func SynthJobsFromEnv() (int, error) {
+	value := os.Getenv(SynthJobsEnvVar)
+	if value == "" {
+		return 1, nil
+	}
+	jobs, err := strconv.Atoi(value)
+	if err != nil || jobs < 1 {
+		return 0, fmt.Errorf("%s must be a positive number, got %q", SynthJobsEnvVar, value)
+	}
+	return jobs, nil
+}
+
+// CheckSynthPool rejects the settings that need every selected source in the same synth: lattice,
+// which associates each VPC once across the stacks of a synth, and depends_on between connections of
+// two selected sources, which orders their stacks in the manifest of that synth.
+func CheckSynthPool(cfg YAMLConfig, sources []string) error {
+	if cfg.Lattice != nil {
+		return fmt.Errorf("--jobs synthesizes each source separately, but lattice associates each VPC once " +
+			"across the stacks of a synth; drop --jobs")
+	}
+	selected := make(map[string]bool, len(sources))
+	for _, source := range sources {
+		selected[source] = true
+	}
+	for _, source := range sources {
+		for _, entry := range expandMatrixEntries(cfg, source, cfg.PeeringMatrix[source], func(string, ...interface{}) {}) {
+			for _, dep := range entry.DependsOn {
+				other := strings.SplitN(dep, "/", 2)[0]
+				if other != source && selected[other] {
+					return fmt.Errorf("%s/%s depends on %s, whose stack --jobs synthesizes separately and cannot order before it; "+
+						"synthesize %s and %s without --jobs", source, entry.Peer, dep, source, other)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// RunSourcePool runs the synth of every source, at most jobs at a time, and returns the results in
+// the order of sources.
+func RunSourcePool(sources []string, jobs int, run func(source string) SourceResult) []SourceResult {
+	results := make([]SourceResult, len(sources))
+	slots := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	for i, source := range sources {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, source string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			results[i] = run(source)
+		}(i, source)
+	}
+	wg.Wait()
+	return results
+}
+
+// RunSynthPool synthesizes every source of the CDKTF_SOURCE pattern in a process of its own, at most
+// jobs at a time, so a failing source neither stops nor interleaves with the others. Each process
+// writes to a directory of its own inside the outdir and logs to <outdir>/logs/<source>.log; the
+// stacks of successful sources are moved into the outdir and listed in one manifest, and their
+// connections' stacks merged into the placements file, when one is given. A summary of every source
+// is logged, and the first failed source's exit code is returned with the error.
+func RunSynthPool(cfg YAMLConfig, pattern string, jobs int, placementsPath string) error {
+	sources, err := MatchSources(cfg, pattern)
+	if err != nil {
+		return WithExitCode(ExitValidation, fmt.Errorf("CDKTF_SOURCE: %w", err))
+	}
+	if err := CheckSynthPool(cfg, sources); err != nil {
+		return WithExitCode(ExitValidation, err)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return WithExitCode(ExitSynth, fmt.Errorf("cannot start the synth of a source: %w", err))
+	}
+	outdir := synthOutdir()
+	logDir := filepath.Join(outdir, "logs")
+	if err := os.MkdirAll(logDir, 0o755); err != nil {
+		return WithExitCode(ExitSynth, err)
+	}
+	log.Printf("[synth] Synthesizing %d source(s), %d at a time; logs in %s", len(sources), jobs, logDir)
+
+	var mu sync.Mutex
+	manifests := make(map[string][]byte)
+	placements := make(map[string]Placements)
+	results := RunSourcePool(sources, jobs, func(source string) SourceResult {
+		started := time.Now()
+		r := SourceResult{Source: source, Log: filepath.Join(logDir, source+".log")}
+		manifest, placed, err := synthPooledSource(exe, outdir, r.Log, source)
+		if err != nil {
+			if data, readErr := os.ReadFile(r.Log); readErr == nil {
+				r.Tail = lastLines(string(data), sourceLogTail)
+			}
+			// A synth that exited reports its own failure; anything else failed around it.
+			r.Code = ExitSynth
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+				r.Code = exitErr.ExitCode()
+			} else {
+				r.Tail = append(r.Tail, err.Error())
+			}
+		} else {
+			mu.Lock()
+			manifests[source] = manifest
+			if placed != nil {
+				placements[source] = placed
+			}
+			mu.Unlock()
+		}
+		r.Duration = time.Since(started)
+		status := "ok"
+		if r.Code != 0 {
+			status = fmt.Sprintf("failed (exit %d)", r.Code)
+		}
+		log.Printf("[synth] %s: %s in %s", source, status, r.Duration.Round(time.Millisecond))
+		return r
+	})
+
+	var ordered [][]byte
+	for _, r := range results {
+		if manifest, ok := manifests[r.Source]; ok {
+			ordered = append(ordered, manifest)
+		}
+	}
+	if len(ordered) > 0 {
+		merged, err := MergeManifests(ordered)
+		if err == nil {
+			err = os.WriteFile(filepath.Join(outdir, "manifest.json"), merged, 0o644)
+		}
+		if err != nil {
+			return WithExitCode(ExitSynth, fmt.Errorf("failed to write the manifest: %w", err))
+		}
+	}
+
+	if len(placements) > 0 {
+		if err := mergePlacements(placementsPath, placements); err != nil {
+			return WithExitCode(ExitSynth, fmt.Errorf("failed to update the placements file: %w", err))
+		}
+	}
+
+	PrintSourceResults(log.Writer(), results)
+	var failed []string
+	code := 0
+	for _, r := range results {
+		if r.Code != 0 {
+			failed = append(failed, r.Source)
+			if code == 0 {
+				code = r.Code
+			}
+		}
+	}
+	if len(failed) > 0 {
+		return WithExitCode(code, fmt.Errorf("%d of %d source(s) failed: %s", len(failed), len(results), strings.Join(failed, ", ")))
+	}
+	return nil
+}
+
+// synthPooledSource synthesizes one source in a child process writing to a temporary directory
+// inside outdir, moves the stacks it synthesized (and their HCL) into outdir, and returns its
+// manifest and, when it split stacks, its placements. The addresses.json of previous synths are
+// copied in first, for the removed blocks of forgotten connections.
+func synthPooledSource(exe, outdir, logPath, source string) ([]byte, Placements, error) {
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer logFile.Close()
+	workdir, err := os.MkdirTemp(outdir, ".source-"+source+"-")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.RemoveAll(workdir)
+	if err := copyAddressMaps(outdir, workdir); err != nil {
+		return nil, nil, err
+	}
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	// The trailing comma keeps the source a pattern, so its stack is named as in a synth of them all.
+	cmd.Env = append(os.Environ(), "CDKTF_SOURCE="+source+",", "CDKTF_OUTDIR="+workdir, SynthWorkerEnvVar+"=1")
+	cmd.Stdout, cmd.Stderr = logFile, logFile
+	if err := cmd.Run(); err != nil {
+		return nil, nil, err
+	}
+
+	manifest, err := os.ReadFile(filepath.Join(workdir, "manifest.json"))
+	if err != nil {
+		return nil, nil, err
+	}
+	stacks, err := manifestStacks(manifest)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, stack := range stacks {
+		for _, dir := range []string{"stacks", "hcl"} {
+			if err := replaceDir(filepath.Join(workdir, dir, stack), filepath.Join(outdir, dir, stack)); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	var placed Placements
+	if _, err := os.Stat(filepath.Join(workdir, PlacementsFile)); err == nil {
+		if placed, err = LoadPlacements(filepath.Join(workdir, PlacementsFile)); err != nil {
+			return nil, nil, err
+		}
+	}
+	return manifest, placed, nil
+}
+
+// mergePlacements updates the placements file with the records of each source's own connections
+// from the placements its synth wrote, leaving other sources' records as the file has them.
+func mergePlacements(path string, bySource map[string]Placements) error {
+	merged, err := LoadPlacements(path)
+	if err != nil {
+		return err
+	}
+	for source, placed := range bySource {
+		for key, stack := range placed {
+			if strings.HasPrefix(key, source+"/") {
+				merged[key] = stack
+			}
+		}
+	}
+	return WritePlacements(path, merged)
+}
+
+// copyAddressMaps copies the addresses.json of every stack in outdir to the same place in workdir.
+func copyAddressMaps(outdir, workdir string) error {
+	paths, err := filepath.Glob(filepath.Join(outdir, "stacks", "*", AddressesFile))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		stack := filepath.Base(filepath.Dir(path))
+		if err := os.MkdirAll(filepath.Join(workdir, "stacks", stack), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(workdir, "stacks", stack, AddressesFile), data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replaceDir moves from to to, replacing what to held. A missing from is not an error.
+func replaceDir(from, to string) error {
+	if _, err := os.Stat(from); os.IsNotExist(err) {
+		return nil
+	}
+	if err := os.RemoveAll(to); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(to), 0o755); err != nil {
+		return err
+	}
+	return os.Rename(from, to)
+}
+
+// manifestStacks returns the names of the stacks of a cdktf manifest, sorted.
+func manifestStacks(manifest []byte) ([]string, error) {
+	var m struct {
+		Stacks map[string]json.RawMessage `json:"stacks"`
+	}
+	if err := json.Unmarshal(manifest, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	stacks := make([]string, 0, len(m.Stacks))
+	for stack := range m.Stacks {
+		stacks = append(stacks, stack)
+	}
+	sort.Strings(stacks)
+	return stacks, nil
+}
+
+// MergeManifests merges the cdktf manifests of several synths into one listing the stacks of all of
+// them; other fields are taken from the first. A stack listed by two manifests is an error.
+func MergeManifests(manifests [][]byte) ([]byte, error) {
+	var merged map[string]json.RawMessage
+	stacks := make(map[string]json.RawMessage)
+	for _, manifest := range manifests {
+		var m map[string]json.RawMessage
+		if err := json.Unmarshal(manifest, &m); err != nil {
+			return nil, fmt.Errorf("invalid manifest: %w", err)
+		}
+		var ms map[string]json.RawMessage
+		if raw, ok := m["stacks"]; ok {
+			if err := json.Unmarshal(raw, &ms); err != nil {
+				return nil, fmt.Errorf("invalid manifest stacks: %w", err)
+			}
+		}
+		for name, stack := range ms {
+			if _, ok := stacks[name]; ok {
+				return nil, fmt.Errorf("stack %s is synthesized by two sources", name)
+			}
+			stacks[name] = stack
+		}
+		if merged == nil {
+			merged = m
+		}
+	}
+	raw, err := json.Marshal(stacks)
+	if err != nil {
+		return nil, err
+	}
+	if merged == nil {
+		merged = make(map[string]json.RawMessage)
+	}
+	merged["stacks"] = raw
+	return json.MarshalIndent(merged, "", "  ")
+}
+
+// lastLines returns the last n non-empty lines of a log.
+func lastLines(text string, n int) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// PrintSourceResults writes the outcome of every source as a table, followed by the last log lines
+// of each failed source.
+func PrintSourceResults(w io.Writer, results []SourceResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SOURCE\tSTATUS\tEXIT\tDURATION\tLOG")
+	for _, r := range results {
+		status := "ok"
+		if r.Code != 0 {
+			status = "FAILED"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", r.Source, status, r.Code, r.Duration.Round(time.Millisecond), r.Log)
+	}
+	tw.Flush()
+	for _, r := range results {
+		if r.Code == 0 || len(r.Tail) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s (last lines of %s):\n", r.Source, r.Log)
+		for _, line := range r.Tail {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package peering
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestRunSourcePool tests that the pool runs every source, never more than jobs at once, and returns
+// the results in the order of the sources.
+func TestRunSourcePool(t *testing.T) {
+	sources := []string{"a", "b", "c", "d", "e"}
+	var mu sync.Mutex
+	running, peak := 0, 0
+	results := RunSourcePool(sources, 2, func(source string) SourceResult {
+		mu.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		code := 0
+		if source == "c" {
+			code = ExitValidation
+		}
+		return SourceResult{Source: source, Code: code}
+	})
+	if peak > 2 {
+		t.Errorf("expected at most 2 synths at once, got %d", peak)
+	}
+	var got []string
+	for _, r := range results {
+		got = append(got, r.Source)
+	}
+	if !reflect.DeepEqual(got, sources) {
+		t.Errorf("expected results in source order, got %v", got)
+	}
+	if results[2].Code != ExitValidation {
+		t.Errorf("expected c to keep its exit code, got %d", results[2].Code)
+	}
+}
+
+// TestCheckSynthPool tests that lattice and depends_on between selected sources refuse the pool.
+func TestCheckSynthPool(t *testing.T) {
+	cfg := YAMLConfig{
+		Peers: map[string]YAMLPeer{"app": {VpcID: "vpc-1"}, "shared": {VpcID: "vpc-2"}, "ops": {VpcID: "vpc-3"}},
+		PeeringMatrix: map[string][]MatrixEntry{
+			"app":    {{Peer: "ops", DependsOn: []string{"shared/ops"}}},
+			"shared": {{Peer: "ops"}},
+		},
+	}
+	if err := CheckSynthPool(cfg, []string{"app"}); err != nil {
+		t.Errorf("dependency on an unselected source: %v", err)
+	}
+	if err := CheckSynthPool(cfg, []string{"app", "shared"}); err == nil || !strings.Contains(err.Error(), "app/ops depends on shared/ops") {
+		t.Errorf("expected a cross-source dependency error, got %v", err)
+	}
+	cfg.Lattice = &LatticeConfig{}
+	if err := CheckSynthPool(cfg, []string{"app"}); err == nil || !strings.Contains(err.Error(), "lattice") {
+		t.Errorf("expected a lattice error, got %v", err)
+	}
+}
+
+// TestSynthJobsFromEnv tests the default of --jobs.
+func TestSynthJobsFromEnv(t *testing.T) {
+	t.Setenv(SynthJobsEnvVar, "")
+	if jobs, err := SynthJobsFromEnv(); err != nil || jobs != 1 {
+		t.Errorf("unset: expected 1, got %d (%v)", jobs, err)
+	}
+	t.Setenv(SynthJobsEnvVar, "4")
+	if jobs, err := SynthJobsFromEnv(); err != nil || jobs != 4 {
+		t.Errorf("4: expected 4, got %d (%v)", jobs, err)
+	}
+	for _, value := range []string{"0", "many"} {
+		t.Setenv(SynthJobsEnvVar, value)
+		if _, err := SynthJobsFromEnv(); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
+
+// TestMergeManifests tests that the stacks of several manifests are listed in one, and that a stack
+// listed twice is an error.
+func TestMergeManifests(t *testing.T) {
+	a := []byte(`{"version":"0.20.0","outdir":"cdktf.out","stacks":{"peering-a":{"name":"peering-a","workingDirectory":"stacks/peering-a"}}}`)
+	b := []byte(`{"version":"0.20.0","outdir":"cdktf.out","stacks":{"peering-b":{"name":"peering-b","workingDirectory":"stacks/peering-b"}}}`)
+	merged, err := MergeManifests([][]byte{a, b})
+	if err != nil {
+		t.Fatalf("MergeManifests: %v", err)
+	}
+	var m struct {
+		Version string                     `json:"version"`
+		Stacks  map[string]json.RawMessage `json:"stacks"`
+	}
+	if err := json.Unmarshal(merged, &m); err != nil {
+		t.Fatalf("invalid merged manifest: %v", err)
+	}
+	if m.Version != "0.20.0" || len(m.Stacks) != 2 {
+		t.Errorf("expected version 0.20.0 and 2 stacks, got %s", merged)
+	}
+	stacks, err := manifestStacks(merged)
+	if err != nil || !reflect.DeepEqual(stacks, []string{"peering-a", "peering-b"}) {
+		t.Errorf("expected both stacks, got %v (%v)", stacks, err)
+	}
+	if _, err := MergeManifests([][]byte{a, a}); err == nil || !strings.Contains(err.Error(), "peering-a") {
+		t.Errorf("expected a duplicate stack error, got %v", err)
+	}
+}
+
+// TestMergePlacements tests that each pooled source updates only its own connections' records.
+func TestMergePlacements(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "placements.json")
+	if err := WritePlacements(path, Placements{"a/x": "main-a", "b/y": "main-b", "c/z": "main-c"}); err != nil {
+		t.Fatal(err)
+	}
+	err := mergePlacements(path, map[string]Placements{
+		"a": {"a/x": "main-a-part2", "b/y": "main-b"},
+		"b": {"a/x": "main-a", "b/y": "main-b-part2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadPlacements(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Placements{"a/x": "main-a-part2", "b/y": "main-b-part2", "c/z": "main-c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+// TestPrintSourceResults tests the summary table and the log tail of failed sources.
+func TestPrintSourceResults(t *testing.T) {
+	var buf bytes.Buffer
+	PrintSourceResults(&buf, []SourceResult{
+		{Source: "app", Duration: 1500 * time.Millisecond, Log: "cdktf.out/logs/app.log"},
+		{Source: "shared", Code: ExitAWS, Duration: time.Second, Log: "cdktf.out/logs/shared.log",
+			Tail: lastLines("[config] Loading\n\nERROR: AssumeRole failed\n", 1)},
+	})
+	out := buf.String()
+	for _, want := range []string{
+		"SOURCE  STATUS  EXIT  DURATION  LOG",
+		"app     ok      0     1.5s      cdktf.out/logs/app.log",
+		"shared  FAILED  5     1s        cdktf.out/logs/shared.log",
+		"shared (last lines of cdktf.out/logs/shared.log):\n  ERROR: AssumeRole failed\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "[config] Loading") {
+		t.Errorf("expected only the last line of the log, got:\n%s", out)
+	}
+}
@@ -0,0 +1,64 @@
+package peering
+
+import "github.com/aws/jsii-runtime-go"
+
+// -------------------------------------------------------------------------------------------------
+// Peering Tags
+// -------------------------------------------------------------------------------------------------
+
+// Peering sides, selecting which environment label and override map apply to a tag set.
+const (
+	SideRequester = "requester" // The peering connection, in the source account.
+	SideAccepter  = "accepter"  // The accepter resource, in the peer account.
+)
+
+// PeeringTags returns the tags of one side of a peering. Both sides share one merged set, in order
+// of increasing precedence: the tool's identifying tags, the side's Environment (the source's for
+// the requester, the peer's for the accepter), config-level tags, per-connection tags, and the
+// side's overrides. The Name tag always comes from the naming strategy.
+func PeeringTags(namer Namer, ctx NameContext, peer PeerConfig, side string) map[string]string {
+	tags := map[string]string{
+		"ManagedBy":   "cdktf",
+		"SourceVpcId": peer.SourceVpcID,
+		"PeerVpcId":   peer.PeerVpcID,
+	}
+
+	env, overrides := peer.SourceEnv, peer.RequesterTags
+	if side == SideAccepter {
+		env, overrides = peer.PeerEnv, peer.AccepterTags
+	}
+	if env != "" {
+		tags["Environment"] = env
+	}
+	for _, m := range []map[string]string{peer.Tags, overrides} {
+		for key, value := range m {
+			tags[key] = value
+		}
+	}
+	tags["Name"] = ConnectionNameTag(namer, ctx, peer)
+	return tags
+}
+
+// stringPtrMap converts a tag map to the pointer form the generated bindings expect.
+func stringPtrMap(m map[string]string) *map[string]*string {
+	out := make(map[string]*string, len(m))
+	for key, value := range m {
+		out[key] = jsii.String(value)
+	}
+	return &out
+}
+
+// mergeTags returns a copy of a with the keys of b it does not already set.
+func mergeTags(a, b map[string]string) map[string]string {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(a)+len(b))
+	for key, value := range b {
+		out[key] = value
+	}
+	for key, value := range a {
+		out[key] = value
+	}
+	return out
+}
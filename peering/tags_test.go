@@ -0,0 +1,46 @@
+package peering
+
+import "testing"
+
+// TestPeeringTags tests that both sides share the merged tag set with per-side overrides.
+func TestPeeringTags(t *testing.T) {
+	peer := PeerConfig{
+		SourceName:    "dev",
+		Name:          "prod",
+		SourceVpcID:   "vpc-1",
+		PeerVpcID:     "vpc-2",
+		SourceEnv:     "staging",
+		PeerEnv:       "prod",
+		Tags:          map[string]string{"Team": "network", "Name": "ignored"},
+		AccepterTags:  map[string]string{"CostCenter": "42"},
+		RequesterTags: map[string]string{"Environment": "shared"},
+	}
+	ctx := ConnectionNameContext(0, peer)
+
+	requester := PeeringTags(LegacyNamer{}, ctx, peer, SideRequester)
+	accepter := PeeringTags(LegacyNamer{}, ctx, peer, SideAccepter)
+
+	tests := []struct {
+		tags  map[string]string
+		key   string
+		value string
+	}{
+		{requester, "Name", "Connection to prod"},
+		{requester, "Team", "network"},
+		{requester, "Environment", "shared"},
+		{requester, "CostCenter", ""},
+		{accepter, "Environment", "prod"},
+		{accepter, "CostCenter", "42"},
+		{accepter, "Team", "network"},
+		{accepter, "ManagedBy", "cdktf"},
+	}
+	for _, tt := range tests {
+		if got := tt.tags[tt.key]; got != tt.value {
+			t.Errorf("tag %s = %q, want %q", tt.key, got, tt.value)
+		}
+	}
+
+	if got := mergeTags(map[string]string{"a": "1"}, map[string]string{"a": "2", "b": "3"}); got["a"] != "1" || got["b"] != "3" {
+		t.Errorf("unexpected merge: %v", got)
+	}
+}
@@ -0,0 +1,73 @@
+package peering
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// -------------------------------------------------------------------------------------------------
+// target-list
+// -------------------------------------------------------------------------------------------------
+
+// runTargetList prints the Terraform addresses of one connection, for applying or planning it alone
+// with -target, each after the stack that holds it. With -args the stack is printed to stderr.
+func runTargetList(args []string) error {
+	fs := flag.NewFlagSet("target-list", flag.ContinueOnError)
+	asArgs := fs.Bool("args", false, "print each address as a -target=<address> argument, and the stack on stderr")
+	placements := placementsFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("expected <source> <peer>, got %d arguments", fs.NArg())
+	}
+	cfg, targets := loadSynthTargets(fs.Arg(0), *placements)
+	stack, i, peer, err := findConnection(targets, fs.Arg(0), fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	if *asArgs {
+		fmt.Fprintf(os.Stderr, "stack %s (%s)\n", stack, stackOutDir(stack))
+	}
+	for _, address := range TargetAddresses(NewNamer(cfg.Naming), ConnectionNameContext(i, peer), peer, cfg.RouteProvenance) {
+		if *asArgs {
+			fmt.Printf("-target=%s\n", address)
+		} else {
+			fmt.Printf("%s\t%s\n", stack, address)
+		}
+	}
+	return nil
+}
+
+// findConnection returns the connection from source to the named peer, the stack holding it, and
+// its index in that stack, which synth numbers it by.
+func findConnection(targets []synthTarget, source, target string) (string, int, PeerConfig, error) {
+	for _, t := range targets {
+		for i, peer := range t.Peers {
+			if peer.Name == target {
+				return t.Stack, i, peer, nil
+			}
+		}
+	}
+	return "", 0, PeerConfig{}, fmt.Errorf("no connection from %q to %q in the peering matrix", source, target)
+}
+
+// TargetAddresses returns the sorted Terraform addresses of every resource one connection manages:
+// the peering, accepter, and options, its routes, and the route provenance records written for it.
+// Targeting them applies the connection without touching the others; for_each addresses cover
+// every instance.
+func TargetAddresses(namer Namer, ctx NameContext, peer PeerConfig, provenance ProvenanceConfig) []string {
+	var out []string
+	for _, address := range ConnectionAddresses(namer, ctx, peer) {
+		out = append(out, address)
+	}
+	for resourceType, resources := range RouteProvenance(namer, ctx, RequesterOnlyPeers([]PeerConfig{peer})[0], provenance) {
+		for name := range resources {
+			out = append(out, resourceType+"."+name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,24 @@
+package peering
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestTargetAddresses tests that a connection's targets cover its resources and provenance records.
+func TestTargetAddresses(t *testing.T) {
+	peer := PeerConfig{SourceName: "dev", Name: "prod", SourceRegion: "us-east-1", PeerRegion: "us-east-1"}
+	got := TargetAddresses(LegacyNamer{}, ConnectionNameContext(1, peer), peer, ProvenanceConfig{TagRouteTables: true})
+	want := []string{
+		"aws_ec2_tag.PeerRouteTableTag1",
+		"aws_ec2_tag.SourceRouteTableTag1",
+		"aws_route.PeerToPeerMainRoute1",
+		"aws_route.SourceToPeerMainRoute1",
+		"aws_vpc_peering_connection.VpcPeering1",
+		"aws_vpc_peering_connection_options.VpcPeeringAccepterOptions1",
+		"aws_vpc_peering_connection_options.VpcPeeringOptions1",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,104 @@
+package peering
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Synth Metrics
+// -------------------------------------------------------------------------------------------------
+
+// Environment variables enabling synth metrics. Metrics are off unless CDKTF_STATSD_ADDR is set.
+const (
+	StatsdAddrEnvVar   = "CDKTF_STATSD_ADDR"   // host:port of a statsd agent, e.g. 127.0.0.1:8125.
+	StatsdPrefixEnvVar = "CDKTF_STATSD_PREFIX" // Prefix of every metric name (DefaultStatsdPrefix if empty).
+)
+
+// DefaultStatsdPrefix is the metric name prefix used when CDKTF_STATSD_PREFIX is unset.
+const DefaultStatsdPrefix = "vpc_peering"
+
+// Metrics emits statsd metrics, with DogStatsD tags, one datagram per metric. A nil Metrics discards
+// everything, so callers need not check whether metrics are enabled. Send errors are ignored: the
+// tool's health metrics must never fail a synth.
+type Metrics struct {
+	w      io.Writer // Destination, a UDP connection in production.
+	prefix string    // Prefix joined to every metric name with a dot.
+}
+
+// metrics records the current synth run; nil unless MetricsFromEnv enabled it.
+var metrics *Metrics
+
+// NewMetrics returns Metrics writing to w with the given name prefix.
+func NewMetrics(w io.Writer, prefix string) *Metrics {
+	return &Metrics{w: w, prefix: prefix}
+}
+
+// MetricsFromEnv returns Metrics sending to the statsd agent at CDKTF_STATSD_ADDR, or nil when it is
+// unset. The address must resolve; nothing is sent until the first metric.
+func MetricsFromEnv() (*Metrics, error) {
+	addr := os.Getenv(StatsdAddrEnvVar)
+	if addr == "" {
+		return nil, nil
+	}
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", StatsdAddrEnvVar, err)
+	}
+	prefix := os.Getenv(StatsdPrefixEnvVar)
+	if prefix == "" {
+		prefix = DefaultStatsdPrefix
+	}
+	return NewMetrics(conn, prefix), nil
+}
+
+// Count adds n to a counter.
+func (m *Metrics) Count(name string, n int, tags map[string]string) {
+	m.send(name, fmt.Sprint(n), "c", tags)
+}
+
+// Gauge sets a gauge.
+func (m *Metrics) Gauge(name string, value int, tags map[string]string) {
+	m.send(name, fmt.Sprint(value), "g", tags)
+}
+
+// Timing records a duration in milliseconds.
+func (m *Metrics) Timing(name string, d time.Duration, tags map[string]string) {
+	m.send(name, fmt.Sprint(d.Milliseconds()), "ms", tags)
+}
+
+// send writes one metric as <prefix>.<name>:<value>|<type>|#<tag>:<value>,..., tags sorted by name.
+func (m *Metrics) send(name, value, kind string, tags map[string]string) {
+	if m == nil {
+		return
+	}
+	line := fmt.Sprintf("%s.%s:%s|%s", m.prefix, name, value, kind)
+	if len(tags) > 0 {
+		pairs := make([]string, 0, len(tags))
+		for k, v := range tags {
+			pairs = append(pairs, k+":"+v)
+		}
+		sort.Strings(pairs)
+		line += "|#" + strings.Join(pairs, ",")
+	}
+	_, _ = io.WriteString(m.w, line)
+}
+
+// recordFailure counts a failed synth by the class of its exit code.
+func recordFailure(code int) {
+	metrics.Count("synth.failures", 1, map[string]string{"class": failureClasses[code]})
+}
+
+// metricTags returns the tags of a stack's metrics: the stack and, for per-source stacks, the source.
+func (t synthTarget) metricTags() map[string]string {
+	tags := map[string]string{"stack": t.Stack}
+	if t.Source != "" {
+		tags["source"] = t.Source
+	}
+	return tags
+}
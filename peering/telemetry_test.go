@@ -0,0 +1,59 @@
+package peering
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// lineWriter records each write, as a UDP connection sends each write as one datagram.
+type lineWriter struct {
+	lines []string
+}
+
+// Write records p as one line.
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.lines = append(w.lines, string(p))
+	return len(p), nil
+}
+
+// TestMetrics tests the statsd wire format of each metric type and that nil Metrics discard.
+func TestMetrics(t *testing.T) {
+	w := &lineWriter{}
+	m := NewMetrics(w, "vpc_peering")
+	m.Count("synth.failures", 1, map[string]string{"class": "validation"})
+	m.Gauge("synth.connections", 12, synthTarget{Stack: "main-dev", Source: "dev"}.metricTags())
+	m.Timing("synth.duration", 1500*time.Millisecond, nil)
+
+	want := []string{
+		"vpc_peering.synth.failures:1|c|#class:validation",
+		"vpc_peering.synth.connections:12|g|#source:dev,stack:main-dev",
+		"vpc_peering.synth.duration:1500|ms",
+	}
+	if strings.Join(w.lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(w.lines, "\n"))
+	}
+
+	var disabled *Metrics
+	disabled.Count("synth.success", 1, nil)
+}
+
+// TestMetricsFromEnv tests that metrics are off by default and sent to the configured agent.
+func TestMetricsFromEnv(t *testing.T) {
+	t.Setenv(StatsdAddrEnvVar, "")
+	if m, err := MetricsFromEnv(); m != nil || err != nil {
+		t.Errorf("expected metrics to be off, got %v, %v", m, err)
+	}
+
+	t.Setenv(StatsdAddrEnvVar, "127.0.0.1:8125")
+	t.Setenv(StatsdPrefixEnvVar, "")
+	m, err := MetricsFromEnv()
+	if err != nil || m == nil || m.prefix != DefaultStatsdPrefix {
+		t.Errorf("expected metrics with prefix %s, got %+v, %v", DefaultStatsdPrefix, m, err)
+	}
+
+	t.Setenv(StatsdAddrEnvVar, "no-port")
+	if _, err := MetricsFromEnv(); err == nil {
+		t.Error("expected an error for an address without a port")
+	}
+}
@@ -0,0 +1,86 @@
+package peering
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Terminal Control
+// -------------------------------------------------------------------------------------------------
+
+// The full-screen view drives the terminal through stty rather than a terminal library, to keep the
+// tool free of extra dependencies; rawTerminal and terminalSize live in terminal_unix.go. Windows has
+// no stty, so there tui offers the line-oriented browser only (terminal_windows.go).
+
+// errNoFullScreen is returned on platforms without stty when the full-screen view is asked for.
+var errNoFullScreen = errors.New("the full-screen tui needs stty, which this platform lacks; run tui -plain for the line-oriented browser")
+
+// isTerminal reports whether f is a character device, such as a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// keySequences maps the escape sequences of the navigation keys to their names.
+var keySequences = map[string]string{
+	"\x1b[A": keyUp, "\x1b[B": keyDown, "\x1bOA": keyUp, "\x1bOB": keyDown,
+	"\x1b[5~": keyPageUp, "\x1b[6~": keyPageDown,
+	"\x1b[H": keyHome, "\x1b[F": keyEnd, "\x1bOH": keyHome, "\x1bOF": keyEnd, "\x1b[1~": keyHome, "\x1b[4~": keyEnd,
+}
+
+// decodeKeys splits the bytes of one read from a raw terminal into keys: the named navigation keys,
+// Enter, Backspace, Escape, Ctrl-C, and printable characters. Other control characters and escape
+// sequences are dropped.
+func decodeKeys(data []byte) []string {
+	var keys []string
+	s := string(data)
+	for len(s) > 0 {
+		if s[0] == 0x1b {
+			if key, n := escapeKey(s); n > 0 {
+				if key != "" {
+					keys = append(keys, key)
+				}
+				s = s[n:]
+				continue
+			}
+			keys = append(keys, keyEscape)
+			s = s[1:]
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s)
+		switch {
+		case r == '\r' || r == '\n':
+			keys = append(keys, keyEnter)
+		case r == 0x7f || r == 0x08:
+			keys = append(keys, keyBackspace)
+		case r == 0x03:
+			keys = append(keys, keyInterrupt)
+		case r >= 0x20:
+			keys = append(keys, string(r))
+		}
+		s = s[size:]
+	}
+	return keys
+}
+
+// escapeKey returns the key of the escape sequence s starts with and its length, "" for an unknown
+// sequence, or a length of 0 when s starts with a lone Escape.
+func escapeKey(s string) (string, int) {
+	for seq, key := range keySequences {
+		if strings.HasPrefix(s, seq) {
+			return key, len(seq)
+		}
+	}
+	if len(s) < 3 || (s[1] != '[' && s[1] != 'O') {
+		return "", 0
+	}
+	for i := 2; i < len(s); i++ {
+		if s[i] >= 0x40 && s[i] <= 0x7e {
+			return "", i + 1
+		}
+	}
+	return "", len(s)
+}
@@ -0,0 +1,49 @@
+//go:build !windows
+
+package peering
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// fullScreen tells whether the full-screen tui can drive the terminal on this platform.
+const fullScreen = true
+
+// stty runs stty with args on the terminal in and returns its output.
+func stty(in *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = in
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// rawTerminal switches the terminal in to raw mode without echo, so keys are read as they are
+// pressed, and returns a function restoring its previous settings.
+func rawTerminal(in *os.File) (func() error, error) {
+	saved, err := stty(in, "-g")
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the terminal settings (is stty on PATH?): %w", err)
+	}
+	if _, err := stty(in, "raw", "-echo"); err != nil {
+		return nil, fmt.Errorf("cannot switch the terminal to raw mode: %w", err)
+	}
+	return func() error {
+		_, err := stty(in, saved)
+		return err
+	}, nil
+}
+
+// terminalSize returns the rows and columns of the terminal in, or 24 by 80 when stty cannot tell.
+func terminalSize(in *os.File) (int, int) {
+	out, err := stty(in, "size")
+	var rows, cols int
+	if err == nil {
+		if _, err := fmt.Sscan(out, &rows, &cols); err == nil && rows > 0 && cols > 0 {
+			return rows, cols
+		}
+	}
+	return 24, 80
+}
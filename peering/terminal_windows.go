@@ -0,0 +1,18 @@
+//go:build windows
+
+package peering
+
+import "os"
+
+// fullScreen tells whether the full-screen tui can drive the terminal on this platform.
+const fullScreen = false
+
+// rawTerminal fails: Windows has no stty to switch the console to raw mode.
+func rawTerminal(in *os.File) (func() error, error) {
+	return nil, errNoFullScreen
+}
+
+// terminalSize returns 24 by 80, as the console size cannot be read without stty.
+func terminalSize(in *os.File) (int, int) {
+	return 24, 80
+}
@@ -0,0 +1,86 @@
+package peering
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Terraform Version Pinning
+// -------------------------------------------------------------------------------------------------
+
+// TerraformSettings pins the Terraform CLI and AWS provider versions the synthesized stacks require,
+// so the output does not depend on the provider bindings generated on each machine.
+type TerraformSettings struct {
+	RequiredVersion    string             `yaml:"required_version,omitempty"`     // Terraform version constraint (e.g. ">= 1.5, < 2.0").
+	AwsProviderVersion string             `yaml:"aws_provider_version,omitempty"` // AWS provider version constraint (e.g. "~> 5.72").
+	S3Backend          *S3BackendSettings `yaml:"s3_backend,omitempty"`           // State in S3 instead of a local file per stack.
+}
+
+// S3BackendSettings keeps the state of every stack in an S3 bucket. The key must contain "{stack}",
+// which is replaced by the stack name, so the main, per-source, accept, and bootstrap stacks do not
+// share a state.
+type S3BackendSettings struct {
+	Bucket        string `yaml:"bucket"`                   // State bucket.
+	Key           string `yaml:"key"`                      // State object key, e.g. "peering/{stack}.tfstate".
+	Region        string `yaml:"region,omitempty"`         // Region of the bucket (default region if empty).
+	DynamoDBTable string `yaml:"dynamodb_table,omitempty"` // Lock table (no state locking if empty).
+}
+
+// versionConstraintPattern matches one term of a Terraform version constraint.
+var versionConstraintPattern = regexp.MustCompile(`^(=|!=|>|>=|<|<=|~>)?\s*v?\d+(\.\d+){0,2}(-[0-9A-Za-z.-]+)?$`)
+
+// validateVersionConstraint checks the syntax of a comma-separated version constraint.
+func validateVersionConstraint(constraint string) error {
+	for _, term := range strings.Split(constraint, ",") {
+		if !versionConstraintPattern.MatchString(strings.TrimSpace(term)) {
+			return fmt.Errorf("invalid version constraint term %q in %q", strings.TrimSpace(term), constraint)
+		}
+	}
+	return nil
+}
+
+// Validate checks that the constraints are well-formed, so terraform init does not reject them.
+func (t TerraformSettings) Validate() error {
+	if t.RequiredVersion != "" {
+		if err := validateVersionConstraint(t.RequiredVersion); err != nil {
+			return fmt.Errorf("required_version: %w", err)
+		}
+	}
+	if t.AwsProviderVersion != "" {
+		if err := validateVersionConstraint(t.AwsProviderVersion); err != nil {
+			return fmt.Errorf("aws_provider_version: %w", err)
+		}
+	}
+	if b := t.S3Backend; b != nil && (b.Bucket == "" || !strings.Contains(b.Key, "{stack}")) {
+		return fmt.Errorf("s3_backend needs a bucket and a key containing {stack}")
+	}
+	return nil
+}
+
+// Apply sets the constraints and the backend in the terraform block of a stack. The AWS provider
+// constraint replaces the one the provider bindings were generated with.
+func (t TerraformSettings) Apply(stack cdktf.TerraformStack) {
+	if b := t.S3Backend; b != nil {
+		config := &cdktf.S3BackendConfig{
+			Bucket:  jsii.String(b.Bucket),
+			Key:     jsii.String(strings.ReplaceAll(b.Key, "{stack}", *stack.Node().Id())),
+			Region:  jsii.String(ResolveRegion(b.Region)),
+			Encrypt: jsii.Bool(true),
+		}
+		if b.DynamoDBTable != "" {
+			config.DynamodbTable = jsii.String(b.DynamoDBTable)
+		}
+		cdktf.NewS3Backend(stack, config)
+	}
+	if t.RequiredVersion != "" {
+		stack.AddOverride(jsii.String("terraform.required_version"), t.RequiredVersion)
+	}
+	if t.AwsProviderVersion != "" {
+		stack.AddOverride(jsii.String("terraform.required_providers.aws.version"), t.AwsProviderVersion)
+	}
+}
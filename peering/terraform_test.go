@@ -0,0 +1,27 @@
+package peering
+
+import "testing"
+
+// TestTerraformSettingsValidate tests the syntax check of version constraints.
+func TestTerraformSettingsValidate(t *testing.T) {
+	tests := []struct {
+		settings TerraformSettings
+		valid    bool
+	}{
+		{TerraformSettings{}, true},
+		{TerraformSettings{RequiredVersion: ">= 1.5, < 2.0", AwsProviderVersion: "~> 5.72"}, true},
+		{TerraformSettings{AwsProviderVersion: "5.72.1"}, true},
+		{TerraformSettings{AwsProviderVersion: "= 6.0.0-beta1"}, true},
+		{TerraformSettings{RequiredVersion: "latest"}, false},
+		{TerraformSettings{RequiredVersion: ">= 1.5,"}, false},
+		{TerraformSettings{AwsProviderVersion: "=> 5.0"}, false},
+		{TerraformSettings{S3Backend: &S3BackendSettings{Bucket: "state", Key: "peering/{stack}.tfstate"}}, true},
+		{TerraformSettings{S3Backend: &S3BackendSettings{Bucket: "state", Key: "peering.tfstate"}}, false},
+		{TerraformSettings{S3Backend: &S3BackendSettings{Key: "peering/{stack}.tfstate"}}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.settings.Validate(); (err == nil) != tt.valid {
+			t.Errorf("Validate(%+v) error = %v, want valid=%v", tt.settings, err, tt.valid)
+		}
+	}
+}
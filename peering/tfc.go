@@ -0,0 +1,345 @@
+package peering
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Terraform Cloud Runs
+// -------------------------------------------------------------------------------------------------
+
+// DefaultTFCHostname is the Terraform Cloud API host, overridable for Terraform Enterprise.
+const DefaultTFCHostname = "app.terraform.io"
+
+// TFCClient calls the Terraform Cloud / Enterprise API.
+type TFCClient struct {
+	BaseURL string       // API root, e.g. https://app.terraform.io.
+	Token   string       // User or team API token.
+	HTTP    *http.Client // HTTP client (http.DefaultClient if nil).
+}
+
+// tfcDocument is the JSON:API envelope of requests and responses.
+type tfcDocument struct {
+	Data tfcResource `json:"data"`
+}
+
+// tfcResource is one JSON:API resource.
+type tfcResource struct {
+	ID            string                 `json:"id,omitempty"`
+	Type          string                 `json:"type"`
+	Attributes    map[string]interface{} `json:"attributes,omitempty"`
+	Relationships map[string]interface{} `json:"relationships,omitempty"`
+}
+
+// do sends a JSON:API request and decodes the response into out when it is not nil.
+func (c *TFCClient) do(method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, strings.TrimRight(c.BaseURL, "/")+"/api/v2"+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("Content-Type", "application/vnd.api+json")
+
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(data)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// WorkspaceID returns the ID of a workspace by organization and name.
+func (c *TFCClient) WorkspaceID(org, workspace string) (string, error) {
+	var doc tfcDocument
+	if err := c.do(http.MethodGet, "/organizations/"+org+"/workspaces/"+workspace, nil, &doc); err != nil {
+		return "", err
+	}
+	return doc.Data.ID, nil
+}
+
+// CreateConfigurationVersion creates a configuration version that does not queue a run by itself,
+// returning its ID and upload URL.
+func (c *TFCClient) CreateConfigurationVersion(workspaceID string) (string, string, error) {
+	in := tfcDocument{Data: tfcResource{Type: "configuration-versions", Attributes: map[string]interface{}{"auto-queue-runs": false}}}
+	var doc tfcDocument
+	if err := c.do(http.MethodPost, "/workspaces/"+workspaceID+"/configuration-versions", in, &doc); err != nil {
+		return "", "", err
+	}
+	uploadURL, _ := doc.Data.Attributes["upload-url"].(string)
+	return doc.Data.ID, uploadURL, nil
+}
+
+// Upload sends a configuration archive to the upload URL of a configuration version.
+func (c *TFCClient) Upload(uploadURL string, archive []byte) error {
+	req, err := http.NewRequest(http.MethodPut, uploadURL, bytes.NewReader(archive))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("upload failed: %s", resp.Status)
+	}
+	return nil
+}
+
+// status reads the status attribute of a resource.
+func (c *TFCClient) status(path string) (string, map[string]interface{}, error) {
+	var doc tfcDocument
+	if err := c.do(http.MethodGet, path, nil, &doc); err != nil {
+		return "", nil, err
+	}
+	status, _ := doc.Data.Attributes["status"].(string)
+	return status, doc.Data.Attributes, nil
+}
+
+// CreateRun queues a run of a configuration version in a workspace.
+func (c *TFCClient) CreateRun(workspaceID, configVersionID, message string, autoApply bool) (string, error) {
+	in := tfcDocument{Data: tfcResource{
+		Type:       "runs",
+		Attributes: map[string]interface{}{"message": message, "auto-apply": autoApply},
+		Relationships: map[string]interface{}{
+			"workspace":             map[string]interface{}{"data": map[string]string{"type": "workspaces", "id": workspaceID}},
+			"configuration-version": map[string]interface{}{"data": map[string]string{"type": "configuration-versions", "id": configVersionID}},
+		},
+	}}
+	var doc tfcDocument
+	if err := c.do(http.MethodPost, "/runs", in, &doc); err != nil {
+		return "", err
+	}
+	return doc.Data.ID, nil
+}
+
+// Run statuses in which a run has stopped, successfully or not.
+var tfcFinalRunStatuses = map[string]bool{
+	"applied": true, "planned_and_finished": true, "errored": true, "discarded": true,
+	"canceled": true, "force_canceled": true, "policy_soft_failed": true,
+}
+
+// TFCRunSucceeded reports whether a final run status means the configuration is in place.
+func TFCRunSucceeded(status string) bool {
+	return status == "applied" || status == "planned_and_finished"
+}
+
+// WaitForRun polls a run, printing each status change, until it stops or awaits confirmation. It
+// returns the last status and whether the run is waiting to be confirmed.
+func (c *TFCClient) WaitForRun(runID string, interval time.Duration, w io.Writer) (string, bool, error) {
+	last := ""
+	for {
+		status, attrs, err := c.status("/runs/" + runID)
+		if err != nil {
+			return last, false, err
+		}
+		if status != last {
+			fmt.Fprintf(w, "[tfc] %s: %s\n", runID, status)
+			last = status
+		}
+		if tfcFinalRunStatuses[status] {
+			return status, false, nil
+		}
+		if actions, ok := attrs["actions"].(map[string]interface{}); ok && actions["is-confirmable"] == true {
+			return status, true, nil
+		}
+		time.Sleep(interval)
+	}
+}
+
+// PackStack archives the synthesized stack for upload. The backend block is dropped, since
+// runs in the workspace keep their state in the workspace.
+func PackStack(dir string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "cdk.tf.json"))
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", dir, err)
+	}
+	if tf, ok := doc["terraform"].(map[string]interface{}); ok {
+		delete(tf, "backend")
+	}
+	if data, err = json.MarshalIndent(doc, "", "  "); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Name: "cdk.tf.json", Mode: 0o644, Size: int64(len(data)), ModTime: time.Now()}); err != nil {
+		return nil, err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// TFCToken returns the API token for a host from TFE_TOKEN, TF_TOKEN_<host>, or the credentials
+// file terraform login writes.
+func TFCToken(hostname string) (string, error) {
+	if token := os.Getenv("TFE_TOKEN"); token != "" {
+		return token, nil
+	}
+	envName := "TF_TOKEN_" + strings.NewReplacer(".", "_", "-", "__").Replace(hostname)
+	if token := os.Getenv(envName); token != "" {
+		return token, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(filepath.Join(home, ".terraform.d", "credentials.tfrc.json"))
+	if err != nil {
+		return "", fmt.Errorf("no token for %s: set TFE_TOKEN or %s, or run terraform login", hostname, envName)
+	}
+	var creds struct {
+		Credentials map[string]struct {
+			Token string `json:"token"`
+		} `json:"credentials"`
+	}
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return "", fmt.Errorf("failed to parse terraform credentials: %w", err)
+	}
+	if token := creds.Credentials[hostname].Token; token != "" {
+		return token, nil
+	}
+	return "", fmt.Errorf("no token for %s in terraform credentials; run terraform login %s", hostname, hostname)
+}
+
+// -------------------------------------------------------------------------------------------------
+// apply
+// -------------------------------------------------------------------------------------------------
+
+// runApply synthesizes the stack and applies it through a Terraform Cloud workspace: it uploads the
+// configuration, queues a run, and follows it until it finishes or awaits confirmation.
+func runApply(args []string) error {
+	fs := flag.NewFlagSet("apply", flag.ContinueOnError)
+	viaTFC := fs.Bool("via-tfc", false, "apply through a Terraform Cloud workspace (the only mode; local applies use make deploy)")
+	hostname := fs.String("hostname", DefaultTFCHostname, "Terraform Cloud or Enterprise hostname")
+	org := fs.String("org", os.Getenv("TFE_ORGANIZATION"), "organization of the workspace (default $TFE_ORGANIZATION)")
+	workspace := fs.String("workspace", os.Getenv("TFE_WORKSPACE"), "workspace to run in (default $TFE_WORKSPACE)")
+	autoApply := fs.Bool("auto-apply", false, "apply without confirmation once the plan succeeds")
+	message := fs.String("message", "Applied by vpc-peering-tool", "run message shown in Terraform Cloud")
+	poll := fs.Duration("poll", 5*time.Second, "interval between run status checks")
+	skipSynth := fs.Bool("no-synth", false, "upload the existing synth output instead of synthesizing first")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if !*viaTFC {
+		return fmt.Errorf("apply only runs through Terraform Cloud (-via-tfc); use make deploy to apply locally")
+	}
+	if *org == "" || *workspace == "" {
+		return fmt.Errorf("-org and -workspace are required")
+	}
+	if source := sourceArg(fs); source != "" {
+		os.Setenv("CDKTF_SOURCE", source)
+	}
+
+	token, err := TFCToken(*hostname)
+	if err != nil {
+		return err
+	}
+	if !*skipSynth {
+		if out, err := runChild(); err != nil {
+			os.Stderr.Write(out)
+			return fmt.Errorf("synth failed: %w", err)
+		}
+	}
+	archive, err := PackStack(stackOutDir(StackName))
+	if err != nil {
+		return err
+	}
+
+	client := &TFCClient{BaseURL: "https://" + *hostname, Token: token}
+	workspaceID, err := client.WorkspaceID(*org, *workspace)
+	if err != nil {
+		return err
+	}
+	cvID, uploadURL, err := client.CreateConfigurationVersion(workspaceID)
+	if err != nil {
+		return err
+	}
+	if err := client.Upload(uploadURL, archive); err != nil {
+		return err
+	}
+	for {
+		status, _, err := client.status("/configuration-versions/" + cvID)
+		if err != nil {
+			return err
+		}
+		if status == "uploaded" {
+			break
+		}
+		if status == "errored" {
+			return fmt.Errorf("configuration version %s failed to ingest", cvID)
+		}
+		time.Sleep(*poll)
+	}
+
+	runID, err := client.CreateRun(workspaceID, cvID, *message, *autoApply)
+	if err != nil {
+		return err
+	}
+	runURL := fmt.Sprintf("https://%s/app/%s/workspaces/%s/runs/%s", *hostname, *org, *workspace, runID)
+	log.Printf("[tfc] Queued %s", runURL)
+
+	status, confirmable, err := client.WaitForRun(runID, *poll, log.Writer())
+	switch {
+	case err != nil:
+		return err
+	case confirmable:
+		log.Printf("[tfc] Plan finished; confirm or discard the run at %s", runURL)
+		return nil
+	case !TFCRunSucceeded(status):
+		return fmt.Errorf("run %s ended %s: %s", runID, status, runURL)
+	}
+	return nil
+}
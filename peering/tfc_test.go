@@ -0,0 +1,167 @@
+package peering
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestPackStack tests that the stack is archived as cdk.tf.json without its backend.
+func TestPackStack(t *testing.T) {
+	dir := t.TempDir()
+	stack := `{"terraform":{"backend":{"s3":{"bucket":"b"}},"required_providers":{"aws":{"source":"aws"}}},"resource":{}}`
+	if err := os.WriteFile(filepath.Join(dir, "cdk.tf.json"), []byte(stack), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	archive, err := PackStack(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	hdr, err := tr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Name != "cdk.tf.json" {
+		t.Errorf("expected cdk.tf.json, got %s", hdr.Name)
+	}
+	var doc map[string]map[string]interface{}
+	data, _ := io.ReadAll(tr)
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := doc["terraform"]["backend"]; ok {
+		t.Error("expected the backend to be dropped")
+	}
+	if _, ok := doc["terraform"]["required_providers"]; !ok {
+		t.Error("expected the other terraform settings to be kept")
+	}
+	if _, err := tr.Next(); err != io.EOF {
+		t.Errorf("expected a single file, got %v", err)
+	}
+}
+
+// TestTFCRun tests the upload, run creation, and status polling against a fake API.
+func TestTFCRun(t *testing.T) {
+	statuses := []string{"pending", "planning", "planning", "applying", "applied"}
+	var uploaded []byte
+	var run tfcDocument
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	reply := func(w http.ResponseWriter, id string, attrs map[string]interface{}) {
+		json.NewEncoder(w).Encode(tfcDocument{Data: tfcResource{ID: id, Attributes: attrs}})
+	}
+	mux.HandleFunc("/api/v2/organizations/acme/workspaces/peering", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		reply(w, "ws-1", nil)
+	})
+	mux.HandleFunc("/api/v2/workspaces/ws-1/configuration-versions", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, "cv-1", map[string]interface{}{"upload-url": srv.URL + "/upload/cv-1"})
+	})
+	mux.HandleFunc("/upload/cv-1", func(w http.ResponseWriter, r *http.Request) {
+		uploaded, _ = io.ReadAll(r.Body)
+	})
+	mux.HandleFunc("/api/v2/runs", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&run)
+		reply(w, "run-1", nil)
+	})
+	mux.HandleFunc("/api/v2/runs/run-1", func(w http.ResponseWriter, r *http.Request) {
+		status := statuses[0]
+		if len(statuses) > 1 {
+			statuses = statuses[1:]
+		}
+		reply(w, "run-1", map[string]interface{}{"status": status})
+	})
+
+	client := &TFCClient{BaseURL: srv.URL, Token: "tok"}
+	if _, err := (&TFCClient{BaseURL: srv.URL, Token: "bad"}).WorkspaceID("acme", "peering"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected an authorization error, got %v", err)
+	}
+	ws, err := client.WorkspaceID("acme", "peering")
+	if err != nil || ws != "ws-1" {
+		t.Fatalf("expected ws-1, got %q, %v", ws, err)
+	}
+	cv, url, err := client.CreateConfigurationVersion(ws)
+	if err != nil || cv != "cv-1" {
+		t.Fatalf("expected cv-1, got %q, %v", cv, err)
+	}
+	if err := client.Upload(url, []byte("archive")); err != nil || string(uploaded) != "archive" {
+		t.Fatalf("expected the archive to be uploaded, got %q, %v", uploaded, err)
+	}
+	runID, err := client.CreateRun(ws, cv, "msg", true)
+	if err != nil || runID != "run-1" {
+		t.Fatalf("expected run-1, got %q, %v", runID, err)
+	}
+	if run.Data.Attributes["auto-apply"] != true || run.Data.Attributes["message"] != "msg" {
+		t.Errorf("unexpected run attributes %v", run.Data.Attributes)
+	}
+
+	var out bytes.Buffer
+	status, confirmable, err := client.WaitForRun(runID, 0, &out)
+	if err != nil || status != "applied" || confirmable {
+		t.Fatalf("expected applied, got %q, %v, %v", status, confirmable, err)
+	}
+	want := "[tfc] run-1: pending\n[tfc] run-1: planning\n[tfc] run-1: applying\n[tfc] run-1: applied\n"
+	if out.String() != want {
+		t.Errorf("expected status changes\n%s\ngot\n%s", want, out.String())
+	}
+}
+
+// TestWaitForRunConfirmable tests that polling stops when the run awaits confirmation.
+func TestWaitForRunConfirmable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(tfcDocument{Data: tfcResource{ID: "run-1", Attributes: map[string]interface{}{
+			"status": "planned", "actions": map[string]interface{}{"is-confirmable": true},
+		}}})
+	}))
+	defer srv.Close()
+	status, confirmable, err := (&TFCClient{BaseURL: srv.URL}).WaitForRun("run-1", 0, io.Discard)
+	if err != nil || status != "planned" || !confirmable {
+		t.Errorf("expected a confirmable planned run, got %q, %v, %v", status, confirmable, err)
+	}
+	if TFCRunSucceeded("errored") || !TFCRunSucceeded("planned_and_finished") {
+		t.Error("unexpected run outcome")
+	}
+}
+
+// TestTFCToken tests the token lookup order.
+func TestTFCToken(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("TFE_TOKEN", "")
+	t.Setenv("TF_TOKEN_tfe_example_com", "")
+	if _, err := TFCToken("tfe.example.com"); err == nil {
+		t.Error("expected an error without credentials")
+	}
+	os.MkdirAll(filepath.Join(home, ".terraform.d"), 0o755)
+	os.WriteFile(filepath.Join(home, ".terraform.d", "credentials.tfrc.json"),
+		[]byte(`{"credentials":{"tfe.example.com":{"token":"file"}}}`), 0o600)
+	for _, tc := range []struct{ env, value, want string }{
+		{"", "", "file"},
+		{"TF_TOKEN_tfe_example_com", "host", "host"},
+		{"TFE_TOKEN", "global", "global"},
+	} {
+		if tc.env != "" {
+			t.Setenv(tc.env, tc.value)
+		}
+		if got, err := TFCToken("tfe.example.com"); err != nil || got != tc.want {
+			t.Errorf("expected %q, got %q, %v", tc.want, got, err)
+		}
+	}
+}
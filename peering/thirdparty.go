@@ -0,0 +1,69 @@
+package peering
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Third-Party Peers
+// -------------------------------------------------------------------------------------------------
+
+// accountIDPattern matches AWS account IDs.
+var accountIDPattern = regexp.MustCompile(`^\d{12}$`)
+
+// ThirdParty reports whether the peer's VPC belongs to an account the tool has no role in: it
+// declares account_id without role_arn or role_arns. Connections to it build only the requester
+// side; the owner accepts the peering and routes back on their own.
+func (p YAMLPeer) ThirdParty() bool {
+	return p.AccountID != "" && p.RoleArn == "" && len(p.RoleArns) == 0
+}
+
+// ValidateThirdPartyPeers checks the account_id of every peer declaring one, and that it matches the
+// account of the peer's role when the ARN names it.
+func ValidateThirdPartyPeers(cfg YAMLConfig) error {
+	for _, name := range sortedPeerNames(cfg) {
+		p := cfg.Peers[name]
+		if p.AccountID == "" {
+			continue
+		}
+		if !accountIDPattern.MatchString(p.AccountID) {
+			return fmt.Errorf("peer %q: account_id must be a 12-digit account ID, got %q", name, p.AccountID)
+		}
+		if account := GetAccountIDFromRoleArn(p.RoleArn); account != "" && account != p.AccountID {
+			return fmt.Errorf("peer %q: account_id %s does not match the account of role_arn %s", name, p.AccountID, p.RoleArn)
+		}
+	}
+	return nil
+}
+
+// ValidateThirdPartyConnection rejects the settings of a connection that need a role in a
+// third-party peer's account: a third-party source, peer-side routes, options, or resources, and
+// acceptance by the accept stack. The peer's CIDR is required, as its VPC cannot be looked up.
+func ValidateThirdPartyConnection(entry MatrixEntry, sourcePeer, peerPeer YAMLPeer) error {
+	if sourcePeer.ThirdParty() {
+		return fmt.Errorf("the source has account_id but no role_arn; the peering can only be requested from a VPC the tool has a role for")
+	}
+	if !peerPeer.ThirdParty() {
+		return nil
+	}
+	if peerPeer.Cidr == "" {
+		return fmt.Errorf("the peer has no role_arn, so its VPC cannot be looked up; set its cidr")
+	}
+	_, peerExtra, _ := SplitExtraRoutes(entry.ExtraRoutes)
+	switch {
+	case peerPeer.DNSResolution:
+		return fmt.Errorf("dns_resolution needs the peering options of the peer's account; its owner enables them after accepting")
+	case entry.PeerRoutes != nil || len(peerExtra) > 0:
+		return fmt.Errorf("the peer has no role_arn, so peer_routes and peer-side extra_routes cannot be managed; its owner routes back")
+	case entry.Acceptance == AcceptanceManual:
+		return fmt.Errorf("acceptance: manual accepts with a role in the peer's account; the peer's owner accepts a third-party peering")
+	case entry.ManageOptions:
+		return fmt.Errorf("manage_options needs a role in the peer's account")
+	case entry.DNSProfileArn != "":
+		return fmt.Errorf("dns_profile_arn associates the profile with the peer VPC, which needs a role in its account")
+	case entry.Inspection != nil && entry.Inspection.Peer != nil:
+		return fmt.Errorf("via_inspection.peer routes the peer VPC, which needs a role in its account")
+	}
+	return nil
+}
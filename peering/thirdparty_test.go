@@ -0,0 +1,93 @@
+package peering
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestValidateThirdPartyPeers tests the format of account_id and its agreement with role_arn.
+func TestValidateThirdPartyPeers(t *testing.T) {
+	tests := []struct {
+		peer    YAMLPeer
+		wantErr bool
+	}{
+		{YAMLPeer{VpcID: "vpc-1", AccountID: "333333333333"}, false},
+		{YAMLPeer{VpcID: "vpc-1", AccountID: "3333"}, true},
+		{YAMLPeer{VpcID: "vpc-1", AccountID: "333333333333", RoleArn: "arn:aws:iam::333333333333:role/peering"}, false},
+		{YAMLPeer{VpcID: "vpc-1", AccountID: "333333333333", RoleArn: "arn:aws:iam::444444444444:role/peering"}, true},
+	}
+	for _, tt := range tests {
+		err := ValidateThirdPartyPeers(YAMLConfig{Peers: map[string]YAMLPeer{"partner": tt.peer}})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%+v: expected error %v, got %v", tt.peer, tt.wantErr, err)
+		}
+	}
+}
+
+// TestResolveThirdPartyConnection tests that a connection to a third-party VPC routes the source
+// side only, and which settings needing the peer's account it rejects.
+func TestResolveThirdPartyConnection(t *testing.T) {
+	cfg := YAMLConfig{Peers: map[string]YAMLPeer{
+		"dev":     {VpcID: "vpc-1", RoleArn: "arn:aws:iam::111111111111:role/peering"},
+		"partner": {VpcID: "vpc-2", AccountID: "333333333333", Cidr: "10.9.0.0/16", Routes: &RoutingConfig{Strategy: RoutingAll}},
+		"nocidr":  {VpcID: "vpc-3", AccountID: "333333333333"},
+	}}
+	peer, err := ResolveConnection(cfg, "dev", MatrixEntry{Peer: "partner"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !peer.ThirdParty || peer.PeerAccountID != "333333333333" || peer.PeerRouting.Strategy != RoutingNone ||
+		peer.SourceRouting.Strategy != RoutingMain || IsAutoAccept(peer) {
+		t.Errorf("unexpected third-party connection: %+v", peer)
+	}
+
+	for _, tt := range []struct {
+		source string
+		entry  MatrixEntry
+		want   string
+	}{
+		{"dev", MatrixEntry{Peer: "nocidr"}, "set its cidr"},
+		{"partner", MatrixEntry{Peer: "dev"}, "the source has account_id"},
+		{"dev", MatrixEntry{Peer: "partner", PeerRoutes: &RoutingConfig{Strategy: RoutingMain}}, "peer_routes"},
+		{"dev", MatrixEntry{Peer: "partner", ExtraRoutes: []ExtraRoute{{Cidr: "10.0.0.0/8", Side: ExtraRoutePeer}}}, "extra_routes"},
+		{"dev", MatrixEntry{Peer: "partner", Acceptance: AcceptanceManual}, "acceptance: manual"},
+	} {
+		if _, err := ResolveConnection(cfg, tt.source, tt.entry); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s -> %+v: expected an error with %q, got %v", tt.source, tt.entry, tt.want, err)
+		}
+	}
+}
+
+// TestThirdPartyAddressesAndPolicies tests that only the peering request and the source routes are
+// managed, and only by the source role.
+func TestThirdPartyAddressesAndPolicies(t *testing.T) {
+	peer := PeerConfig{
+		SourceName: "dev", Name: "partner",
+		SourceVpcID: "vpc-1", SourceRegion: "us-east-1", SourceRoleArn: "arn:aws:iam::111111111111:role/peering",
+		PeerVpcID: "vpc-2", PeerRegion: "us-east-1", PeerAccountID: "333333333333", ThirdParty: true,
+		SourceRouting: RoutingConfig{Strategy: RoutingMain},
+		PeerRouting:   RoutingConfig{Strategy: RoutingNone},
+	}
+	addresses := ConnectionAddresses(LegacyNamer{}, ConnectionNameContext(0, peer), peer)
+	want := map[string]string{
+		KindPeering:         "aws_vpc_peering_connection.VpcPeering0",
+		KindSourceMainRoute: "aws_route.SourceToPeerMainRoute0",
+	}
+	if len(addresses) != len(want) {
+		t.Errorf("expected %v, got %v", want, addresses)
+	}
+	for kind, address := range want {
+		if addresses[kind] != address {
+			t.Errorf("expected %s at %s, got %q", kind, address, addresses[kind])
+		}
+	}
+
+	policies := RolePolicies([]PeerConfig{peer}, PolicyOptions{})
+	if len(policies) != 1 {
+		t.Fatalf("expected the source role only, got %d policies", len(policies))
+	}
+	source := policies[peer.SourceRoleArn]
+	if findStatement(source, "RequestPeeringToConfiguredVpcs") == nil || findStatement(source, "AcceptPeeringsIntoOwnVpcs") != nil {
+		t.Errorf("expected a request without acceptance, got %+v", source)
+	}
+}
@@ -0,0 +1,571 @@
+package peering
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Matrix Browser
+// -------------------------------------------------------------------------------------------------
+
+// MatrixRow is one connection of the peering matrix with its resolved config or validation error.
+type MatrixRow struct {
+	Source   string     // Source peer name.
+	Peer     string     // Target peer name.
+	Config   PeerConfig // Resolved connection (zero if invalid).
+	Err      error      // Validation error, nil if the entry is valid.
+	Disabled bool       // The entry sets enabled: false and is not synthesized.
+}
+
+// BuildMatrixRows resolves every matrix entry without stopping at the first invalid one. Rows are
+// sorted by source and keep matrix order within a source.
+func BuildMatrixRows(cfg YAMLConfig) []MatrixRow {
+	sources := make([]string, 0, len(cfg.PeeringMatrix))
+	for source := range cfg.PeeringMatrix {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	var rows []MatrixRow
+	for _, source := range sources {
+		for _, entry := range ExpandMatrixEntries(cfg, source, cfg.PeeringMatrix[source]) {
+			peer, err := ResolveConnection(cfg, source, entry)
+			rows = append(rows, MatrixRow{Source: source, Peer: entry.Peer, Config: peer, Err: err, Disabled: entry.Disabled()})
+		}
+	}
+	return rows
+}
+
+// Matches reports whether the row contains the filter text in its names, VPCs, regions, or status.
+func (r MatrixRow) Matches(filter string) bool {
+	if filter == "" {
+		return true
+	}
+	fields := []string{r.Source, r.Peer, r.Config.SourceVpcID, r.Config.PeerVpcID, r.Config.SourceRegion, r.Config.PeerRegion, r.status()}
+	for _, field := range fields {
+		if strings.Contains(strings.ToLower(field), strings.ToLower(filter)) {
+			return true
+		}
+	}
+	return false
+}
+
+// status summarizes the validation result and lifecycle state of a row.
+func (r MatrixRow) status() string {
+	switch {
+	case r.Err != nil:
+		return "invalid"
+	case r.Disabled:
+		return "disabled"
+	case r.Config.Decommission != "":
+		return "decommissioning"
+	default:
+		return "ok"
+	}
+}
+
+// Browser is a line-oriented interactive view of the peering matrix.
+type Browser struct {
+	Config YAMLConfig                // Loaded configuration.
+	Rows   []MatrixRow               // All connections.
+	Filter string                    // Current filter text, empty for none.
+	Synth  func(source string) error // Synthesizes the stack for one source.
+	out    io.Writer
+}
+
+// browserHelp lists the browser commands.
+const browserHelp = `Commands:
+  sources                 list sources with connection and invalid counts
+  peers <source>          list the connections of a source with their flags
+  invalid                 list every invalid connection with its error
+  describe <source> <peer>
+                          show everything resolved for one connection
+  filter [text]           only show rows containing text (no text clears the filter)
+  synth <source>          synthesize the stack for a source
+  help                    show this help
+  quit                    leave
+`
+
+// Run reads commands from in until it ends or the user quits.
+func (b *Browser) Run(in io.Reader, out io.Writer) error {
+	b.out = out
+	scanner := bufio.NewScanner(in)
+	b.printSources()
+	for {
+		fmt.Fprint(out, "\n> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return scanner.Err()
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		cmd, args := fields[0], fields[1:]
+		switch {
+		case cmd == "quit" || cmd == "q" || cmd == "exit":
+			return nil
+		case cmd == "sources" || cmd == "ls":
+			b.printSources()
+		case cmd == "peers" && len(args) == 1:
+			b.printPeers(args[0])
+		case cmd == "invalid":
+			b.printInvalid()
+		case cmd == "describe" && len(args) == 2:
+			b.describe(out, args[0], args[1])
+		case cmd == "filter":
+			b.Filter = strings.Join(args, " ")
+			b.printSources()
+		case cmd == "synth" && len(args) == 1:
+			if err := b.Synth(args[0]); err != nil {
+				fmt.Fprintf(out, "synth failed: %v\n", err)
+			}
+		default:
+			fmt.Fprint(out, browserHelp)
+		}
+	}
+}
+
+// visible returns the rows matching the current filter.
+func (b *Browser) visible() []MatrixRow {
+	var rows []MatrixRow
+	for _, row := range b.Rows {
+		if row.Matches(b.Filter) {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// printSources lists each source with its number of visible and invalid connections.
+func (b *Browser) printSources() {
+	var order []string
+	counts := make(map[string][2]int)
+	for _, row := range b.visible() {
+		c, seen := counts[row.Source]
+		if !seen {
+			order = append(order, row.Source)
+		}
+		c[0]++
+		if row.Err != nil {
+			c[1]++
+		}
+		counts[row.Source] = c
+	}
+
+	if b.Filter != "" {
+		fmt.Fprintf(b.out, "Filter: %q\n", b.Filter)
+	}
+	tw := tabwriter.NewWriter(b.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SOURCE\tCONNECTIONS\tINVALID")
+	for _, source := range order {
+		fmt.Fprintf(tw, "%s\t%d\t%d\n", source, counts[source][0], counts[source][1])
+	}
+	tw.Flush()
+}
+
+// printPeers lists the visible connections of a source with their flags.
+func (b *Browser) printPeers(source string) {
+	tw := tabwriter.NewWriter(b.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PEER\tVPC\tREGION\tCROSS-REGION\tDNS\tSOURCE ROUTES\tPEER ROUTES\tSTATUS")
+	for _, row := range b.visible() {
+		if row.Source != source {
+			continue
+		}
+		if row.Err != nil {
+			fmt.Fprintf(tw, "%s\t-\t-\t-\t-\t-\t-\t%s\n", row.Peer, row.status())
+			continue
+		}
+		p := row.Config
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%t\t%t\t%s\t%s\t%s\n",
+			row.Peer, p.PeerVpcID, ResolveRegion(p.PeerRegion), !IsAutoAccept(p), p.EnableDNSResolution,
+			p.SourceRouting.Strategy, p.PeerRouting.Strategy, row.status())
+	}
+	tw.Flush()
+}
+
+// printInvalid lists every visible invalid connection with its error.
+func (b *Browser) printInvalid() {
+	n := 0
+	for _, row := range b.visible() {
+		if row.Err != nil {
+			fmt.Fprintf(b.out, "%s -> %s: %v\n", row.Source, row.Peer, row.Err)
+			n++
+		}
+	}
+	if n == 0 {
+		fmt.Fprintln(b.out, "No invalid connections.")
+	}
+}
+
+// describe writes the full report of one connection, numbered as synth numbers it for the source.
+func (b *Browser) describe(w io.Writer, source, target string) {
+	var peers []PeerConfig
+	for _, row := range b.Rows {
+		if row.Source == source && row.Err == nil {
+			peers = append(peers, row.Config)
+		}
+	}
+	var err error
+	quietLogs(func() { peers, err = MergeDuplicatePairs(peers) })
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return
+	}
+
+	for i, peer := range peers {
+		if peer.Name == target {
+			DescribeConnection(w, NewNamer(b.Config.Naming), "", ConnectionNameContext(i, peer), peer)
+			return
+		}
+	}
+	fmt.Fprintf(w, "no valid connection from %q to %q\n", source, target)
+}
+
+// -------------------------------------------------------------------------------------------------
+// Interactive View
+// -------------------------------------------------------------------------------------------------
+
+// Keys the interactive view handles besides printable characters, as decodeKeys names them.
+const (
+	keyUp        = "up"
+	keyDown      = "down"
+	keyPageUp    = "pgup"
+	keyPageDown  = "pgdown"
+	keyHome      = "home"
+	keyEnd       = "end"
+	keyEnter     = "enter"
+	keyEscape    = "esc"
+	keyBackspace = "backspace"
+	keyInterrupt = "ctrl-c"
+)
+
+// Escape sequences switching to the terminal's alternate screen with the cursor hidden, and back.
+const (
+	enterScreen = "\x1b[?1049h\x1b[?25l"
+	leaveScreen = "\x1b[?25h\x1b[?1049l"
+)
+
+// MatrixView is the full-screen view of the peering matrix: a scrollable list of connections that
+// narrows as a filter is typed, and the report of the selected connection. It keeps the state and
+// renders frames; Run feeds it the keys read from the terminal.
+type MatrixView struct {
+	Browser     *Browser // Rows, filter, report, and synth of the matrix.
+	InvalidOnly bool     // Only list invalid connections.
+	cursor      int      // Selected row among the listed ones.
+	offset      int      // First listed row on screen.
+	filtering   bool     // Keys edit the filter.
+	report      []string // Lines of the report shown instead of the list (nil for the list).
+	reportTop   int      // First report line on screen.
+	pageSize    int      // Rows or report lines per screen, as last rendered.
+}
+
+// rows returns the listed rows: those matching the filter, and only invalid ones with InvalidOnly.
+func (v *MatrixView) rows() []MatrixRow {
+	var rows []MatrixRow
+	for _, row := range v.Browser.visible() {
+		if !v.InvalidOnly || row.Err != nil {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// Selected returns the selected row, or false when no row is listed.
+func (v *MatrixView) Selected() (MatrixRow, bool) {
+	rows := v.rows()
+	if len(rows) == 0 {
+		return MatrixRow{}, false
+	}
+	return rows[clampInt(v.cursor, 0, len(rows)-1)], true
+}
+
+// HandleKey applies one key. It returns the source to synthesize when the key asks for a synth, and
+// whether the user quit.
+func (v *MatrixView) HandleKey(key string) (synth string, quit bool) {
+	if key == keyInterrupt {
+		return "", true
+	}
+	page := clampInt(v.pageSize, 1, v.pageSize)
+
+	if v.report != nil {
+		switch key {
+		case keyUp, "k":
+			v.reportTop--
+		case keyDown, "j":
+			v.reportTop++
+		case keyPageUp:
+			v.reportTop -= page
+		case keyPageDown, " ":
+			v.reportTop += page
+		case keyHome, "g":
+			v.reportTop = 0
+		case keyEnd, "G":
+			v.reportTop = len(v.report)
+		case keyEscape, keyEnter, "q":
+			v.report = nil
+			return "", false
+		}
+		v.reportTop = clampInt(v.reportTop, 0, len(v.report)-page)
+		return "", false
+	}
+
+	if v.filtering {
+		switch key {
+		case keyEnter:
+			v.filtering = false
+		case keyEscape:
+			v.filtering = false
+			v.Browser.Filter = ""
+		case keyBackspace:
+			if r := []rune(v.Browser.Filter); len(r) > 0 {
+				v.Browser.Filter = string(r[:len(r)-1])
+			}
+		default:
+			if utf8.RuneCountInString(key) == 1 {
+				v.Browser.Filter += key
+			}
+		}
+		v.cursor, v.offset = 0, 0
+		return "", false
+	}
+
+	n := len(v.rows())
+	switch key {
+	case "q":
+		return "", true
+	case keyEscape:
+		v.Browser.Filter = ""
+	case keyUp, "k":
+		v.cursor--
+	case keyDown, "j":
+		v.cursor++
+	case keyPageUp:
+		v.cursor -= page
+	case keyPageDown, " ":
+		v.cursor += page
+	case keyHome, "g":
+		v.cursor = 0
+	case keyEnd, "G":
+		v.cursor = n - 1
+	case "/":
+		v.filtering = true
+	case "i":
+		v.InvalidOnly = !v.InvalidOnly
+		v.cursor, v.offset = 0, 0
+	case keyEnter:
+		if row, ok := v.Selected(); ok {
+			var report strings.Builder
+			if row.Err != nil {
+				fmt.Fprintf(&report, "Invalid: %v\n", row.Err)
+			} else {
+				v.Browser.describe(&report, row.Source, row.Peer)
+			}
+			v.report = strings.Split(strings.TrimRight(report.String(), "\n"), "\n")
+			v.reportTop = 0
+		}
+	case "s":
+		if row, ok := v.Selected(); ok {
+			return row.Source, false
+		}
+	}
+	v.cursor = clampInt(v.cursor, 0, n-1)
+	return "", false
+}
+
+// Render draws a frame of width by height cells for a terminal in raw mode: it clears the screen,
+// ends lines with CRLF, and shows the selected row in reverse video. The list scrolls to keep the
+// selected row on screen.
+func (v *MatrixView) Render(width, height int) string {
+	body := clampInt(height-3, 1, height)
+	v.pageSize = body
+	var lines []string
+
+	if v.report != nil {
+		row, _ := v.Selected()
+		lines = append(lines, fitLine(fmt.Sprintf("%s -> %s", row.Source, row.Peer), width), "")
+		for i := v.reportTop; i < minInt(v.reportTop+body, len(v.report)); i++ {
+			lines = append(lines, fitLine(v.report[i], width))
+		}
+		for len(lines) < height-1 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, fitLine("up/down scroll  esc back", width))
+		return "\x1b[H\x1b[2J" + strings.Join(lines, "\r\n")
+	}
+
+	rows := v.rows()
+	invalid := 0
+	for _, row := range v.Browser.Rows {
+		if row.Err != nil {
+			invalid++
+		}
+	}
+	title := fmt.Sprintf("Peering matrix: %d connections, %d invalid, %d listed", len(v.Browser.Rows), invalid, len(rows))
+	if v.InvalidOnly {
+		title += " (invalid only)"
+	}
+	if v.Browser.Filter != "" && !v.filtering {
+		title += fmt.Sprintf(", filter %q", v.Browser.Filter)
+	}
+	table := matrixTable(rows)
+	lines = append(lines, fitLine(title, width), fitLine(table[0], width))
+
+	if v.cursor < v.offset {
+		v.offset = v.cursor
+	}
+	if v.cursor >= v.offset+body {
+		v.offset = v.cursor - body + 1
+	}
+	for i := v.offset; i < minInt(v.offset+body, len(rows)); i++ {
+		line := fitLine(table[i+1], width)
+		if i == v.cursor {
+			line = "\x1b[7m" + line + strings.Repeat(" ", clampInt(width-utf8.RuneCountInString(line), 0, width)) + "\x1b[0m"
+		}
+		lines = append(lines, line)
+	}
+	for len(lines) < height-1 {
+		lines = append(lines, "")
+	}
+	footer := "/ filter  i invalid only  enter details  s synth source  q quit"
+	if v.filtering {
+		footer = "Filter: " + v.Browser.Filter + "_"
+	}
+	lines = append(lines, fitLine(footer, width))
+	return "\x1b[H\x1b[2J" + strings.Join(lines, "\r\n")
+}
+
+// matrixTable formats rows as aligned columns; the first line is the header.
+func matrixTable(rows []MatrixRow) []string {
+	var buf strings.Builder
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SOURCE\tPEER\tPEER VPC\tREGION\tDNS\tROUTES\tSTATUS")
+	for _, row := range rows {
+		if row.Err != nil {
+			fmt.Fprintf(tw, "%s\t%s\t-\t-\t-\t-\t%s\n", row.Source, row.Peer, row.status())
+			continue
+		}
+		p := row.Config
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%t\t%s/%s\t%s\n", row.Source, row.Peer, p.PeerVpcID, ResolveRegion(p.PeerRegion),
+			p.EnableDNSResolution, p.SourceRouting.Strategy, p.PeerRouting.Strategy, row.status())
+	}
+	tw.Flush()
+	return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+}
+
+// fitLine cuts a line to at most width characters.
+func fitLine(s string, width int) string {
+	if r := []rune(s); len(r) > width {
+		return string(r[:clampInt(width, 0, len(r))])
+	}
+	return s
+}
+
+// clampInt limits v to [lo, hi], preferring lo when the range is empty.
+func clampInt(v, lo, hi int) int {
+	if v > hi {
+		v = hi
+	}
+	if v < lo {
+		v = lo
+	}
+	return v
+}
+
+// Run shows the view full screen until the user quits, reading keys from in and drawing on out. A
+// synth leaves the full screen while cdktf runs, and the view returns after Enter.
+func (v *MatrixView) Run(in, out *os.File) error {
+	restore, err := rawTerminal(in)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(out, enterScreen)
+	defer func() {
+		fmt.Fprint(out, leaveScreen)
+		if restore != nil {
+			restore()
+		}
+	}()
+
+	buf := make([]byte, 64)
+	for {
+		height, width := terminalSize(in)
+		fmt.Fprint(out, v.Render(width, height))
+		n, err := in.Read(buf)
+		if err != nil {
+			return err
+		}
+		for _, key := range decodeKeys(buf[:n]) {
+			source, quit := v.HandleKey(key)
+			if quit {
+				return nil
+			}
+			if source == "" {
+				continue
+			}
+			fmt.Fprint(out, leaveScreen)
+			if err := restore(); err != nil {
+				return err
+			}
+			if err := v.Browser.Synth(source); err != nil {
+				fmt.Fprintf(out, "synth failed: %v\n", err)
+			}
+			fmt.Fprint(out, "Press Enter to return to the matrix.")
+			bufio.NewReader(in).ReadString('\n')
+			if restore, err = rawTerminal(in); err != nil {
+				return err
+			}
+			fmt.Fprint(out, enterScreen)
+		}
+	}
+}
+
+// -------------------------------------------------------------------------------------------------
+// tui
+// -------------------------------------------------------------------------------------------------
+
+// runTUI opens the full-screen matrix view on the terminal, or the line-oriented browser with -plain
+// or when stdin or stdout is not a terminal. Platforms without stty require -plain.
+func runTUI(args []string) error {
+	fs := flag.NewFlagSet("tui", flag.ContinueOnError)
+	plain := fs.Bool("plain", false, "use the line-oriented browser instead of the full-screen view")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+	interactive := isTerminal(os.Stdin) && isTerminal(os.Stdout)
+	if !*plain && interactive && !fullScreen {
+		return errNoFullScreen
+	}
+	cfg := LoadConfig(ConfigPath())
+
+	var rows []MatrixRow
+	quietLogs(func() { rows = BuildMatrixRows(cfg) })
+
+	b := &Browser{Config: cfg, Rows: rows, Synth: synthSource}
+	if *plain || !interactive {
+		fmt.Fprint(os.Stdout, browserHelp+"\n")
+		return b.Run(os.Stdin, os.Stdout)
+	}
+	return (&MatrixView{Browser: b}).Run(os.Stdin, os.Stdout)
+}
+
+// synthSource runs "cdktf synth" for a single source.
+func synthSource(source string) error {
+	cmd := exec.Command("cdktf", "synth")
+	cmd.Env = append(os.Environ(), "CDKTF_SOURCE="+source)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,121 @@
+package peering
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestBrowser tests listing, filtering, and validation status in the matrix browser.
+func TestBrowser(t *testing.T) {
+	cfg := YAMLConfig{
+		Peers: map[string]YAMLPeer{
+			"dev":  {VpcID: "vpc-1", Region: "us-east-1"},
+			"prod": {VpcID: "vpc-2", Region: "us-west-2"},
+			"qa":   {VpcID: "vpc-3", Region: "us-east-1"},
+		},
+		PeeringMatrix: map[string][]MatrixEntry{
+			"dev": {{Peer: "prod"}, {Peer: "qa", DestinationCidrs: []string{"not-a-cidr"}}},
+			"qa":  {{Peer: "missing"}},
+		},
+	}
+	rows := BuildMatrixRows(cfg)
+	if len(rows) != 3 || rows[0].Err != nil || rows[1].Err == nil || rows[2].Err == nil {
+		t.Fatalf("unexpected rows: %+v", rows)
+	}
+
+	var synthed string
+	b := &Browser{Config: cfg, Rows: rows, Synth: func(source string) error { synthed = source; return nil }}
+	var out bytes.Buffer
+	input := "peers dev\ninvalid\nfilter prod\nsynth dev\nquit\n"
+	if err := b.Run(strings.NewReader(input), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	text := out.String()
+	for _, want := range []string{"vpc-2", "invalid destination CIDR", "missing peer config", `Filter: "prod"`} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output missing %q:\n%s", want, text)
+		}
+	}
+	if b.Filter != "prod" || len(b.visible()) != 1 {
+		t.Errorf("expected one row matching the filter, got %d", len(b.visible()))
+	}
+	if synthed != "dev" {
+		t.Errorf("expected synth for dev, got %q", synthed)
+	}
+}
+
+// TestMatrixView tests moving through, filtering, and opening connections in the full-screen view.
+func TestMatrixView(t *testing.T) {
+	cfg := YAMLConfig{
+		Peers: map[string]YAMLPeer{
+			"dev":  {VpcID: "vpc-1", Region: "us-east-1"},
+			"prod": {VpcID: "vpc-2", Region: "us-west-2"},
+			"qa":   {VpcID: "vpc-3", Region: "us-east-1"},
+		},
+		PeeringMatrix: map[string][]MatrixEntry{
+			"dev": {{Peer: "prod"}, {Peer: "qa", DestinationCidrs: []string{"not-a-cidr"}}},
+			"qa":  {{Peer: "missing"}},
+		},
+	}
+	v := &MatrixView{Browser: &Browser{Config: cfg, Rows: BuildMatrixRows(cfg)}}
+	press := func(keys ...string) (synth string, quit bool) {
+		for _, key := range keys {
+			if synth, quit = v.HandleKey(key); synth != "" || quit {
+				return synth, quit
+			}
+		}
+		return "", false
+	}
+
+	frame := v.Render(100, 10)
+	if !strings.Contains(frame, "3 connections, 2 invalid, 3 listed") || !strings.Contains(frame, "\x1b[7mdev     prod") {
+		t.Errorf("unexpected first frame:\n%q", frame)
+	}
+	if lines := strings.Split(frame, "\r\n"); len(lines) != 10 {
+		t.Errorf("expected 10 lines, got %d", len(lines))
+	}
+
+	press(keyDown, keyDown, keyDown)
+	if row, _ := v.Selected(); row.Source != "qa" {
+		t.Errorf("expected the cursor to stop on the last row, got %s -> %s", row.Source, row.Peer)
+	}
+	press(keyEnter)
+	if frame := v.Render(100, 10); !strings.Contains(frame, "qa -> missing") || !strings.Contains(frame, "missing peer config") {
+		t.Errorf("expected the error of the invalid row, got:\n%q", frame)
+	}
+	press(keyEscape, "i")
+	if n := len(v.rows()); n != 2 {
+		t.Errorf("expected 2 invalid rows, got %d", n)
+	}
+
+	press("i", "/", "p", "r", "x", keyBackspace, "o")
+	if frame := v.Render(100, 10); !strings.Contains(frame, "Filter: pro_") || len(v.rows()) != 1 {
+		t.Errorf("expected the filter to narrow the list while typed, got %d rows:\n%q", len(v.rows()), frame)
+	}
+	press(keyEnter, keyEnter)
+	if frame := v.Render(100, 40); !strings.Contains(frame, "dev -> prod") || !strings.Contains(frame, "vpc-2") {
+		t.Errorf("expected the report of dev -> prod, got:\n%q", frame)
+	}
+	if synth, _ := press(keyEscape, "s"); synth != "dev" {
+		t.Errorf("expected a synth of dev, got %q", synth)
+	}
+	press(keyEscape)
+	if v.Browser.Filter != "" {
+		t.Errorf("expected Escape to clear the filter, got %q", v.Browser.Filter)
+	}
+	if _, quit := press("q"); !quit {
+		t.Error("expected q to quit")
+	}
+}
+
+// TestDecodeKeys tests that raw terminal input splits into keys.
+func TestDecodeKeys(t *testing.T) {
+	got := decodeKeys([]byte("ab\x1b[A\x1b[6~\r\x7f\x1b\x03\x1b[1;5Cé"))
+	want := []string{"a", "b", keyUp, keyPageDown, keyEnter, keyBackspace, keyEscape, keyInterrupt, "é"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeKeys = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,266 @@
+package peering
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Reachability Analysis
+// -------------------------------------------------------------------------------------------------
+
+// AnalysisResult is the outcome of one Network Insights analysis.
+type AnalysisResult struct {
+	PathFound   bool     // Whether a path exists between source and destination.
+	Explanation []string // Blocking components, e.g. "NO_ROUTE_TO_DESTINATION rtb-0abc".
+}
+
+// ReachabilityClient finds endpoints and runs Reachability Analyzer analyses in one account and
+// region. Implemented by the AWS CLI and by fakes in tests.
+type ReachabilityClient interface {
+	// FindENI returns an in-use network interface of the VPC, the one holding ip when it is set.
+	FindENI(vpcID, ip string) (string, error)
+	// Analyze runs an analysis from source to destination and waits for its result.
+	Analyze(source, destination, protocol string, port int, timeout time.Duration, keep bool) (AnalysisResult, error)
+}
+
+// VerifyOptions controls the analyses run by Verify.
+type VerifyOptions struct {
+	Protocol string        // tcp or udp.
+	Port     int           // Destination port, 0 for any.
+	Timeout  time.Duration // Maximum time to wait for each analysis.
+	Keep     bool          // Keep the created paths and analyses for inspection in the console.
+}
+
+// VerifyResult is the verdict for one direction of one connection.
+type VerifyResult struct {
+	Connection string // Connection key.
+	Direction  string // "source->peer" or "peer->source".
+	Status     string // PASS, FAIL, SKIP, or ERROR.
+	Detail     string // Blocking components or the reason for SKIP/ERROR.
+}
+
+// Verify runs Reachability Analyzer in both directions of every connection. Analyses run with the
+// credentials of the side they start from. Cross-region connections are skipped, since analyses
+// cannot span regions, as are directions without routing.
+func Verify(peers []PeerConfig, connect func(region, roleArn string) (ReachabilityClient, error), opts VerifyOptions) []VerifyResult {
+	var results []VerifyResult
+	for _, peer := range peers {
+		key := ConnectionKey(peer)
+		type direction struct {
+			name             string
+			routing          RoutingConfig
+			fromVpc, toVpc   string
+			fromIP, toIP     string
+			fromRole, toRole string
+		}
+		directions := []direction{
+			{"source->peer", peer.SourceRouting, peer.SourceVpcID, peer.PeerVpcID, peer.CheckIPs.Source, peer.CheckIPs.Peer, peer.SourceRoleArn, peer.PeerRoleArn},
+			{"peer->source", peer.PeerRouting, peer.PeerVpcID, peer.SourceVpcID, peer.CheckIPs.Peer, peer.CheckIPs.Source, peer.PeerRoleArn, peer.SourceRoleArn},
+		}
+		for _, d := range directions {
+			result := VerifyResult{Connection: key, Direction: d.name}
+			switch {
+			case peer.ThirdParty:
+				result.Status, result.Detail = "SKIP", "the peer VPC belongs to a third party"
+			case !IsAutoAccept(peer):
+				result.Status, result.Detail = "SKIP", "cross-region paths are not supported by Reachability Analyzer"
+			case d.routing.Strategy == RoutingNone:
+				result.Status, result.Detail = "SKIP", "no routes managed on this side"
+			default:
+				result.Status, result.Detail = verifyDirection(connect, ResolveRegion(peer.SourceRegion), d.fromRole, d.toRole, d.fromVpc, d.toVpc, d.fromIP, d.toIP, opts)
+			}
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// verifyDirection finds both endpoints and runs one analysis.
+func verifyDirection(connect func(region, roleArn string) (ReachabilityClient, error), region, fromRole, toRole, fromVpc, toVpc, fromIP, toIP string, opts VerifyOptions) (string, string) {
+	from, err := connect(region, fromRole)
+	if err != nil {
+		return "ERROR", err.Error()
+	}
+	to, err := connect(region, toRole)
+	if err != nil {
+		return "ERROR", err.Error()
+	}
+	source, err := from.FindENI(fromVpc, fromIP)
+	if err != nil {
+		return "ERROR", err.Error()
+	}
+	destination, err := to.FindENI(toVpc, toIP)
+	if err != nil {
+		return "ERROR", err.Error()
+	}
+
+	result, err := from.Analyze(source, destination, opts.Protocol, opts.Port, opts.Timeout, opts.Keep)
+	if err != nil {
+		return "ERROR", err.Error()
+	}
+	if result.PathFound {
+		return "PASS", fmt.Sprintf("%s -> %s", source, destination)
+	}
+	return "FAIL", fmt.Sprintf("%s -> %s: %s", source, destination, strings.Join(result.Explanation, "; "))
+}
+
+// PrintVerifyResults writes the verdicts as a table.
+func PrintVerifyResults(w io.Writer, results []VerifyResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CONNECTION\tDIRECTION\tRESULT\tDETAIL")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Connection, r.Direction, r.Status, r.Detail)
+	}
+	tw.Flush()
+}
+
+// -------------------------------------------------------------------------------------------------
+// AWS CLI Implementation
+// -------------------------------------------------------------------------------------------------
+
+// FindENI returns an in-use network interface of the VPC, preferring the one holding ip.
+func (c *AWSCLI) FindENI(vpcID, ip string) (string, error) {
+	args := []string{"ec2", "describe-network-interfaces", "--filters", "Name=vpc-id,Values=" + vpcID, "Name=status,Values=in-use"}
+	if ip != "" {
+		args = append(args, "Name=addresses.private-ip-address,Values="+ip)
+	}
+	var out struct {
+		NetworkInterfaces []struct {
+			NetworkInterfaceID string `json:"NetworkInterfaceId"`
+		} `json:"NetworkInterfaces"`
+	}
+	if err := c.Run(&out, args...); err != nil {
+		return "", err
+	}
+	if len(out.NetworkInterfaces) == 0 {
+		if ip != "" {
+			return "", fmt.Errorf("no in-use network interface with address %s in %s", ip, vpcID)
+		}
+		return "", fmt.Errorf("no in-use network interface in %s; set check_ips to choose endpoints", vpcID)
+	}
+	ids := make([]string, 0, len(out.NetworkInterfaces))
+	for _, eni := range out.NetworkInterfaces {
+		ids = append(ids, eni.NetworkInterfaceID)
+	}
+	sort.Strings(ids)
+	return ids[0], nil
+}
+
+// Analyze creates a Network Insights path, starts an analysis, and polls until it completes.
+func (c *AWSCLI) Analyze(source, destination, protocol string, port int, timeout time.Duration, keep bool) (AnalysisResult, error) {
+	args := []string{"ec2", "create-network-insights-path", "--source", source, "--destination", destination, "--protocol", protocol,
+		"--tag-specifications", "ResourceType=network-insights-path,Tags=[{Key=ManagedBy,Value=vpc-peering-tool}]"}
+	if port > 0 {
+		args = append(args, "--destination-port", fmt.Sprint(port))
+	}
+	var path struct {
+		NetworkInsightsPath struct {
+			ID string `json:"NetworkInsightsPathId"`
+		} `json:"NetworkInsightsPath"`
+	}
+	if err := c.Run(&path, args...); err != nil {
+		return AnalysisResult{}, err
+	}
+	pathID := path.NetworkInsightsPath.ID
+	if !keep {
+		defer c.Run(&struct{}{}, "ec2", "delete-network-insights-path", "--network-insights-path-id", pathID)
+	}
+
+	var started struct {
+		NetworkInsightsAnalysis struct {
+			ID string `json:"NetworkInsightsAnalysisId"`
+		} `json:"NetworkInsightsAnalysis"`
+	}
+	if err := c.Run(&started, "ec2", "start-network-insights-analysis", "--network-insights-path-id", pathID); err != nil {
+		return AnalysisResult{}, err
+	}
+	analysisID := started.NetworkInsightsAnalysis.ID
+	if !keep {
+		defer c.Run(&struct{}{}, "ec2", "delete-network-insights-analysis", "--network-insights-analysis-id", analysisID)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		var out struct {
+			NetworkInsightsAnalyses []struct {
+				Status           string `json:"Status"`
+				StatusMessage    string `json:"StatusMessage"`
+				NetworkPathFound bool   `json:"NetworkPathFound"`
+				Explanations     []struct {
+					ExplanationCode string `json:"ExplanationCode"`
+					Component       struct {
+						ID string `json:"Id"`
+					} `json:"Component"`
+				} `json:"Explanations"`
+			} `json:"NetworkInsightsAnalyses"`
+		}
+		if err := c.Run(&out, "ec2", "describe-network-insights-analyses", "--network-insights-analysis-ids", analysisID); err != nil {
+			return AnalysisResult{}, err
+		}
+		if len(out.NetworkInsightsAnalyses) == 0 {
+			return AnalysisResult{}, fmt.Errorf("analysis %s disappeared", analysisID)
+		}
+		a := out.NetworkInsightsAnalyses[0]
+		switch a.Status {
+		case "succeeded":
+			result := AnalysisResult{PathFound: a.NetworkPathFound}
+			for _, e := range a.Explanations {
+				result.Explanation = append(result.Explanation, strings.TrimSpace(e.ExplanationCode+" "+e.Component.ID))
+			}
+			return result, nil
+		case "failed":
+			return AnalysisResult{}, fmt.Errorf("analysis %s failed: %s", analysisID, a.StatusMessage)
+		}
+		if time.Now().After(deadline) {
+			return AnalysisResult{}, fmt.Errorf("analysis %s did not finish within %s", analysisID, timeout)
+		}
+		time.Sleep(5 * time.Second)
+	}
+}
+
+// -------------------------------------------------------------------------------------------------
+// verify
+// -------------------------------------------------------------------------------------------------
+
+// runVerify analyzes the reachability of every connection of a source after apply.
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	protocol := fs.String("protocol", "tcp", "protocol to analyze (tcp or udp)")
+	port := fs.Int("port", 0, "destination port to analyze (0 for any)")
+	timeout := fs.Duration("timeout", 5*time.Minute, "maximum time to wait for each analysis")
+	keep := fs.Bool("keep", false, "keep the created paths and analyses")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, peers := loadSourcePeers(sourceArg(fs))
+	clients := make(map[string]ReachabilityClient)
+	connect := func(region, roleArn string) (ReachabilityClient, error) {
+		key := region + "|" + roleArn
+		if c, ok := clients[key]; ok {
+			return c, nil
+		}
+		c, err := NewAWSCLI(region, roleArn, cfg.Provider)
+		if err != nil {
+			return nil, err
+		}
+		clients[key] = c
+		return c, nil
+	}
+
+	results := Verify(peers, connect, VerifyOptions{Protocol: *protocol, Port: *port, Timeout: *timeout, Keep: *keep})
+	PrintVerifyResults(os.Stdout, results)
+	for _, r := range results {
+		if r.Status == "FAIL" || r.Status == "ERROR" {
+			return fmt.Errorf("reachability verification failed")
+		}
+	}
+	return nil
+}
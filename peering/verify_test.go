@@ -0,0 +1,44 @@
+package peering
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeReachability reports a path only for endpoints in the listed VPC pairs.
+type fakeReachability struct {
+	reachable map[string]bool
+}
+
+func (f fakeReachability) FindENI(vpcID, ip string) (string, error) { return "eni-" + vpcID, nil }
+
+func (f fakeReachability) Analyze(source, destination, _ string, _ int, _ time.Duration, _ bool) (AnalysisResult, error) {
+	if f.reachable[source+">"+destination] {
+		return AnalysisResult{PathFound: true}, nil
+	}
+	return AnalysisResult{Explanation: []string{"NO_ROUTE_TO_DESTINATION rtb-1"}}, nil
+}
+
+// TestVerify tests verdicts per direction, including skipped sides and regions.
+func TestVerify(t *testing.T) {
+	client := fakeReachability{reachable: map[string]bool{"eni-vpc-1>eni-vpc-2": true}}
+	peers := []PeerConfig{
+		{SourceName: "dev", Name: "prod", SourceVpcID: "vpc-1", PeerVpcID: "vpc-2",
+			SourceRouting: RoutingConfig{Strategy: RoutingMain}, PeerRouting: RoutingConfig{Strategy: RoutingMain}},
+		{SourceName: "dev", Name: "qa", SourceVpcID: "vpc-1", PeerVpcID: "vpc-3", PeerRegion: "eu-west-1"},
+	}
+	results := Verify(peers, func(string, string) (ReachabilityClient, error) { return client, nil }, VerifyOptions{Protocol: "tcp"})
+
+	want := []string{"PASS", "FAIL", "SKIP", "SKIP"}
+	if len(results) != len(want) {
+		t.Fatalf("expected %d results, got %d", len(want), len(results))
+	}
+	for i, r := range results {
+		if r.Status != want[i] {
+			t.Errorf("result %d (%s %s): expected %s, got %s (%s)", i, r.Connection, r.Direction, want[i], r.Status, r.Detail)
+		}
+	}
+	if results[1].Detail != "eni-vpc-2 -> eni-vpc-1: NO_ROUTE_TO_DESTINATION rtb-1" {
+		t.Errorf("unexpected failure detail: %q", results[1].Detail)
+	}
+}
@@ -0,0 +1,245 @@
+package peering
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// -------------------------------------------------------------------------------------------------
+// Synth Diff
+// -------------------------------------------------------------------------------------------------
+
+// SynthDiff lists the blocks that changed between two synthesized stacks, by address.
+type SynthDiff struct {
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
+// Empty reports whether nothing changed.
+func (d SynthDiff) Empty() bool {
+	return len(d.Added)+len(d.Removed)+len(d.Changed) == 0
+}
+
+// SynthAddresses maps every resource, data source, output, and check block of a synthesized stack
+// (cdk.tf.json) to its canonical JSON body. Data sources are prefixed "data.", outputs "output.",
+// and checks "check.".
+func SynthAddresses(data []byte) (map[string]string, error) {
+	var doc map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse synthesized stack: %w", err)
+	}
+
+	out := make(map[string]string)
+	add := func(address string, body json.RawMessage) error {
+		var v interface{}
+		if err := json.Unmarshal(body, &v); err != nil {
+			return fmt.Errorf("%s: %w", address, err)
+		}
+		canonical, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		out[address] = string(canonical)
+		return nil
+	}
+
+	for _, block := range []struct{ key, prefix string }{{"resource", ""}, {"data", "data."}} {
+		for resourceType, raw := range doc[block.key] {
+			var byName map[string]json.RawMessage
+			if err := json.Unmarshal(raw, &byName); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", block.key, resourceType, err)
+			}
+			for name, body := range byName {
+				if err := add(block.prefix+resourceType+"."+name, body); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	for _, block := range []struct{ key, prefix string }{{"output", "output."}, {"check", "check."}} {
+		for name, body := range doc[block.key] {
+			if err := add(block.prefix+name, body); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return out, nil
+}
+
+// DiffSynth compares two synthesized stacks. A nil before stack counts as empty.
+func DiffSynth(before, after []byte) (SynthDiff, error) {
+	var diff SynthDiff
+	old := map[string]string{}
+	if before != nil {
+		var err error
+		if old, err = SynthAddresses(before); err != nil {
+			return diff, err
+		}
+	}
+	current, err := SynthAddresses(after)
+	if err != nil {
+		return diff, err
+	}
+
+	for address, body := range current {
+		prev, ok := old[address]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, address)
+		case prev != body:
+			diff.Changed = append(diff.Changed, address)
+		}
+	}
+	for address := range old {
+		if _, ok := current[address]; !ok {
+			diff.Removed = append(diff.Removed, address)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+	return diff, nil
+}
+
+// PrintSynthDiff writes one line per changed address, prefixed +, -, or ~.
+func PrintSynthDiff(w io.Writer, d SynthDiff) {
+	if d.Empty() {
+		fmt.Fprintln(w, "  no changes")
+		return
+	}
+	for _, address := range d.Added {
+		fmt.Fprintf(w, "  + %s\n", address)
+	}
+	for _, address := range d.Removed {
+		fmt.Fprintf(w, "  - %s\n", address)
+	}
+	for _, address := range d.Changed {
+		fmt.Fprintf(w, "  ~ %s\n", address)
+	}
+	fmt.Fprintf(w, "  %d added, %d removed, %d changed\n", len(d.Added), len(d.Removed), len(d.Changed))
+}
+
+// -------------------------------------------------------------------------------------------------
+// Watch Mode
+// -------------------------------------------------------------------------------------------------
+
+// WatchInterval is how often watch mode checks the config for changes.
+const WatchInterval = 500 * time.Millisecond
+
+// fileVersion identifies one version of a file by modification time and size.
+type fileVersion struct {
+	modified time.Time
+	size     int64
+}
+
+// statVersion returns the current version of a file, or the zero version when it cannot be read.
+func statVersion(path string) fileVersion {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileVersion{}
+	}
+	return fileVersion{info.ModTime(), info.Size()}
+}
+
+// WatchFile calls run once, then again whenever the file changes, until run returns false. It
+// polls instead of subscribing to file system events, which keeps the tool dependency-free and also
+// sees editors that replace the file on save.
+func WatchFile(path string, interval time.Duration, run func() bool) {
+	last := statVersion(path)
+	if !run() {
+		return
+	}
+	for {
+		time.Sleep(interval)
+		current := statVersion(path)
+		if current == last {
+			continue
+		}
+		last = current
+		if !run() {
+			return
+		}
+	}
+}
+
+// synthOutdir returns the directory a synth writes to, honouring CDKTF_OUTDIR like the app does.
+func synthOutdir() string {
+	if outdir := os.Getenv("CDKTF_OUTDIR"); outdir != "" {
+		return outdir
+	}
+	return "cdktf.out"
+}
+
+// stackOutDir returns the directory a synth writes a stack to.
+func stackOutDir(stack string) string {
+	return filepath.Join(synthOutdir(), "stacks", stack)
+}
+
+// synthOutputPath returns where a synth writes the main stack.
+func synthOutputPath() string {
+	return filepath.Join(stackOutDir(StackName), "cdk.tf.json")
+}
+
+// runChild runs this executable with the given arguments, returning its combined output.
+func runChild(args ...string) ([]byte, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	cmd := exec.Command(exe, args...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err = cmd.Run()
+	return out.Bytes(), err
+}
+
+// RunWatch lints and synthesizes on every change to the config, printing the lint findings when it
+// fails and otherwise the blocks the new synth added, removed, or changed. Each run is a separate
+// process, so a broken config reports its error without ending the watch.
+func RunWatch(synthArgs []string) error {
+	path := ConfigPath()
+	if IsRemoteConfig(path) {
+		return fmt.Errorf("cannot watch %s: only local configs can be watched", path)
+	}
+	outPath := synthOutputPath()
+	previous, _ := os.ReadFile(outPath)
+
+	log.Printf("[watch] Watching %s; press Ctrl-C to stop", path)
+	WatchFile(path, WatchInterval, func() bool {
+		log.Printf("[watch] %s", time.Now().Format("15:04:05"))
+		if out, err := runChild("lint"); err != nil {
+			os.Stdout.Write(out)
+			return true
+		}
+		if out, err := runChild(synthArgs...); err != nil {
+			os.Stdout.Write(out)
+			log.Printf("[watch] synth failed: %v", err)
+			return true
+		}
+
+		current, err := os.ReadFile(outPath)
+		if err != nil {
+			log.Printf("[watch] %v", err)
+			return true
+		}
+		diff, err := DiffSynth(previous, current)
+		if err != nil {
+			log.Printf("[watch] %v", err)
+			return true
+		}
+		PrintSynthDiff(os.Stdout, diff)
+		previous = current
+		return true
+	})
+	return nil
+}
@@ -0,0 +1,88 @@
+package peering
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestDiffSynth tests the address-level diff of two synthesized stacks.
+func TestDiffSynth(t *testing.T) {
+	before := []byte(`{
+  "resource": {
+    "aws_route": {
+      "SourceToPeerMainRoute0": {"destination_cidr_block": "10.1.0.0/16"},
+      "PeerToPeerMainRoute0": {"destination_cidr_block": "10.0.0.0/16"}
+    },
+    "aws_vpc_peering_connection": {"VpcPeering0": {"vpc_id": "vpc-1", "peer_vpc_id": "vpc-2"}}
+  },
+  "data": {"aws_vpc": {"SourceVpcData0": {"id": "vpc-1"}}},
+  "output": {"VpcPeeringConnectionId_0": {"value": "x"}}
+}`)
+	after := []byte(`{
+  "resource": {
+    "aws_route": {
+      "SourceToPeerMainRoute0": {"destination_cidr_block": "10.1.0.0/24"}
+    },
+    "aws_vpc_peering_connection": {"VpcPeering0": {"peer_vpc_id": "vpc-2", "vpc_id": "vpc-1"}}
+  },
+  "data": {"aws_vpc": {"SourceVpcData0": {"id": "vpc-1"}}},
+  "output": {"VpcPeeringConnectionId_0": {"value": "x"}, "connections": {"value": {}}},
+  "check": {"ConnectivityCheck0": {"assert": []}}
+}`)
+
+	diff, err := DiffSynth(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Added) != 2 || diff.Added[0] != "check.ConnectivityCheck0" || diff.Added[1] != "output.connections" {
+		t.Errorf("unexpected added: %v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0] != "aws_route.PeerToPeerMainRoute0" {
+		t.Errorf("unexpected removed: %v", diff.Removed)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0] != "aws_route.SourceToPeerMainRoute0" {
+		t.Errorf("expected only the route to change (key order ignored), got %v", diff.Changed)
+	}
+
+	first, err := DiffSynth(nil, before)
+	if err != nil || len(first.Added) != 5 {
+		t.Errorf("expected every block added without a previous synth, got %+v, %v", first, err)
+	}
+	if same, _ := DiffSynth(after, after); !same.Empty() {
+		t.Errorf("expected no changes, got %+v", same)
+	}
+}
+
+// TestWatchFile tests that the callback runs initially and again after the file changes.
+func TestWatchFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ConfigFileName)
+	if err := os.WriteFile(path, []byte("peers: {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	runs := 0
+	done := make(chan struct{})
+	go func() {
+		WatchFile(path, 5*time.Millisecond, func() bool {
+			runs++
+			if runs == 1 {
+				if err := os.WriteFile(path, []byte("peers: {foo: {}}\n"), 0o644); err != nil {
+					t.Error(err)
+				}
+			}
+			return runs < 2
+		})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch did not rerun after the file changed")
+	}
+	if runs != 2 {
+		t.Errorf("expected 2 runs, got %d", runs)
+	}
+}
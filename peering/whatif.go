@@ -0,0 +1,182 @@
+package peering
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+)
+
+// -------------------------------------------------------------------------------------------------
+// what-if
+// -------------------------------------------------------------------------------------------------
+
+// WhatIfQuota is the quota usage of one VPC of a proposed connection, without and with it.
+type WhatIfQuota struct {
+	Peer           string `json:"peer"`            // Peer name of the VPC.
+	VpcID          string `json:"vpc_id"`          // VPC ID.
+	PeeringsBefore int    `json:"peerings_before"` // Peerings of the VPC today.
+	PeeringsAfter  int    `json:"peerings_after"`  // Peerings of the VPC with the connection.
+	RoutesBefore   int    `json:"routes_before"`   // Estimated peering routes per route table today.
+	RoutesAfter    int    `json:"routes_after"`    // Estimated peering routes per route table with the connection.
+}
+
+// WhatIfReport is what adding one connection to the peering matrix would do.
+type WhatIfReport struct {
+	Source    string        `json:"source"`    // Source peer of the proposed connection.
+	Peer      string        `json:"peer"`      // Target peer of the proposed connection.
+	Valid     bool          `json:"valid"`     // Whether none of the findings is an error.
+	Findings  []Diagnostic  `json:"findings"`  // Lint findings the connection adds to those of the config.
+	Quotas    []WhatIfQuota `json:"quotas"`    // Quota usage of both VPCs.
+	Resources []string      `json:"resources"` // Addresses of the resources the source's stack would gain, sorted.
+}
+
+// SimulateConnection adds source -> peer, with default settings, to a copy of the config and reports
+// the lint findings it introduces (the rules run on both configs and only new findings are kept),
+// the quota usage of both VPCs, and the resources it would create. The config is left untouched.
+func SimulateConnection(cfg YAMLConfig, source, peer string, rules []LintRule) (WhatIfReport, error) {
+	for _, name := range []string{source, peer} {
+		if _, ok := cfg.Peers[name]; !ok {
+			return WhatIfReport{}, fmt.Errorf("no peer named %q", name)
+		}
+	}
+	for _, row := range BuildMatrixRows(cfg) {
+		if row.Source == source && row.Peer == peer {
+			return WhatIfReport{}, fmt.Errorf("%q -> %q is already in the peering matrix; see describe", source, peer)
+		}
+	}
+
+	proposed := cfg
+	proposed.PeeringMatrix = make(map[string][]MatrixEntry, len(cfg.PeeringMatrix)+1)
+	for s, entries := range cfg.PeeringMatrix {
+		proposed.PeeringMatrix[s] = entries
+	}
+	proposed.PeeringMatrix[source] = append(append([]MatrixEntry(nil), cfg.PeeringMatrix[source]...), MatrixEntry{Peer: peer})
+
+	report := WhatIfReport{Source: source, Peer: peer, Valid: true}
+	existing := make(map[Diagnostic]bool)
+	for _, d := range Lint(cfg, rules) {
+		existing[d] = true
+	}
+	for _, d := range Lint(proposed, rules) {
+		if !existing[d] {
+			report.Findings = append(report.Findings, d)
+			report.Valid = report.Valid && d.Severity != SeverityError
+		}
+	}
+
+	current, err := peeringConnections(cfg)
+	if err != nil {
+		return WhatIfReport{}, err
+	}
+	withPeer, err := peeringConnections(proposed)
+	if err != nil {
+		return WhatIfReport{}, fmt.Errorf("%q -> %q: %w", source, peer, err)
+	}
+	before, after := CountVpcUsage(current), CountVpcUsage(withPeer)
+	for _, name := range []string{source, peer} {
+		q := WhatIfQuota{Peer: name, VpcID: cfg.Peers[name].VpcID}
+		if u := before[q.VpcID]; u != nil {
+			q.PeeringsBefore, q.RoutesBefore = u.Peerings, u.Routes
+		}
+		if u := after[q.VpcID]; u != nil {
+			q.PeeringsAfter, q.RoutesAfter = u.Peerings, u.Routes
+		}
+		report.Quotas = append(report.Quotas, q)
+	}
+
+	// The new entry is the last of its source, so its index in the stack is the number of the
+	// source's connections before it.
+	index := 0
+	namer := NewNamer(cfg.Naming)
+	for _, row := range BuildMatrixRows(proposed) {
+		if row.Source != source || row.Err != nil || row.Disabled || row.Config.Lattice {
+			continue
+		}
+		if row.Peer != peer {
+			index++
+			continue
+		}
+		for _, address := range ConnectionAddresses(namer, ConnectionNameContext(index, row.Config), row.Config) {
+			report.Resources = append(report.Resources, address)
+		}
+		sort.Strings(report.Resources)
+	}
+	return report, nil
+}
+
+// PrintWhatIf writes the findings, quota usage, and resources of a proposed connection.
+func PrintWhatIf(w io.Writer, r WhatIfReport) {
+	verdict := "can be added"
+	if !r.Valid {
+		verdict = "cannot be added as is"
+	}
+	fmt.Fprintf(w, "%s -> %s %s\n", r.Source, r.Peer, verdict)
+
+	fmt.Fprintf(w, "\nFindings\n")
+	if len(r.Findings) == 0 {
+		fmt.Fprintf(w, "  none\n")
+	} else {
+		PrintDiagnostics(w, r.Findings)
+	}
+
+	fmt.Fprintf(w, "\nQuotas\n")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "  PEER\tVPC\tPEERINGS (of %d)\tROUTES PER TABLE (of %d)\n", PeeringsPerVpcQuota, RoutesPerTableQuota)
+	for _, q := range r.Quotas {
+		fmt.Fprintf(tw, "  %s\t%s\t%d -> %d\t%d -> %d\n", q.Peer, q.VpcID, q.PeeringsBefore, q.PeeringsAfter, q.RoutesBefore, q.RoutesAfter)
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "\nResources (%d)\n", len(r.Resources))
+	for _, address := range r.Resources {
+		fmt.Fprintf(w, "  %s\n", address)
+	}
+}
+
+// runWhatIf reports what adding a connection would do, without editing the config.
+func runWhatIf(args []string) error {
+	fs := flag.NewFlagSet("what-if", flag.ContinueOnError)
+	source := fs.String("source", "", "source peer of the proposed connection")
+	peer := fs.String("peer", "", "target peer of the proposed connection")
+	format := fs.String("format", "text", "output format: text or json")
+	lookup := fs.Bool("lookup", false, "look up the CIDRs of VPCs without declared cidrs in AWS and check them for conflicts")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *source == "" || *peer == "" || fs.NArg() > 0 {
+		return fmt.Errorf("expected --source <peer> --peer <peer>")
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q (use text or json)", *format)
+	}
+	cfg := LoadConfig(ConfigPath())
+
+	rules := LintRules()
+	if *lookup {
+		rules = append(rules, lookupCidrsRule(cfg.Provider))
+	}
+	var report WhatIfReport
+	var err error
+	quietLogs(func() { report, err = SimulateConnection(cfg, *source, *peer, rules) })
+	if err != nil {
+		return err
+	}
+
+	if *format == "json" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	} else {
+		PrintWhatIf(os.Stdout, report)
+	}
+	if !report.Valid {
+		return WithExitCode(ExitValidation, fmt.Errorf("%s -> %s would not be valid", *source, *peer))
+	}
+	return nil
+}
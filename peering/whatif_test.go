@@ -0,0 +1,60 @@
+package peering
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestSimulateConnection tests the report for a proposed connection and that the config is left
+// untouched.
+func TestSimulateConnection(t *testing.T) {
+	cfg := YAMLConfig{
+		Peers: map[string]YAMLPeer{
+			"dev":     {VpcID: "vpc-1", Region: "us-east-1", Cidrs: []string{"10.0.0.0/16"}},
+			"prod":    {VpcID: "vpc-2", Region: "us-east-1", Cidrs: []string{"10.1.0.0/16"}},
+			"staging": {VpcID: "vpc-3", Region: "us-east-1", Cidrs: []string{"10.0.128.0/17"}},
+		},
+		PeeringMatrix: map[string][]MatrixEntry{"dev": {{Peer: "prod"}}},
+	}
+
+	report, err := SimulateConnection(cfg, "prod", "staging", LintRules())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Valid || len(cfg.PeeringMatrix) != 1 {
+		t.Errorf("expected a valid connection and an untouched config, got %+v, matrix %v", report, cfg.PeeringMatrix)
+	}
+	for _, d := range report.Findings {
+		if d.Rule == "unused-peer" {
+			t.Errorf("unexpected finding %+v", d)
+		}
+	}
+	if q := report.Quotas[0]; q.Peer != "prod" || q.PeeringsBefore != 1 || q.PeeringsAfter != 2 || q.RoutesBefore != 1 || q.RoutesAfter != 2 {
+		t.Errorf("unexpected quota usage of prod: %+v", q)
+	}
+	if q := report.Quotas[1]; q.PeeringsBefore != 0 || q.PeeringsAfter != 1 {
+		t.Errorf("unexpected quota usage of staging: %+v", q)
+	}
+	found := false
+	for _, address := range report.Resources {
+		found = found || address == "aws_vpc_peering_connection.VpcPeering0"
+	}
+	if !found {
+		t.Errorf("expected the peering of prod's first connection among %v", report.Resources)
+	}
+
+	overlapping, err := SimulateConnection(cfg, "dev", "staging", LintRules())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if overlapping.Valid || len(overlapping.Findings) == 0 || !strings.Contains(overlapping.Findings[0].Message, "overlaps") {
+		t.Errorf("expected an overlap error, got %+v", overlapping.Findings)
+	}
+
+	if _, err := SimulateConnection(cfg, "dev", "prod", LintRules()); err == nil {
+		t.Error("expected an error for a connection already in the matrix")
+	}
+	if _, err := SimulateConnection(cfg, "dev", "qa", LintRules()); err == nil {
+		t.Error("expected an error for an unknown peer")
+	}
+}